@@ -6,6 +6,7 @@
 package main
 
 import (
+	"compress/flate"
 	"context"
 	"fmt"
 	"log/slog"
@@ -25,9 +26,7 @@ func main() {
 	n := getCaseCount()
 	slog.Info("case count", slog.Int("n", n+1))
 
-	// Not implemented in Timpani (so excluded in "config/fuzzingserver.json"):
-	//   - 6.4.*: Fail-fast on invalid UTF-8 frames,
-	//   - 12.* and 13.*: WebSocket compression.
+	// Not implemented in Timpani: 6.4.* (fail-fast on invalid UTF-8 frames).
 	for i := range n {
 		runCase(i + 1)
 	}
@@ -36,7 +35,7 @@ func main() {
 }
 
 func dial(url string) (*websocket.Conn, error) {
-	return websocket.Dial(context.Background(), url)
+	return websocket.Dial(context.Background(), url, websocket.WithDeflate(flate.DefaultCompression))
 }
 
 // getCaseCount retrieves the number of enabled test cases from
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/urfave/cli/v3"
+
+	"github.com/tzrikka/timpani/internal/config"
+)
+
+const redacted = "[REDACTED]"
+
+// secretFlagNames are substrings of flag names whose values must never be printed.
+var secretFlagNames = []string{"password", "secret", "token"}
+
+// configCommand defines the "config" subcommand, to troubleshoot
+// configuration issues without starting the server and worker.
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "inspect the configuration",
+		Commands: []*cli.Command{
+			{
+				Name:  "check",
+				Usage: "validate the configuration, and print its effective values (with secrets redacted)",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return checkConfig(cmd.Root(), configFile().SourceURI())
+				},
+			},
+			{
+				Name:  "path",
+				Usage: "print the path of the configuration file",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					_, err := fmt.Fprintln(cmd.Root().Writer, configFile().SourceURI())
+					return err
+				},
+			},
+		},
+	}
+}
+
+// checkConfig reports unknown keys in the configuration file and invalid flag values,
+// and prints the effective value of each flag. Note that flag validators and type
+// conversions (e.g. durations) are also enforced by the CLI library before this.
+func checkConfig(cmd *cli.Command, path string) error {
+	issues, err := unknownTOMLKeys(path, config.Keys())
+	if err != nil {
+		return err
+	}
+	issues = append(issues, invalidValues(cmd)...)
+
+	printEffectiveConfig(cmd.Writer, cmd)
+
+	for _, i := range issues {
+		_, _ = fmt.Fprintln(cmd.ErrWriter, "Config error: "+i)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d configuration error(s) in %q", len(issues), path)
+	}
+
+	return nil
+}
+
+// unknownTOMLKeys returns a description of each key in the given TOML file that is not
+// mapped to any CLI flag (see [config.TOML]), but only under known sections (e.g.
+// "temporal.taskqueue").
+func unknownTOMLKeys(path string, known map[string]bool) ([]string, error) {
+	sections := tomlSections(known)
+
+	md, err := toml.DecodeFile(path, &map[string]any{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	var issues []string
+	for _, k := range md.Keys() {
+		if md.Type(k...) == "Hash" {
+			continue // Table header, not a value.
+		}
+		key := k.String()
+		if !sections[k[0]] || known[key] {
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("unknown key %q", key))
+	}
+
+	return issues, nil
+}
+
+// tomlSections returns the (possibly nested) sections that the given TOML keys belong to.
+func tomlSections(keys map[string]bool) map[string]bool {
+	sections := map[string]bool{}
+	for key := range keys {
+		for i, c := range key {
+			if c == '.' {
+				sections[key[:i]] = true
+			}
+		}
+	}
+	return sections
+}
+
+// flagSources returns the value source chain of the given flag, if it has one.
+// All the flag types of urfave/cli are instantiations of the same generic
+// struct, but it doesn't expose the chain through any interface.
+func flagSources(f cli.Flag) *cli.ValueSourceChain {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	s := v.Elem().FieldByName("Sources")
+	if !s.IsValid() || !s.CanAddr() {
+		return nil
+	}
+
+	vsc, _ := s.Addr().Interface().(*cli.ValueSourceChain)
+	return vsc
+}
+
+// invalidValues checks flag values that are not covered by flag validators.
+func invalidValues(cmd *cli.Command) []string {
+	var issues []string
+	for _, f := range cmd.Flags {
+		name := f.Names()[0]
+		var err error
+
+		switch v := f.Get().(type) {
+		case string:
+			if strings.HasSuffix(name, "-address") && v != "" {
+				err = validateAddress(v)
+			}
+		case time.Duration:
+			if v < 0 {
+				err = errors.New("negative duration")
+			}
+		}
+
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("invalid value for %q: %v", name, err))
+		}
+	}
+	return issues
+}
+
+// validateAddress checks that the given string is a "host:port" address,
+// optionally with an HTTP(S) scheme prefix and a path suffix.
+func validateAddress(addr string) error {
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "http://"), "https://")
+	addr, _, _ = strings.Cut(addr, "/")
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// printEffectiveConfig prints the value of each flag, and where it came from. Note that
+// command-line flags take precedence over environment variables and TOML keys, so the
+// reported source of a flag that is set in more than one way may be inaccurate.
+func printEffectiveConfig(w io.Writer, cmd *cli.Command) {
+	fs := slices.Clone(cmd.Flags)
+	slices.SortFunc(fs, func(a, b cli.Flag) int {
+		return strings.Compare(a.Names()[0], b.Names()[0])
+	})
+
+	for _, f := range fs {
+		name := f.Names()[0]
+		if name == "help" || name == "version" {
+			continue
+		}
+
+		value := fmt.Sprint(f.Get())
+		source := "default"
+		if cmd.IsSet(name) {
+			source = "command line"
+			if vsc := flagSources(f); vsc != nil {
+				if _, vs, ok := vsc.LookupWithSource(); ok {
+					source = vs.String()
+				}
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "%s = %q (%s)\n", name, redact(name, value), source)
+	}
+}
+
+// redact hides the values of secret flags, and passwords in URLs.
+func redact(name, value string) string {
+	if value == "" {
+		return value
+	}
+
+	for _, s := range secretFlagNames {
+		if strings.Contains(name, s) {
+			return redacted
+		}
+	}
+
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+
+	return value
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/thrippy"
+	httpclient "github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/http/webhooks"
+	"github.com/tzrikka/timpani/pkg/listeners"
+	"github.com/tzrikka/timpani/pkg/listeners/slack"
+)
+
+// doctorCommand defines the "doctor" subcommand, to diagnose connectivity
+// issues end-to-end (e.g. when onboarding a new deployment), without
+// starting the server and worker.
+func doctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "check connectivity to Thrippy, Temporal, third-party services, and event listeners",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "webhook-url",
+				Usage: "public base URL of the HTTP server (e.g. a tunnel or a load balancer), to check that it's reachable from outside",
+			},
+			&cli.BoolFlag{
+				Name: "slack-socket-mode-dial",
+				Usage: "also open (and close) a real Slack Socket Mode connection; don't use this while listeners are running, " +
+					"because Slack distributes events between all the open connections, so some of them may be dropped",
+			},
+			&cli.DurationFlag{
+				Name:  "check-timeout",
+				Usage: "timeout of each check",
+				Value: 10 * time.Second,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := httpclient.ConfigureTransport(cmd); err != nil {
+				return err
+			}
+
+			d := &doctor{cmd: cmd, links: map[string]doctorLink{}}
+			defer d.close()
+
+			return runDoctorChecks(ctx, cmd.Root().Writer, d.checks(), cmd.Duration("check-timeout"))
+		},
+	}
+}
+
+// doctorCheck is a single step of the "doctor" subcommand. It returns optional details
+// if it passes, or a [skipReason] error if it doesn't apply to the current configuration.
+type doctorCheck struct {
+	name string
+	hint string // Remediation hint, printed only if the check fails.
+	run  func(ctx context.Context) (string, error)
+}
+
+// skipReason explains why a [doctorCheck] was skipped, e.g. because
+// it's irrelevant to the current configuration, or depends on a failed check.
+type skipReason string
+
+func (s skipReason) Error() string {
+	return string(s)
+}
+
+// runDoctorChecks runs all the given checks sequentially, even after a failure, and prints
+// the result of each one, along with remediation hints for failures. It returns an error
+// if any of the checks failed, so the process's exit code reflects the overall status.
+func runDoctorChecks(ctx context.Context, w io.Writer, checks []doctorCheck, timeout time.Duration) error {
+	failed := 0
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		details, err := c.run(ctx)
+		cancel()
+
+		var skip skipReason
+		switch {
+		case errors.As(err, &skip):
+			_, _ = fmt.Fprintf(w, "SKIP: %s (%s)\n", c.name, skip)
+		case err != nil:
+			_, _ = fmt.Fprintf(w, "FAIL: %s: %v\n", c.name, err)
+			if c.hint != "" {
+				_, _ = fmt.Fprintf(w, "      Hint: %s\n", c.hint)
+			}
+			failed++
+		case details != "":
+			_, _ = fmt.Fprintf(w, "PASS: %s (%s)\n", c.name, details)
+		default:
+			_, _ = fmt.Fprintf(w, "PASS: %s\n", c.name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d doctor check(s) failed", failed, len(checks))
+	}
+	return nil
+}
+
+// doctor holds the state which is shared by the checks of the "doctor"
+// subcommand, because later checks depend on the results of earlier ones.
+type doctor struct {
+	cmd *cli.Command
+
+	thrippyConn *grpc.ClientConn
+	links       map[string]doctorLink // Service name (lowercase) to Thrippy link data.
+
+	temporal client.Client
+}
+
+type doctorLink struct {
+	id       string
+	template string
+	secrets  map[string]string
+}
+
+func (d *doctor) close() {
+	if d.thrippyConn != nil {
+		_ = d.thrippyConn.Close()
+	}
+	if d.temporal != nil {
+		d.temporal.Close()
+	}
+}
+
+// checks returns the checks of the "doctor" subcommand, in the order in which they run.
+func (d *doctor) checks() []doctorCheck {
+	checks := []doctorCheck{
+		{
+			name: "Thrippy gRPC service at " + d.cmd.String("thrippy-grpc-address"),
+			hint: "check the --thrippy-grpc-address and Thrippy TLS flags, that the Thrippy server is running, and that the configured link IDs exist",
+			run:  d.checkThrippy,
+		},
+		{
+			name: fmt.Sprintf("Temporal namespace %q at %s", d.cmd.String("temporal-namespace"), d.cmd.String("temporal-address")),
+			hint: "check the --temporal-address and --temporal-namespace flags, that the Temporal server is running, and that the namespace exists",
+			run:  d.checkTemporalNamespace,
+		},
+		{
+			name: fmt.Sprintf("Temporal task queue %q pollers", d.cmd.String("temporal-task-queue")),
+			hint: "start a Timpani worker (--role=all or --role=worker) with the same --temporal-task-queue",
+			run:  d.checkTemporalPollers,
+		},
+	}
+
+	for _, s := range services {
+		if _, err := thrippy.LinkID(d.cmd, s); errors.Is(err, thrippy.ErrLinkIDNotConfigured) {
+			continue
+		}
+		service := strings.ToLower(s)
+		checks = append(checks, doctorCheck{
+			name: s + " API credentials",
+			hint: "re-authorize the Thrippy link, or update its credentials",
+			run: func(ctx context.Context) (string, error) {
+				return d.checkLinkCredentials(ctx, service)
+			},
+		})
+		if service == "slack" {
+			checks = append(checks, doctorCheck{
+				name: "Slack Socket Mode connection",
+				hint: "check that Socket Mode is enabled in the Slack app, and that the link's app-level token has the connections:write scope",
+				run:  d.checkSlackSocketMode,
+			})
+		}
+	}
+
+	return append(checks, doctorCheck{
+		name: "webhook URL reachability",
+		hint: fmt.Sprintf("check the tunnel, reverse proxy, or firewall in front of --webhook-port %d", d.cmd.Int("webhook-port")),
+		run:  d.checkWebhookURL,
+	})
+}
+
+// checkThrippy connects to the Thrippy gRPC service, and retrieves
+// the templates and credentials of all the configured Thrippy links.
+func (d *doctor) checkThrippy(ctx context.Context) (string, error) {
+	conn, err := thrippy.NewConn(d.cmd.String("thrippy-grpc-address"), thrippy.SecureCreds(ctx, d.cmd), 0)
+	if err != nil {
+		return "", err
+	}
+	d.thrippyConn = conn
+
+	conn.Connect()
+	for s := conn.GetState(); s != connectivity.Ready; s = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, s) {
+			return "", fmt.Errorf("gRPC connection state: %s", s)
+		}
+	}
+
+	var found []string
+	c := thrippypb.NewThrippyServiceClient(conn)
+	for _, s := range services {
+		id, err := thrippy.LinkID(d.cmd, s)
+		if errors.Is(err, thrippy.ErrLinkIDNotConfigured) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", s, err)
+		}
+
+		link, err := c.GetLink(ctx, thrippypb.GetLinkRequest_builder{LinkId: new(id)}.Build())
+		if status.Code(err) == codes.NotFound {
+			return "", fmt.Errorf("%s link %q not found", s, id)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s link %q: %w", s, id, err)
+		}
+
+		creds, err := c.GetCredentials(ctx, thrippypb.GetCredentialsRequest_builder{LinkId: new(id)}.Build())
+		if err != nil {
+			return "", fmt.Errorf("%s link %q credentials: %w", s, id, err)
+		}
+		if len(creds.GetCredentials()) == 0 {
+			return "", fmt.Errorf("%s link %q is not initialized", s, id)
+		}
+
+		d.links[strings.ToLower(s)] = doctorLink{id: id, template: link.GetTemplate(), secrets: creds.GetCredentials()}
+		found = append(found, fmt.Sprintf("%s=%s", strings.ToLower(s), link.GetTemplate()))
+	}
+
+	if len(found) == 0 {
+		return "no links configured", nil
+	}
+	return "links: " + strings.Join(found, ", "), nil
+}
+
+// checkTemporalNamespace connects to the Temporal server, and describes the configured namespace.
+func (d *doctor) checkTemporalNamespace(ctx context.Context) (string, error) {
+	c, err := client.DialContext(ctx, client.Options{
+		HostPort:  d.cmd.String("temporal-address"),
+		Namespace: d.cmd.String("temporal-namespace"),
+		Logger:    log.NewStructuredLogger(slog.New(slog.DiscardHandler)),
+	})
+	if err != nil {
+		return "", err
+	}
+	d.temporal = c
+
+	req := &workflowservice.DescribeNamespaceRequest{Namespace: d.cmd.String("temporal-namespace")}
+	resp, err := c.WorkflowService().DescribeNamespace(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	return "state: " + resp.GetNamespaceInfo().GetState().String(), nil
+}
+
+// checkTemporalPollers checks that Temporal workers are polling the configured task
+// queue, unless this process's role doesn't expect a worker (i.e. "--role=listener").
+func (d *doctor) checkTemporalPollers(ctx context.Context) (string, error) {
+	if d.cmd.String("role") == roleListener {
+		return "", skipReason("no worker expected with --role=listener")
+	}
+	if d.temporal == nil {
+		return "", skipReason("Temporal is unavailable")
+	}
+
+	tq := d.cmd.String("temporal-task-queue")
+	if tq == "" {
+		return "", errors.New("task queue not configured")
+	}
+
+	resp, err := d.temporal.DescribeTaskQueue(ctx, tq, enumspb.TASK_QUEUE_TYPE_ACTIVITY)
+	if err != nil {
+		return "", err
+	}
+	n := len(resp.GetPollers())
+	if n == 0 {
+		return "", errors.New("no pollers")
+	}
+
+	return fmt.Sprintf("%d poller(s)", n), nil
+}
+
+// checkLinkCredentials calls the cheapest authenticated API method of the given
+// service, with the credentials of its Thrippy link (see [webhooks.ProbeLink]).
+func (d *doctor) checkLinkCredentials(ctx context.Context, service string) (string, error) {
+	link, ok := d.links[service]
+	if !ok {
+		return "", skipReason("Thrippy link data is unavailable")
+	}
+
+	if err := webhooks.ProbeLink(ctx, link.template, link.secrets); err != nil {
+		if errors.Is(err, webhooks.ErrNoLinkProbe) {
+			return "", skipReason(fmt.Sprintf("no credentials check for link template %q", link.template))
+		}
+		return "", err
+	}
+
+	return "link template: " + link.template, nil
+}
+
+// checkSlackSocketMode checks that Slack generates Socket Mode URLs, if the Slack link is
+// configured to receive events over WebSocket. It opens and closes a single connection
+// only if "--slack-socket-mode-dial" is set, because that connection may receive (and
+// drop) live events while the listeners of the same Slack app are running.
+func (d *doctor) checkSlackSocketMode(ctx context.Context) (string, error) {
+	link, ok := d.links["slack"]
+	if !ok {
+		return "", skipReason("Thrippy link data is unavailable")
+	}
+	if _, ok := listeners.ConnectionHandlers[link.template]; !ok {
+		return "", skipReason(fmt.Sprintf("link template %q doesn't use Socket Mode", link.template))
+	}
+
+	dial := d.cmd.Bool("slack-socket-mode-dial")
+	if err := slack.ProbeConnection(ctx, link.secrets, dial); err != nil {
+		return "", err
+	}
+	if !dial {
+		return "URL generated, connection not dialed without --slack-socket-mode-dial", nil
+	}
+	return "", nil
+}
+
+// checkWebhookURL sends a request to the health-check endpoint of the HTTP server, through the
+// public URL that third-party services use to send webhooks (e.g. a tunnel or a load balancer).
+func (d *doctor) checkWebhookURL(ctx context.Context) (string, error) {
+	base := d.cmd.String("webhook-url")
+	if base == "" {
+		return "", skipReason("--webhook-url not specified")
+	}
+
+	u, err := url.JoinPath(base, "healthz")
+	if err != nil {
+		return "", err
+	}
+
+	_, _, _, err = httpclient.HTTPRequest(ctx, http.MethodGet, u, "", "", "", nil)
+	return "", err
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	pass := func(_ context.Context) (string, error) { return "", nil }
+	details := func(_ context.Context) (string, error) { return "2 poller(s)", nil }
+	skip := func(_ context.Context) (string, error) { return "", skipReason("not configured") }
+	fail := func(_ context.Context) (string, error) { return "", errors.New("connection refused") }
+	timeout := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	tests := []struct {
+		name    string
+		checks  []doctorCheck
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "all_pass_or_skip",
+			checks: []doctorCheck{{name: "a", run: pass}, {name: "b", run: details}, {name: "c", run: skip}},
+			want:   "PASS: a\nPASS: b (2 poller(s))\nSKIP: c (not configured)\n",
+		},
+		{
+			name:    "failure_with_hint",
+			checks:  []doctorCheck{{name: "a", hint: "start the server", run: fail}, {name: "b", run: pass}},
+			want:    "FAIL: a: connection refused\n      Hint: start the server\nPASS: b\n",
+			wantErr: true,
+		},
+		{
+			name:    "timeout",
+			checks:  []doctorCheck{{name: "a", run: timeout}},
+			want:    "FAIL: a: context deadline exceeded\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := runDoctorChecks(t.Context(), buf, tt.checks, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runDoctorChecks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("runDoctorChecks() output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoctorCheckWebhookURL(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		wantErr  bool
+		wantSkip bool
+	}{
+		{
+			name:     "not_specified",
+			wantSkip: true,
+		},
+		{
+			name: "reachable",
+			url:  s.URL,
+		},
+		{
+			name:    "wrong_path",
+			url:     s.URL + "/prefix",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cli.Command{Flags: []cli.Flag{&cli.StringFlag{Name: "webhook-url"}}}
+			_ = cmd.Set("webhook-url", tt.url)
+
+			d := &doctor{cmd: cmd}
+			_, err := d.checkWebhookURL(t.Context())
+
+			var skip skipReason
+			if errors.As(err, &skip) != tt.wantSkip {
+				t.Errorf("checkWebhookURL() error = %v, want skip = %v", err, tt.wantSkip)
+			}
+			if !tt.wantSkip && (err != nil) != tt.wantErr {
+				t.Errorf("checkWebhookURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoctorCheckTemporalPollers(t *testing.T) {
+	cmd := &cli.Command{Flags: []cli.Flag{&cli.StringFlag{Name: "role"}}}
+
+	tests := []struct {
+		role string
+	}{
+		{role: roleListener},
+		{role: roleAll}, // Temporal is unavailable.
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			_ = cmd.Set("role", tt.role)
+
+			d := &doctor{cmd: cmd}
+			var skip skipReason
+			if _, err := d.checkTemporalPollers(t.Context()); !errors.As(err, &skip) {
+				t.Errorf("checkTemporalPollers() error = %v, want skip", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lmittmann/tint"
+	"github.com/urfave/cli/v3"
+
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/pkg/http/webhooks"
+	"github.com/tzrikka/timpani/pkg/listeners"
+)
+
+// templateAliases map service names to a representative
+// Thrippy link template, for convenience in CLI flags.
+var templateAliases = map[string]string{
+	"bitbucket": "bitbucket-app-oauth",
+	"github":    "github-webhook",
+	"slack":     "slack-oauth",
+}
+
+// listenersCommand defines the "listeners" subcommand, to troubleshoot
+// webhook setup issues (e.g. signing secret mismatches) without real traffic.
+func listenersCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "listeners",
+		Usage: "troubleshoot event listeners",
+		Commands: []*cli.Command{
+			{
+				Name:  "verify",
+				Usage: "run the verification checks of a webhook handler against a captured delivery, or sign a payload with --generate",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "template",
+						Usage:    "Thrippy link template (e.g. slack-oauth, bitbucket-server), or service name: " + strings.Join(slices.Sorted(maps.Keys(templateAliases)), ", "),
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "secret-file",
+						Usage:    "file containing the link's signing/webhook secret",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "payload-file",
+						Usage:    "file containing the raw body of the webhook delivery",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "headers-file",
+						Usage: `file containing the HTTP headers of the webhook delivery, one "Name: value" per line`,
+					},
+					&cli.BoolFlag{
+						Name:  "generate",
+						Usage: "sign the payload and print a curl command to send it, instead of verifying it",
+					},
+					&cli.StringFlag{
+						Name:  "url",
+						Usage: "webhook URL for the generated curl command",
+						Value: fmt.Sprintf("http://localhost:%d/webhook/LINK_ID", webhooks.DefaultWebhookPort),
+					},
+					&cli.StringFlag{
+						Name:  "content-type",
+						Usage: "content type of the generated webhook delivery",
+						Value: "application/json",
+					},
+				},
+				Action: verifyWebhookAction,
+			},
+		},
+	}
+}
+
+func verifyWebhookAction(_ context.Context, cmd *cli.Command) error {
+	v, err := webhookVerifier(cmd.String("template"))
+	if err != nil {
+		return err
+	}
+
+	secret, err := os.ReadFile(cmd.String("secret-file"))
+	if err != nil {
+		return fmt.Errorf("failed to read secret file: %w", err)
+	}
+	payload, err := os.ReadFile(cmd.String("payload-file"))
+	if err != nil {
+		return fmt.Errorf("failed to read payload file: %w", err)
+	}
+
+	if cmd.Bool("generate") {
+		h := v.Sign(strings.TrimSpace(string(secret)), payload, time.Now().UTC())
+		h.Set("Content-Type", cmd.String("content-type"))
+		return printCurlCommand(cmd.Root().Writer, cmd.String("url"), h, cmd.String("payload-file"))
+	}
+
+	if cmd.String("headers-file") == "" {
+		return errors.New("missing required flag: --headers-file (or use --generate)")
+	}
+	raw, err := os.ReadFile(cmd.String("headers-file"))
+	if err != nil {
+		return fmt.Errorf("failed to read headers file: %w", err)
+	}
+	h, err := parseHeaders(string(raw))
+	if err != nil {
+		return err
+	}
+
+	r := intlis.RequestData{
+		Headers:     h,
+		RawPayload:  payload,
+		LinkSecrets: map[string]string{v.SecretName: strings.TrimSpace(string(secret))},
+	}
+	return runWebhookChecks(cmd.Root().Writer, v, r)
+}
+
+// webhookVerifier returns the verification steps of the
+// webhook handler of a Thrippy link template or service name.
+func webhookVerifier(template string) (intlis.WebhookVerifier, error) {
+	if t, ok := templateAliases[template]; ok {
+		template = t
+	}
+
+	v, ok := listeners.WebhookVerifiers[template]
+	if !ok {
+		return v, fmt.Errorf("unsupported link template for webhook verification: %q", template)
+	}
+	return v, nil
+}
+
+// runWebhookChecks runs all the checks of a webhook handler, even after a failure (unlike the handler itself),
+// and prints the result of each one, along with the handler's own log messages which explain failures.
+func runWebhookChecks(w io.Writer, v intlis.WebhookVerifier, r intlis.RequestData) error {
+	l := slog.New(tint.NewHandler(w, &tint.Options{Level: slog.LevelInfo, NoColor: true, ReplaceAttr: dropTime}))
+
+	failed := 0
+	for _, c := range v.Checks {
+		if statusCode := c.Check(l, r); statusCode != http.StatusOK {
+			_, _ = fmt.Fprintf(w, "FAIL: %s (HTTP %d %s)\n", c.Name, statusCode, http.StatusText(statusCode))
+			failed++
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "PASS: %s\n", c.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d webhook verification check(s) failed", failed, len(v.Checks))
+	}
+	return nil
+}
+
+// dropTime removes timestamps from the log messages of [runWebhookChecks].
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// parseHeaders parses HTTP headers in their wire format, e.g. as captured by "curl -v"
+// or a reverse proxy. An optional request or status line at the beginning is ignored.
+func parseHeaders(s string) (http.Header, error) {
+	h := http.Header{}
+	for i, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if i == 0 && (strings.HasPrefix(line, "HTTP/") || strings.Contains(line, " HTTP/")) {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header line %d: %q", i+1, line)
+		}
+		h.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return h, nil
+}
+
+// printCurlCommand prints a curl command which sends a signed webhook delivery, with sorted headers.
+func printCurlCommand(w io.Writer, url string, h http.Header, payloadFile string) error {
+	var sb strings.Builder
+	sb.WriteString("curl -X POST " + url)
+	for _, k := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[k] {
+			fmt.Fprintf(&sb, " \\\n  -H '%s: %s'", k, v)
+		}
+	}
+	fmt.Fprintf(&sb, " \\\n  --data-binary '@%s'\n", payloadFile)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
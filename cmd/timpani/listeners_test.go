@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+)
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    http.Header
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			want: http.Header{},
+		},
+		{
+			name: "request_line_and_crlf",
+			s:    "POST /webhook/id HTTP/1.1\r\nContent-Type: application/json\r\nx-hub-signature-256: sha256=abc\r\n\r\n",
+			want: http.Header{
+				"Content-Type":        []string{"application/json"},
+				"X-Hub-Signature-256": []string{"sha256=abc"},
+			},
+		},
+		{
+			name: "value_with_colon",
+			s:    "Host: localhost:14480\n",
+			want: http.Header{"Host": []string{"localhost:14480"}},
+		},
+		{
+			name:    "invalid_line",
+			s:       "Content-Type: application/json\nfoo\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeaders(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWebhookChecks(t *testing.T) {
+	body := []byte(`{"foo":"bar"}`)
+	tests := []struct {
+		name     string
+		template string
+		secret   string
+		wantErr  bool
+		wantFail string
+	}{
+		{
+			name:     "bitbucket_server",
+			template: "bitbucket-server",
+			secret:   "secret",
+		},
+		{
+			name:     "github",
+			template: "github",
+			secret:   "secret",
+		},
+		{
+			name:     "slack",
+			template: "slack",
+			secret:   "secret",
+		},
+		{
+			name:     "slack_secret_mismatch",
+			template: "slack",
+			secret:   "other",
+			wantErr:  true,
+			wantFail: "FAIL: signature (HTTP 403 Forbidden)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := webhookVerifier(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := v.Sign("secret", body, time.Now())
+			h.Set("Content-Type", "application/json")
+			r := intlis.RequestData{Headers: h, RawPayload: body, LinkSecrets: map[string]string{v.SecretName: tt.secret}}
+
+			w := new(bytes.Buffer)
+			if err := runWebhookChecks(w, v, r); (err != nil) != tt.wantErr {
+				t.Errorf("runWebhookChecks() error = %v, wantErr %v\n%s", err, tt.wantErr, w)
+			}
+			if tt.wantFail != "" && !strings.Contains(w.String(), tt.wantFail) {
+				t.Errorf("runWebhookChecks() output = %q, want %q", w, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifierUnsupported(t *testing.T) {
+	if _, err := webhookVerifier("jira-user-token"); err == nil {
+		t.Error("webhookVerifier() error = nil, want unsupported template error")
+	}
+}
@@ -3,19 +3,27 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/lmittmann/tint"
 	altsrc "github.com/urfave/cli-altsrc/v3"
 	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/worker"
 
+	"github.com/tzrikka/timpani/internal/buildinfo"
+	"github.com/tzrikka/timpani/internal/config"
 	"github.com/tzrikka/timpani/internal/logger"
 	"github.com/tzrikka/timpani/internal/thrippy"
+	"github.com/tzrikka/timpani/pkg/api/github"
 	"github.com/tzrikka/timpani/pkg/http/client"
 	"github.com/tzrikka/timpani/pkg/http/webhooks"
+	"github.com/tzrikka/timpani/pkg/otel"
 	"github.com/tzrikka/timpani/pkg/temporal"
 	"github.com/tzrikka/xdg"
 )
@@ -25,8 +33,16 @@ const (
 	ConfigFileName = "config.toml"
 )
 
+// Roles of a Timpani process, to scale event listeners and the Temporal worker independently.
+const (
+	roleAll      = "all"
+	roleListener = "listener"
+	roleWorker   = "worker"
+)
+
 var services = []string{
 	"Bitbucket",
+	"Confluence",
 	"GitHub",
 	"Jira",
 	"Slack",
@@ -40,22 +56,22 @@ func main() {
 	}
 
 	cmd := &cli.Command{
-		Name:    "timpani",
-		Usage:   "Temporal worker that sends API calls and receives event notifications",
-		Version: bi.Main.Version,
-		Flags:   flags(),
+		Name:     "timpani",
+		Usage:    "Temporal worker that sends API calls and receives event notifications",
+		Version:  buildinfo.FromBuildInfo(bi).String(),
+		Flags:    flags(),
+		Commands: []*cli.Command{configCommand(), doctorCommand(), listenersCommand()},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			if cmd.Bool("health-check") {
 				return sendHealthzRequest(ctx, cmd.Int("webhook-port"))
 			}
 
-			initLog(cmd.Bool("dev"), cmd.Bool("pretty-log"), bi)
-			s := webhooks.NewHTTPServer(ctx, cmd)
-			go s.Run(ctx)
-			if err := s.ConnectLinks(ctx); err != nil {
+			initLog(cmd, bi)
+			if err := client.ConfigureTransport(cmd); err != nil {
 				return err
 			}
-			return temporal.Run(ctx, cmd, bi)
+			go watchConfig(ctx, cmd)
+			return run(ctx, cmd, bi)
 		},
 	}
 
@@ -66,6 +82,7 @@ func main() {
 }
 
 func flags() []cli.Flag {
+	path := configFile()
 	fs := []cli.Flag{
 		&cli.BoolFlag{
 			Name:  "dev",
@@ -73,7 +90,35 @@ func flags() []cli.Flag {
 		},
 		&cli.BoolFlag{
 			Name:  "pretty-log",
-			Usage: "human-readable console logging, instead of JSON",
+			Usage: "human-readable console logging, instead of JSON (same as --log-format=console)",
+		},
+		&cli.StringFlag{
+			Name:  "log-level",
+			Usage: "minimum log level: trace, debug, info, warn, error (default = debug with --dev, otherwise info)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_LOG_LEVEL"),
+				config.TOML("logging.level", path),
+			),
+			Validator: validateLogLevel,
+		},
+		&cli.StringFlag{
+			Name:  "log-format",
+			Usage: "log format: json, console (default = console with --dev or --pretty-log, otherwise json)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_LOG_FORMAT"),
+				config.TOML("logging.format", path),
+			),
+			Validator: validateLogFormat,
+		},
+		&cli.StringFlag{
+			Name:  "role",
+			Usage: "components to run: listener (HTTP server and event listeners), worker (Temporal worker), or all",
+			Value: roleAll,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_ROLE"),
+				config.TOML("timpani.role", path),
+			),
+			Validator: validateRole,
 		},
 		&cli.BoolFlag{
 			Name:  "health-check",
@@ -81,10 +126,11 @@ func flags() []cli.Flag {
 		},
 	}
 
-	path := configFile()
 	fs = append(fs, temporal.Flags(path)...)
 	fs = append(fs, thrippy.Flags(path)...)
 	fs = append(fs, webhooks.Flags(path)...)
+	fs = append(fs, client.Flags(path)...)
+	fs = append(fs, github.Flags(path)...)
 
 	for _, s := range services {
 		fs = append(fs, thrippy.LinkIDFlag(path, s))
@@ -108,36 +154,176 @@ func configFile() altsrc.StringSourcer {
 	return altsrc.StringSourcer(path)
 }
 
-// initLog initializes the logger for Timpani's HTTP server and Temporal
-// worker, based on whether it's running in development mode or not.
-func initLog(dev, prettyLog bool, bi *debug.BuildInfo) {
-	var handler slog.Handler
-	switch {
-	case dev: // Including dev && prettyLog.
-		handler = tint.NewHandler(os.Stdout, &tint.Options{
-			Level:      slog.LevelDebug,
-			TimeFormat: "15:04:05.000",
-			AddSource:  true,
-		})
-	case prettyLog: // But not dev.
-		handler = tint.NewHandler(os.Stdout, &tint.Options{
-			Level:      slog.LevelInfo,
-			TimeFormat: "15:04:05.000",
-			AddSource:  true,
-		})
-	default: // Production JSON log.
-		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-			Level:     slog.LevelInfo,
-			AddSource: true,
+// logLevel is the minimum level of the default logger. It may change at runtime, see [reloadConfig].
+var logLevel = new(slog.LevelVar)
+
+// initLog initializes the logger for Timpani's HTTP server and Temporal worker, based on
+// the "--log-level" and "--log-format" flags, with defaults that depend on whether it's
+// running in development mode or not.
+func initLog(cmd *cli.Command, bi *debug.BuildInfo) {
+	level, format := logSettings(cmd.Bool("dev"), cmd.Bool("pretty-log"), cmd.String("log-level"), cmd.String("log-format"))
+	logLevel.Set(level)
+
+	w := os.Stderr // Production JSON log.
+	if format == "console" {
+		w = os.Stdout
+	}
+
+	slog.SetDefault(slog.New(newLogHandler(w, logLevel, format)))
+	info := buildinfo.FromBuildInfo(bi)
+	slog.Info("build versions", slog.String("go", info.GoVersion), slog.String("main", info.Version),
+		slog.String("vcs_revision", info.Revision), slog.String("vcs_time", info.Time), slog.Bool("vcs_modified", info.Modified))
+	otel.SetBuildInfoGauge(time.Now().UTC(), info.Version, info.Revision, info.GoVersion)
+}
+
+// logSettings resolves the effective log level and format, based on CLI flags.
+// The level and format strings are expected to be either valid or empty.
+func logSettings(dev, prettyLog bool, level, format string) (slog.Level, string) {
+	if level == "" {
+		level = "info"
+		if dev {
+			level = "debug"
+		}
+	}
+
+	if format == "" {
+		format = "json"
+		if dev || prettyLog {
+			format = "console"
+		}
+	}
+
+	l, _ := parseLogLevel(level)
+	return l, format
+}
+
+// newLogHandler returns a human-readable console handler or a JSON handler. Source code
+// locations are added only if the initial level is debug or trace, because they're expensive.
+func newLogHandler(w io.Writer, level slog.Leveler, format string) slog.Handler {
+	addSource := level.Level() <= slog.LevelDebug
+	if format == "console" {
+		return tint.NewHandler(w, &tint.Options{
+			Level:       level,
+			TimeFormat:  "15:04:05.000",
+			AddSource:   addSource,
+			ReplaceAttr: replaceTraceLevel,
 		})
 	}
 
-	slog.SetDefault(slog.New(handler))
-	slog.Info("build versions", slog.String("go", bi.GoVersion), slog.String("main", bi.Main.Version))
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		AddSource:   addSource,
+		ReplaceAttr: replaceTraceLevel,
+	})
+}
+
+// replaceTraceLevel prints [logger.LevelTrace] as "TRACE" instead of "DEBUG-4".
+func replaceTraceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if l, ok := a.Value.Any().(slog.Level); ok && l == logger.LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "trace":
+		return logger.LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level: %q", s)
+	}
+}
+
+func validateLogLevel(s string) error {
+	_, err := parseLogLevel(s)
+	return err
+}
+
+func validateLogFormat(s string) error {
+	if s != "json" && s != "console" {
+		return fmt.Errorf("invalid log format: %q", s)
+	}
+	return nil
+}
+
+// Variables only to facilitate testing without starting real servers.
+var (
+	startListeners = func(ctx context.Context, cmd *cli.Command) error {
+		s := webhooks.NewHTTPServer(ctx, cmd)
+		if err := s.CheckLinkRoutes(ctx); err != nil {
+			return err
+		}
+		go s.Run(ctx)
+		if err := s.ConnectLinks(ctx); err != nil {
+			return err
+		}
+		go s.ProbeLinks(ctx)
+		return nil
+	}
+	runWorker   = temporal.Run
+	interruptCh = worker.InterruptCh
+)
+
+// run starts the HTTP server and event listeners, and/or the Temporal worker, based on
+// the "--role" flag. It blocks until the worker stops, or until a listener-only
+// process is interrupted.
+func run(ctx context.Context, cmd *cli.Command, bi *debug.BuildInfo) error {
+	role := cmd.String("role")
+	if err := checkRoleConfig(cmd, role); err != nil {
+		return err
+	}
+	slog.Info("starting Timpani", slog.String("role", role))
+
+	if role == roleAll || role == roleListener {
+		if err := startListeners(ctx, cmd); err != nil {
+			return err
+		}
+	}
+
+	if role == roleListener {
+		<-interruptCh()
+		return nil
+	}
+
+	return runWorker(ctx, cmd, bi)
+}
+
+// checkRoleConfig ensures that the settings which are required by the components
+// of the given role are not blank. Thrippy link IDs are optional in all roles.
+func checkRoleConfig(cmd *cli.Command, role string) error {
+	required := []string{"temporal-address", "temporal-namespace", "thrippy-grpc-address"}
+	if role != roleListener {
+		required = append(required, "temporal-task-queue")
+	}
+
+	for _, name := range required {
+		if cmd.String(name) == "" {
+			return fmt.Errorf("missing required setting for role %q: --%s", role, name)
+		}
+	}
+
+	return nil
+}
+
+func validateRole(s string) error {
+	if s != roleAll && s != roleListener && s != roleWorker {
+		return fmt.Errorf("invalid role: %q", s)
+	}
+	return nil
 }
 
 func sendHealthzRequest(ctx context.Context, port int) error {
 	url := fmt.Sprintf("http://localhost:%d/healthz", port)
-	_, _, _, err := client.HTTPRequest(ctx, http.MethodGet, url, "", "", "", nil)
+	_, _, _, err := client.HTTPRequest(ctx, http.MethodGet, url, "", "", "", nil, client.WithTimeout(client.Timeout))
 	return err
 }
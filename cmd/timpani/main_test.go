@@ -1,11 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/tzrikka/timpani/internal/logger"
 )
 
 func TestFlags(t *testing.T) {
@@ -79,3 +89,240 @@ func TestSendHealthzRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestLogSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		dev        bool
+		prettyLog  bool
+		level      string
+		format     string
+		wantLevel  slog.Level
+		wantFormat string
+	}{
+		{
+			name:       "production_defaults",
+			wantLevel:  slog.LevelInfo,
+			wantFormat: "json",
+		},
+		{
+			name:       "dev_defaults",
+			dev:        true,
+			wantLevel:  slog.LevelDebug,
+			wantFormat: "console",
+		},
+		{
+			name:       "pretty_log",
+			prettyLog:  true,
+			wantLevel:  slog.LevelInfo,
+			wantFormat: "console",
+		},
+		{
+			name:       "explicit_flags_override_dev",
+			dev:        true,
+			level:      "warn",
+			format:     "json",
+			wantLevel:  slog.LevelWarn,
+			wantFormat: "json",
+		},
+		{
+			name:       "trace",
+			level:      "TRACE",
+			wantLevel:  logger.LevelTrace,
+			wantFormat: "json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLevel, gotFormat := logSettings(tt.dev, tt.prettyLog, tt.level, tt.format)
+			if gotLevel != tt.wantLevel {
+				t.Errorf("logSettings() level = %v, want %v", gotLevel, tt.wantLevel)
+			}
+			if gotFormat != tt.wantFormat {
+				t.Errorf("logSettings() format = %q, want %q", gotFormat, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestNewLogHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  slog.Level
+		format string
+		want   []string
+		unwant []string
+	}{
+		{
+			name:   "json_warn",
+			level:  slog.LevelWarn,
+			format: "json",
+			want:   []string{"warn message"},
+			unwant: []string{"trace message", "debug message", "info message", `"source"`},
+		},
+		{
+			name:   "console_warn",
+			level:  slog.LevelWarn,
+			format: "console",
+			want:   []string{"warn message"},
+			unwant: []string{"trace message", "debug message", "info message"},
+		},
+		{
+			name:   "json_trace",
+			level:  logger.LevelTrace,
+			format: "json",
+			want:   []string{`"level":"TRACE"`, "trace message", "debug message", `"source"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l := slog.New(newLogHandler(buf, tt.level, tt.format))
+			l.Log(t.Context(), logger.LevelTrace, "trace message")
+			l.Debug("debug message")
+			l.Info("info message")
+			l.Warn("warn message")
+
+			got := buf.String()
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("log output doesn't contain %q: %s", s, got)
+				}
+			}
+			for _, s := range tt.unwant {
+				if strings.Contains(got, s) {
+					t.Errorf("log output contains %q: %s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateLogFlags(t *testing.T) {
+	for _, s := range []string{"trace", "debug", "info", "warn", "error", "WARN"} {
+		if err := validateLogLevel(s); err != nil {
+			t.Errorf("validateLogLevel(%q) = %v", s, err)
+		}
+	}
+	if err := validateLogLevel("verbose"); err == nil {
+		t.Error("validateLogLevel(\"verbose\") = nil, want error")
+	}
+
+	for _, s := range []string{"json", "console"} {
+		if err := validateLogFormat(s); err != nil {
+			t.Errorf("validateLogFormat(%q) = %v", s, err)
+		}
+	}
+	if err := validateLogFormat("xml"); err == nil {
+		t.Error("validateLogFormat(\"xml\") = nil, want error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         string
+		blank        string
+		listenerErr  error
+		wantListener bool
+		wantWorker   bool
+		wantErr      bool
+	}{
+		{
+			name:         "all",
+			role:         roleAll,
+			wantListener: true,
+			wantWorker:   true,
+		},
+		{
+			name:         "listener",
+			role:         roleListener,
+			wantListener: true,
+		},
+		{
+			name:       "worker",
+			role:       roleWorker,
+			wantWorker: true,
+		},
+		{
+			name:         "listener_without_task_queue",
+			role:         roleListener,
+			blank:        "temporal-task-queue",
+			wantListener: true,
+		},
+		{
+			name:    "worker_without_task_queue",
+			role:    roleWorker,
+			blank:   "temporal-task-queue",
+			wantErr: true,
+		},
+		{
+			name:    "listener_without_temporal_address",
+			role:    roleListener,
+			blank:   "temporal-address",
+			wantErr: true,
+		},
+		{
+			name:         "listener_error",
+			role:         roleAll,
+			listenerErr:  errors.New("connection error"),
+			wantListener: true,
+			wantErr:      true,
+		},
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origListeners, origWorker, origInterrupt := startListeners, runWorker, interruptCh
+	t.Cleanup(func() {
+		startListeners, runWorker, interruptCh = origListeners, origWorker, origInterrupt
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotListener, gotWorker bool
+			startListeners = func(context.Context, *cli.Command) error {
+				gotListener = true
+				return tt.listenerErr
+			}
+			runWorker = func(context.Context, *cli.Command, *debug.BuildInfo) error {
+				gotWorker = true
+				return nil
+			}
+			interruptCh = func() <-chan any {
+				ch := make(chan any)
+				close(ch)
+				return ch
+			}
+
+			cmd := &cli.Command{Flags: flags()}
+			_ = cmd.Set("role", tt.role)
+			if tt.blank != "" {
+				_ = cmd.Set(tt.blank, "")
+			}
+
+			err := run(t.Context(), cmd, &debug.BuildInfo{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotListener != tt.wantListener {
+				t.Errorf("run() started listeners = %v, want %v", gotListener, tt.wantListener)
+			}
+			if gotWorker != tt.wantWorker {
+				t.Errorf("run() started worker = %v, want %v", gotWorker, tt.wantWorker)
+			}
+		})
+	}
+}
+
+func TestValidateRole(t *testing.T) {
+	for _, s := range []string{roleAll, roleListener, roleWorker} {
+		if err := validateRole(s); err != nil {
+			t.Errorf("validateRole(%q) error = %v", s, err)
+		}
+	}
+	if err := validateRole("both"); err == nil {
+		t.Error("validateRole() should reject unknown roles")
+	}
+}
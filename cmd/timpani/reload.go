@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/tzrikka/timpani/pkg/listeners/slack"
+)
+
+// dynamicSettings maps the names of flags which can be changed at runtime, without
+// restarting the process, to functions that apply their new raw values (an empty
+// value means that the setting was removed). All other settings are structural.
+var dynamicSettings = map[string]func(cmd *cli.Command, value string) error{
+	"log-level": func(cmd *cli.Command, value string) error {
+		if value != "" {
+			if err := validateLogLevel(value); err != nil {
+				return err
+			}
+		}
+		level, _ := logSettings(cmd.Bool("dev"), cmd.Bool("pretty-log"), value, "")
+		logLevel.Set(level)
+		return nil
+	},
+	"slack-event-allowlist": func(_ *cli.Command, value string) error {
+		slack.SetEventAllowlist(strings.Split(value, ","))
+		return nil
+	},
+}
+
+// watchConfig calls [reloadConfig] whenever the process receives a SIGHUP
+// signal, until the context is canceled. Flags which were set in the command
+// line or by environment variables are never reloaded (see [pinnedFlags]).
+func watchConfig(ctx context.Context, cmd *cli.Command) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+
+	pinned := pinnedFlags(cmd, os.Args[1:])
+	current := sourceValues(cmd)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			slog.Info("reloading configuration")
+			reloadConfig(cmd, pinned, current, sourceValues(cmd))
+		}
+	}
+}
+
+// pinnedFlags returns the names of the flags which were set in the command line or by
+// environment variables. They take precedence over the configuration file, so reloading
+// it mustn't change them. urfave/cli doesn't report whether a value came from the command
+// line (it ignores the other sources in that case), so this checks the raw arguments.
+func pinnedFlags(cmd *cli.Command, args []string) map[string]bool {
+	inArgs := map[string]bool{}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if name, ok := strings.CutPrefix(arg, "-"); ok {
+			name, _, _ = strings.Cut(strings.TrimPrefix(name, "-"), "=")
+			inArgs[name] = true
+		}
+	}
+
+	pinned := map[string]bool{}
+	for _, f := range cmd.Flags {
+		name := f.Names()[0]
+		if !cmd.IsSet(name) {
+			continue
+		}
+
+		if slices.ContainsFunc(f.Names(), func(n string) bool { return inArgs[n] }) {
+			pinned[name] = true
+			continue
+		}
+
+		if vsc := flagSources(f); vsc != nil {
+			if _, vs, ok := vsc.LookupWithSource(); ok {
+				if env, ok := vs.(cli.EnvValueSource); ok && env.IsFromEnv() {
+					pinned[name] = true
+				}
+			}
+		}
+	}
+	return pinned
+}
+
+// sourceValues returns the raw values of all the flags which were set by environment
+// variables or the configuration file, keyed by flag name. The file is re-read on
+// each call, whereas environment variables can't change during the process's lifetime.
+func sourceValues(cmd *cli.Command) map[string]string {
+	vals := map[string]string{}
+	for _, f := range cmd.Flags {
+		name := f.Names()[0]
+		vsc := flagSources(f)
+		if vsc == nil {
+			continue
+		}
+		if v, ok := vsc.Lookup(); ok {
+			vals[name] = v
+		}
+	}
+	return vals
+}
+
+// reloadConfig applies changes in dynamic settings, and updates the current values
+// accordingly. Changes in structural settings are logged, but not applied.
+func reloadConfig(cmd *cli.Command, pinned map[string]bool, current, updated map[string]string) {
+	for _, f := range cmd.Flags {
+		name := f.Names()[0]
+		v, ok := updated[name]
+		if v == current[name] || pinned[name] {
+			continue // Unchanged, or set in the command line or by an environment variable.
+		}
+
+		l := slog.With(slog.String("flag", name))
+		apply, dynamic := dynamicSettings[name]
+		if !dynamic {
+			l.Warn("ignoring change in structural setting, restart required")
+			continue
+		}
+
+		if err := apply(cmd, v); err != nil {
+			l.Error("failed to apply new setting", slog.Any("error", err))
+			continue
+		}
+
+		if ok {
+			current[name] = v
+		} else {
+			delete(current, name)
+		}
+		l.Info("applied new setting", slog.String("value", redact(name, v)))
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	altsrc "github.com/urfave/cli-altsrc/v3"
+	"github.com/urfave/cli-altsrc/v3/toml"
+	"github.com/urfave/cli/v3"
+)
+
+func TestReloadConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     map[string]string
+		updated     map[string]string
+		commandLine bool
+		pinned      bool
+		initLevel   slog.Level
+		want        map[string]string
+		wantLevel   slog.Level
+	}{
+		{
+			name:      "no_changes",
+			current:   map[string]string{"log-level": "warn"},
+			updated:   map[string]string{"log-level": "warn"},
+			want:      map[string]string{"log-level": "warn"},
+			initLevel: slog.LevelWarn,
+			wantLevel: slog.LevelWarn,
+		},
+		{
+			name:      "dynamic_change",
+			current:   map[string]string{"log-level": "warn"},
+			updated:   map[string]string{"log-level": "debug"},
+			want:      map[string]string{"log-level": "debug"},
+			initLevel: slog.LevelWarn,
+			wantLevel: slog.LevelDebug,
+		},
+		{
+			name:      "dynamic_removal",
+			current:   map[string]string{"log-level": "warn"},
+			updated:   map[string]string{},
+			want:      map[string]string{},
+			initLevel: slog.LevelWarn,
+			wantLevel: slog.LevelInfo,
+		},
+		{
+			name:      "invalid_dynamic_change",
+			current:   map[string]string{"log-level": "warn"},
+			updated:   map[string]string{"log-level": "loud"},
+			want:      map[string]string{"log-level": "warn"},
+			initLevel: slog.LevelWarn,
+			wantLevel: slog.LevelWarn,
+		},
+		{
+			name:      "structural_change",
+			current:   map[string]string{"webhook-port": "1234"},
+			updated:   map[string]string{"webhook-port": "5678"},
+			want:      map[string]string{"webhook-port": "1234"},
+			initLevel: slog.LevelInfo,
+			wantLevel: slog.LevelInfo,
+		},
+		{
+			name:        "command_line_flag",
+			current:     map[string]string{},
+			updated:     map[string]string{"log-level": "debug"},
+			want:        map[string]string{},
+			initLevel:   slog.LevelError,
+			wantLevel:   slog.LevelError,
+			commandLine: true,
+			pinned:      true,
+		},
+		{
+			name:        "command_line_flag_also_in_file",
+			current:     map[string]string{"log-level": "warn"},
+			updated:     map[string]string{"log-level": "debug"},
+			want:        map[string]string{"log-level": "warn"},
+			initLevel:   slog.LevelError,
+			wantLevel:   slog.LevelError,
+			commandLine: true,
+			pinned:      true,
+		},
+		{
+			name:      "env_var",
+			current:   map[string]string{"log-level": "error"},
+			updated:   map[string]string{"log-level": "debug"},
+			want:      map[string]string{"log-level": "error"},
+			initLevel: slog.LevelError,
+			wantLevel: slog.LevelError,
+			pinned:    true,
+		},
+	}
+
+	t.Cleanup(func() { logLevel.Set(slog.LevelInfo) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cli.Command{Flags: []cli.Flag{
+				&cli.BoolFlag{Name: "dev"},
+				&cli.BoolFlag{Name: "pretty-log"},
+				&cli.StringFlag{Name: "log-level"},
+				&cli.IntFlag{Name: "webhook-port"},
+			}}
+			if tt.commandLine {
+				_ = cmd.Set("log-level", "error")
+			}
+
+			logLevel.Set(tt.initLevel)
+			current := maps.Clone(tt.current)
+			reloadConfig(cmd, map[string]bool{"log-level": tt.pinned}, current, tt.updated)
+
+			if !maps.Equal(current, tt.want) {
+				t.Errorf("reloadConfig() current = %v, want %v", current, tt.want)
+			}
+			if got := logLevel.Level(); got != tt.wantLevel {
+				t.Errorf("reloadConfig() log level = %v, want %v", got, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestPinnedFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		toml string
+		want map[string]bool
+	}{
+		{
+			name: "default",
+			want: map[string]bool{},
+		},
+		{
+			name: "file",
+			toml: `level = "warn"`,
+			want: map[string]bool{},
+		},
+		{
+			name: "command_line",
+			args: []string{"--log-level", "debug"},
+			want: map[string]bool{"log-level": true},
+		},
+		{
+			name: "command_line_with_equals_and_file",
+			args: []string{"-log-level=warn"},
+			toml: `level = "warn"`,
+			want: map[string]bool{"log-level": true},
+		},
+		{
+			name: "env_var_and_file",
+			env:  "debug",
+			toml: `level = "warn"`,
+			want: map[string]bool{"log-level": true},
+		},
+		{
+			name: "after_terminator",
+			args: []string{"--", "--log-level"},
+			want: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(path, []byte(tt.toml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.env != "" {
+				t.Setenv("TIMPANI_TEST_LOG_LEVEL", tt.env)
+			}
+
+			var got map[string]bool
+			cmd := &cli.Command{
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name: "log-level",
+						Sources: cli.NewValueSourceChain(
+							cli.EnvVar("TIMPANI_TEST_LOG_LEVEL"),
+							toml.TOML("level", altsrc.StringSourcer(path)),
+						),
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					got = pinnedFlags(cmd, tt.args)
+					return nil
+				},
+			}
+			if err := cmd.Run(t.Context(), append([]string{"timpani"}, tt.args...)); err != nil {
+				t.Fatalf("Command.Run() error = %v", err)
+			}
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("pinnedFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
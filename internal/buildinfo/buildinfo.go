@@ -0,0 +1,65 @@
+// Package buildinfo extracts version control and toolchain details
+// from the information that is embedded in the running binary.
+package buildinfo
+
+import (
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// Info describes the build of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information of the running binary. It's computed only once.
+var Get = sync.OnceValue(func() Info {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return Info{Version: "unknown"}
+	}
+	return FromBuildInfo(bi)
+})
+
+// FromBuildInfo converts the given [debug.BuildInfo] into an [Info],
+// based on the "vcs.*" settings which are embedded by "go build".
+func FromBuildInfo(bi *debug.BuildInfo) Info {
+	info := Info{Version: bi.Main.Version, GoVersion: bi.GoVersion}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified, _ = strconv.ParseBool(s.Value)
+		}
+	}
+	return info
+}
+
+// String returns a short human-readable description of the build,
+// e.g. "v1.2.3 (commit 0123456789ab, 2026-01-02T03:04:05Z)".
+func (i Info) String() string {
+	if i.Revision == "" {
+		return i.Version
+	}
+
+	rev := i.Revision
+	if len(rev) > 12 {
+		rev = rev[:12]
+	}
+	if i.Modified {
+		rev += "-dirty"
+	}
+	if i.Time != "" {
+		rev += ", " + i.Time
+	}
+
+	return i.Version + " (commit " + rev + ")"
+}
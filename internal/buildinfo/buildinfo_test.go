@@ -0,0 +1,64 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.26.1",
+		Main:      debug.Module{Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "-trimpath", Value: "true"},
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "0123456789abcdef0123456789abcdef01234567"},
+			{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	got := FromBuildInfo(bi)
+	want := Info{
+		Version:   "v1.2.3",
+		Revision:  "0123456789abcdef0123456789abcdef01234567",
+		Time:      "2026-01-02T03:04:05Z",
+		Modified:  true,
+		GoVersion: "go1.26.1",
+	}
+	if got != want {
+		t.Errorf("FromBuildInfo() = %+v, want %+v", got, want)
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{
+			name: "no_vcs",
+			info: Info{Version: "(devel)"},
+			want: "(devel)",
+		},
+		{
+			name: "clean",
+			info: Info{Version: "v1.2.3", Revision: "0123456789abcdef", Time: "2026-01-02T03:04:05Z"},
+			want: "v1.2.3 (commit 0123456789ab, 2026-01-02T03:04:05Z)",
+		},
+		{
+			name: "modified",
+			info: Info{Version: "v1.2.3", Revision: "0123456789abcdef", Modified: true},
+			want: "v1.2.3 (commit 0123456789ab-dirty)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.String(); got != tt.want {
+				t.Errorf("Info.String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
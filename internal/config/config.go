@@ -0,0 +1,34 @@
+// Package config records the TOML keys which are mapped to CLI flags, where the
+// flags are defined, so that the configuration file can be checked for unknown keys.
+package config
+
+import (
+	"maps"
+	"sync"
+
+	altsrc "github.com/urfave/cli-altsrc/v3"
+	"github.com/urfave/cli-altsrc/v3/toml"
+)
+
+var (
+	keys   = map[string]bool{}
+	keysMu sync.RWMutex
+)
+
+// TOML is a wrapper of [toml.TOML], which also records the given key (see [Keys]).
+// Use it instead of [toml.TOML] in all the CLI flags of Timpani.
+func TOML(key string, source altsrc.Sourcer) *altsrc.ValueSource {
+	keysMu.Lock()
+	keys[key] = true
+	keysMu.Unlock()
+
+	return toml.TOML(key, source)
+}
+
+// Keys returns all the TOML keys which were passed to [TOML] so far.
+func Keys() map[string]bool {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	return maps.Clone(keys)
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	altsrc "github.com/urfave/cli-altsrc/v3"
+)
+
+func TestTOML(t *testing.T) {
+	src := altsrc.StringSourcer("/path/to/config.toml")
+	_ = TOML("foo.bar", src)
+	_ = TOML("foo.baz.qux", src)
+
+	keys := Keys()
+	for _, k := range []string{"foo.bar", "foo.baz.qux"} {
+		if !keys[k] {
+			t.Errorf("Keys() doesn't contain %q", k)
+		}
+	}
+	if keys["foo"] {
+		t.Error("Keys() contains section name \"foo\"")
+	}
+
+	keys["other"] = true
+	if Keys()["other"] {
+		t.Error("Keys() returned the internal map, not a copy")
+	}
+}
@@ -0,0 +1,115 @@
+// Package idempotency prevents retried Temporal activities from repeating
+// non-idempotent API calls (e.g. posting a message) which already succeeded.
+//
+// None of the API providers that Timpani supports has native idempotency keys,
+// so this is a local best-effort mechanism, with at-most-once caveats:
+//   - It can't help if an API call succeeded but its response was lost (e.g.
+//     a client-side timeout), because there is no response to record
+//   - Successful responses are recorded only in the memory of the worker that
+//     received them, so a retry on another worker (or after a restart) repeats
+//     the API call
+//   - Old responses are evicted when the cache is full, after [DefaultSize]
+//     more recent ones
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// DefaultSize is the maximum number of responses in the worker's cache.
+const DefaultSize = 1024
+
+var cache = NewCache(DefaultSize)
+
+// Cache is a concurrency-safe LRU cache of successful API responses.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // Front = most recently used.
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key   string
+	value any
+}
+
+// NewCache initializes a [Cache] with the given maximum number of entries.
+func NewCache(size int) *Cache {
+	return &Cache{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the cached value of the given key, if there is one.
+func (c *Cache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return e.Value.(*entry).value, true
+}
+
+// Put adds or replaces the value of the given key, and evicts
+// the least recently used entry if the cache exceeds its size.
+func (c *Cache) Put(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.Value.(*entry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
+
+// Len returns the current number of entries in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Do calls f and records its successful response, unless the idempotency key is empty.
+// In retry attempts of the same activity with the same key, it returns the recorded
+// response instead of calling f again. See the package's documentation for caveats.
+func Do[T any](ctx context.Context, activityName, key string, f func() (T, error)) (T, error) {
+	return do(ctx, cache, activityName, key, f)
+}
+
+func do[T any](ctx context.Context, c *Cache, activityName, key string, f func() (T, error)) (T, error) {
+	if key == "" {
+		return f()
+	}
+
+	cacheKey := activityName + "/" + key
+	if activity.GetInfo(ctx).Attempt > 1 {
+		if v, ok := c.Get(cacheKey); ok {
+			activity.GetLogger(ctx).Info("skipping retried API call with recorded response",
+				slog.String("activity_name", activityName), slog.String("idempotency_key", key))
+			return v.(T), nil
+		}
+	}
+
+	resp, err := f()
+	if err == nil {
+		c.Put(cacheKey, resp)
+	}
+	return resp, err
+}
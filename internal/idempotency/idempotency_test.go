@@ -0,0 +1,110 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a") // "b" is now the least recently used.
+	c.Put("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) = found, want evicted")
+	}
+	for k, want := range map[string]int{"a": 1, "c": 3} {
+		if got, ok := c.Get(k); !ok || got != want {
+			t.Errorf("Get(%s) = %v, %v, want %d", k, got, ok, want)
+		}
+	}
+
+	c.Put("a", 4)
+	if got, _ := c.Get("a"); got != 4 {
+		t.Errorf("Get(a) = %v, want 4", got)
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+// TestDoRetryAfterSuccess simulates an activity whose API call succeeded,
+// but the activity itself failed afterwards, and then Temporal retried it.
+func TestDoRetryAfterSuccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		wantCalls int
+	}{
+		{
+			name:      "without_key",
+			wantCalls: 2,
+		},
+		{
+			name:      "with_key",
+			key:       "key",
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache(DefaultSize)
+			calls := 0
+
+			act := func(ctx context.Context) (string, error) {
+				resp, err := do(ctx, c, "test.activity", tt.key, func() (string, error) {
+					calls++
+					return fmt.Sprintf("response %d", calls), nil
+				})
+				if err != nil {
+					return "", err
+				}
+				if activity.GetInfo(ctx).Attempt == 1 {
+					return "", errors.New("lost activity completion")
+				}
+				return resp, nil
+			}
+
+			wf := func(ctx workflow.Context) (string, error) {
+				ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+					StartToCloseTimeout: time.Minute,
+					RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 2},
+				})
+				var resp string
+				err := workflow.ExecuteActivity(ctx, "act").Get(ctx, &resp)
+				return resp, err
+			}
+
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(act, activity.RegisterOptions{Name: "act"})
+			env.RegisterWorkflow(wf)
+			env.ExecuteWorkflow(wf)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("workflow error = %v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("API calls = %d, want %d", calls, tt.wantCalls)
+			}
+
+			var got string
+			if err := env.GetWorkflowResult(&got); err != nil {
+				t.Fatal(err)
+			}
+			if want := "response 1"; tt.key != "" && got != want {
+				t.Errorf("activity result = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package listeners
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// ReportPanic logs a recovered panic with a stack trace, and counts it as a metric.
+// It should be called by deferred functions right after [recover], which stops the
+// panic only when it's called directly by the deferred function, e.g.:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			listeners.ReportPanic(l, "component name", r)
+//		}
+//	}()
+func ReportPanic(l *slog.Logger, component string, r any) {
+	l.Error("recovered from panic", slog.String("component", component),
+		slog.Any("panic", r), slog.String("stack", string(debug.Stack())))
+	otel.IncrementPanicCounter(time.Now().UTC(), component, fmt.Sprint(r))
+}
@@ -0,0 +1,32 @@
+package listeners
+
+import (
+	"sync"
+)
+
+// pendingResponses maps the IDs of synchronous webhook responses which are
+// supplied by workflows to the channels of the handlers that wait for them.
+var pendingResponses sync.Map
+
+// AwaitResponse registers a webhook handler which is waiting for a response body with
+// the given ID, to be supplied by a workflow via [DeliverResponse]. The handler must
+// call the returned function when it stops waiting, even if it received a response.
+func AwaitResponse(id string) (<-chan map[string]any, func()) {
+	ch := make(chan map[string]any, 1)
+	pendingResponses.Store(id, ch)
+	return ch, func() { pendingResponses.Delete(id) }
+}
+
+// DeliverResponse hands a response body to the webhook handler which is waiting for it.
+// It returns false if there is no such handler in this process: it already stopped
+// waiting, received another response, or never existed (e.g. if it's running in a
+// different Timpani process than the worker which calls this function).
+func DeliverResponse(id string, body map[string]any) bool {
+	v, ok := pendingResponses.LoadAndDelete(id)
+	if !ok {
+		return false
+	}
+
+	v.(chan map[string]any) <- body //nolint:errcheck // Type conversion always succeeds.
+	return true
+}
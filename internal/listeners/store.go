@@ -0,0 +1,87 @@
+package listeners
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store is a small key-value store with expiring keys, for state that all the
+// replicas of the webhook server must share (e.g. duplicate suppression).
+// The default implementation ([MemoryStore]) is suitable only for a single replica.
+type Store interface {
+	// GetSet sets the value of the given key, with the given TTL, and returns its
+	// previous value, and whether it existed (and didn't expire yet) before this call.
+	GetSet(ctx context.Context, key, value string, ttl time.Duration) (string, bool, error)
+	// Incr increments the integer value of the given key, and returns the new value.
+	// If the key doesn't exist (or expired), it's initialized to 1 with the given TTL,
+	// which isn't extended by subsequent increments (i.e. a fixed window).
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// MemoryStore is the default [Store], which is
+// local to the memory of a single process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time // Only to facilitate testing.
+}
+
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemoryStore initializes an empty [MemoryStore].
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}, now: time.Now}
+}
+
+// GetSet implements [Store.GetSet]. It never returns an error.
+func (s *MemoryStore) GetSet(_ context.Context, key, value string, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.evictExpired(now)
+
+	prev, found := s.entries[key]
+	s.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return prev.value, found, nil
+}
+
+// Incr implements [Store.Incr]. It returns an error only
+// if the current value of the key is not an integer.
+func (s *MemoryStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.evictExpired(now)
+
+	e, found := s.entries[key]
+	if !found {
+		s.entries[key] = memoryEntry{value: "1", expires: now.Add(ttl)}
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(e.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	n++
+	e.value = strconv.FormatInt(n, 10)
+	s.entries[key] = e
+	return n, nil
+}
+
+// evictExpired keeps the store small. It must be called while holding the mutex.
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for k, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package listeners
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testStores returns all the [Store] implementations, with a function
+// for each one that advances its clock, to test key expiration.
+func testStores(t *testing.T) map[string]struct {
+	store   Store
+	advance func(time.Duration)
+} {
+	t.Helper()
+
+	mem := NewMemoryStore()
+	now := time.Now()
+	mem.now = func() time.Time { return now }
+
+	mr := miniredis.RunT(t)
+	rs, err := NewRedisStore("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rs.Close() })
+
+	return map[string]struct {
+		store   Store
+		advance func(time.Duration)
+	}{
+		"memory": {store: mem, advance: func(d time.Duration) { now = now.Add(d) }},
+		"redis":  {store: rs, advance: mr.FastForward},
+	}
+}
+
+func TestStoreGetSet(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			if _, found, err := s.store.GetSet(ctx, "k", "v1", time.Minute); err != nil || found {
+				t.Fatalf("GetSet() first = found %v, error %v", found, err)
+			}
+
+			prev, found, err := s.store.GetSet(ctx, "k", "v2", time.Minute)
+			if err != nil || !found || prev != "v1" {
+				t.Fatalf("GetSet() second = %q, %v, %v, want %q, true, nil", prev, found, err, "v1")
+			}
+
+			s.advance(2 * time.Minute)
+			if _, found, err := s.store.GetSet(ctx, "k", "v3", time.Minute); err != nil || found {
+				t.Errorf("GetSet() after TTL = found %v, error %v", found, err)
+			}
+		})
+	}
+}
+
+func TestStoreIncr(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			for want := int64(1); want <= 3; want++ {
+				if got, err := s.store.Incr(ctx, "counter", time.Minute); err != nil || got != want {
+					t.Fatalf("Incr() = %d, %v, want %d", got, err, want)
+				}
+				s.advance(10 * time.Second) // Doesn't extend the TTL.
+			}
+
+			s.advance(time.Minute)
+			if got, err := s.store.Incr(ctx, "counter", time.Minute); err != nil || got != 1 {
+				t.Errorf("Incr() after TTL = %d, %v, want 1", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package listeners
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Timing is a breakdown of the time it takes to handle a single webhook request,
+// to diagnose which phase is slow when a handler exceeds its latency SLO. Its
+// methods are no-ops for nil receivers, so handlers can call them unconditionally.
+type Timing struct {
+	LinkFetch    time.Duration // Thrippy link data.
+	Verification time.Duration // [WebhookCheck]s, e.g. signature verification.
+	Dispatch     time.Duration // Temporal signals.
+}
+
+// Verified adds the time since the given start of a verification phase.
+func (t *Timing) Verified(start time.Time) {
+	if t != nil {
+		t.Verification += time.Since(start)
+	}
+}
+
+// Dispatched adds the time since the given start of a dispatch phase.
+func (t *Timing) Dispatched(start time.Time) {
+	if t != nil {
+		t.Dispatch += time.Since(start)
+	}
+}
+
+// LogValue implements the [slog.LogValuer] interface, to log all the phases as a group.
+func (t *Timing) LogValue() slog.Value {
+	if t == nil {
+		return slog.GroupValue()
+	}
+	return slog.GroupValue(
+		slog.Duration("link_fetch", t.LinkFetch),
+		slog.Duration("verification", t.Verification),
+		slog.Duration("dispatch", t.Dispatch),
+	)
+}
+
+// Verify runs the checks of a webhook handler in order, and returns the error status code
+// of the first one that fails, or [http.StatusOK]. It also records their duration in
+// [RequestData.Timing].
+func (v WebhookVerifier) Verify(l *slog.Logger, r RequestData) int {
+	defer r.Timing.Verified(time.Now())
+
+	for _, c := range v.Checks {
+		if statusCode := c.Check(l, r); statusCode != http.StatusOK {
+			return statusCode
+		}
+	}
+	return http.StatusOK
+}
@@ -6,36 +6,151 @@ package listeners
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 type TemporalConfig struct {
 	HostPort  string
 	Namespace string
 	TaskQueue string
+
+	CompressSignals bool
+	MaxSignalSize   int // Bytes (0 = default).
+
+	// LinkRoutes overrides the namespace and task queue of specific Thrippy
+	// link IDs, to isolate tenants from each other, see [TemporalConfig.ForLink].
+	LinkRoutes map[string]LinkRoute
+}
+
+// LinkRoute is a per-link override of [TemporalConfig.Namespace] and [TemporalConfig.TaskQueue].
+type LinkRoute struct {
+	Namespace string
+	TaskQueue string // Optional (empty = the default task queue).
+}
+
+// ForLink returns the configuration for a specific Thrippy link: with the namespace and task
+// queue of its [LinkRoute], if it has one. It doesn't contain the routes of any other link.
+func (c TemporalConfig) ForLink(linkID string) TemporalConfig {
+	r, ok := c.LinkRoutes[linkID]
+	c.LinkRoutes = nil
+	if !ok {
+		return c
+	}
+
+	c.Namespace = r.Namespace
+	if r.TaskQueue != "" {
+		c.TaskQueue = r.TaskQueue
+	}
+	return c
+}
+
+// ParseLinkRoutes parses "<link ID>=<namespace>[/<task queue>]" entries into [LinkRoute]s.
+func ParseLinkRoutes(entries []string) (map[string]LinkRoute, error) {
+	routes := map[string]LinkRoute{}
+	var errs []error
+	for _, e := range entries {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+
+		id, dest, ok := strings.Cut(e, "=")
+		id, dest = strings.TrimSpace(id), strings.TrimSpace(dest)
+		ns, tq, _ := strings.Cut(dest, "/")
+		if !ok || id == "" || ns == "" || strings.HasSuffix(dest, "/") {
+			errs = append(errs, fmt.Errorf("invalid link route %q: expected <link ID>=<namespace>[/<task queue>]", e))
+			continue
+		}
+		if _, ok := routes[id]; ok {
+			errs = append(errs, fmt.Errorf("duplicate route for link %q", id))
+			continue
+		}
+
+		routes[id] = LinkRoute{Namespace: ns, TaskQueue: tq}
+	}
+
+	if len(routes) == 0 {
+		routes = nil
+	}
+	return routes, errors.Join(errs...)
 }
 
 type RequestData struct {
+	LinkID      string
 	PathSuffix  string
 	Headers     http.Header
-	WebForm     url.Values
 	RawPayload  []byte
-	JSONPayload map[string]any
 	LinkSecrets map[string]string
 	Temporal    TemporalConfig
+	Timing      *Timing // Optional.
+}
+
+// DecodeJSON decodes the raw payload of a request with a JSON content type, and returns
+// nil for other content types. Handlers should call it only after verifying the request's
+// signature, so that unauthenticated requests can't make us spend resources on parsing.
+func (r RequestData) DecodeJSON() (map[string]any, error) {
+	if len(r.RawPayload) == 0 || !strings.HasPrefix(r.Headers.Get("Content-Type"), "application/json") {
+		return nil, nil
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(r.RawPayload, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// DecodeForm parses the raw payload of a request with a web form content type, and returns
+// nil for other content types. Handlers should call it only after verifying the request's
+// signature, so that unauthenticated requests can't make us spend resources on parsing.
+func (r RequestData) DecodeForm() (url.Values, error) {
+	if len(r.RawPayload) == 0 || !strings.HasPrefix(r.Headers.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return nil, nil
+	}
+
+	return url.ParseQuery(string(r.RawPayload))
 }
 
 type LinkData struct {
 	ID       string
 	Template string
 	Secrets  map[string]string
+
+	Connections int   // Number of redundant stateful connections (0 = 1).
+	Store       Store // State shared by all the replicas (nil = local memory).
+
+	// OnConnFailure is an optional callback for stateful connections which fail
+	// permanently (e.g. because of revoked credentials). It's called at most once.
+	OnConnFailure func(err error)
 }
 
 type WebhookHandlerFunc func(ctx context.Context, w http.ResponseWriter, r RequestData) int
 
 type ConnHandlerFunc func(ctx context.Context, tc TemporalConfig, data LinkData) error
 
+// WebhookCheck is a single verification step of a webhook handler (e.g. the request's
+// signature), which returns [http.StatusOK] or the error status code of the handler.
+type WebhookCheck struct {
+	Name  string
+	Check func(l *slog.Logger, r RequestData) int
+}
+
+// WebhookVerifier exposes the verification steps of a webhook handler, so they
+// can also be run outside of an HTTP server, e.g. against a captured delivery.
+type WebhookVerifier struct {
+	SecretName string // Key in [RequestData.LinkSecrets].
+	Checks     []WebhookCheck
+
+	// Sign returns the headers which authenticate the given payload, to generate test deliveries.
+	Sign func(secret string, body []byte, t time.Time) http.Header
+}
+
 const (
 	WaitForEventWorkflow = "timpani.waitForEvent"
 )
@@ -43,4 +158,42 @@ const (
 type WaitForEventRequest struct {
 	Signal  string `json:"signal"`
 	Timeout string `json:"timeout,omitempty"`
+
+	// Deadline is an optional alternative to Timeout (they are mutually exclusive):
+	// an absolute time in RFC 3339 format (e.g. "2026-10-16T17:00:00Z"), which unlike
+	// a duration doesn't drift when the caller replays or continues as new.
+	Deadline string `json:"deadline,omitempty"`
+
+	// Filter is optional: if it's not empty, the workflow ignores signals whose payload
+	// doesn't contain all of its keys and values (e.g. "timpani_link_id"). Keys of nested
+	// values are dot-separated paths (e.g. "check_suite.head_sha").
+	Filter map[string]string `json:"filter,omitempty"`
+}
+
+// Matches checks whether the given signal payload satisfies the request's [WaitForEventRequest.Filter].
+func (r WaitForEventRequest) Matches(payload map[string]any) bool {
+	for k, want := range r.Filter {
+		v, ok := lookup(payload, k)
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup finds a value in a JSON map, by its top-level key or by a dot-separated path of keys.
+func lookup(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+
+	k, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := m[k].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookup(nested, rest)
 }
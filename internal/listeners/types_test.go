@@ -0,0 +1,118 @@
+package listeners
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLinkRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]LinkRoute
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "namespace_only",
+			entries: []string{"link1=team-a"},
+			want:    map[string]LinkRoute{"link1": {Namespace: "team-a"}},
+		},
+		{
+			name:    "namespace_and_task_queue",
+			entries: []string{" link1 = team-a/events ", "link2=team-b"},
+			want: map[string]LinkRoute{
+				"link1": {Namespace: "team-a", TaskQueue: "events"},
+				"link2": {Namespace: "team-b"},
+			},
+		},
+		{
+			name:    "missing_namespace",
+			entries: []string{"link1=/events"},
+			wantErr: true,
+		},
+		{
+			name:    "missing_task_queue",
+			entries: []string{"link1=team-a/"},
+			wantErr: true,
+		},
+		{
+			name:    "missing_link_id",
+			entries: []string{"=team-a"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate_link_id",
+			entries: []string{"link1=team-a", "link1=team-b"},
+			want:    map[string]LinkRoute{"link1": {Namespace: "team-a"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLinkRoutes(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLinkRoutes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLinkRoutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporalConfigForLink(t *testing.T) {
+	cfg := TemporalConfig{
+		HostPort:  "localhost:7233",
+		Namespace: "default",
+		TaskQueue: "timpani",
+		LinkRoutes: map[string]LinkRoute{
+			"link1": {Namespace: "team-a", TaskQueue: "events"},
+			"link2": {Namespace: "team-b"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		linkID        string
+		wantNamespace string
+		wantTaskQueue string
+	}{
+		{
+			name:          "default",
+			linkID:        "link3",
+			wantNamespace: "default",
+			wantTaskQueue: "timpani",
+		},
+		{
+			name:          "namespace_and_task_queue",
+			linkID:        "link1",
+			wantNamespace: "team-a",
+			wantTaskQueue: "events",
+		},
+		{
+			name:          "namespace_only",
+			linkID:        "link2",
+			wantNamespace: "team-b",
+			wantTaskQueue: "timpani",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.ForLink(tt.linkID)
+			if got.Namespace != tt.wantNamespace || got.TaskQueue != tt.wantTaskQueue {
+				t.Errorf("ForLink() = %s/%s, want %s/%s", got.Namespace, got.TaskQueue, tt.wantNamespace, tt.wantTaskQueue)
+			}
+			if got.HostPort != cfg.HostPort {
+				t.Errorf("ForLink() host = %q, want %q", got.HostPort, cfg.HostPort)
+			}
+			if got.LinkRoutes != nil {
+				t.Errorf("ForLink() link routes = %v, want nil", got.LinkRoutes)
+			}
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"time"
 )
 
+// LevelTrace is more verbose than [slog.LevelDebug], e.g. for every single WebSocket frame.
+const LevelTrace = slog.LevelDebug - 4
+
 type ctxKey struct{}
 
 var ctxLoggerKey = ctxKey{}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := slog.New(slog.NewJSONHandler(buf, nil)).With(slog.String("link_id", "link ID"))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]string
+	}{
+		{
+			name: "attached_logger",
+			ctx:  WithContext(t.Context(), l),
+			want: map[string]string{"link_id": "link ID"},
+		},
+		{
+			name: "derived_logger",
+			ctx:  WithContext(t.Context(), l.With(slog.String("link_type", "github"))),
+			want: map[string]string{"link_id": "link ID", "link_type": "github"},
+		},
+		{
+			name: "derived_context",
+			ctx:  derivedContext(t, WithContext(t.Context(), l)),
+			want: map[string]string{"link_id": "link ID"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			FromContext(tt.ctx).Info("test", slog.String("foo", "bar"))
+
+			got := map[string]any{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+			}
+			tt.want["foo"] = "bar"
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("FromContext() log field %q = %v, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// derivedContext simulates a typical handoff of a context (e.g.
+// with a timeout) from one package to another, before logging.
+func derivedContext(t *testing.T, ctx context.Context) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	return ctx
+}
@@ -0,0 +1,159 @@
+// Package redact removes secrets from URLs, HTTP headers, JSON values, and errors, before they are logged.
+package redact
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Placeholder replaces the values of sensitive query parameters and headers.
+const Placeholder = "REDACTED"
+
+// sensitiveQueryKeys are query parameter names whose values must never be logged
+// (e.g. Slack Socket Mode URLs contain a "ticket" parameter), in addition to names
+// that contain any of the [sensitiveSubstrings].
+var sensitiveQueryKeys = map[string]bool{
+	"code":      true,
+	"key":       true,
+	"sig":       true,
+	"signature": true,
+	"ticket":    true,
+}
+
+// sensitiveHeaders are HTTP header names whose values must never be logged,
+// in addition to names that contain any of the [sensitiveSubstrings].
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+}
+
+var sensitiveSubstrings = []string{"password", "secret", "signature", "token", "api-key", "api_key"}
+
+// URL returns the given URL with its password and the values
+// of sensitive query parameters replaced by a [Placeholder].
+// If the URL cannot be parsed, its entire query is replaced.
+func URL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		if before, _, found := strings.Cut(s, "?"); found {
+			return before + "?" + Placeholder
+		}
+		return s
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = Query(u.RawQuery)
+	}
+
+	return u.Redacted()
+}
+
+// Query returns the given URL-encoded query, with the values of sensitive
+// parameters replaced by a [Placeholder]. If the query cannot be parsed,
+// it is replaced entirely.
+func Query(raw string) string {
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return Placeholder
+	}
+
+	for k := range q {
+		if sensitiveQueryKeys[strings.ToLower(k)] || sensitive(k) {
+			q[k] = []string{Placeholder}
+		}
+	}
+	return q.Encode()
+}
+
+// Header returns a copy of the given HTTP headers, with
+// the values of sensitive ones replaced by a [Placeholder].
+func Header(h http.Header) http.Header {
+	c := h.Clone()
+	for k := range c {
+		if SensitiveHeader(k) {
+			c[k] = []string{Placeholder}
+		}
+	}
+	return c
+}
+
+// SensitiveHeader checks whether the given HTTP header name may contain credentials.
+func SensitiveHeader(name string) bool {
+	return sensitiveHeaders[http.CanonicalHeaderKey(name)] || sensitive(name)
+}
+
+// JSON returns a copy of the given decoded JSON value (see [encoding/json.Unmarshal]),
+// with the values of sensitive object fields replaced by a [Placeholder], at any depth.
+func JSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			if sensitive(k) {
+				m[k] = Placeholder
+			} else {
+				m[k] = JSON(e)
+			}
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = JSON(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// Error returns an error whose message doesn't contain the sensitive parts of the URL
+// in the given error's chain (see [URL]), if there is one. The original error is never
+// exposed, because callers (e.g. Temporal's failure converter) may serialize every error
+// in the chain: a [*url.Error] is replaced by a copy with a redacted URL, and any other
+// error is replaced by one which doesn't unwrap, but still supports [errors.Is].
+func Error(err error) error {
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		return err
+	}
+
+	r := URL(ue.URL)
+	if r == ue.URL {
+		return err
+	}
+
+	if err == ue { //nolint:errorlint // Checking identity, not equivalence.
+		return &url.Error{Op: ue.Op, URL: r, Err: ue.Err}
+	}
+
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), ue.URL, r), err: err}
+}
+
+// redactedError hides the original error, to prevent the exposure of its message.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveSubstrings {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
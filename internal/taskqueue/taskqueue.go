@@ -0,0 +1,38 @@
+// Package taskqueue routes the activities of each third-party service to an optional
+// dedicated Temporal task queue, so that a service outage which makes its activities
+// hang can't exhaust the worker slots of other services. Workflows and signals always
+// use the main task queue of the Timpani worker.
+package taskqueue
+
+import (
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/workflow"
+)
+
+// MainFlag is the name of the CLI flag which configures the main task queue.
+const MainFlag = "temporal-task-queue"
+
+// Flag returns the name of the CLI flag which configures
+// the dedicated task queue of the given service (e.g. "slack").
+func Flag(service string) string {
+	return MainFlag + "-" + service
+}
+
+// Service returns the task queue of the given service's activities: its
+// dedicated one, if configured, or else the main task queue of the worker.
+func Service(cmd *cli.Command, service string) string {
+	if q := cmd.String(Flag(service)); q != "" {
+		return q
+	}
+	return cmd.String(MainFlag)
+}
+
+// Activities returns the task queue on which a Timpani workflow should schedule the
+// activities of its own service: the given one (see [Service]), or the workflow's
+// task queue if it's empty (e.g. in unit tests).
+func Activities(ctx workflow.Context, queue string) string {
+	if queue != "" {
+		return queue
+	}
+	return workflow.GetInfo(ctx).TaskQueueName
+}
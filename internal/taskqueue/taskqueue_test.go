@@ -0,0 +1,39 @@
+package taskqueue
+
+import (
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+func TestService(t *testing.T) {
+	tests := []struct {
+		name      string
+		dedicated string
+		want      string
+	}{
+		{
+			name: "main_queue",
+			want: "timpani",
+		},
+		{
+			name:      "dedicated_queue",
+			dedicated: "timpani-slack",
+			want:      "timpani-slack",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cli.Command{Flags: []cli.Flag{
+				&cli.StringFlag{Name: MainFlag, Value: "timpani"},
+				&cli.StringFlag{Name: Flag("slack")},
+			}}
+			_ = cmd.Set(Flag("slack"), tt.dedicated)
+
+			if got := Service(cmd, "slack"); got != tt.want {
+				t.Errorf("Service() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package thrippy
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
@@ -14,26 +15,74 @@ import (
 	"go.temporal.io/sdk/activity"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 
 	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/logger"
 )
 
-const (
-	timeout = 3 * time.Second
-)
+// retryPolicy is a gRPC service config which retries transient errors
+// (e.g. server restarts and network blips) with exponential backoff. See:
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md
+var retryPolicy = fmt.Sprintf(`{"methodConfig": [{
+	"name": [{"service": %q}],
+	"retryPolicy": {
+		"maxAttempts": 4,
+		"initialBackoff": "0.1s",
+		"maxBackoff": "1s",
+		"backoffMultiplier": 2,
+		"retryableStatusCodes": ["UNAVAILABLE", "DEADLINE_EXCEEDED"]
+	}
+}]}`, thrippypb.ThrippyService_ServiceDesc.ServiceName)
 
 type LinkClient struct {
-	LinkID   string
-	grpcAddr string
-	creds    credentials.TransportCredentials
+	LinkID  string
+	conn    *grpc.ClientConn
+	timeout time.Duration
 }
 
 func NewLinkClient(ctx context.Context, id string, cmd *cli.Command) LinkClient {
 	return LinkClient{
-		LinkID:   id,
-		grpcAddr: cmd.String("thrippy-grpc-address"),
-		creds:    SecureCreds(ctx, cmd),
+		LinkID:  id,
+		conn:    Conn(ctx, cmd),
+		timeout: cmd.Duration("thrippy-grpc-timeout"),
+	}
+}
+
+// Conn initializes a reusable gRPC client connection to the Thrippy service,
+// based on CLI flags. Errors abort the application with a log message.
+func Conn(ctx context.Context, cmd *cli.Command) *grpc.ClientConn {
+	addr := cmd.String("thrippy-grpc-address")
+	conn, err := NewConn(addr, SecureCreds(ctx, cmd), cmd.Duration("thrippy-grpc-keepalive"))
+	if err != nil {
+		logger.FatalErrorContext(ctx, "failed to initialize Thrippy gRPC client", err, slog.String("grpc_addr", addr))
+	}
+	return conn
+}
+
+// NewConn initializes a reusable gRPC client connection to the Thrippy service. It retries calls
+// that fail due to transient errors, and sends keepalive pings (if the interval is positive) to
+// detect connections which were dropped silently by the network (e.g. NAT timeouts), instead
+// of waiting for the next call to time out. The connection itself is established lazily.
+func NewConn(addr string, creds credentials.TransportCredentials, keepaliveTime time.Duration) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(retryPolicy),
+	}
+	if keepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: keepaliveTime}))
+	}
+
+	return grpc.NewClient(addr, opts...)
+}
+
+// CallContext returns a copy of the given context with the given
+// deadline for gRPC calls, or with a default one if it's not positive.
+func CallContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultGRPCTimeout
 	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // LinkCreds returns the saved secrets of the given Thrippy link, or of the receiver's default link
@@ -46,15 +95,8 @@ func (t *LinkClient) LinkCreds(ctx context.Context, linkID string) (map[string]s
 
 	l := activity.GetLogger(ctx)
 
-	conn, err := grpc.NewClient(t.grpcAddr, grpc.WithTransportCredentials(t.creds))
-	if err != nil {
-		l.Error("gRPC connection error", slog.Any("error", err), slog.String("grpc_addr", t.grpcAddr))
-		return nil, err
-	}
-	defer conn.Close()
-
-	c := thrippypb.NewThrippyServiceClient(conn)
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	c := thrippypb.NewThrippyServiceClient(t.conn)
+	ctx, cancel := CallContext(ctx, t.timeout)
 	defer cancel()
 
 	resp, err := c.GetCredentials(ctx, thrippypb.GetCredentialsRequest_builder{LinkId: new(linkID)}.Build())
@@ -67,36 +109,34 @@ func (t *LinkClient) LinkCreds(ctx context.Context, linkID string) (map[string]s
 	return resp.GetCredentials(), nil
 }
 
-// LinkData returns the template name and saved secrets of the receiver's Thrippy link.
-// This function does not distinguish between "not found" and other gRPC errors. The
-// output must not be cached as it may change at any time, e.g. OAuth access tokens.
-func (t *LinkClient) LinkData(ctx context.Context) (string, map[string]string, error) {
-	l := activity.GetLogger(ctx)
-
-	conn, err := grpc.NewClient(t.grpcAddr, grpc.WithTransportCredentials(t.creds))
-	if err != nil {
-		l.Error("gRPC connection error", slog.Any("error", err), slog.String("grpc_addr", t.grpcAddr))
-		return "", nil, err
+// LinkData returns the template name and saved secrets of the given Thrippy link, or of the
+// receiver's default link if no link ID is given. This function does not distinguish between
+// "not found" and other gRPC errors. The output must not be cached as it may change at any
+// time, e.g. OAuth access tokens.
+func (t *LinkClient) LinkData(ctx context.Context, linkID string) (string, map[string]string, error) {
+	if linkID == "" {
+		linkID = t.LinkID
 	}
-	defer conn.Close()
 
-	c := thrippypb.NewThrippyServiceClient(conn)
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	l := activity.GetLogger(ctx)
+
+	c := thrippypb.NewThrippyServiceClient(t.conn)
+	ctx, cancel := CallContext(ctx, t.timeout)
 	defer cancel()
 
 	// Template.
-	resp1, err := c.GetLink(ctx, thrippypb.GetLinkRequest_builder{LinkId: new(t.LinkID)}.Build())
+	resp1, err := c.GetLink(ctx, thrippypb.GetLinkRequest_builder{LinkId: new(linkID)}.Build())
 	if err != nil {
 		l.Error("bad response from gRPC service", slog.Any("error", err),
-			slog.String("link_id", t.LinkID), slog.String("client_method", "GetLink"))
+			slog.String("link_id", linkID), slog.String("client_method", "GetLink"))
 		return "", nil, err
 	}
 
 	// Credentials.
-	resp2, err := c.GetCredentials(ctx, thrippypb.GetCredentialsRequest_builder{LinkId: new(t.LinkID)}.Build())
+	resp2, err := c.GetCredentials(ctx, thrippypb.GetCredentialsRequest_builder{LinkId: new(linkID)}.Build())
 	if err != nil {
 		l.Error("bad response from gRPC service", slog.Any("error", err),
-			slog.String("link_id", t.LinkID), slog.String("client_method", "GetCredentials"))
+			slog.String("link_id", linkID), slog.String("client_method", "GetCredentials"))
 		return "", nil, err
 	}
 
@@ -0,0 +1,116 @@
+package thrippy
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"go.temporal.io/sdk/testsuite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+)
+
+type flakyServer struct {
+	thrippypb.UnimplementedThrippyServiceServer
+
+	failures atomic.Int32
+	code     codes.Code
+	creds    map[string]string
+}
+
+func (s *flakyServer) GetCredentials(_ context.Context, _ *thrippypb.GetCredentialsRequest) (*thrippypb.GetCredentialsResponse, error) {
+	if s.failures.Add(-1) >= 0 {
+		return nil, status.Error(s.code, "transient error")
+	}
+	return thrippypb.GetCredentialsResponse_builder{Credentials: s.creds}.Build(), nil
+}
+
+func TestLinkClientLinkCreds(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		code     codes.Code
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "happy_path",
+			want: map[string]string{"foo": "bar"},
+		},
+		{
+			name:     "unavailable_once",
+			failures: 1,
+			code:     codes.Unavailable,
+			want:     map[string]string{"foo": "bar"},
+		},
+		{
+			name:     "deadline_exceeded_once",
+			failures: 1,
+			code:     codes.DeadlineExceeded,
+			want:     map[string]string{"foo": "bar"},
+		},
+		{
+			name:     "unavailable_too_many_times",
+			failures: 10,
+			code:     codes.Unavailable,
+			wantErr:  true,
+		},
+		{
+			name:     "non_retryable_error",
+			failures: 1,
+			code:     codes.PermissionDenied,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := net.ListenConfig{}
+			lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := &flakyServer{code: tt.code, creds: map[string]string{"foo": "bar"}}
+			s.failures.Store(tt.failures)
+			gs := grpc.NewServer()
+			thrippypb.RegisterThrippyServiceServer(gs, s)
+			go func() {
+				_ = gs.Serve(lis)
+			}()
+			defer gs.Stop()
+
+			conn, err := NewConn(lis.Addr().String(), insecure.NewCredentials(), DefaultGRPCKeepalive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			c := &LinkClient{LinkID: "link ID", conn: conn}
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(c.LinkCreds)
+
+			val, err := env.ExecuteActivity(c.LinkCreds, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LinkClient.LinkCreds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got map[string]string
+			if err := val.Get(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LinkClient.LinkCreds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,21 @@
 package thrippy
 
 import (
-	"log/slog"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/lithammer/shortuuid/v4"
 	altsrc "github.com/urfave/cli-altsrc/v3"
-	"github.com/urfave/cli-altsrc/v3/toml"
 	"github.com/urfave/cli/v3"
+
+	"github.com/tzrikka/timpani/internal/config"
 )
 
 const (
-	DefaultGRPCAddress = "localhost:14460"
+	DefaultGRPCAddress   = "localhost:14460"
+	DefaultGRPCTimeout   = 3 * time.Second
+	DefaultGRPCKeepalive = 5 * time.Minute
 )
 
 // Flags defines CLI flags to configure a Thrippy gRPC client. These flags are
@@ -24,7 +28,25 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Value: DefaultGRPCAddress,
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("THRIPPY_GRPC_ADDRESS"),
-				toml.TOML("thrippy.grpc_address", configFilePath),
+				config.TOML("thrippy.grpc_address", configFilePath),
+			),
+		},
+		&cli.DurationFlag{
+			Name:  "thrippy-grpc-timeout",
+			Usage: "Deadline for each Thrippy gRPC call, including retries",
+			Value: DefaultGRPCTimeout,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("THRIPPY_GRPC_TIMEOUT"),
+				config.TOML("thrippy.grpc_timeout", configFilePath),
+			),
+		},
+		&cli.DurationFlag{
+			Name:  "thrippy-grpc-keepalive",
+			Usage: "Interval of keepalive pings to the Thrippy gRPC server (0 = disabled)",
+			Value: DefaultGRPCKeepalive,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("THRIPPY_GRPC_KEEPALIVE"),
+				config.TOML("thrippy.grpc_keepalive", configFilePath),
 			),
 		},
 		&cli.StringFlag{
@@ -32,7 +54,7 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Usage: "Thrippy gRPC client's public certificate PEM file (mTLS only)",
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("THRIPPY_CLIENT_CERT"),
-				toml.TOML("thrippy.client_cert", configFilePath),
+				config.TOML("thrippy.client_cert", configFilePath),
 			),
 			TakesFile: true,
 		},
@@ -41,7 +63,7 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Usage: "Thrippy gRPC client's private key PEM file (mTLS only)",
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("THRIPPY_CLIENT_KEY"),
-				toml.TOML("thrippy.client_key", configFilePath),
+				config.TOML("thrippy.client_key", configFilePath),
 			),
 			TakesFile: true,
 		},
@@ -50,7 +72,7 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Usage: "Thrippy gRPC server's CA certificate PEM file (both TLS and mTLS)",
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("THRIPPY_SERVER_CA_CERT"),
-				toml.TOML("thrippy.server_ca_cert", configFilePath),
+				config.TOML("thrippy.server_ca_cert", configFilePath),
 			),
 			TakesFile: true,
 		},
@@ -59,7 +81,7 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Usage: "Thrippy gRPC server's name override (for testing, both TLS and mTLS)",
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("THRIPPY_SERVER_NAME_OVERRIDE"),
-				toml.TOML("thrippy.server_name_override", configFilePath),
+				config.TOML("thrippy.server_name_override", configFilePath),
 			),
 		},
 	}
@@ -74,26 +96,30 @@ func LinkIDFlag(configFilePath altsrc.StringSourcer, service string) cli.Flag {
 		Usage: "Thrippy link ID for " + service,
 		Sources: cli.NewValueSourceChain(
 			cli.EnvVar("THRIPPY_LINK_"+strings.ToUpper(service)),
-			toml.TOML("thrippy.links."+lowerCase, configFilePath),
+			config.TOML("thrippy.links."+lowerCase, configFilePath),
 		),
 		Validator: validateOptionalUUID,
 	}
 }
 
+// Reasons that [LinkID] may reject the configured Thrippy link ID of a third-party service.
+var (
+	ErrLinkIDNotConfigured = errors.New("Thrippy link ID not configured") //nolint:staticcheck // Proper noun.
+	ErrLinkIDInvalid       = errors.New("invalid Thrippy link ID")
+)
+
 // LinkID extracts and checks the configured Thrippy link ID for the given third-party service.
-func LinkID(cmd *cli.Command, service string) (string, bool) {
+func LinkID(cmd *cli.Command, service string) (string, error) {
 	id := cmd.String("thrippy-link-" + strings.ToLower(service))
 	if id == "" {
-		slog.Warn("Thrippy link ID not configured for " + service)
-		return "", false
+		return "", ErrLinkIDNotConfigured
 	}
 
 	if _, err := shortuuid.DefaultEncoder.Decode(id); err != nil {
-		slog.Error("invalid Thrippy link ID configured for " + service)
-		return "", false
+		return "", ErrLinkIDInvalid
 	}
 
-	return id, true
+	return id, nil
 }
 
 func validateOptionalUUID(id string) error {
@@ -1,6 +1,7 @@
 package thrippy
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/urfave/cli/v3"
@@ -8,23 +9,24 @@ import (
 
 func TestLinkID(t *testing.T) {
 	tests := []struct {
-		name   string
-		flag   string
-		want   string
-		wantOK bool
+		name    string
+		flag    string
+		want    string
+		wantErr error
 	}{
 		{
-			name: "no_value",
+			name:    "no_value",
+			wantErr: ErrLinkIDNotConfigured,
 		},
 		{
-			name: "invalid_value",
-			flag: "1",
+			name:    "invalid_value",
+			flag:    "1",
+			wantErr: ErrLinkIDInvalid,
 		},
 		{
-			name:   "happy_path",
-			flag:   "jPQqh6z3mahiw5xFtnyESK",
-			want:   "jPQqh6z3mahiw5xFtnyESK",
-			wantOK: true,
+			name: "happy_path",
+			flag: "jPQqh6z3mahiw5xFtnyESK",
+			want: "jPQqh6z3mahiw5xFtnyESK",
 		},
 	}
 
@@ -34,12 +36,12 @@ func TestLinkID(t *testing.T) {
 				Flags: []cli.Flag{&cli.StringFlag{Name: "thrippy-link-service"}},
 			}
 			_ = cmd.Set("thrippy-link-service", tt.flag)
-			got, gotOK := LinkID(cmd, "service")
+			got, err := LinkID(cmd, "service")
 			if got != tt.want {
 				t.Errorf("LinkID() got = %v, want %v", got, tt.want)
 			}
-			if gotOK != tt.wantOK {
-				t.Errorf("LinkID() OK = %v, want %v", gotOK, tt.wantOK)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("LinkID() error = %v, want %v", err, tt.wantErr)
 			}
 		})
 	}
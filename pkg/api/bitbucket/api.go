@@ -3,6 +3,7 @@ package bitbucket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -22,12 +23,24 @@ const (
 	BaseURL = "https://api.bitbucket.org/2.0"
 )
 
-// httpDelete is a Bitbucket-specific HTTP DELETE wrapper for [client.HTTPRequest].
+// cloudBaseURL is the [BaseURL] of Bitbucket Cloud API calls, overridden only in unit tests.
+var cloudBaseURL = BaseURL
+
+// provider identifies Bitbucket API calls, for provider-specific client behavior.
+var provider = client.WithProvider(client.Provider{Name: "bitbucket", ErrorDetails: errorDetails})
+
+// errorDetails extracts the request ID from Bitbucket HTTP error responses.
+// Bitbucket doesn't specify error codes, only human-readable messages.
+func errorDetails(header http.Header, _ []byte) (string, string) {
+	return "", header.Get("X-Request-Id")
+}
+
+// httpDelete is a Bitbucket-specific HTTP DELETE wrapper for [client.HTTPRequestFull].
 func (a *API) httpDelete(ctx context.Context, linkID, path string, query url.Values) error {
 	return a.httpRequest(ctx, linkID, path, http.MethodDelete, query, nil)
 }
 
-// httpGet is a Bitbucket-specific HTTP GET wrapper for [client.HTTPRequest].
+// httpGet is a Bitbucket-specific HTTP GET wrapper for [client.HTTPRequestFull].
 func (a *API) httpGet(ctx context.Context, name, linkID, path string, query url.Values, jsonResp any) error {
 	t := time.Now().UTC()
 	err := a.httpRequest(ctx, linkID, path, http.MethodGet, query, jsonResp)
@@ -35,7 +48,7 @@ func (a *API) httpGet(ctx context.Context, name, linkID, path string, query url.
 	return err
 }
 
-// httpGetText is a Bitbucket-specific HTTP GET wrapper for [client.HTTPRequest].
+// httpGetText is a Bitbucket-specific HTTP GET wrapper for [client.HTTPRequestFull].
 // Unlike [httpGet], it expects a plaintext response body and returns it unparsed.
 func (a *API) httpGetText(ctx context.Context, name, linkID, path string, query url.Values) (string, error) {
 	t := time.Now().UTC()
@@ -44,20 +57,77 @@ func (a *API) httpGetText(ctx context.Context, name, linkID, path string, query
 	otel.IncrementAPICallCounter(t, name, err)
 
 	if err != nil {
-		if strings.HasPrefix(err.Error(), "404 Not Found") {
-			return "", temporal.NewNonRetryableApplicationError(err.Error(), "BitbucketAPIError", err, query.Encode())
-		}
-		return "", err
+		return "", notFoundError(err, query)
 	}
 	return resp.String(), nil
 }
 
-// httpPost is a Bitbucket-specific HTTP POST wrapper for [client.HTTPRequest].
+// httpGetToFile is a Bitbucket-specific HTTP GET wrapper for [client.HTTPRequestToFile].
+// Unlike [httpGetText], it writes the plaintext response body into a local file, instead
+// of returning it, in order to support large responses.
+func (a *API) httpGetToFile(
+	ctx context.Context,
+	name, linkID, path string,
+	query url.Values,
+	filePath string,
+	maxSize int64,
+) (*client.FileSinkResponse, error) {
+	t := time.Now().UTC()
+	resp, err := a.httpRequestToFile(ctx, linkID, path, query, filePath, maxSize)
+	otel.IncrementAPICallCounter(t, name, err)
+
+	if err != nil {
+		return nil, notFoundError(err, query)
+	}
+	return resp, nil
+}
+
+// notFoundError converts HTTP 404 errors into non-retryable errors.
+func notFoundError(err error, query url.Values) error {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return temporal.NewNonRetryableApplicationError(apiErr.Error(), "BitbucketAPIError", err, query.Encode())
+	}
+	return err
+}
+
+// isNotFound reports whether an API call failed with an HTTP 404 error.
+func isNotFound(err error) bool {
+	var apiErr *client.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (a *API) httpRequestToFile(
+	ctx context.Context,
+	linkID, path string,
+	query url.Values,
+	filePath string,
+	maxSize int64,
+) (*client.FileSinkResponse, error) {
+	l, apiURL, auth, err := a.httpRequestPrep(ctx, linkID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.RequestOpt{provider, client.WithFileTransferTimeout()}
+	resp, _, err := client.HTTPRequestToFile(ctx, http.MethodGet, apiURL, auth, client.AcceptText, "", query, filePath, maxSize, opts...)
+	if err != nil {
+		l.Error("HTTP request error", slog.Any("error", err), slog.String("http_method", http.MethodGet),
+			slog.String("url", apiURL), slog.String("file_path", filePath))
+		return nil, client.NewApplicationError(err)
+	}
+
+	l.Info("sent HTTP request", slog.String("link_id", linkID), slog.String("http_method", http.MethodGet),
+		slog.String("url", apiURL), slog.String("file_path", filePath), slog.Int64("size", resp.Size))
+	return resp, nil
+}
+
+// httpPost is a Bitbucket-specific HTTP POST wrapper for [client.HTTPRequestFull].
 func (a *API) httpPost(ctx context.Context, linkID, path string, jsonBody, jsonResp any) error {
 	return a.httpRequest(ctx, linkID, path, http.MethodPost, jsonBody, jsonResp)
 }
 
-// httpPut is a Bitbucket-specific HTTP PUT wrapper for [client.HTTPRequest].
+// httpPut is a Bitbucket-specific HTTP PUT wrapper for [client.HTTPRequestFull].
 func (a *API) httpPut(ctx context.Context, linkID, path string, jsonBody, jsonResp any) error {
 	return a.httpRequest(ctx, linkID, path, http.MethodPut, jsonBody, jsonResp)
 }
@@ -74,10 +144,10 @@ func (a *API) httpRequest(ctx context.Context, linkID, path, method string, quer
 		accept = client.AcceptText
 	}
 
-	rawResp, _, _, err := client.HTTPRequest(ctx, method, apiURL, auth, accept, client.ContentJSON, queryOrJSONBody)
+	resp, err := client.HTTPRequestFull(ctx, method, apiURL, auth, accept, client.ContentJSON, queryOrJSONBody, provider)
 	if err != nil {
 		l.Error("HTTP request error", slog.Any("error", err), slog.String("http_method", method), slog.String("url", apiURL))
-		return err
+		return client.NewApplicationError(err)
 	}
 
 	l.Info("sent HTTP request", slog.String("link_id", linkID), slog.String("http_method", method), slog.String("url", apiURL))
@@ -87,15 +157,15 @@ func (a *API) httpRequest(ctx context.Context, linkID, path, method string, quer
 	}
 
 	if ok {
-		_, err := parsedResp.(*strings.Builder).Write(rawResp) //nolint:errcheck // Unparsed plaintext.
+		_, err := parsedResp.(*strings.Builder).Write(resp.Body) //nolint:errcheck // Unparsed plaintext.
 		return err
 	}
 
-	if err := json.Unmarshal(rawResp, parsedResp); err != nil {
+	if err := json.Unmarshal(resp.Body, parsedResp); err != nil {
 		msg := "failed to decode HTTP response's JSON body"
 		l.Error(msg, slog.Any("error", err), slog.String("url", apiURL))
 		msg = fmt.Sprintf("%s: %v", msg, err)
-		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(rawResp))
+		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp.Body))
 	}
 
 	return nil
@@ -103,29 +173,48 @@ func (a *API) httpRequest(ctx context.Context, linkID, path, method string, quer
 
 // httpRequestPrep supports custom Thrippy link IDs (for user impersonation).
 // If it's empty, we use the Timpani server's preconfigured Bitbucket link ID.
+// The link's template determines whether it's Bitbucket Cloud or Server.
+// The path may contain a query, for non-GET requests that require one.
 func (a *API) httpRequestPrep(ctx context.Context, linkID, path string) (l log.Logger, apiURL, auth string, err error) {
 	l = activity.GetLogger(ctx)
 
+	var template string
 	var secrets map[string]string
-	secrets, err = a.thrippy.LinkCreds(ctx, linkID)
+	template, secrets, err = a.thrippy.LinkData(ctx, linkID)
 	if err != nil {
 		return l, "", "", err
 	}
 
-	apiURL, err = url.JoinPath(BaseURL, path)
+	baseURL, auth := cloudBaseURL, authHeader(secrets)
+	if template == ServerTemplate {
+		if baseURL, err = serverBaseURL(secrets); err != nil {
+			l.Error("invalid Bitbucket Server link", slog.Any("error", err), slog.String("link_id", linkID))
+			return l, "", "", err
+		}
+		auth = serverAuthHeader(secrets)
+	}
+
+	path, query, _ := strings.Cut(path, "?")
+	apiURL, err = url.JoinPath(baseURL, path)
 	if err != nil {
 		l.Error("failed to construct Bitbucket API URL", slog.Any("error", err),
-			slog.String("base_url", BaseURL), slog.String("path", path))
-		err = temporal.NewNonRetryableApplicationError(err.Error(), fmt.Sprintf("%T", err), err, BaseURL, path)
+			slog.String("base_url", baseURL), slog.String("path", path))
+		err = temporal.NewNonRetryableApplicationError(err.Error(), fmt.Sprintf("%T", err), err, baseURL, path)
 		return l, "", "", err
 	}
+	if query != "" {
+		apiURL += "?" + query
+	}
 
+	return l, apiURL, auth, nil
+}
+
+// authHeader returns the value of an HTTP "Authorization" header, based on the given link secrets.
+func authHeader(secrets map[string]string) string {
 	// "access_token" has a value only in "bitbucket-app-oauth" link secrets.
 	// The others have values only in "bitbucket-user-token" link secrets.
-	auth = secrets["access_token"]
-	if auth == "" {
-		auth = fmt.Sprintf("Basic %s:%s", secrets["email"], secrets["api_token"])
+	if auth := secrets["access_token"]; auth != "" {
+		return auth
 	}
-
-	return l, apiURL, auth, nil
+	return fmt.Sprintf("Basic %s:%s", secrets["email"], secrets["api_token"])
 }
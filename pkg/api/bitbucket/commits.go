@@ -2,12 +2,89 @@ package bitbucket
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
 
 	"github.com/tzrikka/timpani-api/pkg/bitbucket"
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
 )
 
+//revive:disable:exported
+const (
+	CommitsCreateCommentActivityName = "bitbucket.commits.createComment"
+	CommitsGetActivityName           = "bitbucket.commits.get"
+	CommitsListCommentsActivityName  = "bitbucket.commits.listComments"
+) //revive:enable:exported
+
+// CommitsRequest contains common fields for requests about a single commit.
+type CommitsRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+
+	Workspace string `json:"workspace"`
+	RepoSlug  string `json:"repo_slug"`
+	Commit    string `json:"commit"`
+}
+
+func (r CommitsRequest) path(suffix string) string {
+	return fmt.Sprintf("/repositories/%s/%s/commit/%s%s", r.Workspace, r.RepoSlug, r.Commit, suffix)
+}
+
+// CommitsCreateCommentRequest is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-commit-commit-comments-post
+//
+// Path is optional, to create an inline comment on a file instead of a
+// general comment on the commit. Line is optional too, but requires Path.
+type CommitsCreateCommentRequest struct {
+	CommitsRequest
+
+	Markdown string `json:"text"`
+
+	ParentID string `json:"parent_id,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// CommitsListCommentsRequest is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-commit-commit-comments-get
+type CommitsListCommentsRequest struct {
+	CommitsRequest
+
+	// https://developer.atlassian.com/cloud/bitbucket/rest/intro/#pagination
+	PageLen string `json:"pagelen,omitempty"`
+	Page    string `json:"page,omitempty"`
+
+	Next string `json:"next,omitempty"` // Populated and used only in Timpani, for pagination.
+}
+
+// CommitsListCommentsResponse is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-commit-commit-comments-get
+type CommitsListCommentsResponse struct {
+	Values []bitbucket.Comment `json:"values"`
+
+	// https://developer.atlassian.com/cloud/bitbucket/rest/intro/#pagination
+	Size    int    `json:"size,omitempty"`
+	PageLen int    `json:"pagelen,omitempty"`
+	Page    int    `json:"page,omitempty"`
+	Next    string `json:"next,omitempty"`
+}
+
+type commitCommentBody struct {
+	Content prCommentContent       `json:"content"`
+	Parent  *prCreateCommentParent `json:"parent,omitempty"`
+	Inline  *commentInline         `json:"inline,omitempty"`
+}
+
+type commentInline struct {
+	Path string `json:"path"`
+	To   int    `json:"to,omitempty"`
+}
+
 // CommitsDiffActivity is based on:
 // https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-diff-spec-get
 func (a *API) CommitsDiffActivity(ctx context.Context, req bitbucket.CommitsDiffRequest) (string, error) {
@@ -20,6 +97,31 @@ func (a *API) CommitsDiffActivity(ctx context.Context, req bitbucket.CommitsDiff
 	return a.httpGetText(ctx, bitbucket.CommitsDiffActivityName, req.ThrippyLinkID, path, query)
 }
 
+// CommitsDiffToFileActivityName is a Timpani-specific variant of [bitbucket.CommitsDiffActivityName].
+const CommitsDiffToFileActivityName = "bitbucket.timpani.commits.diffToFile"
+
+// CommitsDiffToFileRequest is like [bitbucket.CommitsDiffRequest],
+// but the diff is written into a local file instead of being returned.
+// MaxSize is optional (default = [client.MaxStreamSize]).
+type CommitsDiffToFileRequest struct {
+	bitbucket.CommitsDiffRequest
+
+	FilePath string `json:"file_path"`
+	MaxSize  int64  `json:"max_size,omitempty"`
+}
+
+// CommitsDiffToFileActivity is a variant of [API.CommitsDiffActivity] for large diffs, which
+// writes the diff into a local file (on the Timpani worker's host) instead of returning it.
+func (a *API) CommitsDiffToFileActivity(ctx context.Context, req CommitsDiffToFileRequest) (*client.FileSinkResponse, error) {
+	path := fmt.Sprintf("/repositories/%s/%s/diff/%s", req.Workspace, req.RepoSlug, req.Spec)
+	query := url.Values{}
+	if req.Path != "" {
+		query.Set("path", req.Path)
+	}
+
+	return a.httpGetToFile(ctx, CommitsDiffToFileActivityName, req.ThrippyLinkID, path, query, req.FilePath, req.MaxSize)
+}
+
 // CommitsDiffstatActivity is based on:
 // https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-diffstat-spec-get
 func (a *API) CommitsDiffstatActivity(ctx context.Context, req bitbucket.CommitsDiffstatRequest) (*bitbucket.CommitsDiffstatResponse, error) {
@@ -52,3 +154,73 @@ func (a *API) CommitsDiffstatActivity(ctx context.Context, req bitbucket.Commits
 	}
 	return resp, nil
 }
+
+// CommitsCreateCommentActivity is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-commit-commit-comments-post
+func (a *API) CommitsCreateCommentActivity(ctx context.Context, req CommitsCreateCommentRequest) (*bitbucket.Comment, error) {
+	t := time.Now().UTC()
+	body, err := newCommitCommentBody(req)
+	if err != nil {
+		otel.IncrementAPICallCounter(t, CommitsCreateCommentActivityName, err)
+		return nil, err
+	}
+
+	resp := new(bitbucket.Comment)
+	err = a.httpPost(ctx, req.ThrippyLinkID, req.path("/comments"), body, resp)
+	otel.IncrementAPICallCounter(t, CommitsCreateCommentActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// newCommitCommentBody converts the request of [API.CommitsCreateCommentActivity]
+// into the JSON body of the API call, like the body of PR comments.
+func newCommitCommentBody(req CommitsCreateCommentRequest) (*commitCommentBody, error) {
+	body := &commitCommentBody{Content: prCommentContent{Raw: req.Markdown}}
+	if req.ParentID != "" {
+		id, err := strconv.Atoi(req.ParentID)
+		if err != nil {
+			return nil, temporal.NewNonRetryableApplicationError("invalid parent ID", fmt.Sprintf("%T", err), err, req.ParentID)
+		}
+		body.Parent = &prCreateCommentParent{ID: id}
+	}
+
+	if req.Line != 0 && req.Path == "" {
+		err := errors.New("inline comment line requires a file path")
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), fmt.Sprintf("%T", err), err, req.Line)
+	}
+	if req.Path != "" {
+		body.Inline = &commentInline{Path: req.Path, To: req.Line}
+	}
+
+	return body, nil
+}
+
+// CommitsGetActivity is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-commit-commit-get
+func (a *API) CommitsGetActivity(ctx context.Context, req CommitsRequest) (*bitbucket.Commit, error) {
+	resp := new(bitbucket.Commit)
+	err := a.httpGet(ctx, CommitsGetActivityName, req.ThrippyLinkID, req.path(""), url.Values{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CommitsListCommentsActivity is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-commits/#api-repositories-workspace-repo-slug-commit-commit-comments-get
+func (a *API) CommitsListCommentsActivity(ctx context.Context, req CommitsListCommentsRequest) (*CommitsListCommentsResponse, error) {
+	path, query, err := paginatedQuery(CommitsListCommentsActivityName, req.path("/comments"), req.PageLen, req.Page, req.Next)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(CommitsListCommentsResponse)
+	err = a.httpGet(ctx, CommitsListCommentsActivityName, req.ThrippyLinkID, path, query, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
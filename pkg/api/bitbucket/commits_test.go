@@ -0,0 +1,76 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewCommitCommentBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CommitsCreateCommentRequest
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "general_comment",
+			req:  CommitsCreateCommentRequest{Markdown: "Pushed directly to main"},
+			want: `{"content":{"raw":"Pushed directly to main"}}`,
+		},
+		{
+			name: "reply",
+			req:  CommitsCreateCommentRequest{Markdown: "Ack", ParentID: "123"},
+			want: `{"content":{"raw":"Ack"},"parent":{"id":123}}`,
+		},
+		{
+			name: "inline_file",
+			req:  CommitsCreateCommentRequest{Markdown: "Nit", Path: "main.go"},
+			want: `{"content":{"raw":"Nit"},"inline":{"path":"main.go"}}`,
+		},
+		{
+			name: "inline_line",
+			req:  CommitsCreateCommentRequest{Markdown: "Nit", Path: "main.go", Line: 42},
+			want: `{"content":{"raw":"Nit"},"inline":{"path":"main.go","to":42}}`,
+		},
+		{
+			name:    "invalid_parent_id",
+			req:     CommitsCreateCommentRequest{Markdown: "Ack", ParentID: "abc"},
+			wantErr: true,
+		},
+		{
+			name:    "line_without_path",
+			req:     CommitsCreateCommentRequest{Markdown: "Nit", Line: 42},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := newCommitCommentBody(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCommitCommentBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := json.Marshal(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("newCommitCommentBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitsRequestPath(t *testing.T) {
+	req := CommitsRequest{Workspace: "ws", RepoSlug: "repo", Commit: "abc123"}
+	if got, want := req.path(""), "/repositories/ws/repo/commit/abc123"; got != want {
+		t.Errorf("path() = %q, want %q", got, want)
+	}
+	if got, want := req.path("/comments"), "/repositories/ws/repo/commit/abc123/comments"; got != want {
+		t.Errorf("path() = %q, want %q", got, want)
+	}
+}
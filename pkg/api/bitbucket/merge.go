@@ -0,0 +1,185 @@
+package bitbucket
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/bitbucket"
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+)
+
+// TimpaniMergeWhenApprovedWorkflowName is a Timpani-specific workflow, see [API.TimpaniMergeWhenApprovedWorkflow].
+const TimpaniMergeWhenApprovedWorkflowName = "bitbucket.timpani.mergeWhenApproved"
+
+// Signals which may change the outcome of a merge policy check. The listener
+// converts the ":" in Bitbucket event keys to "." in Temporal signal names.
+var mergePolicySignals = []string{
+	"bitbucket.events.pullrequest.approved",
+	"bitbucket.events.pullrequest.changes_request_removed",
+}
+
+// TimpaniMergeWhenApprovedRequest extends [bitbucket.PullRequestsMergeRequest] with a
+// merge policy: the minimum number of approvals (default = 1), and whether all the
+// tasks in the pull request must be resolved. Pending change requests always block
+// the merge. The timeout is optional (e.g. "24h", default = no timeout).
+type TimpaniMergeWhenApprovedRequest struct {
+	bitbucket.PullRequestsMergeRequest
+
+	MinApprovals       int  `json:"min_approvals,omitempty"`
+	RequireNoOpenTasks bool `json:"require_no_open_tasks,omitempty"`
+
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// TimpaniMergeWhenApprovedResponse reports whether the pull request was merged, and the
+// latest policy check results. State is set only if the pull request is no longer open
+// for reasons other than this workflow (e.g. "MERGED" or "DECLINED").
+type TimpaniMergeWhenApprovedResponse struct {
+	Merged           bool   `json:"merged"`
+	Approvals        int    `json:"approvals"`
+	ChangesRequested int    `json:"changes_requested"`
+	OpenTasks        int    `json:"open_tasks"`
+	State            string `json:"state,omitempty"`
+}
+
+// TimpaniMergeWhenApprovedWorkflow is a convenience wrapper over [PullRequestsGetActivity],
+// [PullRequestsListTasksActivity], and [PullRequestsMergeActivity]. It merges a pull request
+// as soon as it satisfies the request's policy, re-checking it whenever the pull request is
+// approved or a change request is removed, until the request's timeout.
+func (a *API) TimpaniMergeWhenApprovedWorkflow(
+	ctx workflow.Context,
+	req TimpaniMergeWhenApprovedRequest,
+) (*TimpaniMergeWhenApprovedResponse, error) {
+	timeout := time.Duration(0)
+	if req.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError("invalid timeout: "+err.Error(), "error", err, req.Timeout)
+		}
+	}
+	deadline := workflow.Now(ctx).Add(timeout)
+
+	if req.MinApprovals == 0 {
+		req.MinApprovals = 1
+	}
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+
+	for {
+		resp, err := checkMergePolicy(actCtx, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.State != "" {
+			return resp, nil
+		}
+
+		if resp.Approvals >= req.MinApprovals && resp.ChangesRequested == 0 && (!req.RequireNoOpenTasks || resp.OpenTasks == 0) {
+			merge := workflow.ExecuteActivity(actCtx, bitbucket.PullRequestsMergeActivityName, req.PullRequestsMergeRequest)
+			if err := merge.Get(ctx, nil); err != nil {
+				return nil, fmt.Errorf("failed to merge pull request: %w", err)
+			}
+			resp.Merged = true
+			return resp, nil
+		}
+
+		remaining := time.Duration(0)
+		if timeout > 0 {
+			if remaining = deadline.Sub(workflow.Now(ctx)); remaining <= 0 {
+				return resp, nil
+			}
+		}
+
+		if err := waitForMergePolicySignal(ctx, req.PullRequestID, remaining); err != nil {
+			if timeout > 0 && !workflow.Now(ctx).Before(deadline) {
+				return resp, nil
+			}
+			return nil, fmt.Errorf("failed to wait for events: %w", err)
+		}
+	}
+}
+
+// checkMergePolicy counts the approvals, change requests, and unresolved tasks of a pull request.
+func checkMergePolicy(ctx workflow.Context, req TimpaniMergeWhenApprovedRequest) (*TimpaniMergeWhenApprovedResponse, error) {
+	var pr map[string]any
+	if err := workflow.ExecuteActivity(ctx, bitbucket.PullRequestsGetActivityName, req.PullRequestsRequest).Get(ctx, &pr); err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	resp := new(TimpaniMergeWhenApprovedResponse)
+	if state, _ := pr["state"].(string); state != "" && state != "OPEN" {
+		resp.State = state
+		return resp, nil
+	}
+
+	participants, _ := pr["participants"].([]any)
+	for _, p := range participants {
+		m, _ := p.(map[string]any)
+		switch {
+		case m["approved"] == true:
+			resp.Approvals++
+		case m["state"] == "changes_requested":
+			resp.ChangesRequested++
+		}
+	}
+
+	if !req.RequireNoOpenTasks {
+		return resp, nil
+	}
+
+	tasksReq := bitbucket.PullRequestsListTasksRequest{PullRequestsRequest: req.PullRequestsRequest}
+	for {
+		tasks := new(bitbucket.PullRequestsListTasksResponse)
+		if err := workflow.ExecuteActivity(ctx, bitbucket.PullRequestsListTasksActivityName, tasksReq).Get(ctx, tasks); err != nil {
+			return nil, fmt.Errorf("failed to list pull request tasks: %w", err)
+		}
+
+		for _, t := range tasks.Values {
+			if t.State == "UNRESOLVED" {
+				resp.OpenTasks++
+			}
+		}
+
+		if tasks.Next == "" {
+			return resp, nil
+		}
+		tasksReq.Next = tasks.Next
+	}
+}
+
+// waitForMergePolicySignal waits for the first signal about the given pull request
+// in [mergePolicySignals], with an optional timeout (0 = no timeout).
+func waitForMergePolicySignal(ctx workflow.Context, prID string, timeout time.Duration) error {
+	ctx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+
+	// https://docs.temporal.io/develop/go/observability#visibility
+	attr := temporal.NewSearchAttributeKeyKeywordList("WaitingForSignals").ValueSet(mergePolicySignals)
+	ctx = workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{TypedSearchAttributes: temporal.NewSearchAttributes(attr)})
+
+	var err error
+	selector := workflow.NewSelector(ctx)
+	for _, signal := range mergePolicySignals {
+		req := listeners.WaitForEventRequest{Signal: signal, Filter: map[string]string{"pullrequest.id": prID}}
+		if timeout > 0 {
+			req.Timeout = timeout.String()
+		}
+
+		fut := workflow.ExecuteChildWorkflow(ctx, listeners.WaitForEventWorkflow, req)
+		selector.AddFuture(fut, func(f workflow.Future) {
+			err = f.Get(ctx, nil)
+		})
+	}
+
+	// The first signal (or timeout) is enough, the other child workflow is canceled.
+	selector.Select(ctx)
+	return err
+}
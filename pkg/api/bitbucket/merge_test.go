@@ -0,0 +1,170 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/bitbucket"
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+func testPullRequest(state string, participants ...string) map[string]any {
+	ps := make([]any, 0, len(participants))
+	for _, p := range participants {
+		ps = append(ps, map[string]any{"state": p, "approved": p == "approved"})
+	}
+	return map[string]any{"id": float64(7), "state": state, "participants": ps}
+}
+
+func TestTimpaniMergeWhenApprovedWorkflow(t *testing.T) {
+	tests := []struct {
+		name         string
+		minApprovals int
+		requireTasks bool
+		prs          []map[string]any
+		tasks        map[string][]string // Page ("" = first) to task states.
+		events       map[string]int      // Signal to number of events.
+		want         TimpaniMergeWhenApprovedResponse
+	}{
+		{
+			name:         "already_approved",
+			minApprovals: 2,
+			prs:          []map[string]any{testPullRequest("OPEN", "approved", "approved", "")},
+			want:         TimpaniMergeWhenApprovedResponse{Merged: true, Approvals: 2},
+		},
+		{
+			name: "wait_for_approval",
+			prs:  []map[string]any{testPullRequest("OPEN", ""), testPullRequest("OPEN", "approved")},
+			events: map[string]int{
+				"bitbucket.events.pullrequest.approved": 1,
+			},
+			want: TimpaniMergeWhenApprovedResponse{Merged: true, Approvals: 1},
+		},
+		{
+			name: "wait_for_change_request_removal",
+			prs: []map[string]any{
+				testPullRequest("OPEN", "approved", "changes_requested"),
+				testPullRequest("OPEN", "approved", ""),
+			},
+			events: map[string]int{
+				"bitbucket.events.pullrequest.changes_request_removed": 1,
+			},
+			want: TimpaniMergeWhenApprovedResponse{Merged: true, Approvals: 1},
+		},
+		{
+			name:         "no_open_tasks",
+			requireTasks: true,
+			prs:          []map[string]any{testPullRequest("OPEN", "approved")},
+			tasks:        map[string][]string{"": {"RESOLVED"}, "2": {"RESOLVED"}},
+			want:         TimpaniMergeWhenApprovedResponse{Merged: true, Approvals: 1},
+		},
+		{
+			name:         "open_tasks_timeout",
+			requireTasks: true,
+			prs:          []map[string]any{testPullRequest("OPEN", "approved")},
+			tasks:        map[string][]string{"": {"RESOLVED"}, "2": {"UNRESOLVED", "UNRESOLVED"}},
+			events: map[string]int{
+				"bitbucket.events.pullrequest.approved": 1,
+			},
+			want: TimpaniMergeWhenApprovedResponse{Approvals: 1, OpenTasks: 2},
+		},
+		{
+			name: "declined",
+			prs:  []map[string]any{testPullRequest("OPEN"), testPullRequest("DECLINED")},
+			events: map[string]int{
+				"bitbucket.events.pullrequest.changes_request_removed": 1,
+			},
+			want: TimpaniMergeWhenApprovedResponse{State: "DECLINED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gets := 0
+			get := func(_ context.Context, _ bitbucket.PullRequestsGetRequest) (map[string]any, error) {
+				pr := tt.prs[min(gets, len(tt.prs)-1)]
+				gets++
+				return pr, nil
+			}
+			listTasks := func(_ context.Context, req bitbucket.PullRequestsListTasksRequest) (*bitbucket.PullRequestsListTasksResponse, error) {
+				states, ok := tt.tasks[req.Next]
+				if !ok {
+					return nil, fmt.Errorf("unexpected tasks page: %q", req.Next)
+				}
+				resp := &bitbucket.PullRequestsListTasksResponse{}
+				for _, s := range states {
+					resp.Values = append(resp.Values, bitbucket.Task{State: s})
+				}
+				if req.Next == "" {
+					resp.Next = "2"
+				}
+				return resp, nil
+			}
+			merges := 0
+			merge := func(_ context.Context, req bitbucket.PullRequestsMergeRequest) error {
+				if req.PullRequestID != "7" || req.MergeStrategy != "squash" {
+					return fmt.Errorf("unexpected merge request: %v", req)
+				}
+				merges++
+				return nil
+			}
+			events := maps.Clone(tt.events)
+			wait := func(ctx workflow.Context, req listeners.WaitForEventRequest) (map[string]any, error) {
+				if req.Filter["pullrequest.id"] != "7" {
+					return nil, fmt.Errorf("unexpected wait request: %v", req)
+				}
+				if events[req.Signal] == 0 {
+					timeout, _ := time.ParseDuration(req.Timeout)
+					_ = workflow.Sleep(ctx, timeout)
+					return nil, errors.New("timeout")
+				}
+				events[req.Signal]--
+				return map[string]any{"pullrequest": map[string]any{"id": float64(7)}}, nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(get, activity.RegisterOptions{Name: bitbucket.PullRequestsGetActivityName})
+			env.RegisterActivityWithOptions(listTasks, activity.RegisterOptions{Name: bitbucket.PullRequestsListTasksActivityName})
+			env.RegisterActivityWithOptions(merge, activity.RegisterOptions{Name: bitbucket.PullRequestsMergeActivityName})
+			env.RegisterWorkflowWithOptions(wait, workflow.RegisterOptions{Name: listeners.WaitForEventWorkflow})
+			env.RegisterWorkflow(a.TimpaniMergeWhenApprovedWorkflow)
+
+			req := TimpaniMergeWhenApprovedRequest{MinApprovals: tt.minApprovals, RequireNoOpenTasks: tt.requireTasks, Timeout: "1h"}
+			req.Workspace, req.RepoSlug, req.PullRequestID, req.MergeStrategy = "workspace", "repo", "7", "squash"
+			env.ExecuteWorkflow(a.TimpaniMergeWhenApprovedWorkflow, req)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("TimpaniMergeWhenApprovedWorkflow() error = %v", err)
+			}
+			got := new(TimpaniMergeWhenApprovedResponse)
+			if err := env.GetWorkflowResult(got); err != nil {
+				t.Fatal(err)
+			}
+
+			if *got != tt.want {
+				t.Errorf("TimpaniMergeWhenApprovedWorkflow() = %+v, want %+v", *got, tt.want)
+			}
+			wantMerges := 0
+			if tt.want.Merged {
+				wantMerges = 1
+			}
+			if merges != wantMerges {
+				t.Errorf("merge activity calls = %d, want %d", merges, wantMerges)
+			}
+			for signal, n := range events {
+				if n > 0 {
+					t.Errorf("unconsumed %q events: %d", signal, n)
+				}
+			}
+		})
+	}
+}
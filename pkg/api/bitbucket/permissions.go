@@ -0,0 +1,154 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/tzrikka/timpani-api/pkg/bitbucket"
+)
+
+//revive:disable:exported
+const (
+	RepositoriesGetUserPermissionActivityName   = "bitbucket.repositories.getUserPermission"
+	RepositoriesListUserPermissionsActivityName = "bitbucket.repositories.listUserPermissions"
+	WorkspacesGetPermissionForUserActivityName  = "bitbucket.workspaces.getPermissionForUser"
+) //revive:enable:exported
+
+// PermissionNone is the permission of users who don't have an explicit permission.
+const PermissionNone = "none"
+
+// RepositoriesGetUserPermissionRequest is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-permissions-config-users-selected-user-id-get
+//
+// SelectedUser is the user's account ID or UUID.
+type RepositoriesGetUserPermissionRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+
+	Workspace    string `json:"workspace"`
+	RepoSlug     string `json:"repo_slug"`
+	SelectedUser string `json:"selected_user"`
+}
+
+// RepositoryUserPermission is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-permissions-config-users-selected-user-id-get
+type RepositoryUserPermission struct {
+	Permission string          `json:"permission"` // "admin", "write", "read", or "none".
+	User       *bitbucket.User `json:"user,omitempty"`
+}
+
+// RepositoriesListUserPermissionsRequest is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-permissions-config-users-get
+type RepositoriesListUserPermissionsRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+
+	Workspace string `json:"workspace"`
+	RepoSlug  string `json:"repo_slug"`
+
+	// https://developer.atlassian.com/cloud/bitbucket/rest/intro/#pagination
+	PageLen string `json:"pagelen,omitempty"`
+	Page    string `json:"page,omitempty"`
+
+	Next string `json:"next,omitempty"` // Populated and used only in Timpani, for pagination.
+}
+
+// RepositoriesListUserPermissionsResponse is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-permissions-config-users-get
+type RepositoriesListUserPermissionsResponse struct {
+	Values []RepositoryUserPermission `json:"values"`
+
+	// https://developer.atlassian.com/cloud/bitbucket/rest/intro/#pagination
+	Size    int    `json:"size,omitempty"`
+	PageLen int    `json:"pagelen,omitempty"`
+	Page    int    `json:"page,omitempty"`
+	Next    string `json:"next,omitempty"`
+}
+
+// WorkspacesGetPermissionForUserRequest is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-workspaces/#api-workspaces-workspace-permissions-get
+//
+// SelectedUser is the user's account ID or UUID (with curly braces).
+type WorkspacesGetPermissionForUserRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+
+	Workspace    string `json:"workspace"`
+	SelectedUser string `json:"selected_user"`
+}
+
+// WorkspaceUserPermission is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-workspaces/#api-workspaces-workspace-permissions-get
+type WorkspaceUserPermission struct {
+	Permission string          `json:"permission"` // "owner", "collaborator", "member", or "none".
+	User       *bitbucket.User `json:"user,omitempty"`
+}
+
+// RepositoriesGetUserPermissionActivity is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-permissions-config-users-selected-user-id-get
+//
+// If the user doesn't have an explicit permission in the repository (HTTP 404), this isn't
+// an error: the response's permission is [PermissionNone]. Note that users may still have
+// implicit access to the repository, via groups or workspace permissions.
+func (a *API) RepositoriesGetUserPermissionActivity(ctx context.Context, req RepositoriesGetUserPermissionRequest) (*RepositoryUserPermission, error) {
+	path := fmt.Sprintf("/repositories/%s/%s/permissions-config/users/%s", req.Workspace, req.RepoSlug, url.PathEscape(req.SelectedUser))
+
+	resp := new(RepositoryUserPermission)
+	err := a.httpGet(ctx, RepositoriesGetUserPermissionActivityName, req.ThrippyLinkID, path, nil, resp)
+	if isNotFound(err) {
+		return &RepositoryUserPermission{Permission: PermissionNone}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RepositoriesListUserPermissionsActivity is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-permissions-config-users-get
+func (a *API) RepositoriesListUserPermissionsActivity(
+	ctx context.Context,
+	req RepositoriesListUserPermissionsRequest,
+) (*RepositoriesListUserPermissionsResponse, error) {
+	path := fmt.Sprintf("/repositories/%s/%s/permissions-config/users", req.Workspace, req.RepoSlug)
+	path, query, err := paginatedQuery(RepositoriesListUserPermissionsActivityName, path, req.PageLen, req.Page, req.Next)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(RepositoriesListUserPermissionsResponse)
+	err = a.httpGet(ctx, RepositoriesListUserPermissionsActivityName, req.ThrippyLinkID, path, query, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// WorkspacesGetPermissionForUserActivity is based on:
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-workspaces/#api-workspaces-workspace-permissions-get
+//
+// If the user isn't a member of the workspace, this isn't an error:
+// the response's permission is [PermissionNone].
+func (a *API) WorkspacesGetPermissionForUserActivity(
+	ctx context.Context,
+	req WorkspacesGetPermissionForUserRequest,
+) (*WorkspaceUserPermission, error) {
+	path := fmt.Sprintf("/workspaces/%s/permissions", req.Workspace)
+	query := url.Values{}
+	field := "account_id"
+	if strings.HasPrefix(req.SelectedUser, "{") {
+		field = "uuid"
+	}
+	query.Set("q", fmt.Sprintf(`user.%s="%s"`, field, req.SelectedUser))
+
+	resp := new(struct {
+		Values []WorkspaceUserPermission `json:"values"`
+	})
+	err := a.httpGet(ctx, WorkspacesGetPermissionForUserActivityName, req.ThrippyLinkID, path, query, resp)
+	if isNotFound(err) || (err == nil && len(resp.Values) == 0) {
+		return &WorkspaceUserPermission{Permission: PermissionNone}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Values[0], nil
+}
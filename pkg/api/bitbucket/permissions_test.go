@@ -0,0 +1,203 @@
+package bitbucket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.temporal.io/sdk/testsuite"
+)
+
+// testCloudAPI returns an [API] whose Bitbucket Cloud API calls are sent to the given handler.
+func testCloudAPI(t *testing.T, h http.HandlerFunc) *API {
+	t.Helper()
+
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+
+	orig := cloudBaseURL
+	cloudBaseURL = s.URL + "/2.0"
+	t.Cleanup(func() { cloudBaseURL = orig })
+
+	return testLinkAPI(t, "bitbucket-user-token", map[string]string{"email": "user@example.com", "api_token": "token"})
+}
+
+func TestRepositoriesGetUserPermissionActivity(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		resp     string
+		want     string
+		wantUser bool
+		wantErr  bool
+	}{
+		{
+			name:     "explicit_permission",
+			status:   http.StatusOK,
+			resp:     `{"permission": "write", "user": {"account_id": "123", "display_name": "User"}}`,
+			want:     "write",
+			wantUser: true,
+		},
+		{
+			name:   "no_explicit_permission",
+			status: http.StatusNotFound,
+			resp:   `{"type": "error", "error": {"message": "Not found"}}`,
+			want:   PermissionNone,
+		},
+		{
+			name:    "forbidden",
+			status:  http.StatusForbidden,
+			resp:    `{"type": "error", "error": {"message": "Forbidden"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath := ""
+			a := testCloudAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.resp))
+			})
+
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.RepositoriesGetUserPermissionActivity)
+			req := RepositoriesGetUserPermissionRequest{Workspace: "ws", RepoSlug: "repo", SelectedUser: "123"}
+			val, err := env.ExecuteActivity(a.RepositoriesGetUserPermissionActivity, req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepositoriesGetUserPermissionActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if want := "/2.0/repositories/ws/repo/permissions-config/users/123"; gotPath != want {
+				t.Errorf("URL path = %q, want %q", gotPath, want)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			resp := new(RepositoryUserPermission)
+			if err := val.Get(resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Permission != tt.want {
+				t.Errorf("permission = %q, want %q", resp.Permission, tt.want)
+			}
+			if (resp.User != nil) != tt.wantUser {
+				t.Errorf("user = %+v, wantUser %v", resp.User, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestRepositoriesListUserPermissionsActivity(t *testing.T) {
+	var gotQueries []string
+	a := testCloudAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		gotQueries = append(gotQueries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"size": 2, "pagelen": 1, "page": 2, "values": [{"permission": "read"}]}`))
+			return
+		}
+		next := "http://" + r.Host + "/2.0/repositories/ws/repo/permissions-config/users?pagelen=1&page=2"
+		_, _ = w.Write([]byte(`{"size": 2, "pagelen": 1, "page": 1, "next": "` + next + `", "values": [{"permission": "admin"}]}`))
+	})
+
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.RepositoriesListUserPermissionsActivity)
+	req := RepositoriesListUserPermissionsRequest{Workspace: "ws", RepoSlug: "repo", PageLen: "1"}
+
+	var perms []string
+	for range 2 {
+		val, err := env.ExecuteActivity(a.RepositoriesListUserPermissionsActivity, req)
+		if err != nil {
+			t.Fatalf("RepositoriesListUserPermissionsActivity() error = %v", err)
+		}
+
+		resp := new(RepositoriesListUserPermissionsResponse)
+		if err := val.Get(resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range resp.Values {
+			perms = append(perms, v.Permission)
+		}
+		req.Next = resp.Next
+	}
+
+	if req.Next != "" {
+		t.Errorf("last page next = %q, want \"\"", req.Next)
+	}
+	if len(perms) != 2 || perms[0] != "admin" || perms[1] != "read" {
+		t.Errorf("permissions = %v, want [admin read]", perms)
+	}
+	if want := []string{"pagelen=1", "page=2&pagelen=1"}; len(gotQueries) != 2 || gotQueries[0] != want[0] || gotQueries[1] != want[1] {
+		t.Errorf("URL queries = %q, want %q", gotQueries, want)
+	}
+}
+
+func TestWorkspacesGetPermissionForUserActivity(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		resp      string
+		wantQuery string
+		want      string
+	}{
+		{
+			name:      "account_id",
+			user:      "123",
+			resp:      `{"values": [{"permission": "owner", "user": {"account_id": "123"}}]}`,
+			wantQuery: `user.account_id="123"`,
+			want:      "owner",
+		},
+		{
+			name:      "uuid",
+			user:      "{abc}",
+			resp:      `{"values": [{"permission": "member", "user": {"uuid": "{abc}"}}]}`,
+			wantQuery: `user.uuid="{abc}"`,
+			want:      "member",
+		},
+		{
+			name:      "not_a_member",
+			user:      "123",
+			resp:      `{"values": []}`,
+			wantQuery: `user.account_id="123"`,
+			want:      PermissionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath, gotQuery := "", ""
+			a := testCloudAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotQuery = r.URL.Path, r.URL.Query().Get("q")
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.resp))
+			})
+
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.WorkspacesGetPermissionForUserActivity)
+			req := WorkspacesGetPermissionForUserRequest{Workspace: "ws", SelectedUser: tt.user}
+			val, err := env.ExecuteActivity(a.WorkspacesGetPermissionForUserActivity, req)
+			if err != nil {
+				t.Fatalf("WorkspacesGetPermissionForUserActivity() error = %v", err)
+			}
+
+			if want := "/2.0/workspaces/ws/permissions"; gotPath != want {
+				t.Errorf("URL path = %q, want %q", gotPath, want)
+			}
+			if gotQuery != tt.wantQuery {
+				t.Errorf("q = %q, want %q", gotQuery, tt.wantQuery)
+			}
+
+			resp := new(WorkspaceUserPermission)
+			if err := val.Get(resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Permission != tt.want {
+				t.Errorf("permission = %q, want %q", resp.Permission, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+// ProbeLink checks that the credentials of a Thrippy link are still valid, using the cheapest
+// authenticated Bitbucket API call: https://developer.atlassian.com/cloud/bitbucket/rest/api-group-users/#api-user-get
+// (or https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-repository/#api-api-latest-profile-recent-repos-get
+// in Bitbucket Server links).
+//
+// Unlike the rest of this package, this function runs outside of Temporal activities.
+func ProbeLink(ctx context.Context, template string, secrets map[string]string) error {
+	apiURL, auth := BaseURL+"/user", authHeader(secrets)
+	if template == ServerTemplate {
+		baseURL, err := serverBaseURL(secrets)
+		if err != nil {
+			return err
+		}
+		apiURL, auth = baseURL+"/profile/recent/repos?limit=1", serverAuthHeader(secrets)
+	}
+
+	_, _, _, err := client.HTTPRequest(ctx, http.MethodGet, apiURL, auth, client.AcceptJSON, "", nil, client.WithTimeout(client.Timeout))
+	return err
+}
@@ -6,26 +6,35 @@ import (
 	"github.com/urfave/cli/v3"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 
 	"github.com/tzrikka/timpani-api/pkg/bitbucket"
+	"github.com/tzrikka/timpani/internal/taskqueue"
 	"github.com/tzrikka/timpani/internal/thrippy"
 )
 
 type API struct {
-	thrippy thrippy.LinkClient
+	thrippy   thrippy.LinkClient
+	taskQueue string // For activities of Timpani workflows, see [taskqueue.Activities].
 }
 
 // Register exposes Temporal activities and workflows via the Timpani worker.
-func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
-	id, ok := thrippy.LinkID(cmd, "Bitbucket")
-	if !ok {
-		return
+// It returns an error, without registering anything, if the service's Thrippy
+// link ID is missing or invalid.
+func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) error {
+	id, err := thrippy.LinkID(cmd, "Bitbucket")
+	if err != nil {
+		return err
 	}
 
-	a := API{thrippy: thrippy.NewLinkClient(ctx, id, cmd)}
+	a := API{thrippy: thrippy.NewLinkClient(ctx, id, cmd), taskQueue: taskqueue.Service(cmd, "bitbucket")}
 
+	registerActivity(w, a.CommitsCreateCommentActivity, CommitsCreateCommentActivityName)
 	registerActivity(w, a.CommitsDiffActivity, bitbucket.CommitsDiffActivityName)
+	registerActivity(w, a.CommitsDiffToFileActivity, CommitsDiffToFileActivityName)
 	registerActivity(w, a.CommitsDiffstatActivity, bitbucket.CommitsDiffstatActivityName)
+	registerActivity(w, a.CommitsGetActivity, CommitsGetActivityName)
+	registerActivity(w, a.CommitsListCommentsActivity, CommitsListCommentsActivityName)
 
 	registerActivity(w, a.PullRequestsApproveActivity, bitbucket.PullRequestsApproveActivityName)
 	registerActivity(w, a.PullRequestsCreateCommentActivity, bitbucket.PullRequestsCreateCommentActivityName)
@@ -43,13 +52,31 @@ func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
 	registerActivity(w, a.PullRequestsUpdateActivity, bitbucket.PullRequestsUpdateActivityName)
 	registerActivity(w, a.PullRequestsUpdateCommentActivity, bitbucket.PullRequestsUpdateCommentActivityName)
 
+	registerActivity(w, a.RepositoriesGetUserPermissionActivity, RepositoriesGetUserPermissionActivityName)
+	registerActivity(w, a.RepositoriesListUserPermissionsActivity, RepositoriesListUserPermissionsActivityName)
+
+	registerActivity(w, a.ServerPullRequestsApproveActivity, ServerPullRequestsApproveActivityName)
+	registerActivity(w, a.ServerPullRequestsCreateCommentActivity, ServerPullRequestsCreateCommentActivityName)
+	registerActivity(w, a.ServerPullRequestsGetActivity, ServerPullRequestsGetActivityName)
+	registerActivity(w, a.ServerPullRequestsListActivity, ServerPullRequestsListActivityName)
+	registerActivity(w, a.ServerPullRequestsMergeActivity, ServerPullRequestsMergeActivityName)
+
 	registerActivity(w, a.SourceGetFileActivity, bitbucket.SourceGetFileActivityName)
 
 	registerActivity(w, a.UsersGetActivity, bitbucket.UsersGetActivityName)
 
+	registerActivity(w, a.WorkspacesGetPermissionForUserActivity, WorkspacesGetPermissionForUserActivityName)
 	registerActivity(w, a.WorkspacesListMembersActivity, bitbucket.WorkspacesListMembersActivityName)
+
+	registerWorkflow(w, a.TimpaniMergeWhenApprovedWorkflow, TimpaniMergeWhenApprovedWorkflowName)
+
+	return nil
 }
 
 func registerActivity(w worker.Worker, f any, name string) {
 	w.RegisterActivityWithOptions(f, activity.RegisterOptions{Name: name})
 }
+
+func registerWorkflow(w worker.Worker, f any, name string) {
+	w.RegisterWorkflowWithOptions(f, workflow.RegisterOptions{Name: name})
+}
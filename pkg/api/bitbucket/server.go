@@ -0,0 +1,219 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+const (
+	// ServerTemplate is the Thrippy link template of Bitbucket Server / Data Center.
+	// Its link secrets contain "base_url", and either "token" (an HTTP access token)
+	// or "username" and "password".
+	ServerTemplate = "bitbucket-server"
+
+	serverAPIPath = "/rest/api/1.0"
+)
+
+// Bitbucket Server / Data Center activity names. They are Timpani-specific,
+// because the REST API of Bitbucket Server is different from Bitbucket Cloud's.
+const (
+	ServerPullRequestsApproveActivityName       = "bitbucket.server.pullrequests.approve"
+	ServerPullRequestsCreateCommentActivityName = "bitbucket.server.pullrequests.createComment"
+	ServerPullRequestsGetActivityName           = "bitbucket.server.pullrequests.get"
+	ServerPullRequestsListActivityName          = "bitbucket.server.pullrequests.list"
+	ServerPullRequestsMergeActivityName         = "bitbucket.server.pullrequests.merge"
+)
+
+// ServerPullRequestsRequest identifies a pull request in Bitbucket Server / Data Center.
+type ServerPullRequestsRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+
+	ProjectKey    string `json:"project_key"`
+	RepoSlug      string `json:"repo_slug"`
+	PullRequestID string `json:"pull_request_id"`
+}
+
+// ServerPullRequestsCreateCommentRequest is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-pullrequestid-comments-post
+type ServerPullRequestsCreateCommentRequest struct {
+	ServerPullRequestsRequest
+
+	Markdown string `json:"markdown"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+// ServerPullRequestsListRequest is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-get
+type ServerPullRequestsListRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+
+	ProjectKey string `json:"project_key"`
+	RepoSlug   string `json:"repo_slug"`
+	State      string `json:"state,omitempty"` // "OPEN" (default), "DECLINED", "MERGED", or "ALL".
+
+	// https://developer.atlassian.com/server/bitbucket/rest/v1000/intro/#paged-apis
+	Start string `json:"start,omitempty"`
+	Limit string `json:"limit,omitempty"`
+}
+
+// ServerPullRequestsListResponse is a single page of pull requests. To get the next
+// page, set the request's Start field to NextPageStart, unless IsLastPage is true.
+type ServerPullRequestsListResponse struct {
+	ServerPage
+
+	Values []map[string]any `json:"values"`
+}
+
+// ServerPage contains the pagination details of Bitbucket Server list responses, based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/intro/#paged-apis
+type ServerPage struct {
+	Size          int  `json:"size"`
+	Limit         int  `json:"limit"`
+	Start         int  `json:"start"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart,omitempty"`
+}
+
+// ServerPullRequestsMergeRequest is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-pullrequestid-merge-post
+//
+// Version is required: it is the "version" field of the pull request, as returned by
+// [API.ServerPullRequestsGetActivity], to prevent merging a concurrently-modified one.
+type ServerPullRequestsMergeRequest struct {
+	ServerPullRequestsRequest
+
+	Version    int    `json:"version"`
+	Message    string `json:"message,omitempty"`
+	StrategyID string `json:"strategy_id,omitempty"`
+}
+
+type serverCommentBody struct {
+	Text   string                 `json:"text"`
+	Parent *prCreateCommentParent `json:"parent,omitempty"`
+}
+
+type serverMergeBody struct {
+	Message    string `json:"message,omitempty"`
+	StrategyID string `json:"strategyId,omitempty"`
+}
+
+func (r ServerPullRequestsRequest) path(suffix string) string {
+	return fmt.Sprintf("/projects/%s/repos/%s/pull-requests/%s%s", r.ProjectKey, r.RepoSlug, r.PullRequestID, suffix)
+}
+
+// ServerPullRequestsApproveActivity is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-pullrequestid-approve-post
+func (a *API) ServerPullRequestsApproveActivity(ctx context.Context, req ServerPullRequestsRequest) error {
+	t := time.Now().UTC()
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.path("/approve"), nil, nil)
+	otel.IncrementAPICallCounter(t, ServerPullRequestsApproveActivityName, err)
+
+	return err
+}
+
+// ServerPullRequestsCreateCommentActivity is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-pullrequestid-comments-post
+func (a *API) ServerPullRequestsCreateCommentActivity(ctx context.Context, req ServerPullRequestsCreateCommentRequest) (map[string]any, error) {
+	t := time.Now().UTC()
+	body := &serverCommentBody{Text: req.Markdown}
+	if req.ParentID != "" {
+		id, err := strconv.Atoi(req.ParentID)
+		if err != nil {
+			otel.IncrementAPICallCounter(t, ServerPullRequestsCreateCommentActivityName, err)
+			return nil, temporal.NewNonRetryableApplicationError("invalid parent ID", fmt.Sprintf("%T", err), err, req.ParentID)
+		}
+		body.Parent = &prCreateCommentParent{ID: id}
+	}
+
+	resp := map[string]any{}
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.path("/comments"), body, &resp)
+	otel.IncrementAPICallCounter(t, ServerPullRequestsCreateCommentActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ServerPullRequestsGetActivity is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-pullrequestid-get
+func (a *API) ServerPullRequestsGetActivity(ctx context.Context, req ServerPullRequestsRequest) (map[string]any, error) {
+	resp := map[string]any{}
+	if err := a.httpGet(ctx, ServerPullRequestsGetActivityName, req.ThrippyLinkID, req.path(""), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ServerPullRequestsListActivity is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-get
+func (a *API) ServerPullRequestsListActivity(ctx context.Context, req ServerPullRequestsListRequest) (*ServerPullRequestsListResponse, error) {
+	path := fmt.Sprintf("/projects/%s/repos/%s/pull-requests", req.ProjectKey, req.RepoSlug)
+	query := serverPaginatedQuery(req.Start, req.Limit)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	resp := new(ServerPullRequestsListResponse)
+	if err := a.httpGet(ctx, ServerPullRequestsListActivityName, req.ThrippyLinkID, path, query, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ServerPullRequestsMergeActivity is based on:
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-pull-requests/#api-api-latest-projects-projectkey-repos-repositoryslug-pull-requests-pullrequestid-merge-post
+func (a *API) ServerPullRequestsMergeActivity(ctx context.Context, req ServerPullRequestsMergeRequest) (map[string]any, error) {
+	path := req.path("/merge?version=" + strconv.Itoa(req.Version))
+	body := &serverMergeBody{Message: req.Message, StrategyID: req.StrategyID}
+
+	t := time.Now().UTC()
+	resp := map[string]any{}
+	err := a.httpPost(ctx, req.ThrippyLinkID, path, body, &resp)
+	otel.IncrementAPICallCounter(t, ServerPullRequestsMergeActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func serverPaginatedQuery(start, limit string) url.Values {
+	query := url.Values{}
+	query.Set("limit", "100") // Default = 25, but we prefer to minimize the number of API calls.
+
+	if limit != "" {
+		query.Set("limit", limit)
+	}
+	if start != "" {
+		query.Set("start", start)
+	}
+
+	return query
+}
+
+// serverBaseURL returns the REST API base URL of a Bitbucket Server link.
+func serverBaseURL(secrets map[string]string) (string, error) {
+	baseURL := strings.TrimSuffix(secrets["base_url"], "/")
+	if baseURL == "" {
+		msg := "missing base URL in Bitbucket Server link"
+		return "", temporal.NewNonRetryableApplicationError(msg, "error", nil)
+	}
+	return baseURL + serverAPIPath, nil
+}
+
+// serverAuthHeader returns the value of an HTTP "Authorization" header for Bitbucket Server links.
+func serverAuthHeader(secrets map[string]string) string {
+	if token := secrets["token"]; token != "" {
+		return token
+	}
+	return fmt.Sprintf("Basic %s:%s", secrets["username"], secrets["password"])
+}
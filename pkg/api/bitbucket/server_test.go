@@ -0,0 +1,243 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"google.golang.org/grpc"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+type thrippyServer struct {
+	thrippypb.UnimplementedThrippyServiceServer
+
+	template string
+	secrets  map[string]string
+}
+
+func (s *thrippyServer) GetLink(_ context.Context, _ *thrippypb.GetLinkRequest) (*thrippypb.GetLinkResponse, error) {
+	return thrippypb.GetLinkResponse_builder{Template: new(s.template)}.Build(), nil
+}
+
+func (s *thrippyServer) GetCredentials(_ context.Context, _ *thrippypb.GetCredentialsRequest) (*thrippypb.GetCredentialsResponse, error) {
+	return thrippypb.GetCredentialsResponse_builder{Credentials: s.secrets}.Build(), nil
+}
+
+// testServerAPI returns an [API] whose Thrippy link points to the given Bitbucket Server base URL.
+func testServerAPI(t *testing.T, baseURL string) *API {
+	t.Helper()
+	return testLinkAPI(t, ServerTemplate, map[string]string{"base_url": baseURL + "/", "token": "token"})
+}
+
+// testLinkAPI returns an [API] whose Thrippy link has the given template and secrets.
+func testLinkAPI(t *testing.T, template string, secrets map[string]string) *API {
+	t.Helper()
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gs := grpc.NewServer()
+	thrippypb.RegisterThrippyServiceServer(gs, &thrippyServer{template: template, secrets: secrets})
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dev"},
+			&cli.StringFlag{Name: "thrippy-grpc-address"},
+		},
+	}
+	_ = cmd.Set("dev", "true")
+	_ = cmd.Set("thrippy-grpc-address", lis.Addr().String())
+
+	return &API{thrippy: thrippy.NewLinkClient(t.Context(), "link ID", cmd)}
+}
+
+type serverRequest struct {
+	method, path, query, auth string
+	body                      map[string]any
+}
+
+// testServer returns a fake Bitbucket Server which records the last request it received.
+func testServer(t *testing.T, resp string) (*httptest.Server, *serverRequest) {
+	t.Helper()
+
+	got := new(serverRequest)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method, got.path, got.query, got.auth = r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization")
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			_ = json.Unmarshal(b, &got.body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(s.Close)
+
+	return s, got
+}
+
+func TestServerPullRequestsActivities(t *testing.T) {
+	pr := ServerPullRequestsRequest{ProjectKey: "PROJ", RepoSlug: "repo", PullRequestID: "12"}
+	prPath := "/rest/api/1.0/projects/PROJ/repos/repo/pull-requests/12"
+
+	tests := []struct {
+		name      string
+		activity  string // Method name.
+		req       any
+		wantMeth  string
+		wantPath  string
+		wantQuery string
+		wantBody  map[string]any
+	}{
+		{
+			name:     "approve",
+			activity: "ServerPullRequestsApproveActivity",
+			req:      pr,
+			wantMeth: http.MethodPost,
+			wantPath: prPath + "/approve",
+		},
+		{
+			name:     "create_comment",
+			activity: "ServerPullRequestsCreateCommentActivity",
+			req:      ServerPullRequestsCreateCommentRequest{ServerPullRequestsRequest: pr, Markdown: "LGTM", ParentID: "3"},
+			wantMeth: http.MethodPost,
+			wantPath: prPath + "/comments",
+			wantBody: map[string]any{"text": "LGTM", "parent": map[string]any{"id": float64(3)}},
+		},
+		{
+			name:     "get",
+			activity: "ServerPullRequestsGetActivity",
+			req:      pr,
+			wantMeth: http.MethodGet,
+			wantPath: prPath,
+		},
+		{
+			name:      "merge",
+			activity:  "ServerPullRequestsMergeActivity",
+			req:       ServerPullRequestsMergeRequest{ServerPullRequestsRequest: pr, Version: 5, StrategyID: "squash"},
+			wantMeth:  http.MethodPost,
+			wantPath:  prPath + "/merge",
+			wantQuery: "version=5",
+			wantBody:  map[string]any{"strategyId": "squash"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, got := testServer(t, "{}")
+			a := testServerAPI(t, s.URL)
+
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			act := reflect.ValueOf(a).MethodByName(tt.activity).Interface()
+			env.RegisterActivityWithOptions(act, activity.RegisterOptions{Name: tt.activity})
+			if _, err := env.ExecuteActivity(tt.activity, tt.req); err != nil {
+				t.Fatalf("%s activity error = %v", tt.name, err)
+			}
+
+			if got.method != tt.wantMeth {
+				t.Errorf("HTTP method = %q, want %q", got.method, tt.wantMeth)
+			}
+			if got.path != tt.wantPath {
+				t.Errorf("URL path = %q, want %q", got.path, tt.wantPath)
+			}
+			if got.query != tt.wantQuery {
+				t.Errorf("URL query = %q, want %q", got.query, tt.wantQuery)
+			}
+			if got.auth != "Bearer token" {
+				t.Errorf("Authorization header = %q, want %q", got.auth, "Bearer token")
+			}
+			if tt.wantBody != nil {
+				gotJSON, _ := json.Marshal(got.body)
+				wantJSON, _ := json.Marshal(tt.wantBody)
+				if string(gotJSON) != string(wantJSON) {
+					t.Errorf("JSON body = %s, want %s", gotJSON, wantJSON)
+				}
+			}
+		})
+	}
+}
+
+func TestServerPullRequestsListActivity(t *testing.T) {
+	s, got := testServer(t, `{"size": 1, "limit": 1, "start": 0, "isLastPage": false, "nextPageStart": 1, "values": [{"id": 12}]}`)
+	a := testServerAPI(t, s.URL)
+
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.ServerPullRequestsListActivity)
+	req := ServerPullRequestsListRequest{ProjectKey: "PROJ", RepoSlug: "repo", State: "ALL", Limit: "1"}
+	val, err := env.ExecuteActivity(a.ServerPullRequestsListActivity, req)
+	if err != nil {
+		t.Fatalf("ServerPullRequestsListActivity() error = %v", err)
+	}
+
+	if want := "/rest/api/1.0/projects/PROJ/repos/repo/pull-requests"; got.path != want {
+		t.Errorf("URL path = %q, want %q", got.path, want)
+	}
+	if want := "limit=1&state=ALL"; got.query != want {
+		t.Errorf("URL query = %q, want %q", got.query, want)
+	}
+
+	resp := new(ServerPullRequestsListResponse)
+	if err := val.Get(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsLastPage || resp.NextPageStart != 1 || len(resp.Values) != 1 {
+		t.Errorf("ServerPullRequestsListActivity() = %+v", resp)
+	}
+}
+
+func TestServerAuthHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		want    string
+	}{
+		{
+			name:    "token",
+			secrets: map[string]string{"token": "abc"},
+			want:    "abc",
+		},
+		{
+			name:    "username_and_password",
+			secrets: map[string]string{"username": "user", "password": "pass"},
+			want:    "Basic user:pass",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverAuthHeader(tt.secrets); got != tt.want {
+				t.Errorf("serverAuthHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerBaseURL(t *testing.T) {
+	got, err := serverBaseURL(map[string]string{"base_url": "https://bitbucket.example.com/"})
+	if err != nil {
+		t.Fatalf("serverBaseURL() error = %v", err)
+	}
+	if want := "https://bitbucket.example.com/rest/api/1.0"; got != want {
+		t.Errorf("serverBaseURL() = %q, want %q", got, want)
+	}
+
+	if _, err := serverBaseURL(map[string]string{}); err == nil {
+		t.Error("serverBaseURL() error = nil")
+	}
+}
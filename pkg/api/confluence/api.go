@@ -0,0 +1,125 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+const (
+	URLPathPrefix = "/wiki/api/v2"
+)
+
+// provider identifies Confluence API calls (see [client.WithProvider]).
+var provider = client.WithProvider(client.Provider{Name: "confluence", ErrorDetails: errorDetails})
+
+// errorDetails extracts the request ID from Confluence HTTP error responses.
+// Confluence doesn't specify error codes, only human-readable messages.
+func errorDetails(header http.Header, _ []byte) (string, string) {
+	return "", header.Get("Atl-Traceid")
+}
+
+// httpGet is a Confluence-specific HTTP GET wrapper for [client.HTTPRequestFull].
+func (a *API) httpGet(ctx context.Context, name, pathSuffix string, query url.Values, jsonResp any) error {
+	t := time.Now().UTC()
+	err := a.httpRequest(ctx, pathSuffix, http.MethodGet, query, jsonResp)
+	otel.IncrementAPICallCounter(t, name, err)
+
+	if err != nil {
+		return notFoundError(err, pathSuffix)
+	}
+	return nil
+}
+
+// httpPost is a Confluence-specific HTTP POST wrapper for [client.HTTPRequestFull].
+func (a *API) httpPost(ctx context.Context, name, pathSuffix string, jsonBody, jsonResp any) error {
+	t := time.Now().UTC()
+	err := a.httpRequest(ctx, pathSuffix, http.MethodPost, jsonBody, jsonResp)
+	otel.IncrementAPICallCounter(t, name, err)
+	return err
+}
+
+// httpPut is a Confluence-specific HTTP PUT wrapper for [client.HTTPRequestFull].
+func (a *API) httpPut(ctx context.Context, name, pathSuffix string, jsonBody, jsonResp any) error {
+	t := time.Now().UTC()
+	err := a.httpRequest(ctx, pathSuffix, http.MethodPut, jsonBody, jsonResp)
+	otel.IncrementAPICallCounter(t, name, err)
+	return err
+}
+
+// notFoundError converts HTTP 404 errors into non-retryable errors.
+func notFoundError(err error, pathSuffix string) error {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return temporal.NewNonRetryableApplicationError(apiErr.Error(), "ConfluenceAPIError", err, pathSuffix)
+	}
+	return err
+}
+
+func (a *API) httpRequest(ctx context.Context, pathSuffix, method string, queryOrJSONBody, jsonResp any) error {
+	l, apiURL, auth, err := a.httpRequestPrep(ctx, pathSuffix)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.HTTPRequestFull(ctx, method, apiURL, auth, client.AcceptJSON, client.ContentJSON, queryOrJSONBody, provider)
+	if err != nil {
+		l.Error("HTTP request error", slog.Any("error", err),
+			slog.String("http_method", method), slog.String("url", apiURL))
+		return client.NewApplicationError(err)
+	}
+
+	l.Info("sent HTTP request", slog.String("link_id", a.thrippy.LinkID),
+		slog.String("http_method", method), slog.String("url", apiURL))
+
+	if jsonResp == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(resp.Body, jsonResp); err != nil {
+		msg := "failed to decode HTTP response's JSON body"
+		l.Error(msg, slog.Any("error", err), slog.String("url", apiURL))
+		msg = fmt.Sprintf("%s: %v", msg, err)
+		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp.Body))
+	}
+
+	return nil
+}
+
+func (a *API) httpRequestPrep(ctx context.Context, pathSuffix string) (l log.Logger, apiURL, auth string, err error) {
+	l = activity.GetLogger(ctx)
+
+	var secrets map[string]string
+	secrets, err = a.thrippy.LinkCreds(ctx, "")
+	if err != nil {
+		return l, "", "", err
+	}
+
+	apiURL, err = url.JoinPath(secrets["base_url"], URLPathPrefix, pathSuffix)
+	if err != nil {
+		l.Error("failed to construct Confluence API URL", slog.Any("error", err),
+			slog.String("base_url", secrets["base_url"]), slog.String("path", URLPathPrefix+pathSuffix))
+		err = temporal.NewNonRetryableApplicationError(err.Error(), fmt.Sprintf("%T", err), err, URLPathPrefix, pathSuffix)
+		return l, "", "", err
+	}
+
+	return l, apiURL, authHeader(secrets), err
+}
+
+// authHeader returns the value of an HTTP "Authorization" header, based on the given link secrets.
+// Like Bitbucket and Jira user tokens, this is an Atlassian account's email address and API token.
+func authHeader(secrets map[string]string) string {
+	return fmt.Sprintf("Basic %s:%s", secrets["email"], secrets["api_token"])
+}
@@ -0,0 +1,53 @@
+package confluence
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"google.golang.org/grpc"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+type thrippyServer struct {
+	thrippypb.UnimplementedThrippyServiceServer
+
+	secrets map[string]string
+}
+
+func (s *thrippyServer) GetCredentials(_ context.Context, _ *thrippypb.GetCredentialsRequest) (*thrippypb.GetCredentialsResponse, error) {
+	return thrippypb.GetCredentialsResponse_builder{Credentials: s.secrets}.Build(), nil
+}
+
+// testAPI returns an [API] whose Thrippy link points to the given Confluence site URL.
+func testAPI(t *testing.T, baseURL string) *API {
+	t.Helper()
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gs := grpc.NewServer()
+	secrets := map[string]string{"base_url": baseURL, "email": "user@example.com", "api_token": "token"}
+	thrippypb.RegisterThrippyServiceServer(gs, &thrippyServer{secrets: secrets})
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dev"},
+			&cli.StringFlag{Name: "thrippy-grpc-address"},
+		},
+	}
+	_ = cmd.Set("dev", "true")
+	_ = cmd.Set("thrippy-grpc-address", lis.Addr().String())
+
+	return &API{thrippy: thrippy.NewLinkClient(t.Context(), "link ID", cmd)}
+}
@@ -0,0 +1,186 @@
+package confluence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+//revive:disable:exported
+const (
+	PagesCreateActivityName = "confluence.pages.create"
+	PagesGetActivityName    = "confluence.pages.get"
+	PagesUpdateActivityName = "confluence.pages.update"
+) //revive:enable:exported
+
+// defaultRepresentation is the default format of page bodies in requests.
+const defaultRepresentation = "storage"
+
+// Page is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-id-get
+type Page struct {
+	ID        string              `json:"id"`
+	Status    string              `json:"status"`
+	Title     string              `json:"title"`
+	SpaceID   string              `json:"spaceId"`
+	ParentID  string              `json:"parentId,omitempty"`
+	AuthorID  string              `json:"authorId,omitempty"`
+	CreatedAt string              `json:"createdAt,omitempty"`
+	Version   PageVersion         `json:"version"`
+	Body      map[string]PageBody `json:"body,omitempty"` // Key = body format, e.g. "storage".
+	Links     map[string]string   `json:"_links,omitempty"`
+}
+
+// PageVersion is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-id-get
+type PageVersion struct {
+	Number    int    `json:"number"`
+	Message   string `json:"message,omitempty"`
+	MinorEdit bool   `json:"minorEdit,omitempty"`
+	AuthorID  string `json:"authorId,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// PageBody is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-post
+type PageBody struct {
+	Representation string `json:"representation"` // "storage" (default) or "atlas_doc_format".
+	Value          string `json:"value"`
+}
+
+// PagesCreateRequest is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-post
+type PagesCreateRequest struct {
+	SpaceID  string   `json:"space_id"`
+	Status   string   `json:"status,omitempty"` // "current" (default) or "draft".
+	Title    string   `json:"title,omitempty"`
+	ParentID string   `json:"parent_id,omitempty"`
+	Body     PageBody `json:"body"`
+}
+
+type pagesCreateBody struct {
+	SpaceID  string   `json:"spaceId"`
+	Status   string   `json:"status,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	ParentID string   `json:"parentId,omitempty"`
+	Body     PageBody `json:"body"`
+}
+
+// PagesCreateActivity is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-post
+func (a *API) PagesCreateActivity(ctx context.Context, req PagesCreateRequest) (*Page, error) {
+	body := pagesCreateBody{
+		SpaceID:  req.SpaceID,
+		Status:   req.Status,
+		Title:    req.Title,
+		ParentID: req.ParentID,
+		Body:     pageBody(req.Body),
+	}
+
+	resp := new(Page)
+	if err := a.httpPost(ctx, PagesCreateActivityName, "pages", body, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PagesGetRequest is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-id-get
+type PagesGetRequest struct {
+	PageID     string `json:"page_id"`
+	BodyFormat string `json:"body_format,omitempty"` // E.g. "storage", "atlas_doc_format", "view".
+}
+
+// PagesGetActivity is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-id-get
+func (a *API) PagesGetActivity(ctx context.Context, req PagesGetRequest) (*Page, error) {
+	query := url.Values{}
+	if req.BodyFormat != "" {
+		query.Set("body-format", req.BodyFormat)
+	}
+
+	resp := new(Page)
+	if err := a.httpGet(ctx, PagesGetActivityName, "pages/"+req.PageID, query, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PagesUpdateRequest is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-id-put
+//
+// The activity fetches the page's current version number, and increments it. If BaseVersion
+// is not 0, it's the version on which the update is based, and the activity fails without
+// retries if the page has a different version, i.e. it was modified by someone else.
+type PagesUpdateRequest struct {
+	PageID         string   `json:"page_id"`
+	Status         string   `json:"status,omitempty"` // Default = "current".
+	Title          string   `json:"title"`
+	Body           PageBody `json:"body"`
+	VersionMessage string   `json:"version_message,omitempty"`
+	BaseVersion    int      `json:"base_version,omitempty"`
+}
+
+type pagesUpdateBody struct {
+	ID      string      `json:"id"`
+	Status  string      `json:"status"`
+	Title   string      `json:"title"`
+	Body    PageBody    `json:"body"`
+	Version PageVersion `json:"version"`
+}
+
+// PagesUpdateActivity is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-page/#api-pages-id-put
+//
+// Confluence rejects updates whose version number isn't exactly 1 more than the page's
+// current version (HTTP 409). Such conflicts, e.g. concurrent updates of the same page
+// by different workflows, are retryable: each attempt refreshes the version number.
+func (a *API) PagesUpdateActivity(ctx context.Context, req PagesUpdateRequest) (*Page, error) {
+	current, err := a.PagesGetActivity(ctx, PagesGetRequest{PageID: req.PageID})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.BaseVersion != 0 && req.BaseVersion != current.Version.Number {
+		msg := fmt.Sprintf("Confluence page modified since version %d (current version: %d)", req.BaseVersion, current.Version.Number)
+		return nil, temporal.NewNonRetryableApplicationError(msg, "ConfluenceVersionConflict", nil, req.PageID, current.Version.Number)
+	}
+
+	status := req.Status
+	if status == "" {
+		status = "current"
+	}
+
+	body := pagesUpdateBody{
+		ID:      req.PageID,
+		Status:  status,
+		Title:   req.Title,
+		Body:    pageBody(req.Body),
+		Version: PageVersion{Number: current.Version.Number + 1, Message: req.VersionMessage},
+	}
+
+	resp := new(Page)
+	if err := a.httpPut(ctx, PagesUpdateActivityName, "pages/"+req.PageID, body, resp); err != nil {
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+			msg := fmt.Sprintf("Confluence page version %d already exists", body.Version.Number)
+			return nil, temporal.NewApplicationErrorWithCause(msg, "ConfluenceVersionConflict", err, req.PageID)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// pageBody sets the default representation of a page body, if it's missing.
+func pageBody(b PageBody) PageBody {
+	if b.Representation == "" {
+		b.Representation = defaultRepresentation
+	}
+	return b
+}
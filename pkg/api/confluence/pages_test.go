@@ -0,0 +1,153 @@
+package confluence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestPagesCreateActivity(t *testing.T) {
+	var got pagesCreateBody
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/wiki/api/v2/pages" {
+			t.Errorf("API call = %s %s, want POST /wiki/api/v2/pages", r.Method, r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user@example.com" || pass != "token" {
+			t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(`{"id": "123", "title": "Incident", "version": {"number": 1}}`))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.PagesCreateActivity)
+
+	req := PagesCreateRequest{SpaceID: "456", Title: "Incident", Body: PageBody{Value: "<p>Timeline</p>"}}
+	v, err := env.ExecuteActivity(a.PagesCreateActivity, req)
+	if err != nil {
+		t.Fatalf("PagesCreateActivity() error = %v", err)
+	}
+
+	page := new(Page)
+	if err := v.Get(page); err != nil {
+		t.Fatal(err)
+	}
+	if page.ID != "123" || page.Version.Number != 1 {
+		t.Errorf("PagesCreateActivity() = %+v", page)
+	}
+
+	want := pagesCreateBody{SpaceID: "456", Title: "Incident", Body: PageBody{Representation: "storage", Value: "<p>Timeline</p>"}}
+	if got != want {
+		t.Errorf("request body = %+v, want %+v", got, want)
+	}
+}
+
+func TestPagesUpdateActivity(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseVersion   int
+		conflicts     int
+		wantVersions  []int
+		wantErr       bool
+		wantRetryable bool
+	}{
+		{
+			name:         "no_conflict",
+			wantVersions: []int{6},
+		},
+		{
+			name:         "expected_base_version",
+			baseVersion:  5,
+			wantVersions: []int{6},
+		},
+		{
+			name:        "stale_base_version",
+			baseVersion: 4,
+			wantErr:     true,
+		},
+		{
+			name:          "concurrent_update",
+			conflicts:     1,
+			wantVersions:  []int{6},
+			wantErr:       true,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var versions []int
+			current, conflicts := 5, tt.conflicts
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if r.Method == http.MethodGet {
+					_, _ = fmt.Fprintf(w, `{"id": "123", "version": {"number": %d}}`, current)
+					return
+				}
+
+				body := pagesUpdateBody{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Error(err)
+				}
+				versions = append(versions, body.Version.Number)
+
+				if conflicts > 0 {
+					conflicts--
+					current++ // Someone else updated the page in the meantime.
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+				current = body.Version.Number
+				_, _ = fmt.Fprintf(w, `{"id": "123", "version": {"number": %d}}`, current)
+			}))
+			defer s.Close()
+
+			a := testAPI(t, s.URL)
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.PagesUpdateActivity)
+
+			req := PagesUpdateRequest{PageID: "123", Title: "Incident", BaseVersion: tt.baseVersion}
+			_, err := env.ExecuteActivity(a.PagesUpdateActivity, req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PagesUpdateActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				appErr := new(temporal.ApplicationError)
+				if !errors.As(err, &appErr) || appErr.Type() != "ConfluenceVersionConflict" {
+					t.Fatalf("PagesUpdateActivity() error = %v, want ConfluenceVersionConflict", err)
+				}
+				if appErr.NonRetryable() == tt.wantRetryable {
+					t.Errorf("PagesUpdateActivity() error retryable = %v, want %v", !appErr.NonRetryable(), tt.wantRetryable)
+				}
+			}
+
+			if fmt.Sprint(versions) != fmt.Sprint(tt.wantVersions) {
+				t.Errorf("updated versions = %v, want %v", versions, tt.wantVersions)
+			}
+
+			if tt.wantRetryable {
+				// The next attempt refreshes the version number.
+				if _, err := env.ExecuteActivity(a.PagesUpdateActivity, req); err != nil {
+					t.Fatalf("PagesUpdateActivity() retry error = %v", err)
+				}
+				if want := []int{6, 7}; fmt.Sprint(versions) != fmt.Sprint(want) {
+					t.Errorf("updated versions after retry = %v, want %v", versions, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package confluence
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+type API struct {
+	thrippy thrippy.LinkClient
+}
+
+// Register exposes Temporal activities and workflows via the Timpani worker.
+// It returns an error, without registering anything, if the service's Thrippy
+// link ID is missing or invalid.
+func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) error {
+	id, err := thrippy.LinkID(cmd, "Confluence")
+	if err != nil {
+		return err
+	}
+
+	a := API{thrippy: thrippy.NewLinkClient(ctx, id, cmd)}
+
+	registerActivity(w, a.PagesCreateActivity, PagesCreateActivityName)
+	registerActivity(w, a.PagesGetActivity, PagesGetActivityName)
+	registerActivity(w, a.PagesUpdateActivity, PagesUpdateActivityName)
+
+	registerActivity(w, a.SpacesGetActivity, SpacesGetActivityName)
+
+	return nil
+}
+
+func registerActivity(w worker.Worker, f any, name string) {
+	w.RegisterActivityWithOptions(f, activity.RegisterOptions{Name: name})
+}
@@ -0,0 +1,68 @@
+package confluence
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+//revive:disable:exported
+const (
+	SpacesGetActivityName = "confluence.spaces.get"
+) //revive:enable:exported
+
+// Space is based on:
+// https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space/#api-spaces-id-get
+type Space struct {
+	ID         string            `json:"id"`
+	Key        string            `json:"key"`
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Status     string            `json:"status"`
+	HomepageID string            `json:"homepageId,omitempty"`
+	AuthorID   string            `json:"authorId,omitempty"`
+	CreatedAt  string            `json:"createdAt,omitempty"`
+	Links      map[string]string `json:"_links,omitempty"`
+}
+
+// SpacesGetRequest identifies a space either by its ID, or by its key
+// (which appears in page URLs). Exactly one of them must be specified.
+type SpacesGetRequest struct {
+	SpaceID string `json:"space_id,omitempty"`
+	Key     string `json:"key,omitempty"`
+}
+
+// SpacesGetActivity is based on:
+//   - https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space/#api-spaces-id-get
+//   - https://developer.atlassian.com/cloud/confluence/rest/v2/api-group-space/#api-spaces-get
+func (a *API) SpacesGetActivity(ctx context.Context, req SpacesGetRequest) (*Space, error) {
+	if (req.SpaceID == "") == (req.Key == "") {
+		msg := "space ID and key are mutually-exclusive, specify exactly one"
+		return nil, temporal.NewNonRetryableApplicationError(msg, "error", errors.New(msg))
+	}
+
+	if req.SpaceID != "" {
+		resp := new(Space)
+		if err := a.httpGet(ctx, SpacesGetActivityName, "spaces/"+req.SpaceID, nil, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	query := url.Values{}
+	query.Set("keys", req.Key)
+	resp := struct {
+		Results []Space `json:"results"`
+	}{}
+	if err := a.httpGet(ctx, SpacesGetActivityName, "spaces", query, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Results) == 0 {
+		msg := "Confluence space not found"
+		return nil, temporal.NewNonRetryableApplicationError(msg, "ConfluenceAPIError", errors.New(msg), req.Key)
+	}
+	return &resp.Results[0], nil
+}
@@ -10,7 +10,9 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,120 +25,239 @@ import (
 
 const (
 	defaultAccept = "application/vnd.github+json"
+	diffAccept    = "application/vnd.github.diff"
+
+	// https://docs.github.com/en/rest/about-the-rest-api/api-versions
+	apiVersionHeader  = "X-GitHub-Api-Version"
+	defaultAPIVersion = "2022-11-28"
+	autoAPIVersion    = "auto"
 )
 
+// provider identifies GitHub API calls (see [client.WithProvider]).
+var provider = client.WithProvider(client.Provider{Name: "github", ErrorDetails: errorDetails})
+
+// errorDetails extracts the request ID and the first validation error code (if there
+// is one) from GitHub HTTP error responses, based on:
+// https://docs.github.com/en/rest/using-the-rest-api/troubleshooting-the-rest-api?apiVersion=2022-11-28
+func errorDetails(header http.Header, body []byte) (string, string) {
+	resp := struct {
+		Errors []struct {
+			Code string `json:"code"`
+		} `json:"errors"`
+	}{}
+
+	code := ""
+	if err := json.Unmarshal(body, &resp); err == nil && len(resp.Errors) > 0 {
+		code = resp.Errors[0].Code
+	}
+	return code, header.Get("X-Github-Request-Id")
+}
+
 type tokenResponse struct {
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// httpDelete is a GitHub-specific HTTP DELETE wrapper for [client.HTTPRequest].
-func (a *API) httpDelete(ctx context.Context, linkID, path string, query url.Values) error {
-	_, err := a.httpRequest(ctx, linkID, path, http.MethodDelete, defaultAccept, query, nil)
+// tokenExpiryMargin ensures that cached installation access tokens
+// don't expire while they're being used by slow or retried API calls.
+const tokenExpiryMargin = 5 * time.Minute
+
+// installationTokens caches installation access tokens of GitHub apps. The keys
+// are the API base URL, the app's client ID, and the installation ID.
+var installationTokens sync.Map // -> [*tokenResponse].
+
+// Installation can be embedded in activity requests, to authenticate API calls as a specific
+// installation of a GitHub app, e.g. the "installation.id" in a GitHub webhook event, instead
+// of the installation which is preconfigured in the Thrippy link. This requires a
+// "github-app-jwt" link. If the ID is 0, the link's installation is used.
+type Installation struct {
+	InstallationID int64 `json:"installation_id,omitempty"`
+}
+
+// httpDelete is a GitHub-specific HTTP DELETE wrapper for [client.HTTPRequestFull].
+func (a *API) httpDelete(ctx context.Context, linkID string, installID int64, path, accept string, query url.Values) error {
+	_, err := a.httpRequest(ctx, linkID, installID, path, http.MethodDelete, accept, query, nil)
 	return err
 }
 
-// httpGet is a GitHub-specific HTTP GET wrapper for [client.HTTPRequest].
-func (a *API) httpGet(ctx context.Context, linkID, path string, query url.Values, jsonResp any) (bool, error) {
-	link, err := a.httpRequest(ctx, linkID, path, http.MethodGet, defaultAccept, query, jsonResp)
+// httpGet is a GitHub-specific HTTP GET wrapper for [client.HTTPRequestFull].
+func (a *API) httpGet(ctx context.Context, linkID string, installID int64, path, accept string, query url.Values, jsonResp any) (bool, error) {
+	link, err := a.httpRequest(ctx, linkID, installID, path, http.MethodGet, accept, query, jsonResp)
 	return link != "", err
 }
 
-// httpPatch is a GitHub-specific HTTP PATCH wrapper for [client.HTTPRequest].
-func (a *API) httpPatch(ctx context.Context, linkID, path, accept string, jsonBody, jsonResp any) error {
-	_, err := a.httpRequest(ctx, linkID, path, http.MethodPatch, accept, jsonBody, jsonResp)
+// httpPatch is a GitHub-specific HTTP PATCH wrapper for [client.HTTPRequestFull].
+func (a *API) httpPatch(ctx context.Context, linkID string, installID int64, path, accept string, jsonBody, jsonResp any) error {
+	_, err := a.httpRequest(ctx, linkID, installID, path, http.MethodPatch, accept, jsonBody, jsonResp)
 	return err
 }
 
-// httpPost is a GitHub-specific HTTP POST wrapper for [client.HTTPRequest].
-func (a *API) httpPost(ctx context.Context, linkID, path, accept string, jsonBody, jsonResp any) error {
-	_, err := a.httpRequest(ctx, linkID, path, http.MethodPost, accept, jsonBody, jsonResp)
+// httpPost is a GitHub-specific HTTP POST wrapper for [client.HTTPRequestFull].
+func (a *API) httpPost(ctx context.Context, linkID string, installID int64, path, accept string, jsonBody, jsonResp any) error {
+	_, err := a.httpRequest(ctx, linkID, installID, path, http.MethodPost, accept, jsonBody, jsonResp)
 	return err
 }
 
-// httpPut is a GitHub-specific HTTP PUT wrapper for [client.HTTPRequest].
-func (a *API) httpPut(ctx context.Context, linkID, path, accept string, jsonBody, jsonResp any) error {
-	_, err := a.httpRequest(ctx, linkID, path, http.MethodPut, accept, jsonBody, jsonResp)
+// httpPut is a GitHub-specific HTTP PUT wrapper for [client.HTTPRequestFull].
+func (a *API) httpPut(ctx context.Context, linkID string, installID int64, path, accept string, jsonBody, jsonResp any) error {
+	_, err := a.httpRequest(ctx, linkID, installID, path, http.MethodPut, accept, jsonBody, jsonResp)
 	return err
 }
 
-func (a *API) httpRequest(ctx context.Context, linkID, path, method, accept string, queryOrJSONBody, parsedResp any) (string, error) {
-	l, apiURL, auth, err := a.httpRequestPrep(ctx, linkID, path)
+func (a *API) httpRequest(
+	ctx context.Context,
+	linkID string,
+	installID int64,
+	path, method, accept string,
+	queryOrJSONBody, parsedResp any,
+) (string, error) {
+	l, apiURL, auth, opts, err := a.httpRequestPrep(ctx, linkID, installID, path)
 	if err != nil {
 		return "", err
 	}
 
-	rawResp, headers, _, err := client.HTTPRequest(ctx, method, apiURL, auth, accept, client.ContentJSON, queryOrJSONBody)
+	resp, err := client.HTTPRequestFull(ctx, method, apiURL, auth, accept, client.ContentJSON, queryOrJSONBody, opts...)
+	if resp != nil {
+		a.updateBudget(linkID, installID, resp.Header)
+	}
 	if err != nil {
 		l.Error("HTTP request error", slog.Any("error", err), slog.String("http_method", method), slog.String("url", apiURL))
-		return "", err
+		return "", client.NewApplicationError(err)
 	}
 
 	l.Info("sent HTTP request", slog.String("link_id", linkID), slog.String("http_method", method), slog.String("url", apiURL))
 
 	if parsedResp == nil {
-		return headers.Get("link"), nil // No response body expected.
+		return resp.Header.Get("link"), nil // No response body expected.
 	}
 
-	if err := json.Unmarshal(rawResp, parsedResp); err != nil {
+	if err := json.Unmarshal(resp.Body, parsedResp); err != nil {
 		msg := "failed to decode HTTP response's JSON body"
 		l.Error(msg, slog.Any("error", err), slog.String("url", apiURL))
 		msg = fmt.Sprintf("%s: %v", msg, err)
-		return "", temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(rawResp))
+		return "", temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp.Body))
 	}
 
-	return headers.Get("link"), nil
+	return resp.Header.Get("link"), nil
+}
+
+// httpGetToFile is a GitHub-specific HTTP GET wrapper for [client.HTTPRequestToFile].
+// It writes the response body into a local file, instead of returning it, in order
+// to support large responses.
+func (a *API) httpGetToFile(
+	ctx context.Context,
+	linkID string,
+	installID int64,
+	path, accept, filePath string,
+	maxSize int64,
+) (*client.FileSinkResponse, error) {
+	l, apiURL, auth, opts, err := a.httpRequestPrep(ctx, linkID, installID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, client.WithFileTransferTimeout())
+	resp, header, err := client.HTTPRequestToFile(ctx, http.MethodGet, apiURL, auth, accept, "", nil, filePath, maxSize, opts...)
+	a.updateBudget(linkID, installID, header)
+	if err != nil {
+		l.Error("HTTP request error", slog.Any("error", err), slog.String("http_method", http.MethodGet),
+			slog.String("url", apiURL), slog.String("file_path", filePath))
+		return nil, client.NewApplicationError(err)
+	}
+
+	l.Info("sent HTTP request", slog.String("link_id", linkID), slog.String("http_method", http.MethodGet),
+		slog.String("url", apiURL), slog.String("file_path", filePath), slog.Int64("size", resp.Size))
+	return resp, nil
 }
 
 // httpRequestPrep supports custom Thrippy link IDs (for user impersonation).
 // If it's empty, we use the Timpani server's preconfigured GitHub link ID.
-func (a *API) httpRequestPrep(ctx context.Context, linkID, path string) (l log.Logger, apiURL, auth string, err error) {
+// It also supports custom GitHub app installation IDs (see [Installation]).
+// It also returns the request options that are common to all GitHub API calls,
+// unless the link's rate limit budget requires pacing (see [API.paceRequest]).
+func (a *API) httpRequestPrep(
+	ctx context.Context,
+	linkID string,
+	installID int64,
+	path string,
+) (l log.Logger, apiURL, auth string, opts []client.RequestOpt, err error) {
 	l = activity.GetLogger(ctx)
 
+	if err = a.paceRequest(ctx, linkID, installID, path); err != nil {
+		return l, "", "", nil, err
+	}
+
 	var secrets map[string]string
 	secrets, err = a.thrippy.LinkCreds(ctx, linkID)
 	if err != nil {
-		return l, "", "", err
+		return l, "", "", nil, err
 	}
 
-	baseURL := secrets["api_base_url"] // Added automatically to "github-app-jwt" links.
-	if baseURL == "" {
-		baseURL = secrets["base_url"] // Manual and optional in "github-app-user" and "github-user-pat" links.
-		if baseURL == "" {
-			baseURL = "https://api.github.com"
-		} else {
-			baseURL += "/api/v3" // GitHub Enterprise Server (GHES).
-		}
-	}
+	opts = requestOpts(secrets)
 
+	baseURL := apiBaseURL(secrets)
 	apiURL, err = url.JoinPath(baseURL, path)
 	if err != nil {
 		l.Error("failed to construct GitHub API URL", slog.Any("error", err),
 			slog.String("base_url", baseURL), slog.String("path", path))
 		err = temporal.NewNonRetryableApplicationError(err.Error(), fmt.Sprintf("%T", err), err, baseURL, path)
-		return l, "", "", err
+		return l, "", "", nil, err
+	}
+	if path == graphqlPath {
+		apiURL = graphqlURL(baseURL)
 	}
 
 	// "access_token" has a value only in "github-app-user" link secrets.
 	// "pat" has a value only in "github-user-pat" link secrets.
 	if auth := secrets["access_token"] + secrets["pat"]; auth != "" {
-		return l, apiURL, auth, nil
+		if installID != 0 {
+			msg := "installation ID is supported only in GitHub app links"
+			return l, "", "", nil, temporal.NewNonRetryableApplicationError(msg, "error", nil, linkID, installID)
+		}
+		return l, apiURL, auth, opts, nil
 	}
 
-	// Generating JWTs (and using them to generate installation tokens) is supported only for "github-app-jwt" links.
-	auth, err = generateJWT(secrets["client_id"], secrets["private_key"])
-	if err != nil {
-		msg := "failed to generate JWT for GitHub API call"
-		l.Warn(msg, slog.Any("error", err), slog.String("link_id", a.thrippy.LinkID))
-		return l, "", "", temporal.NewNonRetryableApplicationError(msg, "error", err, a.thrippy.LinkID)
+	// Installation tokens are supported only for "github-app-jwt" links.
+	id := secrets["install_id"]
+	if installID != 0 {
+		id = strconv.FormatInt(installID, 10)
 	}
 
-	auth, err = a.createInstallationToken(ctx, baseURL, secrets["install_id"], auth)
+	auth, err = a.installationToken(ctx, baseURL, id, secrets, opts)
 	if err != nil {
-		return l, "", "", err
+		return l, "", "", nil, err
 	}
 
-	return l, apiURL, auth, nil
+	return l, apiURL, auth, opts, nil
+}
+
+// requestOpts returns the [client.RequestOpt]s of GitHub API calls, based on the given link secrets.
+// GitHub Enterprise Server (GHES) links may specify an "api_version" to override the default REST
+// API version, or "auto" to omit the version header, for GHES versions that reject it.
+func requestOpts(secrets map[string]string) []client.RequestOpt {
+	version := secrets["api_version"]
+	switch version {
+	case autoAPIVersion:
+		return []client.RequestOpt{provider}
+	case "":
+		version = defaultAPIVersion
+	}
+
+	return []client.RequestOpt{provider, client.WithHeader(apiVersionHeader, version)}
+}
+
+// apiBaseURL returns the base URL of the GitHub API, based on the given link secrets.
+func apiBaseURL(secrets map[string]string) string {
+	if baseURL := secrets["api_base_url"]; baseURL != "" {
+		return baseURL // Added automatically to "github-app-jwt" links.
+	}
+
+	// Manual and optional in "github-app-user" and "github-user-pat" links.
+	if baseURL := secrets["base_url"]; baseURL != "" {
+		return baseURL + "/api/v3" // GitHub Enterprise Server (GHES).
+	}
+
+	return "https://api.github.com"
 }
 
 // generateJWT generates a JSON Web Token (JWT) for a GitHub app. Based on:
@@ -178,10 +299,38 @@ func generateJWT(clientID, privateKey string) (string, error) {
 	return signedToken, nil
 }
 
+// installationToken returns a cached installation access token for a GitHub app, or generates
+// a JWT to create a new one. Tokens are cached until shortly before they expire (after an hour),
+// to avoid creating a new token for each API call.
+func (a *API) installationToken(ctx context.Context, baseURL, installID string, secrets map[string]string, opts []client.RequestOpt) (string, error) {
+	key := strings.Join([]string{baseURL, secrets["client_id"], installID}, "|")
+	if v, ok := installationTokens.Load(key); ok {
+		if t := v.(*tokenResponse); time.Until(t.ExpiresAt) > tokenExpiryMargin { //nolint:errcheck // Type conversion always succeeds.
+			return t.Token, nil
+		}
+	}
+
+	// Generating JWTs (and using them to generate installation tokens) is supported only for "github-app-jwt" links.
+	auth, err := generateJWT(secrets["client_id"], secrets["private_key"])
+	if err != nil {
+		msg := "failed to generate JWT for GitHub API call"
+		activity.GetLogger(ctx).Warn(msg, slog.Any("error", err), slog.String("link_id", a.thrippy.LinkID))
+		return "", temporal.NewNonRetryableApplicationError(msg, "error", err, a.thrippy.LinkID)
+	}
+
+	t, err := a.createInstallationToken(ctx, baseURL, installID, auth, opts)
+	if err != nil {
+		return "", err
+	}
+
+	installationTokens.Store(key, t)
+	return t.Token, nil
+}
+
 // createInstallationToken retrieves a new installation access token for a GitHub app. Based on:
 //   - https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-an-installation-access-token-for-a-github-app
 //   - https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#create-an-installation-access-token-for-an-app
-func (a *API) createInstallationToken(ctx context.Context, baseURL, installID, auth string) (string, error) {
+func (a *API) createInstallationToken(ctx context.Context, baseURL, installID, auth string, opts []client.RequestOpt) (*tokenResponse, error) {
 	l := activity.GetLogger(ctx)
 	post := http.MethodPost
 
@@ -189,23 +338,23 @@ func (a *API) createInstallationToken(ctx context.Context, baseURL, installID, a
 	if err != nil {
 		l.Error("failed to construct GitHub installation access token URL", slog.Any("error", err),
 			slog.String("base_url", baseURL), slog.String("install_id", installID))
-		return "", err
+		return nil, err
 	}
 
-	rawResp, _, _, err := client.HTTPRequest(ctx, post, tokenURL, auth, defaultAccept, "", http.NoBody)
+	resp, err := client.HTTPRequestFull(ctx, post, tokenURL, auth, defaultAccept, "", http.NoBody, opts...)
 	if err != nil {
 		l.Error("HTTP request error", slog.Any("error", err), slog.String("http_method", post), slog.String("url", tokenURL))
-		return "", err
+		return nil, client.NewApplicationError(err)
 	}
 	l.Info("sent HTTP request", slog.String("link_id", a.thrippy.LinkID),
 		slog.String("http_method", post), slog.String("url", tokenURL))
 
 	jsonResp := new(tokenResponse)
-	if err := json.Unmarshal(rawResp, jsonResp); err != nil {
+	if err := json.Unmarshal(resp.Body, jsonResp); err != nil {
 		l.Error("failed to decode GitHub installation access token response",
-			slog.Any("error", err), slog.String("response", string(rawResp)))
-		return "", err
+			slog.Any("error", err), slog.String("response", string(resp.Body)))
+		return nil, err
 	}
 
-	return jsonResp.Token, nil
+	return jsonResp, nil
 }
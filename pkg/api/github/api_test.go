@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"google.golang.org/grpc"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+type thrippyServer struct {
+	thrippypb.UnimplementedThrippyServiceServer
+
+	secrets map[string]string
+}
+
+func (s *thrippyServer) GetCredentials(_ context.Context, _ *thrippypb.GetCredentialsRequest) (*thrippypb.GetCredentialsResponse, error) {
+	return thrippypb.GetCredentialsResponse_builder{Credentials: s.secrets}.Build(), nil
+}
+
+// testAPI returns an [API] whose Thrippy link points to the given GitHub API base URL.
+func testAPI(t *testing.T, baseURL string) *API {
+	t.Helper()
+	return testLinkAPI(t, map[string]string{"api_base_url": baseURL, "pat": "token"})
+}
+
+// testLinkAPI returns an [API] whose Thrippy link has the given secrets.
+func testLinkAPI(t *testing.T, secrets map[string]string) *API {
+	t.Helper()
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gs := grpc.NewServer()
+	thrippypb.RegisterThrippyServiceServer(gs, &thrippyServer{secrets: secrets})
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dev"},
+			&cli.StringFlag{Name: "thrippy-grpc-address"},
+		},
+	}
+	_ = cmd.Set("dev", "true")
+	_ = cmd.Set("thrippy-grpc-address", lis.Addr().String())
+
+	return &API{thrippy: thrippy.NewLinkClient(t.Context(), "link ID", cmd)}
+}
+
+func TestHTTPHelpersAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		accept string
+	}{
+		{
+			name:   "delete_default",
+			method: http.MethodDelete,
+			accept: defaultAccept,
+		},
+		{
+			name:   "get_default",
+			method: http.MethodGet,
+			accept: defaultAccept,
+		},
+		{
+			name:   "get_raw",
+			method: http.MethodGet,
+			accept: "application/vnd.github.raw+json",
+		},
+		{
+			name:   "patch_raw",
+			method: http.MethodPatch,
+			accept: "application/vnd.github.raw+json",
+		},
+		{
+			name:   "post_diff",
+			method: http.MethodPost,
+			accept: diffAccept,
+		},
+		{
+			name:   "put_default",
+			method: http.MethodPut,
+			accept: defaultAccept,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod, gotAccept string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod, gotAccept = r.Method, r.Header.Get("Accept")
+				_, _ = w.Write([]byte("{}"))
+			}))
+			defer s.Close()
+
+			a := testAPI(t, s.URL)
+			f := func(ctx context.Context) error {
+				resp := map[string]any{}
+				var err error
+				switch tt.method {
+				case http.MethodDelete:
+					err = a.httpDelete(ctx, "", 0, "/path", tt.accept, nil)
+				case http.MethodGet:
+					_, err = a.httpGet(ctx, "", 0, "/path", tt.accept, nil, &resp)
+				case http.MethodPatch:
+					err = a.httpPatch(ctx, "", 0, "/path", tt.accept, resp, &resp)
+				case http.MethodPost:
+					err = a.httpPost(ctx, "", 0, "/path", tt.accept, resp, &resp)
+				case http.MethodPut:
+					err = a.httpPut(ctx, "", 0, "/path", tt.accept, resp, &resp)
+				}
+				return err
+			}
+
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(f, activity.RegisterOptions{Name: tt.name})
+			if _, err := env.ExecuteActivity(tt.name); err != nil {
+				t.Fatalf("%s request error = %v", tt.method, err)
+			}
+
+			if gotMethod != tt.method {
+				t.Errorf("HTTP method = %q, want %q", gotMethod, tt.method)
+			}
+			if gotAccept != tt.accept {
+				t.Errorf("Accept header = %q, want %q", gotAccept, tt.accept)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+//revive:disable:exported
+const (
+	ChecksListForRefActivityName     = "github.checks.listForRef"
+	ChecksRerequestSuiteActivityName = "github.checks.rerequestSuite"
+	TimpaniAwaitChecksWorkflowName   = "github.timpani.awaitChecks"
+) //revive:enable:exported
+
+const (
+	checkSuiteSignal                  = "github.events.check_suite"
+	checkSuiteConclusionPending       = "pending"
+	checkSuiteConclusionWithoutSuites = "neutral"
+)
+
+// CheckRun is based on:
+// https://docs.github.com/en/rest/checks/runs?apiVersion=2022-11-28#get-a-check-run
+type CheckRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+	HTMLURL    string `json:"html_url,omitempty"`
+
+	CheckSuite struct {
+		ID int64 `json:"id"`
+	} `json:"check_suite"`
+}
+
+// ChecksListForRefRequest is based on:
+// https://docs.github.com/en/rest/checks/runs?apiVersion=2022-11-28#list-check-runs-for-a-git-reference
+type ChecksListForRefRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Ref   string `json:"ref"`
+}
+
+// ChecksListForRefActivity is based on:
+// https://docs.github.com/en/rest/checks/runs?apiVersion=2022-11-28#list-check-runs-for-a-git-reference
+//
+// Pagination is handled internally, to return all the check runs of the Git reference.
+func (a *API) ChecksListForRefActivity(ctx context.Context, req ChecksListForRefRequest) ([]CheckRun, error) {
+	path := fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs", req.Owner, req.Repo, url.PathEscape(req.Ref))
+	query := url.Values{}
+	query.Set("per_page", "100") // Default = 30, but we prefer to minimize the number of API calls.
+
+	var runs []CheckRun
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		t := time.Now().UTC()
+		resp := new(struct {
+			TotalCount int        `json:"total_count"`
+			CheckRuns  []CheckRun `json:"check_runs"`
+		})
+		_, err := a.httpGet(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, query, resp)
+		otel.IncrementAPICallCounter(t, ChecksListForRefActivityName, err)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, resp.CheckRuns...)
+		if len(resp.CheckRuns) == 0 || len(runs) >= resp.TotalCount {
+			if _, err := client.LimitResponseSize(ChecksListForRefActivityName, runs, &runs, false); err != nil {
+				return nil, err
+			}
+			return runs, nil
+		}
+	}
+}
+
+// ChecksRerequestSuiteRequest is based on:
+// https://docs.github.com/en/rest/checks/suites?apiVersion=2022-11-28#rerequest-a-check-suite
+type ChecksRerequestSuiteRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner        string `json:"owner"`
+	Repo         string `json:"repo"`
+	CheckSuiteID int64  `json:"check_suite_id"`
+}
+
+// ChecksRerequestSuiteActivity is based on:
+// https://docs.github.com/en/rest/checks/suites?apiVersion=2022-11-28#rerequest-a-check-suite
+func (a *API) ChecksRerequestSuiteActivity(ctx context.Context, req ChecksRerequestSuiteRequest) error {
+	path := fmt.Sprintf("/repos/%s/%s/check-suites/%d/rerequest", req.Owner, req.Repo, req.CheckSuiteID)
+
+	t := time.Now().UTC()
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil, nil)
+	otel.IncrementAPICallCounter(t, ChecksRerequestSuiteActivityName, err)
+
+	return err
+}
+
+// TimpaniAwaitChecksRequest identifies a Git reference (branch name, tag name, or commit
+// SHA) whose checks to await. The timeout is optional (e.g. "30m", default = no timeout).
+type TimpaniAwaitChecksRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Ref   string `json:"ref"`
+
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// TimpaniAwaitChecksResponse contains the aggregate conclusion of all the check
+// suites: "success" (all of them succeeded, or were neutral or skipped), "failure"
+// (at least one of them didn't), "pending" (timeout while waiting for at least one of
+// them), or "neutral" (there are no check suites). It also contains the conclusion of
+// each check suite, and the IDs of the failed check suites which were rerequested.
+type TimpaniAwaitChecksResponse struct {
+	Conclusion  string           `json:"conclusion"`
+	HeadSHA     string           `json:"head_sha,omitempty"`
+	Suites      map[int64]string `json:"suites,omitempty"`
+	Rerequested []int64          `json:"rerequested,omitempty"`
+}
+
+// TimpaniAwaitChecksWorkflow is a convenience wrapper over [ChecksListForRefActivity] and
+// [ChecksRerequestSuiteActivity]. It rerequests the failed check suites of a Git reference
+// (once), and then waits for "check_suite" events until all of them are completed, or
+// until the request's timeout. It returns the aggregate conclusion of all the check suites.
+func (a *API) TimpaniAwaitChecksWorkflow(ctx workflow.Context, req TimpaniAwaitChecksRequest) (*TimpaniAwaitChecksResponse, error) {
+	timeout := time.Duration(0)
+	if req.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError("invalid timeout: "+err.Error(), "error", err, req.Timeout)
+		}
+	}
+	deadline := workflow.Now(ctx).Add(timeout)
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+
+	var runs []CheckRun
+	listReq := ChecksListForRefRequest{
+		ThrippyLinkID: req.ThrippyLinkID,
+		Installation:  req.Installation,
+		Owner:         req.Owner,
+		Repo:          req.Repo,
+		Ref:           req.Ref,
+	}
+	if err := workflow.ExecuteActivity(actCtx, ChecksListForRefActivityName, listReq).Get(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to list check runs: %w", err)
+	}
+
+	resp := &TimpaniAwaitChecksResponse{Suites: checkSuiteConclusions(runs)}
+	if len(runs) > 0 {
+		resp.HeadSHA = runs[0].HeadSHA
+	}
+
+	// Sorted iteration, for deterministic workflow execution.
+	for _, id := range slices.Sorted(maps.Keys(resp.Suites)) {
+		if !checkConclusionFailed(resp.Suites[id]) {
+			continue
+		}
+		rerequest := ChecksRerequestSuiteRequest{
+			ThrippyLinkID: req.ThrippyLinkID, Installation: req.Installation,
+			Owner: req.Owner, Repo: req.Repo, CheckSuiteID: id,
+		}
+		if err := workflow.ExecuteActivity(actCtx, ChecksRerequestSuiteActivityName, rerequest).Get(ctx, nil); err != nil {
+			return nil, fmt.Errorf("failed to rerequest check suite %d: %w", id, err)
+		}
+		resp.Suites[id] = checkSuiteConclusionPending
+		resp.Rerequested = append(resp.Rerequested, id)
+	}
+
+	// https://docs.temporal.io/develop/go/observability#visibility
+	attr := temporal.NewSearchAttributeKeyKeywordList("WaitingForSignals").ValueSet([]string{checkSuiteSignal})
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{TypedSearchAttributes: temporal.NewSearchAttributes(attr)})
+	filter := map[string]string{"action": "completed", "check_suite.head_sha": resp.HeadSHA}
+
+	for aggregateConclusion(resp.Suites) == checkSuiteConclusionPending {
+		waitReq := listeners.WaitForEventRequest{Signal: checkSuiteSignal, Filter: filter}
+		if timeout > 0 {
+			remaining := deadline.Sub(workflow.Now(ctx))
+			if remaining <= 0 {
+				break
+			}
+			waitReq.Timeout = remaining.String()
+		}
+
+		var payload map[string]any
+		if err := workflow.ExecuteChildWorkflow(childCtx, listeners.WaitForEventWorkflow, waitReq).Get(ctx, &payload); err != nil {
+			if timeout > 0 && !workflow.Now(ctx).Before(deadline) {
+				break
+			}
+			return nil, fmt.Errorf("failed to wait for events: %w", err)
+		}
+
+		event, err := Decode[CheckSuiteEvent](payload)
+		if err != nil {
+			workflow.GetLogger(ctx).Warn("ignoring unexpected GitHub check suite event", slog.Any("error", err))
+			continue
+		}
+		suite := event.CheckSuite
+		if _, ok := resp.Suites[suite.ID]; ok && suite.Conclusion != "" {
+			resp.Suites[suite.ID] = suite.Conclusion
+		}
+	}
+
+	resp.Conclusion = aggregateConclusion(resp.Suites)
+	return resp, nil
+}
+
+// checkSuiteConclusions derives the conclusion of each check suite from its check runs:
+// "pending" if any of them isn't completed yet, otherwise the first failed conclusion, if
+// there is one, otherwise "success".
+func checkSuiteConclusions(runs []CheckRun) map[int64]string {
+	suites := make(map[int64]string)
+	for _, r := range runs {
+		id := r.CheckSuite.ID
+		if _, ok := suites[id]; !ok {
+			suites[id] = "success"
+		}
+
+		switch {
+		case r.Status != "completed":
+			suites[id] = checkSuiteConclusionPending
+		case checkConclusionFailed(r.Conclusion) && suites[id] != checkSuiteConclusionPending:
+			suites[id] = r.Conclusion
+		}
+	}
+	return suites
+}
+
+// checkConclusionFailed is based on the possible conclusions in:
+// https://docs.github.com/en/rest/checks/suites?apiVersion=2022-11-28#get-a-check-suite
+func checkConclusionFailed(conclusion string) bool {
+	switch conclusion {
+	case "action_required", "cancelled", "failure", "startup_failure", "stale", "timed_out":
+		return true
+	default:
+		return false
+	}
+}
+
+func aggregateConclusion(suites map[int64]string) string {
+	if len(suites) == 0 {
+		return checkSuiteConclusionWithoutSuites
+	}
+
+	conclusion := "success"
+	for _, c := range suites {
+		if c == checkSuiteConclusionPending {
+			return checkSuiteConclusionPending
+		}
+		if checkConclusionFailed(c) {
+			conclusion = "failure"
+		}
+	}
+	return conclusion
+}
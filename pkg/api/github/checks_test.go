@@ -0,0 +1,192 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+func TestChecksListForRefActivity(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.EscapedPath(); got != "/repos/owner/repo/commits/feature%2Fbranch/check-runs" {
+			t.Errorf("unexpected URL path: %s", got)
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`{"total_count": 3, "check_runs": [{"id": 1}, {"id": 2}]}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"total_count": 3, "check_runs": [{"id": 3}]}`))
+		default:
+			t.Errorf("unexpected page: %s", r.URL.Query().Get("page"))
+		}
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.ChecksListForRefActivity)
+
+	req := ChecksListForRefRequest{Owner: "owner", Repo: "repo", Ref: "feature/branch"}
+	v, err := env.ExecuteActivity(a.ChecksListForRefActivity, req)
+	if err != nil {
+		t.Fatalf("ChecksListForRefActivity() error = %v", err)
+	}
+
+	var got []CheckRun
+	if err := v.Get(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("ChecksListForRefActivity() = %d check runs, want 3", len(got))
+	}
+}
+
+func TestChecksListForRefActivityTooLarge(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"total_count": 2, "check_runs": [{"id": 1}, {"id": 2}]}`))
+	}))
+	defer s.Close()
+
+	client.SetMaxResponseSize(100) // Each check run is ~65 bytes.
+	t.Cleanup(func() { client.SetMaxResponseSize(0) })
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.ChecksListForRefActivity)
+
+	req := ChecksListForRefRequest{Owner: "owner", Repo: "repo", Ref: "main"}
+	_, err := env.ExecuteActivity(a.ChecksListForRefActivity, req)
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != "ResponseTooLargeError" {
+		t.Errorf("ChecksListForRefActivity() error = %v, want ResponseTooLargeError", err)
+	}
+}
+
+func testCheckRun(suiteID int64, status, conclusion string) CheckRun {
+	r := CheckRun{HeadSHA: "sha", Status: status, Conclusion: conclusion}
+	r.CheckSuite.ID = suiteID
+	return r
+}
+
+func checkSuiteEvent(suiteID int64, conclusion string) map[string]any {
+	return map[string]any{
+		"action":      "completed",
+		"check_suite": map[string]any{"id": float64(suiteID), "head_sha": "sha", "conclusion": conclusion},
+	}
+}
+
+func TestTimpaniAwaitChecksWorkflow(t *testing.T) {
+	tests := []struct {
+		name            string
+		runs            []CheckRun
+		events          []map[string]any
+		want            string
+		wantRerequested []int64
+	}{
+		{
+			name: "no_check_suites",
+			want: "neutral",
+		},
+		{
+			name: "all_succeeded",
+			runs: []CheckRun{testCheckRun(1, "completed", "success"), testCheckRun(2, "completed", "skipped")},
+			want: "success",
+		},
+		{
+			name:   "wait_for_pending_suite",
+			runs:   []CheckRun{testCheckRun(1, "completed", "success"), testCheckRun(2, "in_progress", "")},
+			events: []map[string]any{checkSuiteEvent(3, "failure"), checkSuiteEvent(2, "success")},
+			want:   "success",
+		},
+		{
+			name:            "rerequest_and_succeed",
+			runs:            []CheckRun{testCheckRun(1, "completed", "success"), testCheckRun(2, "completed", "failure")},
+			events:          []map[string]any{checkSuiteEvent(2, "success")},
+			want:            "success",
+			wantRerequested: []int64{2},
+		},
+		{
+			name: "rerequest_and_fail_again",
+			runs: []CheckRun{
+				testCheckRun(1, "completed", "timed_out"), testCheckRun(2, "completed", "success"), testCheckRun(2, "completed", "failure"),
+			},
+			events:          []map[string]any{checkSuiteEvent(2, "success"), checkSuiteEvent(1, "timed_out")},
+			want:            "failure",
+			wantRerequested: []int64{1, 2},
+		},
+		{
+			name:            "timeout",
+			runs:            []CheckRun{testCheckRun(1, "completed", "cancelled")},
+			want:            "pending",
+			wantRerequested: []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := func(_ context.Context, _ ChecksListForRefRequest) ([]CheckRun, error) {
+				return tt.runs, nil
+			}
+			var rerequested []int64
+			rerequest := func(_ context.Context, req ChecksRerequestSuiteRequest) error {
+				rerequested = append(rerequested, req.CheckSuiteID)
+				return nil
+			}
+			events := tt.events
+			wait := func(ctx workflow.Context, req listeners.WaitForEventRequest) (map[string]any, error) {
+				if req.Signal != checkSuiteSignal || req.Filter["check_suite.head_sha"] != "sha" {
+					return nil, fmt.Errorf("unexpected wait request: %v", req)
+				}
+				if len(events) == 0 {
+					timeout, _ := time.ParseDuration(req.Timeout)
+					_ = workflow.Sleep(ctx, timeout)
+					return nil, errors.New("timeout")
+				}
+				e := events[0]
+				events = events[1:]
+				return e, nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(list, activity.RegisterOptions{Name: ChecksListForRefActivityName})
+			env.RegisterActivityWithOptions(rerequest, activity.RegisterOptions{Name: ChecksRerequestSuiteActivityName})
+			env.RegisterWorkflowWithOptions(wait, workflow.RegisterOptions{Name: listeners.WaitForEventWorkflow})
+			env.RegisterWorkflow(a.TimpaniAwaitChecksWorkflow)
+
+			req := TimpaniAwaitChecksRequest{Owner: "owner", Repo: "repo", Ref: "main", Timeout: "1h"}
+			env.ExecuteWorkflow(a.TimpaniAwaitChecksWorkflow, req)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("TimpaniAwaitChecksWorkflow() error = %v", err)
+			}
+			got := new(TimpaniAwaitChecksResponse)
+			if err := env.GetWorkflowResult(got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Conclusion != tt.want {
+				t.Errorf("TimpaniAwaitChecksWorkflow() conclusion = %q, want %q", got.Conclusion, tt.want)
+			}
+			if !reflect.DeepEqual(rerequested, tt.wantRerequested) {
+				t.Errorf("rerequested check suites = %v, want %v", rerequested, tt.wantRerequested)
+			}
+			if len(events) > 0 {
+				t.Errorf("unconsumed check_suite events: %v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tzrikka/timpani-api/pkg/github"
+)
+
+// originalEventKey is the same as [temporal.OriginalEventKey], which
+// listeners add to signal payloads (this package can't import it).
+const originalEventKey = "timpani_original_event"
+
+// Event is the set of GitHub webhook event payloads which [Decode] supports.
+type Event interface {
+	PullRequestEvent | PushEvent | IssueCommentEvent | CheckRunEvent | CheckSuiteEvent | WorkflowRunEvent
+}
+
+// decodable is implemented by pointers to all the types in [Event].
+type decodable interface {
+	eventType() string
+	missingFields() []string
+}
+
+// MissingFieldsError is returned by [Decode] when a GitHub webhook
+// payload doesn't contain all the required fields of its type.
+type MissingFieldsError struct {
+	Type   string
+	Fields []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("GitHub %q event is missing required fields: %s", e.Type, strings.Join(e.Fields, ", "))
+}
+
+// Decode converts an untyped GitHub webhook event payload, e.g. one that was received by
+// [listeners.WaitForEventWorkflow], into a typed struct. Unknown fields are ignored. If the
+// payload was annotated by Timpani's GitHub listener with a different event type, this
+// function returns an error.
+//
+// If the payload is missing required fields, this function returns a [MissingFieldsError]
+// along with the partially-decoded struct, so callers may still use it on a best-effort basis.
+func Decode[T Event](payload map[string]any) (T, error) {
+	var t T
+	d := any(&t).(decodable)
+
+	if typ, _ := payload[originalEventKey].(string); typ != "" && typ != d.eventType() {
+		return t, fmt.Errorf("unexpected GitHub event type %q, want %q", typ, d.eventType())
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return t, fmt.Errorf("failed to encode GitHub %q event: %w", d.eventType(), err)
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return t, fmt.Errorf("failed to decode GitHub %q event: %w", d.eventType(), err)
+	}
+
+	if missing := d.missingFields(); len(missing) > 0 {
+		return t, &MissingFieldsError{Type: d.eventType(), Fields: missing}
+	}
+	return t, nil
+}
+
+// required appends the given field name to the list of missing fields, if its value is empty.
+func required[T comparable](missing []string, name string, value T) []string {
+	var zero T
+	if value == zero {
+		return append(missing, name)
+	}
+	return missing
+}
+
+// EventRepository is the subset of a repository's fields that is common to all webhook
+// events. It's different from [github.Repository] because "push" events use Unix
+// timestamps instead of ISO 8601 strings. Based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type EventRepository struct {
+	ID            int64       `json:"id"`
+	NodeID        string      `json:"node_id"`
+	Name          string      `json:"name"`
+	FullName      string      `json:"full_name"`
+	Owner         github.User `json:"owner"`
+	HTMLURL       string      `json:"html_url"`
+	DefaultBranch string      `json:"default_branch,omitempty"`
+	Private       bool        `json:"private"`
+	Fork          bool        `json:"fork"`
+}
+
+// EventInstallation identifies the GitHub app installation which received a webhook event,
+// to be used in activity requests (see [Installation]). Based on:
+// https://docs.github.com/en/apps/creating-github-apps/writing-code-for-a-github-app/building-a-github-app-that-responds-to-webhook-events
+type EventInstallation struct {
+	ID     int64  `json:"id"`
+	NodeID string `json:"node_id,omitempty"`
+}
+
+// PullRequestEvent is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#pull_request
+type PullRequestEvent struct {
+	Action       string             `json:"action"`
+	Number       int                `json:"number"`
+	PullRequest  github.PullRequest `json:"pull_request"`
+	Before       string             `json:"before,omitempty"` // Only in "synchronize" events.
+	After        string             `json:"after,omitempty"`  // Only in "synchronize" events.
+	Repository   EventRepository    `json:"repository"`
+	Sender       github.User        `json:"sender"`
+	Installation *EventInstallation `json:"installation,omitempty"`
+}
+
+// HeadSHA returns the SHA of the latest commit in the pull request's head branch.
+func (e PullRequestEvent) HeadSHA() string {
+	return e.PullRequest.Head.SHA
+}
+
+// IsMergedClose reports whether the event is the closure of the pull request due to a
+// merge, as opposed to closing it without merging (both are "closed" actions).
+func (e PullRequestEvent) IsMergedClose() bool {
+	return e.Action == "closed" && e.PullRequest.Merged
+}
+
+func (e *PullRequestEvent) eventType() string {
+	return "pull_request"
+}
+
+func (e *PullRequestEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "action", e.Action)
+	missing = required(missing, "number", e.Number)
+	missing = required(missing, "pull_request.head.sha", e.PullRequest.Head.SHA)
+	return required(missing, "repository.full_name", e.Repository.FullName)
+}
+
+// PushEvent is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type PushEvent struct {
+	Ref          string             `json:"ref"`
+	Before       string             `json:"before"`
+	After        string             `json:"after"`
+	BaseRef      string             `json:"base_ref,omitempty"`
+	Created      bool               `json:"created"`
+	Deleted      bool               `json:"deleted"`
+	Forced       bool               `json:"forced"`
+	Compare      string             `json:"compare,omitempty"`
+	Commits      []PushCommit       `json:"commits,omitempty"`
+	HeadCommit   *PushCommit        `json:"head_commit,omitempty"`
+	Pusher       GitCommitAuthor    `json:"pusher"`
+	Repository   EventRepository    `json:"repository"`
+	Sender       github.User        `json:"sender"`
+	Installation *EventInstallation `json:"installation,omitempty"`
+}
+
+// PushCommit is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type PushCommit struct {
+	ID        string          `json:"id"`
+	TreeID    string          `json:"tree_id"`
+	Distinct  bool            `json:"distinct"`
+	Message   string          `json:"message"`
+	Timestamp string          `json:"timestamp"`
+	URL       string          `json:"url"`
+	Author    GitCommitAuthor `json:"author"`
+	Committer GitCommitAuthor `json:"committer"`
+	Added     []string        `json:"added,omitempty"`
+	Removed   []string        `json:"removed,omitempty"`
+	Modified  []string        `json:"modified,omitempty"`
+}
+
+// HeadSHA returns the SHA of the most recent commit on the
+// pushed Git reference, which is all zeros if it was deleted.
+func (e PushEvent) HeadSHA() string {
+	return e.After
+}
+
+// Branch returns the name of the pushed branch, or an
+// empty string if the pushed Git reference isn't a branch.
+func (e PushEvent) Branch() string {
+	b, ok := strings.CutPrefix(e.Ref, "refs/heads/")
+	if !ok {
+		return ""
+	}
+	return b
+}
+
+func (e *PushEvent) eventType() string {
+	return "push"
+}
+
+func (e *PushEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "ref", e.Ref)
+	missing = required(missing, "after", e.After)
+	return required(missing, "repository.full_name", e.Repository.FullName)
+}
+
+// IssueCommentEvent is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#issue_comment
+type IssueCommentEvent struct {
+	Action       string              `json:"action"`
+	Issue        github.Issue        `json:"issue"`
+	Comment      github.IssueComment `json:"comment"`
+	Repository   EventRepository     `json:"repository"`
+	Sender       github.User         `json:"sender"`
+	Installation *EventInstallation  `json:"installation,omitempty"`
+}
+
+// IsPullRequest reports whether the comment is on a pull request rather than an issue.
+func (e IssueCommentEvent) IsPullRequest() bool {
+	return e.Issue.PullRequest != nil
+}
+
+func (e *IssueCommentEvent) eventType() string {
+	return "issue_comment"
+}
+
+func (e *IssueCommentEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "action", e.Action)
+	missing = required(missing, "issue.number", e.Issue.Number)
+	missing = required(missing, "comment.id", e.Comment.ID)
+	return required(missing, "repository.full_name", e.Repository.FullName)
+}
+
+// CheckRunEvent is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#check_run
+type CheckRunEvent struct {
+	Action       string             `json:"action"`
+	CheckRun     CheckRun           `json:"check_run"`
+	Repository   EventRepository    `json:"repository"`
+	Sender       github.User        `json:"sender"`
+	Installation *EventInstallation `json:"installation,omitempty"`
+}
+
+// HeadSHA returns the SHA of the commit that is being checked.
+func (e CheckRunEvent) HeadSHA() string {
+	return e.CheckRun.HeadSHA
+}
+
+func (e *CheckRunEvent) eventType() string {
+	return "check_run"
+}
+
+func (e *CheckRunEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "action", e.Action)
+	missing = required(missing, "check_run.id", e.CheckRun.ID)
+	return required(missing, "check_run.head_sha", e.CheckRun.HeadSHA)
+}
+
+// CheckSuiteEvent is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#check_suite
+type CheckSuiteEvent struct {
+	Action       string             `json:"action"`
+	CheckSuite   CheckSuite         `json:"check_suite"`
+	Repository   EventRepository    `json:"repository"`
+	Sender       github.User        `json:"sender"`
+	Installation *EventInstallation `json:"installation,omitempty"`
+}
+
+// CheckSuite is based on:
+// https://docs.github.com/en/rest/checks/suites?apiVersion=2022-11-28#get-a-check-suite
+type CheckSuite struct {
+	ID         int64  `json:"id"`
+	HeadBranch string `json:"head_branch,omitempty"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+}
+
+// HeadSHA returns the SHA of the commit that is being checked.
+func (e CheckSuiteEvent) HeadSHA() string {
+	return e.CheckSuite.HeadSHA
+}
+
+func (e *CheckSuiteEvent) eventType() string {
+	return "check_suite"
+}
+
+func (e *CheckSuiteEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "action", e.Action)
+	missing = required(missing, "check_suite.id", e.CheckSuite.ID)
+	return required(missing, "check_suite.head_sha", e.CheckSuite.HeadSHA)
+}
+
+// WorkflowRunEvent is based on:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#workflow_run
+type WorkflowRunEvent struct {
+	Action       string             `json:"action"`
+	WorkflowRun  WorkflowRun        `json:"workflow_run"`
+	Workflow     map[string]any     `json:"workflow,omitempty"`
+	Repository   EventRepository    `json:"repository"`
+	Sender       github.User        `json:"sender"`
+	Installation *EventInstallation `json:"installation,omitempty"`
+}
+
+// WorkflowRun is based on:
+// https://docs.github.com/en/rest/actions/workflow-runs?apiVersion=2022-11-28#get-a-workflow-run
+type WorkflowRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	WorkflowID int64  `json:"workflow_id"`
+	HeadBranch string `json:"head_branch"`
+	HeadSHA    string `json:"head_sha"`
+	Path       string `json:"path,omitempty"`
+	Event      string `json:"event"` // The event that triggered the run, e.g. "push".
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+	RunNumber  int    `json:"run_number"`
+	RunAttempt int    `json:"run_attempt"`
+	HTMLURL    string `json:"html_url"`
+	CreatedAt  string `json:"created_at,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+}
+
+// HeadSHA returns the SHA of the commit on which the workflow ran.
+func (e WorkflowRunEvent) HeadSHA() string {
+	return e.WorkflowRun.HeadSHA
+}
+
+func (e *WorkflowRunEvent) eventType() string {
+	return "workflow_run"
+}
+
+func (e *WorkflowRunEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "action", e.Action)
+	missing = required(missing, "workflow_run.id", e.WorkflowRun.ID)
+	return required(missing, "workflow_run.head_sha", e.WorkflowRun.HeadSHA)
+}
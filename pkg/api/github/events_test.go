@@ -0,0 +1,363 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readEvent reads a captured GitHub webhook payload from the "testdata/events" directory.
+func readEvent(t *testing.T, name string) map[string]any {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "events", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDecodePullRequestEvent(t *testing.T) {
+	payload := readEvent(t, "pull_request")
+	payload[originalEventKey] = "pull_request"
+
+	got, err := Decode[PullRequestEvent](payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.Action != "closed" || got.Number != 42 || got.PullRequest.Number != 42 {
+		t.Errorf("Decode() = action %q, number %d", got.Action, got.Number)
+	}
+	if sha := got.HeadSHA(); sha != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("HeadSHA() = %q", sha)
+	}
+	if !got.IsMergedClose() {
+		t.Error("IsMergedClose() = false, want true")
+	}
+	if got.PullRequest.MergedBy == nil || got.PullRequest.MergedBy.Login != "hubot" {
+		t.Errorf("Decode().PullRequest.MergedBy = %+v", got.PullRequest.MergedBy)
+	}
+	if got.Repository.FullName != "octo-org/hello-world" || got.Repository.Owner.Login != "octo-org" {
+		t.Errorf("Decode().Repository = %+v", got.Repository)
+	}
+	if got.Installation == nil || got.Installation.ID != 55555555 {
+		t.Errorf("Decode().Installation = %+v", got.Installation)
+	}
+}
+
+func TestPullRequestEventIsMergedClose(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		merged bool
+		want   bool
+	}{
+		{
+			name:   "merged",
+			action: "closed",
+			merged: true,
+			want:   true,
+		},
+		{
+			name:   "closed_without_merge",
+			action: "closed",
+		},
+		{
+			name:   "not_closed",
+			action: "synchronize",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := PullRequestEvent{Action: tt.action}
+			e.PullRequest.Merged = tt.merged
+			if got := e.IsMergedClose(); got != tt.want {
+				t.Errorf("IsMergedClose() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePushEvent(t *testing.T) {
+	got, err := Decode[PushEvent](readEvent(t, "push"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if sha := got.HeadSHA(); sha != "9f1c2e3d4b5a69788796a5b4c3d2e1f0a9b8c7d6" {
+		t.Errorf("HeadSHA() = %q", sha)
+	}
+	if b := got.Branch(); b != "main" {
+		t.Errorf("Branch() = %q, want %q", b, "main")
+	}
+	if got.Pusher.Name != "hubot" {
+		t.Errorf("Decode().Pusher = %+v", got.Pusher)
+	}
+	if len(got.Commits) != 1 || got.HeadCommit == nil || got.HeadCommit.Author.Name != "Hubot" {
+		t.Errorf("Decode().Commits = %+v, HeadCommit = %+v", got.Commits, got.HeadCommit)
+	}
+	want := []string{"uploader/retry.go", "uploader/upload.go"}
+	if !reflect.DeepEqual(got.HeadCommit.Modified, want) {
+		t.Errorf("Decode().HeadCommit.Modified = %v, want %v", got.HeadCommit.Modified, want)
+	}
+	if got.Repository.FullName != "octo-org/hello-world" {
+		t.Errorf("Decode().Repository = %+v", got.Repository)
+	}
+}
+
+func TestPushEventBranch(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{
+			name: "branch",
+			ref:  "refs/heads/feature/branch",
+			want: "feature/branch",
+		},
+		{
+			name: "tag",
+			ref:  "refs/tags/v1.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (PushEvent{Ref: tt.ref}).Branch(); got != tt.want {
+				t.Errorf("Branch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeIssueCommentEvent(t *testing.T) {
+	got, err := Decode[IssueCommentEvent](readEvent(t, "issue_comment"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.Action != "created" || got.Issue.Number != 42 {
+		t.Errorf("Decode() = action %q, issue %d", got.Action, got.Issue.Number)
+	}
+	if got.Comment.ID != 2934567890 || got.Comment.Body != "/retest" || got.Comment.User.Login != "hubot" {
+		t.Errorf("Decode().Comment = %+v", got.Comment)
+	}
+	if !got.IsPullRequest() {
+		t.Error("IsPullRequest() = false, want true")
+	}
+}
+
+func TestDecodeCheckRunEvent(t *testing.T) {
+	got, err := Decode[CheckRunEvent](readEvent(t, "check_run"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := CheckRun{
+		ID:         41234567890,
+		Name:       "build (ubuntu-latest)",
+		HeadSHA:    "0123456789abcdef0123456789abcdef01234567",
+		Status:     "completed",
+		Conclusion: "failure",
+		HTMLURL:    "https://github.com/octo-org/hello-world/actions/runs/15345678901/job/41234567890",
+	}
+	want.CheckSuite.ID = 38765432109
+	if !reflect.DeepEqual(got.CheckRun, want) {
+		t.Errorf("Decode().CheckRun = %+v, want %+v", got.CheckRun, want)
+	}
+	if sha := got.HeadSHA(); sha != want.HeadSHA {
+		t.Errorf("HeadSHA() = %q, want %q", sha, want.HeadSHA)
+	}
+}
+
+func TestDecodeCheckSuiteEvent(t *testing.T) {
+	got, err := Decode[CheckSuiteEvent](readEvent(t, "check_suite"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := CheckSuite{
+		ID:         38765432109,
+		HeadBranch: "uploader-retries",
+		HeadSHA:    "0123456789abcdef0123456789abcdef01234567",
+		Status:     "completed",
+		Conclusion: "success",
+	}
+	if !reflect.DeepEqual(got.CheckSuite, want) {
+		t.Errorf("Decode().CheckSuite = %+v, want %+v", got.CheckSuite, want)
+	}
+}
+
+func TestDecodeWorkflowRunEvent(t *testing.T) {
+	got, err := Decode[WorkflowRunEvent](readEvent(t, "workflow_run"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := WorkflowRun{
+		ID:         15345678901,
+		Name:       "CI",
+		WorkflowID: 4567890,
+		HeadBranch: "uploader-retries",
+		HeadSHA:    "0123456789abcdef0123456789abcdef01234567",
+		Path:       ".github/workflows/ci.yml",
+		Event:      "pull_request",
+		Status:     "completed",
+		Conclusion: "success",
+		RunNumber:  812,
+		RunAttempt: 1,
+		HTMLURL:    "https://github.com/octo-org/hello-world/actions/runs/15345678901",
+		CreatedAt:  "2025-06-01T10:00:30Z",
+		UpdatedAt:  "2025-06-01T10:09:00Z",
+	}
+	if !reflect.DeepEqual(got.WorkflowRun, want) {
+		t.Errorf("Decode().WorkflowRun = %+v, want %+v", got.WorkflowRun, want)
+	}
+	if got.Workflow["state"] != "active" {
+		t.Errorf("Decode().Workflow = %v", got.Workflow)
+	}
+}
+
+func TestDecodeMissingFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		delete  func(map[string]any)
+		decode  func(map[string]any) error
+		want    []string
+	}{
+		{
+			name:    "pull_request_without_head",
+			fixture: "pull_request",
+			delete: func(m map[string]any) {
+				delete(m["pull_request"].(map[string]any), "head")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[PullRequestEvent](m)
+				return err
+			},
+			want: []string{"pull_request.head.sha"},
+		},
+		{
+			name:    "push_without_ref_and_repo",
+			fixture: "push",
+			delete: func(m map[string]any) {
+				delete(m, "ref")
+				delete(m, "repository")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[PushEvent](m)
+				return err
+			},
+			want: []string{"ref", "repository.full_name"},
+		},
+		{
+			name:    "issue_comment_without_comment",
+			fixture: "issue_comment",
+			delete: func(m map[string]any) {
+				delete(m, "comment")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[IssueCommentEvent](m)
+				return err
+			},
+			want: []string{"comment.id"},
+		},
+		{
+			name:    "check_run_without_action",
+			fixture: "check_run",
+			delete: func(m map[string]any) {
+				delete(m, "action")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[CheckRunEvent](m)
+				return err
+			},
+			want: []string{"action"},
+		},
+		{
+			name:    "check_suite_without_id",
+			fixture: "check_suite",
+			delete: func(m map[string]any) {
+				delete(m["check_suite"].(map[string]any), "id")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[CheckSuiteEvent](m)
+				return err
+			},
+			want: []string{"check_suite.id"},
+		},
+		{
+			name:    "workflow_run_without_run",
+			fixture: "workflow_run",
+			delete: func(m map[string]any) {
+				delete(m, "workflow_run")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[WorkflowRunEvent](m)
+				return err
+			},
+			want: []string{"workflow_run.id", "workflow_run.head_sha"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := readEvent(t, tt.fixture)
+			tt.delete(payload)
+
+			err := tt.decode(payload)
+			var mfe *MissingFieldsError
+			if !errors.As(err, &mfe) {
+				t.Fatalf("Decode() error = %v, want MissingFieldsError", err)
+			}
+			if !reflect.DeepEqual(mfe.Fields, tt.want) {
+				t.Errorf("Decode() missing fields = %v, want %v", mfe.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeUnexpectedEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		decode  func(map[string]any) error
+	}{
+		{
+			name:    "annotated_push_as_pull_request",
+			payload: map[string]any{"ref": "refs/heads/main", originalEventKey: "push"},
+			decode: func(m map[string]any) error {
+				_, err := Decode[PullRequestEvent](m)
+				return err
+			},
+		},
+		{
+			name:    "invalid_field_type",
+			payload: map[string]any{"action": "completed", "check_run": map[string]any{"id": "not a number"}},
+			decode: func(m map[string]any) error {
+				_, err := Decode[CheckRunEvent](m)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decode(tt.payload)
+			if err == nil {
+				t.Fatal("Decode() error = nil")
+			}
+			var mfe *MissingFieldsError
+			if errors.As(err, &mfe) {
+				t.Errorf("Decode() error = %v, want non-MissingFieldsError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+//revive:disable:exported
+const (
+	GitCreateBlobActivityName   = "github.git.createBlob"
+	GitCreateCommitActivityName = "github.git.createCommit"
+	GitCreateTreeActivityName   = "github.git.createTree"
+	GitGetBlobActivityName      = "github.git.getBlob"
+	GitGetTreeActivityName      = "github.git.getTree"
+	GitUpdateRefActivityName    = "github.git.updateRef"
+) //revive:enable:exported
+
+// rawAccept returns the raw content of Git blobs, instead of JSON with base64 encoding:
+// https://docs.github.com/en/rest/git/blobs?apiVersion=2022-11-28#custom-media-types-for-git-blobs
+const rawAccept = "application/vnd.github.raw+json"
+
+// GitGetBlobRequest is based on:
+// https://docs.github.com/en/rest/git/blobs?apiVersion=2022-11-28#get-a-blob
+//
+// If FilePath is specified, the blob's content is written into a local file (on the Timpani
+// worker's host) instead of being returned, in order to support blobs of any size. MaxSize is
+// optional (default = [client.MaxStreamSize] for files, or 3/4 of [client.MaxResponseSize] for
+// returned content, which is base64-encoded in the activity's JSON result).
+type GitGetBlobRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	FileSHA string `json:"file_sha"`
+
+	FilePath string `json:"file_path,omitempty"`
+	MaxSize  int64  `json:"max_size,omitempty"`
+}
+
+// GitGetBlobResponse contains either the decoded content of a blob, or the
+// path of the local file that it was written into (see [GitGetBlobRequest]).
+type GitGetBlobResponse struct {
+	SHA      string `json:"sha"`
+	Size     int64  `json:"size"`
+	Content  []byte `json:"content,omitempty"`
+	FilePath string `json:"file_path,omitempty"`
+}
+
+// GitGetBlobActivity is based on:
+// https://docs.github.com/en/rest/git/blobs?apiVersion=2022-11-28#get-a-blob
+func (a *API) GitGetBlobActivity(ctx context.Context, req GitGetBlobRequest) (*GitGetBlobResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/git/blobs/%s", req.Owner, req.Repo, req.FileSHA)
+
+	if req.FilePath != "" {
+		t := time.Now().UTC()
+		resp, err := a.httpGetToFile(ctx, req.ThrippyLinkID, req.InstallationID, path, rawAccept, req.FilePath, req.MaxSize)
+		otel.IncrementAPICallCounter(t, GitGetBlobActivityName, err)
+		if err != nil {
+			return nil, err
+		}
+		return &GitGetBlobResponse{SHA: req.FileSHA, Size: resp.Size, FilePath: resp.FilePath}, nil
+	}
+
+	t := time.Now().UTC()
+	resp := new(struct {
+		SHA      string `json:"sha"`
+		Size     int64  `json:"size"`
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	})
+	_, err := a.httpGet(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil, resp)
+	otel.IncrementAPICallCounter(t, GitGetBlobActivityName, err)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := req.MaxSize
+	if maxSize <= 0 {
+		maxSize = int64(client.MaxResponseSize()) * 3 / 4
+	}
+	if resp.Size > maxSize {
+		msg := fmt.Sprintf("%s blob is too large (%d bytes, limit is %d): specify a file path", GitGetBlobActivityName, resp.Size, maxSize)
+		return nil, temporal.NewNonRetryableApplicationError(msg, "ResponseTooLargeError", nil, resp.Size, maxSize)
+	}
+
+	content, err := decodeBlob(resp.Content, resp.Encoding)
+	if err != nil {
+		msg := "failed to decode GitHub blob content"
+		activity.GetLogger(ctx).Error(msg, slog.Any("error", err), slog.String("sha", resp.SHA), slog.String("encoding", resp.Encoding))
+		return nil, temporal.NewNonRetryableApplicationError(fmt.Sprintf("%s: %v", msg, err), fmt.Sprintf("%T", err), err, resp.SHA)
+	}
+
+	return &GitGetBlobResponse{SHA: resp.SHA, Size: resp.Size, Content: content}, nil
+}
+
+// decodeBlob decodes the content of a blob, as returned by the GitHub API.
+// Base64-encoded content is split into lines, which are ignored.
+func decodeBlob(content, encoding string) ([]byte, error) {
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.DecodeString(strings.ReplaceAll(content, "\n", ""))
+	case "utf-8", "":
+		return []byte(content), nil
+	default:
+		return nil, fmt.Errorf("unsupported blob encoding: %q", encoding)
+	}
+}
+
+// GitCreateBlobRequest is based on:
+// https://docs.github.com/en/rest/git/blobs?apiVersion=2022-11-28#create-a-blob
+//
+// The content is always sent base64-encoded, so it may contain binary data.
+type GitCreateBlobRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	Content []byte `json:"content"`
+}
+
+// GitObject identifies a Git object (blob, tree, commit, or tag) in GitHub API responses.
+type GitObject struct {
+	SHA  string `json:"sha"`
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// GitCreateBlobActivity is based on:
+// https://docs.github.com/en/rest/git/blobs?apiVersion=2022-11-28#create-a-blob
+func (a *API) GitCreateBlobActivity(ctx context.Context, req GitCreateBlobRequest) (*GitObject, error) {
+	path := fmt.Sprintf("/repos/%s/%s/git/blobs", req.Owner, req.Repo)
+	body := map[string]string{
+		"content":  base64.StdEncoding.EncodeToString(req.Content),
+		"encoding": "base64",
+	}
+
+	t := time.Now().UTC()
+	resp := new(GitObject)
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, body, resp)
+	otel.IncrementAPICallCounter(t, GitCreateBlobActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GitTreeEntry is based on:
+// https://docs.github.com/en/rest/git/trees?apiVersion=2022-11-28#get-a-tree
+//
+// When creating trees, specify either the SHA of an
+// existing object, or the content of a new blob (but not both).
+type GitTreeEntry struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	SHA     string `json:"sha,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// GitTree is based on:
+// https://docs.github.com/en/rest/git/trees?apiVersion=2022-11-28#get-a-tree
+//
+// Truncated means that GitHub's limit on the number of entries (or Timpani's
+// [client.MaxResponseSize]) was exceeded. To get all the entries, get
+// the subtrees one at a time, non-recursively.
+type GitTree struct {
+	SHA       string         `json:"sha"`
+	URL       string         `json:"url,omitempty"`
+	Tree      []GitTreeEntry `json:"tree"`
+	Truncated bool           `json:"truncated"`
+}
+
+// GitGetTreeRequest is based on:
+// https://docs.github.com/en/rest/git/trees?apiVersion=2022-11-28#get-a-tree
+type GitGetTreeRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	TreeSHA   string `json:"tree_sha"` // Or a branch/tag name.
+	Recursive bool   `json:"recursive,omitempty"`
+}
+
+// GitGetTreeActivity is based on:
+// https://docs.github.com/en/rest/git/trees?apiVersion=2022-11-28#get-a-tree
+func (a *API) GitGetTreeActivity(ctx context.Context, req GitGetTreeRequest) (*GitTree, error) {
+	path := fmt.Sprintf("/repos/%s/%s/git/trees/%s", req.Owner, req.Repo, url.PathEscape(req.TreeSHA))
+	query := url.Values{}
+	if req.Recursive {
+		query.Set("recursive", "true")
+	}
+
+	t := time.Now().UTC()
+	resp := new(GitTree)
+	_, err := a.httpGet(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, query, resp)
+	otel.IncrementAPICallCounter(t, GitGetTreeActivityName, err)
+	if err != nil {
+		return nil, err
+	}
+
+	truncated, err := client.LimitResponseSize(GitGetTreeActivityName, resp, &resp.Tree, true)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		resp.Truncated = true
+	}
+	if resp.Truncated {
+		activity.GetLogger(ctx).Warn("GitHub tree is truncated", slog.String("sha", resp.SHA), slog.Int("entries", len(resp.Tree)))
+	}
+
+	return resp, nil
+}
+
+// GitCreateTreeRequest is based on:
+// https://docs.github.com/en/rest/git/trees?apiVersion=2022-11-28#create-a-tree
+type GitCreateTreeRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner    string         `json:"owner"`
+	Repo     string         `json:"repo"`
+	Tree     []GitTreeEntry `json:"tree"`
+	BaseTree string         `json:"base_tree,omitempty"`
+}
+
+// GitCreateTreeActivity is based on:
+// https://docs.github.com/en/rest/git/trees?apiVersion=2022-11-28#create-a-tree
+func (a *API) GitCreateTreeActivity(ctx context.Context, req GitCreateTreeRequest) (*GitTree, error) {
+	path := fmt.Sprintf("/repos/%s/%s/git/trees", req.Owner, req.Repo)
+	body := struct {
+		Tree     []GitTreeEntry `json:"tree"`
+		BaseTree string         `json:"base_tree,omitempty"`
+	}{Tree: req.Tree, BaseTree: req.BaseTree}
+
+	t := time.Now().UTC()
+	resp := new(GitTree)
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, body, resp)
+	otel.IncrementAPICallCounter(t, GitCreateTreeActivityName, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// The response lists all the entries of the new tree, not just the ones in the request.
+	if _, err := client.LimitResponseSize(GitCreateTreeActivityName, resp, &resp.Tree, true); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GitCommitAuthor is based on:
+// https://docs.github.com/en/rest/git/commits?apiVersion=2022-11-28#create-a-commit
+type GitCommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Date  string `json:"date,omitempty"`
+}
+
+// GitCreateCommitRequest is based on:
+// https://docs.github.com/en/rest/git/commits?apiVersion=2022-11-28#create-a-commit
+//
+// Author and Committer are optional (default = the authenticated user or app).
+type GitCreateCommitRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner     string           `json:"owner"`
+	Repo      string           `json:"repo"`
+	Message   string           `json:"message"`
+	Tree      string           `json:"tree"`
+	Parents   []string         `json:"parents,omitempty"`
+	Author    *GitCommitAuthor `json:"author,omitempty"`
+	Committer *GitCommitAuthor `json:"committer,omitempty"`
+}
+
+// GitCommit is based on:
+// https://docs.github.com/en/rest/git/commits?apiVersion=2022-11-28#create-a-commit
+type GitCommit struct {
+	SHA       string           `json:"sha"`
+	HTMLURL   string           `json:"html_url,omitempty"`
+	Message   string           `json:"message"`
+	Tree      GitObject        `json:"tree"`
+	Parents   []GitObject      `json:"parents"`
+	Author    *GitCommitAuthor `json:"author,omitempty"`
+	Committer *GitCommitAuthor `json:"committer,omitempty"`
+}
+
+// GitCreateCommitActivity is based on:
+// https://docs.github.com/en/rest/git/commits?apiVersion=2022-11-28#create-a-commit
+func (a *API) GitCreateCommitActivity(ctx context.Context, req GitCreateCommitRequest) (*GitCommit, error) {
+	path := fmt.Sprintf("/repos/%s/%s/git/commits", req.Owner, req.Repo)
+	body := struct {
+		Message   string           `json:"message"`
+		Tree      string           `json:"tree"`
+		Parents   []string         `json:"parents,omitempty"`
+		Author    *GitCommitAuthor `json:"author,omitempty"`
+		Committer *GitCommitAuthor `json:"committer,omitempty"`
+	}{Message: req.Message, Tree: req.Tree, Parents: req.Parents, Author: req.Author, Committer: req.Committer}
+
+	t := time.Now().UTC()
+	resp := new(GitCommit)
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, body, resp)
+	otel.IncrementAPICallCounter(t, GitCreateCommitActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GitUpdateRefRequest is based on:
+// https://docs.github.com/en/rest/git/refs?apiVersion=2022-11-28#update-a-reference
+//
+// Ref is fully qualified, but without the "refs/" prefix (e.g. "heads/main").
+type GitUpdateRefRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Ref   string `json:"ref"`
+	SHA   string `json:"sha"`
+	Force bool   `json:"force,omitempty"`
+}
+
+// GitRef is based on:
+// https://docs.github.com/en/rest/git/refs?apiVersion=2022-11-28#update-a-reference
+type GitRef struct {
+	Ref    string    `json:"ref"`
+	URL    string    `json:"url,omitempty"`
+	Object GitObject `json:"object"`
+}
+
+// GitUpdateRefActivity is based on:
+// https://docs.github.com/en/rest/git/refs?apiVersion=2022-11-28#update-a-reference
+func (a *API) GitUpdateRefActivity(ctx context.Context, req GitUpdateRefRequest) (*GitRef, error) {
+	path := fmt.Sprintf("/repos/%s/%s/git/refs/%s", req.Owner, req.Repo, strings.TrimPrefix(req.Ref, "refs/"))
+	body := map[string]any{"sha": req.SHA, "force": req.Force}
+
+	t := time.Now().UTC()
+	resp := new(GitRef)
+	err := a.httpPatch(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, body, resp)
+	otel.IncrementAPICallCounter(t, GitUpdateRefActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
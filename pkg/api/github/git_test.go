@@ -0,0 +1,290 @@
+package github
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestGitBlobRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{
+			name:    "text",
+			content: []byte("hello\nworld\n"),
+		},
+		{
+			name:    "binary",
+			content: []byte{0x00, 0xff, 0xfe, 0x80, 0x7f},
+		},
+		{
+			name:    "multiline_base64",
+			content: []byte(strings.Repeat("0123456789", 20)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored string // Base64.
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPost:
+					body := map[string]string{}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Error(err)
+					}
+					if body["encoding"] != "base64" {
+						t.Errorf("blob encoding = %q, want %q", body["encoding"], "base64")
+					}
+					stored = body["content"]
+					_, _ = w.Write([]byte(`{"sha": "abc", "url": "https://api.github.com/repos/o/r/git/blobs/abc"}`))
+				case http.MethodGet:
+					// GitHub splits base64-encoded content into lines of 60 characters.
+					var lines []string
+					for len(stored) > 60 {
+						lines = append(lines, stored[:60])
+						stored = stored[60:]
+					}
+					lines = append(lines, stored)
+					content := strings.Join(lines, "\n") + "\n"
+					_, _ = fmt.Fprintf(w, `{"sha": "abc", "size": %d, "content": %q, "encoding": "base64"}`, len(tt.content), content)
+				}
+			}))
+			defer s.Close()
+
+			a := testAPI(t, s.URL)
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.GitCreateBlobActivity)
+			env.RegisterActivity(a.GitGetBlobActivity)
+
+			v, err := env.ExecuteActivity(a.GitCreateBlobActivity, GitCreateBlobRequest{Owner: "o", Repo: "r", Content: tt.content})
+			if err != nil {
+				t.Fatalf("GitCreateBlobActivity() error = %v", err)
+			}
+			obj := new(GitObject)
+			if err := v.Get(obj); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err = env.ExecuteActivity(a.GitGetBlobActivity, GitGetBlobRequest{Owner: "o", Repo: "r", FileSHA: obj.SHA})
+			if err != nil {
+				t.Fatalf("GitGetBlobActivity() error = %v", err)
+			}
+			got := new(GitGetBlobResponse)
+			if err := v.Get(got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Content, tt.content) {
+				t.Errorf("GitGetBlobActivity() content = %q, want %q", got.Content, tt.content)
+			}
+		})
+	}
+}
+
+func TestGitGetBlobActivityTooLarge(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		content := base64.StdEncoding.EncodeToString([]byte("0123456789"))
+		_, _ = fmt.Fprintf(w, `{"sha": "abc", "size": 10, "content": %q, "encoding": "base64"}`, content)
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.GitGetBlobActivity)
+
+	_, err := env.ExecuteActivity(a.GitGetBlobActivity, GitGetBlobRequest{Owner: "o", Repo: "r", FileSHA: "abc", MaxSize: 5})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != "ResponseTooLargeError" {
+		t.Errorf("GitGetBlobActivity() error = %v, want ResponseTooLargeError", err)
+	}
+}
+
+func TestGitGetBlobActivityToFile(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != rawAccept {
+			t.Errorf("Accept header = %q, want %q", got, rawAccept)
+		}
+		_, _ = w.Write([]byte("raw content"))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.GitGetBlobActivity)
+
+	path := filepath.Join(t.TempDir(), "blob")
+	v, err := env.ExecuteActivity(a.GitGetBlobActivity, GitGetBlobRequest{Owner: "o", Repo: "r", FileSHA: "abc", FilePath: path})
+	if err != nil {
+		t.Fatalf("GitGetBlobActivity() error = %v", err)
+	}
+
+	got := new(GitGetBlobResponse)
+	if err := v.Get(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &GitGetBlobResponse{SHA: "abc", Size: 11, FilePath: path}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GitGetBlobActivity() = %+v, want %+v", got, want)
+	}
+
+	b, err := os.ReadFile(path) //gosec:disable G304 // Test file.
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "raw content" {
+		t.Errorf("file content = %q, want %q", b, "raw content")
+	}
+}
+
+func TestDecodeBlob(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		encoding string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "base64",
+			content:  "aGVs\nbG8=\n",
+			encoding: "base64",
+			want:     "hello",
+		},
+		{
+			name:     "utf8",
+			content:  "hello",
+			encoding: "utf-8",
+			want:     "hello",
+		},
+		{
+			name:     "invalid_base64",
+			content:  "!!!",
+			encoding: "base64",
+			wantErr:  true,
+		},
+		{
+			name:     "unsupported_encoding",
+			content:  "hello",
+			encoding: "utf-16",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBlob(tt.content, tt.encoding)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeBlob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decodeBlob() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitGetTreeActivity(t *testing.T) {
+	tests := []struct {
+		name          string
+		recursive     bool
+		truncated     bool
+		wantQuery     string
+		wantTruncated bool
+	}{
+		{
+			name: "non_recursive",
+		},
+		{
+			name:      "recursive",
+			recursive: true,
+			wantQuery: "recursive=true",
+		},
+		{
+			name:          "truncated_by_github",
+			recursive:     true,
+			truncated:     true,
+			wantQuery:     "recursive=true",
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.EscapedPath(); got != "/repos/o/r/git/trees/feature%2Fbranch" {
+					t.Errorf("unexpected URL path: %s", got)
+				}
+				if got := r.URL.RawQuery; got != tt.wantQuery {
+					t.Errorf("URL query = %q, want %q", got, tt.wantQuery)
+				}
+				_, _ = fmt.Fprintf(w, `{"sha": "abc", "tree": [{"path": "a.txt", "mode": "100644", "type": "blob", "sha": "def", "size": 1}], "truncated": %t}`, tt.truncated)
+			}))
+			defer s.Close()
+
+			a := testAPI(t, s.URL)
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.GitGetTreeActivity)
+
+			req := GitGetTreeRequest{Owner: "o", Repo: "r", TreeSHA: "feature/branch", Recursive: tt.recursive}
+			v, err := env.ExecuteActivity(a.GitGetTreeActivity, req)
+			if err != nil {
+				t.Fatalf("GitGetTreeActivity() error = %v", err)
+			}
+
+			got := new(GitTree)
+			if err := v.Get(got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Tree) != 1 || got.Tree[0].Path != "a.txt" {
+				t.Errorf("GitGetTreeActivity() tree = %+v, want 1 entry", got.Tree)
+			}
+			if got.Truncated != tt.wantTruncated {
+				t.Errorf("GitGetTreeActivity() truncated = %v, want %v", got.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestGitUpdateRefActivity(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("HTTP method = %s, want %s", r.Method, http.MethodPatch)
+		}
+		if got := r.URL.Path; got != "/repos/o/r/git/refs/heads/feature/branch" {
+			t.Errorf("unexpected URL path: %s", got)
+		}
+		_, _ = w.Write([]byte(`{"ref": "refs/heads/feature/branch", "object": {"sha": "abc", "type": "commit"}}`))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.GitUpdateRefActivity)
+
+	req := GitUpdateRefRequest{Owner: "o", Repo: "r", Ref: "refs/heads/feature/branch", SHA: "abc"}
+	v, err := env.ExecuteActivity(a.GitUpdateRefActivity, req)
+	if err != nil {
+		t.Fatalf("GitUpdateRefActivity() error = %v", err)
+	}
+
+	got := new(GitRef)
+	if err := v.Get(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Object.SHA != "abc" {
+		t.Errorf("GitUpdateRefActivity() object SHA = %q, want %q", got.Object.SHA, "abc")
+	}
+}
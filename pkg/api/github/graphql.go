@@ -0,0 +1,67 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// graphqlPath is a placeholder path which [API.httpRequestPrep]
+// replaces with the GraphQL API endpoint, see [graphqlURL].
+const graphqlPath = "/graphql"
+
+// graphqlRequest is based on:
+// https://docs.github.com/en/graphql/guides/forming-calls-with-graphql#communicating-with-graphql
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphqlError  `json:"errors,omitempty"`
+}
+
+type graphqlError struct {
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// graphqlURL returns the GraphQL API endpoint that corresponds to a REST API base URL:
+// https://docs.github.com/en/graphql/guides/forming-calls-with-graphql#the-graphql-endpoint
+func graphqlURL(baseURL string) string {
+	if base, ok := strings.CutSuffix(baseURL, "/api/v3"); ok {
+		return base + "/api/graphql" // GitHub Enterprise Server (GHES).
+	}
+	return strings.TrimSuffix(baseURL, "/") + graphqlPath
+}
+
+// graphql sends a GraphQL query or mutation, and decodes the "data" field of the response
+// into the given value. GitHub reports GraphQL errors with an HTTP 200 status, so they are
+// converted into activity errors here. Only rate-limit errors are retryable.
+func (a *API) graphql(ctx context.Context, linkID string, installID int64, query string, vars map[string]any, data any) error {
+	resp := new(graphqlResponse)
+	body := graphqlRequest{Query: query, Variables: vars}
+	if err := a.httpPost(ctx, linkID, installID, graphqlPath, defaultAccept, body, resp); err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		e := resp.Errors[0]
+		msg := "GitHub GraphQL error: " + e.Message
+		if e.Type == "RATE_LIMITED" {
+			return temporal.NewApplicationError(msg, "GitHubGraphQLError", e.Type)
+		}
+		return temporal.NewNonRetryableApplicationError(msg, "GitHubGraphQLError", nil, e.Type, e.Path)
+	}
+
+	if err := json.Unmarshal(resp.Data, data); err != nil {
+		msg := fmt.Sprintf("failed to decode GitHub GraphQL response data: %v", err)
+		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, string(resp.Data))
+	}
+	return nil
+}
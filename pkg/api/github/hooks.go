@@ -0,0 +1,273 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+//revive:disable:exported
+const (
+	OrgsCreateWebhookActivityName  = "github.orgs.createWebhook"
+	OrgsDeleteWebhookActivityName  = "github.orgs.deleteWebhook"
+	OrgsListWebhooksActivityName   = "github.orgs.listWebhooks"
+	OrgsPingWebhookActivityName    = "github.orgs.pingWebhook"
+	ReposCreateWebhookActivityName = "github.repos.createWebhook"
+	ReposDeleteWebhookActivityName = "github.repos.deleteWebhook"
+	ReposListWebhooksActivityName  = "github.repos.listWebhooks"
+	ReposPingWebhookActivityName   = "github.repos.pingWebhook"
+) //revive:enable:exported
+
+// linkWebhookSecret is the name of the Thrippy link secret which
+// GitHub webhook handlers use to verify incoming deliveries.
+const linkWebhookSecret = "webhook_secret"
+
+// WebhookConfig is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#create-a-repository-webhook
+type WebhookConfig struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"` // "json" (default) or "form".
+	Secret      string `json:"secret,omitempty"`       // Redacted by GitHub in responses.
+	InsecureSSL string `json:"insecure_ssl,omitempty"` // "0" (default) or "1".
+}
+
+// Webhook is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#get-a-repository-webhook
+type Webhook struct {
+	ID            int64         `json:"id"`
+	Name          string        `json:"name"`
+	Active        bool          `json:"active"`
+	Events        []string      `json:"events"`
+	Config        WebhookConfig `json:"config"`
+	URL           string        `json:"url"`
+	PingURL       string        `json:"ping_url,omitempty"`
+	DeliveriesURL string        `json:"deliveries_url,omitempty"`
+	CreatedAt     string        `json:"created_at"`
+	UpdatedAt     string        `json:"updated_at"`
+
+	LastResponse *WebhookLastResponse `json:"last_response,omitempty"` // Only in repository webhooks.
+}
+
+// WebhookLastResponse is the status of the latest delivery of a repository webhook.
+type WebhookLastResponse struct {
+	Code    int    `json:"code,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// WebhookParams are the settings of a new webhook. If UseLinkSecret is true, the secret is
+// the "webhook_secret" of the activity's Thrippy link, instead of [WebhookConfig.Secret],
+// so it isn't recorded in the workflow's history, and webhook deliveries to that link
+// are verified with it automatically.
+type WebhookParams struct {
+	Config        WebhookConfig `json:"config"`
+	Events        []string      `json:"events,omitempty"` // Default = ["push"].
+	Active        *bool         `json:"active,omitempty"` // Default = true.
+	UseLinkSecret bool          `json:"use_link_secret,omitempty"`
+}
+
+type webhookBody struct {
+	Name   string        `json:"name"`
+	Config WebhookConfig `json:"config"`
+	Events []string      `json:"events,omitempty"`
+	Active *bool         `json:"active,omitempty"`
+}
+
+// ReposCreateWebhookRequest is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#create-a-repository-webhook
+type ReposCreateWebhookRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	WebhookParams
+}
+
+// ReposCreateWebhookActivity is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#create-a-repository-webhook
+func (a *API) ReposCreateWebhookActivity(ctx context.Context, req ReposCreateWebhookRequest) (*Webhook, error) {
+	path := fmt.Sprintf("/repos/%s/%s/hooks", req.Owner, req.Repo)
+	return a.createWebhook(ctx, ReposCreateWebhookActivityName, req.ThrippyLinkID, req.InstallationID, path, req.WebhookParams)
+}
+
+// ReposListWebhooksRequest is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#list-repository-webhooks
+type ReposListWebhooksRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+
+	// https://docs.github.com/rest/using-the-rest-api/using-pagination-in-the-rest-api
+	PerPage int `json:"per_page,omitempty"`
+	Page    int `json:"page,omitempty"`
+}
+
+// ReposListWebhooksActivity is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#list-repository-webhooks
+//
+// Pagination is handled internally, unless the request specifies a page or page size.
+func (a *API) ReposListWebhooksActivity(ctx context.Context, req ReposListWebhooksRequest) ([]Webhook, error) {
+	path := fmt.Sprintf("/repos/%s/%s/hooks", req.Owner, req.Repo)
+	return paginatedActivity[Webhook](ctx, a, ReposListWebhooksActivityName, req.ThrippyLinkID, req.InstallationID, path, nil, req.PerPage, req.Page)
+}
+
+// ReposWebhookRequest identifies a repository webhook.
+type ReposWebhookRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	HookID int64  `json:"hook_id"`
+}
+
+// ReposPingWebhookActivity is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#ping-a-repository-webhook
+func (a *API) ReposPingWebhookActivity(ctx context.Context, req ReposWebhookRequest) error {
+	path := fmt.Sprintf("/repos/%s/%s/hooks/%d/pings", req.Owner, req.Repo, req.HookID)
+
+	t := time.Now().UTC()
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil, nil)
+	otel.IncrementAPICallCounter(t, ReposPingWebhookActivityName, err)
+
+	return err
+}
+
+// ReposDeleteWebhookActivity is based on:
+// https://docs.github.com/en/rest/repos/webhooks?apiVersion=2022-11-28#delete-a-repository-webhook
+func (a *API) ReposDeleteWebhookActivity(ctx context.Context, req ReposWebhookRequest) error {
+	path := fmt.Sprintf("/repos/%s/%s/hooks/%d", req.Owner, req.Repo, req.HookID)
+
+	t := time.Now().UTC()
+	err := a.httpDelete(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil)
+	otel.IncrementAPICallCounter(t, ReposDeleteWebhookActivityName, err)
+
+	return err
+}
+
+// OrgsCreateWebhookRequest is based on:
+// https://docs.github.com/en/rest/orgs/webhooks?apiVersion=2022-11-28#create-an-organization-webhook
+type OrgsCreateWebhookRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Org string `json:"org"`
+	WebhookParams
+}
+
+// OrgsCreateWebhookActivity is based on:
+// https://docs.github.com/en/rest/orgs/webhooks?apiVersion=2022-11-28#create-an-organization-webhook
+func (a *API) OrgsCreateWebhookActivity(ctx context.Context, req OrgsCreateWebhookRequest) (*Webhook, error) {
+	path := fmt.Sprintf("/orgs/%s/hooks", req.Org)
+	return a.createWebhook(ctx, OrgsCreateWebhookActivityName, req.ThrippyLinkID, req.InstallationID, path, req.WebhookParams)
+}
+
+// OrgsListWebhooksRequest is based on:
+// https://docs.github.com/en/rest/orgs/webhooks?apiVersion=2022-11-28#list-organization-webhooks
+type OrgsListWebhooksRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Org string `json:"org"`
+
+	// https://docs.github.com/rest/using-the-rest-api/using-pagination-in-the-rest-api
+	PerPage int `json:"per_page,omitempty"`
+	Page    int `json:"page,omitempty"`
+}
+
+// OrgsListWebhooksActivity is based on:
+// https://docs.github.com/en/rest/orgs/webhooks?apiVersion=2022-11-28#list-organization-webhooks
+//
+// Pagination is handled internally, unless the request specifies a page or page size.
+func (a *API) OrgsListWebhooksActivity(ctx context.Context, req OrgsListWebhooksRequest) ([]Webhook, error) {
+	path := fmt.Sprintf("/orgs/%s/hooks", req.Org)
+	return paginatedActivity[Webhook](ctx, a, OrgsListWebhooksActivityName, req.ThrippyLinkID, req.InstallationID, path, nil, req.PerPage, req.Page)
+}
+
+// OrgsWebhookRequest identifies an organization webhook.
+type OrgsWebhookRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Org    string `json:"org"`
+	HookID int64  `json:"hook_id"`
+}
+
+// OrgsPingWebhookActivity is based on:
+// https://docs.github.com/en/rest/orgs/webhooks?apiVersion=2022-11-28#ping-an-organization-webhook
+func (a *API) OrgsPingWebhookActivity(ctx context.Context, req OrgsWebhookRequest) error {
+	path := fmt.Sprintf("/orgs/%s/hooks/%d/pings", req.Org, req.HookID)
+
+	t := time.Now().UTC()
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil, nil)
+	otel.IncrementAPICallCounter(t, OrgsPingWebhookActivityName, err)
+
+	return err
+}
+
+// OrgsDeleteWebhookActivity is based on:
+// https://docs.github.com/en/rest/orgs/webhooks?apiVersion=2022-11-28#delete-an-organization-webhook
+func (a *API) OrgsDeleteWebhookActivity(ctx context.Context, req OrgsWebhookRequest) error {
+	path := fmt.Sprintf("/orgs/%s/hooks/%d", req.Org, req.HookID)
+
+	t := time.Now().UTC()
+	err := a.httpDelete(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil)
+	otel.IncrementAPICallCounter(t, OrgsDeleteWebhookActivityName, err)
+
+	return err
+}
+
+// createWebhook implements both [API.ReposCreateWebhookActivity] and [API.OrgsCreateWebhookActivity].
+func (a *API) createWebhook(ctx context.Context, activityName, linkID string, installID int64, path string, p WebhookParams) (*Webhook, error) {
+	body, err := a.webhookBody(ctx, linkID, p)
+	if err != nil {
+		return nil, err
+	}
+
+	t := time.Now().UTC()
+	resp := new(Webhook)
+	err = a.httpPost(ctx, linkID, installID, path, defaultAccept, body, resp)
+	otel.IncrementAPICallCounter(t, activityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// webhookBody constructs the JSON body of a webhook creation request, and
+// resolves the webhook secret from the Thrippy link's credentials if needed.
+func (a *API) webhookBody(ctx context.Context, linkID string, p WebhookParams) (*webhookBody, error) {
+	cfg := p.Config
+	if cfg.ContentType == "" {
+		cfg.ContentType = "json"
+	}
+
+	if p.UseLinkSecret {
+		if cfg.Secret != "" {
+			msg := "webhook secret and use_link_secret are mutually exclusive"
+			return nil, temporal.NewNonRetryableApplicationError(msg, "error", errors.New(msg))
+		}
+
+		secrets, err := a.thrippy.LinkCreds(ctx, linkID)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Secret = secrets[linkWebhookSecret]
+		if cfg.Secret == "" {
+			msg := "webhook secret not found in Thrippy link credentials"
+			return nil, temporal.NewNonRetryableApplicationError(msg, "error", errors.New(msg), linkID)
+		}
+	}
+
+	return &webhookBody{Name: "web", Config: cfg, Events: p.Events, Active: p.Active}, nil
+}
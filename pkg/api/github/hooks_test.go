@@ -0,0 +1,165 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestCreateWebhookActivities(t *testing.T) {
+	tests := []struct {
+		name       string
+		secrets    map[string]string
+		params     WebhookParams
+		wantSecret string
+		wantErr    bool
+	}{
+		{
+			name:       "plaintext_secret",
+			secrets:    map[string]string{},
+			params:     WebhookParams{Config: WebhookConfig{URL: "https://example.com/webhook", Secret: "plaintext"}},
+			wantSecret: "plaintext",
+		},
+		{
+			name:       "link_secret",
+			secrets:    map[string]string{"webhook_secret": "from link"},
+			params:     WebhookParams{Config: WebhookConfig{URL: "https://example.com/webhook"}, UseLinkSecret: true},
+			wantSecret: "from link",
+		},
+		{
+			name:    "missing_link_secret",
+			secrets: map[string]string{},
+			params:  WebhookParams{Config: WebhookConfig{URL: "https://example.com/webhook"}, UseLinkSecret: true},
+			wantErr: true,
+		},
+		{
+			name:    "both_secrets",
+			secrets: map[string]string{"webhook_secret": "from link"},
+			params:  WebhookParams{Config: WebhookConfig{URL: "https://example.com/webhook", Secret: "plaintext"}, UseLinkSecret: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+
+				body := new(webhookBody)
+				if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+					t.Error(err)
+				}
+				want := &webhookBody{Name: "web", Events: []string{"push", "pull_request"}, Config: WebhookConfig{
+					URL: "https://example.com/webhook", ContentType: "json", Secret: tt.wantSecret,
+				}}
+				if !reflect.DeepEqual(body, want) {
+					t.Errorf("request body = %+v, want %+v", body, want)
+				}
+
+				_, _ = w.Write([]byte(`{"id": 123, "name": "web", "active": true, "config": {"secret": "********"}}`))
+			}))
+			defer s.Close()
+
+			tt.secrets["api_base_url"] = s.URL
+			tt.secrets["pat"] = "token"
+			a := testLinkAPI(t, tt.secrets)
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.ReposCreateWebhookActivity)
+			env.RegisterActivity(a.OrgsCreateWebhookActivity)
+
+			tt.params.Events = []string{"push", "pull_request"}
+			_, err1 := env.ExecuteActivity(a.ReposCreateWebhookActivity, ReposCreateWebhookRequest{Owner: "o", Repo: "r", WebhookParams: tt.params})
+			_, err2 := env.ExecuteActivity(a.OrgsCreateWebhookActivity, OrgsCreateWebhookRequest{Org: "o", WebhookParams: tt.params})
+
+			for _, err := range []error{err1, err2} {
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("create webhook activity error = %v, wantErr %v", err, tt.wantErr)
+				}
+				var appErr *temporal.ApplicationError
+				if tt.wantErr && (!errors.As(err, &appErr) || !appErr.NonRetryable()) {
+					t.Errorf("create webhook activity error = %v, want non-retryable", err)
+				}
+			}
+
+			if tt.wantErr {
+				if len(paths) > 0 {
+					t.Errorf("unexpected API calls: %v", paths)
+				}
+				return
+			}
+			if want := []string{"/repos/o/r/hooks", "/orgs/o/hooks"}; !reflect.DeepEqual(paths, want) {
+				t.Errorf("API call paths = %v, want %v", paths, want)
+			}
+		})
+	}
+}
+
+func TestWebhookActivitiesPaths(t *testing.T) {
+	var got []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"id": 1}, {"id": 2}]`))
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.ReposListWebhooksActivity)
+	env.RegisterActivity(a.ReposPingWebhookActivity)
+	env.RegisterActivity(a.ReposDeleteWebhookActivity)
+	env.RegisterActivity(a.OrgsListWebhooksActivity)
+	env.RegisterActivity(a.OrgsPingWebhookActivity)
+	env.RegisterActivity(a.OrgsDeleteWebhookActivity)
+
+	v, err := env.ExecuteActivity(a.ReposListWebhooksActivity, ReposListWebhooksRequest{Owner: "o", Repo: "r"})
+	if err != nil {
+		t.Fatalf("ReposListWebhooksActivity() error = %v", err)
+	}
+	var hooks []Webhook
+	if err := v.Get(&hooks); err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 2 || hooks[1].ID != 2 {
+		t.Errorf("ReposListWebhooksActivity() = %+v, want 2 webhooks", hooks)
+	}
+
+	repoHook := ReposWebhookRequest{Owner: "o", Repo: "r", HookID: 123}
+	orgHook := OrgsWebhookRequest{Org: "o", HookID: 456}
+	for _, f := range []func() error{
+		func() error { _, err := env.ExecuteActivity(a.ReposPingWebhookActivity, repoHook); return err },
+		func() error { _, err := env.ExecuteActivity(a.ReposDeleteWebhookActivity, repoHook); return err },
+		func() error {
+			_, err := env.ExecuteActivity(a.OrgsListWebhooksActivity, OrgsListWebhooksRequest{Org: "o"})
+			return err
+		},
+		func() error { _, err := env.ExecuteActivity(a.OrgsPingWebhookActivity, orgHook); return err },
+		func() error { _, err := env.ExecuteActivity(a.OrgsDeleteWebhookActivity, orgHook); return err },
+	} {
+		if err := f(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"GET /repos/o/r/hooks",
+		"POST /repos/o/r/hooks/123/pings",
+		"DELETE /repos/o/r/hooks/123",
+		"GET /orgs/o/hooks",
+		"POST /orgs/o/hooks/456/pings",
+		"DELETE /orgs/o/hooks/456",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("API calls = %v, want %v", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/tzrikka/timpani-api/pkg/github"
+	"github.com/tzrikka/timpani/internal/idempotency"
 	"github.com/tzrikka/timpani/pkg/otel"
 )
 
@@ -13,29 +14,55 @@ type issueCommentMarkdown struct {
 	Body string `json:"body"`
 }
 
+// IssuesCommentsCreateRequest extends [github.IssuesCommentsCreateRequest] with an
+// optional idempotency key, which prevents retries of this activity from creating
+// duplicate comments if a previous attempt already succeeded (best-effort, see the
+// caveats in [idempotency]). Its JSON encoding is compatible with the original.
+type IssuesCommentsCreateRequest struct {
+	github.IssuesCommentsCreateRequest
+	Installation
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
 // IssuesCommentsCreateActivity is based on:
 // https://docs.github.com/en/rest/issues/comments?apiVersion=2022-11-28#create-an-issue-comment
-func (a *API) IssuesCommentsCreateActivity(ctx context.Context, req github.IssuesCommentsCreateRequest) (*github.IssueComment, error) {
+func (a *API) IssuesCommentsCreateActivity(ctx context.Context, req IssuesCommentsCreateRequest) (*github.IssueComment, error) {
 	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", req.Owner, req.Repo, req.IssueNumber)
 
-	t := time.Now().UTC()
-	resp := new(github.IssueComment)
-	err := a.httpPost(ctx, req.ThrippyLinkID, path, "application/vnd.github.raw+json", issueCommentMarkdown{Body: req.Body}, resp)
-	otel.IncrementAPICallCounter(t, github.IssuesCommentsCreateActivityName, err)
+	return idempotency.Do(ctx, github.IssuesCommentsCreateActivityName, req.IdempotencyKey, func() (*github.IssueComment, error) {
+		t := time.Now().UTC()
+		resp := new(github.IssueComment)
+		accept := "application/vnd.github.raw+json"
+		err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, accept, issueCommentMarkdown{Body: req.Body}, resp)
+		otel.IncrementAPICallCounter(t, github.IssuesCommentsCreateActivityName, err)
 
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
 }
 
+// Issue comment activity requests, with an optional [Installation].
+type (
+	IssuesCommentsDeleteRequest struct {
+		github.IssuesCommentsDeleteRequest
+		Installation
+	}
+	IssuesCommentsUpdateRequest struct {
+		github.IssuesCommentsUpdateRequest
+		Installation
+	}
+)
+
 // IssuesCommentsDeleteActivity is based on:
 // https://docs.github.com/en/rest/issues/comments?apiVersion=2022-11-28#delete-an-issue-comment
-func (a *API) IssuesCommentsDeleteActivity(ctx context.Context, req github.IssuesCommentsDeleteRequest) error {
+func (a *API) IssuesCommentsDeleteActivity(ctx context.Context, req IssuesCommentsDeleteRequest) error {
 	path := fmt.Sprintf("/repos/%s/%s/issues/comments/%d", req.Owner, req.Repo, req.CommentID)
 
 	t := time.Now().UTC()
-	err := a.httpDelete(ctx, req.ThrippyLinkID, path, nil)
+	err := a.httpDelete(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil)
 	otel.IncrementAPICallCounter(t, github.IssuesCommentsDeleteActivityName, err)
 
 	if err != nil {
@@ -46,12 +73,13 @@ func (a *API) IssuesCommentsDeleteActivity(ctx context.Context, req github.Issue
 
 // IssuesCommentsUpdateActivity is based on:
 // https://docs.github.com/en/rest/issues/comments?apiVersion=2022-11-28#update-an-issue-comment
-func (a *API) IssuesCommentsUpdateActivity(ctx context.Context, req github.IssuesCommentsUpdateRequest) (*github.IssueComment, error) {
+func (a *API) IssuesCommentsUpdateActivity(ctx context.Context, req IssuesCommentsUpdateRequest) (*github.IssueComment, error) {
 	path := fmt.Sprintf("/repos/%s/%s/issues/comments/%d", req.Owner, req.Repo, req.CommentID)
 
 	t := time.Now().UTC()
 	resp := new(github.IssueComment)
-	err := a.httpPatch(ctx, req.ThrippyLinkID, path, "application/vnd.github.raw+json", issueCommentMarkdown{Body: req.Body}, resp)
+	accept := "application/vnd.github.raw+json"
+	err := a.httpPatch(ctx, req.ThrippyLinkID, req.InstallationID, path, accept, issueCommentMarkdown{Body: req.Body}, resp)
 	otel.IncrementAPICallCounter(t, github.IssuesCommentsUpdateActivityName, err)
 
 	if err != nil {
@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/github"
+)
+
+func TestIssuesCommentsCreateIdempotency(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		wantPosts int32
+	}{
+		{
+			name:      "without_key",
+			wantPosts: 2,
+		},
+		{
+			name:      "with_key",
+			key:       t.Name() + "/with_key",
+			wantPosts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts atomic.Int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				posts.Add(1)
+				_, _ = w.Write([]byte(`{"id": 1}`))
+			}))
+			defer s.Close()
+
+			// The API call succeeds, but the first attempt of the activity fails afterwards.
+			a := testAPI(t, s.URL)
+			act := func(ctx context.Context, req IssuesCommentsCreateRequest) (*github.IssueComment, error) {
+				resp, err := a.IssuesCommentsCreateActivity(ctx, req)
+				if err == nil && activity.GetInfo(ctx).Attempt == 1 {
+					return nil, errors.New("lost activity completion")
+				}
+				return resp, err
+			}
+
+			wf := func(ctx workflow.Context, req IssuesCommentsCreateRequest) error {
+				ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+					StartToCloseTimeout: time.Minute,
+					RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 2},
+				})
+				return workflow.ExecuteActivity(ctx, "act", req).Get(ctx, nil)
+			}
+
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(act, activity.RegisterOptions{Name: "act"})
+			env.RegisterWorkflow(wf)
+
+			req := IssuesCommentsCreateRequest{IdempotencyKey: tt.key}
+			req.Owner, req.Repo, req.IssueNumber, req.Body = "owner", "repo", 1, "comment"
+			env.ExecuteWorkflow(wf, req)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("workflow error = %v", err)
+			}
+			if got := posts.Load(); got != tt.wantPosts {
+				t.Errorf("HTTP POST requests = %d, want %d", got, tt.wantPosts)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// MetaGetActivityName is a Timpani-specific activity, see [API.MetaGetActivity].
+const MetaGetActivityName = "github.meta.get"
+
+// MetaGetRequest is based on:
+// https://docs.github.com/en/enterprise-server@latest/rest/meta/meta#get-github-enterprise-server-meta-information
+type MetaGetRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+}
+
+// MetaGetResponse contains only the capability-related subset of the server's
+// meta information. InstalledVersion is empty in github.com links.
+type MetaGetResponse struct {
+	InstalledVersion string `json:"installed_version,omitempty"`
+}
+
+// MetaGetActivity is based on:
+// https://docs.github.com/en/enterprise-server@latest/rest/meta/meta#get-github-enterprise-server-meta-information
+//
+// The result is also cached per Thrippy link, for other activities to check
+// whether a GitHub Enterprise Server (GHES) supports the API calls they make.
+func (a *API) MetaGetActivity(ctx context.Context, req MetaGetRequest) (*MetaGetResponse, error) {
+	t := time.Now().UTC()
+	resp := new(MetaGetResponse)
+	_, err := a.httpGet(ctx, req.ThrippyLinkID, 0, "/meta", defaultAccept, nil, resp)
+	otel.IncrementAPICallCounter(t, MetaGetActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.meta.Store(a.metaKey(req.ThrippyLinkID), resp)
+	return resp, nil
+}
+
+func (a *API) metaKey(linkID string) string {
+	if linkID == "" {
+		return a.thrippy.LinkID
+	}
+	return linkID
+}
+
+// requireGHESVersion fails fast with a clear non-retryable error if the link's server
+// is a GitHub Enterprise Server (GHES) which is older than the given version, instead
+// of letting the caller receive a confusing HTTP 404 error. If the server's version is
+// unknown (and cannot be retrieved), it lets the caller try its luck anyway.
+func (a *API) requireGHESVersion(ctx context.Context, linkID, feature, minVersion string) error {
+	meta, ok := a.meta.Load(a.metaKey(linkID))
+	if !ok {
+		var err error
+		if meta, err = a.MetaGetActivity(ctx, MetaGetRequest{ThrippyLinkID: linkID}); err != nil {
+			activity.GetLogger(ctx).Warn("failed to check GitHub server version", slog.Any("error", err))
+			return nil
+		}
+	}
+
+	version := meta.(*MetaGetResponse).InstalledVersion
+	if version == "" || versionAtLeast(version, minVersion) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%s requires GitHub Enterprise Server %s or later, but the server's version is %s", feature, minVersion, version)
+	return temporal.NewNonRetryableApplicationError(msg, "GHESVersionError", nil, version)
+}
+
+// versionAtLeast compares dot-separated numeric versions, e.g. "3.9.0" >= "3.13" is false.
+func versionAtLeast(version, minVersion string) bool {
+	vs, ms := strings.Split(version, "."), strings.Split(minVersion, ".")
+	for i := range max(len(vs), len(ms)) {
+		v, m := versionPart(vs, i), versionPart(ms, i)
+		if v != m {
+			return v > m
+		}
+	}
+	return true
+}
+
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[i])
+	return n
+}
@@ -0,0 +1,157 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani-api/pkg/github"
+)
+
+func TestAPIVersionHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    []string
+	}{
+		{
+			name: "default",
+			want: []string{defaultAPIVersion},
+		},
+		{
+			name:    "custom",
+			version: "2026-03-10",
+			want:    []string{"2026-03-10"},
+		},
+		{
+			name:    "auto",
+			version: autoAPIVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Values(apiVersionHeader)
+				_, _ = w.Write([]byte("{}"))
+			}))
+			defer s.Close()
+
+			a := testLinkAPI(t, map[string]string{"api_base_url": s.URL, "pat": "token", "api_version": tt.version})
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.MetaGetActivity)
+			if _, err := env.ExecuteActivity(a.MetaGetActivity, MetaGetRequest{}); err != nil {
+				t.Fatalf("MetaGetActivity() error = %v", err)
+			}
+
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("%s header = %q, want %q", apiVersionHeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireGHESVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    string
+		wantErr bool
+	}{
+		{
+			name: "github_com",
+			meta: `{"verifiable_password_authentication": false}`,
+		},
+		{
+			name:    "old_ghes",
+			meta:    `{"installed_version": "3.9.0"}`,
+			wantErr: true,
+		},
+		{
+			name: "new_ghes",
+			meta: `{"installed_version": "3.14.2"}`,
+		},
+		{
+			name: "meta_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v3/meta":
+					if tt.meta == "" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(tt.meta))
+				case "/api/v3/user/123":
+					_, _ = w.Write([]byte(`{"id": 123}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer s.Close()
+
+			a := testLinkAPI(t, map[string]string{"base_url": s.URL, "pat": "token"})
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.UsersGetActivity)
+			_, err := env.ExecuteActivity(a.UsersGetActivity, github.UsersGetRequest{AccountID: "123"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UsersGetActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var appErr *temporal.ApplicationError
+			if tt.wantErr && (!errors.As(err, &appErr) || !appErr.NonRetryable()) {
+				t.Errorf("UsersGetActivity() error = %v, want non-retryable application error", err)
+			}
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version    string
+		minVersion string
+		want       bool
+	}{
+		{
+			version:    "3.9.0",
+			minVersion: "3.13",
+			want:       false,
+		},
+		{
+			version:    "3.13.0",
+			minVersion: "3.13",
+			want:       true,
+		},
+		{
+			version:    "3.14",
+			minVersion: "3.13.5",
+			want:       true,
+		},
+		{
+			version:    "4.0.1",
+			minVersion: "3.13",
+			want:       true,
+		},
+		{
+			version:    "2.22.9",
+			minVersion: "3.0",
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := versionAtLeast(tt.version, tt.minVersion); got != tt.want {
+				t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.minVersion, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+//revive:disable:exported
+const (
+	IssuesCreateMilestoneActivityName = "github.issues.createMilestone"
+	IssuesListMilestonesActivityName  = "github.issues.listMilestones"
+	IssuesUpdateMilestoneActivityName = "github.issues.updateMilestone"
+) //revive:enable:exported
+
+// Milestone is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#get-a-milestone
+type Milestone struct {
+	ID           int64          `json:"id"`
+	NodeID       string         `json:"node_id"`
+	Number       int            `json:"number"`
+	State        string         `json:"state"`
+	Title        string         `json:"title"`
+	Description  string         `json:"description,omitempty"`
+	Creator      map[string]any `json:"creator,omitempty"`
+	OpenIssues   int            `json:"open_issues"`
+	ClosedIssues int            `json:"closed_issues"`
+	URL          string         `json:"url"`
+	HTMLURL      string         `json:"html_url"`
+	CreatedAt    string         `json:"created_at"`
+	UpdatedAt    string         `json:"updated_at"`
+	ClosedAt     string         `json:"closed_at,omitempty"`
+	DueOn        string         `json:"due_on,omitempty"`
+}
+
+// MilestoneParams are the settings of a new or updated milestone.
+// Empty fields are omitted, i.e. left unchanged in updates.
+type MilestoneParams struct {
+	Title       string `json:"title,omitempty"`
+	State       string `json:"state,omitempty"` // "open" (default) or "closed".
+	Description string `json:"description,omitempty"`
+	DueOn       string `json:"due_on,omitempty"` // ISO 8601 timestamp.
+}
+
+// IssuesListMilestonesRequest is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#list-milestones
+type IssuesListMilestonesRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	State     string `json:"state,omitempty"`     // "open" (default), "closed", or "all".
+	Sort      string `json:"sort,omitempty"`      // "due_on" (default) or "completeness".
+	Direction string `json:"direction,omitempty"` // "asc" (default) or "desc".
+
+	// https://docs.github.com/rest/using-the-rest-api/using-pagination-in-the-rest-api
+	PerPage int `json:"per_page,omitempty"`
+	Page    int `json:"page,omitempty"`
+}
+
+// IssuesListMilestonesActivity is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#list-milestones
+//
+// Pagination is handled internally, unless the request specifies a page or page size.
+func (a *API) IssuesListMilestonesActivity(ctx context.Context, req IssuesListMilestonesRequest) ([]Milestone, error) {
+	path := fmt.Sprintf("/repos/%s/%s/milestones", req.Owner, req.Repo)
+
+	query := url.Values{}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	if req.Sort != "" {
+		query.Set("sort", req.Sort)
+	}
+	if req.Direction != "" {
+		query.Set("direction", req.Direction)
+	}
+
+	activityName := IssuesListMilestonesActivityName
+	return paginatedActivity[Milestone](ctx, a, activityName, req.ThrippyLinkID, req.InstallationID, path, query, req.PerPage, req.Page)
+}
+
+// IssuesCreateMilestoneRequest is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#create-a-milestone
+type IssuesCreateMilestoneRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	MilestoneParams
+}
+
+// IssuesCreateMilestoneActivity is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#create-a-milestone
+func (a *API) IssuesCreateMilestoneActivity(ctx context.Context, req IssuesCreateMilestoneRequest) (*Milestone, error) {
+	path := fmt.Sprintf("/repos/%s/%s/milestones", req.Owner, req.Repo)
+
+	t := time.Now().UTC()
+	resp := new(Milestone)
+	err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, req.MilestoneParams, resp)
+	otel.IncrementAPICallCounter(t, IssuesCreateMilestoneActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// IssuesUpdateMilestoneRequest is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#update-a-milestone
+type IssuesUpdateMilestoneRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner           string `json:"owner"`
+	Repo            string `json:"repo"`
+	MilestoneNumber int    `json:"milestone_number"`
+	MilestoneParams
+}
+
+// IssuesUpdateMilestoneActivity is based on:
+// https://docs.github.com/en/rest/issues/milestones?apiVersion=2022-11-28#update-a-milestone
+func (a *API) IssuesUpdateMilestoneActivity(ctx context.Context, req IssuesUpdateMilestoneRequest) (*Milestone, error) {
+	path := fmt.Sprintf("/repos/%s/%s/milestones/%d", req.Owner, req.Repo, req.MilestoneNumber)
+
+	t := time.Now().UTC()
+	resp := new(Milestone)
+	err := a.httpPatch(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, req.MilestoneParams, resp)
+	otel.IncrementAPICallCounter(t, IssuesUpdateMilestoneActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
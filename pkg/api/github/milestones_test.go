@@ -0,0 +1,78 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestMilestonesActivities(t *testing.T) {
+	var got []string
+	var bodies []map[string]any
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.RequestURI())
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"number": 1, "title": "v1.0"}, {"number": 2, "title": "v1.1"}]`))
+			return
+		}
+
+		body := map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, body)
+		_, _ = w.Write([]byte(`{"number": 3, "title": "v2.0", "state": "open"}`))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.IssuesListMilestonesActivity)
+	env.RegisterActivity(a.IssuesCreateMilestoneActivity)
+	env.RegisterActivity(a.IssuesUpdateMilestoneActivity)
+
+	v, err := env.ExecuteActivity(a.IssuesListMilestonesActivity, IssuesListMilestonesRequest{Owner: "o", Repo: "r", State: "all"})
+	if err != nil {
+		t.Fatalf("IssuesListMilestonesActivity() error = %v", err)
+	}
+	var milestones []Milestone
+	if err := v.Get(&milestones); err != nil {
+		t.Fatal(err)
+	}
+	if len(milestones) != 2 || milestones[1].Title != "v1.1" {
+		t.Errorf("IssuesListMilestonesActivity() = %+v, want 2 milestones", milestones)
+	}
+
+	create := IssuesCreateMilestoneRequest{Owner: "o", Repo: "r", MilestoneParams: MilestoneParams{
+		Title: "v2.0", DueOn: "2026-12-31T00:00:00Z",
+	}}
+	if _, err := env.ExecuteActivity(a.IssuesCreateMilestoneActivity, create); err != nil {
+		t.Fatalf("IssuesCreateMilestoneActivity() error = %v", err)
+	}
+
+	update := IssuesUpdateMilestoneRequest{Owner: "o", Repo: "r", MilestoneNumber: 3, MilestoneParams: MilestoneParams{State: "closed"}}
+	if _, err := env.ExecuteActivity(a.IssuesUpdateMilestoneActivity, update); err != nil {
+		t.Fatalf("IssuesUpdateMilestoneActivity() error = %v", err)
+	}
+
+	want := []string{
+		"GET /repos/o/r/milestones?page=1&per_page=100&state=all",
+		"POST /repos/o/r/milestones",
+		"PATCH /repos/o/r/milestones/3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("API calls = %v, want %v", got, want)
+	}
+
+	wantBodies := []map[string]any{
+		{"title": "v2.0", "due_on": "2026-12-31T00:00:00Z"},
+		{"state": "closed"},
+	}
+	if !reflect.DeepEqual(bodies, wantBodies) {
+		t.Errorf("request bodies = %v, want %v", bodies, wantBodies)
+	}
+}
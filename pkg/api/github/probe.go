@@ -0,0 +1,38 @@
+package github
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+// ProbeLink checks that the credentials of a Thrippy link are still valid, using the
+// cheapest authenticated GitHub API call: [checking the rate limit status] for users,
+// or [getting the authenticated app] for GitHub apps (which also verifies the private
+// key, without generating an installation token). Links without API credentials, i.e.
+// "github-webhook" links, are considered valid.
+//
+// Unlike the rest of this package, this function runs outside of Temporal activities.
+//
+// [checking the rate limit status]: https://docs.github.com/en/rest/rate-limit/rate-limit#get-rate-limit-status-for-the-authenticated-user
+// [getting the authenticated app]: https://docs.github.com/en/rest/apps/apps#get-the-authenticated-app
+func ProbeLink(ctx context.Context, _ string, secrets map[string]string) error {
+	path, auth := "/rate_limit", secrets["access_token"]+secrets["pat"]
+	if auth == "" {
+		if secrets["client_id"] == "" {
+			return nil
+		}
+
+		var err error
+		path = "/app"
+		if auth, err = generateJWT(secrets["client_id"], secrets["private_key"]); err != nil {
+			return err
+		}
+	}
+
+	apiURL := apiBaseURL(secrets) + path
+	opts := append(requestOpts(secrets), client.WithTimeout(client.Timeout))
+	_, _, _, err := client.HTTPRequest(ctx, http.MethodGet, apiURL, auth, defaultAccept, "", nil, opts...)
+	return err
+}
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"context"
+	"time"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// Projects (v2) are supported only by GitHub's GraphQL API:
+// https://docs.github.com/en/issues/planning-and-tracking-with-projects/automating-your-project/using-the-api-to-manage-projects.
+// All the IDs in these activities are GraphQL node IDs, e.g. the "node_id"
+// of issues and PRs in REST API responses and webhook events.
+
+//revive:disable:exported
+const (
+	ProjectsV2AddItemActivityName              = "github.projectsV2.addItem"
+	ProjectsV2SetSingleSelectFieldActivityName = "github.projectsV2.setSingleSelectField"
+) //revive:enable:exported
+
+// https://docs.github.com/en/graphql/reference/mutations#addprojectv2itembyid
+const addProjectV2ItemMutation = `mutation($projectId: ID!, $contentId: ID!) {
+  addProjectV2ItemById(input: {projectId: $projectId, contentId: $contentId}) {
+    item { id }
+  }
+}`
+
+// https://docs.github.com/en/graphql/reference/mutations#updateprojectv2itemfieldvalue
+const updateProjectV2ItemFieldMutation = `mutation($projectId: ID!, $itemId: ID!, $fieldId: ID!, $optionId: String!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $projectId, itemId: $itemId, fieldId: $fieldId, value: {singleSelectOptionId: $optionId}}) {
+    projectV2Item { id }
+  }
+}`
+
+// ProjectsV2AddItemRequest is based on:
+// https://docs.github.com/en/graphql/reference/mutations#addprojectv2itembyid
+type ProjectsV2AddItemRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	ProjectID string `json:"project_id"`
+	ContentID string `json:"content_id"` // Node ID of an issue or a pull request.
+}
+
+// ProjectsV2AddItemResponse contains the node ID of the project item, which is the
+// same as the ID of the existing item if the content was already in the project.
+type ProjectsV2AddItemResponse struct {
+	ItemID string `json:"item_id"`
+}
+
+// ProjectsV2AddItemActivity is based on:
+// https://docs.github.com/en/graphql/reference/mutations#addprojectv2itembyid
+func (a *API) ProjectsV2AddItemActivity(ctx context.Context, req ProjectsV2AddItemRequest) (*ProjectsV2AddItemResponse, error) {
+	vars := map[string]any{"projectId": req.ProjectID, "contentId": req.ContentID}
+	data := struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		} `json:"addProjectV2ItemById"`
+	}{}
+
+	t := time.Now().UTC()
+	err := a.graphql(ctx, req.ThrippyLinkID, req.InstallationID, addProjectV2ItemMutation, vars, &data)
+	otel.IncrementAPICallCounter(t, ProjectsV2AddItemActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectsV2AddItemResponse{ItemID: data.AddProjectV2ItemByID.Item.ID}, nil
+}
+
+// ProjectsV2SetSingleSelectFieldRequest is based on:
+// https://docs.github.com/en/graphql/reference/mutations#updateprojectv2itemfieldvalue
+//
+// This is the common way to set the "Status" column of a project item.
+// The field and option IDs are listed in the project's "fields" connection:
+// https://docs.github.com/en/graphql/reference/objects#projectv2singleselectfield
+type ProjectsV2SetSingleSelectFieldRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	ProjectID string `json:"project_id"`
+	ItemID    string `json:"item_id"`
+	FieldID   string `json:"field_id"`
+	OptionID  string `json:"option_id"`
+}
+
+// ProjectsV2SetSingleSelectFieldActivity is based on:
+// https://docs.github.com/en/graphql/reference/mutations#updateprojectv2itemfieldvalue
+func (a *API) ProjectsV2SetSingleSelectFieldActivity(ctx context.Context, req ProjectsV2SetSingleSelectFieldRequest) error {
+	vars := map[string]any{
+		"projectId": req.ProjectID,
+		"itemId":    req.ItemID,
+		"fieldId":   req.FieldID,
+		"optionId":  req.OptionID,
+	}
+	data := map[string]any{}
+
+	t := time.Now().UTC()
+	err := a.graphql(ctx, req.ThrippyLinkID, req.InstallationID, updateProjectV2ItemFieldMutation, vars, &data)
+	otel.IncrementAPICallCounter(t, ProjectsV2SetSingleSelectFieldActivityName, err)
+
+	return err
+}
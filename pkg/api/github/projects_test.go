@@ -0,0 +1,144 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{
+			name:    "github_com",
+			baseURL: "https://api.github.com",
+			want:    "https://api.github.com/graphql",
+		},
+		{
+			name:    "ghes",
+			baseURL: "https://github.example.com/api/v3",
+			want:    "https://github.example.com/api/graphql",
+		},
+		{
+			name:    "trailing_slash",
+			baseURL: "http://127.0.0.1:1234/",
+			want:    "http://127.0.0.1:1234/graphql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graphqlURL(tt.baseURL); got != tt.want {
+				t.Errorf("graphqlURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectsV2Activities(t *testing.T) {
+	var got []graphqlRequest
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/graphql" {
+			t.Errorf("API call = %s %s, want POST /graphql", r.Method, r.URL.Path)
+		}
+
+		req := graphqlRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		got = append(got, req)
+
+		if strings.Contains(req.Query, "addProjectV2ItemById") {
+			_, _ = w.Write([]byte(`{"data": {"addProjectV2ItemById": {"item": {"id": "PVTI_1"}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"updateProjectV2ItemFieldValue": {"projectV2Item": {"id": "PVTI_1"}}}}`))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.ProjectsV2AddItemActivity)
+	env.RegisterActivity(a.ProjectsV2SetSingleSelectFieldActivity)
+
+	v, err := env.ExecuteActivity(a.ProjectsV2AddItemActivity, ProjectsV2AddItemRequest{ProjectID: "PVT_1", ContentID: "PR_1"})
+	if err != nil {
+		t.Fatalf("ProjectsV2AddItemActivity() error = %v", err)
+	}
+	resp := new(ProjectsV2AddItemResponse)
+	if err := v.Get(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ItemID != "PVTI_1" {
+		t.Errorf("ProjectsV2AddItemActivity() = %+v, want item ID %q", resp, "PVTI_1")
+	}
+
+	req := ProjectsV2SetSingleSelectFieldRequest{ProjectID: "PVT_1", ItemID: resp.ItemID, FieldID: "PVTSSF_1", OptionID: "47fc9ee4"}
+	if _, err := env.ExecuteActivity(a.ProjectsV2SetSingleSelectFieldActivity, req); err != nil {
+		t.Fatalf("ProjectsV2SetSingleSelectFieldActivity() error = %v", err)
+	}
+
+	want := []graphqlRequest{
+		{
+			Query:     addProjectV2ItemMutation,
+			Variables: map[string]any{"projectId": "PVT_1", "contentId": "PR_1"},
+		},
+		{
+			Query:     updateProjectV2ItemFieldMutation,
+			Variables: map[string]any{"projectId": "PVT_1", "itemId": "PVTI_1", "fieldId": "PVTSSF_1", "optionId": "47fc9ee4"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GraphQL requests = %+v, want %+v", got, want)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		errType       string
+		wantRetryable bool
+	}{
+		{
+			name:    "not_found",
+			errType: "NOT_FOUND",
+		},
+		{
+			name:          "rate_limited",
+			errType:       "RATE_LIMITED",
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"data": null, "errors": [{"type": "` + tt.errType + `", "message": "oops"}]}`))
+			}))
+			defer s.Close()
+
+			a := testAPI(t, s.URL)
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.ProjectsV2AddItemActivity)
+
+			_, err := env.ExecuteActivity(a.ProjectsV2AddItemActivity, ProjectsV2AddItemRequest{ProjectID: "PVT_1", ContentID: "I_1"})
+			appErr := new(temporal.ApplicationError)
+			if !errors.As(err, &appErr) {
+				t.Fatalf("ProjectsV2AddItemActivity() error = %v, want ApplicationError", err)
+			}
+			if appErr.NonRetryable() == tt.wantRetryable {
+				t.Errorf("ProjectsV2AddItemActivity() error retryable = %v, want %v", !appErr.NonRetryable(), tt.wantRetryable)
+			}
+		})
+	}
+}
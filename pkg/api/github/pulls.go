@@ -8,17 +8,63 @@ import (
 	"time"
 
 	"github.com/tzrikka/timpani-api/pkg/github"
+	"github.com/tzrikka/timpani/internal/idempotency"
+	"github.com/tzrikka/timpani/pkg/http/client"
 	"github.com/tzrikka/timpani/pkg/otel"
 )
 
+// Pull request activity requests, with an optional [Installation].
+type (
+	PullRequestsGetRequest struct {
+		github.PullRequestsGetRequest
+		Installation
+	}
+	PullRequestsListCommitsRequest struct {
+		github.PullRequestsListCommitsRequest
+		Installation
+	}
+	PullRequestsListFilesRequest struct {
+		github.PullRequestsListFilesRequest
+		Installation
+	}
+	PullRequestsMergeRequest struct {
+		github.PullRequestsMergeRequest
+		Installation
+	}
+	PullRequestsCommentsDeleteRequest struct {
+		github.PullRequestsCommentsDeleteRequest
+		Installation
+	}
+	PullRequestsCommentsUpdateRequest struct {
+		github.PullRequestsCommentsUpdateRequest
+		Installation
+	}
+	PullRequestsReviewsDeleteRequest struct {
+		github.PullRequestsReviewsDeleteRequest
+		Installation
+	}
+	PullRequestsReviewsDismissRequest struct {
+		github.PullRequestsReviewsDismissRequest
+		Installation
+	}
+	PullRequestsReviewsSubmitRequest struct {
+		github.PullRequestsReviewsSubmitRequest
+		Installation
+	}
+	PullRequestsReviewsUpdateRequest struct {
+		github.PullRequestsReviewsUpdateRequest
+		Installation
+	}
+)
+
 // PullRequestsGetActivity is based on:
 // https://docs.github.com/en/rest/pulls/pulls?apiVersion=2022-11-28#get-a-pull-request
-func (a *API) PullRequestsGetActivity(ctx context.Context, req github.PullRequestsGetRequest) (*github.PullRequest, error) {
+func (a *API) PullRequestsGetActivity(ctx context.Context, req PullRequestsGetRequest) (*github.PullRequest, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", req.Owner, req.Repo, req.PullNumber)
 
 	t := time.Now().UTC()
 	resp := new(github.PullRequest)
-	_, err := a.httpGet(ctx, req.ThrippyLinkID, path, nil, resp)
+	_, err := a.httpGet(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil, resp)
 	otel.IncrementAPICallCounter(t, github.PullRequestsGetActivityName, err)
 
 	if err != nil {
@@ -27,16 +73,45 @@ func (a *API) PullRequestsGetActivity(ctx context.Context, req github.PullReques
 	return resp, nil
 }
 
+// PullRequestsDiffToFileActivityName is a Timpani-specific activity, see [API.PullRequestsDiffToFileActivity].
+const PullRequestsDiffToFileActivityName = "github.timpani.pulls.diffToFile"
+
+// PullRequestsDiffToFileRequest is like [github.PullRequestsGetRequest], but for the
+// pull request's diff, which is written into a local file instead of being returned.
+// MaxSize is optional (default = [client.MaxStreamSize]).
+type PullRequestsDiffToFileRequest struct {
+	PullRequestsGetRequest
+
+	FilePath string `json:"file_path"`
+	MaxSize  int64  `json:"max_size,omitempty"`
+}
+
+// PullRequestsDiffToFileActivity writes the diff of a pull request into a local file
+// (on the Timpani worker's host), in order to support diffs of any size. Based on:
+// https://docs.github.com/en/rest/pulls/pulls?apiVersion=2022-11-28#get-a-pull-request
+func (a *API) PullRequestsDiffToFileActivity(ctx context.Context, req PullRequestsDiffToFileRequest) (*client.FileSinkResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", req.Owner, req.Repo, req.PullNumber)
+
+	t := time.Now().UTC()
+	resp, err := a.httpGetToFile(ctx, req.ThrippyLinkID, req.InstallationID, path, diffAccept, req.FilePath, req.MaxSize)
+	otel.IncrementAPICallCounter(t, PullRequestsDiffToFileActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // PullRequestsListCommitsActivity is based on:
 // https://docs.github.com/en/rest/pulls/pulls?apiVersion=2022-11-28#list-commits-on-a-pull-request
 //
 // Pagination is handled internally if both PerPage and Page are 0 in the request, but either way
 // the results are limited to a maximum of 250 commits. To receive a complete list, call [CommitsList].
-func (a *API) PullRequestsListCommitsActivity(ctx context.Context, req github.PullRequestsListCommitsRequest) ([]github.Commit, error) {
+func (a *API) PullRequestsListCommitsActivity(ctx context.Context, req PullRequestsListCommitsRequest) ([]github.Commit, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/commits", req.Owner, req.Repo, req.PullNumber)
 	activityName := github.PullRequestsListCommitsActivityName
 
-	return paginatedActivity[github.Commit](ctx, a, activityName, req.ThrippyLinkID, path, req.PerPage, req.Page)
+	return paginatedActivity[github.Commit](ctx, a, activityName, req.ThrippyLinkID, req.InstallationID, path, nil, req.PerPage, req.Page)
 }
 
 // PullRequestsListFilesActivity is based on:
@@ -44,21 +119,31 @@ func (a *API) PullRequestsListCommitsActivity(ctx context.Context, req github.Pu
 //
 // Pagination is handled internally if both PerPage and Page are 0 in the
 // request, but either way the results are limited to a maximum of 3000 files.
-func (a *API) PullRequestsListFilesActivity(ctx context.Context, req github.PullRequestsListFilesRequest) ([]github.File, error) {
+func (a *API) PullRequestsListFilesActivity(ctx context.Context, req PullRequestsListFilesRequest) ([]github.File, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/files", req.Owner, req.Repo, req.PullNumber)
 	activityName := github.PullRequestsListFilesActivityName
 
-	return paginatedActivity[github.File](ctx, a, activityName, req.ThrippyLinkID, path, req.PerPage, req.Page)
+	return paginatedActivity[github.File](ctx, a, activityName, req.ThrippyLinkID, req.InstallationID, path, nil, req.PerPage, req.Page)
 }
 
-func paginatedActivity[T any](ctx context.Context, a *API, activityName, linkID, path string, perPage, page int) ([]T, error) {
+func paginatedActivity[T any](
+	ctx context.Context,
+	a *API,
+	activityName, linkID string,
+	installID int64,
+	path string,
+	query url.Values,
+	perPage, page int,
+) ([]T, error) {
 	paginate := perPage == 0 && page == 0
 	if paginate {
 		perPage = 100 // Default = 30, but we prefer to minimize the number of API calls.
 		page = 1
 	}
 
-	query := url.Values{}
+	if query == nil {
+		query = url.Values{}
+	}
 	if perPage != 0 {
 		query.Set("per_page", strconv.Itoa(perPage))
 	}
@@ -72,7 +157,7 @@ func paginatedActivity[T any](ctx context.Context, a *API, activityName, linkID,
 	for hasMore {
 		t := time.Now().UTC()
 		resp := new([]T)
-		more, err := a.httpGet(ctx, linkID, path, query, resp)
+		more, err := a.httpGet(ctx, linkID, installID, path, defaultAccept, query, resp)
 		otel.IncrementAPICallCounter(t, activityName, err)
 		if err != nil {
 			return nil, err
@@ -85,17 +170,21 @@ func paginatedActivity[T any](ctx context.Context, a *API, activityName, linkID,
 		query.Set("page", strconv.Itoa(page))
 	}
 
+	// Responses are plain JSON arrays, so there's no way to indicate truncation.
+	if _, err := client.LimitResponseSize(activityName, results, &results, false); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
 // PullRequestsMergeActivity is based on:
 // https://docs.github.com/en/rest/pulls/pulls?apiVersion=2022-11-28#merge-a-pull-request
-func (a *API) PullRequestsMergeActivity(ctx context.Context, req github.PullRequestsMergeRequest) (*github.PullRequestsMergeResponse, error) {
+func (a *API) PullRequestsMergeActivity(ctx context.Context, req PullRequestsMergeRequest) (*github.PullRequestsMergeResponse, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/merge", req.Owner, req.Repo, req.PullNumber)
 
 	t := time.Now().UTC()
 	resp := new(github.PullRequestsMergeResponse)
-	err := a.httpPut(ctx, req.ThrippyLinkID, path, defaultAccept, req, resp)
+	err := a.httpPut(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, req.PullRequestsMergeRequest, resp)
 	otel.IncrementAPICallCounter(t, github.PullRequestsMergeActivityName, err)
 
 	if err != nil {
@@ -104,61 +193,85 @@ func (a *API) PullRequestsMergeActivity(ctx context.Context, req github.PullRequ
 	return resp, nil
 }
 
+// PullRequestsCommentsCreateRequest extends [github.PullRequestsCommentsCreateRequest]
+// with an optional idempotency key, which prevents retries of this activity from creating
+// duplicate comments if a previous attempt already succeeded (best-effort, see the caveats
+// in [idempotency]). Its JSON encoding is compatible with the original.
+type PullRequestsCommentsCreateRequest struct {
+	github.PullRequestsCommentsCreateRequest
+	Installation
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
 // PullRequestsCommentsCreateActivity is based on:
 // https://docs.github.com/en/rest/pulls/comments?apiVersion=2022-11-28#create-a-review-comment-for-a-pull-request
-func (a *API) PullRequestsCommentsCreateActivity(ctx context.Context, req github.PullRequestsCommentsCreateRequest) (*github.PullComment, error) {
+func (a *API) PullRequestsCommentsCreateActivity(ctx context.Context, req PullRequestsCommentsCreateRequest) (*github.PullComment, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/comments", req.Owner, req.Repo, req.PullNumber)
 
-	linkID := req.ThrippyLinkID
-	req.ThrippyLinkID = ""
-	req.Owner = ""
-	req.Repo = ""
-	req.PullNumber = 0
+	body := req.PullRequestsCommentsCreateRequest
+	body.ThrippyLinkID = ""
+	body.Owner = ""
+	body.Repo = ""
+	body.PullNumber = 0
 
-	t := time.Now().UTC()
-	resp := new(github.PullComment)
-	err := a.httpPost(ctx, linkID, path, defaultAccept, req, resp)
-	otel.IncrementAPICallCounter(t, github.PullRequestsCommentsCreateActivityName, err)
+	return idempotency.Do(ctx, github.PullRequestsCommentsCreateActivityName, req.IdempotencyKey, func() (*github.PullComment, error) {
+		t := time.Now().UTC()
+		resp := new(github.PullComment)
+		err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, body, resp)
+		otel.IncrementAPICallCounter(t, github.PullRequestsCommentsCreateActivityName, err)
 
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
+}
+
+// PullRequestsCommentsCreateReplyRequest is like [PullRequestsCommentsCreateRequest],
+// but for [github.PullRequestsCommentsCreateReplyRequest].
+type PullRequestsCommentsCreateReplyRequest struct {
+	github.PullRequestsCommentsCreateReplyRequest
+	Installation
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // PullRequestsCommentsCreateReplyActivity is based on:
 // https://docs.github.com/en/rest/pulls/comments?apiVersion=2022-11-28#create-a-reply-for-a-review-comment
 func (a *API) PullRequestsCommentsCreateReplyActivity(
 	ctx context.Context,
-	req github.PullRequestsCommentsCreateReplyRequest,
+	req PullRequestsCommentsCreateReplyRequest,
 ) (*github.PullComment, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/comments/%d/replies", req.Owner, req.Repo, req.PullNumber, req.CommentID)
 
-	linkID := req.ThrippyLinkID
-	req.ThrippyLinkID = ""
-	req.Owner = ""
-	req.Repo = ""
-	req.PullNumber = 0
-	req.CommentID = 0
+	body := req.PullRequestsCommentsCreateReplyRequest
+	body.ThrippyLinkID = ""
+	body.Owner = ""
+	body.Repo = ""
+	body.PullNumber = 0
+	body.CommentID = 0
 
-	t := time.Now().UTC()
-	resp := new(github.PullComment)
-	err := a.httpPost(ctx, linkID, path, defaultAccept, req, resp)
-	otel.IncrementAPICallCounter(t, github.PullRequestsCommentsCreateReplyActivityName, err)
+	return idempotency.Do(ctx, github.PullRequestsCommentsCreateReplyActivityName, req.IdempotencyKey, func() (*github.PullComment, error) {
+		t := time.Now().UTC()
+		resp := new(github.PullComment)
+		err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, body, resp)
+		otel.IncrementAPICallCounter(t, github.PullRequestsCommentsCreateReplyActivityName, err)
 
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
 }
 
 // PullRequestsCommentsDeleteActivity is based on:
 // https://docs.github.com/en/rest/pulls/comments?apiVersion=2022-11-28#delete-a-review-comment-for-a-pull-request
-func (a *API) PullRequestsCommentsDeleteActivity(ctx context.Context, req github.PullRequestsCommentsDeleteRequest) error {
+func (a *API) PullRequestsCommentsDeleteActivity(ctx context.Context, req PullRequestsCommentsDeleteRequest) error {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/comments/%d", req.Owner, req.Repo, req.CommentID)
 
 	t := time.Now().UTC()
-	err := a.httpDelete(ctx, req.ThrippyLinkID, path, nil)
+	err := a.httpDelete(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil)
 	otel.IncrementAPICallCounter(t, github.PullRequestsCommentsDeleteActivityName, err)
 
 	if err != nil {
@@ -169,7 +282,7 @@ func (a *API) PullRequestsCommentsDeleteActivity(ctx context.Context, req github
 
 // PullRequestsCommentsUpdateActivity is based on:
 // https://docs.github.com/en/rest/pulls/comments?apiVersion=2022-11-28#update-a-review-comment-for-a-pull-request
-func (a *API) PullRequestsCommentsUpdateActivity(ctx context.Context, req github.PullRequestsCommentsUpdateRequest) (*github.PullComment, error) {
+func (a *API) PullRequestsCommentsUpdateActivity(ctx context.Context, req PullRequestsCommentsUpdateRequest) (*github.PullComment, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/comments/%d", req.Owner, req.Repo, req.CommentID)
 
 	linkID := req.ThrippyLinkID
@@ -180,7 +293,8 @@ func (a *API) PullRequestsCommentsUpdateActivity(ctx context.Context, req github
 
 	t := time.Now().UTC()
 	resp := new(github.PullComment)
-	err := a.httpPatch(ctx, linkID, path, "application/vnd.github-commitcomment.raw+json", req, resp)
+	accept := "application/vnd.github-commitcomment.raw+json"
+	err := a.httpPatch(ctx, linkID, req.InstallationID, path, accept, req.PullRequestsCommentsUpdateRequest, resp)
 	otel.IncrementAPICallCounter(t, github.PullRequestsCommentsUpdateActivityName, err)
 
 	if err != nil {
@@ -189,35 +303,46 @@ func (a *API) PullRequestsCommentsUpdateActivity(ctx context.Context, req github
 	return resp, nil
 }
 
+// PullRequestsReviewsCreateRequest is like [PullRequestsCommentsCreateRequest],
+// but for [github.PullRequestsReviewsCreateRequest].
+type PullRequestsReviewsCreateRequest struct {
+	github.PullRequestsReviewsCreateRequest
+	Installation
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
 // PullRequestsReviewsCreateActivity is based on:
 // https://docs.github.com/en/rest/pulls/reviews?apiVersion=2022-11-28#create-a-review-for-a-pull-request
-func (a *API) PullRequestsReviewsCreateActivity(ctx context.Context, req github.PullRequestsReviewsCreateRequest) (*github.Review, error) {
+func (a *API) PullRequestsReviewsCreateActivity(ctx context.Context, req PullRequestsReviewsCreateRequest) (*github.Review, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", req.Owner, req.Repo, req.PullNumber)
 
-	linkID := req.ThrippyLinkID
-	req.ThrippyLinkID = ""
-	req.Owner = ""
-	req.Repo = ""
-	req.PullNumber = 0
+	body := req.PullRequestsReviewsCreateRequest
+	body.ThrippyLinkID = ""
+	body.Owner = ""
+	body.Repo = ""
+	body.PullNumber = 0
 
-	t := time.Now().UTC()
-	resp := new(github.Review)
-	err := a.httpPost(ctx, linkID, path, "application/vnd.github-commitcomment.raw+json", req, resp)
-	otel.IncrementAPICallCounter(t, github.PullRequestsReviewsCreateActivityName, err)
+	return idempotency.Do(ctx, github.PullRequestsReviewsCreateActivityName, req.IdempotencyKey, func() (*github.Review, error) {
+		t := time.Now().UTC()
+		resp := new(github.Review)
+		err := a.httpPost(ctx, req.ThrippyLinkID, req.InstallationID, path, "application/vnd.github-commitcomment.raw+json", body, resp)
+		otel.IncrementAPICallCounter(t, github.PullRequestsReviewsCreateActivityName, err)
 
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
 }
 
 // PullRequestsReviewsDeleteActivity is based on:
 // https://docs.github.com/en/rest/pulls/reviews?apiVersion=2022-11-28#delete-a-pending-review-for-a-pull-request
-func (a *API) PullRequestsReviewsDeleteActivity(ctx context.Context, req github.PullRequestsReviewsDeleteRequest) error {
+func (a *API) PullRequestsReviewsDeleteActivity(ctx context.Context, req PullRequestsReviewsDeleteRequest) error {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews/%d", req.Owner, req.Repo, req.PullNumber, req.ReviewID)
 
 	t := time.Now().UTC()
-	err := a.httpDelete(ctx, req.ThrippyLinkID, path, nil)
+	err := a.httpDelete(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, nil)
 	otel.IncrementAPICallCounter(t, github.PullRequestsReviewsDeleteActivityName, err)
 
 	if err != nil {
@@ -228,7 +353,7 @@ func (a *API) PullRequestsReviewsDeleteActivity(ctx context.Context, req github.
 
 // PullRequestsReviewsDismissActivity is based on:
 // https://docs.github.com/en/rest/pulls/reviews?apiVersion=2022-11-28#dismiss-a-review-for-a-pull-request
-func (a *API) PullRequestsReviewsDismissActivity(ctx context.Context, req github.PullRequestsReviewsDismissRequest) (*github.Review, error) {
+func (a *API) PullRequestsReviewsDismissActivity(ctx context.Context, req PullRequestsReviewsDismissRequest) (*github.Review, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews/%d/dismissals", req.Owner, req.Repo, req.PullNumber, req.ReviewID)
 
 	linkID := req.ThrippyLinkID
@@ -240,7 +365,8 @@ func (a *API) PullRequestsReviewsDismissActivity(ctx context.Context, req github
 
 	t := time.Now().UTC()
 	resp := new(github.Review)
-	err := a.httpPut(ctx, linkID, path, "application/vnd.github-commitcomment.raw+json", req, resp)
+	accept := "application/vnd.github-commitcomment.raw+json"
+	err := a.httpPut(ctx, linkID, req.InstallationID, path, accept, req.PullRequestsReviewsDismissRequest, resp)
 	otel.IncrementAPICallCounter(t, github.PullRequestsReviewsDismissActivityName, err)
 
 	if err != nil {
@@ -251,7 +377,7 @@ func (a *API) PullRequestsReviewsDismissActivity(ctx context.Context, req github
 
 // PullRequestsReviewsSubmitActivity is based on:
 // https://docs.github.com/en/rest/pulls/reviews?apiVersion=2022-11-28#submit-a-review-for-a-pull-request
-func (a *API) PullRequestsReviewsSubmitActivity(ctx context.Context, req github.PullRequestsReviewsSubmitRequest) (*github.Review, error) {
+func (a *API) PullRequestsReviewsSubmitActivity(ctx context.Context, req PullRequestsReviewsSubmitRequest) (*github.Review, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews/%d/events", req.Owner, req.Repo, req.PullNumber, req.ReviewID)
 
 	linkID := req.ThrippyLinkID
@@ -263,7 +389,8 @@ func (a *API) PullRequestsReviewsSubmitActivity(ctx context.Context, req github.
 
 	t := time.Now().UTC()
 	resp := new(github.Review)
-	err := a.httpPost(ctx, linkID, path, "application/vnd.github-commitcomment.raw+json", req, resp)
+	accept := "application/vnd.github-commitcomment.raw+json"
+	err := a.httpPost(ctx, linkID, req.InstallationID, path, accept, req.PullRequestsReviewsSubmitRequest, resp)
 	otel.IncrementAPICallCounter(t, github.PullRequestsReviewsSubmitActivityName, err)
 
 	if err != nil {
@@ -274,7 +401,7 @@ func (a *API) PullRequestsReviewsSubmitActivity(ctx context.Context, req github.
 
 // PullRequestsReviewsUpdateActivity is based on:
 // https://docs.github.com/en/rest/pulls/reviews?apiVersion=2022-11-28#update-a-review-for-a-pull-request
-func (a *API) PullRequestsReviewsUpdateActivity(ctx context.Context, req github.PullRequestsReviewsUpdateRequest) (*github.Review, error) {
+func (a *API) PullRequestsReviewsUpdateActivity(ctx context.Context, req PullRequestsReviewsUpdateRequest) (*github.Review, error) {
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews/%d", req.Owner, req.Repo, req.PullNumber, req.ReviewID)
 
 	linkID := req.ThrippyLinkID
@@ -286,7 +413,8 @@ func (a *API) PullRequestsReviewsUpdateActivity(ctx context.Context, req github.
 
 	t := time.Now().UTC()
 	resp := new(github.Review)
-	err := a.httpPut(ctx, linkID, path, "application/vnd.github-commitcomment.raw+json", req, resp)
+	accept := "application/vnd.github-commitcomment.raw+json"
+	err := a.httpPut(ctx, linkID, req.InstallationID, path, accept, req.PullRequestsReviewsUpdateRequest, resp)
 	otel.IncrementAPICallCounter(t, github.PullRequestsReviewsUpdateActivityName, err)
 
 	if err != nil {
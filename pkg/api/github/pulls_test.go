@@ -0,0 +1,151 @@
+package github
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/github"
+)
+
+// pullRequestEvent is a trimmed-down "pull_request" webhook event payload.
+const pullRequestEvent = `{
+	"action": "opened",
+	"installation": {"id": 42},
+	"repository": {"name": "repo", "owner": {"login": "owner"}},
+	"pull_request": {"number": 7}
+}`
+
+func TestPullRequestsGetActivityInstallation(t *testing.T) {
+	installationTokens.Clear()
+	t.Cleanup(installationTokens.Clear)
+
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)})
+
+	tokenCalls := new(atomic.Int32)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/app/installations/42/access_tokens":
+			// Only app JWTs may create installation tokens.
+			_, err := jwt.Parse(auth, func(_ *jwt.Token) (any, error) { return &pk.PublicKey, nil },
+				jwt.WithIssuer("client ID"), jwt.WithValidMethods([]string{"RS256"}))
+			if err != nil {
+				t.Errorf("invalid app JWT: %v", err)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokenCalls.Add(1)
+			expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+			_, _ = fmt.Fprintf(w, `{"token": "ghs_installation_42", "expires_at": %q}`, expiresAt)
+
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/pulls/7":
+			// Only the webhook's installation may read the pull request.
+			if auth != "ghs_installation_42" {
+				t.Errorf("unexpected token: %q", auth)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"number": 7, "state": "open"}`))
+
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	a := testLinkAPI(t, map[string]string{
+		"api_base_url": s.URL,
+		"client_id":    "client ID",
+		"private_key":  string(pemKey),
+		"install_id":   "1", // The link's default installation, overridden by the webhook's.
+	})
+
+	// Extract the installation ID from the webhook event, and call the activity twice.
+	wf := func(ctx workflow.Context, event map[string]any) ([]github.PullRequest, error) {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: 10 * time.Second})
+
+		installation, _ := event["installation"].(map[string]any)
+		id, _ := installation["id"].(float64)
+		pr, _ := event["pull_request"].(map[string]any)
+		number, _ := pr["number"].(float64)
+
+		req := PullRequestsGetRequest{Installation: Installation{InstallationID: int64(id)}}
+		req.Owner, req.Repo, req.PullNumber = "owner", "repo", int(number)
+
+		prs := make([]github.PullRequest, 2)
+		for i := range prs {
+			if err := workflow.ExecuteActivity(ctx, github.PullRequestsGetActivityName, req).Get(ctx, &prs[i]); err != nil {
+				return nil, err
+			}
+		}
+		return prs, nil
+	}
+
+	env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+	env.RegisterActivityWithOptions(a.PullRequestsGetActivity, activity.RegisterOptions{Name: github.PullRequestsGetActivityName})
+	env.RegisterWorkflow(wf)
+
+	event := map[string]any{}
+	if err := json.Unmarshal([]byte(pullRequestEvent), &event); err != nil {
+		t.Fatal(err)
+	}
+	env.ExecuteWorkflow(wf, event)
+
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow error = %v", err)
+	}
+	var got []github.PullRequest
+	if err := env.GetWorkflowResult(&got); err != nil {
+		t.Fatal(err)
+	}
+	for _, pr := range got {
+		if pr.Number != 7 {
+			t.Errorf("PullRequestsGetActivity() = %+v, want pull request 7", pr)
+		}
+	}
+	if n := tokenCalls.Load(); n != 1 {
+		t.Errorf("installation token requests = %d, want 1 (cached)", n)
+	}
+}
+
+func TestPullRequestsGetActivityInstallationWithoutApp(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL) // Personal access token.
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.PullRequestsGetActivity)
+
+	req := PullRequestsGetRequest{Installation: Installation{InstallationID: 42}}
+	req.Owner, req.Repo, req.PullNumber = "owner", "repo", 7
+
+	_, err := env.ExecuteActivity(a.PullRequestsGetActivity, req)
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+		t.Errorf("PullRequestsGetActivity() error = %v, want non-retryable error", err)
+	}
+}
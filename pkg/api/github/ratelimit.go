@@ -0,0 +1,159 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	altsrc "github.com/urfave/cli-altsrc/v3"
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/internal/config"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+const (
+	// RateLimitGetActivityName is a Timpani-specific activity, see [API.RateLimitGetActivity].
+	RateLimitGetActivityName = "github.rateLimit.get"
+
+	rateLimitFloorFlag = "github-rate-limit-floor"
+
+	rateLimitPath = "/rate_limit"
+	coreResource  = "core"
+)
+
+// Flags defines CLI flags to configure the GitHub activities of the Timpani worker. These flags
+// are usually set using environment variables or the application's configuration file.
+func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  rateLimitFloorFlag,
+			Usage: "remaining GitHub REST API requests per link, below which activities wait for the rate limit's reset (default = disabled)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_GITHUB_RATE_LIMIT_FLOOR"),
+				config.TOML("github.rate_limit_floor", configFilePath),
+			),
+		},
+	}
+}
+
+// RateLimitGetRequest is based on:
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+type RateLimitGetRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+}
+
+// RateLimitGetResponse is based on:
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+type RateLimitGetResponse struct {
+	Resources RateLimitResources `json:"resources"`
+}
+
+// RateLimitResources contains only the most commonly used subset of
+// the rate limit buckets in a [RateLimitGetResponse].
+type RateLimitResources struct {
+	Core    RateLimit `json:"core"`
+	Search  RateLimit `json:"search"`
+	GraphQL RateLimit `json:"graphql"`
+}
+
+// RateLimit is based on:
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+type RateLimit struct {
+	Limit     int   `json:"limit"`
+	Used      int   `json:"used"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"` // Unix seconds.
+}
+
+// RateLimitGetActivity is based on:
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+//
+// This API call doesn't count against the rate limits, and isn't subject to pacing (see [Flags]).
+// Its result also refreshes the core budget which the pacing of other activities is based on.
+func (a *API) RateLimitGetActivity(ctx context.Context, req RateLimitGetRequest) (*RateLimitGetResponse, error) {
+	t := time.Now().UTC()
+	resp := new(RateLimitGetResponse)
+	_, err := a.httpGet(ctx, req.ThrippyLinkID, req.InstallationID, rateLimitPath, defaultAccept, nil, resp)
+	otel.IncrementAPICallCounter(t, RateLimitGetActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	core := resp.Resources.Core
+	a.budgets.Store(a.budgetKey(req.ThrippyLinkID, req.InstallationID), rateBudget{remaining: core.Remaining, reset: time.Unix(core.Reset, 0)})
+	return resp, nil
+}
+
+// rateBudget is the most recently observed core rate limit of a Thrippy link
+// (and a GitHub app installation), based on the headers of API responses:
+// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#checking-the-status-of-your-rate-limit
+type rateBudget struct {
+	remaining int
+	reset     time.Time
+}
+
+func (a *API) budgetKey(linkID string, installID int64) string {
+	return fmt.Sprintf("%s|%d", a.metaKey(linkID), installID)
+}
+
+// updateBudget refreshes the core budget of a Thrippy link
+// (and a GitHub app installation), based on an API response.
+func (a *API) updateBudget(linkID string, installID int64, header http.Header) {
+	if header == nil || header.Get("X-Ratelimit-Resource") != coreResource {
+		return // E.g. GHES with rate limiting disabled, or other buckets.
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	a.budgets.Store(a.budgetKey(linkID, installID), rateBudget{remaining: remaining, reset: time.Unix(reset, 0)})
+}
+
+// paceRequest preserves the last requests in the core budget of a Thrippy link (and a GitHub
+// app installation) for other, possibly more critical workflows: if the remaining budget is
+// below the configured floor (see [Flags]), it returns a retryable error which delays the
+// activity's next attempt until the budget's reset, instead of sending the request.
+func (a *API) paceRequest(ctx context.Context, linkID string, installID int64, path string) error {
+	if a.rateLimitFloor <= 0 || !isCoreResource(path) {
+		return nil
+	}
+
+	v, ok := a.budgets.Load(a.budgetKey(linkID, installID))
+	if !ok {
+		return nil
+	}
+
+	b := v.(rateBudget) //nolint:errcheck // Type conversion always succeeds.
+	wait := time.Until(b.reset)
+	if b.remaining >= a.rateLimitFloor || wait <= 0 {
+		return nil
+	}
+
+	wait = wait.Truncate(time.Second) + time.Second
+	activity.GetLogger(ctx).Warn("pacing GitHub API call to preserve rate limit budget", slog.String("link_id", a.metaKey(linkID)),
+		slog.Int("remaining", b.remaining), slog.Int("floor", a.rateLimitFloor), slog.Duration("retry_after", wait))
+
+	opts := temporal.ApplicationErrorOptions{NextRetryDelay: wait, Details: []any{b.remaining, b.reset}}
+	return temporal.NewApplicationErrorWithOptions("GitHub API rate limit budget below floor", "RateLimitError", opts)
+}
+
+// isCoreResource checks whether an API call counts against the core budget, based on:
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#about-rate-limits
+func isCoreResource(path string) bool {
+	return path != graphqlPath && path != rateLimitPath && !strings.HasPrefix(path, "/search/")
+}
@@ -0,0 +1,174 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestRateLimitGetActivity(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != rateLimitPath {
+			t.Errorf("request path = %q, want %q", r.URL.Path, rateLimitPath)
+		}
+		_, _ = w.Write([]byte(`{"resources": {
+			"core": {"limit": 5000, "used": 4990, "remaining": 10, "reset": 1700000000},
+			"search": {"limit": 30, "used": 1, "remaining": 29, "reset": 1700000060},
+			"graphql": {"limit": 5000, "used": 0, "remaining": 5000, "reset": 1700003600}
+		}}`))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.RateLimitGetActivity)
+	val, err := env.ExecuteActivity(a.RateLimitGetActivity, RateLimitGetRequest{})
+	if err != nil {
+		t.Fatalf("RateLimitGetActivity() error = %v", err)
+	}
+
+	got := new(RateLimitGetResponse)
+	if err := val.Get(got); err != nil {
+		t.Fatal(err)
+	}
+	want := RateLimitResources{
+		Core:    RateLimit{Limit: 5000, Used: 4990, Remaining: 10, Reset: 1700000000},
+		Search:  RateLimit{Limit: 30, Used: 1, Remaining: 29, Reset: 1700000060},
+		GraphQL: RateLimit{Limit: 5000, Remaining: 5000, Reset: 1700003600},
+	}
+	if got.Resources != want {
+		t.Errorf("RateLimitGetActivity() = %+v, want %+v", got.Resources, want)
+	}
+
+	v, ok := a.budgets.Load(a.budgetKey("", 0))
+	if !ok {
+		t.Fatal("RateLimitGetActivity() didn't store the core budget")
+	}
+	if b := v.(rateBudget); b.remaining != 10 || !b.reset.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("core budget = %+v, want 10 requests until %v", b, time.Unix(1700000000, 0))
+	}
+}
+
+func TestPaceRequest(t *testing.T) {
+	future, past := time.Now().Add(time.Minute), time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name    string
+		floor   int
+		budget  *rateBudget
+		path    string
+		wantErr bool
+	}{
+		{
+			name:   "pacing_disabled",
+			budget: &rateBudget{remaining: 1, reset: future},
+			path:   "/repos/o/r",
+		},
+		{
+			name:  "unknown_budget",
+			floor: 10,
+			path:  "/repos/o/r",
+		},
+		{
+			name:   "budget_above_floor",
+			floor:  10,
+			budget: &rateBudget{remaining: 10, reset: future},
+			path:   "/repos/o/r",
+		},
+		{
+			name:    "budget_below_floor",
+			floor:   10,
+			budget:  &rateBudget{remaining: 9, reset: future},
+			path:    "/repos/o/r",
+			wantErr: true,
+		},
+		{
+			name:   "budget_already_reset",
+			floor:  10,
+			budget: &rateBudget{remaining: 0, reset: past},
+			path:   "/repos/o/r",
+		},
+		{
+			name:   "graphql",
+			floor:  10,
+			budget: &rateBudget{remaining: 0, reset: future},
+			path:   graphqlPath,
+		},
+		{
+			name:   "search",
+			floor:  10,
+			budget: &rateBudget{remaining: 0, reset: future},
+			path:   "/search/issues",
+		},
+		{
+			name:   "rate_limit",
+			floor:  10,
+			budget: &rateBudget{remaining: 0, reset: future},
+			path:   rateLimitPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &API{rateLimitFloor: tt.floor}
+			if tt.budget != nil {
+				a.budgets.Store(a.budgetKey("link", 0), *tt.budget)
+			}
+
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			pace := func(ctx context.Context) error {
+				return a.paceRequest(ctx, "link", 0, tt.path)
+			}
+			env.RegisterActivity(pace)
+			_, err := env.ExecuteActivity(pace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("paceRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPacingNearExhaustedBudget(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Ratelimit-Resource", "core")
+		w.Header().Set("X-Ratelimit-Remaining", strconv.Itoa(10-requests))
+		w.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(reset, 10))
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer s.Close()
+
+	a := testAPI(t, s.URL)
+	a.rateLimitFloor = 9
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.MetaGetActivity)
+
+	// The first 2 requests leave 9 and then 8 remaining requests in the budget.
+	for i := range 2 {
+		if _, err := env.ExecuteActivity(a.MetaGetActivity, MetaGetRequest{}); err != nil {
+			t.Fatalf("MetaGetActivity() attempt %d error = %v", i, err)
+		}
+	}
+
+	// Subsequent requests are delayed until the budget's reset, instead of being sent.
+	_, err := env.ExecuteActivity(a.MetaGetActivity, MetaGetRequest{})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != "RateLimitError" {
+		t.Fatalf("MetaGetActivity() error = %v, want RateLimitError", err)
+	}
+	if d := appErr.NextRetryDelay(); d <= 0 || d > time.Minute+time.Second {
+		t.Errorf("MetaGetActivity() next retry delay = %v, want up to a minute", d)
+	}
+	if requests != 2 {
+		t.Errorf("HTTP requests = %d, want 2", requests)
+	}
+}
@@ -2,33 +2,71 @@ package github
 
 import (
 	"context"
+	"sync"
 
 	"github.com/urfave/cli/v3"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 
 	"github.com/tzrikka/timpani-api/pkg/github"
+	"github.com/tzrikka/timpani/internal/taskqueue"
 	"github.com/tzrikka/timpani/internal/thrippy"
 )
 
 type API struct {
-	thrippy thrippy.LinkClient
+	thrippy   thrippy.LinkClient
+	meta      sync.Map // Thrippy link ID -> [*MetaGetResponse].
+	taskQueue string   // For activities of Timpani workflows, see [taskqueue.Activities].
+
+	budgets        sync.Map // Thrippy link ID + installation ID -> [rateBudget].
+	rateLimitFloor int      // 0 = no pacing, see [Flags].
 }
 
 // Register exposes Temporal activities and workflows via the Timpani worker.
-func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
-	id, ok := thrippy.LinkID(cmd, "GitHub")
-	if !ok {
-		return
+// It returns an error, without registering anything, if the service's Thrippy
+// link ID is missing or invalid.
+func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) error {
+	id, err := thrippy.LinkID(cmd, "GitHub")
+	if err != nil {
+		return err
+	}
+
+	a := &API{
+		thrippy:        thrippy.NewLinkClient(ctx, id, cmd),
+		taskQueue:      taskqueue.Service(cmd, "github"),
+		rateLimitFloor: cmd.Int(rateLimitFloorFlag),
 	}
 
-	a := API{thrippy: thrippy.NewLinkClient(ctx, id, cmd)}
+	registerActivity(w, a.ChecksListForRefActivity, ChecksListForRefActivityName)
+	registerActivity(w, a.ChecksRerequestSuiteActivity, ChecksRerequestSuiteActivityName)
+
+	registerActivity(w, a.GitCreateBlobActivity, GitCreateBlobActivityName)
+	registerActivity(w, a.GitCreateCommitActivity, GitCreateCommitActivityName)
+	registerActivity(w, a.GitCreateTreeActivity, GitCreateTreeActivityName)
+	registerActivity(w, a.GitGetBlobActivity, GitGetBlobActivityName)
+	registerActivity(w, a.GitGetTreeActivity, GitGetTreeActivityName)
+	registerActivity(w, a.GitUpdateRefActivity, GitUpdateRefActivityName)
 
 	registerActivity(w, a.IssuesCommentsCreateActivity, github.IssuesCommentsCreateActivityName)
 	registerActivity(w, a.IssuesCommentsDeleteActivity, github.IssuesCommentsDeleteActivityName)
 	registerActivity(w, a.IssuesCommentsUpdateActivity, github.IssuesCommentsUpdateActivityName)
+	registerActivity(w, a.IssuesCreateMilestoneActivity, IssuesCreateMilestoneActivityName)
+	registerActivity(w, a.IssuesListMilestonesActivity, IssuesListMilestonesActivityName)
+	registerActivity(w, a.IssuesUpdateMilestoneActivity, IssuesUpdateMilestoneActivityName)
+
+	registerActivity(w, a.MetaGetActivity, MetaGetActivityName)
+
+	registerActivity(w, a.OrgsCreateWebhookActivity, OrgsCreateWebhookActivityName)
+	registerActivity(w, a.OrgsDeleteWebhookActivity, OrgsDeleteWebhookActivityName)
+	registerActivity(w, a.OrgsListWebhooksActivity, OrgsListWebhooksActivityName)
+	registerActivity(w, a.OrgsPingWebhookActivity, OrgsPingWebhookActivityName)
+
+	registerActivity(w, a.ProjectsV2AddItemActivity, ProjectsV2AddItemActivityName)
+	registerActivity(w, a.ProjectsV2SetSingleSelectFieldActivity, ProjectsV2SetSingleSelectFieldActivityName)
 
 	registerActivity(w, a.PullRequestsGetActivity, github.PullRequestsGetActivityName)
+	registerActivity(w, a.PullRequestsDiffToFileActivity, PullRequestsDiffToFileActivityName)
 	registerActivity(w, a.PullRequestsListCommitsActivity, github.PullRequestsListCommitsActivityName)
 	registerActivity(w, a.PullRequestsListFilesActivity, github.PullRequestsListFilesActivityName)
 	registerActivity(w, a.PullRequestsMergeActivity, github.PullRequestsMergeActivityName)
@@ -42,10 +80,26 @@ func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
 	registerActivity(w, a.PullRequestsReviewsSubmitActivity, github.PullRequestsReviewsSubmitActivityName)
 	registerActivity(w, a.PullRequestsReviewsUpdateActivity, github.PullRequestsReviewsUpdateActivityName)
 
+	registerActivity(w, a.RateLimitGetActivity, RateLimitGetActivityName)
+
+	registerActivity(w, a.ReposCompareCommitsActivity, ReposCompareCommitsActivityName)
+	registerActivity(w, a.ReposCreateWebhookActivity, ReposCreateWebhookActivityName)
+	registerActivity(w, a.ReposDeleteWebhookActivity, ReposDeleteWebhookActivityName)
+	registerActivity(w, a.ReposListWebhooksActivity, ReposListWebhooksActivityName)
+	registerActivity(w, a.ReposPingWebhookActivity, ReposPingWebhookActivityName)
+
 	registerActivity(w, a.UsersGetActivity, github.UsersGetActivityName)
 	registerActivity(w, a.UsersListActivity, github.UsersListActivityName)
+
+	registerWorkflow(w, a.TimpaniAwaitChecksWorkflow, TimpaniAwaitChecksWorkflowName)
+
+	return nil
 }
 
 func registerActivity(w worker.Worker, f any, name string) {
 	w.RegisterActivityWithOptions(f, activity.RegisterOptions{Name: name})
 }
+
+func registerWorkflow(w worker.Worker, f any, name string) {
+	w.RegisterWorkflowWithOptions(f, workflow.RegisterOptions{Name: name})
+}
@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tzrikka/timpani-api/pkg/github"
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// ReposCompareCommitsActivityName is the name of [API.ReposCompareCommitsActivity].
+const ReposCompareCommitsActivityName = "github.repos.compareCommits"
+
+// ReposCompareCommitsRequest is based on:
+// https://docs.github.com/en/rest/commits/commits?apiVersion=2022-11-28#compare-two-commits
+//
+// Base and Head may be branch names, tag names, or commit SHAs. If SummaryOnly is true,
+// the response doesn't contain the lists of commits and files, to keep it small.
+type ReposCompareCommitsRequest struct {
+	ThrippyLinkID string `json:"thrippy_link_id,omitempty"`
+	Installation
+
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Base  string `json:"base"`
+	Head  string `json:"head"`
+
+	SummaryOnly bool `json:"summary_only,omitempty"`
+}
+
+// ReposCompareCommitsResponse is based on:
+// https://docs.github.com/en/rest/commits/commits?apiVersion=2022-11-28#compare-two-commits
+//
+// GitHub lists up to 300 files. If the files don't fit in the activity's response (see
+// [client.MaxResponseSize]), the list is truncated further, and FilesTruncated is true.
+type ReposCompareCommitsResponse struct {
+	HTMLURL         string        `json:"html_url,omitempty"`
+	Status          string        `json:"status"` // "ahead", "behind", "diverged", or "identical".
+	AheadBy         int           `json:"ahead_by"`
+	BehindBy        int           `json:"behind_by"`
+	TotalCommits    int           `json:"total_commits"`
+	MergeBaseCommit github.Commit `json:"merge_base_commit"`
+
+	Commits        []github.Commit `json:"commits,omitempty"`
+	Files          []github.File   `json:"files,omitempty"`
+	FilesTruncated bool            `json:"files_truncated,omitempty"`
+}
+
+// ReposCompareCommitsActivity is based on:
+// https://docs.github.com/en/rest/commits/commits?apiVersion=2022-11-28#compare-two-commits
+//
+// Pagination of commits is handled internally, to return all of them. GitHub paginates only
+// the commits, so the files are listed only in the first page.
+func (a *API) ReposCompareCommitsActivity(ctx context.Context, req ReposCompareCommitsRequest) (*ReposCompareCommitsResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/compare/%s...%s", req.Owner, req.Repo, url.PathEscape(req.Base), url.PathEscape(req.Head))
+	query := url.Values{}
+	query.Set("per_page", "100") // Default = 250 without pagination, 30 with it.
+	if req.SummaryOnly {
+		query.Set("per_page", "1")
+	}
+
+	var result *ReposCompareCommitsResponse
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		t := time.Now().UTC()
+		resp := new(ReposCompareCommitsResponse)
+		_, err := a.httpGet(ctx, req.ThrippyLinkID, req.InstallationID, path, defaultAccept, query, resp)
+		otel.IncrementAPICallCounter(t, ReposCompareCommitsActivityName, err)
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			result = resp
+		} else {
+			result.Commits = append(result.Commits, resp.Commits...)
+		}
+
+		if req.SummaryOnly || len(resp.Commits) == 0 || len(result.Commits) >= result.TotalCommits {
+			break
+		}
+	}
+
+	if req.SummaryOnly {
+		result.Commits, result.Files = nil, nil
+		return result, nil
+	}
+
+	// The files are truncated first, because the commits are more important in release notes.
+	truncated, err := client.LimitResponseSize(ReposCompareCommitsActivityName, result, &result.Files, true)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		result.FilesTruncated = true
+		activity.GetLogger(ctx).Warn("GitHub commit comparison files are truncated",
+			slog.String("basehead", req.Base+"..."+req.Head), slog.Int("files", len(result.Files)))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+func TestReposCompareCommitsActivity(t *testing.T) {
+	tests := []struct {
+		name        string
+		summaryOnly bool
+		wantPages   int
+		wantCommits int
+		wantFiles   int
+	}{
+		{
+			name:        "all_pages",
+			wantPages:   3,
+			wantCommits: 5,
+			wantFiles:   2,
+		},
+		{
+			name:        "summary_only",
+			summaryOnly: true,
+			wantPages:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pages++
+				if got := r.URL.EscapedPath(); got != "/repos/owner/repo/compare/v1.0...feature%2Fbranch" {
+					t.Errorf("unexpected URL path: %s", got)
+				}
+
+				summary := `"status": "ahead", "ahead_by": 5, "behind_by": 0, "total_commits": 5, "merge_base_commit": {"sha": "base"}`
+				switch r.URL.Query().Get("page") {
+				case "1":
+					_, _ = w.Write([]byte(`{` + summary + `, "commits": [{"sha": "1"}, {"sha": "2"}],
+						"files": [{"filename": "a.go"}, {"filename": "b.go"}]}`))
+				case "2":
+					_, _ = w.Write([]byte(`{` + summary + `, "commits": [{"sha": "3"}, {"sha": "4"}]}`))
+				case "3":
+					_, _ = w.Write([]byte(`{` + summary + `, "commits": [{"sha": "5"}]}`))
+				default:
+					t.Errorf("unexpected page: %s", r.URL.Query().Get("page"))
+				}
+			}))
+			defer s.Close()
+
+			a := testAPI(t, s.URL)
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivity(a.ReposCompareCommitsActivity)
+
+			req := ReposCompareCommitsRequest{Owner: "owner", Repo: "repo", Base: "v1.0", Head: "feature/branch", SummaryOnly: tt.summaryOnly}
+			v, err := env.ExecuteActivity(a.ReposCompareCommitsActivity, req)
+			if err != nil {
+				t.Fatalf("ReposCompareCommitsActivity() error = %v", err)
+			}
+
+			got := new(ReposCompareCommitsResponse)
+			if err := v.Get(got); err != nil {
+				t.Fatal(err)
+			}
+			if pages != tt.wantPages {
+				t.Errorf("ReposCompareCommitsActivity() API calls = %d, want %d", pages, tt.wantPages)
+			}
+			if got.Status != "ahead" || got.AheadBy != 5 || got.MergeBaseCommit.SHA != "base" {
+				t.Errorf("ReposCompareCommitsActivity() summary = %+v", got)
+			}
+			if len(got.Commits) != tt.wantCommits || len(got.Files) != tt.wantFiles {
+				t.Errorf("ReposCompareCommitsActivity() = %d commits and %d files, want %d and %d",
+					len(got.Commits), len(got.Files), tt.wantCommits, tt.wantFiles)
+			}
+			if tt.wantCommits > 0 && got.Commits[tt.wantCommits-1].SHA != "5" {
+				t.Errorf("ReposCompareCommitsActivity() last commit = %q, want %q", got.Commits[tt.wantCommits-1].SHA, "5")
+			}
+		})
+	}
+}
+
+func TestReposCompareCommitsActivityTruncatedFiles(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"status": "identical", "total_commits": 0, "files": [{"patch": "` +
+			strings.Repeat("x", 500) + `"}, {"patch": "` + strings.Repeat("y", 500) + `"}]}`))
+	}))
+	defer s.Close()
+
+	client.SetMaxResponseSize(1500) // Each file is ~630 bytes, and the rest is ~400 bytes.
+	t.Cleanup(func() { client.SetMaxResponseSize(0) })
+
+	a := testAPI(t, s.URL)
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivity(a.ReposCompareCommitsActivity)
+
+	req := ReposCompareCommitsRequest{Owner: "owner", Repo: "repo", Base: "main", Head: "main"}
+	v, err := env.ExecuteActivity(a.ReposCompareCommitsActivity, req)
+	if err != nil {
+		t.Fatalf("ReposCompareCommitsActivity() error = %v", err)
+	}
+
+	got := new(ReposCompareCommitsResponse)
+	if err := v.Get(got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Files) != 1 || !got.FilesTruncated {
+		t.Errorf("ReposCompareCommitsActivity() = %d files, truncated = %v, want 1 and true", len(got.Files), got.FilesTruncated)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/tzrikka/timpani-api/pkg/github"
+	"github.com/tzrikka/timpani/pkg/http/client"
 	"github.com/tzrikka/timpani/pkg/otel"
 )
 
@@ -26,9 +27,16 @@ func (a *API) UsersGetActivity(ctx context.Context, req github.UsersGetRequest)
 	}
 	path = fmt.Sprintf("%s/%s%s", path, req.AccountID, req.Username)
 
+	// https://docs.github.com/en/enterprise-server@3.13/rest/users/users#get-a-user-using-their-id
+	if req.AccountID != "" {
+		if err := a.requireGHESVersion(ctx, "", "getting a user by account ID", "3.13"); err != nil {
+			return nil, err
+		}
+	}
+
 	t := time.Now().UTC()
 	resp := map[string]any{}
-	_, err := a.httpGet(ctx, "", path, nil, &resp)
+	_, err := a.httpGet(ctx, "", 0, path, defaultAccept, nil, &resp)
 	otel.IncrementAPICallCounter(t, github.UsersGetActivityName, err)
 
 	if err != nil {
@@ -50,11 +58,14 @@ func (a *API) UsersListActivity(ctx context.Context, req github.UsersListRequest
 
 	t := time.Now().UTC()
 	resp := []map[string]any{}
-	_, err := a.httpGet(ctx, "", "/users/list", query, &resp)
+	_, err := a.httpGet(ctx, "", 0, "/users", defaultAccept, query, &resp)
 	otel.IncrementAPICallCounter(t, github.UsersListActivityName, err)
 
 	if err != nil {
 		return nil, err
 	}
+	if _, err := client.LimitResponseSize(github.UsersListActivityName, resp, &resp, false); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
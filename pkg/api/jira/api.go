@@ -3,11 +3,11 @@ package jira
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/log"
@@ -20,11 +20,21 @@ const (
 	URLPathPrefix = "/rest/api/3"
 )
 
-// httpGet is a Jira-specific HTTP GET wrapper for [client.HTTPRequest].
+// provider identifies Jira API calls (see [client.WithProvider]).
+var provider = client.WithProvider(client.Provider{Name: "jira", ErrorDetails: errorDetails})
+
+// errorDetails extracts the request ID from Jira HTTP error responses.
+// Jira doesn't specify error codes, only human-readable messages.
+func errorDetails(header http.Header, _ []byte) (string, string) {
+	return "", header.Get("X-Arequestid")
+}
+
+// httpGet is a Jira-specific HTTP GET wrapper for [client.HTTPRequestFull].
 func (a *API) httpGet(ctx context.Context, pathSuffix string, query url.Values, jsonResp any) error {
 	if err := a.httpRequest(ctx, pathSuffix, http.MethodGet, query, jsonResp); err != nil {
-		if strings.HasPrefix(err.Error(), "404 Not Found") {
-			return temporal.NewNonRetryableApplicationError(err.Error(), "JiraAPIError", err, query.Encode())
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return temporal.NewNonRetryableApplicationError(apiErr.Error(), "JiraAPIError", err, query.Encode())
 		}
 		return err
 	}
@@ -37,11 +47,11 @@ func (a *API) httpRequest(ctx context.Context, pathSuffix, method string, queryO
 		return err
 	}
 
-	resp, _, _, err := client.HTTPRequest(ctx, method, apiURL, auth, client.AcceptJSON, client.ContentJSON, queryOrJSONBody)
+	resp, err := client.HTTPRequestFull(ctx, method, apiURL, auth, client.AcceptJSON, client.ContentJSON, queryOrJSONBody, provider)
 	if err != nil {
 		l.Error("HTTP request error", slog.Any("error", err),
 			slog.String("http_method", method), slog.String("url", apiURL))
-		return err
+		return client.NewApplicationError(err)
 	}
 
 	l.Info("sent HTTP request", slog.String("link_id", a.thrippy.LinkID),
@@ -51,11 +61,11 @@ func (a *API) httpRequest(ctx context.Context, pathSuffix, method string, queryO
 		return nil
 	}
 
-	if err := json.Unmarshal(resp, jsonResp); err != nil {
+	if err := json.Unmarshal(resp.Body, jsonResp); err != nil {
 		msg := "failed to decode HTTP response's JSON body"
 		l.Error(msg, slog.Any("error", err), slog.String("url", apiURL))
 		msg = fmt.Sprintf("%s: %v", msg, err)
-		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp))
+		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp.Body))
 	}
 
 	return nil
@@ -78,12 +88,15 @@ func (a *API) httpRequestPrep(ctx context.Context, pathSuffix string) (l log.Log
 		return l, "", "", err
 	}
 
+	return l, apiURL, authHeader(secrets), err
+}
+
+// authHeader returns the value of an HTTP "Authorization" header, based on the given link secrets.
+func authHeader(secrets map[string]string) string {
 	// "access_token" has a value only in "jira-app-oauth" link secrets.
 	// The others have values only in "jira-user-token" link secrets.
-	auth = secrets["access_token"]
-	if auth == "" {
-		auth = fmt.Sprintf("Basic %s:%s", secrets["email"], secrets["api_token"])
+	if auth := secrets["access_token"]; auth != "" {
+		return auth
 	}
-
-	return l, apiURL, auth, err
+	return fmt.Sprintf("Basic %s:%s", secrets["email"], secrets["api_token"])
 }
@@ -0,0 +1,24 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+// ProbeLink checks that the credentials of a Thrippy link are still valid, using the cheapest authenticated
+// Jira API call: https://developer.atlassian.com/cloud/jira/platform/rest/v3/api-group-myself/#api-rest-api-3-myself-get
+//
+// Unlike the rest of this package, this function runs outside of Temporal activities.
+func ProbeLink(ctx context.Context, _ string, secrets map[string]string) error {
+	apiURL, err := url.JoinPath(secrets["base_url"], URLPathPrefix, "/myself")
+	if err != nil {
+		return err
+	}
+
+	auth := authHeader(secrets)
+	_, _, _, err = client.HTTPRequest(ctx, http.MethodGet, apiURL, auth, client.AcceptJSON, "", nil, client.WithTimeout(client.Timeout))
+	return err
+}
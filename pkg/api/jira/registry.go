@@ -16,16 +16,20 @@ type API struct {
 }
 
 // Register exposes Temporal activities and workflows via the Timpani worker.
-func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
-	id, ok := thrippy.LinkID(cmd, "Jira")
-	if !ok {
-		return
+// It returns an error, without registering anything, if the service's Thrippy
+// link ID is missing or invalid.
+func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) error {
+	id, err := thrippy.LinkID(cmd, "Jira")
+	if err != nil {
+		return err
 	}
 
 	a := API{thrippy: thrippy.NewLinkClient(ctx, id, cmd)}
 
 	registerActivity(w, a.UsersGetActivity, jira.UsersGetActivityName)
 	registerActivity(w, a.UsersSearchActivity, jira.UsersSearchActivityName)
+
+	return nil
 }
 
 func registerActivity(w worker.Worker, f any, name string) {
@@ -0,0 +1,237 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+// Admin API methods are available only in Enterprise Grid orgs, with a user
+// token of an org admin or owner (the "user_token" secret of the Thrippy link),
+// which has the relevant "admin.*" scopes: https://docs.slack.dev/admins/.
+
+//revive:disable:exported
+const (
+	AdminConversationsSearchActivityName   = "slack.admin.conversations.search"
+	AdminConversationsSetTeamsActivityName = "slack.admin.conversations.setTeams"
+	AdminUsersListActivityName             = "slack.admin.users.list"
+	AdminUsersSessionResetActivityName     = "slack.admin.users.session.reset"
+) //revive:enable:exported
+
+// adminErrors indicate that an admin API call will never succeed
+// with the link's current token, org plan, or app configuration.
+var adminErrors = []string{"feature_not_enabled", "not_an_admin", "not_an_enterprise"}
+
+// isAdminMethod checks whether the given Slack API method
+// requires a user token instead of a bot token.
+func isAdminMethod(urlSuffix string) bool {
+	return strings.HasPrefix(urlSuffix, "slack.admin.")
+}
+
+// adminError classifies the error in an admin API response, if there is one.
+func adminError(resp slack.Response, details ...any) error {
+	if resp.OK {
+		return nil
+	}
+	if slices.Contains(adminErrors, resp.Error) {
+		return temporal.NewNonRetryableApplicationError(resp.Error, "SlackAPIError", nil, details...)
+	}
+	return errors.New("Slack API error: " + resp.Error)
+}
+
+// AdminConversationsSearchRequest is based on:
+// https://docs.slack.dev/reference/methods/admin.conversations.search/
+type AdminConversationsSearchRequest struct {
+	Query              string   `json:"query,omitempty"`
+	TeamIDs            []string `json:"team_ids,omitempty"`
+	SearchChannelTypes []string `json:"search_channel_types,omitempty"` // E.g. "private", "archived".
+	ConnectedTeamIDs   []string `json:"connected_team_ids,omitempty"`
+	Sort               string   `json:"sort,omitempty"`     // "relevant" (default), "name", "member_count", "created".
+	SortDir            string   `json:"sort_dir,omitempty"` // "asc" (default) or "desc".
+
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// AdminConversationsSearchResponse is based on:
+// https://docs.slack.dev/reference/methods/admin.conversations.search/
+//
+// To get the next page, set the request's Cursor field to NextCursor, while it's not empty.
+type AdminConversationsSearchResponse struct {
+	slack.Response
+
+	Conversations []map[string]any `json:"conversations,omitempty"`
+	NextCursor    string           `json:"next_cursor,omitempty"`
+	TotalCount    int              `json:"total_count,omitempty"`
+}
+
+// AdminConversationsSearchActivity is based on:
+// https://docs.slack.dev/reference/methods/admin.conversations.search/
+func (a *API) AdminConversationsSearchActivity(ctx context.Context, req AdminConversationsSearchRequest) (*AdminConversationsSearchResponse, error) {
+	resp := new(AdminConversationsSearchResponse)
+	if err := a.httpGet(ctx, AdminConversationsSearchActivityName, adminConversationsSearchQuery(req), resp); err != nil {
+		return nil, err
+	}
+
+	if err := adminError(resp.Response, req.Query); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func adminConversationsSearchQuery(req AdminConversationsSearchRequest) url.Values {
+	query := url.Values{}
+	if req.Query != "" {
+		query.Set("query", req.Query)
+	}
+	if len(req.TeamIDs) > 0 {
+		query.Set("team_ids", strings.Join(req.TeamIDs, ","))
+	}
+	if len(req.SearchChannelTypes) > 0 {
+		query.Set("search_channel_types", strings.Join(req.SearchChannelTypes, ","))
+	}
+	if len(req.ConnectedTeamIDs) > 0 {
+		query.Set("connected_team_ids", strings.Join(req.ConnectedTeamIDs, ","))
+	}
+	if req.Sort != "" {
+		query.Set("sort", req.Sort)
+	}
+	if req.SortDir != "" {
+		query.Set("sort_dir", req.SortDir)
+	}
+	if req.Cursor != "" {
+		query.Set("cursor", req.Cursor)
+	}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	return query
+}
+
+// AdminConversationsSetTeamsRequest is based on:
+// https://docs.slack.dev/reference/methods/admin.conversations.setTeams/
+type AdminConversationsSetTeamsRequest struct {
+	ChannelID     string   `json:"channel_id"`
+	OrgChannel    bool     `json:"org_channel,omitempty"`
+	TargetTeamIDs []string `json:"target_team_ids,omitempty"`
+	TeamID        string   `json:"team_id,omitempty"`
+}
+
+// adminConversationsSetTeamsBody is the JSON body of [AdminConversationsSetTeamsRequest],
+// in which the team IDs are a comma-separated string instead of a list.
+type adminConversationsSetTeamsBody struct {
+	ChannelID     string `json:"channel_id"`
+	OrgChannel    bool   `json:"org_channel,omitempty"`
+	TargetTeamIDs string `json:"target_team_ids,omitempty"`
+	TeamID        string `json:"team_id,omitempty"`
+}
+
+// AdminConversationsSetTeamsActivity is based on:
+// https://docs.slack.dev/reference/methods/admin.conversations.setTeams/
+func (a *API) AdminConversationsSetTeamsActivity(ctx context.Context, req AdminConversationsSetTeamsRequest) (*slack.Response, error) {
+	body := adminConversationsSetTeamsBody{
+		ChannelID:     req.ChannelID,
+		OrgChannel:    req.OrgChannel,
+		TargetTeamIDs: strings.Join(req.TargetTeamIDs, ","),
+		TeamID:        req.TeamID,
+	}
+
+	resp := new(slack.Response)
+	if err := a.httpPost(ctx, AdminConversationsSetTeamsActivityName, body, resp); err != nil {
+		return nil, err
+	}
+
+	if err := adminError(*resp, req.ChannelID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AdminUsersListRequest is based on:
+// https://docs.slack.dev/reference/methods/admin.users.list/
+type AdminUsersListRequest struct {
+	TeamID                           string `json:"team_id,omitempty"`
+	IncludeDeactivatedUserWorkspaces bool   `json:"include_deactivated_user_workspaces,omitempty"`
+	IsActive                         *bool  `json:"is_active,omitempty"`
+	OnlyGuests                       bool   `json:"only_guests,omitempty"`
+
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// AdminUsersListResponse is based on:
+// https://docs.slack.dev/reference/methods/admin.users.list/
+//
+// To get the next page, set the request's Cursor field to
+// the response's "next_cursor" metadata, while it's not empty.
+type AdminUsersListResponse struct {
+	slack.Response
+
+	Users []map[string]any `json:"users,omitempty"`
+}
+
+// AdminUsersListActivity is based on:
+// https://docs.slack.dev/reference/methods/admin.users.list/
+func (a *API) AdminUsersListActivity(ctx context.Context, req AdminUsersListRequest) (*AdminUsersListResponse, error) {
+	resp := new(AdminUsersListResponse)
+	if err := a.httpGet(ctx, AdminUsersListActivityName, adminUsersListQuery(req), resp); err != nil {
+		return nil, err
+	}
+
+	if err := adminError(resp.Response, req.TeamID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func adminUsersListQuery(req AdminUsersListRequest) url.Values {
+	query := url.Values{}
+	if req.TeamID != "" {
+		query.Set("team_id", req.TeamID)
+	}
+	if req.IncludeDeactivatedUserWorkspaces {
+		query.Set("include_deactivated_user_workspaces", "true")
+	}
+	if req.IsActive != nil {
+		query.Set("is_active", strconv.FormatBool(*req.IsActive))
+	}
+	if req.OnlyGuests {
+		query.Set("only_guests", "true")
+	}
+	if req.Cursor != "" {
+		query.Set("cursor", req.Cursor)
+	}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	return query
+}
+
+// AdminUsersSessionResetRequest is based on:
+// https://docs.slack.dev/reference/methods/admin.users.session.reset/
+type AdminUsersSessionResetRequest struct {
+	UserID     string `json:"user_id"`
+	MobileOnly bool   `json:"mobile_only,omitempty"`
+	WebOnly    bool   `json:"web_only,omitempty"`
+}
+
+// AdminUsersSessionResetActivity is based on:
+// https://docs.slack.dev/reference/methods/admin.users.session.reset/
+func (a *API) AdminUsersSessionResetActivity(ctx context.Context, req AdminUsersSessionResetRequest) (*slack.Response, error) {
+	resp := new(slack.Response)
+	if err := a.httpPost(ctx, AdminUsersSessionResetActivityName, req, resp); err != nil {
+		return nil, err
+	}
+
+	if err := adminError(*resp, req.UserID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,82 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+func TestLinkTokenAdmin(t *testing.T) {
+	secrets := map[string]string{"bot_token": "xoxb", "user_token": "xoxp"}
+	if got, kind := linkToken(secrets, AdminUsersListActivityName); got != "xoxp" {
+		t.Errorf("linkToken() = %q, %q, want %q", got, kind, "xoxp")
+	}
+	if got, kind := linkToken(map[string]string{"bot_token": "xoxb"}, AdminUsersSessionResetActivityName); got != "" {
+		t.Errorf("linkToken() = %q, %q, want no token", got, kind)
+	}
+}
+
+func TestAdminError(t *testing.T) {
+	tests := []struct {
+		name             string
+		resp             slack.Response
+		wantErr          bool
+		wantNonRetryable bool
+	}{
+		{
+			name: "ok",
+			resp: slack.Response{OK: true},
+		},
+		{
+			name:             "feature_not_enabled",
+			resp:             slack.Response{Error: "feature_not_enabled"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:             "not_an_admin",
+			resp:             slack.Response{Error: "not_an_admin"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:    "ratelimited",
+			resp:    slack.Response{Error: "ratelimited"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := adminError(tt.resp, "U123")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("adminError() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var appErr *temporal.ApplicationError
+			nonRetryable := errors.As(err, &appErr) && appErr.NonRetryable()
+			if nonRetryable != tt.wantNonRetryable {
+				t.Errorf("adminError() non-retryable = %v, want %v", nonRetryable, tt.wantNonRetryable)
+			}
+		})
+	}
+}
+
+func TestAdminConversationsSearchQuery(t *testing.T) {
+	req := AdminConversationsSearchRequest{Query: "eng", TeamIDs: []string{"T1", "T2"}, Cursor: "abc", Limit: 20}
+	want := "cursor=abc&limit=20&query=eng&team_ids=T1%2CT2"
+	if got := adminConversationsSearchQuery(req).Encode(); got != want {
+		t.Errorf("adminConversationsSearchQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestAdminUsersListQuery(t *testing.T) {
+	req := AdminUsersListRequest{TeamID: "T1", IsActive: new(false), Cursor: "abc"}
+	want := "cursor=abc&is_active=false&team_id=T1"
+	if got := adminUsersListQuery(req).Encode(); got != want {
+		t.Errorf("adminUsersListQuery() = %q, want %q", got, want)
+	}
+}
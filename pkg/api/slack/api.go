@@ -8,6 +8,8 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"path"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,22 +22,54 @@ import (
 	"github.com/tzrikka/timpani/pkg/otel"
 )
 
-func (a *API) httpRequestPrep(ctx context.Context, urlSuffix string) (l log.Logger, t time.Time, apiURL, botToken string, err error) {
+// provider identifies Slack API calls (see [client.WithProvider]).
+var provider = client.WithProvider(client.Provider{Name: "slack", ErrorDetails: errorDetails, AuditFields: auditFields})
+
+// errorDetails extracts the error code and request ID from Slack HTTP error responses.
+func errorDetails(header http.Header, body []byte) (string, string) {
+	resp := new(slack.Response)
+	_ = json.Unmarshal(body, resp)
+	return resp.Error, header.Get("X-Slack-Req-Id")
+}
+
+// methodAuditFields are the request fields which identify the targets of Slack API
+// methods in audit records (see [client.SetAuditLogger]), if they differ from the
+// [client.DefaultAuditFields]. Message texts and blocks are never recorded.
+var methodAuditFields = map[string][]string{
+	"chat.delete":        {"channel", "ts"},
+	"chat.postEphemeral": {"channel", "thread_ts", "user"},
+	"chat.postMessage":   {"channel", "thread_ts", "reply_broadcast"},
+	"chat.update":        {"channel", "ts"},
+
+	"conversations.kick":                        {"channel", "user"},
+	"conversations.open":                        {"channel", "users"},
+	"conversations.requestSharedInvite.approve": {"invite_id", "channel_id"},
+	"conversations.requestSharedInvite.deny":    {"invite_id"},
+
+	"files.completeUploadExternal": {"channel_id", "thread_ts"},
+
+	"reactions.add":    {"channel", "timestamp", "name"},
+	"reactions.remove": {"channel", "timestamp", "name"},
+}
+
+// auditFields returns the request fields which identify the target of
+// the given Slack API endpoint (e.g. "/api/chat.postMessage") in audit records.
+func auditFields(endpoint string) []string {
+	return methodAuditFields[path.Base(endpoint)]
+}
+
+func (a *API) httpRequestPrep(ctx context.Context, urlSuffix string) (l log.Logger, t time.Time, apiURL, token string, err error) {
 	l = activity.GetLogger(ctx)
 	t = time.Now().UTC()
 
 	var template string
 	var secrets map[string]string
-	template, secrets, err = a.thrippy.LinkData(ctx)
+	template, secrets, err = a.thrippy.LinkData(ctx, "")
 	if err != nil {
 		return l, t, "", "", err
 	}
 
-	baseURL := "https://slack.com"
-	if template == "slack-oauth-gov" {
-		baseURL = "https://slack-gov.com" // https://docs.slack.dev/govslack
-	}
-
+	baseURL := apiBaseURL(template)
 	suffix := strings.TrimPrefix(urlSuffix, "slack.")
 	apiURL, err = url.JoinPath(baseURL, "api", suffix)
 	if err != nil {
@@ -45,53 +79,91 @@ func (a *API) httpRequestPrep(ctx context.Context, urlSuffix string) (l log.Logg
 		return l, t, "", "", err
 	}
 
-	botToken = secrets["bot_token"]
-	if botToken == "" {
-		botToken = secrets["access_token"] // Short-lived OAuth token.
-	}
-	if botToken == "" {
-		msg := "Slack bot token not found in Thrippy link credentials"
+	token, kind := linkToken(secrets, urlSuffix)
+	if token == "" {
+		msg := fmt.Sprintf("Slack %s not found in Thrippy link credentials", kind)
 		l.Warn(msg, slog.String("link_id", a.thrippy.LinkID))
 		err = temporal.NewNonRetryableApplicationError(msg, "error", nil, a.thrippy.LinkID)
-		return l, t, apiURL, botToken, err
+		return l, t, apiURL, token, err
+	}
+
+	return l, t, apiURL, token, nil
+}
+
+// apiBaseURL returns the base URL of the Slack API, based on the given link template.
+func apiBaseURL(template string) string {
+	if template == "slack-oauth-gov" {
+		return "https://slack-gov.com" // https://docs.slack.dev/govslack
 	}
+	return "https://slack.com"
+}
+
+// appTokenMethods are the Slack API methods which require the app-level token
+// of the link (https://docs.slack.dev/authentication/tokens#app-level) instead of
+// its bot token. Other methods don't accept app-level tokens at all.
+var appTokenMethods = []string{
+	AppsEventAuthorizationsListActivityName,
+}
 
-	return l, t, apiURL, botToken, nil
+// linkToken returns the token from the given link secrets which is required by the
+// given Slack API method, and a description of its kind (for error messages).
+// The token is an empty string if the link doesn't contain it.
+func linkToken(secrets map[string]string, urlSuffix string) (token, kind string) {
+	if slices.Contains(appTokenMethods, urlSuffix) {
+		return secrets["app_token"], "app-level token"
+	}
+	if isAdminMethod(urlSuffix) {
+		return secrets["user_token"], "admin user token"
+	}
+	return linkBotToken(secrets), "bot token"
 }
 
-// httpGet is a Slack-specific HTTP GET wrapper for [client.HTTPRequest].
+// linkBotToken returns the bot token from the given link secrets,
+// or an empty string if the link doesn't contain a bot token.
+func linkBotToken(secrets map[string]string) string {
+	if token := secrets["bot_token"]; token != "" {
+		return token
+	}
+	return secrets["access_token"] // Short-lived OAuth token.
+}
+
+// httpGet is a Slack-specific HTTP GET wrapper for [client.HTTPRequestFull].
 func (a *API) httpGet(ctx context.Context, urlSuffix string, query url.Values, jsonResp any) error {
-	l, t, apiURL, botToken, err := a.httpRequestPrep(ctx, urlSuffix)
+	l, t, apiURL, token, err := a.httpRequestPrep(ctx, urlSuffix)
 	if err != nil {
 		return err
 	}
 
-	resp, _, retryAfter, err := client.HTTPRequest(ctx, http.MethodGet, apiURL, botToken, client.AcceptJSON, "", query)
+	resp, err := client.HTTPRequestFull(ctx, http.MethodGet, apiURL, token, client.AcceptJSON, "", query, provider)
 	if err != nil {
 		otel.IncrementAPICallCounter(t, urlSuffix, err)
 
-		if retryAfter > 0 {
-			l.Warn("throttling HTTP GET request", slog.Int("retry_after", retryAfter), slog.String("url", apiURL))
-			opts := temporal.ApplicationErrorOptions{NextRetryDelay: time.Second * time.Duration(retryAfter)}
-			return temporal.NewApplicationErrorWithOptions(err.Error(), "RateLimitError", opts)
+		if resp != nil && resp.RetryAfter > 0 {
+			l.Warn("throttling HTTP GET request", slog.Int("retry_after", resp.RetryAfter), slog.String("url", apiURL))
+			opts := temporal.ApplicationErrorOptions{NextRetryDelay: time.Second * time.Duration(resp.RetryAfter), Cause: err}
+			return temporal.NewApplicationErrorWithOptions("Slack API rate limit", "RateLimitError", opts)
 		}
 
 		l.Error("HTTP GET request error", slog.Any("error", err), slog.String("url", apiURL))
-		return err
+		return client.NewApplicationError(err)
 	}
 
-	if err := json.Unmarshal(resp, jsonResp); err != nil {
+	if err := json.Unmarshal(resp.Body, jsonResp); err != nil {
 		otel.IncrementAPICallCounter(t, urlSuffix, err)
 
 		msg := "failed to decode HTTP GET response's JSON body"
 		l.Error(msg, slog.Any("error", err), slog.String("url", apiURL))
 		msg = fmt.Sprintf("%s: %v", msg, err)
-		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp))
+		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp.Body))
 	}
 
 	baseResp := new(slack.Response)
-	if err := json.Unmarshal(resp, baseResp); err == nil && !baseResp.OK && strings.Contains(baseResp.Error, "invalid") {
-		err = errors.New(string(resp))
+	if err := json.Unmarshal(resp.Body, baseResp); err == nil {
+		a.identity.invalidateOnAuthError(baseResp.Error)
+		reportWarnings(l, t, urlSuffix, baseResp)
+	}
+	if !baseResp.OK && strings.Contains(baseResp.Error, "invalid") {
+		err = errors.New(string(resp.Body))
 		otel.IncrementAPICallCounter(t, urlSuffix, err)
 		return temporal.NewNonRetryableApplicationError(baseResp.Error, "SlackAPIError", err, jsonResp)
 	}
@@ -101,39 +173,43 @@ func (a *API) httpGet(ctx context.Context, urlSuffix string, query url.Values, j
 	return nil
 }
 
-// httpPost is a Slack-specific HTTP POST wrapper for [client.HTTPRequest].
+// httpPost is a Slack-specific HTTP POST wrapper for [client.HTTPRequestFull].
 func (a *API) httpPost(ctx context.Context, urlSuffix string, jsonBody, jsonResp any) error {
-	l, t, apiURL, botToken, err := a.httpRequestPrep(ctx, urlSuffix)
+	l, t, apiURL, token, err := a.httpRequestPrep(ctx, urlSuffix)
 	if err != nil {
 		return err
 	}
 
-	resp, _, retryAfter, err := client.HTTPRequest(ctx, http.MethodPost, apiURL, botToken, client.AcceptJSON, client.ContentJSON, jsonBody)
+	resp, err := client.HTTPRequestFull(ctx, http.MethodPost, apiURL, token, client.AcceptJSON, client.ContentJSON, jsonBody, provider)
 	if err != nil {
 		otel.IncrementAPICallCounter(t, urlSuffix, err)
 
-		if retryAfter > 0 {
-			l.Warn("throttling HTTP POST request", slog.Int("retry_after", retryAfter), slog.String("url", apiURL))
-			opts := temporal.ApplicationErrorOptions{NextRetryDelay: time.Second * time.Duration(retryAfter)}
-			return temporal.NewApplicationErrorWithOptions(err.Error(), "RateLimitError", opts)
+		if resp != nil && resp.RetryAfter > 0 {
+			l.Warn("throttling HTTP POST request", slog.Int("retry_after", resp.RetryAfter), slog.String("url", apiURL))
+			opts := temporal.ApplicationErrorOptions{NextRetryDelay: time.Second * time.Duration(resp.RetryAfter), Cause: err}
+			return temporal.NewApplicationErrorWithOptions("Slack API rate limit", "RateLimitError", opts)
 		}
 
 		l.Error("HTTP POST request error", slog.Any("error", err), slog.String("url", apiURL))
-		return err
+		return client.NewApplicationError(err)
 	}
 
-	if err := json.Unmarshal(resp, jsonResp); err != nil {
+	if err := json.Unmarshal(resp.Body, jsonResp); err != nil {
 		otel.IncrementAPICallCounter(t, urlSuffix, err)
 
 		msg := "failed to decode HTTP POST response's JSON body"
 		l.Error(msg, slog.Any("error", err), slog.String("url", apiURL))
 		msg = fmt.Sprintf("%s: %v", msg, err)
-		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp))
+		return temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err, apiURL, string(resp.Body))
 	}
 
 	baseResp := new(slack.Response)
-	if err := json.Unmarshal(resp, baseResp); err == nil && !baseResp.OK && strings.Contains(baseResp.Error, "invalid") {
-		err = errors.New(string(resp))
+	if err := json.Unmarshal(resp.Body, baseResp); err == nil {
+		a.identity.invalidateOnAuthError(baseResp.Error)
+		reportWarnings(l, t, urlSuffix, baseResp)
+	}
+	if !baseResp.OK && strings.Contains(baseResp.Error, "invalid") {
+		err = errors.New(string(resp.Body))
 		otel.IncrementAPICallCounter(t, urlSuffix, err)
 		return temporal.NewNonRetryableApplicationError(baseResp.Error, "SlackAPIError", err, jsonResp)
 	}
@@ -143,16 +219,61 @@ func (a *API) httpPost(ctx context.Context, urlSuffix string, jsonBody, jsonResp
 	return nil
 }
 
-// httpPostFile is an HTTP POST wrapper of [client.HTTPRequest] for uploading files to Slack.
+// reportWarnings logs and counts the warnings in a Slack API response (e.g. deprecations or
+// missing scopes), which don't fail the call yet, so operators notice them before they do.
+// See https://docs.slack.dev/apis/web-api/#responses.
+func reportWarnings(l log.Logger, t time.Time, activityName string, resp *slack.Response) {
+	warnings := responseWarnings(resp)
+	var msgs []string
+	if resp.ResponseMetadata != nil {
+		msgs = resp.ResponseMetadata.Messages
+	}
+	if len(warnings) == 0 && len(msgs) == 0 {
+		return
+	}
+
+	l.Warn("Slack API response warnings", slog.String("activity", activityName),
+		slog.Any("warnings", warnings), slog.Any("messages", msgs))
+	for _, w := range warnings {
+		otel.IncrementDeprecationCounter(t, activityName+":"+w)
+	}
+}
+
+// responseWarnings returns the unique warning codes in a Slack API response, from both
+// its "warning" field (comma-separated) and its "response_metadata.warnings" list.
+func responseWarnings(resp *slack.Response) []string {
+	var ws []string
+	if resp.Warning != "" {
+		ws = strings.Split(resp.Warning, ",")
+	}
+	if resp.ResponseMetadata != nil {
+		ws = append(ws, resp.ResponseMetadata.Warnings...)
+	}
+
+	var unique []string
+	for _, w := range ws {
+		if w = strings.TrimSpace(w); w != "" && !slices.Contains(unique, w) {
+			unique = append(unique, w)
+		}
+	}
+	return unique
+}
+
+// httpPostFile is an HTTP POST wrapper of [client.HTTPRequestFull] for uploading files to Slack.
 func (a *API) httpPostFile(ctx context.Context, uploadURL, contentType string, content []byte) error {
 	l := activity.GetLogger(ctx)
 	t := time.Now().UTC()
 
-	if resp, _, _, err := client.HTTPRequest(ctx, http.MethodPost, uploadURL, "", "", contentType, content); err != nil {
+	resp, err := client.HTTPRequestFull(ctx, http.MethodPost, uploadURL, "", "", contentType, content, client.WithFileTransferTimeout())
+	if err != nil {
+		var body []byte
+		if resp != nil {
+			body = resp.Body
+		}
 		l.Error("HTTP POST request error", slog.Any("error", err), slog.String("url", uploadURL),
-			slog.String("content_type", contentType), slog.String("response", string(resp)))
+			slog.String("content_type", contentType), slog.String("response", string(body)))
 		otel.IncrementAPICallCounter(t, slack.TimpaniUploadExternalActivityName, err)
-		return err
+		return client.NewApplicationError(err)
 	}
 
 	l.Info("sent HTTP POST request", slog.String("url", uploadURL),
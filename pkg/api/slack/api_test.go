@@ -0,0 +1,141 @@
+package slack
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/log"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+func TestResponseWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *slack.Response
+		want []string
+	}{
+		{
+			name: "none",
+			resp: &slack.Response{OK: true},
+		},
+		{
+			name: "warning_field",
+			resp: &slack.Response{OK: true, Warning: "missing_charset,superfluous_charset"},
+			want: []string{"missing_charset", "superfluous_charset"},
+		},
+		{
+			name: "response_metadata",
+			resp: &slack.Response{OK: true, ResponseMetadata: &slack.ResponseMetadata{Warnings: []string{"missing_charset"}}},
+			want: []string{"missing_charset"},
+		},
+		{
+			name: "duplicates",
+			resp: &slack.Response{OK: true, Warning: "missing_charset", ResponseMetadata: &slack.ResponseMetadata{
+				Warnings: []string{"missing_charset", "method_deprecated"},
+			}},
+			want: []string{"missing_charset", "method_deprecated"},
+		},
+		{
+			name: "messages_only",
+			resp: &slack.Response{OK: true, ResponseMetadata: &slack.ResponseMetadata{
+				Messages: []string{"[WARN] A Content-Type HTTP header was presented but did not declare a charset"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := responseWarnings(tt.resp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("responseWarnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// testLogger records the messages of Temporal log calls.
+type testLogger struct {
+	log.Logger
+
+	msgs []string
+}
+
+func (l *testLogger) Warn(msg string, _ ...any) {
+	l.msgs = append(l.msgs, msg)
+}
+
+func TestReportWarnings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	l := new(testLogger)
+	reportWarnings(l, now, "slack.chat.postMessage", &slack.Response{OK: true})
+	if len(l.msgs) != 0 {
+		t.Errorf("reportWarnings() without warnings logged %q", l.msgs)
+	}
+
+	resp := &slack.Response{OK: true, ResponseMetadata: &slack.ResponseMetadata{
+		Warnings: []string{"missing_scope", "method_deprecated"},
+		Messages: []string{"[WARN] missing scope: chat:write.customize"},
+	}}
+	reportWarnings(l, now, "slack.chat.postMessage", resp)
+	if want := []string{"Slack API response warnings"}; !reflect.DeepEqual(l.msgs, want) {
+		t.Errorf("reportWarnings() logged %q, want %q", l.msgs, want)
+	}
+
+	f, err := os.Open(fmt.Sprintf(otel.DefaultMetricsFileDeprec, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var got []string
+	for s := bufio.NewScanner(f); s.Scan(); {
+		_, feature, _ := strings.Cut(s.Text(), ",")
+		got = append(got, feature)
+	}
+	want := []string{"slack.chat.postMessage:missing_scope", "slack.chat.postMessage:method_deprecated"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deprecation counter features = %q, want %q", got, want)
+	}
+}
+
+func TestAuditFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		want     []string
+	}{
+		{
+			name:     "chat_post_message",
+			endpoint: "/api/chat.postMessage",
+			want:     []string{"channel", "thread_ts", "reply_broadcast"},
+		},
+		{
+			name:     "shared_invite_approve",
+			endpoint: "/api/conversations.requestSharedInvite.approve",
+			want:     []string{"invite_id", "channel_id"},
+		},
+		{
+			name:     "default",
+			endpoint: "/api/conversations.create",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditFields(tt.endpoint); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
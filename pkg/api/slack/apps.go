@@ -0,0 +1,76 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+//revive:disable:exported
+const (
+	AppsEventAuthorizationsListActivityName = "slack.apps.event.authorizations.list"
+) //revive:enable:exported
+
+// AppsEventAuthorizationsListRequest is based on:
+// https://docs.slack.dev/reference/methods/apps.event.authorizations.list/
+//
+// The event context is an opaque string in Events API payloads, which the
+// Slack listeners also copy to the "timpani_event_context" key of signals.
+type AppsEventAuthorizationsListRequest struct {
+	EventContext string `json:"event_context"`
+
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// AppsEventAuthorizationsListResponse is based on:
+// https://docs.slack.dev/reference/methods/apps.event.authorizations.list/
+type AppsEventAuthorizationsListResponse struct {
+	slack.Response
+
+	Authorizations []EventAuthorization `json:"authorizations,omitempty"`
+}
+
+// EventAuthorization is an installation of the app
+// (in a workspace or an org) to which an event is visible.
+type EventAuthorization struct {
+	EnterpriseID        string `json:"enterprise_id,omitempty"`
+	TeamID              string `json:"team_id,omitempty"`
+	UserID              string `json:"user_id,omitempty"`
+	IsBot               bool   `json:"is_bot,omitempty"`
+	IsEnterpriseInstall bool   `json:"is_enterprise_install,omitempty"`
+}
+
+// AppsEventAuthorizationsListActivity is based on:
+// https://docs.slack.dev/reference/methods/apps.event.authorizations.list/
+//
+// This is the only activity which uses the app-level token of the Thrippy link (with the
+// "authorizations:read" scope) instead of its bot token. Events of org-wide apps may contain
+// only a truncated list of authorizations, so this is the way to get all of them. Pagination
+// is the caller's responsibility, based on the response's "next_cursor" metadata.
+func (a *API) AppsEventAuthorizationsListActivity(ctx context.Context, req AppsEventAuthorizationsListRequest) (*AppsEventAuthorizationsListResponse, error) {
+	resp := new(AppsEventAuthorizationsListResponse)
+	if err := a.httpGet(ctx, AppsEventAuthorizationsListActivityName, appsEventAuthorizationsListQuery(req), resp); err != nil {
+		return nil, err
+	}
+
+	if !resp.OK {
+		return nil, errors.New("Slack API error: " + resp.Error)
+	}
+	return resp, nil
+}
+
+func appsEventAuthorizationsListQuery(req AppsEventAuthorizationsListRequest) url.Values {
+	query := url.Values{}
+	query.Set("event_context", req.EventContext)
+	if req.Cursor != "" {
+		query.Set("cursor", req.Cursor)
+	}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	return query
+}
@@ -0,0 +1,81 @@
+package slack
+
+import (
+	"testing"
+)
+
+func TestLinkToken(t *testing.T) {
+	secrets := map[string]string{"app_token": "xapp", "bot_token": "xoxb"}
+
+	tests := []struct {
+		name      string
+		secrets   map[string]string
+		urlSuffix string
+		want      string
+		wantKind  string
+	}{
+		{
+			name:      "bot_token",
+			secrets:   secrets,
+			urlSuffix: "slack.chat.postMessage",
+			want:      "xoxb",
+			wantKind:  "bot token",
+		},
+		{
+			name:      "app_token",
+			secrets:   secrets,
+			urlSuffix: AppsEventAuthorizationsListActivityName,
+			want:      "xapp",
+			wantKind:  "app-level token",
+		},
+		{
+			name:      "missing_app_token",
+			secrets:   map[string]string{"bot_token": "xoxb"},
+			urlSuffix: AppsEventAuthorizationsListActivityName,
+			wantKind:  "app-level token",
+		},
+		{
+			name:      "access_token_fallback",
+			secrets:   map[string]string{"access_token": "xoxe", "app_token": "xapp"},
+			urlSuffix: "slack.auth.test",
+			want:      "xoxe",
+			wantKind:  "bot token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, kind := linkToken(tt.secrets, tt.urlSuffix)
+			if got != tt.want || kind != tt.wantKind {
+				t.Errorf("linkToken() = %q, %q, want %q, %q", got, kind, tt.want, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestAppsEventAuthorizationsListQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		req  AppsEventAuthorizationsListRequest
+		want string
+	}{
+		{
+			name: "first_page",
+			req:  AppsEventAuthorizationsListRequest{EventContext: "4-abc"},
+			want: "event_context=4-abc",
+		},
+		{
+			name: "next_page",
+			req:  AppsEventAuthorizationsListRequest{EventContext: "4-abc", Cursor: "dXNlcjpVMEc5V0ZYTlo=", Limit: 100},
+			want: "cursor=dXNlcjpVMEc5V0ZYTlo%3D&event_context=4-abc&limit=100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appsEventAuthorizationsListQuery(tt.req).Encode(); got != tt.want {
+				t.Errorf("appsEventAuthorizationsListQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
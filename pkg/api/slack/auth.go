@@ -3,12 +3,28 @@ package slack
 import (
 	"context"
 	"errors"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
 )
 
+// TimpaniSelfIdentityActivityName is a Timpani-specific activity, see [API.TimpaniSelfIdentityActivity].
+const TimpaniSelfIdentityActivityName = "slack.timpani.selfIdentity"
+
+// identityTTL is how long the result of auth.test is cached. The identity
+// of a bot doesn't change, but its token may be revoked or rotated.
+const identityTTL = 12 * time.Hour
+
+// authErrors indicate that the link's token was revoked or rotated:
+// https://docs.slack.dev/reference/methods/auth.test#errors.
+var authErrors = []string{"account_inactive", "invalid_auth", "not_authed", "token_revoked"}
+
 // AuthTestActivity is based on:
 // https://docs.slack.dev/reference/methods/auth.test/
+//
+// The result is also cached, see [API.TimpaniSelfIdentityActivity].
 func (a *API) AuthTestActivity(ctx context.Context) (*slack.AuthTestResponse, error) {
 	resp := new(slack.AuthTestResponse)
 	if err := a.httpPost(ctx, slack.AuthTestActivityName, nil, resp); err != nil {
@@ -18,5 +34,56 @@ func (a *API) AuthTestActivity(ctx context.Context) (*slack.AuthTestResponse, er
 	if !resp.OK {
 		return nil, errors.New("Slack API error: " + resp.Error)
 	}
+
+	a.identity.set(resp, time.Now())
 	return resp, nil
 }
+
+// TimpaniSelfIdentityActivity returns the identity of the link's bot (e.g. its user and
+// team IDs), which is the cached result of [API.AuthTestActivity], to enable workflows
+// to check it cheaply and repeatedly (e.g. to ignore their own messages). The cache
+// expires after [identityTTL], and is invalidated by authentication errors in any activity.
+func (a *API) TimpaniSelfIdentityActivity(ctx context.Context) (*slack.AuthTestResponse, error) {
+	if resp := a.identity.get(time.Now()); resp != nil {
+		return resp, nil
+	}
+	return a.AuthTestActivity(ctx)
+}
+
+// identityCache is a thread-safe cache of a single auth.test result.
+type identityCache struct {
+	mu      sync.Mutex
+	resp    *slack.AuthTestResponse
+	expires time.Time
+}
+
+func (c *identityCache) get(now time.Time) *slack.AuthTestResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resp == nil || now.After(c.expires) {
+		return nil
+	}
+	return c.resp
+}
+
+func (c *identityCache) set(resp *slack.AuthTestResponse, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resp = resp
+	c.expires = now.Add(identityTTL)
+}
+
+// invalidateOnAuthError clears the cache if the given Slack API error code
+// indicates that the link's token is no longer valid for the cached identity.
+func (c *identityCache) invalidateOnAuthError(code string) {
+	if !slices.Contains(authErrors, code) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resp = nil
+}
@@ -0,0 +1,35 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+func TestIdentityCache(t *testing.T) {
+	c := new(identityCache)
+	now := time.Now()
+	if got := c.get(now); got != nil {
+		t.Fatalf("empty identityCache.get() = %v, want nil", got)
+	}
+
+	resp := &slack.AuthTestResponse{UserID: "U1", TeamID: "T1"}
+	c.set(resp, now)
+	if got := c.get(now.Add(time.Hour)); got != resp {
+		t.Errorf("identityCache.get() = %v, want %v", got, resp)
+	}
+	if got := c.get(now.Add(identityTTL + time.Second)); got != nil {
+		t.Errorf("expired identityCache.get() = %v, want nil", got)
+	}
+
+	c.invalidateOnAuthError("channel_not_found")
+	if got := c.get(now); got != resp {
+		t.Errorf("identityCache.get() after unrelated error = %v, want %v", got, resp)
+	}
+
+	c.invalidateOnAuthError("token_revoked")
+	if got := c.get(now); got != nil {
+		t.Errorf("identityCache.get() after auth error = %v, want nil", got)
+	}
+}
@@ -0,0 +1,166 @@
+// Package blocks provides typed constructors of Slack [Block Kit] blocks and
+// elements, which marshal to the JSON that the Slack API expects, and check
+// Slack's length limits before API calls (instead of failing in them).
+//
+// [Block Kit]: https://docs.slack.dev/block-kit
+package blocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Length limits, based on https://docs.slack.dev/reference/block-kit/blocks.
+const (
+	MaxBlocks          = 50
+	MaxHeaderLength    = 150
+	MaxSectionLength   = 3000
+	MaxActionElements  = 25
+	MaxContextElements = 10
+)
+
+// Block is a visual component of a Slack message.
+type Block interface {
+	validate() error
+}
+
+// Build validates the given blocks, and converts them into the generic
+// type of the "blocks" field in Slack API requests (e.g. [slack.ChatPostMessageRequest]).
+//
+// [slack.ChatPostMessageRequest]: https://pkg.go.dev/github.com/tzrikka/timpani-api/pkg/slack#ChatPostMessageRequest
+func Build(bs ...Block) ([]map[string]any, error) {
+	if len(bs) > MaxBlocks {
+		return nil, fmt.Errorf("too many blocks: %d > %d", len(bs), MaxBlocks)
+	}
+
+	for i, b := range bs {
+		if err := b.validate(); err != nil {
+			return nil, fmt.Errorf("invalid block %d: %w", i, err)
+		}
+	}
+
+	j, err := json.Marshal(bs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode blocks: %w", err)
+	}
+
+	var ms []map[string]any
+	if err := json.Unmarshal(j, &ms); err != nil {
+		return nil, fmt.Errorf("failed to decode blocks: %w", err)
+	}
+	return ms, nil
+}
+
+// Text is a [text object], either plain text or markdown.
+//
+// [text object]: https://docs.slack.dev/reference/block-kit/composition-objects/text-object
+type Text struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// PlainText returns a plain-text [Text] object, with emojis enabled.
+func PlainText(s string) *Text {
+	return &Text{Type: "plain_text", Text: s, Emoji: true}
+}
+
+// Markdown returns a markdown [Text] object.
+func Markdown(s string) *Text {
+	return &Text{Type: "mrkdwn", Text: s}
+}
+
+// HeaderBlock is based on https://docs.slack.dev/reference/block-kit/blocks/header-block.
+type HeaderBlock struct {
+	Type string `json:"type"`
+	Text *Text  `json:"text"`
+}
+
+// Header returns a [HeaderBlock] with the given plain text.
+func Header(text string) *HeaderBlock {
+	return &HeaderBlock{Type: "header", Text: PlainText(text)}
+}
+
+func (b *HeaderBlock) validate() error {
+	return checkLength("header text", b.Text.Text, MaxHeaderLength)
+}
+
+// SectionBlock is based on https://docs.slack.dev/reference/block-kit/blocks/section-block.
+type SectionBlock struct {
+	Type string `json:"type"`
+	Text *Text  `json:"text"`
+}
+
+// Section returns a [SectionBlock] with the given markdown text.
+func Section(markdown string) *SectionBlock {
+	return &SectionBlock{Type: "section", Text: Markdown(markdown)}
+}
+
+func (b *SectionBlock) validate() error {
+	return checkLength("section text", b.Text.Text, MaxSectionLength)
+}
+
+// DividerBlock is based on https://docs.slack.dev/reference/block-kit/blocks/divider-block.
+type DividerBlock struct {
+	Type string `json:"type"`
+}
+
+// Divider returns a [DividerBlock].
+func Divider() *DividerBlock {
+	return &DividerBlock{Type: "divider"}
+}
+
+func (b *DividerBlock) validate() error {
+	return nil
+}
+
+// ActionsBlock is based on https://docs.slack.dev/reference/block-kit/blocks/actions-block.
+type ActionsBlock struct {
+	Type     string    `json:"type"`
+	Elements []Element `json:"elements"`
+}
+
+// Actions returns an [ActionsBlock] with the given interactive elements.
+func Actions(es ...Element) *ActionsBlock {
+	return &ActionsBlock{Type: "actions", Elements: es}
+}
+
+func (b *ActionsBlock) validate() error {
+	if len(b.Elements) == 0 || len(b.Elements) > MaxActionElements {
+		return fmt.Errorf("actions block must contain 1-%d elements, not %d", MaxActionElements, len(b.Elements))
+	}
+
+	for i, e := range b.Elements {
+		if err := e.validate(); err != nil {
+			return fmt.Errorf("invalid element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ContextBlock is based on https://docs.slack.dev/reference/block-kit/blocks/context-block.
+type ContextBlock struct {
+	Type     string  `json:"type"`
+	Elements []*Text `json:"elements"`
+}
+
+// Context returns a [ContextBlock] with the given text elements.
+func Context(ts ...*Text) *ContextBlock {
+	return &ContextBlock{Type: "context", Elements: ts}
+}
+
+func (b *ContextBlock) validate() error {
+	if len(b.Elements) == 0 || len(b.Elements) > MaxContextElements {
+		return fmt.Errorf("context block must contain 1-%d elements, not %d", MaxContextElements, len(b.Elements))
+	}
+	return nil
+}
+
+// checkLength checks the length of the given string in characters, not bytes.
+func checkLength(name, s string, maxLength int) error {
+	if n := utf8.RuneCountInString(s); n > maxLength {
+		return fmt.Errorf("%s is too long: %d > %d characters", name, n, maxLength)
+	}
+	return nil
+}
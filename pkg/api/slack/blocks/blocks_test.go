@@ -0,0 +1,113 @@
+package blocks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	got, err := Build(
+		Header("Deployment"),
+		Section("Deploy *v1.2.3* to production?"),
+		Divider(),
+		Actions(
+			Button("details", "Details", ""),
+			Select("region", "Region", SelectOption("US", "us"), SelectOption("EU", "eu")),
+		),
+		Context(Markdown("Requested by <@U123>"), PlainText("2 minutes ago")),
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	compareGolden(t, got, filepath.Join("testdata", "all_blocks.json"))
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocks  []Block
+		wantErr string
+	}{
+		{
+			name:    "long_header",
+			blocks:  []Block{Header(strings.Repeat("a", MaxHeaderLength+1))},
+			wantErr: "invalid block 0: header text is too long: 151 > 150 characters",
+		},
+		{
+			name:    "long_section",
+			blocks:  []Block{Divider(), Section(strings.Repeat("a", MaxSectionLength+1))},
+			wantErr: "invalid block 1: section text is too long: 3001 > 3000 characters",
+		},
+		{
+			name:    "long_button_label",
+			blocks:  []Block{Actions(Button("id", strings.Repeat("界", MaxButtonLabelLength+1), ""))}, //nolint:gosmopolitan // Test string.
+			wantErr: "invalid block 0: invalid element 0: button label is too long: 76 > 75 characters",
+		},
+		{
+			name:    "missing_action_id",
+			blocks:  []Block{Actions(Button("", "label", ""))},
+			wantErr: "invalid block 0: invalid element 0: missing action ID",
+		},
+		{
+			name:    "empty_actions",
+			blocks:  []Block{Actions()},
+			wantErr: "invalid block 0: actions block must contain 1-25 elements, not 0",
+		},
+		{
+			name:    "select_without_options",
+			blocks:  []Block{Actions(Select("id", ""))},
+			wantErr: "invalid block 0: invalid element 0: select must contain 1-100 options, not 0",
+		},
+		{
+			name:    "empty_context",
+			blocks:  []Block{Context()},
+			wantErr: "invalid block 0: context block must contain 1-10 elements, not 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Build(tt.blocks...)
+			if err == nil {
+				t.Fatal("Build() error = nil")
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("Build() error = %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// compareGolden checks that the JSON encoding of the given value is
+// equivalent to the content of the given file (ignoring whitespace
+// and the order of object keys).
+func compareGolden(t *testing.T, got any, path string) {
+	t.Helper()
+
+	want, err := os.ReadFile(path) //gosec:disable G304 // Test data.
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g, w any
+	if err := json.Unmarshal(j, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("JSON = %s, want golden file %s:\n%s", j, path, want)
+	}
+}
@@ -0,0 +1,108 @@
+package blocks
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Length limits, based on https://docs.slack.dev/reference/block-kit/block-elements.
+const (
+	MaxActionIDLength    = 255
+	MaxButtonLabelLength = 75
+	MaxButtonValueLength = 2000
+	MaxOptionLabelLength = 75
+	MaxOptionValueLength = 150
+	MaxPlaceholderLength = 150
+	MaxSelectOptions     = 100
+)
+
+// Element is an interactive component in an [ActionsBlock].
+type Element interface {
+	validate() error
+}
+
+// ButtonElement is based on https://docs.slack.dev/reference/block-kit/block-elements/button-element.
+type ButtonElement struct {
+	Type     string `json:"type"`
+	Text     *Text  `json:"text"`
+	ActionID string `json:"action_id"`
+	Value    string `json:"value,omitempty"`
+	Style    string `json:"style,omitempty"`
+}
+
+// Button returns a [ButtonElement] with the default style.
+func Button(actionID, label, value string) *ButtonElement {
+	return &ButtonElement{Type: "button", Text: PlainText(label), ActionID: actionID, Value: value}
+}
+
+// Primary sets the button's style to green.
+func (e *ButtonElement) Primary() *ButtonElement {
+	e.Style = "primary"
+	return e
+}
+
+// Danger sets the button's style to red.
+func (e *ButtonElement) Danger() *ButtonElement {
+	e.Style = "danger"
+	return e
+}
+
+func (e *ButtonElement) validate() error {
+	return errors.Join(
+		checkActionID(e.ActionID),
+		checkLength("button label", e.Text.Text, MaxButtonLabelLength),
+		checkLength("button value", e.Value, MaxButtonValueLength),
+	)
+}
+
+// SelectElement is based on https://docs.slack.dev/reference/block-kit/block-elements/select-menu-element#static_select.
+type SelectElement struct {
+	Type        string    `json:"type"`
+	ActionID    string    `json:"action_id"`
+	Placeholder *Text     `json:"placeholder,omitempty"`
+	Options     []*Option `json:"options"`
+}
+
+// Select returns a [SelectElement] with a static list of options.
+func Select(actionID, placeholder string, opts ...*Option) *SelectElement {
+	e := &SelectElement{Type: "static_select", ActionID: actionID, Options: opts}
+	if placeholder != "" {
+		e.Placeholder = PlainText(placeholder)
+	}
+	return e
+}
+
+func (e *SelectElement) validate() error {
+	errs := []error{checkActionID(e.ActionID)}
+	if e.Placeholder != nil {
+		errs = append(errs, checkLength("select placeholder", e.Placeholder.Text, MaxPlaceholderLength))
+	}
+
+	if len(e.Options) == 0 || len(e.Options) > MaxSelectOptions {
+		errs = append(errs, fmt.Errorf("select must contain 1-%d options, not %d", MaxSelectOptions, len(e.Options)))
+	}
+	for _, o := range e.Options {
+		errs = append(errs, checkLength("option label", o.Text.Text, MaxOptionLabelLength))
+		errs = append(errs, checkLength("option value", o.Value, MaxOptionValueLength))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Option is based on https://docs.slack.dev/reference/block-kit/composition-objects/option-object.
+type Option struct {
+	Text  *Text  `json:"text"`
+	Value string `json:"value"`
+}
+
+// SelectOption returns an [Option] for a [SelectElement].
+func SelectOption(label, value string) *Option {
+	return &Option{Text: PlainText(label), Value: value}
+}
+
+func checkActionID(id string) error {
+	if id == "" {
+		return errors.New("missing action ID")
+	}
+	return checkLength("action ID", id, MaxActionIDLength)
+}
@@ -17,7 +17,10 @@ import (
 	"go.temporal.io/sdk/workflow"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/idempotency"
 	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+	"github.com/tzrikka/timpani/pkg/api/slack/blocks"
 )
 
 const (
@@ -95,9 +98,33 @@ func (a *API) ChatPostEphemeralActivity(ctx context.Context, req slack.ChatPostE
 	return resp, nil
 }
 
+// ChatPostMessageRequest extends [slack.ChatPostMessageRequest] with Timpani-specific
+// options, which are not sent to Slack. Its JSON encoding is compatible with the original.
+type ChatPostMessageRequest struct {
+	slack.ChatPostMessageRequest
+
+	// ConvertMarkdown converts the Text field from GitHub-flavored
+	// markdown to Slack's mrkdwn format (see [ConvertMarkdown]).
+	ConvertMarkdown bool `json:"convert_markdown,omitempty"`
+
+	// IdempotencyKey prevents retries of this activity from posting duplicate
+	// messages, if a previous attempt already succeeded (best-effort, see the
+	// caveats in [idempotency]).
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
 // ChatPostMessageActivity is based on:
 // https://docs.slack.dev/reference/methods/chat.postMessage/
-func (a *API) ChatPostMessageActivity(ctx context.Context, req slack.ChatPostMessageRequest) (*slack.ChatPostMessageResponse, error) {
+func (a *API) ChatPostMessageActivity(ctx context.Context, req ChatPostMessageRequest) (*slack.ChatPostMessageResponse, error) {
+	return idempotency.Do(ctx, slack.ChatPostMessageActivityName, req.IdempotencyKey, func() (*slack.ChatPostMessageResponse, error) {
+		return a.chatPostMessage(ctx, req)
+	})
+}
+
+func (a *API) chatPostMessage(ctx context.Context, req ChatPostMessageRequest) (*slack.ChatPostMessageResponse, error) {
+	if req.ConvertMarkdown {
+		req.Text = ConvertMarkdown(req.Text)
+	}
 	if l := len(req.MarkdownText); l > MarkdownTextMaxLength {
 		activity.GetLogger(ctx).Warn("truncating Slack message markdown",
 			slog.Int("original_length", l), slog.Int("new_length", MarkdownTextMaxLength))
@@ -110,7 +137,7 @@ func (a *API) ChatPostMessageActivity(ctx context.Context, req slack.ChatPostMes
 	}
 
 	resp := new(slack.ChatPostMessageResponse)
-	if err := a.httpPost(ctx, slack.ChatPostMessageActivityName, req, resp); err != nil {
+	if err := a.httpPost(ctx, slack.ChatPostMessageActivityName, req.ChatPostMessageRequest, resp); err != nil {
 		return nil, err
 	}
 
@@ -120,7 +147,7 @@ func (a *API) ChatPostMessageActivity(ctx context.Context, req slack.ChatPostMes
 	case "msg_too_long":
 		req.Text = strconv.Itoa(len(req.Text))
 		req.MarkdownText = strconv.Itoa(len(req.MarkdownText))
-		return nil, temporal.NewNonRetryableApplicationError(resp.Error, "SlackAPIError", nil, req)
+		return nil, temporal.NewNonRetryableApplicationError(resp.Error, "SlackAPIError", nil, req.ChatPostMessageRequest)
 	}
 	if !resp.OK {
 		return nil, errors.New("Slack API error: " + resp.Error)
@@ -175,30 +202,56 @@ func truncate(s string, maxLength int) string {
 	return strings.TrimSpace(string(r)) + " (truncated)"
 }
 
+// TimpaniPostApprovalRequest extends [slack.TimpaniPostApprovalRequest] with Timpani-specific
+// options. Its JSON encoding is compatible with the original.
+type TimpaniPostApprovalRequest struct {
+	slack.TimpaniPostApprovalRequest
+
+	// ConvertMarkdown converts the Message field from GitHub-flavored
+	// markdown to Slack's mrkdwn format (see [ConvertMarkdown]).
+	ConvertMarkdown bool `json:"convert_markdown,omitempty"`
+
+	// AuditChannel is an optional channel ID, to which the workflow posts a
+	// summary of the approval decision: who clicked which button, and when.
+	AuditChannel string `json:"audit_channel,omitempty"`
+}
+
 // TimpaniPostApprovalWorkflow is a convenience wrapper over
 // [ChatPostMessageActivity]. It sends an interactive message to a
 // user/group/channel with a short header, a markdown message, and
 // 2 buttons. It then waits for (and returns) the user selection.
+// If the request specifies an audit channel, it also posts a summary
+// of the decision there, but failing to do so doesn't fail the workflow.
 //
 // For message formatting tips, see
 // https://docs.slack.dev/messaging/formatting-message-text.
-func (a *API) TimpaniPostApprovalWorkflow(ctx workflow.Context, req slack.TimpaniPostApprovalRequest) (*slack.TimpaniPostApprovalResponse, error) {
+func (a *API) TimpaniPostApprovalWorkflow(ctx workflow.Context, req TimpaniPostApprovalRequest) (*slack.TimpaniPostApprovalResponse, error) {
+	if req.ConvertMarkdown {
+		req.Message = ConvertMarkdown(req.Message)
+	}
+
 	info := workflow.GetInfo(ctx)
 	id := base64.RawURLEncoding.EncodeToString([]byte(info.WorkflowExecution.ID))
+	bs, err := approvalBlocks(req.TimpaniPostApprovalRequest, id)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid approval message: "+err.Error(), "SlackBlocksError", err)
+	}
+
 	txCallCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		TaskQueue:           info.TaskQueueName,
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
 		StartToCloseTimeout: 5 * time.Second,
 		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
 	})
 	txCallFut := workflow.ExecuteActivity(txCallCtx, slack.ChatPostMessageActivityName, slack.ChatPostMessageRequest{
 		Channel:        req.Channel,
-		Blocks:         approvalBlocks(req, id),
+		Blocks:         bs,
 		ThreadTS:       req.ThreadTS,
 		ReplyBroadcast: req.ReplyBroadcast,
 		Metadata:       req.Metadata,
 	})
 
-	if err := txCallFut.Get(ctx, nil); err != nil {
+	msg := new(slack.ChatPostMessageResponse)
+	if err := txCallFut.Get(ctx, msg); err != nil {
 		return nil, fmt.Errorf("failed to post chat message: %w", err)
 	}
 
@@ -216,16 +269,80 @@ func (a *API) TimpaniPostApprovalWorkflow(ctx workflow.Context, req slack.Timpan
 		return nil, fmt.Errorf("failed to wait for events: %w", err)
 	}
 
+	if req.AuditChannel != "" {
+		a.postApprovalAudit(ctx, req, msg, payload)
+	}
+
 	return &slack.TimpaniPostApprovalResponse{InteractionEvent: payload}, nil
 }
 
+// ApprovalAuditFailuresMetric counts failures to post approval decisions to audit channels.
+const ApprovalAuditFailuresMetric = "timpani_slack_approval_audit_failures"
+
+// postApprovalAudit mirrors an approval decision to the request's audit channel. This
+// is best-effort: failures are logged and counted, without failing the caller.
+func (a *API) postApprovalAudit(ctx workflow.Context, req TimpaniPostApprovalRequest, msg *slack.ChatPostMessageResponse, payload map[string]any) {
+	info := workflow.GetInfo(ctx)
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 5 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+	})
+	l := workflow.GetLogger(ctx)
+
+	link := new(slack.ChatGetPermalinkResponse)
+	linkReq := slack.ChatGetPermalinkRequest{Channel: msg.Channel, MessageTS: msg.TS}
+	if err := workflow.ExecuteActivity(ctx, slack.ChatGetPermalinkActivityName, linkReq).Get(ctx, link); err != nil {
+		l.Warn("failed to get permalink of Slack approval message", slog.Any("error", err))
+	}
+
+	event, err := Decode[BlockActionsPayload](payload)
+	if err != nil {
+		l.Warn("unexpected Slack approval interaction event", slog.Any("error", err))
+	}
+
+	postReq := ChatPostMessageRequest{IdempotencyKey: info.WorkflowExecution.ID + "/audit"}
+	postReq.Channel = req.AuditChannel
+	postReq.Text = approvalAuditText(req.Header, link.Permalink, info.WorkflowExecution.ID, event)
+
+	if err := workflow.ExecuteActivity(ctx, slack.ChatPostMessageActivityName, postReq).Get(ctx, nil); err != nil {
+		l.Error("failed to post Slack approval audit message", slog.Any("error", err),
+			slog.String("audit_channel", req.AuditChannel))
+		workflow.GetMetricsHandler(ctx).Counter(ApprovalAuditFailuresMetric).Inc(1)
+	}
+}
+
+// approvalAuditText summarizes a "block_actions" interaction event in Slack's mrkdwn format.
+func approvalAuditText(header, permalink, workflowID string, event BlockActionsPayload) string {
+	var action BlockAction
+	if len(event.Actions) > 0 {
+		action = event.Actions[0]
+	}
+	label := ""
+	if action.Text != nil {
+		label = action.Text.Text
+	}
+
+	ts := ""
+	if secs, err := strconv.ParseFloat(action.ActionTS, 64); err == nil {
+		ts = fmt.Sprintf(" <!date^%d^{date_short_pretty} at {time_secs}|%s>", int64(secs), action.ActionTS)
+	}
+
+	if permalink != "" {
+		header = fmt.Sprintf("<%s|%s>", permalink, header)
+	}
+
+	return fmt.Sprintf("*Approval decision:* <@%s> clicked *%s* (`%s`)%s\n*Request:* %s\n*Workflow ID:* `%s`",
+		event.User.ID, label, action.Value, ts, header, workflowID)
+}
+
 const (
 	DefaultGreenButton = "Approve"
 	DefaultRedButton   = "Deny"
 )
 
 // approvalBlocks is based on https://docs.slack.dev/block-kit.
-func approvalBlocks(req slack.TimpaniPostApprovalRequest, id string) []map[string]any {
+func approvalBlocks(req slack.TimpaniPostApprovalRequest, id string) ([]map[string]any, error) {
 	greenButton := req.GreenButton
 	if greenButton == "" {
 		greenButton = DefaultGreenButton
@@ -236,54 +353,14 @@ func approvalBlocks(req slack.TimpaniPostApprovalRequest, id string) []map[strin
 		redButton = DefaultRedButton
 	}
 
-	return []map[string]any{
-		{
-			"type": "header",
-			"text": map[string]any{
-				"type":  "plain_text",
-				"text":  req.Header,
-				"emoji": true,
-			},
-		},
-		{
-			"type": "divider",
-		},
-		{
-			"type": "section",
-			"text": map[string]string{
-				"type": "mrkdwn",
-				"text": req.Message,
-			},
-		},
-		{
-			"type": "divider",
-		},
-		{
-			"type": "actions",
-			"elements": []map[string]any{
-				{
-					"type":  "button",
-					"style": "primary",
-					"text": map[string]any{
-						"type":  "plain_text",
-						"text":  greenButton,
-						"emoji": true,
-					},
-					"value":     "approve",
-					"action_id": "id1_" + id,
-				},
-				{
-					"type":  "button",
-					"style": "danger",
-					"text": map[string]any{
-						"type":  "plain_text",
-						"text":  redButton,
-						"emoji": true,
-					},
-					"value":     "deny",
-					"action_id": "id2_" + id,
-				},
-			},
-		},
-	}
+	return blocks.Build(
+		blocks.Header(req.Header),
+		blocks.Divider(),
+		blocks.Section(req.Message),
+		blocks.Divider(),
+		blocks.Actions(
+			blocks.Button("id1_"+id, greenButton, "approve").Primary(),
+			blocks.Button("id2_"+id, redButton, "deny").Danger(),
+		),
+	)
 }
@@ -1,9 +1,21 @@
 package slack
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/listeners"
 )
 
 func TestTruncate(t *testing.T) {
@@ -37,29 +49,112 @@ func TestTruncate(t *testing.T) {
 }
 
 func TestApprovalBlocks(t *testing.T) {
-	blocks := approvalBlocks(slack.TimpaniPostApprovalRequest{RedButton: "red"}, "id")
+	got, err := approvalBlocks(slack.TimpaniPostApprovalRequest{Header: "Header", Message: "*Message*", RedButton: "red"}, "id")
+	if err != nil {
+		t.Fatalf("approvalBlocks() error = %v", err)
+	}
 
-	elems, ok := blocks[4]["elements"]
-	if !ok {
-		t.Fatal(`approvalBlocks() last block doesn't contain "elements" key`)
+	want, err := os.ReadFile(filepath.Join("testdata", "approval_blocks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w []map[string]any
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatal(err)
 	}
 
-	es, ok := elems.([]map[string]any)
-	if !ok {
-		t.Fatalf("approvalBlocks() last block's elements type: got %T, want []map[string]any", elems)
+	if !reflect.DeepEqual(got, w) {
+		t.Errorf("approvalBlocks() = %v, want golden file:\n%s", got, want)
 	}
 
-	want := DefaultGreenButton
-	if got := es[0]["text"].(map[string]any)["text"]; got != want { //nolint:errcheck // Type conversion always succeeds.
-		t.Errorf("approvalBlocks() green button label = %q, want %q", got, want)
+	long := slack.TimpaniPostApprovalRequest{GreenButton: strings.Repeat("a", 76)}
+	if _, err := approvalBlocks(long, "id"); err == nil {
+		t.Error("approvalBlocks() with a long button label: error = nil")
 	}
+}
 
-	want = "red"
-	if got := es[1]["text"].(map[string]any)["text"]; got != want { //nolint:errcheck // Type conversion always succeeds.
-		t.Errorf("approvalBlocks() red button label = %q, want %q", got, want)
+func TestTimpaniPostApprovalWorkflowAudit(t *testing.T) {
+	tests := []struct {
+		name         string
+		auditChannel string
+		linkErr      error
+		auditErr     error
+		wantPosts    int
+		wantText     []string
+	}{
+		{
+			name:      "without_audit_channel",
+			wantPosts: 1,
+		},
+		{
+			name:         "with_audit_channel",
+			auditChannel: "C2",
+			wantPosts:    2,
+			wantText: []string{
+				"<@U1> clicked *Approve* (`approve`)", "<!date^1700000000^", "<https://slack.com/archives/C1/p1|Header>",
+				"`default-test-workflow-id`",
+			},
+		},
+		{
+			name:         "permalink_error",
+			auditChannel: "C2",
+			linkErr:      errors.New("permalink error"),
+			wantPosts:    2,
+			wantText:     []string{"*Request:* Header\n"},
+		},
+		{
+			name:         "audit_post_error",
+			auditChannel: "C2",
+			auditErr:     errors.New("post error"),
+			wantPosts:    4, // 1 approval message + 3 audit attempts.
+		},
 	}
 
-	if es[0]["action_id"] == es[1]["action_id"] {
-		t.Error("approvalBlocks() button action IDs must be unique")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts []ChatPostMessageRequest
+			post := func(_ context.Context, req ChatPostMessageRequest) (*slack.ChatPostMessageResponse, error) {
+				posts = append(posts, req)
+				if req.Channel == tt.auditChannel && tt.auditErr != nil {
+					return nil, tt.auditErr
+				}
+				return &slack.ChatPostMessageResponse{Channel: req.Channel, TS: "1"}, nil
+			}
+			link := func(_ context.Context, req slack.ChatGetPermalinkRequest) (*slack.ChatGetPermalinkResponse, error) {
+				if tt.linkErr != nil {
+					return nil, tt.linkErr
+				}
+				return &slack.ChatGetPermalinkResponse{Permalink: "https://slack.com/archives/" + req.Channel + "/p" + req.MessageTS}, nil
+			}
+			wait := func(_ workflow.Context, _ listeners.WaitForEventRequest) (map[string]any, error) {
+				return map[string]any{
+					"user":    map[string]any{"id": "U1"},
+					"actions": []any{map[string]any{"value": "approve", "text": map[string]any{"text": "Approve"}, "action_ts": "1700000000.123"}},
+				}, nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(post, activity.RegisterOptions{Name: slack.ChatPostMessageActivityName})
+			env.RegisterActivityWithOptions(link, activity.RegisterOptions{Name: slack.ChatGetPermalinkActivityName})
+			env.RegisterWorkflowWithOptions(wait, workflow.RegisterOptions{Name: listeners.WaitForEventWorkflow})
+			env.RegisterWorkflow(a.TimpaniPostApprovalWorkflow)
+
+			req := TimpaniPostApprovalRequest{AuditChannel: tt.auditChannel}
+			req.Channel, req.Header, req.Message = "C1", "Header", "Message"
+			env.ExecuteWorkflow(a.TimpaniPostApprovalWorkflow, req)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("TimpaniPostApprovalWorkflow() error = %v", err)
+			}
+			if len(posts) != tt.wantPosts {
+				t.Fatalf("ChatPostMessageActivity calls = %d, want %d", len(posts), tt.wantPosts)
+			}
+			for _, want := range tt.wantText {
+				if got := posts[1].Text; !strings.Contains(got, want) {
+					t.Errorf("audit message text = %q, want it to contain %q", got, want)
+				}
+			}
+		})
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"go.temporal.io/sdk/temporal"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/pkg/http/client"
 )
 
 // ConversationsArchiveActivity is based on:
@@ -59,9 +60,35 @@ func (a *API) ConversationsCreateActivity(ctx context.Context, req slack.Convers
 	return resp, nil
 }
 
+// ResponseSizeOptions are Timpani-specific options of list activities.
+type ResponseSizeOptions struct {
+	// TruncateLargeResponse removes items from the end of the response's list, instead of
+	// failing the activity, if the response exceeds [client.MaxResponseSize]. Either way,
+	// callers should use small enough page sizes to avoid this.
+	TruncateLargeResponse bool `json:"truncate_large_response,omitempty"`
+}
+
+// ConversationsHistoryRequest extends [slack.ConversationsHistoryRequest] with
+// Timpani-specific options. Its JSON encoding is compatible with the original.
+type ConversationsHistoryRequest struct {
+	slack.ConversationsHistoryRequest
+	ResponseSizeOptions
+}
+
+// ConversationsHistoryResponse extends [slack.ConversationsHistoryResponse]
+// with an indication whether [client.LimitResponseSize] removed messages.
+type ConversationsHistoryResponse struct {
+	slack.ConversationsHistoryResponse
+
+	Truncated bool `json:"truncated,omitempty"`
+}
+
 // ConversationsHistoryActivity is based on:
 // https://docs.slack.dev/reference/methods/conversations.history/
-func (a *API) ConversationsHistoryActivity(ctx context.Context, req slack.ConversationsHistoryRequest) (*slack.ConversationsHistoryResponse, error) {
+//
+// Messages are returned newest first, so if the response is truncated, the "latest"
+// argument of the next request should be the timestamp of the last returned message.
+func (a *API) ConversationsHistoryActivity(ctx context.Context, req ConversationsHistoryRequest) (*ConversationsHistoryResponse, error) {
 	query := url.Values{}
 	query.Set("channel", req.Channel)
 	if req.IncludeAllMetadata {
@@ -83,7 +110,7 @@ func (a *API) ConversationsHistoryActivity(ctx context.Context, req slack.Conver
 		query.Set("cursor", req.Cursor)
 	}
 
-	resp := new(slack.ConversationsHistoryResponse)
+	resp := new(ConversationsHistoryResponse)
 	if err := a.httpGet(ctx, slack.ConversationsHistoryActivityName, query, resp); err != nil {
 		return nil, err
 	}
@@ -91,6 +118,12 @@ func (a *API) ConversationsHistoryActivity(ctx context.Context, req slack.Conver
 	if !resp.OK {
 		return nil, errors.New("Slack API error: " + resp.Error)
 	}
+
+	var err error
+	resp.Truncated, err = client.LimitResponseSize(slack.ConversationsHistoryActivityName, resp, &resp.Messages, req.TruncateLargeResponse)
+	if err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -208,6 +241,11 @@ func (a *API) ConversationsListActivity(ctx context.Context, req slack.Conversat
 	if !resp.OK {
 		return nil, errors.New("Slack API error: " + resp.Error)
 	}
+
+	// Truncation isn't supported, because the cursor of the next page would skip the removed channels.
+	if _, err := client.LimitResponseSize(slack.ConversationsListActivityName, resp, &resp.Channels, false); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -262,9 +300,27 @@ func (a *API) ConversationsRenameActivity(ctx context.Context, req slack.Convers
 	return resp, nil
 }
 
+// ConversationsRepliesRequest extends [slack.ConversationsRepliesRequest] with
+// Timpani-specific options. Its JSON encoding is compatible with the original.
+type ConversationsRepliesRequest struct {
+	slack.ConversationsRepliesRequest
+	ResponseSizeOptions
+}
+
+// ConversationsRepliesResponse extends [slack.ConversationsRepliesResponse]
+// with an indication whether [client.LimitResponseSize] removed messages.
+type ConversationsRepliesResponse struct {
+	slack.ConversationsRepliesResponse
+
+	Truncated bool `json:"truncated,omitempty"`
+}
+
 // ConversationsRepliesActivity is based on:
 // https://docs.slack.dev/reference/methods/conversations.replies/
-func (a *API) ConversationsRepliesActivity(ctx context.Context, req slack.ConversationsRepliesRequest) (*slack.ConversationsRepliesResponse, error) {
+//
+// Messages are returned oldest first, so if the response is truncated, the "oldest"
+// argument of the next request should be the timestamp of the last returned message.
+func (a *API) ConversationsRepliesActivity(ctx context.Context, req ConversationsRepliesRequest) (*ConversationsRepliesResponse, error) {
 	query := url.Values{}
 	query.Set("channel", req.Channel)
 	query.Set("ts", req.TS)
@@ -287,7 +343,7 @@ func (a *API) ConversationsRepliesActivity(ctx context.Context, req slack.Conver
 		query.Set("cursor", req.Cursor)
 	}
 
-	resp := new(slack.ConversationsRepliesResponse)
+	resp := new(ConversationsRepliesResponse)
 	if err := a.httpGet(ctx, slack.ConversationsRepliesActivityName, query, resp); err != nil {
 		return nil, err
 	}
@@ -295,6 +351,12 @@ func (a *API) ConversationsRepliesActivity(ctx context.Context, req slack.Conver
 	if !resp.OK {
 		return nil, errors.New("Slack API error: " + resp.Error)
 	}
+
+	var err error
+	resp.Truncated, err = client.LimitResponseSize(slack.ConversationsRepliesActivityName, resp, &resp.Messages, req.TruncateLargeResponse)
+	if err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -0,0 +1,296 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Event is the set of Slack event and interaction payloads which [Decode] supports.
+type Event interface {
+	MessageEvent | ReactionAddedEvent | AppMentionEvent | BlockActionsPayload | ViewSubmissionPayload
+}
+
+// decodable is implemented by pointers to all the types in [Event].
+type decodable interface {
+	eventType() string
+	missingFields() []string
+}
+
+// MissingFieldsError is returned by [Decode] when a Slack
+// payload doesn't contain all the required fields of its type.
+type MissingFieldsError struct {
+	Type   string
+	Fields []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("Slack %q payload is missing required fields: %s", e.Type, strings.Join(e.Fields, ", "))
+}
+
+// Decode converts an untyped Slack event or interaction payload, e.g. one that was received
+// by [listeners.WaitForEventWorkflow], into a typed struct. Events API envelopes are unwrapped
+// automatically, unknown fields are ignored, and Timpani's annotations are preserved in the
+// original map only.
+//
+// If the payload is missing required fields, this function returns a [MissingFieldsError]
+// along with the partially-decoded struct, so callers may still use it on a best-effort basis.
+func Decode[T Event](payload map[string]any) (T, error) {
+	var t T
+	d := any(&t).(decodable)
+
+	// https://docs.slack.dev/apis/events-api#events-JSON
+	if payload["type"] == "event_callback" {
+		if event, ok := payload["event"].(map[string]any); ok {
+			payload = event
+		}
+	}
+
+	typ, _ := payload["type"].(string)
+	if typ != "" && typ != d.eventType() {
+		return t, fmt.Errorf("unexpected Slack payload type %q, want %q", typ, d.eventType())
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return t, fmt.Errorf("failed to encode Slack %q payload: %w", d.eventType(), err)
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return t, fmt.Errorf("failed to decode Slack %q payload: %w", d.eventType(), err)
+	}
+
+	if missing := d.missingFields(); len(missing) > 0 {
+		return t, &MissingFieldsError{Type: d.eventType(), Fields: missing}
+	}
+	return t, nil
+}
+
+// required appends the given field name to the list of missing fields, if its value is empty.
+func required(missing []string, name, value string) []string {
+	if value == "" {
+		return append(missing, name)
+	}
+	return missing
+}
+
+// MessageEvent is based on:
+//   - https://docs.slack.dev/reference/events/message
+//   - https://docs.slack.dev/reference/events/message.channels
+type MessageEvent struct {
+	Type        string           `json:"type"`
+	Subtype     string           `json:"subtype,omitempty"`
+	Channel     string           `json:"channel"`
+	ChannelType string           `json:"channel_type,omitempty"`
+	User        string           `json:"user,omitempty"`
+	BotID       string           `json:"bot_id,omitempty"`
+	Team        string           `json:"team,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	Blocks      []map[string]any `json:"blocks,omitempty"`
+	TS          string           `json:"ts"`
+	ThreadTS    string           `json:"thread_ts,omitempty"`
+	EventTS     string           `json:"event_ts,omitempty"`
+	Edited      *MessageEdited   `json:"edited,omitempty"`
+}
+
+// MessageEdited is based on:
+// https://docs.slack.dev/reference/events/message/message_changed
+type MessageEdited struct {
+	User string `json:"user"`
+	TS   string `json:"ts"`
+}
+
+func (e *MessageEvent) eventType() string {
+	return "message"
+}
+
+func (e *MessageEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "type", e.Type)
+	missing = required(missing, "channel", e.Channel)
+	return required(missing, "ts", e.TS)
+}
+
+// ReactionAddedEvent is based on:
+// https://docs.slack.dev/reference/events/reaction_added
+type ReactionAddedEvent struct {
+	Type     string       `json:"type"`
+	User     string       `json:"user"`
+	Reaction string       `json:"reaction"`
+	ItemUser string       `json:"item_user,omitempty"`
+	Item     ReactionItem `json:"item"`
+	EventTS  string       `json:"event_ts,omitempty"`
+}
+
+// ReactionItem is based on:
+// https://docs.slack.dev/reference/events/reaction_added
+type ReactionItem struct {
+	Type        string `json:"type"` // "message", "file", or "file_comment".
+	Channel     string `json:"channel,omitempty"`
+	TS          string `json:"ts,omitempty"`
+	File        string `json:"file,omitempty"`
+	FileComment string `json:"file_comment,omitempty"`
+}
+
+func (e *ReactionAddedEvent) eventType() string {
+	return "reaction_added"
+}
+
+func (e *ReactionAddedEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "type", e.Type)
+	missing = required(missing, "user", e.User)
+	missing = required(missing, "reaction", e.Reaction)
+	return required(missing, "item.type", e.Item.Type)
+}
+
+// AppMentionEvent is based on:
+// https://docs.slack.dev/reference/events/app_mention
+type AppMentionEvent struct {
+	Type     string           `json:"type"`
+	User     string           `json:"user"`
+	Team     string           `json:"team,omitempty"`
+	Channel  string           `json:"channel"`
+	Text     string           `json:"text"`
+	Blocks   []map[string]any `json:"blocks,omitempty"`
+	TS       string           `json:"ts"`
+	ThreadTS string           `json:"thread_ts,omitempty"`
+	EventTS  string           `json:"event_ts,omitempty"`
+}
+
+func (e *AppMentionEvent) eventType() string {
+	return "app_mention"
+}
+
+func (e *AppMentionEvent) missingFields() []string {
+	var missing []string
+	missing = required(missing, "type", e.Type)
+	missing = required(missing, "user", e.User)
+	missing = required(missing, "channel", e.Channel)
+	missing = required(missing, "text", e.Text)
+	return required(missing, "ts", e.TS)
+}
+
+// BlockActionsPayload is based on:
+// https://docs.slack.dev/reference/interaction-payloads/block_actions-payload
+type BlockActionsPayload struct {
+	Type        string              `json:"type"`
+	User        InteractionUser     `json:"user"`
+	APIAppID    string              `json:"api_app_id,omitempty"`
+	Team        *InteractionTeam    `json:"team,omitempty"`
+	Container   map[string]any      `json:"container,omitempty"`
+	TriggerID   string              `json:"trigger_id,omitempty"`
+	Channel     *InteractionChannel `json:"channel,omitempty"`
+	Message     map[string]any      `json:"message,omitempty"`
+	View        map[string]any      `json:"view,omitempty"`
+	State       map[string]any      `json:"state,omitempty"`
+	ResponseURL string              `json:"response_url,omitempty"`
+	Actions     []BlockAction       `json:"actions"`
+}
+
+// BlockAction is based on:
+// https://docs.slack.dev/reference/interaction-payloads/block_actions-payload#fields
+type BlockAction struct {
+	Type           string         `json:"type"`
+	ActionID       string         `json:"action_id"`
+	BlockID        string         `json:"block_id,omitempty"`
+	Text           *TextObject    `json:"text,omitempty"`
+	Value          string         `json:"value,omitempty"`
+	Style          string         `json:"style,omitempty"`
+	SelectedOption map[string]any `json:"selected_option,omitempty"`
+	ActionTS       string         `json:"action_ts,omitempty"`
+}
+
+// TextObject is based on https://docs.slack.dev/reference/block-kit/composition-objects/text-object.
+type TextObject struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// InteractionUser is based on:
+// https://docs.slack.dev/reference/interaction-payloads/block_actions-payload#fields
+type InteractionUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username,omitempty"`
+	Name     string `json:"name,omitempty"`
+	TeamID   string `json:"team_id,omitempty"`
+}
+
+// InteractionTeam is based on:
+// https://docs.slack.dev/reference/interaction-payloads/block_actions-payload#fields
+type InteractionTeam struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain,omitempty"`
+}
+
+// InteractionChannel is based on:
+// https://docs.slack.dev/reference/interaction-payloads/block_actions-payload#fields
+type InteractionChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+func (p *BlockActionsPayload) eventType() string {
+	return "block_actions"
+}
+
+func (p *BlockActionsPayload) missingFields() []string {
+	var missing []string
+	missing = required(missing, "type", p.Type)
+	missing = required(missing, "user.id", p.User.ID)
+	if len(p.Actions) == 0 {
+		return append(missing, "actions")
+	}
+	for i, a := range p.Actions {
+		missing = required(missing, fmt.Sprintf("actions[%d].action_id", i), a.ActionID)
+	}
+	return missing
+}
+
+// ViewSubmissionPayload is based on:
+// https://docs.slack.dev/reference/interaction-payloads/view-interactions-payload#view_submission
+type ViewSubmissionPayload struct {
+	Type         string           `json:"type"`
+	User         InteractionUser  `json:"user"`
+	APIAppID     string           `json:"api_app_id,omitempty"`
+	Team         *InteractionTeam `json:"team,omitempty"`
+	TriggerID    string           `json:"trigger_id,omitempty"`
+	View         SubmittedView    `json:"view"`
+	ResponseURLs []map[string]any `json:"response_urls,omitempty"`
+}
+
+// SubmittedView is based on:
+// https://docs.slack.dev/reference/interaction-payloads/view-interactions-payload#view_submission
+type SubmittedView struct {
+	ID              string           `json:"id"`
+	TeamID          string           `json:"team_id,omitempty"`
+	Type            string           `json:"type"`
+	CallbackID      string           `json:"callback_id,omitempty"`
+	ExternalID      string           `json:"external_id,omitempty"`
+	PrivateMetadata string           `json:"private_metadata,omitempty"`
+	Hash            string           `json:"hash,omitempty"`
+	RootViewID      string           `json:"root_view_id,omitempty"`
+	PreviousViewID  string           `json:"previous_view_id,omitempty"`
+	AppID           string           `json:"app_id,omitempty"`
+	BotID           string           `json:"bot_id,omitempty"`
+	Blocks          []map[string]any `json:"blocks,omitempty"`
+	State           ViewState        `json:"state"`
+}
+
+// ViewState is based on https://docs.slack.dev/reference/views/view-object/#state.
+// Values are keyed by block IDs and then action IDs, e.g.
+// Values["block_id"]["action_id"]["value"] for "plain_text_input" elements.
+type ViewState struct {
+	Values map[string]map[string]map[string]any `json:"values"`
+}
+
+func (p *ViewSubmissionPayload) eventType() string {
+	return "view_submission"
+}
+
+func (p *ViewSubmissionPayload) missingFields() []string {
+	var missing []string
+	missing = required(missing, "type", p.Type)
+	missing = required(missing, "user.id", p.User.ID)
+	return required(missing, "view.id", p.View.ID)
+}
@@ -0,0 +1,336 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readEvent reads a captured Slack payload from the "testdata/events" directory.
+func readEvent(t *testing.T, name string) map[string]any {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "events", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDecodeMessageEvent(t *testing.T) {
+	got, err := Decode[MessageEvent](readEvent(t, "message"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := MessageEvent{
+		Type:        "message",
+		Channel:     "C0123456789",
+		ChannelType: "channel",
+		User:        "U0123456789",
+		Team:        "T0123456789",
+		Text:        "Deploy is done :tada:",
+		TS:          "1717171717.123456",
+		ThreadTS:    "1717171700.000100",
+		EventTS:     "1717171717.123456",
+	}
+	if len(got.Blocks) != 1 || got.Blocks[0]["type"] != "rich_text" {
+		t.Errorf("Decode().Blocks = %v, want 1 rich_text block", got.Blocks)
+	}
+	got.Blocks = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeReactionAddedEvent(t *testing.T) {
+	got, err := Decode[ReactionAddedEvent](readEvent(t, "reaction_added"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := ReactionAddedEvent{
+		Type:     "reaction_added",
+		User:     "U0123456789",
+		Reaction: "white_check_mark",
+		ItemUser: "U0BOT000000",
+		Item:     ReactionItem{Type: "message", Channel: "C0123456789", TS: "1717171700.000100"},
+		EventTS:  "1717171720.000200",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeAppMentionEvent(t *testing.T) {
+	got, err := Decode[AppMentionEvent](readEvent(t, "app_mention"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := AppMentionEvent{
+		Type:    "app_mention",
+		User:    "U0123456789",
+		Team:    "T0123456789",
+		Channel: "C0123456789",
+		Text:    "<@U0BOT000000> status please",
+		TS:      "1717171730.000300",
+		EventTS: "1717171730.000300",
+	}
+	if len(got.Blocks) != 1 {
+		t.Errorf("Decode().Blocks = %v, want 1 block", got.Blocks)
+	}
+	got.Blocks = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeBlockActionsPayload(t *testing.T) {
+	got, err := Decode[BlockActionsPayload](readEvent(t, "block_actions"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.User.ID != "U0123456789" || got.User.Username != "jdoe" {
+		t.Errorf("Decode().User = %+v", got.User)
+	}
+	if got.Team == nil || got.Team.Domain != "example" {
+		t.Errorf("Decode().Team = %+v", got.Team)
+	}
+	if got.Channel == nil || got.Channel.Name != "deployments" {
+		t.Errorf("Decode().Channel = %+v", got.Channel)
+	}
+	if got.Container["message_ts"] != "1717171700.000100" {
+		t.Errorf("Decode().Container = %v", got.Container)
+	}
+	if got.Message["text"] != "Deploy to production?" {
+		t.Errorf("Decode().Message = %v", got.Message)
+	}
+
+	want := []BlockAction{{
+		Type:     "button",
+		ActionID: "approve",
+		BlockID:  "approval-buttons",
+		Text:     &TextObject{Type: "plain_text", Text: "Approve", Emoji: true},
+		Value:    "approve",
+		Style:    "primary",
+		ActionTS: "1717171740.000400",
+	}}
+	if !reflect.DeepEqual(got.Actions, want) {
+		t.Errorf("Decode().Actions = %+v, want %+v", got.Actions, want)
+	}
+}
+
+func TestDecodeViewSubmissionPayload(t *testing.T) {
+	got, err := Decode[ViewSubmissionPayload](readEvent(t, "view_submission"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.User.ID != "U0123456789" {
+		t.Errorf("Decode().User = %+v", got.User)
+	}
+	if got.View.ID != "V0123456789" || got.View.CallbackID != "rollback_form" {
+		t.Errorf("Decode().View = %+v", got.View)
+	}
+	if got.View.PrivateMetadata != `{"workflow_id":"wf-1"}` {
+		t.Errorf("Decode().View.PrivateMetadata = %q", got.View.PrivateMetadata)
+	}
+	if v := got.View.State.Values["reason"]["reason_input"]["value"]; v != "Error rate spiked after deploy" {
+		t.Errorf("Decode().View.State = %v", got.View.State.Values)
+	}
+}
+
+func TestDecodeExtraFields(t *testing.T) {
+	payload := readEvent(t, "reaction_added")
+	payload["_link_id"] = "link"
+	event, _ := payload["event"].(map[string]any)
+	event["unknown_field"] = map[string]any{"nested": true}
+
+	got, err := Decode[ReactionAddedEvent](payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Reaction != "white_check_mark" {
+		t.Errorf("Decode().Reaction = %q, want %q", got.Reaction, "white_check_mark")
+	}
+}
+
+func TestDecodeMissingFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		delete  func(map[string]any)
+		decode  func(map[string]any) error
+		want    []string
+	}{
+		{
+			name:    "message_without_ts",
+			fixture: "message",
+			delete: func(m map[string]any) {
+				delete(m["event"].(map[string]any), "ts")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[MessageEvent](m)
+				return err
+			},
+			want: []string{"ts"},
+		},
+		{
+			name:    "reaction_without_item",
+			fixture: "reaction_added",
+			delete: func(m map[string]any) {
+				delete(m["event"].(map[string]any), "item")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[ReactionAddedEvent](m)
+				return err
+			},
+			want: []string{"item.type"},
+		},
+		{
+			name:    "app_mention_without_user_and_text",
+			fixture: "app_mention",
+			delete: func(m map[string]any) {
+				e := m["event"].(map[string]any)
+				delete(e, "user")
+				delete(e, "text")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[AppMentionEvent](m)
+				return err
+			},
+			want: []string{"user", "text"},
+		},
+		{
+			name:    "block_actions_without_type_and_user",
+			fixture: "block_actions",
+			delete: func(m map[string]any) {
+				delete(m, "type")
+				delete(m, "user")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[BlockActionsPayload](m)
+				return err
+			},
+			want: []string{"type", "user.id"},
+		},
+		{
+			name:    "block_actions_without_actions",
+			fixture: "block_actions",
+			delete: func(m map[string]any) {
+				m["actions"] = []any{}
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[BlockActionsPayload](m)
+				return err
+			},
+			want: []string{"actions"},
+		},
+		{
+			name:    "block_actions_without_action_id",
+			fixture: "block_actions",
+			delete: func(m map[string]any) {
+				delete(m["actions"].([]any)[0].(map[string]any), "action_id")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[BlockActionsPayload](m)
+				return err
+			},
+			want: []string{"actions[0].action_id"},
+		},
+		{
+			name:    "view_submission_without_view",
+			fixture: "view_submission",
+			delete: func(m map[string]any) {
+				delete(m, "view")
+			},
+			decode: func(m map[string]any) error {
+				_, err := Decode[ViewSubmissionPayload](m)
+				return err
+			},
+			want: []string{"view.id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := readEvent(t, tt.fixture)
+			tt.delete(payload)
+
+			err := tt.decode(payload)
+			var mfe *MissingFieldsError
+			if !errors.As(err, &mfe) {
+				t.Fatalf("Decode() error = %v, want MissingFieldsError", err)
+			}
+			if !reflect.DeepEqual(mfe.Fields, tt.want) {
+				t.Errorf("Decode() missing fields = %v, want %v", mfe.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePartialResult(t *testing.T) {
+	payload := readEvent(t, "block_actions")
+	delete(payload, "user")
+
+	got, err := Decode[BlockActionsPayload](payload)
+	if err == nil {
+		t.Fatal("Decode() error = nil")
+	}
+	if len(got.Actions) != 1 || got.Actions[0].Value != "approve" {
+		t.Errorf("Decode().Actions = %+v, want partially-decoded payload", got.Actions)
+	}
+}
+
+func TestDecodeUnexpectedType(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		decode  func(map[string]any) error
+	}{
+		{
+			name:    "message_as_app_mention",
+			payload: readEvent(t, "message"),
+			decode: func(m map[string]any) error {
+				_, err := Decode[AppMentionEvent](m)
+				return err
+			},
+		},
+		{
+			name:    "block_actions_as_view_submission",
+			payload: readEvent(t, "block_actions"),
+			decode: func(m map[string]any) error {
+				_, err := Decode[ViewSubmissionPayload](m)
+				return err
+			},
+		},
+		{
+			name:    "invalid_field_type",
+			payload: map[string]any{"type": "message", "channel": "C0123456789", "ts": 123},
+			decode: func(m map[string]any) error {
+				_, err := Decode[MessageEvent](m)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decode(tt.payload)
+			if err == nil {
+				t.Fatal("Decode() error = nil")
+			}
+			var mfe *MissingFieldsError
+			if errors.As(err, &mfe) {
+				t.Errorf("Decode() error = %v, want non-MissingFieldsError", err)
+			}
+		})
+	}
+}
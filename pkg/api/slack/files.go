@@ -3,14 +3,105 @@ package slack
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/temporal"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
 )
 
+//revive:disable:exported
+const (
+	FilesInfoActivityName = "slack.files.info"
+	FilesListActivityName = "slack.files.list"
+) //revive:enable:exported
+
+// TimpaniFilesDownloadActivityName is a Timpani-specific activity, see [API.TimpaniFilesDownloadActivity].
+const TimpaniFilesDownloadActivityName = "slack.timpani.files.download"
+
+// FilesInfoRequest is based on:
+// https://docs.slack.dev/reference/methods/files.info/
+type FilesInfoRequest struct {
+	File string `json:"file"`
+
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// FilesInfoResponse is based on:
+// https://docs.slack.dev/reference/methods/files.info/
+type FilesInfoResponse struct {
+	slack.Response
+
+	File     *slack.File      `json:"file,omitempty"`
+	Comments []map[string]any `json:"comments,omitempty"`
+}
+
+// FilesListRequest is based on:
+// https://docs.slack.dev/reference/methods/files.list/
+type FilesListRequest struct {
+	Channel string `json:"channel,omitempty"`
+	User    string `json:"user,omitempty"`
+	TSFrom  string `json:"ts_from,omitempty"`
+	TSTo    string `json:"ts_to,omitempty"`
+	Types   string `json:"types,omitempty"`
+
+	Count int `json:"count,omitempty"`
+	Page  int `json:"page,omitempty"`
+
+	TeamID string `json:"team_id,omitempty"`
+}
+
+// FilesListResponse is based on:
+// https://docs.slack.dev/reference/methods/files.list/
+//
+// To get the next page, increment the request's Page field, while it's less than Paging.Pages.
+type FilesListResponse struct {
+	slack.Response
+
+	Files  []slack.File `json:"files,omitempty"`
+	Paging *FilesPaging `json:"paging,omitempty"`
+}
+
+// FilesPaging is the pagination details of [FilesListResponse].
+type FilesPaging struct {
+	Count int `json:"count"`
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Pages int `json:"pages"`
+}
+
+// TimpaniFilesDownloadRequest specifies a private Slack file to download (its URL is
+// the "url_private_download" field of a [slack.File]). If FilePath is specified, the
+// file is written into it, in order to support large files. Otherwise, its content is
+// returned. MaxSize is optional (default = [client.MaxStreamSize] for local files, or
+// 3/4 of [client.MaxResponseSize] for returned content, which is base64-encoded in the
+// activity's JSON result).
+type TimpaniFilesDownloadRequest struct {
+	URL      string `json:"url"`
+	FilePath string `json:"file_path,omitempty"`
+	MaxSize  int64  `json:"max_size,omitempty"`
+}
+
+// TimpaniFilesDownloadResponse contains either the content of the downloaded
+// file, or the path of the local file that it was written into.
+type TimpaniFilesDownloadResponse struct {
+	FilePath    string `json:"file_path,omitempty"`
+	Content     []byte `json:"content,omitempty"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
 // FilesGetUploadURLExternalActivity is based on:
 // https://docs.slack.dev/reference/methods/files.getUploadURLExternal/
 //
@@ -72,7 +163,44 @@ func (a *API) FilesDeleteActivity(ctx context.Context, req slack.FilesDeleteRequ
 		return nil, err
 	}
 
-	if resp.Error == "file_not_found" {
+	if err := filesDeleteError(resp, req.File); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// filesDeleteError distinguishes between Slack API errors that indicate that the file
+// doesn't exist (anymore), which will never succeed if retried, and all other errors.
+func filesDeleteError(resp *slack.FilesDeleteResponse, file string) error {
+	switch resp.Error {
+	case "file_deleted", "file_not_found", "no_file_data_found":
+		return temporal.NewNonRetryableApplicationError(resp.Error, "SlackAPIError", nil, file)
+	}
+	if !resp.OK {
+		return errors.New("Slack API error: " + resp.Error)
+	}
+	return nil
+}
+
+// FilesInfoActivity is based on:
+// https://docs.slack.dev/reference/methods/files.info/
+func (a *API) FilesInfoActivity(ctx context.Context, req FilesInfoRequest) (*FilesInfoResponse, error) {
+	query := url.Values{}
+	query.Set("file", req.File)
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	if req.Cursor != "" {
+		query.Set("cursor", req.Cursor)
+	}
+
+	resp := new(FilesInfoResponse)
+	if err := a.httpGet(ctx, FilesInfoActivityName, query, resp); err != nil {
+		return nil, err
+	}
+
+	switch resp.Error {
+	case "file_deleted", "file_not_found":
 		return nil, temporal.NewNonRetryableApplicationError(resp.Error, "SlackAPIError", nil, req.File)
 	}
 	if !resp.OK {
@@ -80,3 +208,136 @@ func (a *API) FilesDeleteActivity(ctx context.Context, req slack.FilesDeleteRequ
 	}
 	return resp, nil
 }
+
+// FilesListActivity is based on:
+// https://docs.slack.dev/reference/methods/files.list/
+func (a *API) FilesListActivity(ctx context.Context, req FilesListRequest) (*FilesListResponse, error) {
+	resp := new(FilesListResponse)
+	if err := a.httpGet(ctx, FilesListActivityName, filesListQuery(req), resp); err != nil {
+		return nil, err
+	}
+
+	if !resp.OK {
+		return nil, errors.New("Slack API error: " + resp.Error)
+	}
+	return resp, nil
+}
+
+func filesListQuery(req FilesListRequest) url.Values {
+	query := url.Values{}
+	if req.Channel != "" {
+		query.Set("channel", req.Channel)
+	}
+	if req.User != "" {
+		query.Set("user", req.User)
+	}
+	if req.TSFrom != "" {
+		query.Set("ts_from", req.TSFrom)
+	}
+	if req.TSTo != "" {
+		query.Set("ts_to", req.TSTo)
+	}
+	if req.Types != "" {
+		query.Set("types", req.Types)
+	}
+	if req.Count != 0 {
+		query.Set("count", strconv.Itoa(req.Count))
+	}
+	if req.Page != 0 {
+		query.Set("page", strconv.Itoa(req.Page))
+	}
+	if req.TeamID != "" {
+		query.Set("team_id", req.TeamID)
+	}
+	return query
+}
+
+// TimpaniFilesDownloadActivity downloads a private Slack file, using the Slack bot token,
+// and either returns its content or writes it into a local file (on the Timpani worker's
+// host). Based on the "Accessing private files" section in https://docs.slack.dev/messaging/working-with-files.
+func (a *API) TimpaniFilesDownloadActivity(ctx context.Context, req TimpaniFilesDownloadRequest) (*TimpaniFilesDownloadResponse, error) {
+	// Don't send the bot token anywhere else.
+	if !isSlackFileURL(req.URL) {
+		msg := "invalid Slack file URL"
+		return nil, temporal.NewNonRetryableApplicationError(msg, "error", nil, req.URL)
+	}
+
+	_, secrets, err := a.thrippy.LinkData(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	t := time.Now().UTC()
+	resp, err := downloadFile(ctx, req, linkBotToken(secrets))
+	otel.IncrementAPICallCounter(t, TimpaniFilesDownloadActivityName, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// isSlackFileURL checks that the given URL points to a Slack file server,
+// e.g. "https://files.slack.com/files-pri/..." or GovSlack's equivalent.
+func isSlackFileURL(fileURL string) bool {
+	u, err := url.Parse(fileURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+
+	host := u.Hostname()
+	return strings.HasSuffix(host, ".slack.com") || strings.HasSuffix(host, ".slack-gov.com")
+}
+
+func downloadFile(ctx context.Context, req TimpaniFilesDownloadRequest, botToken string) (*TimpaniFilesDownloadResponse, error) {
+	l := activity.GetLogger(ctx)
+
+	body, headers, err := client.HTTPRequestStream(ctx, http.MethodGet, req.URL, botToken, "", "", nil, client.WithFileTransferTimeout())
+	if err != nil {
+		l.Error("HTTP GET request error", slog.Any("error", err), slog.String("url", req.URL))
+		return nil, err
+	}
+	defer body.Close()
+
+	contentType := headers.Get("Content-Type")
+	if req.FilePath == "" {
+		return readFile(ctx, req, body, contentType)
+	}
+
+	maxSize := req.MaxSize
+	if maxSize <= 0 {
+		maxSize = client.MaxStreamSize
+	}
+
+	n, err := client.StreamToFile(body, req.FilePath, maxSize)
+	if err != nil {
+		l.Error("failed to save Slack file", slog.Any("error", err), slog.String("url", req.URL), slog.String("file_path", req.FilePath))
+		return nil, err
+	}
+
+	l.Info("downloaded Slack file", slog.String("url", req.URL), slog.String("file_path", req.FilePath), slog.Int64("size", n))
+	return &TimpaniFilesDownloadResponse{FilePath: req.FilePath, Size: n, ContentType: contentType}, nil
+}
+
+// readFile reads a downloaded Slack file into memory, to return it as the
+// activity's result, if it isn't too large for Temporal's payload limits.
+func readFile(ctx context.Context, req TimpaniFilesDownloadRequest, body io.Reader, contentType string) (*TimpaniFilesDownloadResponse, error) {
+	maxSize := req.MaxSize
+	if maxSize <= 0 {
+		maxSize = int64(client.MaxResponseSize()) * 3 / 4
+	}
+
+	content, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		activity.GetLogger(ctx).Error("failed to read Slack file", slog.Any("error", err), slog.String("url", req.URL))
+		return nil, err
+	}
+
+	if n := int64(len(content)); n > maxSize {
+		msg := fmt.Sprintf("%s file is too large (limit is %d bytes): specify a file path", TimpaniFilesDownloadActivityName, maxSize)
+		return nil, temporal.NewNonRetryableApplicationError(msg, "ResponseTooLargeError", nil, req.URL, maxSize)
+	}
+
+	activity.GetLogger(ctx).Info("downloaded Slack file", slog.String("url", req.URL), slog.Int("size", len(content)))
+	return &TimpaniFilesDownloadResponse{Content: content, Size: int64(len(content)), ContentType: contentType}, nil
+}
@@ -0,0 +1,201 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+func TestFilesDeleteError(t *testing.T) {
+	tests := []struct {
+		name             string
+		resp             *slack.FilesDeleteResponse
+		wantErr          bool
+		wantNonRetryable bool
+	}{
+		{
+			name: "ok",
+			resp: &slack.FilesDeleteResponse{OK: true},
+		},
+		{
+			name:             "file_deleted",
+			resp:             &slack.FilesDeleteResponse{Error: "file_deleted"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:             "file_not_found",
+			resp:             &slack.FilesDeleteResponse{Error: "file_not_found"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:             "no_file_data_found",
+			resp:             &slack.FilesDeleteResponse{Error: "no_file_data_found"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:    "retryable_error",
+			resp:    &slack.FilesDeleteResponse{Error: "ratelimited"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := filesDeleteError(tt.resp, "F123")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filesDeleteError() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var appErr *temporal.ApplicationError
+			if got := errors.As(err, &appErr) && appErr.NonRetryable(); got != tt.wantNonRetryable {
+				t.Errorf("filesDeleteError() non-retryable = %v, want %v", got, tt.wantNonRetryable)
+			}
+		})
+	}
+}
+
+func TestIsSlackFileURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{
+			name: "slack",
+			url:  "https://files.slack.com/files-pri/T123-F456/download/file.txt",
+			want: true,
+		},
+		{
+			name: "govslack",
+			url:  "https://files.slack-gov.com/files-pri/T123-F456/download/file.txt",
+			want: true,
+		},
+		{
+			name: "http",
+			url:  "http://files.slack.com/files-pri/T123-F456/download/file.txt",
+		},
+		{
+			name: "other_host",
+			url:  "https://files.example.com/files-pri/T123-F456/download/file.txt",
+		},
+		{
+			name: "lookalike_host",
+			url:  "https://files.notslack.com/file.txt",
+		},
+		{
+			name: "invalid_url",
+			url:  "://",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSlackFileURL(tt.url); got != tt.want {
+				t.Errorf("isSlackFileURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilesListQuery(t *testing.T) {
+	req := FilesListRequest{Channel: "C123", User: "U456", TSFrom: "1700000000", Count: 50, Page: 2}
+	want := "channel=C123&count=50&page=2&ts_from=1700000000&user=U456"
+	if got := filesListQuery(req).Encode(); got != want {
+		t.Errorf("filesListQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 10<<20) // 10 MiB.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(content)
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		filePath string
+		maxSize  int64
+		wantSize int64
+		wantErr  bool
+	}{
+		{
+			name:     "local_file",
+			filePath: filepath.Join(t.TempDir(), "file.bin"),
+			wantSize: int64(len(content)),
+		},
+		{
+			name:     "content",
+			maxSize:  int64(len(content)),
+			wantSize: int64(len(content)),
+		},
+		{
+			name:    "content_too_large",
+			maxSize: int64(len(content)) - 1,
+			wantErr: true,
+		},
+		{
+			name:    "content_default_limit",
+			wantErr: true, // 10 MiB > 3/4 of 1 MiB.
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := TimpaniFilesDownloadRequest{URL: s.URL, FilePath: tt.filePath, MaxSize: tt.maxSize}
+			f := func(ctx context.Context) (*TimpaniFilesDownloadResponse, error) {
+				return downloadFile(ctx, req, "token")
+			}
+
+			env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(f, activity.RegisterOptions{Name: "download"})
+			val, err := env.ExecuteActivity("download")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := new(TimpaniFilesDownloadResponse)
+			if err := val.Get(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Size != tt.wantSize {
+				t.Errorf("downloadFile() size = %d, want %d", got.Size, tt.wantSize)
+			}
+			if got.ContentType != "application/octet-stream" {
+				t.Errorf("downloadFile() content type = %q, want %q", got.ContentType, "application/octet-stream")
+			}
+
+			b := got.Content
+			if tt.filePath != "" {
+				if b, err = os.ReadFile(tt.filePath); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(b, content) {
+				t.Errorf("downloaded file content mismatch (%d bytes)", len(b))
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package slack
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+// TimpaniInteractionResponseActivityName is a Timpani-specific activity, see [API.TimpaniInteractionResponseActivity].
+const TimpaniInteractionResponseActivityName = "slack.timpani.interactionResponse"
+
+// TimpaniInteractionResponseRequest contains the response ID of an interaction payload
+// (its "timpani_response_id" field), and the JSON body of the synchronous HTTP response to it.
+type TimpaniInteractionResponseRequest struct {
+	ResponseID string         `json:"response_id"`
+	Body       map[string]any `json:"body"`
+}
+
+// TimpaniInteractionResponseResponse reports whether the response was delivered in time.
+type TimpaniInteractionResponseResponse struct {
+	Delivered bool `json:"delivered"`
+}
+
+// TimpaniInteractionResponseActivity supplies the synchronous HTTP response to an interaction
+// payload with a deferred response policy, e.g. a "view_submission" with validation errors:
+// https://docs.slack.dev/surfaces/modals#displaying_errors. Delivery is best-effort:
+// it works only if the activity runs in the same Timpani process that received the
+// interaction, within a few seconds (otherwise Slack receives an empty 200 OK response).
+func (a *API) TimpaniInteractionResponseActivity(
+	_ context.Context,
+	req TimpaniInteractionResponseRequest,
+) (*TimpaniInteractionResponseResponse, error) {
+	if req.ResponseID == "" {
+		return nil, temporal.NewNonRetryableApplicationError("missing response ID", "error", nil)
+	}
+
+	return &TimpaniInteractionResponseResponse{Delivered: listeners.DeliverResponse(req.ResponseID, req.Body)}, nil
+}
@@ -0,0 +1,132 @@
+package slack
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdHTMLComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	mdCodeFence   = regexp.MustCompile("^(\\s*)(?:```|~~~)")
+	mdBlockquote  = regexp.MustCompile(`^(?:\s*>\s?)+`)
+	mdHeading     = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	mdTaskList    = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+`)
+	mdList        = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+
+	mdAutolink = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	mdLink     = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdBold     = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdItalic   = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdStrike   = regexp.MustCompile(`~~(.+?)~~`)
+	mdLinkRef  = regexp.MustCompile("\x01(\\d+)\x01")
+)
+
+// ConvertMarkdown converts GitHub-flavored markdown (e.g. GitHub and Bitbucket
+// PR descriptions) to Slack's [mrkdwn] format: bold, italics, strikethrough,
+// links and images, code spans and fences, bulleted and task lists, headings,
+// and blockquotes. It also escapes "&", "<", and ">" as Slack requires.
+//
+// The conversion is lossy in some cases:
+//   - Headings become bold lines
+//   - Images become links (with their alt text)
+//   - Code fence languages are removed
+//   - HTML comments are removed, and other HTML tags are shown as-is
+//   - Tables, footnotes, and horizontal rules are shown as-is
+//   - Bold and italic text cannot be nested, or span multiple lines
+//   - Formatting in the text of links is not converted
+//
+// [mrkdwn]: https://docs.slack.dev/messaging/formatting-message-text
+func ConvertMarkdown(s string) string {
+	s = mdHTMLComment.ReplaceAllString(s, "")
+
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		if m := mdCodeFence.FindStringSubmatch(line); m != nil {
+			inFence = !inFence
+			lines[i] = m[1] + "```"
+			continue
+		}
+
+		if inFence {
+			lines[i] = escapeMrkdwn(line)
+		} else {
+			lines[i] = convertMarkdownLine(line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// convertMarkdownLine converts a single line which is not in a code fence.
+func convertMarkdownLine(line string) string {
+	quote := mdBlockquote.FindString(line)
+	line = line[len(quote):]
+	if quote != "" {
+		quote = strings.Repeat("> ", strings.Count(quote, ">"))
+	}
+
+	if m := mdHeading.FindStringSubmatch(line); m != nil {
+		line = "**" + m[1] + "**"
+	} else if m := mdTaskList.FindStringSubmatch(line); m != nil {
+		box := "☐ "
+		if m[2] != " " {
+			box = "☑ "
+		}
+		line = m[1] + box + line[len(m[0]):]
+	} else if m := mdList.FindStringSubmatch(line); m != nil {
+		line = m[1] + "• " + line[len(m[0]):]
+	}
+
+	// Odd parts are code spans, which Slack doesn't format.
+	parts := strings.Split(line, "`")
+	for i, p := range parts {
+		if i%2 == 0 {
+			parts[i] = convertMarkdownText(p)
+		} else {
+			parts[i] = escapeMrkdwn(p)
+		}
+	}
+
+	return quote + strings.Join(parts, "`")
+}
+
+// convertMarkdownText converts inline formatting in text without code spans.
+func convertMarkdownText(s string) string {
+	s = escapeMrkdwn(mdAutolink.ReplaceAllString(s, "$1"))
+
+	// Protect links from the formatting conversions below
+	// (e.g. underscores and asterisks in URLs).
+	var links []string
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		sm := mdLink.FindStringSubmatch(m)
+		link := "<" + sm[2] + ">"
+		if sm[1] != "" {
+			link = fmt.Sprintf("<%s|%s>", sm[2], sm[1])
+		}
+		links = append(links, link)
+		return fmt.Sprintf("\x01%d\x01", len(links)-1)
+	})
+
+	// Bold is temporarily marked with "\x00" to distinguish
+	// it from single asterisks, which are italic in markdown.
+	s = mdBold.ReplaceAllString(s, "\x00$1$2\x00")
+	s = mdItalic.ReplaceAllString(s, "${1}_${2}_")
+	s = mdStrike.ReplaceAllString(s, "~$1~")
+	s = strings.ReplaceAll(s, "\x00", "*")
+
+	return mdLinkRef.ReplaceAllStringFunc(s, func(m string) string {
+		i, _ := strconv.Atoi(strings.Trim(m, "\x01"))
+		return links[i]
+	})
+}
+
+// escapeMrkdwn escapes the control characters of Slack's mrkdwn format, based on
+// https://docs.slack.dev/messaging/formatting-message-text#escaping.
+func escapeMrkdwn(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	return strings.ReplaceAll(s, ">", "&gt;")
+}
@@ -0,0 +1,127 @@
+package slack
+
+import (
+	"testing"
+)
+
+func TestConvertMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{
+			name: "plain_text",
+			md:   "Hello world",
+			want: "Hello world",
+		},
+		{
+			name: "bold",
+			md:   "**bold** and __also bold__",
+			want: "*bold* and *also bold*",
+		},
+		{
+			name: "italics",
+			md:   "*italic* and _also italic_",
+			want: "_italic_ and _also italic_",
+		},
+		{
+			name: "bold_and_italics",
+			md:   "**bold** *italic*",
+			want: "*bold* _italic_",
+		},
+		{
+			name: "strikethrough",
+			md:   "~~removed~~",
+			want: "~removed~",
+		},
+		{
+			name: "link",
+			md:   "See [the docs](https://example.com/a_b_c?x=1&y=2) for details",
+			want: "See <https://example.com/a_b_c?x=1&amp;y=2|the docs> for details",
+		},
+		{
+			name: "link_with_title",
+			md:   `[docs](https://example.com "Title")`,
+			want: "<https://example.com|docs>",
+		},
+		{
+			name: "image",
+			md:   "![screenshot](https://example.com/image.png)",
+			want: "<https://example.com/image.png|screenshot>",
+		},
+		{
+			name: "image_without_alt_text",
+			md:   "![](https://example.com/image.png)",
+			want: "<https://example.com/image.png>",
+		},
+		{
+			name: "autolink",
+			md:   "<https://example.com>",
+			want: "https://example.com",
+		},
+		{
+			name: "escaping",
+			md:   "a < b && c > d",
+			want: "a &lt; b &amp;&amp; c &gt; d",
+		},
+		{
+			name: "inline_code",
+			md:   "Run `go test **/*.go` now",
+			want: "Run `go test **/*.go` now",
+		},
+		{
+			name: "code_fence",
+			md:   "```go\nfunc **main**() {}\n```\n**after**",
+			want: "```\nfunc **main**() {}\n```\n*after*",
+		},
+		{
+			name: "bulleted_lists",
+			md:   "- one\n* two\n  + nested",
+			want: "• one\n• two\n  • nested",
+		},
+		{
+			name: "ordered_list",
+			md:   "1. first\n2. second",
+			want: "1. first\n2. second",
+		},
+		{
+			name: "task_list",
+			md:   "- [ ] todo\n- [x] done",
+			want: "☐ todo\n☑ done",
+		},
+		{
+			name: "headings",
+			md:   "# Title\n### Subtitle ###",
+			want: "*Title*\n*Subtitle*",
+		},
+		{
+			name: "blockquote",
+			md:   "> quoted **text**",
+			want: "> quoted *text*",
+		},
+		{
+			name: "html_comment",
+			md:   "<!-- PR template\ninstructions -->Description",
+			want: "Description",
+		},
+		{
+			name: "lossy_table",
+			md:   "| a | b |\n|---|---|",
+			want: "| a | b |\n|---|---|",
+		},
+		{
+			name: "multiplication_is_not_italics",
+			md:   "2 * 3 * 4",
+			want: "2 * 3 * 4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertMarkdown(tt.md); got != tt.want {
+				t.Errorf("ConvertMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+// ProbeLink checks that the credentials of a Thrippy link are still valid, using the
+// cheapest authenticated Slack API method: https://docs.slack.dev/reference/methods/auth.test/
+//
+// Unlike the rest of this package, this function runs outside of Temporal activities.
+func ProbeLink(ctx context.Context, template string, secrets map[string]string) error {
+	botToken := linkBotToken(secrets)
+	if botToken == "" {
+		return errors.New("Slack bot token not found in Thrippy link credentials")
+	}
+
+	apiURL := apiBaseURL(template) + "/api/auth.test"
+	timeout := client.WithTimeout(client.Timeout)
+	body, _, _, err := client.HTTPRequest(ctx, http.MethodPost, apiURL, botToken, client.AcceptJSON, client.ContentJSON, nil, timeout)
+	if err != nil {
+		return err
+	}
+
+	resp := new(slack.AuthTestResponse)
+	if err := json.Unmarshal(body, resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return errors.New("Slack API error: " + resp.Error)
+	}
+
+	return nil
+}
@@ -3,10 +3,19 @@ package slack
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/taskqueue"
 )
 
 // ReactionsAddActivity is based on:
@@ -104,3 +113,174 @@ func (a *API) ReactionsRemoveActivity(ctx context.Context, req slack.ReactionsRe
 	}
 	return resp, nil
 }
+
+const (
+	// TimpaniAwaitReactionWorkflowName is a Timpani-specific workflow, see [API.TimpaniAwaitReactionWorkflow].
+	TimpaniAwaitReactionWorkflowName = "slack.timpani.awaitReaction"
+
+	reactionAddedSignal = "slack.events.reaction_added"
+)
+
+// TimpaniAwaitReactionRequest specifies a message to watch for reactions: either an existing
+// one (Channel and TS), or a new one which the workflow posts (Channel and Text, in Slack's
+// mrkdwn format). Users and Reactions are optional allowlists of user IDs and emoji names
+// (without colons, e.g. "+1", regardless of skin tones). The timeout is optional too
+// (e.g. "1h", default = no timeout).
+type TimpaniAwaitReactionRequest struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts,omitempty"`
+	Text    string `json:"text,omitempty"`
+
+	Users     []string `json:"users,omitempty"`
+	Reactions []string `json:"reactions,omitempty"`
+	Timeout   string   `json:"timeout,omitempty"`
+}
+
+// TimpaniAwaitReactionResponse contains the first qualifying reaction to the message, and
+// the "reaction_added" event payload (if the reaction was added while the workflow was
+// waiting). If the workflow timed out, it contains only the message's identifiers.
+type TimpaniAwaitReactionResponse struct {
+	Channel  string         `json:"channel"`
+	TS       string         `json:"ts"`
+	User     string         `json:"user,omitempty"`
+	Reaction string         `json:"reaction,omitempty"`
+	Event    map[string]any `json:"event,omitempty"`
+	TimedOut bool           `json:"timed_out,omitempty"`
+}
+
+// TimpaniAwaitReactionWorkflow is a convenience wrapper over [API.ChatPostMessageActivity] and
+// [API.ReactionsGetActivity], for lightweight approvals (e.g. "react with :+1: to approve").
+// It posts a message (unless the request specifies an existing one), and waits for the first
+// "reaction_added" event of that message which satisfies the request's allowlists. Each such
+// reaction is confirmed with [API.ReactionsGetActivity], in case it was already removed.
+// Qualifying reactions which an existing message already has are returned immediately.
+func (a *API) TimpaniAwaitReactionWorkflow(ctx workflow.Context, req TimpaniAwaitReactionRequest) (*TimpaniAwaitReactionResponse, error) {
+	timeout := time.Duration(0)
+	if req.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError("invalid timeout: "+err.Error(), "error", err, req.Timeout)
+		}
+	}
+	deadline := workflow.Now(ctx).Add(timeout)
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 5 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+
+	resp := &TimpaniAwaitReactionResponse{Channel: req.Channel, TS: req.TS}
+	if req.TS == "" {
+		postReq := ChatPostMessageRequest{IdempotencyKey: workflow.GetInfo(ctx).WorkflowExecution.ID + "/message"}
+		postReq.Channel, postReq.Text = req.Channel, req.Text
+		msg := new(slack.ChatPostMessageResponse)
+		if err := workflow.ExecuteActivity(actCtx, slack.ChatPostMessageActivityName, postReq).Get(ctx, msg); err != nil {
+			return nil, fmt.Errorf("failed to post chat message: %w", err)
+		}
+		resp.Channel, resp.TS = msg.Channel, msg.TS
+	} else {
+		msg, err := getReactions(actCtx, resp.Channel, resp.TS)
+		if err != nil {
+			return nil, err
+		}
+		if resp.User, resp.Reaction = firstReaction(msg, req); resp.User != "" {
+			return resp, nil
+		}
+	}
+
+	// https://docs.temporal.io/develop/go/observability#visibility
+	attr := temporal.NewSearchAttributeKeyKeywordList("WaitingForSignals").ValueSet([]string{reactionAddedSignal})
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{TypedSearchAttributes: temporal.NewSearchAttributes(attr)})
+	filter := map[string]string{"event.item.channel": resp.Channel, "event.item.ts": resp.TS}
+
+	for {
+		waitReq := listeners.WaitForEventRequest{Signal: reactionAddedSignal, Filter: filter}
+		if timeout > 0 {
+			remaining := deadline.Sub(workflow.Now(ctx))
+			if remaining <= 0 {
+				resp.TimedOut = true
+				return resp, nil
+			}
+			waitReq.Timeout = remaining.String()
+		}
+
+		var payload map[string]any
+		if err := workflow.ExecuteChildWorkflow(childCtx, listeners.WaitForEventWorkflow, waitReq).Get(ctx, &payload); err != nil {
+			if timeout > 0 && !workflow.Now(ctx).Before(deadline) {
+				resp.TimedOut = true
+				return resp, nil
+			}
+			return nil, fmt.Errorf("failed to wait for events: %w", err)
+		}
+
+		event, _ := payload["event"].(map[string]any)
+		user, _ := event["user"].(string)
+		reaction, _ := event["reaction"].(string)
+		if !reactionAllowed(req, user, reaction) {
+			continue
+		}
+
+		msg, err := getReactions(actCtx, resp.Channel, resp.TS)
+		if err != nil {
+			return nil, err
+		}
+		if hasReaction(msg, user, reaction) {
+			resp.User, resp.Reaction, resp.Event = user, reaction, payload
+			return resp, nil
+		}
+	}
+}
+
+// getReactions returns a message with all of its reactions.
+func getReactions(ctx workflow.Context, channel, ts string) (map[string]any, error) {
+	req := slack.ReactionsGetRequest{Channel: channel, Timestamp: ts, Full: true}
+	resp := new(slack.ReactionsGetResponse)
+	if err := workflow.ExecuteActivity(ctx, slack.ReactionsGetActivityName, req).Get(ctx, resp); err != nil {
+		return nil, fmt.Errorf("failed to get message reactions: %w", err)
+	}
+	return resp.Message, nil
+}
+
+// firstReaction returns the first user and reaction in the given message
+// which satisfy the request's allowlists, or empty strings if there are none.
+func firstReaction(msg map[string]any, req TimpaniAwaitReactionRequest) (user, reaction string) {
+	reactions, _ := msg["reactions"].([]any)
+	for _, r := range reactions {
+		m, _ := r.(map[string]any)
+		name, _ := m["name"].(string)
+		users, _ := m["users"].([]any)
+		for _, u := range users {
+			if id, _ := u.(string); reactionAllowed(req, id, name) {
+				return id, name
+			}
+		}
+	}
+	return "", ""
+}
+
+// hasReaction checks whether the given message has a specific reaction by a specific user.
+func hasReaction(msg map[string]any, user, reaction string) bool {
+	req := TimpaniAwaitReactionRequest{Users: []string{user}, Reactions: []string{reaction}}
+	u, _ := firstReaction(msg, req)
+	return u != ""
+}
+
+// reactionAllowed checks a user ID and an emoji name against the request's allowlists.
+func reactionAllowed(req TimpaniAwaitReactionRequest, user, reaction string) bool {
+	if user == "" || reaction == "" {
+		return false
+	}
+	if len(req.Users) > 0 && !slices.Contains(req.Users, user) {
+		return false
+	}
+	if len(req.Reactions) == 0 {
+		return true
+	}
+
+	reaction, _, _ = strings.Cut(reaction, "::") // Skin tone, e.g. "+1::skin-tone-2".
+	return slices.ContainsFunc(req.Reactions, func(r string) bool {
+		r, _, _ = strings.Cut(strings.Trim(r, ":"), "::")
+		return r == reaction
+	})
+}
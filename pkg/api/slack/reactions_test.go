@@ -0,0 +1,181 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+func TestReactionAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      TimpaniAwaitReactionRequest
+		user     string
+		reaction string
+		want     bool
+	}{
+		{
+			name:     "no_allowlists",
+			user:     "U1",
+			reaction: "eyes",
+			want:     true,
+		},
+		{
+			name:     "allowed_user",
+			req:      TimpaniAwaitReactionRequest{Users: []string{"U1", "U2"}},
+			user:     "U2",
+			reaction: "eyes",
+			want:     true,
+		},
+		{
+			name:     "disallowed_user",
+			req:      TimpaniAwaitReactionRequest{Users: []string{"U1"}},
+			user:     "U2",
+			reaction: "+1",
+		},
+		{
+			name:     "allowed_reaction_with_skin_tone",
+			req:      TimpaniAwaitReactionRequest{Reactions: []string{":+1:", "white_check_mark"}},
+			user:     "U1",
+			reaction: "+1::skin-tone-3",
+			want:     true,
+		},
+		{
+			name:     "disallowed_reaction",
+			req:      TimpaniAwaitReactionRequest{Reactions: []string{"+1"}},
+			user:     "U1",
+			reaction: "-1",
+		},
+		{
+			name: "missing_details",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reactionAllowed(tt.req, tt.user, tt.reaction); got != tt.want {
+				t.Errorf("reactionAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func reactionEvent(user, reaction string) map[string]any {
+	return map[string]any{"type": "event_callback", "event": map[string]any{
+		"type": "reaction_added", "user": user, "reaction": reaction,
+		"item": map[string]any{"type": "message", "channel": "C1", "ts": "1"},
+	}}
+}
+
+func TestTimpaniAwaitReactionWorkflow(t *testing.T) {
+	thumbsUp := map[string]any{"reactions": []any{map[string]any{"name": "+1", "users": []any{"U2"}, "count": 1}}}
+
+	tests := []struct {
+		name      string
+		ts        string
+		timeout   string
+		events    []map[string]any
+		reactions []map[string]any // Results of consecutive reactions.get calls.
+		wantPosts int
+		wantWaits int
+		want      TimpaniAwaitReactionResponse
+	}{
+		{
+			name:      "new_message",
+			events:    []map[string]any{reactionEvent("U3", "+1"), reactionEvent("U2", "eyes"), reactionEvent("U2", "+1")},
+			reactions: []map[string]any{thumbsUp},
+			wantPosts: 1,
+			wantWaits: 3,
+			want:      TimpaniAwaitReactionResponse{Channel: "C1", TS: "1", User: "U2", Reaction: "+1"},
+		},
+		{
+			name:      "existing_message_with_reaction",
+			ts:        "1",
+			reactions: []map[string]any{thumbsUp},
+			want:      TimpaniAwaitReactionResponse{Channel: "C1", TS: "1", User: "U2", Reaction: "+1"},
+		},
+		{
+			name:      "reaction_already_removed",
+			ts:        "1",
+			events:    []map[string]any{reactionEvent("U2", "+1"), reactionEvent("U2", "+1")},
+			reactions: []map[string]any{{}, {}, thumbsUp},
+			wantWaits: 2,
+			want:      TimpaniAwaitReactionResponse{Channel: "C1", TS: "1", User: "U2", Reaction: "+1"},
+		},
+		{
+			name:      "timeout",
+			timeout:   "1h",
+			wantPosts: 1,
+			wantWaits: 1,
+			want:      TimpaniAwaitReactionResponse{Channel: "C1", TS: "1", TimedOut: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := 0
+			post := func(_ context.Context, req ChatPostMessageRequest) (*slack.ChatPostMessageResponse, error) {
+				posts++
+				return &slack.ChatPostMessageResponse{Channel: req.Channel, TS: "1"}, nil
+			}
+			gets := 0
+			get := func(_ context.Context, req slack.ReactionsGetRequest) (*slack.ReactionsGetResponse, error) {
+				if req.Channel != "C1" || req.Timestamp != "1" || !req.Full {
+					return nil, errors.New("unexpected reactions.get request")
+				}
+				gets++
+				return &slack.ReactionsGetResponse{Message: tt.reactions[gets-1]}, nil
+			}
+			waits := 0
+			wait := func(ctx workflow.Context, req listeners.WaitForEventRequest) (map[string]any, error) {
+				waits++
+				if req.Signal != reactionAddedSignal || req.Filter["event.item.ts"] != "1" {
+					return nil, errors.New("unexpected wait request")
+				}
+				if waits > len(tt.events) {
+					_ = workflow.Sleep(ctx, 2*time.Hour)
+					return nil, errors.New("timeout")
+				}
+				return tt.events[waits-1], nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(post, activity.RegisterOptions{Name: slack.ChatPostMessageActivityName})
+			env.RegisterActivityWithOptions(get, activity.RegisterOptions{Name: slack.ReactionsGetActivityName})
+			env.RegisterWorkflowWithOptions(wait, workflow.RegisterOptions{Name: listeners.WaitForEventWorkflow})
+			env.RegisterWorkflow(a.TimpaniAwaitReactionWorkflow)
+
+			req := TimpaniAwaitReactionRequest{Channel: "C1", TS: tt.ts, Text: "React to approve", Users: []string{"U1", "U2"}, Reactions: []string{"+1"}, Timeout: tt.timeout}
+			env.ExecuteWorkflow(a.TimpaniAwaitReactionWorkflow, req)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("TimpaniAwaitReactionWorkflow() error = %v", err)
+			}
+			got := new(TimpaniAwaitReactionResponse)
+			if err := env.GetWorkflowResult(got); err != nil {
+				t.Fatal(err)
+			}
+
+			got.Event = nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("TimpaniAwaitReactionWorkflow() = %+v, want %+v", got, tt.want)
+			}
+			if posts != tt.wantPosts {
+				t.Errorf("ChatPostMessageActivity calls = %d, want %d", posts, tt.wantPosts)
+			}
+			if waits != tt.wantWaits {
+				t.Errorf("WaitForEventWorkflow calls = %d, want %d", waits, tt.wantWaits)
+			}
+		})
+	}
+}
@@ -9,23 +9,36 @@ import (
 	"go.temporal.io/sdk/workflow"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/taskqueue"
 	"github.com/tzrikka/timpani/internal/thrippy"
 )
 
 type API struct {
-	thrippy thrippy.LinkClient
+	thrippy   thrippy.LinkClient
+	identity  identityCache
+	taskQueue string // For activities of Timpani workflows, see [taskqueue.Activities].
 }
 
 // Register exposes Temporal activities and workflows via the Timpani worker.
-func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
-	id, ok := thrippy.LinkID(cmd, "Slack")
-	if !ok {
-		return
+// It returns an error, without registering anything, if the service's Thrippy
+// link ID is missing or invalid.
+func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) error {
+	id, err := thrippy.LinkID(cmd, "Slack")
+	if err != nil {
+		return err
 	}
 
-	a := API{thrippy: thrippy.NewLinkClient(ctx, id, cmd)}
+	a := &API{thrippy: thrippy.NewLinkClient(ctx, id, cmd), taskQueue: taskqueue.Service(cmd, "slack")}
+
+	registerActivity(w, a.AdminConversationsSearchActivity, AdminConversationsSearchActivityName)
+	registerActivity(w, a.AdminConversationsSetTeamsActivity, AdminConversationsSetTeamsActivityName)
+	registerActivity(w, a.AdminUsersListActivity, AdminUsersListActivityName)
+	registerActivity(w, a.AdminUsersSessionResetActivity, AdminUsersSessionResetActivityName)
+
+	registerActivity(w, a.AppsEventAuthorizationsListActivity, AppsEventAuthorizationsListActivityName)
 
 	registerActivity(w, a.AuthTestActivity, slack.AuthTestActivityName)
+	registerActivity(w, a.TimpaniSelfIdentityActivity, TimpaniSelfIdentityActivityName)
 
 	registerActivity(w, a.BookmarksAddActivity, slack.BookmarksAddActivityName)
 	registerActivity(w, a.BookmarksEditActivity, slack.BookmarksEditActivityName)
@@ -54,14 +67,22 @@ func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
 	registerActivity(w, a.ConversationsOpenActivity, slack.ConversationsOpenActivityName)
 	registerActivity(w, a.ConversationsRenameActivity, slack.ConversationsRenameActivityName)
 	registerActivity(w, a.ConversationsRepliesActivity, slack.ConversationsRepliesActivityName)
+	registerActivity(w, a.ConversationsRequestSharedInviteApproveActivity, ConversationsRequestSharedInviteApproveActivityName)
+	registerActivity(w, a.ConversationsRequestSharedInviteDenyActivity, ConversationsRequestSharedInviteDenyActivityName)
+	registerActivity(w, a.ConversationsRequestSharedInviteListActivity, ConversationsRequestSharedInviteListActivityName)
 	registerActivity(w, a.ConversationsSetPurposeActivity, slack.ConversationsSetPurposeActivityName)
 	registerActivity(w, a.ConversationsSetTopicActivity, slack.ConversationsSetTopicActivityName)
 
 	registerActivity(w, a.FilesCompleteUploadExternalActivity, slack.FilesCompleteUploadExternalActivityName)
 	registerActivity(w, a.FilesDeleteActivity, slack.FilesDeleteActivityName)
 	registerActivity(w, a.FilesGetUploadURLExternalActivity, slack.FilesGetUploadURLExternalActivityName)
+	registerActivity(w, a.FilesInfoActivity, FilesInfoActivityName)
+	registerActivity(w, a.FilesListActivity, FilesListActivityName)
+	registerActivity(w, a.TimpaniFilesDownloadActivity, TimpaniFilesDownloadActivityName)
 	registerActivity(w, a.TimpaniUploadExternalActivity, slack.TimpaniUploadExternalActivityName)
 
+	registerActivity(w, a.TimpaniInteractionResponseActivity, TimpaniInteractionResponseActivityName)
+
 	registerActivity(w, a.ReactionsAddActivity, slack.ReactionsAddActivityName)
 	registerActivity(w, a.ReactionsGetActivity, slack.ReactionsGetActivityName)
 	registerActivity(w, a.ReactionsListActivity, slack.ReactionsListActivityName)
@@ -78,6 +99,12 @@ func Register(ctx context.Context, cmd *cli.Command, w worker.Worker) {
 	registerActivity(w, a.UsersProfileGetActivity, slack.UsersProfileGetActivityName)
 
 	registerWorkflow(w, a.TimpaniPostApprovalWorkflow, slack.TimpaniPostApprovalWorkflowName)
+	registerWorkflow(w, a.TimpaniAwaitReactionWorkflow, TimpaniAwaitReactionWorkflowName)
+	registerWorkflow(w, a.TimpaniFetchThreadWorkflow, TimpaniFetchThreadWorkflowName)
+	registerWorkflow(w, a.TimpaniPostMessageWithFilesWorkflow, TimpaniPostMessageWithFilesWorkflowName)
+	registerWorkflow(w, a.TimpaniSharedInviteDecisionWorkflow, TimpaniSharedInviteDecisionWorkflowName)
+
+	return nil
 }
 
 func registerActivity(w worker.Worker, f any, name string) {
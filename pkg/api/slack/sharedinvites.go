@@ -0,0 +1,229 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+)
+
+// Shared invite API methods manage requests to invite external users to Slack Connect
+// channels, with a bot token that has the "conversations.connect:manage" scope:
+// https://docs.slack.dev/apis/slack-connect/.
+
+//revive:disable:exported
+const (
+	ConversationsRequestSharedInviteApproveActivityName = "slack.conversations.requestSharedInvite.approve"
+	ConversationsRequestSharedInviteDenyActivityName    = "slack.conversations.requestSharedInvite.deny"
+	ConversationsRequestSharedInviteListActivityName    = "slack.conversations.requestSharedInvite.list"
+) //revive:enable:exported
+
+// TimpaniSharedInviteDecisionWorkflowName is a Timpani-specific workflow, see [API.TimpaniSharedInviteDecisionWorkflow].
+const TimpaniSharedInviteDecisionWorkflowName = "slack.timpani.sharedInviteDecision"
+
+// sharedInviteErrors indicate that a shared invite was already handled,
+// so approving or denying it again will never succeed.
+var sharedInviteErrors = []string{"already_approved", "invite_not_found"}
+
+// sharedInviteError classifies the error in a shared invite API response, if there is one.
+func sharedInviteError(resp slack.Response, details ...any) error {
+	if resp.OK {
+		return nil
+	}
+	if slices.Contains(sharedInviteErrors, resp.Error) {
+		return temporal.NewNonRetryableApplicationError(resp.Error, "SlackAPIError", nil, details...)
+	}
+	return errors.New("Slack API error: " + resp.Error)
+}
+
+// ConversationsRequestSharedInviteApproveRequest is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.approve/
+//
+// IsExternalLimited restricts the external members of the channel from inviting other people
+// or changing its settings (default = true, i.e. external limited membership). ChannelID is
+// optional, and overrides the channel which the invite was originally requested for.
+type ConversationsRequestSharedInviteApproveRequest struct {
+	InviteID          string               `json:"invite_id"`
+	ChannelID         string               `json:"channel_id,omitempty"`
+	IsExternalLimited *bool                `json:"is_external_limited,omitempty"`
+	Message           *SharedInviteMessage `json:"message,omitempty"`
+}
+
+// SharedInviteMessage is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.approve/
+type SharedInviteMessage struct {
+	Text       string `json:"text"`
+	IsOverride bool   `json:"is_override,omitempty"`
+}
+
+// ConversationsRequestSharedInviteApproveResponse is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.approve/
+type ConversationsRequestSharedInviteApproveResponse struct {
+	slack.Response
+}
+
+// ConversationsRequestSharedInviteApproveActivity is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.approve/
+func (a *API) ConversationsRequestSharedInviteApproveActivity(ctx context.Context, req ConversationsRequestSharedInviteApproveRequest) (*ConversationsRequestSharedInviteApproveResponse, error) {
+	resp := new(ConversationsRequestSharedInviteApproveResponse)
+	if err := a.httpPost(ctx, ConversationsRequestSharedInviteApproveActivityName, req, resp); err != nil {
+		return nil, err
+	}
+
+	if err := sharedInviteError(resp.Response, req.InviteID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ConversationsRequestSharedInviteDenyRequest is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.deny/
+type ConversationsRequestSharedInviteDenyRequest struct {
+	InviteID string `json:"invite_id"`
+	Message  string `json:"message,omitempty"`
+}
+
+// ConversationsRequestSharedInviteDenyResponse is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.deny/
+type ConversationsRequestSharedInviteDenyResponse struct {
+	slack.Response
+}
+
+// ConversationsRequestSharedInviteDenyActivity is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.deny/
+func (a *API) ConversationsRequestSharedInviteDenyActivity(ctx context.Context, req ConversationsRequestSharedInviteDenyRequest) (*ConversationsRequestSharedInviteDenyResponse, error) {
+	resp := new(ConversationsRequestSharedInviteDenyResponse)
+	if err := a.httpPost(ctx, ConversationsRequestSharedInviteDenyActivityName, req, resp); err != nil {
+		return nil, err
+	}
+
+	if err := sharedInviteError(resp.Response, req.InviteID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ConversationsRequestSharedInviteListRequest is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.list/
+type ConversationsRequestSharedInviteListRequest struct {
+	UserID          string   `json:"user_id,omitempty"`
+	InviteIDs       []string `json:"invite_ids,omitempty"`
+	IncludeApproved bool     `json:"include_approved,omitempty"`
+	IncludeDenied   bool     `json:"include_denied,omitempty"`
+	IncludeExpired  bool     `json:"include_expired,omitempty"`
+
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// ConversationsRequestSharedInviteListResponse is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.list/
+//
+// To get the next page, set the request's Cursor field to the response metadata's NextCursor.
+type ConversationsRequestSharedInviteListResponse struct {
+	slack.Response
+
+	Invites []SharedInviteRequest `json:"invite_requests,omitempty"`
+}
+
+// SharedInviteRequest is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.list/
+type SharedInviteRequest struct {
+	ID                string         `json:"id"`
+	Channel           map[string]any `json:"channel,omitempty"`
+	InvitingUser      map[string]any `json:"inviting_user,omitempty"`
+	InvitingTeam      map[string]any `json:"inviting_team,omitempty"`
+	TargetUsers       []any          `json:"target_users,omitempty"`
+	IsExternalLimited bool           `json:"is_external_limited,omitempty"`
+	DateCreated       int64          `json:"date_created,omitempty"`
+	DateExpire        int64          `json:"date_expire,omitempty"`
+	Status            string         `json:"status,omitempty"`
+}
+
+// ConversationsRequestSharedInviteListActivity is based on:
+// https://docs.slack.dev/reference/methods/conversations.requestSharedInvite.list/
+func (a *API) ConversationsRequestSharedInviteListActivity(ctx context.Context, req ConversationsRequestSharedInviteListRequest) (*ConversationsRequestSharedInviteListResponse, error) {
+	resp := new(ConversationsRequestSharedInviteListResponse)
+	if err := a.httpPost(ctx, ConversationsRequestSharedInviteListActivityName, req, resp); err != nil {
+		return nil, err
+	}
+
+	if !resp.OK {
+		return nil, errors.New("Slack API error: " + resp.Error)
+	}
+	return resp, nil
+}
+
+// TimpaniSharedInviteDecisionRequest references a pending Slack Connect shared invite,
+// and the result of a [API.TimpaniPostApprovalWorkflow] about it. The other fields are
+// optional, and passed as-is to the approve or deny activity, respectively.
+type TimpaniSharedInviteDecisionRequest struct {
+	InviteID string                            `json:"invite_id"`
+	Decision slack.TimpaniPostApprovalResponse `json:"decision"`
+
+	IsExternalLimited *bool                `json:"is_external_limited,omitempty"`
+	ApproveMessage    *SharedInviteMessage `json:"approve_message,omitempty"`
+	DenyMessage       string               `json:"deny_message,omitempty"`
+}
+
+// TimpaniSharedInviteDecisionResponse reports whether the shared invite was approved
+// or denied, and the ID of the Slack user who made that decision.
+type TimpaniSharedInviteDecisionResponse struct {
+	Approved bool   `json:"approved"`
+	User     string `json:"user,omitempty"`
+}
+
+// TimpaniSharedInviteDecisionWorkflow is a convenience wrapper over
+// [API.ConversationsRequestSharedInviteApproveActivity] and
+// [API.ConversationsRequestSharedInviteDenyActivity]. It applies the
+// button selection in an approval decision to a pending shared invite.
+func (a *API) TimpaniSharedInviteDecisionWorkflow(ctx workflow.Context, req TimpaniSharedInviteDecisionRequest) (*TimpaniSharedInviteDecisionResponse, error) {
+	if req.InviteID == "" {
+		return nil, temporal.NewNonRetryableApplicationError("missing shared invite ID", "SlackSharedInviteError", nil)
+	}
+
+	event, err := Decode[BlockActionsPayload](req.Decision.InteractionEvent)
+	if err != nil && len(event.Actions) == 0 {
+		msg := "invalid approval decision: " + err.Error()
+		return nil, temporal.NewNonRetryableApplicationError(msg, "SlackSharedInviteError", err, req.InviteID)
+	}
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 5 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+
+	resp := &TimpaniSharedInviteDecisionResponse{User: event.User.ID}
+	switch v := event.Actions[0].Value; v {
+	case "approve":
+		resp.Approved = true
+		approveReq := ConversationsRequestSharedInviteApproveRequest{
+			InviteID:          req.InviteID,
+			IsExternalLimited: req.IsExternalLimited,
+			Message:           req.ApproveMessage,
+		}
+		if err := workflow.ExecuteActivity(ctx, ConversationsRequestSharedInviteApproveActivityName, approveReq).Get(ctx, nil); err != nil {
+			return nil, fmt.Errorf("failed to approve shared invite: %w", err)
+		}
+
+	case "deny":
+		denyReq := ConversationsRequestSharedInviteDenyRequest{InviteID: req.InviteID, Message: req.DenyMessage}
+		if err := workflow.ExecuteActivity(ctx, ConversationsRequestSharedInviteDenyActivityName, denyReq).Get(ctx, nil); err != nil {
+			return nil, fmt.Errorf("failed to deny shared invite: %w", err)
+		}
+
+	default:
+		msg := fmt.Sprintf("unexpected approval decision %q", v)
+		return nil, temporal.NewNonRetryableApplicationError(msg, "SlackSharedInviteError", nil, req.InviteID)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,154 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+func TestSharedInviteError(t *testing.T) {
+	tests := []struct {
+		name             string
+		resp             slack.Response
+		wantErr          bool
+		wantNonRetryable bool
+	}{
+		{
+			name: "ok",
+			resp: slack.Response{OK: true},
+		},
+		{
+			name:             "invite_not_found",
+			resp:             slack.Response{Error: "invite_not_found"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:             "already_approved",
+			resp:             slack.Response{Error: "already_approved"},
+			wantErr:          true,
+			wantNonRetryable: true,
+		},
+		{
+			name:    "ratelimited",
+			resp:    slack.Response{Error: "ratelimited"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sharedInviteError(tt.resp, "I123")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sharedInviteError() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var appErr *temporal.ApplicationError
+			nonRetryable := errors.As(err, &appErr) && appErr.NonRetryable()
+			if nonRetryable != tt.wantNonRetryable {
+				t.Errorf("sharedInviteError() non-retryable = %v, want %v", nonRetryable, tt.wantNonRetryable)
+			}
+		})
+	}
+}
+
+func TestTimpaniSharedInviteDecisionWorkflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		approveErr  error
+		wantApprove int
+		wantDeny    int
+		wantErr     bool
+	}{
+		{
+			name:        "approve",
+			value:       "approve",
+			wantApprove: 1,
+		},
+		{
+			name:     "deny",
+			value:    "deny",
+			wantDeny: 1,
+		},
+		{
+			name:    "unexpected_value",
+			value:   "maybe",
+			wantErr: true,
+		},
+		{
+			name:        "already_approved",
+			value:       "approve",
+			approveErr:  sharedInviteError(slack.Response{Error: "already_approved"}),
+			wantApprove: 1,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvals, denials := 0, 0
+			approve := func(_ context.Context, req ConversationsRequestSharedInviteApproveRequest) (*ConversationsRequestSharedInviteApproveResponse, error) {
+				approvals++
+				if req.InviteID != "I123" || req.IsExternalLimited == nil || !*req.IsExternalLimited {
+					return nil, temporal.NewNonRetryableApplicationError("unexpected approve request", "test", nil, req)
+				}
+				if tt.approveErr != nil {
+					return nil, tt.approveErr
+				}
+				return &ConversationsRequestSharedInviteApproveResponse{Response: slack.Response{OK: true}}, nil
+			}
+			deny := func(_ context.Context, req ConversationsRequestSharedInviteDenyRequest) (*ConversationsRequestSharedInviteDenyResponse, error) {
+				denials++
+				if req.InviteID != "I123" || req.Message != "Sorry" {
+					return nil, temporal.NewNonRetryableApplicationError("unexpected deny request", "test", nil, req)
+				}
+				return &ConversationsRequestSharedInviteDenyResponse{Response: slack.Response{OK: true}}, nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(approve, activity.RegisterOptions{Name: ConversationsRequestSharedInviteApproveActivityName})
+			env.RegisterActivityWithOptions(deny, activity.RegisterOptions{Name: ConversationsRequestSharedInviteDenyActivityName})
+			env.RegisterWorkflow(a.TimpaniSharedInviteDecisionWorkflow)
+
+			payload := map[string]any{
+				"type":    "block_actions",
+				"user":    map[string]any{"id": "U123"},
+				"actions": []any{map[string]any{"type": "button", "action_id": "id1_abc", "value": tt.value}},
+			}
+			env.ExecuteWorkflow(a.TimpaniSharedInviteDecisionWorkflow, TimpaniSharedInviteDecisionRequest{
+				InviteID:          "I123",
+				Decision:          slack.TimpaniPostApprovalResponse{InteractionEvent: payload},
+				IsExternalLimited: new(true),
+				DenyMessage:       "Sorry",
+			})
+
+			if approvals != tt.wantApprove || denials != tt.wantDeny {
+				t.Errorf("approve/deny calls = %d/%d, want %d/%d", approvals, denials, tt.wantApprove, tt.wantDeny)
+			}
+			err := env.GetWorkflowError()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TimpaniSharedInviteDecisionWorkflow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := new(TimpaniSharedInviteDecisionResponse)
+			if err := env.GetWorkflowResult(got); err != nil {
+				t.Fatal(err)
+			}
+			want := TimpaniSharedInviteDecisionResponse{Approved: tt.value == "approve", User: "U123"}
+			if *got != want {
+				t.Errorf("TimpaniSharedInviteDecisionWorkflow() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
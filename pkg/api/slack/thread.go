@@ -0,0 +1,141 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+)
+
+const (
+	// TimpaniFetchThreadWorkflowName is a Timpani-specific workflow, see [API.TimpaniFetchThreadWorkflow].
+	TimpaniFetchThreadWorkflowName = "slack.timpani.fetchThread"
+
+	// DefaultThreadMaxMessages is the default cap on the length of [TimpaniFetchThreadResponse].
+	DefaultThreadMaxMessages = 1000
+
+	// threadRepliesPageSize is based on the recommendation in:
+	// https://docs.slack.dev/reference/methods/conversations.replies/#arguments
+	threadRepliesPageSize = 200
+
+	// maxParallelUsersInfo limits the number of concurrent [API.UsersInfoActivity] calls,
+	// to stay well within the Tier 4 rate limit of the "users.info" API method.
+	maxParallelUsersInfo = 10
+)
+
+// TimpaniFetchThreadRequest identifies a Slack thread by its channel
+// and parent message. MaxMessages is optional (default = 1000).
+type TimpaniFetchThreadRequest struct {
+	Channel     string `json:"channel"`
+	TS          string `json:"ts"`
+	MaxMessages int    `json:"max_messages,omitempty"`
+}
+
+// TimpaniFetchThreadResponse contains the messages of a Slack thread, in chronological
+// order. Truncated indicates that the thread is longer than the request's cap.
+type TimpaniFetchThreadResponse struct {
+	Messages  []ThreadMessage `json:"messages"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// ThreadMessage is a message object, annotated with the real name and the 72x72
+// profile image URL of its author. These annotations are empty in messages without
+// an author (e.g. bot messages), and if the author's details are unavailable.
+type ThreadMessage struct {
+	Message map[string]any `json:"message"`
+
+	UserRealName string `json:"user_real_name,omitempty"`
+	UserImage    string `json:"user_image,omitempty"`
+}
+
+// TimpaniFetchThreadWorkflow is a convenience wrapper over [API.ConversationsRepliesActivity]
+// (all pages, up to the request's cap) and [API.UsersInfoActivity] (once per distinct author,
+// in batches of parallel calls). It returns all the messages in a thread, with user details.
+func (a *API) TimpaniFetchThreadWorkflow(ctx workflow.Context, req TimpaniFetchThreadRequest) (*TimpaniFetchThreadResponse, error) {
+	if req.MaxMessages <= 0 {
+		req.MaxMessages = DefaultThreadMaxMessages
+	}
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 10 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+
+	resp := new(TimpaniFetchThreadResponse)
+	repliesReq := slack.ConversationsRepliesRequest{Channel: req.Channel, TS: req.TS, Limit: threadRepliesPageSize}
+	for {
+		page := new(slack.ConversationsRepliesResponse)
+		if err := workflow.ExecuteActivity(ctx, slack.ConversationsRepliesActivityName, repliesReq).Get(ctx, page); err != nil {
+			return nil, fmt.Errorf("failed to get thread replies: %w", err)
+		}
+
+		for _, msg := range page.Messages {
+			if len(resp.Messages) == req.MaxMessages {
+				resp.Truncated = true
+				break
+			}
+			resp.Messages = append(resp.Messages, ThreadMessage{Message: msg})
+		}
+
+		if resp.Truncated || page.ResponseMetadata == nil || page.ResponseMetadata.NextCursor == "" {
+			break
+		}
+		repliesReq.Cursor = page.ResponseMetadata.NextCursor
+	}
+
+	users := threadUsers(ctx, resp.Messages)
+	for i, msg := range resp.Messages {
+		id, _ := msg.Message["user"].(string)
+		if u := users[id]; u != nil {
+			resp.Messages[i].UserRealName = u.RealName
+			if resp.Messages[i].UserRealName == "" {
+				resp.Messages[i].UserRealName = u.Profile.RealName
+			}
+			resp.Messages[i].UserImage = u.Profile.Image72
+		}
+	}
+
+	return resp, nil
+}
+
+// threadUsers returns the details of the distinct authors of the given messages. Each
+// user is looked up only once, in bounded batches of parallel activities. Failures are
+// logged but otherwise ignored, because missing details shouldn't fail the workflow.
+func threadUsers(ctx workflow.Context, msgs []ThreadMessage) map[string]*slack.User {
+	// Order of appearance, for deterministic workflow execution.
+	var ids []string
+	users := map[string]*slack.User{}
+	for _, msg := range msgs {
+		if id, _ := msg.Message["user"].(string); id != "" {
+			if _, ok := users[id]; !ok {
+				users[id] = nil
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	for start := 0; start < len(ids); start += maxParallelUsersInfo {
+		batch := ids[start:min(start+maxParallelUsersInfo, len(ids))]
+		futs := make([]workflow.Future, len(batch))
+		for i, id := range batch {
+			futs[i] = workflow.ExecuteActivity(ctx, slack.UsersInfoActivityName, slack.UsersInfoRequest{User: id})
+		}
+
+		for i, fut := range futs {
+			info := new(slack.UsersInfoResponse)
+			if err := fut.Get(ctx, info); err != nil {
+				workflow.GetLogger(ctx).Warn("failed to get Slack user info", slog.Any("error", err), slog.String("user_id", batch[i]))
+				continue
+			}
+			users[batch[i]] = info.User
+		}
+	}
+
+	return users
+}
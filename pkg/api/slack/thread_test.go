@@ -0,0 +1,119 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+func TestTimpaniFetchThreadWorkflow(t *testing.T) {
+	// 2 pages, 25 messages by 13 distinct users (U0-U11 and a missing one), and 1 bot message.
+	pages := map[string][]map[string]any{"": {{"ts": "1", "user": "U0"}, {"ts": "2", "bot_id": "B1"}}, "next": nil}
+	for i := range 23 {
+		user := fmt.Sprintf("U%d", i%12)
+		if i == 22 {
+			user = "U_MISSING"
+		}
+		pages["next"] = append(pages["next"], map[string]any{"ts": fmt.Sprintf("%d", i+3), "user": user})
+	}
+
+	tests := []struct {
+		name          string
+		maxMessages   int
+		wantMessages  int
+		wantTruncated bool
+		wantLookups   int
+	}{
+		{
+			name:         "entire_thread",
+			wantMessages: 25,
+			wantLookups:  13,
+		},
+		{
+			name:          "truncated_thread",
+			maxMessages:   5,
+			wantMessages:  5,
+			wantTruncated: true,
+			wantLookups:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies := func(_ context.Context, req slack.ConversationsRepliesRequest) (*slack.ConversationsRepliesResponse, error) {
+				if req.Channel != "C1" || req.TS != "1" {
+					return nil, fmt.Errorf("unexpected replies request: %v", req)
+				}
+				resp := &slack.ConversationsRepliesResponse{Messages: pages[req.Cursor]}
+				if req.Cursor == "" {
+					resp.ResponseMetadata = &slack.ResponseMetadata{NextCursor: "next"}
+				}
+				return resp, nil
+			}
+
+			var mu sync.Mutex
+			lookups := map[string]int{}
+			usersInfo := func(_ context.Context, req slack.UsersInfoRequest) (*slack.UsersInfoResponse, error) {
+				mu.Lock()
+				lookups[req.User]++
+				mu.Unlock()
+				if req.User == "U_MISSING" {
+					return nil, errors.New("Slack API error: user_not_found")
+				}
+				u := &slack.User{ID: req.User, RealName: "Name " + req.User}
+				u.Profile.Image72 = "https://example.com/" + req.User
+				return &slack.UsersInfoResponse{User: u}, nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(replies, activity.RegisterOptions{Name: slack.ConversationsRepliesActivityName})
+			env.RegisterActivityWithOptions(usersInfo, activity.RegisterOptions{Name: slack.UsersInfoActivityName})
+			env.RegisterWorkflow(a.TimpaniFetchThreadWorkflow)
+
+			env.ExecuteWorkflow(a.TimpaniFetchThreadWorkflow, TimpaniFetchThreadRequest{Channel: "C1", TS: "1", MaxMessages: tt.maxMessages})
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("TimpaniFetchThreadWorkflow() error = %v", err)
+			}
+			got := new(TimpaniFetchThreadResponse)
+			if err := env.GetWorkflowResult(got); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.Messages) != tt.wantMessages {
+				t.Errorf("TimpaniFetchThreadWorkflow() = %d messages, want %d", len(got.Messages), tt.wantMessages)
+			}
+			if got.Truncated != tt.wantTruncated {
+				t.Errorf("TimpaniFetchThreadWorkflow() truncated = %v, want %v", got.Truncated, tt.wantTruncated)
+			}
+			if len(lookups) != tt.wantLookups {
+				t.Errorf("distinct user lookups = %d, want %d", len(lookups), tt.wantLookups)
+			}
+			for user, n := range lookups {
+				if n > 1 && user != "U_MISSING" { // Failed lookups are retried by Temporal.
+					t.Errorf("user %s lookups = %d, want 1", user, n)
+				}
+			}
+
+			want := []ThreadMessage{
+				{Message: map[string]any{"ts": "1", "user": "U0"}, UserRealName: "Name U0", UserImage: "https://example.com/U0"},
+				{Message: map[string]any{"ts": "2", "bot_id": "B1"}},
+			}
+			if !reflect.DeepEqual(got.Messages[:2], want) {
+				t.Errorf("TimpaniFetchThreadWorkflow() = %v, want %v", got.Messages[:2], want)
+			}
+			if last := got.Messages[len(got.Messages)-1]; !tt.wantTruncated && last.UserRealName != "" {
+				t.Errorf("message of missing user: real name = %q, want empty", last.UserRealName)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package slack
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+	"github.com/tzrikka/timpani/pkg/api/slack/blocks"
+)
+
+const (
+	// TimpaniPostMessageWithFilesWorkflowName is a Timpani-specific workflow, see [API.TimpaniPostMessageWithFilesWorkflow].
+	TimpaniPostMessageWithFilesWorkflowName = "slack.timpani.postMessageWithFiles"
+
+	// fileProcessingPolls limits the number of [API.FilesInfoActivity] calls per uploaded
+	// file, with exponential backoff between them (1s, 2s, 4s, 8s, 8s, ...), so the
+	// workflow waits for up to about a minute for Slack to process each file.
+	fileProcessingPolls        = 10
+	fileProcessingInitialDelay = time.Second
+	fileProcessingMaxDelay     = 8 * time.Second
+
+	defaultUploadMimeType = "application/octet-stream"
+)
+
+// TimpaniPostMessageWithFilesRequest specifies a message to post, and files to upload and
+// reference in it. The files' content is passed through Temporal, so their total size should
+// be well within Temporal's payload size limit (2 MiB by default). Each file's MimeType is
+// optional (default = "application/octet-stream").
+type TimpaniPostMessageWithFilesRequest struct {
+	ChatPostMessageRequest
+
+	Files []UploadFile `json:"files"`
+}
+
+// UploadFile is a file to upload, in a [TimpaniPostMessageWithFilesRequest].
+type UploadFile struct {
+	Filename string `json:"filename"`
+	Title    string `json:"title,omitempty"`
+	AltTxt   string `json:"alt_txt,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Content  []byte `json:"content"`
+}
+
+// TimpaniPostMessageWithFilesResponse identifies the posted message, and contains the details
+// of the files which are referenced in it, as well as the files which the workflow failed to
+// upload or process (and therefore omitted from the message), with the reasons.
+type TimpaniPostMessageWithFilesResponse struct {
+	Channel     string       `json:"channel"`
+	TS          string       `json:"ts"`
+	Files       []slack.File `json:"files,omitempty"`
+	FailedFiles []FailedFile `json:"failed_files,omitempty"`
+}
+
+// FailedFile is a file which [API.TimpaniPostMessageWithFilesWorkflow] failed to upload or process.
+type FailedFile struct {
+	Filename string `json:"filename"`
+	Error    string `json:"error"`
+}
+
+// TimpaniPostMessageWithFilesWorkflow is a convenience wrapper over the 3 steps of uploading
+// files ([API.FilesGetUploadURLExternalActivity], [API.TimpaniUploadExternalActivity], and
+// [API.FilesCompleteUploadExternalActivity]), [API.FilesInfoActivity], and
+// [API.ChatPostMessageActivity]. It uploads all the files, waits for Slack to process them
+// (because completing uploads is asynchronous), and posts the message with their permalinks.
+//
+// Files which fail to upload or to be processed don't fail the workflow: they are omitted
+// from the message, and reported in the response. Only a failure to post the message does.
+func (a *API) TimpaniPostMessageWithFilesWorkflow(
+	ctx workflow.Context,
+	req TimpaniPostMessageWithFilesRequest,
+) (*TimpaniPostMessageWithFilesResponse, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           taskqueue.Activities(ctx, a.taskQueue),
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+
+	resp := new(TimpaniPostMessageWithFilesResponse)
+	var uploaded []slack.File
+	names := map[string]string{} // File ID -> filename.
+	for _, f := range req.Files {
+		id, err := uploadFile(ctx, f)
+		if err != nil {
+			resp.FailedFiles = append(resp.FailedFiles, FailedFile{Filename: f.Filename, Error: err.Error()})
+			continue
+		}
+		uploaded = append(uploaded, slack.File{ID: id, Title: cmp.Or(f.Title, f.Filename)})
+		names[id] = f.Filename
+	}
+
+	if len(uploaded) > 0 {
+		completeReq := slack.FilesCompleteUploadExternalRequest{Files: uploaded}
+		if err := workflow.ExecuteActivity(ctx, slack.FilesCompleteUploadExternalActivityName, completeReq).Get(ctx, nil); err != nil {
+			err = fmt.Errorf("failed to complete file upload: %w", err)
+			for _, f := range uploaded {
+				resp.FailedFiles = append(resp.FailedFiles, FailedFile{Filename: names[f.ID], Error: err.Error()})
+			}
+			uploaded = nil
+		}
+	}
+
+	for _, f := range uploaded {
+		file, err := waitForFile(ctx, f.ID)
+		if err != nil {
+			resp.FailedFiles = append(resp.FailedFiles, FailedFile{Filename: names[f.ID], Error: err.Error()})
+			continue
+		}
+		resp.Files = append(resp.Files, *file)
+	}
+
+	postReq := req.ChatPostMessageRequest
+	if postReq.IdempotencyKey == "" {
+		postReq.IdempotencyKey = workflow.GetInfo(ctx).WorkflowExecution.ID + "/message"
+	}
+	if err := embedFileLinks(&postReq, resp.Files); err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "error", err)
+	}
+
+	msg := new(slack.ChatPostMessageResponse)
+	if err := workflow.ExecuteActivity(ctx, slack.ChatPostMessageActivityName, postReq).Get(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to post chat message: %w", err)
+	}
+
+	resp.Channel, resp.TS = msg.Channel, msg.TS
+	return resp, nil
+}
+
+// uploadFile performs the first 2 steps of uploading a file to Slack, and returns its ID.
+func uploadFile(ctx workflow.Context, f UploadFile) (string, error) {
+	urlReq := slack.FilesGetUploadURLExternalRequest{Length: len(f.Content), Filename: f.Filename, AltTxt: f.AltTxt}
+	urlResp := new(slack.FilesGetUploadURLExternalResponse)
+	if err := workflow.ExecuteActivity(ctx, slack.FilesGetUploadURLExternalActivityName, urlReq).Get(ctx, urlResp); err != nil {
+		return "", fmt.Errorf("failed to get file upload URL: %w", err)
+	}
+
+	uploadReq := slack.TimpaniUploadExternalRequest{URL: urlResp.UploadURL, MimeType: cmp.Or(f.MimeType, defaultUploadMimeType), Content: f.Content}
+	if err := workflow.ExecuteActivity(ctx, slack.TimpaniUploadExternalActivityName, uploadReq).Get(ctx, nil); err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return urlResp.FileID, nil
+}
+
+// waitForFile polls [API.FilesInfoActivity] with exponential backoff,
+// until Slack finishes processing an uploaded file, or gives up.
+func waitForFile(ctx workflow.Context, id string) (*slack.File, error) {
+	delay := fileProcessingInitialDelay
+	for i := range fileProcessingPolls {
+		if i > 0 {
+			if err := workflow.Sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			delay = min(delay*2, fileProcessingMaxDelay)
+		}
+
+		info := new(FilesInfoResponse)
+		if err := workflow.ExecuteActivity(ctx, FilesInfoActivityName, FilesInfoRequest{File: id}).Get(ctx, info); err != nil {
+			return nil, fmt.Errorf("failed to get file info: %w", err)
+		}
+		if fileProcessed(info.File) {
+			return info.File, nil
+		}
+	}
+
+	return nil, errors.New("timeout waiting for Slack to process the file")
+}
+
+// fileProcessed checks fields which Slack populates only after it processes an uploaded file.
+func fileProcessed(f *slack.File) bool {
+	return f != nil && f.Permalink != "" && f.MimeType != ""
+}
+
+// embedFileLinks appends the permalinks of the given files to the message, in the same
+// format as the message's text, so Slack displays the files along with the message. If
+// the message has blocks, it also appends the links to them, because then Slack displays
+// only the blocks, and uses the text only as a fallback (e.g. in notifications).
+func embedFileLinks(req *ChatPostMessageRequest, files []slack.File) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	if req.ConvertMarkdown {
+		req.Text = ConvertMarkdown(req.Text)
+		req.ConvertMarkdown = false
+	}
+
+	var mrkdwn, markdown []string
+	for _, f := range files {
+		name := cmp.Or(f.Title, f.Name, f.ID)
+		mrkdwn = append(mrkdwn, fmt.Sprintf("<%s|%s>", f.Permalink, mrkdwnEscaper.Replace(name)))
+		markdown = append(markdown, fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(name), f.Permalink))
+	}
+
+	if req.MarkdownText != "" {
+		req.MarkdownText = appendLines(req.MarkdownText, markdown)
+	} else {
+		req.Text = appendLines(req.Text, mrkdwn)
+	}
+
+	if len(req.Blocks) > 0 {
+		bs := make([]blocks.Block, len(mrkdwn))
+		for i, link := range mrkdwn {
+			bs[i] = blocks.Section(link)
+		}
+		links, err := blocks.Build(bs...)
+		if err != nil {
+			return fmt.Errorf("failed to build file link blocks: %w", err)
+		}
+		req.Blocks = append(req.Blocks, links...)
+	}
+
+	return nil
+}
+
+var (
+	// https://docs.slack.dev/messaging/formatting-message-text/#escaping
+	mrkdwnEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+)
+
+func appendLines(text string, lines []string) string {
+	links := strings.Join(lines, "\n")
+	if text == "" {
+		return links
+	}
+	return text + "\n\n" + links
+}
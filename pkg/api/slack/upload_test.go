@@ -0,0 +1,232 @@
+package slack
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani-api/pkg/slack"
+)
+
+func TestTimpaniPostMessageWithFilesWorkflow(t *testing.T) {
+	files := []UploadFile{
+		{Filename: "a.png", MimeType: "image/png", Content: []byte("a")},
+		{Filename: "b.txt", Title: "B", Content: []byte("bb")},
+		{Filename: "c.txt", Content: []byte("ccc")},
+	}
+
+	tests := []struct {
+		name          string
+		uploadErr     string // Filename.
+		completeErr   error
+		unprocessed   string // Filename.
+		wantText      string
+		wantFiles     []string
+		wantFailed    []string
+		wantInfoCalls int
+	}{
+		{
+			name:          "all_files",
+			wantText:      "Hello\n\n<https://slack.com/files/F_a.png|a.png>\n<https://slack.com/files/F_b.txt|B>\n<https://slack.com/files/F_c.txt|c.txt>",
+			wantFiles:     []string{"F_a.png", "F_b.txt", "F_c.txt"},
+			wantInfoCalls: 4, // 1 file is processed only on the second poll.
+		},
+		{
+			name:          "partial_upload_failure",
+			uploadErr:     "b.txt",
+			wantText:      "Hello\n\n<https://slack.com/files/F_a.png|a.png>\n<https://slack.com/files/F_c.txt|c.txt>",
+			wantFiles:     []string{"F_a.png", "F_c.txt"},
+			wantFailed:    []string{"b.txt"},
+			wantInfoCalls: 3,
+		},
+		{
+			name:        "complete_failure",
+			completeErr: temporal.NewNonRetryableApplicationError("invalid_arguments", "SlackAPIError", nil),
+			wantText:    "Hello",
+			wantFailed:  []string{"a.png", "b.txt", "c.txt"},
+		},
+		{
+			name:          "processing_timeout",
+			unprocessed:   "c.txt",
+			wantText:      "Hello\n\n<https://slack.com/files/F_a.png|a.png>\n<https://slack.com/files/F_b.txt|B>",
+			wantFiles:     []string{"F_a.png", "F_b.txt"},
+			wantFailed:    []string{"c.txt"},
+			wantInfoCalls: 2 + fileProcessingPolls,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getURL := func(_ context.Context, req slack.FilesGetUploadURLExternalRequest) (*slack.FilesGetUploadURLExternalResponse, error) {
+				return &slack.FilesGetUploadURLExternalResponse{UploadURL: "https://files.slack.com/upload/" + req.Filename, FileID: "F_" + req.Filename}, nil
+			}
+			upload := func(_ context.Context, req slack.TimpaniUploadExternalRequest) error {
+				if strings.HasSuffix(req.URL, "/"+tt.uploadErr) {
+					return temporal.NewNonRetryableApplicationError("upload error", "error", nil)
+				}
+				return nil
+			}
+			titles := map[string]string{}
+			complete := func(_ context.Context, req slack.FilesCompleteUploadExternalRequest) (*slack.FilesCompleteUploadExternalResponse, error) {
+				if tt.completeErr != nil {
+					return nil, tt.completeErr
+				}
+				for _, f := range req.Files {
+					titles[f.ID] = f.Title
+				}
+				return &slack.FilesCompleteUploadExternalResponse{Files: req.Files}, nil
+			}
+			var mu sync.Mutex
+			infoCalls := map[string]int{}
+			info := func(_ context.Context, req FilesInfoRequest) (*FilesInfoResponse, error) {
+				mu.Lock()
+				infoCalls[req.File]++
+				n := infoCalls[req.File]
+				mu.Unlock()
+				f := &slack.File{ID: req.File, Name: strings.TrimPrefix(req.File, "F_"), Title: titles[req.File]}
+				if req.File == "F_"+tt.unprocessed || (req.File == "F_c.txt" && n == 1) {
+					return &FilesInfoResponse{File: f}, nil
+				}
+				f.MimeType, f.Permalink = "text/plain", "https://slack.com/files/"+req.File
+				return &FilesInfoResponse{File: f}, nil
+			}
+			var posted ChatPostMessageRequest
+			post := func(_ context.Context, req ChatPostMessageRequest) (*slack.ChatPostMessageResponse, error) {
+				posted = req
+				return &slack.ChatPostMessageResponse{Channel: req.Channel, TS: "1"}, nil
+			}
+
+			a := &API{}
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterActivityWithOptions(getURL, activity.RegisterOptions{Name: slack.FilesGetUploadURLExternalActivityName})
+			env.RegisterActivityWithOptions(upload, activity.RegisterOptions{Name: slack.TimpaniUploadExternalActivityName})
+			env.RegisterActivityWithOptions(complete, activity.RegisterOptions{Name: slack.FilesCompleteUploadExternalActivityName})
+			env.RegisterActivityWithOptions(info, activity.RegisterOptions{Name: FilesInfoActivityName})
+			env.RegisterActivityWithOptions(post, activity.RegisterOptions{Name: slack.ChatPostMessageActivityName})
+			env.RegisterWorkflow(a.TimpaniPostMessageWithFilesWorkflow)
+
+			req := TimpaniPostMessageWithFilesRequest{Files: files}
+			req.Channel, req.Text = "C1", "Hello"
+			env.ExecuteWorkflow(a.TimpaniPostMessageWithFilesWorkflow, req)
+
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("TimpaniPostMessageWithFilesWorkflow() error = %v", err)
+			}
+			got := new(TimpaniPostMessageWithFilesResponse)
+			if err := env.GetWorkflowResult(got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Channel != "C1" || got.TS != "1" {
+				t.Errorf("TimpaniPostMessageWithFilesWorkflow() = %s/%s, want C1/1", got.Channel, got.TS)
+			}
+			if posted.Text != tt.wantText {
+				t.Errorf("posted message text = %q, want %q", posted.Text, tt.wantText)
+			}
+			if posted.IdempotencyKey == "" {
+				t.Error("posted message without an idempotency key")
+			}
+
+			var gotFiles, gotFailed []string
+			for _, f := range got.Files {
+				gotFiles = append(gotFiles, f.ID)
+			}
+			for _, f := range got.FailedFiles {
+				gotFailed = append(gotFailed, f.Filename)
+				if f.Error == "" {
+					t.Errorf("failed file %q without an error", f.Filename)
+				}
+			}
+			if !reflect.DeepEqual(gotFiles, tt.wantFiles) {
+				t.Errorf("TimpaniPostMessageWithFilesWorkflow() files = %v, want %v", gotFiles, tt.wantFiles)
+			}
+			if !reflect.DeepEqual(gotFailed, tt.wantFailed) {
+				t.Errorf("TimpaniPostMessageWithFilesWorkflow() failed files = %v, want %v", gotFailed, tt.wantFailed)
+			}
+
+			total := 0
+			for _, n := range infoCalls {
+				total += n
+			}
+			if total != tt.wantInfoCalls {
+				t.Errorf("FilesInfoActivity calls = %d, want %d", total, tt.wantInfoCalls)
+			}
+		})
+	}
+}
+
+func TestEmbedFileLinks(t *testing.T) {
+	files := []slack.File{
+		{ID: "F1", Title: "a <b> & c", Permalink: "https://slack.com/files/F1"},
+		{ID: "F2", Name: "[d].png", Permalink: "https://slack.com/files/F2"},
+	}
+
+	tests := []struct {
+		name  string
+		req   ChatPostMessageRequest
+		files []slack.File
+		want  ChatPostMessageRequest
+	}{
+		{
+			name: "no_files",
+			req:  postRequest("Hello", "", false, nil),
+			want: postRequest("Hello", "", false, nil),
+		},
+		{
+			name:  "text",
+			req:   postRequest("Hello", "", false, nil),
+			files: files,
+			want:  postRequest("Hello\n\n<https://slack.com/files/F1|a &lt;b&gt; &amp; c>\n<https://slack.com/files/F2|[d].png>", "", false, nil),
+		},
+		{
+			name:  "empty_text",
+			files: files[:1],
+			want:  postRequest("<https://slack.com/files/F1|a &lt;b&gt; &amp; c>", "", false, nil),
+		},
+		{
+			name:  "convert_markdown",
+			req:   postRequest("**Hello**", "", true, nil),
+			files: files[:1],
+			want:  postRequest("*Hello*\n\n<https://slack.com/files/F1|a &lt;b&gt; &amp; c>", "", false, nil),
+		},
+		{
+			name:  "markdown_text",
+			req:   postRequest("", "Hello", false, nil),
+			files: files,
+			want:  postRequest("", "Hello\n\n[a <b> & c](https://slack.com/files/F1)\n[\\[d\\].png](https://slack.com/files/F2)", false, nil),
+		},
+		{
+			name:  "blocks",
+			req:   postRequest("Hello", "", false, []map[string]any{{"type": "divider"}}),
+			files: files[:1],
+			want: postRequest("Hello\n\n<https://slack.com/files/F1|a &lt;b&gt; &amp; c>", "", false, []map[string]any{
+				{"type": "divider"},
+				{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "<https://slack.com/files/F1|a &lt;b&gt; &amp; c>"}},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.req
+			if err := embedFileLinks(&got, tt.files); err != nil {
+				t.Fatalf("embedFileLinks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("embedFileLinks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func postRequest(text, markdown string, convert bool, bs []map[string]any) ChatPostMessageRequest {
+	req := ChatPostMessageRequest{ConvertMarkdown: convert}
+	req.Text, req.MarkdownText, req.Blocks = text, markdown, bs
+	return req
+}
@@ -9,6 +9,7 @@ import (
 	"go.temporal.io/sdk/temporal"
 
 	"github.com/tzrikka/timpani-api/pkg/slack"
+	"github.com/tzrikka/timpani/pkg/http/client"
 )
 
 // UsersConversationsActivity is based on:
@@ -112,6 +113,11 @@ func (a *API) UsersListActivity(ctx context.Context, req slack.UsersListRequest)
 	if !resp.OK {
 		return nil, errors.New("Slack API error: " + resp.Error)
 	}
+
+	// Truncation isn't supported, because the cursor of the next page would skip the removed users.
+	if _, err := client.LimitResponseSize(slack.UsersListActivityName, resp, &resp.Members, false); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tzrikka/timpani/internal/redact"
+)
+
+// DefaultAuditFields are the top-level fields of request bodies which are recorded in audit
+// records (see [SetAuditLogger]), unless the request's [Provider] specifies other fields
+// for its endpoint. They identify the target of API calls, not their content.
+var DefaultAuditFields = []string{
+	"channel", "user", "users", "ts", "thread_ts", "id", "name", "state", "event",
+	"head", "base", "ref", "sha", "labels", "assignees", "reviewers",
+}
+
+// AuditFieldsFunc returns the top-level fields of request bodies which may be recorded in
+// audit records of API calls to the given endpoint (URL path), see [Provider]. It may return
+// nil to use the [DefaultAuditFields]. Fields with sensitive names are always redacted.
+type AuditFieldsFunc func(endpoint string) []string
+
+var auditLogger atomic.Pointer[slog.Logger]
+
+// SetAuditLogger enables audit records of mutating API calls (i.e. calls to [HTTPRequestFull]
+// with any HTTP method other than GET, unless they're marked with [WithIdempotent]). Audit
+// records are separate from debug logs, and are emitted regardless of the logger's level.
+// A nil logger disables them, which is the default.
+func SetAuditLogger(l *slog.Logger) {
+	auditLogger.Store(l)
+}
+
+// OpenAuditLog enables audit records (see [SetAuditLogger]) in an append-only file, with
+// a JSON object per line (NDJSON). An empty path disables them. The file is never closed.
+func OpenAuditLog(path string) error {
+	if path == "" {
+		SetAuditLogger(nil)
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //gosec:disable G304 // Configured by the admin.
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	SetAuditLogger(slog.New(slog.NewJSONHandler(f, nil)))
+	return nil
+}
+
+// audit emits an audit record of a mutating API call, if audit records are enabled (see
+// [SetAuditLogger]). It identifies the Temporal workflow that initiated the call, if the
+// context belongs to an activity, and summarizes the request body (see [auditSummary]).
+func audit(ctx context.Context, o *requestOpts, method, apiURL string, queryOrBody any, resp *Response, err error) {
+	l := auditLogger.Load()
+	if l == nil || method == http.MethodGet || o.idempotent {
+		return
+	}
+
+	endpoint := ""
+	if u, perr := url.Parse(apiURL); perr == nil {
+		endpoint = u.Path
+		u.RawQuery = ""
+		apiURL = u.Redacted()
+	} else {
+		apiURL = redact.URL(apiURL)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("provider", o.provider.Name),
+		slog.String("method", method),
+		slog.String("endpoint", apiURL),
+	}
+
+	if activity.IsActivity(ctx) {
+		info := activity.GetInfo(ctx)
+		attrs = append(attrs, slog.String("workflow_id", info.WorkflowExecution.ID),
+			slog.String("run_id", info.WorkflowExecution.RunID), slog.String("workflow_type", info.WorkflowType.Name),
+			slog.String("activity_type", info.ActivityType.Name))
+	}
+
+	var fields []string
+	if o.provider.AuditFields != nil {
+		fields = o.provider.AuditFields(endpoint)
+	}
+	if fields == nil {
+		fields = DefaultAuditFields
+	}
+	if summary := auditSummary(queryOrBody, fields); summary != nil {
+		attrs = append(attrs, slog.Any("request", summary))
+	}
+
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redact.Error(err).Error()))
+	}
+
+	l.LogAttrs(context.Background(), slog.LevelInfo, "outbound API call", attrs...)
+}
+
+// auditSummary returns the given fields of a request body, if they exist, with the values
+// of sensitive fields replaced by a [redact.Placeholder], at any depth. It returns nil if
+// the body is empty, or if it's not a JSON object or URL-encoded form (e.g. a file upload).
+func auditSummary(queryOrBody any, fields []string) map[string]any {
+	var body map[string]any
+	switch v := queryOrBody.(type) {
+	case nil, *MultipartBody:
+		return nil
+	case url.Values:
+		body = make(map[string]any, len(v))
+		for k := range v {
+			body[k] = v.Get(k)
+		}
+	case []byte:
+		if json.Unmarshal(v, &body) != nil {
+			return nil
+		}
+	default:
+		b, err := json.Marshal(v)
+		if err != nil || json.Unmarshal(b, &body) != nil {
+			return nil
+		}
+	}
+
+	summary := map[string]any{}
+	for _, f := range fields {
+		if v, ok := body[f]; ok {
+			summary[f] = v
+		}
+	}
+	if len(summary) == 0 {
+		return nil
+	}
+
+	return redact.JSON(summary).(map[string]any) //nolint:errcheck // Type conversion always succeeds.
+}
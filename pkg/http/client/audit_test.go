@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani/internal/redact"
+)
+
+func TestAuditSummary(t *testing.T) {
+	type body struct {
+		Channel string         `json:"channel"`
+		Token   string         `json:"token"`
+		Text    string         `json:"text"`
+		User    map[string]any `json:"user"`
+	}
+
+	tests := []struct {
+		name   string
+		body   any
+		fields []string
+		want   map[string]any
+	}{
+		{
+			name:   "struct",
+			body:   body{Channel: "C123", Token: "xoxb-secret", Text: "hello"},
+			fields: []string{"channel", "token"},
+			want:   map[string]any{"channel": "C123", "token": redact.Placeholder},
+		},
+		{
+			name:   "nested_token",
+			body:   body{User: map[string]any{"id": "U123", "access_token": "secret"}},
+			fields: []string{"user"},
+			want:   map[string]any{"user": map[string]any{"id": "U123", "access_token": redact.Placeholder}},
+		},
+		{
+			name:   "raw_json",
+			body:   []byte(`{"channel":"C123","client_secret":"secret","text":"hello"}`),
+			fields: []string{"channel", "client_secret"},
+			want:   map[string]any{"channel": "C123", "client_secret": redact.Placeholder},
+		},
+		{
+			name:   "form",
+			body:   url.Values{"name": {"a"}, "api_key": {"secret"}, "other": {"b"}},
+			fields: []string{"name", "api_key"},
+			want:   map[string]any{"name": "a", "api_key": redact.Placeholder},
+		},
+		{
+			name:   "no_allowed_fields",
+			body:   body{Channel: "C123", Text: "hello"},
+			fields: []string{"ts"},
+		},
+		{
+			name:   "not_json_object",
+			body:   []byte("plain text"),
+			fields: DefaultAuditFields,
+		},
+		{
+			name:   "no_body",
+			fields: DefaultAuditFields,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditSummary(tt.body, tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditSummary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPRequestFullAudit(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	if err := OpenAuditLog(path); err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	defer SetAuditLogger(nil)
+
+	p := WithProvider(Provider{Name: "test", AuditFields: func(endpoint string) []string {
+		if endpoint == "/api/chat.postMessage" {
+			return []string{"channel", "token"}
+		}
+		return nil
+	}})
+	body := map[string]any{"channel": "C123", "token": "xoxb-secret", "text": "hello"}
+
+	// Only the last 2 calls are mutating.
+	_, _ = HTTPRequestFull(t.Context(), http.MethodGet, s.URL+"/api/chat.postMessage", "xoxb-auth", "", "", nil, p)
+	_, _ = HTTPRequestFull(t.Context(), http.MethodPost, s.URL+"/api/chat.postMessage", "xoxb-auth", "", ContentJSON, body, p, WithIdempotent())
+	_, _ = HTTPRequestFull(t.Context(), http.MethodPost, s.URL+"/api/chat.postMessage?x=1", "xoxb-auth", "", ContentJSON, body, p)
+	_, _ = HTTPRequestFull(t.Context(), http.MethodDelete, s.URL+"/repos/o/r", "xoxb-auth", "", "", url.Values{"ref": {"main"}}, p)
+
+	b, err := os.ReadFile(path) //gosec:disable G304 // Test file.
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("secret")) || bytes.Contains(b, []byte("xoxb-auth")) || bytes.Contains(b, []byte("hello")) {
+		t.Errorf("audit log contains redacted data:\n%s", b)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log records = %d, want 2:\n%s", len(lines), b)
+	}
+
+	want := []map[string]any{
+		{
+			"provider": "test",
+			"method":   http.MethodPost,
+			"endpoint": s.URL + "/api/chat.postMessage",
+			"request":  map[string]any{"channel": "C123", "token": redact.Placeholder},
+			"status":   float64(http.StatusCreated),
+		},
+		{
+			"provider": "test",
+			"method":   http.MethodDelete,
+			"endpoint": s.URL + "/repos/o/r",
+			"request":  map[string]any{"ref": "main"},
+			"status":   float64(http.StatusNotFound),
+		},
+	}
+	for i, line := range lines {
+		got := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range want[i] {
+			if !reflect.DeepEqual(got[k], v) {
+				t.Errorf("audit record %d: %s = %v, want %v", i, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestAuditWorkflowIDs(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer s.Close()
+
+	buf := new(bytes.Buffer)
+	SetAuditLogger(slog.New(slog.NewJSONHandler(buf, nil)))
+	defer SetAuditLogger(nil)
+
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(func(ctx context.Context) error {
+		_, err := HTTPRequestFull(ctx, http.MethodPost, s.URL, "", "", ContentJSON, nil)
+		return err
+	}, activity.RegisterOptions{Name: "test.activity"})
+	if _, err := env.ExecuteActivity("test.activity"); err != nil {
+		t.Fatalf("ExecuteActivity() error = %v", err)
+	}
+
+	got := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"workflow_id", "run_id", "workflow_type"} {
+		if got[k] == nil || got[k] == "" {
+			t.Errorf("audit record %s = %v, want non-empty", k, got[k])
+		}
+	}
+	if got["activity_type"] != "test.activity" {
+		t.Errorf("audit record activity_type = %v, want %q", got["activity_type"], "test.activity")
+	}
+}
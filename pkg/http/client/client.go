@@ -12,19 +12,33 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/redact"
+	"github.com/tzrikka/timpani/pkg/otel"
 )
 
 const (
-	Timeout = 3 * time.Second
-	MaxSize = 3 << 20 // 3 MiB.
+	DefaultTimeout = 30 * time.Second
+	Timeout        = 3 * time.Second // Short timeout for auxiliary calls, e.g. health checks.
+	MaxSize        = 3 << 20         // 3 MiB.
+	MaxStreamSize  = 100 << 20       // 100 MiB, default for [StreamToFile] callers.
+
+	DefaultRetries = 2
+	MaxRetries     = 10
+	RetryBaseDelay = 200 * time.Millisecond
+	MaxRetryDelay  = 30 * time.Second // Before jitter, and unless the server asks for more.
 
 	AcceptJSON = "application/json"
 	AcceptText = "text/plain"
@@ -33,38 +47,323 @@ const (
 	ContentJSON = "application/json; charset=utf-8"
 )
 
-// HTTPRequest sends an HTTP request to an external API service.
+// RequestOpt customizes a single call to [HTTPRequest].
+type RequestOpt func(*requestOpts)
+
+type requestOpts struct {
+	timeout    time.Duration
+	retries    int
+	idempotent bool
+	provider   Provider
+	headers    http.Header
+}
+
+func newRequestOpts(opts ...RequestOpt) *requestOpts {
+	o := &requestOpts{retries: DefaultRetries}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTimeout sets the maximum duration of a single call to [HTTPRequest], overriding the
+// deadline of its context (unless the context's deadline is earlier), or the [DefaultTimeout].
+func WithTimeout(d time.Duration) RequestOpt {
+	return func(o *requestOpts) {
+		o.timeout = d
+	}
+}
+
+var fileTransferTimeout atomic.Int64
+
+// SetFileTransferTimeout sets the timeout of [WithFileTransferTimeout]
+// (0 = the deadline of the request's context, or the [DefaultTimeout]).
+func SetFileTransferTimeout(d time.Duration) {
+	fileTransferTimeout.Store(int64(max(d, 0)))
+}
+
+// WithFileTransferTimeout is like [WithTimeout], but for file uploads and downloads,
+// which may take much longer than other API calls, with the timeout that was set by
+// [SetFileTransferTimeout], if any. It should precede [WithTimeout], if both are used.
+func WithFileTransferTimeout() RequestOpt {
+	return WithTimeout(time.Duration(fileTransferTimeout.Load()))
+}
+
+// WithRetries sets the maximum number of automatic retries of a single call to
+// [HTTPRequest], instead of [DefaultRetries]. Zero or a negative value disables
+// them, and values above [MaxRetries] are capped.
+func WithRetries(n int) RequestOpt {
+	return func(o *requestOpts) {
+		o.retries = min(max(n, 0), MaxRetries)
+	}
+}
+
+// WithIdempotent marks a request as safe to retry automatically even though
+// its HTTP method isn't GET or DELETE (e.g. a POST that only reads data).
+func WithIdempotent() RequestOpt {
+	return func(o *requestOpts) {
+		o.idempotent = true
+	}
+}
+
+// WithHeader adds a custom HTTP header to a request, e.g. an API version. It may
+// be used multiple times, but it cannot override the headers that [HTTPRequest]
+// sets on its own (auth, MIME types, and encodings).
+func WithHeader(key, value string) RequestOpt {
+	return func(o *requestOpts) {
+		if o.headers == nil {
+			o.headers = http.Header{}
+		}
+		o.headers.Add(key, value)
+	}
+}
+
+// Provider customizes the behavior of [HTTPRequestFull] for a specific API provider.
+type Provider struct {
+	// Name identifies the provider (e.g. "github"), to enable provider-specific
+	// behavior such as gzip-compressed request bodies (see [Flags]).
+	Name string
+
+	// ErrorDetails extracts provider-specific details from HTTP error
+	// responses into [APIError]s. It is optional, and may return empty strings.
+	ErrorDetails ErrorDetailsFunc
+
+	// AuditFields specifies which request body fields may be recorded in audit
+	// records (see [SetAuditLogger]). It is optional, the default is [DefaultAuditFields].
+	AuditFields AuditFieldsFunc
+}
+
+// ErrorDetailsFunc extracts provider-specific details from HTTP error responses, see [Provider].
+type ErrorDetailsFunc func(header http.Header, body []byte) (providerCode, requestID string)
+
+// WithProvider specifies the API provider of a request, to enable provider-specific behavior.
+func WithProvider(p Provider) RequestOpt {
+	return func(o *requestOpts) {
+		o.provider = p
+	}
+}
+
+// Response contains the details of an HTTP response from an external API service.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// RetryAfter is the number of seconds to wait before retrying a rate-limited
+	// request, based on the response's status code and headers, or 0 otherwise.
+	RetryAfter int
+}
+
+// HTTPRequest sends an HTTP request to an external API service. It is a thin
+// wrapper of [HTTPRequestFull] for callers that don't need the response's
+// status code, and don't need the headers and body of error responses.
+//
+// On HTTP 429 (Too Many Requests) responses, the third return value
+// contains the number of seconds to wait before retrying the request.
+func HTTPRequest(
+	ctx context.Context,
+	method, apiURL, auth, accept, contentType string,
+	queryOrBody any,
+	opts ...RequestOpt,
+) ([]byte, http.Header, int, error) {
+	resp, err := HTTPRequestFull(ctx, method, apiURL, auth, accept, contentType, queryOrBody, opts...)
+	if err != nil {
+		if resp != nil {
+			return nil, nil, resp.RetryAfter, err
+		}
+		return nil, nil, 0, err
+	}
+
+	return resp.Body, resp.Header, 0, nil
+}
+
+// HTTPRequestFull sends an HTTP request to an external API service.
+//
+// The queryOrBody parameter may be nil, [url.Values], a []byte slice, a [*MultipartBody]
+// (in which case the contentType parameter is ignored), or any struct that can be
+// encoded as JSON.
 //
-// The queryOrBody parameter may be nil, [url.Values], a []byte slice,
-// or any struct that can be encoded as JSON.
+// The request's deadline is the context's deadline (e.g. a Temporal activity's timeout),
+// unless it's overridden with [WithTimeout]. If neither of them is set, the deadline is
+// based on the [DefaultTimeout].
+//
+// Idempotent requests (GET, DELETE, and others marked with [WithIdempotent]) are retried
+// automatically up to [DefaultRetries] times (see [WithRetries]) on network errors, HTTP 429,
+// and 5xx responses, with a jittered exponential backoff, as long as the deadline allows it.
 //
 // Some errors (failure to construct a request or decode a response body)
 // are returned as non-retryable [temporal.ApplicationError]s.
 //
-// On HTTP 429 (Too Many Requests) responses, the third return value
-// contains the number of seconds to wait before retrying the request.
+// HTTP error responses (status code 400 or higher) are returned as [APIError]s,
+// along with a non-nil [Response], to let callers inspect their details.
+//
+// Mutating requests are also recorded in audit records, if they're enabled (see [SetAuditLogger]).
 //
 // [temporal.ApplicationError]: https://pkg.go.dev/go.temporal.io/temporal#ApplicationError
-func HTTPRequest(ctx context.Context, method, apiURL, auth, accept, contentType string, queryOrBody any) ([]byte, http.Header, int, error) {
-	// Construct the request.
-	if method == http.MethodGet || method == http.MethodDelete {
-		if query, ok := queryOrBody.(url.Values); ok && len(query) > 0 {
-			apiURL = fmt.Sprintf("%s?%s", apiURL, query.Encode())
+func HTTPRequestFull(
+	ctx context.Context,
+	method, apiURL, auth, accept, contentType string,
+	queryOrBody any,
+	opts ...RequestOpt,
+) (*Response, error) {
+	o := newRequestOpts(opts...)
+	resp, err := sendWithRetries(ctx, o, method, apiURL, auth, accept, contentType, queryOrBody)
+	audit(ctx, o, method, apiURL, queryOrBody, resp, err)
+	return resp, err
+}
+
+// sendWithRetries sends an HTTP request, and retries it if it's idempotent, see [HTTPRequestFull].
+func sendWithRetries(ctx context.Context, o *requestOpts, method, apiURL, auth, accept, contentType string, queryOrBody any) (*Response, error) {
+	ctx, cancel := requestContext(ctx, o)
+	defer cancel()
+
+	apiURL = withQuery(method, apiURL, queryOrBody)
+	_, streamed := queryOrBody.(*MultipartBody) // Readers cannot be rewound.
+	maxAttempts := 1
+	if !streamed && (o.idempotent || method == http.MethodGet || method == http.MethodDelete) {
+		maxAttempts += max(0, o.retries)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := sendRequest(ctx, o, method, apiURL, auth, accept, contentType, queryOrBody)
+		if err == nil || attempt >= maxAttempts || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := retryDelay(attempt, resp)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err // Give up now rather than after the deadline.
+		}
+
+		reason := err.Error()
+		if resp != nil {
+			reason = http.StatusText(resp.StatusCode)
+		}
+		host := ""
+		if u, perr := url.Parse(apiURL); perr == nil {
+			host = u.Host
+		}
+
+		logger.FromContext(ctx).Warn("retrying HTTP request", slog.String("method", method), slog.String("host", host),
+			slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.String("reason", reason))
+		otel.IncrementHTTPRetryCounter(time.Now().UTC(), method, host, attempt, reason)
+
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(delay):
 		}
 	}
+}
 
-	reqBody, err := requestBody(method, queryOrBody)
+// sendRequest sends a single HTTP request, and reads its response.
+func sendRequest(ctx context.Context, o *requestOpts, method, apiURL, auth, accept, contentType string, queryOrBody any) (*Response, error) {
+	req, err := newRequest(ctx, o, method, apiURL, auth, accept, contentType, queryOrBody)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, Timeout)
-	defer cancel()
+	// Send the request, and read the response.
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %w", redact.Error(err))
+	}
+	defer resp.Body.Close()
+
+	if err := decompress(resp); err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+
+	return parseResponse(resp, respBody, o.provider.ErrorDetails)
+}
+
+// retryable checks whether a failed HTTP request is worth retrying: network
+// errors, HTTP 429 (Too Many Requests), and 5xx server errors. Other 4xx
+// errors and non-retryable errors (e.g. failure to construct a request) are
+// not, and neither is anything after the request's context is done.
+func retryable(ctx context.Context, resp *Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.NonRetryable() {
+		return false
+	}
+
+	if resp == nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryDelay returns a jittered exponential backoff delay before the next attempt, which is
+// in the range [base/2, base) where base = [RetryBaseDelay] * 2^(attempt-1), up to [MaxRetryDelay],
+// unless the server asked for a longer delay in a rate-limiting response.
+func retryDelay(attempt int, resp *Response) time.Duration {
+	base := RetryBaseDelay
+	for i := 1; i < attempt && base < MaxRetryDelay; i++ {
+		base *= 2 // Not a shift by the attempt number, which may overflow.
+	}
+	base = min(base, MaxRetryDelay)
+	d := base/2 + rand.N(base/2) //gosec:disable G404 // Jitter doesn't need a secure random source.
+
+	if resp != nil && resp.RetryAfter > 0 {
+		d = max(d, time.Duration(resp.RetryAfter)*time.Second)
+	}
+
+	return d
+}
+
+// withQuery appends the given query parameters (if any) to the URL of a GET or DELETE request.
+func withQuery(method, apiURL string, queryOrBody any) string {
+	if method == http.MethodGet || method == http.MethodDelete {
+		if query, ok := queryOrBody.(url.Values); ok && len(query) > 0 {
+			return fmt.Sprintf("%s?%s", apiURL, query.Encode())
+		}
+	}
+	return apiURL
+}
+
+// newRequest constructs an HTTP request, including its body and headers.
+func newRequest(ctx context.Context, o *requestOpts, method, apiURL, auth, accept, contentType string, queryOrBody any) (*http.Request, error) {
+	var reqBody io.Reader
+	compressed := false
+	if mb, ok := queryOrBody.(*MultipartBody); ok {
+		var pr *io.PipeReader
+		pr, contentType = mb.reader()
+		reqBody = pr
+	} else {
+		var err error
+		if reqBody, err = requestBody(method, queryOrBody); err != nil {
+			return nil, err
+		}
+		if reqBody != http.NoBody && GzipRequests(o.provider.Name) {
+			if reqBody, err = compress(reqBody); err != nil {
+				return nil, err
+			}
+			compressed = true
+		}
+	}
 
 	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
 	if err != nil {
-		msg := "failed to construct HTTP request: " + err.Error()
-		return nil, nil, 0, temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), err)
+		if pr, ok := reqBody.(*io.PipeReader); ok {
+			_ = pr.Close() // Stop the encoding goroutine.
+		}
+		msg := "failed to construct HTTP request: " + redact.Error(err).Error()
+		return nil, temporal.NewNonRetryableApplicationError(msg, fmt.Sprintf("%T", err), redact.Error(err))
+	}
+
+	for k, vs := range o.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
 	}
 
 	// Set HTTP headers for auth and request/response MIME types.
@@ -83,19 +382,24 @@ func HTTPRequest(ctx context.Context, method, apiURL, auth, accept, contentType
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	// Send the request, and read the response.
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, nil, 0, fmt.Errorf("failed to send HTTP request: %w", err)
+	// Explicit, so it doesn't depend on the transport (see [decompress]).
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize))
-	if err != nil {
-		return nil, nil, 0, fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
+	return req, nil
+}
 
-	return parseResponse(resp, respBody)
+// requestContext returns a copy of the given context with the appropriate deadline.
+func requestContext(ctx context.Context, o *requestOpts) (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultTimeout)
 }
 
 func requestBody(method string, queryOrBody any) (io.Reader, error) {
@@ -117,13 +421,13 @@ func requestBody(method string, queryOrBody any) (io.Reader, error) {
 	return bytes.NewReader(jsonBody), nil
 }
 
-func parseResponse(resp *http.Response, body []byte) ([]byte, http.Header, int, error) {
+func parseResponse(resp *http.Response, body []byte, details ErrorDetailsFunc) (*Response, error) {
+	r := &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
 	if resp.StatusCode < http.StatusBadRequest {
-		return body, resp.Header, 0, nil
+		return r, nil
 	}
 
 	var retryAfter float64
-	msg := resp.Status
 
 	// Rate-limit handling, based on: https://datatracker.ietf.org/doc/html/rfc6585#section-4.1
 	if resp.StatusCode == http.StatusTooManyRequests {
@@ -140,14 +444,12 @@ func parseResponse(resp *http.Response, body []byte) ([]byte, http.Header, int,
 		}
 	}
 
-	secs := int(math.Max(0, retryAfter))
-	if secs > 0 {
-		msg += fmt.Sprintf(" (retry after %d seconds)", secs)
-	}
+	r.RetryAfter = int(math.Max(0, retryAfter))
 
-	if len(body) > 0 {
-		msg += ": " + string(body)
+	err := &APIError{StatusCode: resp.StatusCode, Status: resp.Status, RetryAfter: r.RetryAfter, Body: string(body)}
+	if details != nil {
+		err.ProviderCode, err.RequestID = details(resp.Header, body)
 	}
 
-	return nil, nil, secs, errors.New(msg)
+	return r, err
 }
@@ -1,12 +1,15 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestHTTPRequest(t *testing.T) {
@@ -66,6 +69,364 @@ func TestHTTPRequest(t *testing.T) {
 	}
 }
 
+func TestHTTPRequestFull(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{
+			name:       "200_ok",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "404_not_found",
+			statusCode: http.StatusNotFound,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("X-Request-Id", "123")
+				w.WriteHeader(tt.statusCode)
+				_, _ = fmt.Fprint(w, "body")
+			}))
+			defer s.Close()
+
+			got, err := HTTPRequestFull(t.Context(), http.MethodGet, s.URL, "", "", "", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HTTPRequestFull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got.StatusCode != tt.statusCode {
+				t.Errorf("HTTPRequestFull() status = %d, want %d", got.StatusCode, tt.statusCode)
+			}
+			if id := got.Header.Get("X-Request-Id"); id != "123" {
+				t.Errorf("HTTPRequestFull() request ID header = %q, want %q", id, "123")
+			}
+			if string(got.Body) != "body" {
+				t.Errorf("HTTPRequestFull() body = %q, want %q", got.Body, "body")
+			}
+		})
+	}
+}
+
+func TestHTTPRequestWithHeader(t *testing.T) {
+	var got http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer s.Close()
+
+	opts := []RequestOpt{WithHeader("X-Api-Version", "1"), WithHeader("Accept", "text/html")}
+	if _, err := HTTPRequestFull(t.Context(), http.MethodGet, s.URL, "", AcceptJSON, "", nil, opts...); err != nil {
+		t.Fatalf("HTTPRequestFull() error = %v", err)
+	}
+
+	if v := got.Get("X-Api-Version"); v != "1" {
+		t.Errorf("custom header = %q, want %q", v, "1")
+	}
+	if v := got.Values("Accept"); len(v) != 1 || v[0] != AcceptJSON {
+		t.Errorf("Accept header = %q, want %q", v, AcceptJSON)
+	}
+}
+
+func TestHTTPRequestDeadline(t *testing.T) {
+	delay := 200 * time.Millisecond
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(delay):
+			_, _ = fmt.Fprint(w, "body")
+		}
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name        string
+		ctxDeadline time.Duration
+		opts        []RequestOpt
+		wantErr     bool
+	}{
+		{
+			name: "default_timeout",
+		},
+		{
+			name:        "long_context_deadline",
+			ctxDeadline: 10 * delay,
+		},
+		{
+			name:        "short_context_deadline",
+			ctxDeadline: delay / 4,
+			wantErr:     true,
+		},
+		{
+			name:    "short_timeout_option",
+			opts:    []RequestOpt{WithTimeout(delay / 4)},
+			wantErr: true,
+		},
+		{
+			name:        "earlier_context_deadline_wins",
+			ctxDeadline: delay / 4,
+			opts:        []RequestOpt{WithTimeout(10 * delay)},
+			wantErr:     true,
+		},
+		{
+			name:        "long_timeout_option",
+			ctxDeadline: 10 * delay,
+			opts:        []RequestOpt{WithTimeout(5 * delay)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			if tt.ctxDeadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxDeadline)
+				defer cancel()
+			}
+
+			_, _, _, err := HTTPRequest(ctx, http.MethodGet, s.URL, "", "", "", nil, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HTTPRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithFileTransferTimeout(t *testing.T) {
+	delay := 200 * time.Millisecond
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(delay):
+			_, _ = fmt.Fprint(w, "body")
+		}
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:    "short_timeout",
+			timeout: delay / 4,
+			wantErr: true,
+		},
+		{
+			name:    "long_timeout",
+			timeout: 5 * delay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetFileTransferTimeout(tt.timeout)
+			t.Cleanup(func() { SetFileTransferTimeout(0) })
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*delay)
+			defer cancel()
+
+			_, _, _, err := HTTPRequest(ctx, http.MethodPost, s.URL, "", "", "", []byte("file"), WithFileTransferTimeout())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HTTPRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPRequestRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		failures    int32
+		statusCode  int
+		retryAfter  string
+		ctxDeadline time.Duration
+		opts        []RequestOpt
+		wantErr     bool
+		wantCalls   int32
+	}{
+		{
+			name:       "get_succeed_on_second_attempt",
+			method:     http.MethodGet,
+			failures:   1,
+			statusCode: http.StatusServiceUnavailable,
+			wantCalls:  2,
+		},
+		{
+			name:       "get_give_up",
+			method:     http.MethodGet,
+			failures:   10,
+			statusCode: http.StatusBadGateway,
+			wantErr:    true,
+			wantCalls:  1 + DefaultRetries,
+		},
+		{
+			name:       "delete_too_many_requests",
+			method:     http.MethodDelete,
+			failures:   1,
+			statusCode: http.StatusTooManyRequests,
+			wantCalls:  2,
+		},
+		{
+			name:       "get_client_error",
+			method:     http.MethodGet,
+			failures:   1,
+			statusCode: http.StatusNotFound,
+			wantErr:    true,
+			wantCalls:  1,
+		},
+		{
+			name:       "post_not_idempotent",
+			method:     http.MethodPost,
+			failures:   1,
+			statusCode: http.StatusServiceUnavailable,
+			wantErr:    true,
+			wantCalls:  1,
+		},
+		{
+			name:       "post_marked_idempotent",
+			method:     http.MethodPost,
+			failures:   1,
+			statusCode: http.StatusServiceUnavailable,
+			opts:       []RequestOpt{WithIdempotent()},
+			wantCalls:  2,
+		},
+		{
+			name:       "retries_disabled",
+			method:     http.MethodGet,
+			failures:   1,
+			statusCode: http.StatusServiceUnavailable,
+			opts:       []RequestOpt{WithRetries(0)},
+			wantErr:    true,
+			wantCalls:  1,
+		},
+		{
+			name:        "retry_after_exceeds_deadline",
+			method:      http.MethodGet,
+			failures:    1,
+			statusCode:  http.StatusTooManyRequests,
+			retryAfter:  "5",
+			ctxDeadline: time.Second,
+			wantErr:     true,
+			wantCalls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.statusCode)
+					return
+				}
+				_, _ = fmt.Fprint(w, "{}")
+			}))
+			defer s.Close()
+
+			ctx := t.Context()
+			if tt.ctxDeadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxDeadline)
+				defer cancel()
+			}
+
+			_, err := HTTPRequestFull(ctx, tt.method, s.URL, "", "", ContentJSON, map[string]string{}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HTTPRequestFull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("HTTPRequestFull() sent %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		resp    *Response
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{
+			name:    "first_attempt",
+			attempt: 1,
+			wantMin: RetryBaseDelay / 2,
+			wantMax: RetryBaseDelay,
+		},
+		{
+			name:    "third_attempt",
+			attempt: 3,
+			wantMin: 2 * RetryBaseDelay,
+			wantMax: 4 * RetryBaseDelay,
+		},
+		{
+			name:    "capped",
+			attempt: 20,
+			wantMin: MaxRetryDelay / 2,
+			wantMax: MaxRetryDelay,
+		},
+		{
+			name:    "high_attempt_number",
+			attempt: 1000,
+			wantMin: MaxRetryDelay / 2,
+			wantMax: MaxRetryDelay,
+		},
+		{
+			name:    "retry_after",
+			attempt: 1,
+			resp:    &Response{RetryAfter: 60},
+			wantMin: time.Minute,
+			wantMax: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				if got := retryDelay(tt.attempt, tt.resp); got < tt.wantMin || got > tt.wantMax {
+					t.Fatalf("retryDelay(%d) = %v, want in [%v, %v]", tt.attempt, got, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{"default", DefaultRetries, DefaultRetries},
+		{"negative", -1, 0},
+		{"huge", 1 << 40, MaxRetries},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newRequestOpts(WithRetries(tt.n)).retries; got != tt.want {
+				t.Errorf("WithRetries(%d) = %d, want %d", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
 func handler(t *testing.T) http.HandlerFunc {
 	t.Helper()
 
@@ -144,14 +505,20 @@ func TestParseResponse(t *testing.T) {
 				resp.Header.Set("Retry-After", strconv.Itoa(tt.retryAfter))
 			}
 
-			_, _, gotWait, err := parseResponse(resp, tt.body)
+			got, err := parseResponse(resp, tt.body, nil)
 			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
 				t.Errorf("parseResponse() error = %v, want %q", err, tt.wantErr)
 				return
 			}
 
-			if gotWait != tt.retryAfter {
-				t.Errorf("parseResponse() wait = %d, want %d", gotWait, tt.retryAfter)
+			if got.StatusCode != tt.statusCode {
+				t.Errorf("parseResponse() status = %d, want %d", got.StatusCode, tt.statusCode)
+			}
+			if string(got.Body) != string(tt.body) {
+				t.Errorf("parseResponse() body = %q, want %q", got.Body, tt.body)
+			}
+			if got.RetryAfter != tt.retryAfter {
+				t.Errorf("parseResponse() wait = %d, want %d", got.RetryAfter, tt.retryAfter)
 			}
 		})
 	}
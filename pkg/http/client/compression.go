@@ -0,0 +1,92 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+const acceptEncoding = "gzip, deflate"
+
+var gzipProviders atomic.Pointer[[]string]
+
+// SetGzipRequestProviders sets the API providers (e.g. "github") that accept gzip-compressed
+// request bodies. Requests to other providers are sent uncompressed. See also [WithProvider].
+func SetGzipRequestProviders(names []string) {
+	names = slices.Clone(names)
+	for i, n := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(n))
+	}
+	gzipProviders.Store(&names)
+}
+
+// GzipRequests reports whether request bodies sent to the given API provider should be gzip-compressed.
+func GzipRequests(provider string) bool {
+	names := gzipProviders.Load()
+	return provider != "" && names != nil && slices.Contains(*names, provider)
+}
+
+func compress(r io.Reader) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, fmt.Errorf("failed to compress HTTP request body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress HTTP request body: %w", err)
+	}
+	return buf, nil
+}
+
+// decompress replaces the body of an HTTP response with a decompressing reader, if needed.
+// Go's default [http.Transport] does this automatically for gzip, but only if the request
+// doesn't specify an "Accept-Encoding" header, and only if compression isn't disabled in
+// a custom transport. Instead of depending on that, we request and handle it explicitly.
+func decompress(resp *http.Response) error {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+
+	switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+	case "gzip":
+		r, err = gzip.NewReader(resp.Body)
+	case "deflate": // Which actually means zlib: https://www.rfc-editor.org/rfc/rfc9110#section-8.4.1.2
+		r, err = zlib.NewReader(resp.Body)
+	default:
+		return nil
+	}
+
+	if errors.Is(err, io.EOF) { // Empty body.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decompress HTTP response body: %w", err)
+	}
+
+	resp.Body = &decompressedBody{ReadCloser: r, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return nil
+}
+
+// decompressedBody closes both the decompressing reader and the original response body.
+type decompressedBody struct {
+	io.ReadCloser
+
+	body io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.body.Close())
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPRequestDecompression(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  string
+		transport *http.Transport
+	}{
+		{
+			name:     "gzip_default_transport",
+			encoding: "gzip",
+		},
+		{
+			name:      "gzip_compression_disabled_in_transport",
+			encoding:  "gzip",
+			transport: &http.Transport{DisableCompression: true},
+		},
+		{
+			name:     "deflate",
+			encoding: "deflate",
+		},
+		{
+			name: "identity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != acceptEncoding {
+					t.Errorf("Accept-Encoding header = %q, want %q", got, acceptEncoding)
+				}
+
+				var wc io.WriteCloser
+				switch tt.encoding {
+				case "gzip":
+					wc = gzip.NewWriter(w)
+				case "deflate":
+					wc = zlib.NewWriter(w)
+				}
+				if wc == nil {
+					_, _ = io.WriteString(w, `{"ok":true}`)
+					return
+				}
+
+				w.Header().Set("Content-Encoding", tt.encoding)
+				_, _ = io.WriteString(wc, `{"ok":true}`)
+				_ = wc.Close()
+			}))
+			defer s.Close()
+
+			if tt.transport != nil {
+				SetHTTPClient(&http.Client{Transport: tt.transport})
+				defer SetHTTPClient(nil)
+			}
+
+			resp, err := HTTPRequestFull(t.Context(), http.MethodGet, s.URL, "", AcceptJSON, "", nil)
+			if err != nil {
+				t.Fatalf("HTTPRequestFull() error = %v", err)
+			}
+			if want := `{"ok":true}`; string(resp.Body) != want {
+				t.Errorf("HTTPRequestFull() body = %q, want %q", resp.Body, want)
+			}
+			if ce := resp.Header.Get("Content-Encoding"); ce != "" {
+				t.Errorf("HTTPRequestFull() Content-Encoding header = %q, want none", ce)
+			}
+		})
+	}
+}
+
+func TestHTTPRequestGzipBody(t *testing.T) {
+	SetGzipRequestProviders([]string{" GitHub", "jira"})
+	defer SetGzipRequestProviders(nil)
+
+	tests := []struct {
+		name     string
+		provider string
+		wantGzip bool
+	}{
+		{
+			name:     "enabled_provider",
+			provider: "github",
+			wantGzip: true,
+		},
+		{
+			name:     "disabled_provider",
+			provider: "slack",
+		},
+		{
+			name: "no_provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEncoding, gotBody string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotEncoding = r.Header.Get("Content-Encoding")
+
+				var body io.Reader = r.Body
+				if gotEncoding == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("failed to decompress request body: %v", err)
+						return
+					}
+					body = zr
+				}
+
+				b, _ := io.ReadAll(body)
+				gotBody = string(b)
+			}))
+			defer s.Close()
+
+			body := map[string]string{"text": strings.Repeat("a", 100)}
+			if _, err := HTTPRequestFull(t.Context(), http.MethodPost, s.URL, "", "", ContentJSON, body, WithProvider(Provider{Name: tt.provider})); err != nil {
+				t.Fatalf("HTTPRequestFull() error = %v", err)
+			}
+
+			if (gotEncoding == "gzip") != tt.wantGzip {
+				t.Errorf("Content-Encoding header = %q, want gzip = %v", gotEncoding, tt.wantGzip)
+			}
+			if want := `{"text":"` + strings.Repeat("a", 100) + `"}`; gotBody != want {
+				t.Errorf("request body = %q, want %q", gotBody, want)
+			}
+		})
+	}
+}
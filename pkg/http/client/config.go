@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+
+	altsrc "github.com/urfave/cli-altsrc/v3"
+	"github.com/urfave/cli/v3"
+
+	"github.com/tzrikka/timpani/internal/config"
+)
+
+const (
+	DefaultTLSMinVersion       = "1.2"
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+)
+
+// Flags defines CLI flags to configure the transport of outbound API calls. These flags
+// are usually set using environment variables or the application's configuration file.
+func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "http-proxy-url",
+			Usage: "proxy URL for outbound API calls (default = based on HTTPS_PROXY/HTTP_PROXY/NO_PROXY)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_PROXY_URL"),
+				config.TOML("http_client.proxy_url", configFilePath),
+			),
+			Validator: validateProxyURL,
+		},
+		&cli.StringFlag{
+			Name:  "http-ca-cert",
+			Usage: "additional CA certificates PEM file for outbound API calls, e.g. for internal PKI",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_CA_CERT"),
+				config.TOML("http_client.ca_cert", configFilePath),
+			),
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:  "http-tls-min-version",
+			Usage: "minimum TLS version for outbound API calls (1.2 or 1.3)",
+			Value: DefaultTLSMinVersion,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_TLS_MIN_VERSION"),
+				config.TOML("http_client.tls_min_version", configFilePath),
+			),
+			Validator: validateTLSMinVersion,
+		},
+		&cli.StringSliceFlag{
+			Name:  "http-gzip-request-providers",
+			Usage: "API providers that accept gzip-compressed request bodies (bitbucket, github, jira, slack)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_GZIP_REQUEST_PROVIDERS"),
+				config.TOML("http_client.gzip_request_providers", configFilePath),
+			),
+		},
+		&cli.StringFlag{
+			Name:  "http-audit-log-file",
+			Usage: "append-only NDJSON file for audit records of mutating outbound API calls (default = disabled)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_AUDIT_LOG_FILE"),
+				config.TOML("http_client.audit_log_file", configFilePath),
+			),
+			TakesFile: true,
+		},
+		&cli.IntFlag{
+			Name:  "http-max-idle-conns",
+			Usage: "maximum number of idle (keep-alive) connections for outbound API calls",
+			Value: DefaultMaxIdleConns,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_MAX_IDLE_CONNS"),
+				config.TOML("http_client.max_idle_conns", configFilePath),
+			),
+		},
+		&cli.IntFlag{
+			Name:  "http-max-idle-conns-per-host",
+			Usage: "maximum number of idle (keep-alive) connections per host for outbound API calls",
+			Value: DefaultMaxIdleConnsPerHost,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_MAX_IDLE_CONNS_PER_HOST"),
+				config.TOML("http_client.max_idle_conns_per_host", configFilePath),
+			),
+		},
+		&cli.DurationFlag{
+			Name:  "http-file-transfer-timeout",
+			Usage: "timeout of file uploads and downloads (default = the activity's timeout)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_FILE_TRANSFER_TIMEOUT"),
+				config.TOML("http_client.file_transfer_timeout", configFilePath),
+			),
+		},
+		&cli.IntFlag{
+			Name:  "http-max-response-size",
+			Usage: "maximum size in bytes of list activity responses, to protect Temporal workflow histories",
+			Value: DefaultMaxResponseSize,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_HTTP_MAX_RESPONSE_SIZE"),
+				config.TOML("http_client.max_response_size", configFilePath),
+			),
+		},
+	}
+}
+
+func validateProxyURL(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid proxy URL: %q", s)
+	}
+	return nil
+}
+
+func validateTLSMinVersion(s string) error {
+	_, err := tlsVersion(s)
+	return err
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// APIError is an HTTP error response (status code 400 or higher) from an external API service.
+type APIError struct {
+	StatusCode   int    `json:"status_code"`
+	Status       string `json:"status"`
+	ProviderCode string `json:"provider_code,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
+	RetryAfter   int    `json:"retry_after,omitempty"`
+	Body         string `json:"body,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Status
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %d seconds)", e.RetryAfter)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request ID %s)", e.RequestID)
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// NewApplicationError converts an error which wraps an [APIError] into a retryable
+// [temporal.ApplicationError] of type "APIError", with the [APIError] as its details,
+// so that workflows can inspect it (e.g. the HTTP status code). If the response
+// specified a retry delay, it is respected. Other errors are returned as-is.
+//
+// [temporal.ApplicationError]: https://pkg.go.dev/go.temporal.io/temporal#ApplicationError
+func NewApplicationError(err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	opts := temporal.ApplicationErrorOptions{Cause: apiErr, Details: []any{*apiErr}}
+	if apiErr.RetryAfter > 0 {
+		opts.NextRetryDelay = time.Duration(apiErr.RetryAfter) * time.Second
+	}
+
+	return temporal.NewApplicationErrorWithOptions("HTTP error response", "APIError", opts)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestParseResponseAPIError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Status:     "422 Unprocessable Entity",
+		Header:     http.Header{"X-Request-Id": []string{"req-123"}},
+	}
+	details := func(h http.Header, body []byte) (string, string) {
+		return "code:" + string(body), h.Get("X-Request-Id")
+	}
+
+	_, err := parseResponse(resp, []byte("invalid"), details)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("parseResponse() error type = %T, want *APIError", err)
+	}
+
+	want := &APIError{
+		StatusCode:   http.StatusUnprocessableEntity,
+		Status:       "422 Unprocessable Entity",
+		ProviderCode: "code:invalid",
+		RequestID:    "req-123",
+		Body:         "invalid",
+	}
+	if !reflect.DeepEqual(apiErr, want) {
+		t.Errorf("parseResponse() error = %#v, want %#v", apiErr, want)
+	}
+
+	if got, want := err.Error(), "422 Unprocessable Entity (request ID req-123): invalid"; got != want {
+		t.Errorf("APIError.Error() = %q, want %q", got, want)
+	}
+}
+
+func TestNewApplicationError(t *testing.T) {
+	apiErr := &APIError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests", RetryAfter: 5}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantType  string
+		wantDelay time.Duration
+	}{
+		{
+			name:      "api_error",
+			err:       apiErr,
+			wantType:  "APIError",
+			wantDelay: 5 * time.Second,
+		},
+		{
+			name:      "wrapped_api_error",
+			err:       fmt.Errorf("wrapped: %w", apiErr),
+			wantType:  "APIError",
+			wantDelay: 5 * time.Second,
+		},
+		{
+			name: "other_error",
+			err:  errors.New("other error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewApplicationError(tt.err)
+
+			var appErr *temporal.ApplicationError
+			if !errors.As(err, &appErr) {
+				if tt.wantType != "" {
+					t.Fatalf("NewApplicationError() = %T, want *temporal.ApplicationError", err)
+				}
+				if !errors.Is(err, tt.err) {
+					t.Errorf("NewApplicationError() = %v, want %v", err, tt.err)
+				}
+				return
+			}
+
+			if appErr.Type() != tt.wantType {
+				t.Errorf("NewApplicationError() type = %q, want %q", appErr.Type(), tt.wantType)
+			}
+			if appErr.NonRetryable() {
+				t.Error("NewApplicationError() is non-retryable")
+			}
+			if appErr.NextRetryDelay() != tt.wantDelay {
+				t.Errorf("NewApplicationError() next retry delay = %v, want %v", appErr.NextRetryDelay(), tt.wantDelay)
+			}
+
+			var got APIError
+			if err := appErr.Details(&got); err != nil {
+				t.Fatalf("ApplicationError.Details() error = %v", err)
+			}
+			if !reflect.DeepEqual(&got, apiErr) {
+				t.Errorf("ApplicationError.Details() = %#v, want %#v", got, apiErr)
+			}
+
+			var cause *APIError
+			if !errors.As(err, &cause) || cause.StatusCode != apiErr.StatusCode {
+				t.Errorf("NewApplicationError() doesn't wrap the APIError")
+			}
+		})
+	}
+}
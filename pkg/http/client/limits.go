@@ -0,0 +1,76 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// DefaultMaxResponseSize is the default limit of [LimitResponseSize]. Activity results are stored
+// verbatim in the workflow's history, and Temporal rejects blobs larger than 2 MiB by default.
+const DefaultMaxResponseSize = 1 << 20 // 1 MiB.
+
+var maxResponseSize atomic.Int64
+
+// SetMaxResponseSize sets the limit of [LimitResponseSize], in bytes (0 = [DefaultMaxResponseSize]).
+func SetMaxResponseSize(n int) {
+	maxResponseSize.Store(int64(n))
+}
+
+// MaxResponseSize returns the limit of [LimitResponseSize], in bytes.
+func MaxResponseSize() int {
+	if n := maxResponseSize.Load(); n > 0 {
+		return int(n)
+	}
+	return DefaultMaxResponseSize
+}
+
+// LimitResponseSize enforces [MaxResponseSize] on the JSON encoding of an activity response,
+// which contains the given list. If the response is too large, it either returns a non-retryable
+// error which tells the caller to paginate, or (if truncate is true) removes items from the end
+// of the list until the response fits, and reports that it did so.
+func LimitResponseSize[T any](activityName string, resp any, list *[]T, truncate bool) (bool, error) {
+	limit := MaxResponseSize()
+	size, err := jsonSize(resp)
+	if err != nil || size <= limit {
+		return false, err
+	}
+
+	if !truncate {
+		return false, responseTooLargeError(activityName, size, limit)
+	}
+
+	// Each removed item also removes at least 1 byte of separator (a comma).
+	excess := size - limit
+	n := len(*list)
+	for n > 0 && excess > 0 {
+		n--
+		itemSize, err := jsonSize((*list)[n])
+		if err != nil {
+			return false, err
+		}
+		excess -= itemSize + 1
+	}
+	*list = (*list)[:n]
+
+	// The list may not be the only large part of the response.
+	if size, err = jsonSize(resp); err != nil || size <= limit {
+		return true, err
+	}
+	return false, responseTooLargeError(activityName, size, limit)
+}
+
+func jsonSize(v any) (int, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode activity response: %w", err)
+	}
+	return len(b), nil
+}
+
+func responseTooLargeError(activityName string, size, limit int) error {
+	msg := fmt.Sprintf("%s response is too large (%d bytes, limit is %d): request smaller pages", activityName, size, limit)
+	return temporal.NewNonRetryableApplicationError(msg, "ResponseTooLargeError", nil, size, limit)
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+type testListResponse struct {
+	Header string   `json:"header,omitempty"`
+	Items  []string `json:"items"`
+}
+
+func TestLimitResponseSize(t *testing.T) {
+	// Each item is 10 bytes when encoded as a JSON string: `"12345678"`.
+	item := strings.Repeat("x", 8)
+	items := []string{item, item, item, item}
+	full, _ := json.Marshal(testListResponse{Items: items})
+
+	tests := []struct {
+		name          string
+		limit         int
+		header        string
+		truncate      bool
+		wantItems     int
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{
+			name:      "at_limit",
+			limit:     len(full),
+			wantItems: 4,
+		},
+		{
+			name:    "one_byte_over_limit",
+			limit:   len(full) - 1,
+			wantErr: true,
+		},
+		{
+			name:          "one_byte_over_limit_truncated",
+			limit:         len(full) - 1,
+			truncate:      true,
+			wantItems:     3,
+			wantTruncated: true,
+		},
+		{
+			name:          "two_items_over_limit_truncated",
+			limit:         len(full) - 12,
+			truncate:      true,
+			wantItems:     2,
+			wantTruncated: true,
+		},
+		{
+			name:     "too_large_without_items",
+			limit:    len(full),
+			header:   strings.Repeat("x", len(full)),
+			truncate: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetMaxResponseSize(tt.limit)
+			t.Cleanup(func() { SetMaxResponseSize(0) })
+
+			resp := &testListResponse{Header: tt.header, Items: append([]string{}, items...)}
+			truncated, err := LimitResponseSize("test.list", resp, &resp.Items, tt.truncate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LimitResponseSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var appErr *temporal.ApplicationError
+				if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != "ResponseTooLargeError" {
+					t.Errorf("LimitResponseSize() error = %v, want non-retryable ResponseTooLargeError", err)
+				}
+				return
+			}
+
+			if truncated != tt.wantTruncated {
+				t.Errorf("LimitResponseSize() = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if len(resp.Items) != tt.wantItems {
+				t.Errorf("LimitResponseSize() items = %d, want %d", len(resp.Items), tt.wantItems)
+			}
+			if b, _ := json.Marshal(resp); len(b) > tt.limit {
+				t.Errorf("LimitResponseSize() response size = %d, want <= %d", len(b), tt.limit)
+			}
+		})
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	t.Cleanup(func() { SetMaxResponseSize(0) })
+
+	if got := MaxResponseSize(); got != DefaultMaxResponseSize {
+		t.Errorf("MaxResponseSize() = %d, want %d", got, DefaultMaxResponseSize)
+	}
+	SetMaxResponseSize(100)
+	if got := MaxResponseSize(); got != 100 {
+		t.Errorf("MaxResponseSize() = %d, want %d", got, 100)
+	}
+}
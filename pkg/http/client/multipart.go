@@ -0,0 +1,68 @@
+package client
+
+import (
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/textproto"
+	"slices"
+)
+
+// MultipartBody is a "multipart/form-data" request body for [HTTPRequest], e.g.
+// for file uploads. It is encoded while the request is being sent, so the files'
+// content isn't buffered in memory. As a result, these requests are never retried
+// automatically, because the files' readers cannot be rewound.
+type MultipartBody struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+// MultipartFile is a single file in a [MultipartBody].
+type MultipartFile struct {
+	FieldName   string
+	Filename    string
+	ContentType string // Default = "application/octet-stream".
+	Content     io.Reader
+}
+
+// reader returns a stream of the multipart-encoded body, and its content type
+// (including the boundary). The caller must close the reader when done with it.
+func (b *MultipartBody) reader() (*io.PipeReader, string) {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(b.encode(w))
+	}()
+
+	return pr, w.FormDataContentType()
+}
+
+func (b *MultipartBody) encode(w *multipart.Writer) error {
+	for _, k := range slices.Sorted(maps.Keys(b.Fields)) {
+		if err := w.WriteField(k, b.Fields[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range b.Files {
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", multipart.FileContentDisposition(f.FieldName, f.Filename))
+		h.Set("Content-Type", ct)
+
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Content); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
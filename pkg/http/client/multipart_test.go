@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPRequestMultipart(t *testing.T) {
+	type part struct {
+		formName, fileName, contentType, content string
+	}
+	var got []part
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("request content type = %q: %v", r.Header.Get("Content-Type"), err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for {
+			p, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Errorf("failed to read multipart body: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, _ := io.ReadAll(p)
+			got = append(got, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(b)})
+		}
+
+		_, _ = fmt.Fprint(w, "{}")
+	}))
+	defer s.Close()
+
+	body := &MultipartBody{
+		Fields: map[string]string{"b": "2", "a": "1"},
+		Files: []MultipartFile{
+			{FieldName: "file", Filename: "a.txt", ContentType: AcceptText, Content: strings.NewReader("hello")},
+			{FieldName: "file", Filename: "b.bin", Content: strings.NewReader("world")},
+		},
+	}
+
+	_, err := HTTPRequestFull(t.Context(), http.MethodPost, s.URL, "", "", ContentJSON, body)
+	if err != nil {
+		t.Fatalf("HTTPRequestFull() error = %v", err)
+	}
+
+	want := []part{
+		{"a", "", "", "1"},
+		{"b", "", "", "2"},
+		{"file", "a.txt", AcceptText, "hello"},
+		{"file", "b.bin", "application/octet-stream", "world"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d parts, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("part %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHTTPRequestMultipartNotRetried(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	body := &MultipartBody{Files: []MultipartFile{{FieldName: "file", Filename: "a.txt", Content: strings.NewReader("hello")}}}
+	if _, err := HTTPRequestFull(t.Context(), http.MethodPost, s.URL, "", "", "", body, WithIdempotent()); err == nil {
+		t.Error("HTTPRequestFull() error = nil, want error")
+	}
+	if calls != 1 {
+		t.Errorf("HTTPRequestFull() sent %d requests, want 1", calls)
+	}
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tzrikka/timpani/internal/redact"
+)
+
+// HTTPRequestStream sends an HTTP request to an external API service, like [HTTPRequestFull],
+// but returns the response body as a stream, instead of reading it into memory. This is
+// useful for large downloads, e.g. files and diffs. Size limits are the caller's responsibility.
+//
+// The caller must close the returned body. It is also closed automatically when the
+// request's context is done (see [HTTPRequestFull] regarding the request's deadline).
+//
+// Streaming requests are never retried automatically. HTTP error responses (status code
+// 400 or higher) are read and returned as errors, along with their headers.
+func HTTPRequestStream(
+	ctx context.Context,
+	method, apiURL, auth, accept, contentType string,
+	queryOrBody any,
+	opts ...RequestOpt,
+) (io.ReadCloser, http.Header, error) {
+	o := newRequestOpts(opts...)
+	ctx, cancel := requestContext(ctx, o)
+
+	req, err := newRequest(ctx, o, method, withQuery(method, apiURL, queryOrBody), auth, accept, contentType, queryOrBody)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to send HTTP request: %w", redact.Error(err))
+	}
+
+	if err := decompress(resp); err != nil {
+		resp.Body.Close()
+		cancel()
+		return nil, nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer cancel()
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize))
+		if err != nil {
+			return nil, resp.Header, fmt.Errorf("failed to read HTTP response body: %w", err)
+		}
+		_, err = parseResponse(resp, body, o.provider.ErrorDetails)
+		return nil, resp.Header, err
+	}
+
+	b := &streamBody{body: resp.Body, cancel: cancel}
+	context.AfterFunc(ctx, func() {
+		_ = b.Close()
+	})
+
+	return b, resp.Header, nil
+}
+
+// streamBody closes the response body of [HTTPRequestStream] and releases
+// the resources of its request context, exactly once, either when the caller
+// closes it or when the context is done, whichever happens first.
+type streamBody struct {
+	body   io.ReadCloser
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	return b.body.Read(p)
+}
+
+func (b *streamBody) Close() error {
+	b.once.Do(func() {
+		b.err = b.body.Close()
+		b.cancel()
+	})
+	return b.err
+}
+
+// FileSinkResponse describes a local file that an activity wrote its output into.
+type FileSinkResponse struct {
+	FilePath string `json:"file_path"`
+	Size     int64  `json:"size"`
+}
+
+// HTTPRequestToFile sends an HTTP request like [HTTPRequestStream], and writes the response
+// body into a local file with [StreamToFile], in order to support large responses. MaxSize
+// is optional (default = [MaxStreamSize]). It also returns the response's headers, even if
+// the request fails (e.g. to inspect rate limits), as long as a response was received.
+func HTTPRequestToFile(
+	ctx context.Context,
+	method, apiURL, auth, accept, contentType string,
+	queryOrBody any,
+	filePath string,
+	maxSize int64,
+	opts ...RequestOpt,
+) (*FileSinkResponse, http.Header, error) {
+	body, header, err := HTTPRequestStream(ctx, method, apiURL, auth, accept, contentType, queryOrBody, opts...)
+	if err != nil {
+		return nil, header, err
+	}
+	defer body.Close()
+
+	if maxSize <= 0 {
+		maxSize = MaxStreamSize
+	}
+	n, err := StreamToFile(body, filePath, maxSize)
+	if err != nil {
+		return nil, header, fmt.Errorf("failed to save HTTP response: %w", err)
+	}
+
+	return &FileSinkResponse{FilePath: filePath, Size: n}, header, nil
+}
+
+// StreamToFile writes a stream (e.g. the response body of [HTTPRequestStream]) into a
+// local file, and returns the number of bytes written. If the stream is larger than
+// maxSize, it deletes the partial file and returns a non-retryable error.
+func StreamToFile(r io.Reader, path string, maxSize int64) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) //gosec:disable G304 // Specified by the caller by design.
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, maxSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if n > maxSize {
+		_ = os.Remove(path)
+		msg := fmt.Sprintf("stream exceeds maximum size (%d bytes)", maxSize)
+		return 0, temporal.NewNonRetryableApplicationError(msg, "SizeLimitError", nil, path)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const streamSize = 10 << 20 // 10 MiB, i.e. larger than [MaxSize].
+
+func streamServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	chunk := bytes.Repeat([]byte("x"), 1<<20)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		for range streamSize / len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestHTTPRequestStream(t *testing.T) {
+	s := streamServer(t)
+	defer s.Close()
+
+	body, headers, err := HTTPRequestStream(t.Context(), http.MethodGet, s.URL, "", "", "", nil)
+	if err != nil {
+		t.Fatalf("HTTPRequestStream() error = %v", err)
+	}
+	defer body.Close()
+
+	if ct := headers.Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("HTTPRequestStream() content type = %q, want %q", ct, "text/plain")
+	}
+
+	n, err := io.Copy(io.Discard, body)
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if n != streamSize {
+		t.Errorf("HTTPRequestStream() body size = %d, want %d", n, streamSize)
+	}
+}
+
+func TestHTTPRequestStreamError(t *testing.T) {
+	s := streamServer(t)
+	defer s.Close()
+
+	body, _, err := HTTPRequestStream(t.Context(), http.MethodGet, s.URL+"/error", "", "", "", nil)
+	if err == nil {
+		t.Error("HTTPRequestStream() error = nil, want error")
+	}
+	if body != nil {
+		t.Error("HTTPRequestStream() body != nil")
+	}
+}
+
+func TestHTTPRequestStreamCancel(t *testing.T) {
+	s := streamServer(t)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	body, _, err := HTTPRequestStream(ctx, http.MethodGet, s.URL, "", "", "", nil)
+	if err != nil {
+		t.Fatalf("HTTPRequestStream() error = %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	if _, err := body.Read(buf); err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+
+	cancel()
+	if _, err := io.Copy(io.Discard, body); err == nil {
+		t.Error("reading a stream after context cancellation: error = nil, want error")
+	}
+}
+
+func TestHTTPRequestStreamRedaction(t *testing.T) {
+	s := streamServer(t)
+	s.Close() // Fail the dial.
+
+	body, _, err := HTTPRequestStream(t.Context(), http.MethodGet, s.URL+"?channel=C123&token=secret", "", "", "", nil)
+	if err == nil {
+		body.Close()
+		t.Fatal("HTTPRequestStream() error = nil, want error")
+	}
+
+	if msg := err.Error(); strings.Contains(msg, "secret") {
+		t.Errorf("HTTPRequestStream() error = %q, should not contain the token", msg)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "channel=C123") {
+		t.Errorf("HTTPRequestStream() error = %q, want redacted URL", msg)
+	}
+}
+
+func TestStreamToFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int64
+		wantErr bool
+	}{
+		{
+			name:    "within_limit",
+			maxSize: streamSize,
+		},
+		{
+			name:    "exceeds_limit",
+			maxSize: streamSize - 1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := streamServer(t)
+			defer s.Close()
+
+			body, _, err := HTTPRequestStream(t.Context(), http.MethodGet, s.URL, "", "", "", nil)
+			if err != nil {
+				t.Fatalf("HTTPRequestStream() error = %v", err)
+			}
+			defer body.Close()
+
+			path := filepath.Join(t.TempDir(), "stream.txt")
+			n, err := StreamToFile(body, path, tt.maxSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StreamToFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			_, statErr := os.Stat(path)
+			if tt.wantErr {
+				if !errors.Is(statErr, os.ErrNotExist) {
+					t.Errorf("StreamToFile() left a partial file: %v", statErr)
+				}
+				return
+			}
+
+			if n != streamSize {
+				t.Errorf("StreamToFile() = %d, want %d", n, streamSize)
+			}
+			if statErr != nil {
+				t.Errorf("StreamToFile() file error = %v", statErr)
+			}
+		})
+	}
+}
+
+func TestHTTPRequestToFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		maxSize int64
+		want    int64
+		wantErr bool
+	}{
+		{
+			name: "default_max_size",
+			want: streamSize,
+		},
+		{
+			name:    "exceeds_limit",
+			maxSize: streamSize - 1,
+			wantErr: true,
+		},
+		{
+			name:    "error_response",
+			path:    "/error",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := streamServer(t)
+			defer s.Close()
+
+			path := filepath.Join(t.TempDir(), "stream.txt")
+			got, header, err := HTTPRequestToFile(t.Context(), http.MethodGet, s.URL+tt.path, "", "", "", nil, path, tt.maxSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HTTPRequestToFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if header == nil {
+				t.Error("HTTPRequestToFile() header = nil")
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want := &FileSinkResponse{FilePath: path, Size: tt.want}
+			if *got != *want {
+				t.Errorf("HTTPRequestToFile() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v3"
+)
+
+// TransportConfig contains the configuration details of
+// the [http.Transport] that is shared by all outbound API calls.
+type TransportConfig struct {
+	ProxyURL            string // Default = based on environment variables.
+	CACertFile          string // Additional CA certificates, on top of the system's.
+	TLSMinVersion       string // Default = [DefaultTLSMinVersion].
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+}
+
+var sharedClient atomic.Pointer[http.Client]
+
+// HTTPClient returns the [http.Client] that is shared by all outbound API calls. It's
+// [http.DefaultClient], unless [ConfigureTransport] or [SetHTTPClient] were called.
+func HTTPClient() *http.Client {
+	if c := sharedClient.Load(); c != nil {
+		return c
+	}
+	return http.DefaultClient
+}
+
+// SetHTTPClient replaces the [http.Client] that is shared by all outbound API calls.
+func SetHTTPClient(c *http.Client) {
+	sharedClient.Store(c)
+}
+
+// ConfigureTransport initializes the [http.Client] that is shared by all
+// outbound API calls, the providers that accept compressed requests, the
+// maximum size of activity responses, and the audit log of mutating API
+// calls (see [OpenAuditLog]), based on CLI flags (see [Flags]).
+// It should be called once during startup, before sending any API calls.
+func ConfigureTransport(cmd *cli.Command) error {
+	t, err := NewTransport(TransportConfig{
+		ProxyURL:            cmd.String("http-proxy-url"),
+		CACertFile:          cmd.String("http-ca-cert"),
+		TLSMinVersion:       cmd.String("http-tls-min-version"),
+		MaxIdleConns:        cmd.Int("http-max-idle-conns"),
+		MaxIdleConnsPerHost: cmd.Int("http-max-idle-conns-per-host"),
+	})
+	if err != nil {
+		return err
+	}
+
+	SetHTTPClient(&http.Client{Transport: t})
+	SetGzipRequestProviders(cmd.StringSlice("http-gzip-request-providers"))
+	SetMaxResponseSize(cmd.Int("http-max-response-size"))
+	SetFileTransferTimeout(cmd.Duration("http-file-transfer-timeout"))
+	return OpenAuditLog(cmd.String("http-audit-log-file"))
+}
+
+// NewTransport returns a copy of [http.DefaultTransport] with the given configuration.
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // Type conversion always succeeds.
+
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if cfg.TLSMinVersion == "" {
+		cfg.TLSMinVersion = DefaultTLSMinVersion
+	}
+	v, err := tlsVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	t.TLSClientConfig = &tls.Config{MinVersion: v}
+
+	if cfg.CACertFile != "" {
+		pool, err := certPool(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+
+	return t, nil
+}
+
+func tlsVersion(s string) (uint16, error) {
+	switch s {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version: %q", s)
+	}
+}
+
+// certPool returns the system's pool of root CAs, extended with the CA certificates in the given PEM file.
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path) //gosec:disable G304 // Specified by admin by design.
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA cert file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("failed to parse CA cert file: " + path)
+	}
+
+	return pool, nil
+}
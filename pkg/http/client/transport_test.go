@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTransportProxy(t *testing.T) {
+	var gotURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		_, _ = fmt.Fprint(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	tr, err := NewTransport(TransportConfig{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewTransport() error = %v", err)
+	}
+	SetHTTPClient(&http.Client{Transport: tr})
+	defer SetHTTPClient(nil)
+
+	got, _, _, err := HTTPRequest(t.Context(), http.MethodGet, "http://api.example.com/path", "", "", "", nil)
+	if err != nil {
+		t.Fatalf("HTTPRequest() error = %v", err)
+	}
+	if string(got) != "proxied" {
+		t.Errorf("HTTPRequest() = %q, want %q", got, "proxied")
+	}
+
+	if want := "http://api.example.com/path"; gotURL != want {
+		t.Errorf("proxy request URL = %q, want %q", gotURL, want)
+	}
+}
+
+func TestNewTransportCACert(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, "ok")
+	}))
+	defer s.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     TransportConfig
+		wantErr bool
+	}{
+		{
+			name:    "unknown_ca",
+			cfg:     TransportConfig{},
+			wantErr: true,
+		},
+		{
+			name: "custom_ca",
+			cfg:  TransportConfig{CACertFile: caPath, TLSMinVersion: "1.3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := NewTransport(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewTransport() error = %v", err)
+			}
+			SetHTTPClient(&http.Client{Transport: tr})
+			defer SetHTTPClient(nil)
+
+			_, _, _, err = HTTPRequest(t.Context(), http.MethodGet, s.URL, "", "", "", nil, WithRetries(0))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HTTPRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTransportErrors(t *testing.T) {
+	badPath := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(badPath, []byte("not a cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  TransportConfig
+	}{
+		{
+			name: "invalid_proxy_url",
+			cfg:  TransportConfig{ProxyURL: "://"},
+		},
+		{
+			name: "unsupported_tls_version",
+			cfg:  TransportConfig{TLSMinVersion: "1.0"},
+		},
+		{
+			name: "missing_ca_file",
+			cfg:  TransportConfig{CACertFile: filepath.Join(t.TempDir(), "missing.pem")},
+		},
+		{
+			name: "invalid_ca_file",
+			cfg:  TransportConfig{CACertFile: badPath},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTransport(tt.cfg); err == nil {
+				t.Error("NewTransport() error = nil, want error")
+			}
+		})
+	}
+}
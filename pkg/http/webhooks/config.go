@@ -2,14 +2,23 @@ package webhooks
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	altsrc "github.com/urfave/cli-altsrc/v3"
-	"github.com/urfave/cli-altsrc/v3/toml"
 	"github.com/urfave/cli/v3"
+
+	"github.com/tzrikka/timpani/internal/config"
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/pkg/listeners/slack"
 )
 
 const (
-	DefaultWebhookPort = 14480
+	DefaultWebhookPort       = 14480
+	DefaultLinkProbeInterval = 5 * time.Minute
+
+	DefaultSlackSocketConnections = 1
+	MaxSlackSocketConnections     = 2
 )
 
 // Flags defines CLI flags to configure an HTTP server. These flags are usually
@@ -22,24 +31,104 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Value: DefaultWebhookPort,
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("TIMPANI_WEBHOOK_PORT"),
-				toml.TOML("http_server.webhook_port", configFilePath),
+				config.TOML("http_server.webhook_port", configFilePath),
 			),
 			Validator: validatePort,
 		},
+		&cli.DurationFlag{
+			Name:  "link-probe-interval",
+			Usage: "interval of Thrippy link credentials checks (0 = disabled)",
+			Value: DefaultLinkProbeInterval,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_LINK_PROBE_INTERVAL"),
+				config.TOML("http_server.link_probe_interval", configFilePath),
+			),
+		},
+		&cli.StringSliceFlag{
+			Name:  "webhook-latency-slo",
+			Usage: "response time thresholds for logging slow webhook handlers: <template or service>=<duration> (defaults: slack=2s, others=5s)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_WEBHOOK_LATENCY_SLO"),
+				config.TOML("http_server.webhook_latency_slo", configFilePath),
+			),
+			Validator: validateLatencySLOs,
+		},
+		&cli.IntFlag{
+			Name:  "slack-socket-connections",
+			Usage: "number of redundant WebSocket connections per Slack Socket Mode link",
+			Value: DefaultSlackSocketConnections,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_SLACK_SOCKET_CONNECTIONS"),
+				config.TOML("http_server.slack_socket_connections", configFilePath),
+			),
+			Validator: validateSlackSocketConnections,
+		},
+		&cli.StringSliceFlag{
+			Name:  "slack-event-allowlist",
+			Usage: "Slack event types to dispatch as Temporal signals (default = all, reloaded on SIGHUP)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_SLACK_EVENT_ALLOWLIST"),
+				config.TOML("http_server.slack_event_allowlist", configFilePath),
+			),
+		},
+		&cli.BoolFlag{
+			Name:  "slack-link-signals",
+			Usage: "namespace Slack signal names with Thrippy link IDs (slack.<link ID>.events.<type>), to isolate multiple Slack apps",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_SLACK_LINK_SIGNALS"),
+				config.TOML("http_server.slack_link_signals", configFilePath),
+			),
+		},
+		&cli.StringSliceFlag{
+			Name:  "slack-interaction-responses",
+			Usage: "response policies of Slack interaction types: <type>=empty, <type>=deferred, or <type>=<JSON body>",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_SLACK_INTERACTION_RESPONSES"),
+				config.TOML("http_server.slack_interaction_responses", configFilePath),
+			),
+			Validator: slack.ValidateInteractionResponses,
+		},
+		&cli.StringFlag{
+			Name:  "redis-url",
+			Usage: "optional Redis URL (redis://... or rediss://...), to share state (e.g. duplicate suppression) between replicas, instead of keeping it in memory",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_REDIS_URL"),
+				config.TOML("http_server.redis_url", configFilePath),
+			),
+			Validator: validateRedisURL,
+		},
 		&cli.StringFlag{
 			Name:  "thrippy-http-address",
 			Usage: "optional Thrippy address, to pass-through OAuth callbacks, to share a single HTTP tunnel",
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("THRIPPY_HTTP_PASSTHROUGH_ADDRESS"),
-				toml.TOML("http_server.thrippy_http_passthrough_address", configFilePath),
+				config.TOML("http_server.thrippy_http_passthrough_address", configFilePath),
 			),
 		},
 	}
 }
 
+func validateRedisURL(u string) error {
+	if u == "" {
+		return nil
+	}
+	_, err := listeners.NewRedisStore(u)
+	return err
+}
+
 func validatePort(p int) error {
 	if p < 0 || p > 65535 {
 		return errors.New("out of range [0-65535]")
 	}
 	return nil
 }
+
+// validateSlackSocketConnections enforces Slack's limit of WebSocket
+// connections per app, while leaving room for seamless reconnections:
+// https://docs.slack.dev/apis/events-api/using-socket-mode#connections
+func validateSlackSocketConnections(n int) error {
+	if n < 1 || n > MaxSlackSocketConnections {
+		return fmt.Errorf("out of range [1-%d]", MaxSlackSocketConnections)
+	}
+	return nil
+}
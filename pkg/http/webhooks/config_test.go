@@ -47,3 +47,38 @@ func TestValidatePort(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSlackSocketConnections(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		wantErr bool
+	}{
+		{
+			name:    "0",
+			n:       0,
+			wantErr: true,
+		},
+		{
+			name: "1",
+			n:    1,
+		},
+		{
+			name: "2",
+			n:    2,
+		},
+		{
+			name:    "3",
+			n:       3,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSlackSocketConnections(tt.n); (err != nil) != tt.wantErr {
+				t.Errorf("validateSlackSocketConnections() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
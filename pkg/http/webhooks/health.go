@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/buildinfo"
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/redact"
+	"github.com/tzrikka/timpani/pkg/api/bitbucket"
+	"github.com/tzrikka/timpani/pkg/api/github"
+	"github.com/tzrikka/timpani/pkg/api/jira"
+	"github.com/tzrikka/timpani/pkg/api/slack"
+	slacklis "github.com/tzrikka/timpani/pkg/listeners/slack"
+	"github.com/tzrikka/timpani/pkg/otel"
+	"github.com/tzrikka/timpani/pkg/temporal"
+)
+
+type linkProbeFunc func(ctx context.Context, template string, secrets map[string]string) error
+
+// linkProbes is a map of functions that check the validity of Thrippy link credentials.
+// The map keys correspond to the prefixes of Thrippy link template names.
+var linkProbes = map[string]linkProbeFunc{
+	"bitbucket": bitbucket.ProbeLink,
+	"github":    github.ProbeLink,
+	"jira":      jira.ProbeLink,
+	"slack":     slack.ProbeLink,
+}
+
+// ErrNoLinkProbe means that [ProbeLink] doesn't support the given Thrippy link template.
+var ErrNoLinkProbe = errors.New("unsupported link template for credentials check")
+
+// ProbeLink checks the credentials of a Thrippy link with the given template and secrets,
+// like [HTTPServer.ProbeLinks], but only once and without recording the result.
+func ProbeLink(ctx context.Context, template string, secrets map[string]string) error {
+	prefix, _, _ := strings.Cut(template, "-")
+	probe, ok := linkProbes[prefix]
+	if !ok {
+		return ErrNoLinkProbe
+	}
+	return probe(ctx, template, secrets)
+}
+
+// LinkHealth is the result of the latest credentials check of a Thrippy link.
+type LinkHealth struct {
+	Template  string    `json:"template"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ProbeLinks periodically checks the credentials of all the configured Thrippy links,
+// to provide an early warning when they are revoked or rotated, before workflows start
+// failing because of them. This function blocks until the context is canceled, and
+// it should be called after [HTTPServer.ConnectLinks], not concurrently with it.
+func (s *HTTPServer) ProbeLinks(ctx context.Context) {
+	if s.probeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, linkID := range s.linkIDs() {
+			if linkID != "" {
+				s.probeLink(ctx, linkID)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeLink checks the credentials of a single Thrippy link, records the result,
+// and logs state transitions (i.e. only the first failure, and the recovery).
+func (s *HTTPServer) probeLink(ctx context.Context, linkID string) {
+	l := logger.FromContext(ctx).With(slog.String("link_id", linkID))
+
+	template, secrets, err := s.linkData(ctx, linkID)
+	if err == nil && secrets == nil {
+		err = errors.New("link not found or not initialized in Thrippy")
+	}
+
+	if err == nil {
+		if err = ProbeLink(ctx, template, secrets); errors.Is(err, ErrNoLinkProbe) {
+			return // Unsupported link template, nothing to check.
+		}
+	}
+
+	h := LinkHealth{Template: template, Healthy: err == nil, CheckedAt: time.Now().UTC()}
+	if err != nil {
+		h.Error = redact.Error(err).Error()
+	}
+
+	s.healthMu.Lock()
+	if s.linkHealth == nil {
+		s.linkHealth = map[string]LinkHealth{}
+	}
+	// Probes may use different credentials than the link's stateful connections.
+	if msg, ok := s.connFailures[linkID]; ok && h.Healthy {
+		h.Healthy, h.Error = false, msg
+		err = errors.New(msg)
+	}
+	prev, found := s.linkHealth[linkID]
+	s.linkHealth[linkID] = h
+	s.healthMu.Unlock()
+
+	otel.SetLinkHealthGauge(h.CheckedAt, linkID, template, h.Healthy)
+
+	l = l.With(slog.String("template", template))
+	switch {
+	case h.Healthy && found && !prev.Healthy:
+		l.Info("Thrippy link credentials are valid again")
+	case !h.Healthy && (!found || prev.Healthy):
+		l.Warn("Thrippy link credentials check failed", slog.Any("error", err))
+	}
+}
+
+// healthStatus is the response of [HTTPServer.healthzHandler]. It contains only booleans,
+// because the endpoint shares the public port of the webhooks: the details of failures
+// (e.g. link IDs and error messages) are available only in the logs, and in the output
+// of the "doctor" command.
+type healthStatus struct {
+	LinksHealthy    bool            `json:"links_healthy"`
+	SlackSocketMode bool            `json:"slack_socket_mode,omitempty"`
+	Registrations   map[string]bool `json:"registrations,omitempty"`
+}
+
+// healthzHandler reports that the HTTP server is alive, along with whether the latest
+// credentials checks of all the Thrippy links succeeded, whether any Slack Socket Mode
+// connection was established, and the registration status of third-party services in
+// the Temporal worker (if it runs in the same process).
+func (s *HTTPServer) healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	status := healthStatus{
+		LinksHealthy:    true,
+		SlackSocketMode: len(slacklis.LastHellos()) > 0,
+	}
+	if regs := temporal.Registrations(); regs != nil {
+		status.Registrations = make(map[string]bool, len(regs))
+		for _, r := range regs {
+			status.Registrations[r.Service] = r.Registered
+		}
+	}
+
+	s.healthMu.RLock()
+	for _, h := range s.linkHealth {
+		status.LinksHealthy = status.LinksHealthy && h.Healthy
+	}
+	s.healthMu.RUnlock()
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// versionHandler reports the version and build details of the running process.
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	body, err := json.Marshal(buildinfo.Get())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/buildinfo"
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+func TestHTTPServerProbeLink(t *testing.T) {
+	t.Chdir(t.TempDir()) // Don't write metrics files in the source tree.
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	thrippypb.RegisterThrippyServiceServer(gs, &server{
+		linkResp:  thrippypb.GetLinkResponse_builder{Template: new("test-template")}.Build(),
+		credsResp: thrippypb.GetCredentialsResponse_builder{Credentials: map[string]string{"aaa": "111"}}.Build(),
+	})
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	defer gs.Stop()
+
+	conn, err := thrippy.NewConn(lis.Addr().String(), insecure.NewCredentials(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var probeErr error
+	linkProbes["test"] = func(_ context.Context, _ string, _ map[string]string) error {
+		return probeErr
+	}
+	defer delete(linkProbes, "test")
+
+	s := &HTTPServer{thrippyConn: conn}
+
+	steps := []struct {
+		name    string
+		err     error
+		healthy bool
+	}{
+		{
+			name:    "initially_healthy",
+			healthy: true,
+		},
+		{
+			name: "revoked",
+			err:  errors.New("invalid_auth"),
+		},
+		{
+			name:    "recovered",
+			healthy: true,
+		},
+	}
+
+	for _, step := range steps {
+		probeErr = step.err
+		s.probeLink(t.Context(), "link ID")
+
+		got := s.linkHealth["link ID"]
+		if got.Template != "test-template" {
+			t.Errorf("%s: template = %q, want %q", step.name, got.Template, "test-template")
+		}
+		if got.Healthy != step.healthy {
+			t.Errorf("%s: healthy = %v, want %v", step.name, got.Healthy, step.healthy)
+		}
+		if (got.Error != "") != (step.err != nil) {
+			t.Errorf("%s: error = %q, want %v", step.name, got.Error, step.err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.healthzHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthzHandler() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	resp := healthStatus{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("healthzHandler() body = %q, error = %v", w.Body.String(), err)
+	}
+	if !resp.LinksHealthy {
+		t.Errorf("healthzHandler() = %+v, want healthy links", resp)
+	}
+
+	// The public response doesn't reveal link IDs or error details.
+	probeErr = &url.Error{Op: "Get", URL: "https://example.com/?token=secret", Err: errors.New("invalid_auth")}
+	s.probeLink(t.Context(), "link ID")
+	if got := s.linkHealth["link ID"].Error; strings.Contains(got, "secret") {
+		t.Errorf("link health error = %q, should be redacted", got)
+	}
+
+	w = httptest.NewRecorder()
+	s.healthzHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	if body := w.Body.String(); strings.Contains(body, "link ID") || strings.Contains(body, "invalid_auth") {
+		t.Errorf("healthzHandler() body = %q, want only status booleans", body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("healthzHandler() body = %q, error = %v", w.Body.String(), err)
+	}
+	if resp.LinksHealthy {
+		t.Errorf("healthzHandler() = %+v, want unhealthy links", resp)
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	versionHandler(w, httptest.NewRequest(http.MethodGet, "/version", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Errorf("versionHandler() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	got := buildinfo.Info{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("versionHandler() body = %q, error = %v", w.Body.String(), err)
+	}
+	if want := buildinfo.Get(); got != want {
+		t.Errorf("versionHandler() = %+v, want %+v", got, want)
+	}
+}
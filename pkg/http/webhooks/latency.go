@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// defaultLatencySLO applies to webhook handlers of link templates
+// which aren't covered by [defaultLatencySLOs] or CLI flags.
+const defaultLatencySLO = 2 * time.Second
+
+// defaultLatencySLOs are response time thresholds of webhook handlers, per service,
+// which leave a safety margin below the timeouts of the services themselves (e.g.
+// Slack requires responses within 3 seconds, and GitHub within 10 seconds).
+var defaultLatencySLOs = map[string]time.Duration{
+	"bitbucket": 5 * time.Second,
+	"github":    5 * time.Second,
+	"jira":      5 * time.Second,
+	"slack":     2 * time.Second,
+}
+
+// validateLatencySLOs checks the syntax of the "webhook-latency-slo" CLI flag.
+func validateLatencySLOs(entries []string) error {
+	_, err := parseLatencySLOs(entries)
+	return err
+}
+
+// parseLatencySLOs parses "<template or service>=<duration>" entries,
+// and returns them on top of [defaultLatencySLOs].
+func parseLatencySLOs(entries []string) (map[string]time.Duration, error) {
+	m := maps.Clone(defaultLatencySLOs)
+	var errs []error
+	for _, e := range entries {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(e, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			errs = append(errs, fmt.Errorf("invalid latency SLO %q: expected <template>=<duration>", e))
+			continue
+		}
+
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("invalid %q latency SLO: %q", k, v))
+			continue
+		}
+		m[k] = d
+	}
+
+	return m, errors.Join(errs...)
+}
+
+// latencySLO returns the response time threshold of a link template's webhook handler:
+// specific to the template (e.g. "slack-oauth"), or to its service (e.g. "slack").
+func (s *HTTPServer) latencySLO(template string) time.Duration {
+	if d, ok := s.latencySLOs[template]; ok {
+		return d
+	}
+	service, _, _ := strings.Cut(template, "-")
+	if d, ok := s.latencySLOs[service]; ok {
+		return d
+	}
+	return defaultLatencySLO
+}
+
+// observeLatency records the response time of a webhook handler, and
+// logs a warning with a breakdown by phase if it exceeds the template's SLO.
+func (s *HTTPServer) observeLatency(l *slog.Logger, template string, statusCode int, start time.Time, t *intlis.Timing) {
+	d := time.Since(start)
+	otel.RecordWebhookLatency(start.UTC(), template, statusCode, d)
+
+	if template == "" { // Rejected before identifying the link.
+		return
+	}
+	if slo := s.latencySLO(template); d > slo {
+		l.Warn("slow webhook handler", slog.Int("status", statusCode), slog.Duration("latency", d),
+			slog.Duration("slo", slo), slog.Any("timing", t))
+	}
+}
+
+// statusWriter is an [http.ResponseWriter] which remembers the response's status code.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap supports [http.ResponseController].
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode returns the response's status code, which is
+// implicitly [http.StatusOK] if the handler didn't write anything.
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
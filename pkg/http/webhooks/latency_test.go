@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+)
+
+func TestParseLatencySLOs(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: defaultLatencySLOs,
+		},
+		{
+			name:    "overrides",
+			entries: []string{"slack=1s", " bitbucket-server = 500ms ", ""},
+			want: map[string]time.Duration{
+				"bitbucket":        5 * time.Second,
+				"bitbucket-server": 500 * time.Millisecond,
+				"github":           5 * time.Second,
+				"jira":             5 * time.Second,
+				"slack":            time.Second,
+			},
+		},
+		{
+			name:    "missing_separator",
+			entries: []string{"slack"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid_duration",
+			entries: []string{"slack=fast"},
+			wantErr: true,
+		},
+		{
+			name:    "non_positive_duration",
+			entries: []string{"slack=0s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLatencySLOs(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLatencySLOs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseLatencySLOs() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("parseLatencySLOs()[%q] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPServerLatencySLO(t *testing.T) {
+	slos, err := parseLatencySLOs([]string{"slack-oauth=1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &HTTPServer{latencySLOs: slos}
+
+	tests := []struct {
+		name     string
+		template string
+		want     time.Duration
+	}{
+		{
+			name:     "template",
+			template: "slack-oauth",
+			want:     time.Second,
+		},
+		{
+			name:     "service",
+			template: "slack-bot-token",
+			want:     2 * time.Second,
+		},
+		{
+			name:     "service_without_suffix",
+			template: "github",
+			want:     5 * time.Second,
+		},
+		{
+			name:     "default",
+			template: "unknown-template",
+			want:     defaultLatencySLO,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.latencySLO(tt.template); got != tt.want {
+				t.Errorf("HTTPServer.latencySLO() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPServerObserveLatency(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	tests := []struct {
+		name     string
+		template string
+		latency  time.Duration
+		wantWarn bool
+	}{
+		{
+			name:     "fast",
+			template: "slack-oauth",
+			latency:  time.Millisecond,
+		},
+		{
+			name:     "slow",
+			template: "slack-oauth",
+			latency:  time.Second,
+			wantWarn: true,
+		},
+		{
+			name:    "unknown_template",
+			latency: time.Second,
+		},
+	}
+
+	s := &HTTPServer{latencySLOs: map[string]time.Duration{"slack": 100 * time.Millisecond}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			l := slog.New(slog.NewTextHandler(buf, nil))
+
+			timing := &intlis.Timing{LinkFetch: 2 * time.Millisecond, Dispatch: 900 * time.Millisecond}
+			s.observeLatency(l, tt.template, http.StatusOK, time.Now().Add(-tt.latency), timing)
+
+			if got := strings.Contains(buf.String(), "slow webhook handler"); got != tt.wantWarn {
+				t.Errorf("HTTPServer.observeLatency() warning = %v, want %v: %s", got, tt.wantWarn, buf.String())
+			}
+			if tt.wantWarn && !strings.Contains(buf.String(), "timing.link_fetch=2ms timing.verification=0s timing.dispatch=900ms") {
+				t.Errorf("HTTPServer.observeLatency() log output doesn't contain timing breakdown: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w http.ResponseWriter)
+		want  int
+	}{
+		{
+			name:  "implicit_ok",
+			write: func(_ http.ResponseWriter) {},
+			want:  http.StatusOK,
+		},
+		{
+			name:  "write_header",
+			write: func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			want:  http.StatusNotFound,
+		},
+		{
+			name:  "write_body",
+			write: func(w http.ResponseWriter) { _, _ = w.Write([]byte("challenge")) },
+			want:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &statusWriter{ResponseWriter: httptest.NewRecorder()}
+			tt.write(w)
+			if got := w.statusCode(); got != tt.want {
+				t.Errorf("statusWriter.statusCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// unknownLinkTTL is the duration for which [HTTPServer.webhookLink] remembers
+// link IDs which aren't stateless webhooks in Thrippy, to reject repeated
+// requests with them quickly, while new links become usable soon after
+// they're created in Thrippy.
+const unknownLinkTTL = 30 * time.Second
+
+// linkIDs returns a snapshot of all the known Thrippy link IDs, both
+// configured and discovered (see [HTTPServer.addWebhookLink]).
+func (s *HTTPServer) linkIDs() []string {
+	s.linksMu.RLock()
+	defer s.linksMu.RUnlock()
+
+	return slices.Collect(maps.Keys(s.webhookLinks))
+}
+
+// webhookLink reports whether the given Thrippy link ID is known, and whether it's
+// a stateless webhook. Unknown link IDs which were already looked up in Thrippy
+// less than [unknownLinkTTL] ago are reported as known non-webhooks.
+func (s *HTTPServer) webhookLink(linkID string) (webhook, known bool) {
+	s.linksMu.RLock()
+	defer s.linksMu.RUnlock()
+
+	if webhook, known = s.webhookLinks[linkID]; known {
+		return webhook, true
+	}
+
+	expiry, found := s.unknownLinks[linkID]
+	return false, found && time.Now().Before(expiry)
+}
+
+// setWebhookLink adds or updates a known Thrippy link ID.
+func (s *HTTPServer) setWebhookLink(linkID string, webhook bool) {
+	s.linksMu.Lock()
+	defer s.linksMu.Unlock()
+
+	if s.webhookLinks == nil {
+		s.webhookLinks = map[string]bool{}
+	}
+	s.webhookLinks[linkID] = webhook
+	delete(s.unknownLinks, linkID)
+}
+
+// addWebhookLink adds a stateless webhook link, which isn't in Timpani's configuration but
+// exists in Thrippy, e.g. a Slack link that was created after the server started, so it can
+// receive webhooks (such as Slack's URL verification) without restarting the server.
+func (s *HTTPServer) addWebhookLink(l *slog.Logger, linkID string) {
+	s.setWebhookLink(linkID, true)
+	l.Info("discovered new stateless webhook link in Thrippy")
+}
+
+// addUnknownLink remembers a link ID which isn't a stateless webhook in Thrippy,
+// for [unknownLinkTTL]. It also removes expired link IDs, to bound memory usage.
+func (s *HTTPServer) addUnknownLink(linkID string) {
+	s.linksMu.Lock()
+	defer s.linksMu.Unlock()
+
+	now := time.Now()
+	if s.unknownLinks == nil {
+		s.unknownLinks = map[string]time.Time{}
+	}
+	maps.DeleteFunc(s.unknownLinks, func(_ string, expiry time.Time) bool {
+		return now.After(expiry)
+	})
+	s.unknownLinks[linkID] = now.Add(unknownLinkTTL)
+}
@@ -0,0 +1,224 @@
+package webhooks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lithammer/shortuuid/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/thrippy"
+	"github.com/tzrikka/timpani/pkg/listeners"
+)
+
+// linksServer is a fake Thrippy server whose links can be added during a test.
+type linksServer struct {
+	thrippypb.UnimplementedThrippyServiceServer
+
+	mu        sync.Mutex
+	templates map[string]string
+	calls     int
+}
+
+func (s *linksServer) GetLink(_ context.Context, r *thrippypb.GetLinkRequest) (*thrippypb.GetLinkResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	template, ok := s.templates[r.GetLinkId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "link not found")
+	}
+	return thrippypb.GetLinkResponse_builder{Template: new(template)}.Build(), nil
+}
+
+func (s *linksServer) GetCredentials(_ context.Context, _ *thrippypb.GetCredentialsRequest) (*thrippypb.GetCredentialsResponse, error) {
+	return thrippypb.GetCredentialsResponse_builder{Credentials: map[string]string{"secret": "value"}}.Build(), nil
+}
+
+func (s *linksServer) addLink(linkID, template string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.templates[linkID] = template
+}
+
+func (s *linksServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+func TestWebhookHandlerNewLinks(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	const webhookTemplate = "test-webhook"
+	listeners.WebhookHandlers[webhookTemplate] = func(_ context.Context, _ http.ResponseWriter, _ intlis.RequestData) int {
+		return http.StatusOK
+	}
+	t.Cleanup(func() { delete(listeners.WebhookHandlers, webhookTemplate) })
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := &linksServer{templates: map[string]string{}}
+	gs := grpc.NewServer()
+	thrippypb.RegisterThrippyServiceServer(gs, ts)
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	conn, err := thrippy.NewConn(lis.Addr().String(), insecure.NewCredentials(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s := &HTTPServer{thrippyConn: conn}
+	send := func(linkID string) int {
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/webhook/"+linkID, http.NoBody)
+		r.SetPathValue("id", linkID)
+		w := httptest.NewRecorder()
+		s.webhookHandler(w, r)
+		return w.Code
+	}
+	expire := func(linkID string) {
+		s.linksMu.Lock()
+		s.unknownLinks[linkID] = time.Now().Add(-time.Second)
+		s.linksMu.Unlock()
+	}
+
+	// The link doesn't exist in Thrippy yet.
+	newID := shortuuid.New()
+	if got := send(newID); got != http.StatusNotFound {
+		t.Fatalf("unknown link: status = %d, want %d", got, http.StatusNotFound)
+	}
+
+	// Repeated requests are rejected without looking up the link in Thrippy again.
+	if got := send(newID); got != http.StatusNotFound {
+		t.Fatalf("cached unknown link: status = %d, want %d", got, http.StatusNotFound)
+	}
+	if got := ts.callCount(); got != 1 {
+		t.Errorf("Thrippy calls after cached unknown link = %d, want 1", got)
+	}
+
+	// The link is created in Thrippy, and becomes known after the negative cache expires.
+	ts.addLink(newID, webhookTemplate)
+	expire(newID)
+	if got := send(newID); got != http.StatusOK {
+		t.Fatalf("new link: status = %d, want %d", got, http.StatusOK)
+	}
+	if webhook, known := s.webhookLink(newID); !webhook || !known {
+		t.Errorf("webhookLink() = %v, %v, want true, true", webhook, known)
+	}
+	if got := send(newID); got != http.StatusOK {
+		t.Errorf("known link: status = %d, want %d", got, http.StatusOK)
+	}
+
+	// Links which aren't stateless webhooks are still rejected.
+	connID := shortuuid.New()
+	ts.addLink(connID, "slack-socket-mode")
+	if got := send(connID); got != http.StatusNotFound {
+		t.Errorf("non-webhook link: status = %d, want %d", got, http.StatusNotFound)
+	}
+	if webhook, known := s.webhookLink(connID); webhook || !known {
+		t.Errorf("webhookLink() = %v, %v, want false, true", webhook, known)
+	}
+}
+
+func TestWebhookHandlerLinkRoutes(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	const webhookTemplate = "test-webhook"
+	got := make(chan intlis.TemporalConfig, 1)
+	listeners.WebhookHandlers[webhookTemplate] = func(_ context.Context, _ http.ResponseWriter, r intlis.RequestData) int {
+		got <- r.Temporal
+		return http.StatusOK
+	}
+	t.Cleanup(func() { delete(listeners.WebhookHandlers, webhookTemplate) })
+
+	routedID, sharedID := shortuuid.New(), shortuuid.New()
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := &linksServer{templates: map[string]string{routedID: webhookTemplate, sharedID: webhookTemplate}}
+	gs := grpc.NewServer()
+	thrippypb.RegisterThrippyServiceServer(gs, ts)
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	conn, err := thrippy.NewConn(lis.Addr().String(), insecure.NewCredentials(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s := &HTTPServer{
+		thrippyConn:  conn,
+		webhookLinks: map[string]bool{routedID: true, sharedID: true},
+		temporal: intlis.TemporalConfig{
+			Namespace:  "default",
+			TaskQueue:  "timpani",
+			LinkRoutes: map[string]intlis.LinkRoute{routedID: {Namespace: "team-a", TaskQueue: "team-a-events"}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		linkID        string
+		wantNamespace string
+		wantTaskQueue string
+	}{
+		{
+			name:          "routed_link",
+			linkID:        routedID,
+			wantNamespace: "team-a",
+			wantTaskQueue: "team-a-events",
+		},
+		{
+			name:          "shared_link",
+			linkID:        sharedID,
+			wantNamespace: "default",
+			wantTaskQueue: "timpani",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/webhook/"+tt.linkID, http.NoBody)
+			r.SetPathValue("id", tt.linkID)
+			w := httptest.NewRecorder()
+			s.webhookHandler(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			tc := <-got
+			if tc.Namespace != tt.wantNamespace || tc.TaskQueue != tt.wantTaskQueue {
+				t.Errorf("Temporal config = %s/%s, want %s/%s", tc.Namespace, tc.TaskQueue, tt.wantNamespace, tt.wantTaskQueue)
+			}
+			if tc.LinkRoutes != nil {
+				t.Errorf("Temporal config link routes = %v, want nil", tc.LinkRoutes)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"time"
+
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/redact"
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// reconnectPollInterval is a variable only to facilitate testing without long waits.
+var reconnectPollInterval = time.Minute
+
+// onConnFailure returns an [intlis.LinkData.OnConnFailure] callback, which reports
+// a permanent failure of a link's stateful connections, and waits in the background
+// for new credentials in Thrippy before re-initializing the link's connections.
+func (s *HTTPServer) onConnFailure(ctx context.Context, data intlis.LinkData, f intlis.ConnHandlerFunc) func(error) {
+	return func(err error) {
+		if err == nil {
+			err = errors.New("connection closed unexpectedly")
+		}
+		logger.FromContext(ctx).Error("stateful connection failed permanently, waiting for new credentials in Thrippy",
+			slog.Any("error", redact.Error(err)))
+		s.setConnFailure(data.ID, data.Template, err)
+		go s.reconnectLink(ctx, data, f)
+	}
+}
+
+// reconnectLink polls Thrippy until the credentials of a link with failed stateful
+// connections change, and then re-initializes its connections with them. If that
+// fails too, it keeps waiting for another change. This function blocks until it
+// succeeds, or the context is canceled.
+func (s *HTTPServer) reconnectLink(ctx context.Context, data intlis.LinkData, f intlis.ConnHandlerFunc) {
+	l := logger.FromContext(ctx)
+	ticker := time.NewTicker(reconnectPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		template, secrets, err := s.linkData(ctx, data.ID)
+		if err != nil || secrets == nil || maps.Equal(secrets, data.Secrets) {
+			continue
+		}
+
+		data.Template, data.Secrets = template, secrets
+		data.OnConnFailure = s.onConnFailure(ctx, data, f)
+		if err := f(ctx, s.temporal.ForLink(data.ID), data); err != nil {
+			l.Error("failed to re-initialize connection with new credentials", slog.Any("error", err))
+			continue
+		}
+
+		l.Info("re-initialized stateful connection with new credentials")
+		s.setConnFailure(data.ID, template, nil)
+		return
+	}
+}
+
+// setConnFailure records (or clears, if the error is nil) a permanent failure of a link's
+// stateful connections, and updates the link's health state accordingly. Unlike credentials
+// check failures, this failure persists until the link's connections are re-initialized.
+func (s *HTTPServer) setConnFailure(linkID, template string, err error) {
+	h := LinkHealth{Template: template, Healthy: err == nil, CheckedAt: time.Now().UTC()}
+
+	s.healthMu.Lock()
+	if s.connFailures == nil {
+		s.connFailures = map[string]string{}
+	}
+	if s.linkHealth == nil {
+		s.linkHealth = map[string]LinkHealth{}
+	}
+	if err != nil {
+		h.Error = redact.Error(err).Error()
+		s.connFailures[linkID] = h.Error
+	} else {
+		delete(s.connFailures, linkID)
+	}
+	s.linkHealth[linkID] = h
+	s.healthMu.Unlock()
+
+	otel.SetLinkHealthGauge(h.CheckedAt, linkID, template, h.Healthy)
+}
@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+func TestHTTPServerReconnectLink(t *testing.T) {
+	t.Chdir(t.TempDir()) // Don't write metrics files in the source tree.
+
+	lc := net.ListenConfig{}
+	lis, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	thrippypb.RegisterThrippyServiceServer(gs, &server{
+		linkResp:  thrippypb.GetLinkResponse_builder{Template: new("test-template")}.Build(),
+		credsResp: thrippypb.GetCredentialsResponse_builder{Credentials: map[string]string{"token": "new"}}.Build(),
+	})
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	defer gs.Stop()
+
+	conn, err := thrippy.NewConn(lis.Addr().String(), insecure.NewCredentials(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	linkProbes["test"] = func(_ context.Context, _ string, _ map[string]string) error {
+		return nil // Probes may use different credentials than stateful connections.
+	}
+	defer delete(linkProbes, "test")
+
+	origInterval := reconnectPollInterval
+	reconnectPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { reconnectPollInterval = origInterval })
+
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		wantErr bool // Connection handler error.
+		want    bool // Reconnection.
+	}{
+		{
+			name:    "unchanged_credentials",
+			secrets: map[string]string{"token": "new"},
+		},
+		{
+			name:    "changed_credentials",
+			secrets: map[string]string{"token": "old"},
+			want:    true,
+		},
+		{
+			name:    "changed_credentials_but_connection_error",
+			secrets: map[string]string{"token": "old"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &HTTPServer{thrippyConn: conn}
+			calls := make(chan intlis.LinkData, 10)
+			f := func(_ context.Context, _ intlis.TemporalConfig, data intlis.LinkData) error {
+				calls <- data
+				if tt.wantErr {
+					return errors.New("connection error")
+				}
+				return nil
+			}
+
+			data := intlis.LinkData{ID: "link ID", Template: "test-template", Secrets: tt.secrets}
+			s.onConnFailure(t.Context(), data, f)(errors.New("invalid_auth"))
+
+			s.probeLink(t.Context(), "link ID")
+			s.healthMu.RLock()
+			h := s.linkHealth["link ID"]
+			s.healthMu.RUnlock()
+			if h.Healthy || h.Error == "" {
+				t.Errorf("link health after failure = %+v, want unhealthy", h)
+			}
+
+			select {
+			case got := <-calls:
+				if !tt.want && !tt.wantErr {
+					t.Fatalf("unexpected reconnection with secrets %v", got.Secrets)
+				}
+				if got.Secrets["token"] != "new" || got.OnConnFailure == nil {
+					t.Errorf("reconnection data = %+v, want new secrets and failure callback", got)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.want || tt.wantErr {
+					t.Fatal("timeout waiting for reconnection")
+				}
+				return
+			}
+
+			// Wait for the reconnection result to be recorded.
+			time.Sleep(50 * time.Millisecond)
+			s.probeLink(t.Context(), "link ID")
+			s.healthMu.RLock()
+			h = s.linkHealth["link ID"]
+			s.healthMu.RUnlock()
+			if h.Healthy != tt.want {
+				t.Errorf("link health after reconnection = %+v, want healthy = %v", h, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,7 @@
 package webhooks
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"io"
 	"io/fs"
@@ -13,17 +11,21 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lithammer/shortuuid/v4"
 	"github.com/urfave/cli/v3"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc"
 
 	"github.com/tzrikka/timpani/images"
 	intlis "github.com/tzrikka/timpani/internal/listeners"
 	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/redact"
 	"github.com/tzrikka/timpani/internal/thrippy"
 	"github.com/tzrikka/timpani/pkg/listeners"
+	"github.com/tzrikka/timpani/pkg/listeners/slack"
+	"github.com/tzrikka/timpani/pkg/temporal"
 )
 
 const (
@@ -32,13 +34,25 @@ const (
 )
 
 type HTTPServer struct {
-	httpPort     int             // To initialize the HTTP server.
-	webhookLinks map[string]bool // Configured Thrippy link IDs.
-	thrippyURL   *url.URL        // Optional passthrough for Thrippy OAuth.
+	httpPort   int      // To initialize the HTTP server.
+	thrippyURL *url.URL // Optional passthrough for Thrippy OAuth.
 
-	thrippyGRPCAddr string
-	thrippyCreds    credentials.TransportCredentials
+	linksMu      sync.RWMutex         // Protects webhookLinks and unknownLinks.
+	webhookLinks map[string]bool      // Configured (and discovered) Thrippy link IDs.
+	unknownLinks map[string]time.Time // Negative cache, see [HTTPServer.webhookLink].
 
+	thrippyConn    *grpc.ClientConn
+	thrippyTimeout time.Duration
+
+	slackConns  int                      // Number of Slack Socket Mode connections per link.
+	latencySLOs map[string]time.Duration // Per template or service, see [HTTPServer.latencySLO].
+
+	probeInterval time.Duration         // Thrippy link credentials checks.
+	healthMu      sync.RWMutex          // Protects linkHealth and connFailures.
+	linkHealth    map[string]LinkHealth // Latest results of credentials checks.
+	connFailures  map[string]string     // Permanent failures of stateful connections.
+
+	store    intlis.Store          // State shared by all replicas, see [newStore].
 	temporal intlis.TemporalConfig // Destination for event notifications.
 }
 
@@ -52,22 +66,56 @@ func NewHTTPServer(ctx context.Context, cmd *cli.Command) *HTTPServer {
 		}
 	}
 
+	slack.SetEventAllowlist(cmd.StringSlice("slack-event-allowlist"))
+	slack.SetLinkSignals(cmd.Bool("slack-link-signals"))
+	slack.SetInteractionResponses(cmd.StringSlice("slack-interaction-responses"))
+	slos, _ := parseLatencySLOs(cmd.StringSlice("webhook-latency-slo")) // Already validated.
+
+	routes, _ := intlis.ParseLinkRoutes(cmd.StringSlice("temporal-link-routes")) // Already validated.
+
 	return &HTTPServer{
-		httpPort:     cmd.Int("webhook-port"),
+		httpPort:   cmd.Int("webhook-port"),
+		thrippyURL: baseURL(cmd.String("thrippy-http-address")),
+
 		webhookLinks: links,
-		thrippyURL:   baseURL(cmd.String("thrippy-http-address")),
 
-		thrippyGRPCAddr: cmd.String("thrippy-grpc-address"),
-		thrippyCreds:    thrippy.SecureCreds(ctx, cmd),
+		thrippyConn:    thrippy.Conn(ctx, cmd),
+		thrippyTimeout: cmd.Duration("thrippy-grpc-timeout"),
+
+		slackConns:  cmd.Int("slack-socket-connections"),
+		latencySLOs: slos,
+
+		probeInterval: cmd.Duration("link-probe-interval"),
+		linkHealth:    map[string]LinkHealth{},
 
+		store: newStore(cmd.String("redis-url")),
 		temporal: intlis.TemporalConfig{
 			HostPort:  cmd.String("temporal-address"),
 			Namespace: cmd.String("temporal-namespace"),
 			TaskQueue: cmd.String("temporal-task-queue"),
+
+			CompressSignals: cmd.Bool("temporal-compress-signals"),
+			MaxSignalSize:   cmd.Int("temporal-max-signal-size"),
+
+			LinkRoutes: routes,
 		},
 	}
 }
 
+// newStore returns a [intlis.RedisStore] if a Redis URL is configured, so that all the
+// replicas of the server share the same state, or a local [intlis.MemoryStore] otherwise.
+func newStore(redisURL string) intlis.Store {
+	if redisURL == "" {
+		return intlis.NewMemoryStore()
+	}
+
+	s, err := intlis.NewRedisStore(redisURL)
+	if err != nil {
+		return intlis.NewMemoryStore() // Unreachable, the flag was already validated.
+	}
+	return s
+}
+
 // baseURL converts the given address (e.g. "localhost:14460") into a URL.
 // If the address is empty, this function returns a nil reference.
 func baseURL(addr string) *url.URL {
@@ -100,12 +148,11 @@ func baseURL(addr string) *url.URL {
 
 // Run starts an HTTP server to expose webhooks, and blocks forever.
 func (s *HTTPServer) Run(ctx context.Context) {
-	http.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
+	http.HandleFunc("GET /healthz", s.healthzHandler)
+	http.HandleFunc("GET /version", versionHandler)
 
-	http.HandleFunc("GET /webhook/{id...}", s.webhookHandler)
-	http.HandleFunc("POST /webhook/{id...}", s.webhookHandler)
+	http.HandleFunc("GET /webhook/{id...}", recoverPanics(s.webhookHandler))
+	http.HandleFunc("POST /webhook/{id...}", recoverPanics(s.webhookHandler))
 
 	if s.thrippyURL != nil {
 		slog.Info("HTTP passthrough for Thrippy OAuth callbacks: " + s.thrippyURL.String())
@@ -132,14 +179,46 @@ func (s *HTTPServer) Run(ctx context.Context) {
 	_ = server.ListenAndServe()
 }
 
+// recoverPanics is an HTTP middleware that assigns a unique ID to each request, for
+// logging, and survives panics in the handler (e.g. due to unexpected payload shapes)
+// by logging them with the request ID and responding with an HTTP 500 error.
+func recoverPanics(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := logger.FromContext(r.Context()).With(slog.String("request_id", shortuuid.New()))
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				intlis.ReportPanic(l, "webhook", p)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+
+		next(w, r.WithContext(logger.WithContext(r.Context(), l)))
+	}
+}
+
 // webhookHandler checks and processes incoming asynchronous
 // event notifications over HTTP from third-party services.
+// It also monitors its response times, see [HTTPServer.observeLatency].
 func (s *HTTPServer) webhookHandler(w http.ResponseWriter, r *http.Request) {
-	l := slog.With(slog.String("http_method", r.Method), slog.String("url_path", r.URL.EscapedPath()))
+	start, timing, sw := time.Now(), new(intlis.Timing), &statusWriter{ResponseWriter: w}
+	w = sw
+
+	l := logger.FromContext(r.Context())
+	template := ""
+	defer func() { s.observeLatency(l, template, sw.statusCode(), start, timing) }()
+
+	l = l.With(slog.String("http_method", r.Method), slog.String("url_path", r.URL.EscapedPath()))
+	if r.URL.RawQuery != "" {
+		l = l.With(slog.String("url_query", redact.Query(r.URL.RawQuery)))
+	}
 	if r.Method == http.MethodPost {
 		l = l.With(slog.String("content_type", r.Header.Get("Content-Type")))
 	}
 	l.Info("received HTTP request")
+	l.Log(r.Context(), logger.LevelTrace, "HTTP request headers", slog.Any("headers", redact.Header(r.Header)))
 
 	linkID, pathSuffix, statusCode := parseURL(l, r)
 	if statusCode != http.StatusOK {
@@ -151,29 +230,45 @@ func (s *HTTPServer) webhookHandler(w http.ResponseWriter, r *http.Request) {
 		l = l.With(slog.String("path_suffix", pathSuffix))
 	}
 
-	// Nuance: "configured, ok := ..." can return "false, true"
-	// (if the link is configured, but not as a stateless webhook).
-	if configured := s.webhookLinks[linkID]; !configured {
+	// Nuance: this can return "false, true" (if the link is configured, but not as a
+	// stateless webhook, or if it's unknown and was recently looked up in Thrippy).
+	webhook, known := s.webhookLink(linkID)
+	if known && !webhook {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	fetched := time.Now()
 	template, secrets, err := s.linkData(r.Context(), linkID)
+	timing.LinkFetch = time.Since(fetched)
 	if statusCode := checkLinkDataForWebhook(l, template, secrets, err); statusCode != http.StatusOK {
+		if !known && statusCode == http.StatusNotFound {
+			s.addUnknownLink(linkID)
+		}
 		w.WriteHeader(statusCode)
 		return
 	}
 
-	raw, decoded, err := parseBody(w, r)
+	// Links which were created in Thrippy after the server started.
+	if !known {
+		if _, ok := listeners.WebhookHandlers[template]; !ok {
+			l.Warn("bad request: unknown link is not a stateless webhook", slog.String("template", template))
+			s.addUnknownLink(linkID)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.addWebhookLink(l, linkID)
+	}
+
+	// JSON and web form decoding is deferred to the service-specific handler, after it verifies
+	// the request's signature: forged requests mustn't be able to make us burn CPU on parsing.
+	raw, err := readBody(w, r)
 	if err != nil {
-		l.Warn("bad request: JSON decoding error", slog.Any("error", err))
+		l.Warn("bad request: body reading error", slog.Any("error", err))
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	r.Body = io.NopCloser(bytes.NewReader(raw))
-	_ = r.ParseForm() //gosec:disable G120 // Body size already limited by [parseBody] call above.
-
 	// Forward the request's data to a service-specific handler.
 	l = l.With(slog.String("template", template))
 	f, ok := listeners.WebhookHandlers[template]
@@ -184,13 +279,13 @@ func (s *HTTPServer) webhookHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	statusCode = f(logger.WithContext(r.Context(), l), w, intlis.RequestData{
+		LinkID:      linkID,
 		PathSuffix:  pathSuffix,
 		Headers:     r.Header,
-		WebForm:     r.Form,
 		RawPayload:  raw,
-		JSONPayload: decoded,
 		LinkSecrets: secrets,
-		Temporal:    s.temporal,
+		Temporal:    s.temporal.ForLink(linkID),
+		Timing:      timing,
 	})
 	if statusCode != 0 {
 		w.WriteHeader(statusCode)
@@ -222,35 +317,32 @@ func parseURL(l *slog.Logger, r *http.Request) (string, string, int) {
 	return id, suffix, http.StatusOK
 }
 
-// parseBody tries to parse the given HTTP request body as JSON.
-// It also returns the raw payload to support authenticity checks.
-// If the request is not a POST with a JSON content type, it returns nil.
-func parseBody(w http.ResponseWriter, r *http.Request) ([]byte, map[string]any, error) {
+// readBody reads the given HTTP request body, up to [MaxSize] bytes, without parsing
+// it (see [intlis.RequestData.DecodeJSON] and [intlis.RequestData.DecodeForm]). If the request is not a POST, it returns nil.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
 	if r.Method != http.MethodPost {
-		return nil, nil, nil
+		return nil, nil
 	}
 
 	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSize))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-		return raw, nil, nil
-	}
-
-	var decoded map[string]any
-	if err := json.Unmarshal(raw, &decoded); err != nil {
-		return nil, nil, err
-	}
+	return raw, nil
+}
 
-	return raw, decoded, nil
+// CheckLinkRoutes ensures that the Temporal namespaces of all the configured
+// per-link routes exist, see [temporal.CheckLinkNamespaces]. It should be
+// called before the server starts to receive events.
+func (s *HTTPServer) CheckLinkRoutes(ctx context.Context) error {
+	return temporal.CheckLinkNamespaces(ctx, s.temporal)
 }
 
 // ConnectLinks initializes stateful connections for all the
 // configured Thrippy links that are not stateless webhooks.
 func (s *HTTPServer) ConnectLinks(ctx context.Context) error {
-	for linkID := range s.webhookLinks {
+	for _, linkID := range s.linkIDs() {
 		if linkID == "" {
 			continue
 		}
@@ -274,10 +366,11 @@ func (s *HTTPServer) ConnectLinks(ctx context.Context) error {
 			return err
 		}
 
-		s.webhookLinks[linkID] = false // Connections are configured, but are not stateless webhooks.
+		s.setWebhookLink(linkID, false) // Connections are configured, but are not stateless webhooks.
 
-		data := intlis.LinkData{ID: linkID, Template: template, Secrets: secrets}
-		if err := f(ctx, s.temporal, data); err != nil {
+		data := intlis.LinkData{ID: linkID, Template: template, Secrets: secrets, Connections: s.slackConns, Store: s.store}
+		data.OnConnFailure = s.onConnFailure(logger.WithContext(ctx, l), data, f)
+		if err := f(logger.WithContext(ctx, l), s.temporal.ForLink(linkID), data); err != nil {
 			l.Error("failed to initialize connection", slog.Any("error", err))
 			return err
 		}
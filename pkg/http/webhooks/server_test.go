@@ -1,6 +1,7 @@
 package webhooks
 
 import (
+	"bytes"
 	"io"
 	"log/slog"
 	"net/http"
@@ -9,6 +10,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	intlis "github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
 )
 
 func TestBaseURL(t *testing.T) {
@@ -120,15 +124,17 @@ func TestParseURL(t *testing.T) {
 	}
 }
 
-func TestParseBody(t *testing.T) {
+func TestReadBody(t *testing.T) {
 	tests := []struct {
-		name        string
-		method      string
-		contentType string
-		body        string
-		wantRaw     []byte
-		wantDecoded map[string]any
-		wantErr     bool
+		name          string
+		method        string
+		contentType   string
+		body          string
+		wantRaw       []byte
+		wantReadErr   bool
+		wantDecoded   map[string]any
+		wantDecodeErr bool
+		wantForm      url.Values
 	}{
 		{
 			name:   "get_without_body",
@@ -140,6 +146,7 @@ func TestParseBody(t *testing.T) {
 			contentType: "application/x-www-form-urlencoded",
 			body:        "key1=value1&key2=value2",
 			wantRaw:     []byte("key1=value1&key2=value2"),
+			wantForm:    url.Values{"key1": {"value1"}, "key2": {"value2"}},
 		},
 		{
 			name:        "post_json",
@@ -158,11 +165,19 @@ func TestParseBody(t *testing.T) {
 			wantDecoded: map[string]any{"key": "value"},
 		},
 		{
-			name:        "post_invalid_json",
+			name:          "post_invalid_json",
+			method:        http.MethodPost,
+			contentType:   "application/json",
+			body:          "{invalid json}",
+			wantRaw:       []byte("{invalid json}"),
+			wantDecodeErr: true,
+		},
+		{
+			name:        "post_too_large",
 			method:      http.MethodPost,
 			contentType: "application/json",
-			body:        "{invalid json}",
-			wantErr:     true,
+			body:        strings.Repeat(" ", MaxSize+1),
+			wantReadErr: true,
 		},
 	}
 
@@ -173,17 +188,94 @@ func TestParseBody(t *testing.T) {
 			r.Header.Set("Content-Type", tt.contentType)
 			w := httptest.NewRecorder()
 
-			raw, decoded, err := parseBody(w, r)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseBody() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			raw, err := readBody(w, r)
+			if (err != nil) != tt.wantReadErr {
+				t.Fatalf("readBody() error = %v, wantErr %v", err, tt.wantReadErr)
 			}
 			if !reflect.DeepEqual(raw, tt.wantRaw) {
-				t.Errorf("parseBody() raw = %q, want %q", raw, tt.wantRaw)
+				t.Errorf("readBody() = %q, want %q", raw, tt.wantRaw)
+			}
+
+			data := intlis.RequestData{Headers: r.Header, RawPayload: raw}
+			decoded, err := data.DecodeJSON()
+			if (err != nil) != tt.wantDecodeErr {
+				t.Fatalf("DecodeJSON() error = %v, wantErr %v", err, tt.wantDecodeErr)
 			}
 			if !reflect.DeepEqual(decoded, tt.wantDecoded) {
-				t.Errorf("parseBody() decoded = %v, want %v", decoded, tt.wantDecoded)
+				t.Errorf("DecodeJSON() = %v, want %v", decoded, tt.wantDecoded)
+			}
+
+			form, err := data.DecodeForm()
+			if err != nil {
+				t.Fatalf("DecodeForm() error = %v", err)
+			}
+			if !reflect.DeepEqual(form, tt.wantForm) {
+				t.Errorf("DecodeForm() = %v, want %v", form, tt.wantForm)
 			}
 		})
 	}
 }
+
+func TestRecoverPanics(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	buf := new(bytes.Buffer)
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+
+	var gotID bool
+	h := recoverPanics(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("panic") {
+			panic("unexpected payload shape")
+		}
+		logger.FromContext(r.Context()).Info("no panic")
+		gotID = strings.Contains(buf.String(), `"request_id"`)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequestWithContext(logger.WithContext(t.Context(), l), http.MethodPost, "/webhook/id?panic", http.NoBody)
+	h(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("recoverPanics() status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	for _, s := range []string{"recovered from panic", `"request_id"`, `"stack"`, "unexpected payload shape"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("log output doesn't contain %q: %s", s, buf.String())
+		}
+	}
+
+	// The server survives, and handles subsequent requests normally.
+	buf.Reset()
+	w = httptest.NewRecorder()
+	r = httptest.NewRequestWithContext(logger.WithContext(t.Context(), l), http.MethodPost, "/webhook/id", http.NoBody)
+	h(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("recoverPanics() status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if !gotID {
+		t.Errorf("handler log output doesn't contain a request ID: %s", buf.String())
+	}
+}
+
+func TestWebhookHandlerRedaction(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	buf := new(bytes.Buffer)
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: logger.LevelTrace}))
+
+	r := httptest.NewRequestWithContext(logger.WithContext(t.Context(), l), http.MethodGet, "/webhook/id?ticket=secret&foo=bar", http.NoBody)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.SetPathValue("id", "id")
+
+	s := &HTTPServer{webhookLinks: map[string]bool{"id": false}} // Known, so not looked up in Thrippy.
+	s.webhookHandler(httptest.NewRecorder(), r)
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("log output contains secrets: %s", buf.String())
+	}
+	for _, want := range []string{"foo=bar", "ticket=REDACTED", `"Authorization":["REDACTED"]`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log output doesn't contain %q: %s", want, buf.String())
+		}
+	}
+}
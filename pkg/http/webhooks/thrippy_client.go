@@ -6,12 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
 	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/thrippy"
 )
 
 // linkData returns the template name and saved secrets of the given Thrippy link.
@@ -20,15 +20,8 @@ import (
 func (s *HTTPServer) linkData(ctx context.Context, linkID string) (string, map[string]string, error) {
 	l := logger.FromContext(ctx).With(slog.String("link_id", linkID))
 
-	conn, err := grpc.NewClient(s.thrippyGRPCAddr, grpc.WithTransportCredentials(s.thrippyCreds))
-	if err != nil {
-		l.Error("gRPC connection error", slog.Any("error", err))
-		return "", nil, err
-	}
-	defer conn.Close()
-
-	c := thrippypb.NewThrippyServiceClient(conn)
-	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	c := thrippypb.NewThrippyServiceClient(s.thrippyConn)
+	ctx, cancel := thrippy.CallContext(ctx, s.thrippyTimeout)
 	defer cancel()
 
 	// Template.
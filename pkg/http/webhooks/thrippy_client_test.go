@@ -15,6 +15,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	thrippypb "github.com/tzrikka/thrippy-api/thrippy/v1"
+	"github.com/tzrikka/timpani/internal/thrippy"
 )
 
 type server struct {
@@ -89,10 +90,13 @@ func TestHTTPServerLinkData(t *testing.T) {
 				_ = gs.Serve(lis)
 			}()
 
-			hs := &HTTPServer{
-				thrippyGRPCAddr: lis.Addr().String(),
-				thrippyCreds:    insecure.NewCredentials(),
+			conn, err := thrippy.NewConn(lis.Addr().String(), insecure.NewCredentials(), 0)
+			if err != nil {
+				t.Fatal(err)
 			}
+			defer conn.Close()
+
+			hs := &HTTPServer{thrippyConn: conn}
 
 			template, secrets, err := hs.linkData(t.Context(), "link ID")
 			if (err != nil) != tt.wantErr {
@@ -0,0 +1,123 @@
+package bitbucket
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/pkg/otel"
+	"github.com/tzrikka/timpani/pkg/temporal"
+)
+
+const (
+	serverSignatureHeader = "X-Hub-Signature"
+	serverPingEvent       = "diagnostics:ping"
+)
+
+// ServerWebhookVerifier exposes the verification steps of [ServerWebhookHandler], in order.
+var ServerWebhookVerifier = listeners.WebhookVerifier{
+	SecretName: "webhook_secret",
+	Checks: []listeners.WebhookCheck{
+		{Name: "content type", Check: checkServerContentTypeHeader},
+		{Name: "signature", Check: checkServerSignature},
+	},
+	Sign: signServerRequest,
+}
+
+// ServerWebhookHandler handles webhook events from Bitbucket Server / Data Center, based on:
+// https://confluence.atlassian.com/bitbucketserver/manage-webhooks-938025878.html
+func ServerWebhookHandler(ctx context.Context, _ http.ResponseWriter, r listeners.RequestData) int {
+	l := logger.FromContext(ctx).With(slog.String("link_type", "bitbucket-server"), slog.String("link_medium", "webhook"))
+	t := time.Now().UTC()
+
+	if statusCode := ServerWebhookVerifier.Verify(l, r); statusCode != http.StatusOK {
+		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
+	}
+
+	// The "Test connection" button in the webhook settings.
+	event := r.Headers.Get(eventHeader)
+	if event == "" {
+		l.Warn("bad request: missing header", slog.String("header", eventHeader))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
+	}
+	if event == serverPingEvent {
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusOK)
+	}
+
+	payload, err := r.DecodeJSON()
+	if err != nil {
+		l.Warn("bad request: JSON decoding error", slog.Any("error", err))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
+	}
+
+	// Dispatch the event notification as a Temporal signal.
+	signalName := serverSignalName(event)
+	payload = temporal.WithOriginalEvent(payload, event)
+	payload = temporal.WithReceipt(payload, t, temporal.ListenerWebhook)
+	dispatched := time.Now()
+	err = temporal.Signal(logger.WithContext(ctx, l), r.Temporal, signalName, payload)
+	r.Timing.Dispatched(dispatched)
+	if err != nil {
+		l.Error("failed to send Temporal signal", slog.Any("error", err))
+		return otel.IncrementWebhookEventCounter(l, t, signalName, http.StatusInternalServerError)
+	}
+
+	return otel.IncrementWebhookEventCounter(l, t, signalName, http.StatusOK)
+}
+
+// serverSignalName maps event keys (e.g. "pr:comment:added") to Temporal
+// signal names (e.g. "bitbucket.server.events.pr.comment.added").
+func serverSignalName(event string) string {
+	return "bitbucket.server.events." + strings.ReplaceAll(event, ":", ".")
+}
+
+// checkServerContentTypeHeader allows the content type to also specify a charset, unlike Bitbucket Cloud.
+func checkServerContentTypeHeader(l *slog.Logger, r listeners.RequestData) int {
+	if ct := r.Headers.Get(contentTypeHeader); !strings.HasPrefix(ct, contentTypeJSON) {
+		l.Warn("bad request: unexpected header value", slog.String("header", contentTypeHeader),
+			slog.String("got", ct), slog.String("want", contentTypeJSON))
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+// checkServerSignature is similar to [github.CheckSignatureHeader], except that Bitbucket
+// Server uses the "X-Hub-Signature" header name for HMAC-SHA256 signatures. The webhook
+// secret is optional in Bitbucket Server, just like in Bitbucket Cloud.
+func checkServerSignature(l *slog.Logger, r listeners.RequestData) int {
+	secret := r.LinkSecrets["webhook_secret"]
+	if secret == "" {
+		return http.StatusOK
+	}
+
+	sig := r.Headers.Get(serverSignatureHeader)
+	if sig == "" {
+		l.Warn("bad request: missing header", slog.String("header", serverSignatureHeader))
+		return http.StatusForbidden
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(serverSignature(secret, r.RawPayload))) {
+		l.Warn("signature verification failed", slog.String("header", serverSignatureHeader), slog.String("signature", sig))
+		return http.StatusForbidden
+	}
+
+	return http.StatusOK
+}
+
+func serverSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signServerRequest returns the signature header that Bitbucket Server would send with the given body.
+func signServerRequest(secret string, body []byte, _ time.Time) http.Header {
+	return http.Header{serverSignatureHeader: []string{serverSignature(secret, body)}}
+}
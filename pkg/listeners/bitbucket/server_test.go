@@ -0,0 +1,146 @@
+package bitbucket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+func TestServerWebhookHandler(t *testing.T) {
+	body := []byte(`{"eventKey":"diagnostics:ping"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	invalid := []byte(`{"eventKey":`)
+	mac.Reset()
+	mac.Write(invalid)
+	invalidSig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name    string
+		ct      string
+		event   string
+		noEvent bool
+		body    []byte
+		sig     string
+		secret  string
+		want    int
+	}{
+		{
+			name: "bad_content_type",
+			ct:   "text/plain",
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "no_secret",
+			ct:   "application/json; charset=utf-8",
+			want: http.StatusOK,
+		},
+		{
+			name:   "missing_signature",
+			ct:     "application/json; charset=utf-8",
+			secret: "secret",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "bad_signature",
+			ct:     "application/json",
+			sig:    "sha256=0123456789abcdef",
+			secret: "secret",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "good_signature",
+			ct:     "application/json; charset=utf-8",
+			sig:    sig,
+			secret: "secret",
+			want:   http.StatusOK,
+		},
+		{
+			name:    "missing_event",
+			ct:      "application/json; charset=utf-8",
+			noEvent: true,
+			sig:     sig,
+			secret:  "secret",
+			want:    http.StatusBadRequest,
+		},
+		{
+			name:   "bad_signature_invalid_json",
+			ct:     "application/json",
+			event:  "pr:opened",
+			body:   invalid,
+			sig:    sig,
+			secret: "secret",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "good_signature_invalid_json",
+			ct:     "application/json",
+			event:  "pr:opened",
+			body:   invalid,
+			sig:    invalidSig,
+			secret: "secret",
+			want:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			event, payload := tt.event, tt.body
+			if event == "" {
+				event, payload = serverPingEvent, body
+			}
+			if tt.noEvent {
+				event = ""
+			}
+			r := listeners.RequestData{
+				Headers: http.Header{
+					contentTypeHeader:     []string{tt.ct},
+					eventHeader:           []string{event},
+					serverSignatureHeader: []string{tt.sig},
+				},
+				RawPayload:  payload,
+				LinkSecrets: map[string]string{"webhook_secret": tt.secret},
+			}
+
+			if got := ServerWebhookHandler(t.Context(), nil, r); got != tt.want {
+				t.Errorf("ServerWebhookHandler() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerSignalName(t *testing.T) {
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{
+			event: "pr:opened",
+			want:  "bitbucket.server.events.pr.opened",
+		},
+		{
+			event: "pr:comment:added",
+			want:  "bitbucket.server.events.pr.comment.added",
+		},
+		{
+			event: "repo:refs_changed",
+			want:  "bitbucket.server.events.repo.refs_changed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			if got := serverSignalName(tt.event); got != tt.want {
+				t.Errorf("serverSignalName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
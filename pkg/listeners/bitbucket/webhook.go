@@ -20,32 +20,69 @@ const (
 	eventHeader       = "X-Event-Key"
 )
 
+// WebhookVerifier exposes the verification steps of [WebhookHandler], in order.
+var WebhookVerifier = listeners.WebhookVerifier{
+	SecretName: "webhook_secret",
+	Checks: []listeners.WebhookCheck{
+		{Name: "content type", Check: checkContentTypeHeader},
+		{Name: "signature", Check: checkSignatureHeader},
+	},
+	Sign: github.WebhookVerifier.Sign,
+}
+
 func WebhookHandler(ctx context.Context, _ http.ResponseWriter, r listeners.RequestData) int {
 	l := logger.FromContext(ctx).With(slog.String("link_type", "bitbucket"), slog.String("link_medium", "webhook"))
 	t := time.Now().UTC()
 
-	if ct := r.Headers.Get(contentTypeHeader); ct != contentTypeJSON {
-		l.Warn("bad request: unexpected header value", slog.String("header", contentTypeHeader),
-			slog.String("got", ct), slog.String("want", contentTypeJSON))
+	if statusCode := WebhookVerifier.Verify(l, r); statusCode != http.StatusOK {
+		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
+	}
+
+	// Misconfigured forwarders may strip this header, which is needed for routing.
+	event := r.Headers.Get(eventHeader)
+	if event == "" {
+		l.Warn("bad request: missing header", slog.String("header", eventHeader))
 		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
 	}
 
-	// Note 1: Bitbucket uses the exact same signature checking method as GitHub.
-	// Note 2: Some large customers of Bitbnucket use proxies to fan-out webhook
-	// events instead of using many webhook registrations, in order to avoid
-	// hitting rate limits. In such cases, the webhook secret may be blank.
-	if r.LinkSecrets["webhook_secret"] != "" {
-		if statusCode := github.CheckSignatureHeader(l, r); statusCode != http.StatusOK {
-			return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
-		}
+	payload, err := r.DecodeJSON()
+	if err != nil {
+		l.Warn("bad request: JSON decoding error", slog.Any("error", err))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
 	}
 
 	// Dispatch the event notification as a Temporal signal.
-	signalName := "bitbucket.events." + strings.ReplaceAll(r.Headers.Get(eventHeader), ":", ".")
-	if err := temporal.Signal(ctx, r.Temporal, signalName, r.JSONPayload); err != nil {
+	signalName := "bitbucket.events." + strings.ReplaceAll(event, ":", ".")
+	payload = temporal.WithOriginalEvent(payload, event)
+	payload = temporal.WithReceipt(payload, t, temporal.ListenerWebhook)
+	dispatched := time.Now()
+	err = temporal.Signal(logger.WithContext(ctx, l), r.Temporal, signalName, payload)
+	r.Timing.Dispatched(dispatched)
+	if err != nil {
 		l.Error("failed to send Temporal signal", slog.Any("error", err))
 		return otel.IncrementWebhookEventCounter(l, t, signalName, http.StatusInternalServerError)
 	}
 
 	return otel.IncrementWebhookEventCounter(l, t, signalName, http.StatusOK)
 }
+
+func checkContentTypeHeader(l *slog.Logger, r listeners.RequestData) int {
+	if ct := r.Headers.Get(contentTypeHeader); ct != contentTypeJSON {
+		l.Warn("bad request: unexpected header value", slog.String("header", contentTypeHeader),
+			slog.String("got", ct), slog.String("want", contentTypeJSON))
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+// checkSignatureHeader uses the exact same signature checking method as GitHub.
+//
+// Some large customers of Bitbucket use proxies to fan-out webhook events instead
+// of using many webhook registrations, in order to avoid hitting rate limits.
+// In such cases, the webhook secret may be blank.
+func checkSignatureHeader(l *slog.Logger, r listeners.RequestData) int {
+	if r.LinkSecrets["webhook_secret"] == "" {
+		return http.StatusOK
+	}
+	return github.CheckSignatureHeader(l, r)
+}
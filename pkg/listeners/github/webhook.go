@@ -5,12 +5,15 @@ package github
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/sha1" //gosec:disable G505 // Opt-in fallback for legacy webhook deliveries.
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"hash"
 	"log/slog"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,31 +28,68 @@ const (
 	contentTypeHeader = "Content-Type"
 	eventHeader       = "X-Github-Event"
 	signatureHeader   = "X-Hub-Signature-256"
+
+	// legacySignatureHeader is HMAC-SHA1, which GitHub still sends alongside
+	// [signatureHeader], but some GHES installations and legacy hook configurations
+	// send only this one. Checking it requires the link secret [allowSHA1Secret].
+	legacySignatureHeader = "X-Hub-Signature"
+	allowSHA1Secret       = "allow_sha1_signature"
 )
 
+// WebhookVerifier exposes the verification steps of [WebhookHandler], in order.
+var WebhookVerifier = listeners.WebhookVerifier{
+	SecretName: "webhook_secret",
+	Checks: []listeners.WebhookCheck{
+		{Name: "content type", Check: checkContentTypeHeader},
+		{Name: "signature", Check: CheckSignatureHeader},
+	},
+	Sign: signRequest,
+}
+
 func WebhookHandler(ctx context.Context, _ http.ResponseWriter, r listeners.RequestData) int {
 	l := logger.FromContext(ctx).With(slog.String("link_type", "github"), slog.String("link_medium", "webhook"))
 	t := time.Now().UTC()
 
-	if statusCode := checkContentTypeHeader(l, r); statusCode != http.StatusOK {
+	if statusCode := WebhookVerifier.Verify(l, r); statusCode != http.StatusOK {
 		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
 	}
-	if statusCode := CheckSignatureHeader(l, r); statusCode != http.StatusOK {
-		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
+
+	// Misconfigured forwarders may strip this header, which is needed for routing.
+	event := r.Headers.Get(eventHeader)
+	if event == "" {
+		l.Warn("bad request: missing header", slog.String("header", eventHeader))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
+	}
+
+	payload, err := r.DecodeJSON()
+	if err != nil {
+		l.Warn("bad request: JSON decoding error", slog.Any("error", err))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
 	}
 
 	// If the payload is a web form, convert it to JSON.
 	if r.Headers.Get(contentTypeHeader) == client.ContentForm {
-		reader := strings.NewReader(r.WebForm.Get("payload"))
-		if err := json.NewDecoder(reader).Decode(&r.JSONPayload); err != nil {
+		form, err := r.DecodeForm()
+		if err != nil {
+			l.Warn("bad request: web form decoding error", slog.Any("error", err))
+			return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
+		}
+
+		reader := strings.NewReader(form.Get("payload"))
+		if err := json.NewDecoder(reader).Decode(&payload); err != nil {
 			l.Error("failed to extract and decode JSON payload from form data", slog.Any("error", err))
 			return otel.IncrementWebhookEventCounter(l, t, "", http.StatusInternalServerError)
 		}
 	}
 
 	// Dispatch the event notification as a Temporal signal.
-	signalName := "github.events." + r.Headers.Get(eventHeader)
-	if err := temporal.Signal(ctx, r.Temporal, signalName, r.JSONPayload); err != nil {
+	signalName := "github.events." + event
+	payload = temporal.WithOriginalEvent(payload, event)
+	payload = temporal.WithReceipt(payload, t, temporal.ListenerWebhook)
+	dispatched := time.Now()
+	err = temporal.Signal(logger.WithContext(ctx, l), r.Temporal, signalName, payload)
+	r.Timing.Dispatched(dispatched)
+	if err != nil {
 		l.Error("failed to send Temporal signal", slog.Any("error", err))
 		return otel.IncrementWebhookEventCounter(l, t, signalName, http.StatusInternalServerError)
 	}
@@ -72,7 +112,12 @@ func checkContentTypeHeader(l *slog.Logger, r listeners.RequestData) int {
 
 // CheckSignatureHeader is defined by and for GitHub, but also reused by Bitbucket.
 func CheckSignatureHeader(l *slog.Logger, r listeners.RequestData) int {
-	sig := r.Headers.Get(signatureHeader)
+	header, h, prefix := signatureHeader, sha256.New, "sha256="
+	sig := r.Headers.Get(header)
+	if sig == "" && allowSHA1(r) {
+		header, h, prefix = legacySignatureHeader, sha1.New, "sha1="
+		sig = r.Headers.Get(header)
+	}
 	if sig == "" {
 		l.Warn("bad request: missing header", slog.String("header", signatureHeader))
 		return http.StatusForbidden
@@ -84,29 +129,37 @@ func CheckSignatureHeader(l *slog.Logger, r listeners.RequestData) int {
 		return http.StatusInternalServerError
 	}
 
-	if !verifySignature(l, secret, sig, r.RawPayload) {
-		l.Warn("signature verification failed", slog.String("signature", sig),
+	if !hmac.Equal([]byte(signature(h, prefix, secret, r.RawPayload)), []byte(sig)) {
+		l.Warn("signature verification failed", slog.String("header", header), slog.String("signature", sig),
 			slog.Bool("has_signing_secret", secret != ""))
 		return http.StatusForbidden
 	}
 
+	if header == legacySignatureHeader {
+		l.Warn("deprecated: webhook delivery verified with HMAC-SHA1 signature",
+			slog.String("header", legacySignatureHeader), slog.String("want", signatureHeader))
+		otel.IncrementDeprecationCounter(time.Now().UTC(), "webhook_sha1_signature")
+	}
+
 	return http.StatusOK
 }
 
-// verifySignature implements
-// https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries.
-func verifySignature(l *slog.Logger, webhookSecret, want string, body []byte) bool {
-	mac := hmac.New(sha256.New, []byte(webhookSecret))
+// allowSHA1 checks whether the link is configured to accept
+// deliveries which are signed only with [legacySignatureHeader].
+func allowSHA1(r listeners.RequestData) bool {
+	allow, _ := strconv.ParseBool(r.LinkSecrets[allowSHA1Secret])
+	return allow
+}
 
-	n, err := mac.Write(body)
-	if err != nil {
-		l.Error("HMAC write error", slog.Any("error", err))
-		return false
-	}
-	if n != len(body) {
-		return false
-	}
+// signature implements
+// https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries.
+func signature(h func() hash.Hash, prefix, webhookSecret string, body []byte) string {
+	mac := hmac.New(h, []byte(webhookSecret))
+	_, _ = mac.Write(body)
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
 
-	got := "sha256=" + hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(got), []byte(want))
+// signRequest returns the signature header that GitHub would send with the given body.
+func signRequest(webhookSecret string, body []byte, _ time.Time) http.Header {
+	return http.Header{signatureHeader: []string{signature(sha256.New, "sha256=", webhookSecret, body)}}
 }
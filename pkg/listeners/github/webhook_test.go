@@ -1,13 +1,41 @@
 package github
 
 import (
+	"bytes"
+	"encoding/json"
 	"log/slog"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
 )
 
+func TestWebhookHandlerLogFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := slog.New(slog.NewJSONHandler(buf, nil)).With(slog.String("link_id", "link ID"))
+	ctx := logger.WithContext(t.Context(), l)
+
+	r := listeners.RequestData{Headers: http.Header{contentTypeHeader: []string{"text/plain"}}}
+	if got := WebhookHandler(ctx, nil, r); got != http.StatusBadRequest {
+		t.Fatalf("WebhookHandler() = %d, want %d", got, http.StatusBadRequest)
+	}
+
+	got := map[string]any{}
+	line, _, _ := bytes.Cut(buf.Bytes(), []byte("\n"))
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", line, err)
+	}
+
+	want := map[string]string{"link_id": "link ID", "link_type": "github", "link_medium": "webhook"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("WebhookHandler() log field %q = %v, want %q", k, got[k], v)
+		}
+	}
+}
+
 func TestCheckContentTypeHeader(t *testing.T) {
 	tests := []struct {
 		name string
@@ -56,11 +84,15 @@ func TestCheckContentTypeHeader(t *testing.T) {
 }
 
 func TestCheckSignatureHeader(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
 	tests := []struct {
-		name   string
-		sig    string
-		secret string
-		want   int
+		name      string
+		sig       string
+		sha1Sig   string
+		allowSHA1 string
+		secret    string
+		want      int
 	}{
 		{
 			name: "none",
@@ -83,19 +115,60 @@ func TestCheckSignatureHeader(t *testing.T) {
 			secret: "secret",
 			want:   http.StatusOK,
 		},
+		{
+			name:    "sha1_only_strict",
+			sha1Sig: "sha1=a18991ff7e4513a1c2d2ee51e3a8e99ca891d9cd",
+			secret:  "secret",
+			want:    http.StatusForbidden,
+		},
+		{
+			name:      "sha1_only_failure",
+			sha1Sig:   "sha1=1234567890abcdef",
+			allowSHA1: "true",
+			secret:    "secret",
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "sha1_only_success",
+			sha1Sig:   "sha1=a18991ff7e4513a1c2d2ee51e3a8e99ca891d9cd",
+			allowSHA1: "true",
+			secret:    "secret",
+			want:      http.StatusOK,
+		},
+		{
+			name:      "both_sha256_wins_failure",
+			sig:       "sha256=1234567890abcdef",
+			sha1Sig:   "sha1=a18991ff7e4513a1c2d2ee51e3a8e99ca891d9cd",
+			allowSHA1: "true",
+			secret:    "secret",
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "both_sha256_wins_success",
+			sig:       "sha256=dc46983557fea127b43af721467eb9b3fde2338fe3e14f51952aa8478c13d355",
+			sha1Sig:   "sha1=1234567890abcdef",
+			allowSHA1: "true",
+			secret:    "secret",
+			want:      http.StatusOK,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := listeners.RequestData{
-				Headers: http.Header{
-					signatureHeader: []string{tt.sig},
-				},
+				Headers: http.Header{},
 				LinkSecrets: map[string]string{
 					"webhook_secret": tt.secret,
+					allowSHA1Secret:  tt.allowSHA1,
 				},
 				RawPayload: []byte("body"),
 			}
+			if tt.sig != "" {
+				r.Headers.Set(signatureHeader, tt.sig)
+			}
+			if tt.sha1Sig != "" {
+				r.Headers.Set(legacySignatureHeader, tt.sha1Sig)
+			}
 
 			if got := CheckSignatureHeader(slog.Default(), r); got != tt.want {
 				t.Errorf("checkSignatureHeader() = %d, want %d", got, tt.want)
@@ -103,3 +176,14 @@ func TestCheckSignatureHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestWebhookHandlerMissingEventHeader(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+	h := signRequest("secret", body, time.Now())
+	h.Set(contentTypeHeader, "application/json")
+
+	r := listeners.RequestData{Headers: h, RawPayload: body, LinkSecrets: map[string]string{"webhook_secret": "secret"}}
+	if got := WebhookHandler(t.Context(), nil, r); got != http.StatusBadRequest {
+		t.Errorf("WebhookHandler() = %d, want %d", got, http.StatusBadRequest)
+	}
+}
@@ -12,6 +12,7 @@ import (
 // Timpani supports. The map keys correspond to Thrippy link template names.
 var WebhookHandlers = map[string]listeners.WebhookHandlerFunc{
 	"bitbucket-app-oauth":  bitbucket.WebhookHandler,
+	"bitbucket-server":     bitbucket.ServerWebhookHandler,
 	"bitbucket-user-token": bitbucket.WebhookHandler,
 	"github-app-jwt":       github.WebhookHandler,
 	"github-user-pat":      github.WebhookHandler,
@@ -23,6 +24,21 @@ var WebhookHandlers = map[string]listeners.WebhookHandlerFunc{
 	"slack-oauth-gov":      slack.WebhookHandler,
 }
 
+// WebhookVerifiers is a map of the request verification steps of the webhook handlers
+// in [WebhookHandlers], to troubleshoot them offline. Handlers which don't authenticate
+// requests (e.g. Jira) are omitted. The map keys correspond to Thrippy link template names.
+var WebhookVerifiers = map[string]listeners.WebhookVerifier{
+	"bitbucket-app-oauth":  bitbucket.WebhookVerifier,
+	"bitbucket-server":     bitbucket.ServerWebhookVerifier,
+	"bitbucket-user-token": bitbucket.WebhookVerifier,
+	"github-app-jwt":       github.WebhookVerifier,
+	"github-user-pat":      github.WebhookVerifier,
+	"github-webhook":       github.WebhookVerifier,
+	"slack-bot-token":      slack.WebhookVerifier,
+	"slack-oauth":          slack.WebhookVerifier,
+	"slack-oauth-gov":      slack.WebhookVerifier,
+}
+
 // ConnectionHandlers is a map of all the stateful connection handlers that
 // Timpani supports. The map keys correspond to Thrippy link template names.
 var ConnectionHandlers = map[string]listeners.ConnHandlerFunc{
@@ -7,39 +7,148 @@ import (
 	"log/slog"
 	"net/url"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/tzrikka/timpani/internal/listeners"
 	"github.com/tzrikka/timpani/internal/logger"
 	"github.com/tzrikka/timpani/pkg/temporal"
 )
 
-func dispatchFromWebhook(ctx context.Context, r listeners.RequestData) (string, error) {
+const (
+	// LinkIDKey is added to all the dispatched Slack event payloads, with the ID of the
+	// Thrippy link that received them, to distinguish between events of multiple apps.
+	LinkIDKey = "timpani_link_id"
+	// AppIDKey is added to all the dispatched Slack event payloads,
+	// with the ID of the Slack app that received them (if known).
+	AppIDKey = "timpani_app_id"
+	// EventContextKey is added to dispatched Events API payloads which have an
+	// "event_context" field. Workflows of org-wide apps can pass it to the
+	// "slack.apps.event.authorizations.list" activity, to get the full list of
+	// installations to which the event is visible (payloads contain only a partial one).
+	EventContextKey = "timpani_event_context"
+)
+
+// linkSignals enables the namespacing of signal names with link IDs, see [SetLinkSignals].
+var linkSignals atomic.Bool
+
+// SetLinkSignals enables or disables the namespacing of Temporal signal names with the
+// IDs of the Thrippy links that received them ("slack.<link ID>.events.<type>" instead
+// of "slack.events.<type>"), for deployments that require hard isolation between
+// multiple Slack apps. Otherwise, workflows can filter events by their [LinkIDKey].
+func SetLinkSignals(enabled bool) {
+	linkSignals.Store(enabled)
+}
+
+// eventAllowlist contains the Slack event types that are dispatched as Temporal
+// signals. If it's empty, all events are dispatched. It may change at runtime.
+var eventAllowlist atomic.Pointer[map[string]bool]
+
+// SetEventAllowlist replaces the Slack event types (e.g. "app_mention", "block_actions",
+// "slash_command") which are dispatched as Temporal signals. Other events are
+// acknowledged, but ignored. An empty list allows all events.
+func SetEventAllowlist(eventTypes []string) {
+	m := make(map[string]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			m[t] = true
+		}
+	}
+	eventAllowlist.Store(&m)
+}
+
+// eventAllowed checks the given event type against the [SetEventAllowlist].
+func eventAllowed(eventType string) bool {
+	m := eventAllowlist.Load()
+	if m == nil || len(*m) == 0 {
+		return true
+	}
+	return (*m)[eventType]
+}
+
+// annotatePayload adds the IDs of the Thrippy link and Slack app that received an event to
+// its payload. The app ID in the payload itself (if there is one) takes precedence over the
+// one that the link reported when it connected. It also surfaces the event context, if any.
+func annotatePayload(payload map[string]any, linkID, appID string) {
+	if id, ok := payload["api_app_id"].(string); ok && id != "" {
+		appID = id
+	}
+
+	payload[LinkIDKey] = linkID
+	if appID != "" {
+		payload[AppIDKey] = appID
+	}
+	if ec, ok := payload["event_context"].(string); ok && ec != "" {
+		payload[EventContextKey] = ec
+	}
+}
+
+// signalName returns the name of the Temporal signal for an annotated event payload.
+func signalName(payload map[string]any, eventType string) string {
+	if linkID, _ := payload[LinkIDKey].(string); linkSignals.Load() && linkID != "" {
+		return fmt.Sprintf("slack.%s.events.%s", linkID, eventType)
+	}
+	return "slack.events." + eventType
+}
+
+// dispatchFromWebhook expects a JSON payload or a web form which was already decoded
+// by the caller, after verifying the request's signature (the other one is nil).
+// The response ID is optional, see [ResponseIDKey].
+func dispatchFromWebhook(
+	ctx context.Context,
+	r listeners.RequestData,
+	jsonPayload map[string]any,
+	form url.Values,
+	responseID string,
+	received time.Time,
+) (string, error) {
 	l := logger.FromContext(ctx)
 
-	signalName, payload, err := parsePayload(r.JSONPayload, r.WebForm)
+	eventType, payload, err := parsePayload(jsonPayload, form)
 	if err != nil {
 		l.Error("failed to decode event payload", slog.Any("error", err))
 		return "", err
 	}
 
-	if err := temporal.Signal(ctx, r.Temporal, signalName, payload); err != nil {
+	annotatePayload(payload, r.LinkID, "")
+	payload = temporal.WithOriginalEvent(payload, eventType)
+	payload = temporal.WithReceipt(payload, received, temporal.ListenerWebhook)
+	annotateSelf(payload, lookupSelf(ctx, r.LinkID, linkBotToken(r.LinkSecrets)))
+	if responseID != "" {
+		payload[ResponseIDKey] = responseID
+	}
+	name := signalName(payload, eventType)
+	if !eventAllowed(eventType) {
+		l.Debug("ignoring Slack event which is not in the allowlist", slog.String("signal", name))
+		return name, nil
+	}
+
+	if err := temporal.Signal(ctx, r.Temporal, name, payload); err != nil {
 		l.Error("failed to send Temporal signal", slog.Any("error", err))
-		return signalName, err // Return signal name for monitoring & debugging purposes.
+		return name, err // Return signal name for monitoring & debugging purposes.
 	}
 
-	return signalName, nil
+	return name, nil
 }
 
+// dispatchFromWebSocket expects a payload which was already annotated by the [dispatchPool].
 func dispatchFromWebSocket(ctx context.Context, tc listeners.TemporalConfig, payload map[string]any) error {
 	l := logger.FromContext(ctx)
 
-	signalName, payload, err := parsePayload(payload, nil)
+	eventType, payload, err := parsePayload(payload, nil)
 	if err != nil {
 		l.Error("failed to decode event payload", slog.Any("error", err))
 		return err
 	}
 
-	if err := temporal.Signal(ctx, tc, signalName, payload); err != nil {
+	payload = temporal.WithOriginalEvent(payload, eventType)
+	name := signalName(payload, eventType)
+	if !eventAllowed(eventType) {
+		l.Debug("ignoring Slack event which is not in the allowlist", slog.String("signal", name))
+		return nil
+	}
+
+	if err := temporal.Signal(ctx, tc, name, payload); err != nil {
 		l.Error("failed to send Temporal signal", slog.Any("error", err))
 		return err
 	}
@@ -76,7 +185,7 @@ func parsePayload(payload map[string]any, webForm url.Values) (string, map[strin
 		eventType = payload["type"]
 	}
 
-	return fmt.Sprintf("slack.events.%s", eventType), payload, nil
+	return fmt.Sprint(eventType), payload, nil
 }
 
 // webFormToMap converts a web form into a Go map which is compatible with JSON.
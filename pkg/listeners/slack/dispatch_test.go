@@ -35,3 +35,125 @@ func TestWebFormToMap(t *testing.T) {
 		})
 	}
 }
+
+func TestEventAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		eventType string
+		want      bool
+	}{
+		{
+			name:      "no_allowlist",
+			eventType: "message",
+			want:      true,
+		},
+		{
+			name:      "allowed",
+			allowlist: []string{"app_mention", " message "},
+			eventType: "message",
+			want:      true,
+		},
+		{
+			name:      "not_allowed",
+			allowlist: []string{"app_mention"},
+			eventType: "message",
+		},
+		{
+			name:      "blank_entries_only",
+			allowlist: []string{""},
+			eventType: "message",
+			want:      true,
+		},
+	}
+
+	t.Cleanup(func() { SetEventAllowlist(nil) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetEventAllowlist(tt.allowlist)
+			if got := eventAllowed(tt.eventType); got != tt.want {
+				t.Errorf("eventAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotatePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		appID   string
+		want    map[string]any
+	}{
+		{
+			name:    "link_id_only",
+			payload: map[string]any{"type": "hello"},
+			want:    map[string]any{"type": "hello", LinkIDKey: "link"},
+		},
+		{
+			name:    "app_id_from_hello",
+			payload: map[string]any{"type": "hello"},
+			appID:   "A1",
+			want:    map[string]any{"type": "hello", LinkIDKey: "link", AppIDKey: "A1"},
+		},
+		{
+			name:    "app_id_from_payload",
+			payload: map[string]any{"api_app_id": "A2"},
+			appID:   "A1",
+			want:    map[string]any{"api_app_id": "A2", LinkIDKey: "link", AppIDKey: "A2"},
+		},
+		{
+			name:    "event_context",
+			payload: map[string]any{"type": "event_callback", "event_context": "4-abc"},
+			want:    map[string]any{"type": "event_callback", "event_context": "4-abc", LinkIDKey: "link", EventContextKey: "4-abc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotatePayload(tt.payload, "link", tt.appID)
+			if !reflect.DeepEqual(tt.payload, tt.want) {
+				t.Errorf("annotatePayload() = %v, want %v", tt.payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignalName(t *testing.T) {
+	tests := []struct {
+		name        string
+		linkSignals bool
+		payload     map[string]any
+		want        string
+	}{
+		{
+			name:    "default",
+			payload: map[string]any{LinkIDKey: "link"},
+			want:    "slack.events.message",
+		},
+		{
+			name:        "link_signals",
+			linkSignals: true,
+			payload:     map[string]any{LinkIDKey: "link"},
+			want:        "slack.link.events.message",
+		},
+		{
+			name:        "link_signals_without_link_id",
+			linkSignals: true,
+			payload:     map[string]any{},
+			want:        "slack.events.message",
+		},
+	}
+
+	t.Cleanup(func() { SetLinkSignals(false) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLinkSignals(tt.linkSignals)
+			if got := signalName(tt.payload, "message"); got != tt.want {
+				t.Errorf("signalName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
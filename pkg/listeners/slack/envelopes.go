@@ -0,0 +1,50 @@
+package slack
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
+)
+
+const (
+	envelopeTTL = 5 * time.Minute
+)
+
+// envelopeCache remembers recently-received Slack Socket Mode envelope IDs,
+// to dispatch each event only once, even when Slack redelivers it (e.g. to a
+// different connection of the same app, if the original one wasn't acked).
+// If its store is shared, this also applies to connections of other replicas.
+type envelopeCache struct {
+	store listeners.Store
+}
+
+// newEnvelopeCache initializes an [envelopeCache] with the given
+// store, or with a local [listeners.MemoryStore] if it's nil.
+func newEnvelopeCache(store listeners.Store) *envelopeCache {
+	if store == nil {
+		store = listeners.NewMemoryStore()
+	}
+	return &envelopeCache{store: store}
+}
+
+// add returns true if the given envelope ID wasn't seen recently. Empty
+// IDs (i.e. messages which aren't events) are never considered duplicates.
+// Store errors aren't considered duplicates either, because dispatching an
+// event twice is better than not dispatching it at all.
+func (e *envelopeCache) add(ctx context.Context, id string) bool {
+	if id == "" {
+		return true
+	}
+
+	_, found, err := e.store.GetSet(ctx, "slack_envelope:"+id, "1", envelopeTTL)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to check for duplicate Slack Socket Mode event",
+			slog.Any("error", err), slog.String("envelope_id", id))
+		return true
+	}
+
+	return !found
+}
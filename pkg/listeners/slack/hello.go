@@ -0,0 +1,58 @@
+package slack
+
+import (
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// Hello is the latest "hello" message which Slack sent to any of the Socket Mode connections
+// of a link. Its details help Slack support to troubleshoot connectivity issues (e.g. which
+// edge host served the connection): https://docs.slack.dev/apis/events-api/using-socket-mode#connect.
+type Hello struct {
+	AppID                     string    `json:"app_id"`
+	NumConnections            int       `json:"num_connections"`
+	Host                      string    `json:"host"`
+	BuildNumber               int       `json:"build_number,omitempty"`
+	ApproximateConnectionTime int       `json:"approximate_connection_time,omitempty"` // Seconds.
+	ReceivedAt                time.Time `json:"received_at"`
+}
+
+var (
+	hellos   = map[string]Hello{}
+	hellosMu sync.RWMutex
+)
+
+// LastHellos returns the latest "hello" message of each Slack
+// Socket Mode link which connected so far, keyed by link ID.
+func LastHellos() map[string]Hello {
+	hellosMu.RLock()
+	defer hellosMu.RUnlock()
+
+	return maps.Clone(hellos)
+}
+
+// recordHello logs the details of a "hello" message, exports the number of the app's
+// connections as a metric, and keeps the message for the health-check endpoint.
+func recordHello(l *slog.Logger, linkID string, msg socketModeMessage, t time.Time) {
+	h := Hello{
+		AppID:                     msg.ConnectionInfo.AppID,
+		NumConnections:            msg.NumConnections,
+		Host:                      msg.DebugInfo.Host,
+		BuildNumber:               msg.DebugInfo.BuildNumber,
+		ApproximateConnectionTime: msg.DebugInfo.ApproximateConnectionTime,
+		ReceivedAt:                t.UTC(),
+	}
+
+	l.Info("received Slack Socket Mode hello", slog.String("app_id", h.AppID),
+		slog.Int("num_connections", h.NumConnections), slog.String("host", h.Host),
+		slog.Int("build_number", h.BuildNumber), slog.Int("approximate_connection_time", h.ApproximateConnectionTime))
+	otel.SetSocketConnectionsGauge(h.ReceivedAt, linkID, h.NumConnections)
+
+	hellosMu.Lock()
+	hellos[linkID] = h
+	hellosMu.Unlock()
+}
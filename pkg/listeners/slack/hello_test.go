@@ -0,0 +1,40 @@
+package slack
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordHello(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+	reset := func() {
+		hellosMu.Lock()
+		hellos = map[string]Hello{}
+		hellosMu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+
+	now := time.Now()
+	c := newFakeSocketClient(1)
+	pool := &dispatchPool{jobs: make(chan dispatchJob, 1), linkID: "link ID"}
+	msg := `{"type":"hello","num_connections":2,"connection_info":{"app_id":"A123"},` +
+		`"debug_info":{"host":"applink-7fc4fdbb64-4x5xq","build_number":10,"approximate_connection_time":18060}}`
+	handleMessage(t.Context(), slog.Default(), c, newEnvelopeCache(nil), pool, []byte(msg), now)
+
+	want := map[string]Hello{
+		"link ID": {
+			AppID:                     "A123",
+			NumConnections:            2,
+			Host:                      "applink-7fc4fdbb64-4x5xq",
+			BuildNumber:               10,
+			ApproximateConnectionTime: 18060,
+			ReceivedAt:                now.UTC(),
+		},
+	}
+	if got := LastHellos(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LastHellos() = %+v, want %+v", got, want)
+	}
+}
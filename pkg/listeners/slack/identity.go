@@ -0,0 +1,129 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/pkg/http/client"
+)
+
+// SelfKey is added to dispatched Slack event payloads which were triggered by the
+// link's own bot (e.g. its own messages), to save workflows the round trip of an
+// auth.test API call just to ignore them.
+const SelfKey = "timpani_is_self"
+
+// How long the identity of a link's bot is cached, or how long to wait
+// before retrying to get it after a failure (to avoid calling auth.test
+// for each event, when it keeps failing).
+const (
+	identityTTL        = 12 * time.Hour
+	identityFailureTTL = time.Minute
+)
+
+// authTestURL is a variable only to facilitate testing with a local server.
+var authTestURL = "https://slack.com/api/auth.test"
+
+// selfIdentity is the subset of https://docs.slack.dev/reference/methods/auth.test
+// which is needed to detect events that were triggered by the link's own bot.
+type selfIdentity struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	BotID  string `json:"bot_id,omitempty"`
+
+	expires time.Time
+}
+
+// identities caches the [selfIdentity] of each Thrippy link's bot, by link ID.
+var identities sync.Map
+
+// lookupSelf returns the cached identity of a link's bot, and calls auth.test with the
+// bot token if it's not cached yet or expired. It returns nil if the identity is unknown.
+// Failures (e.g. authentication errors) replace the link's cached identity with an
+// empty one, which doesn't match any event, until the next retry.
+func lookupSelf(ctx context.Context, linkID, botToken string) *selfIdentity {
+	if v, ok := identities.Load(linkID); ok {
+		if id := v.(*selfIdentity); time.Now().Before(id.expires) { //nolint:errcheck // Type conversion always succeeds.
+			return id
+		}
+	}
+	if botToken == "" {
+		return nil
+	}
+
+	id, err := fetchSelf(ctx, botToken)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to get Slack bot identity", slog.Any("error", err))
+		id = &selfIdentity{expires: time.Now().Add(identityFailureTTL)}
+	}
+
+	identities.Store(linkID, id)
+	return id
+}
+
+// fetchSelf calls https://docs.slack.dev/reference/methods/auth.test.
+func fetchSelf(ctx context.Context, botToken string) (*selfIdentity, error) {
+	body, _, _, err := client.HTTPRequest(ctx, http.MethodPost, authTestURL, botToken, client.AcceptJSON, "", nil, client.WithTimeout(timeout))
+	if err != nil {
+		return nil, err
+	}
+
+	id := new(selfIdentity)
+	if err := json.Unmarshal(body, id); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON in HTTP response body: %w", err)
+	}
+	if !id.OK {
+		return nil, fmt.Errorf("error reported by Slack API: %s", id.Error)
+	}
+
+	id.expires = time.Now().Add(identityTTL)
+	return id, nil
+}
+
+// linkBotToken returns the bot token from the given link secrets, or an empty
+// string if the link doesn't contain one (e.g. Socket Mode links without it).
+func linkBotToken(secrets map[string]string) string {
+	if token := secrets["bot_token"]; token != "" {
+		return token
+	}
+	return secrets["access_token"] // Short-lived OAuth token.
+}
+
+// annotateSelf adds [SelfKey] to the given event payload, if it was triggered by the given bot.
+func annotateSelf(payload map[string]any, self *selfIdentity) {
+	if self == nil {
+		return
+	}
+
+	userID, botID := eventActor(payload)
+	if (userID != "" && userID == self.UserID) || (botID != "" && botID == self.BotID) {
+		payload[SelfKey] = true
+	}
+}
+
+// eventActor extracts the IDs of the user and/or bot that triggered an
+// Events API event, an interaction, or a slash command (if they're known).
+func eventActor(payload map[string]any) (userID, botID string) {
+	// https://docs.slack.dev/apis/events-api#callback-field
+	if e, ok := payload["event"].(map[string]any); ok {
+		userID, _ = e["user"].(string)
+		botID, _ = e["bot_id"].(string)
+		return userID, botID
+	}
+
+	// https://docs.slack.dev/reference/interaction-payloads
+	if u, ok := payload["user"].(map[string]any); ok {
+		userID, _ = u["id"].(string)
+		return userID, ""
+	}
+
+	// https://docs.slack.dev/interactivity/implementing-slash-commands#app_command_handling
+	userID, _ = payload["user_id"].(string)
+	return userID, ""
+}
@@ -0,0 +1,106 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLookupSelf(t *testing.T) {
+	calls := new(atomic.Int32)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer xoxb-valid" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"user_id":"U1","bot_id":"B1"}`))
+	}))
+	defer s.Close()
+
+	orig := authTestURL
+	authTestURL = s.URL
+	t.Cleanup(func() { authTestURL = orig })
+
+	if id := lookupSelf(t.Context(), "no token", ""); id != nil {
+		t.Errorf("lookupSelf() without token = %v, want nil", id)
+	}
+
+	for range 3 {
+		id := lookupSelf(t.Context(), "valid", "xoxb-valid")
+		if id == nil || id.UserID != "U1" || id.BotID != "B1" {
+			t.Fatalf("lookupSelf() = %v, want U1 and B1", id)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("auth.test calls = %d, want 1 (cached)", got)
+	}
+
+	for range 3 {
+		if id := lookupSelf(t.Context(), "revoked", "xoxb-revoked"); id == nil || id.UserID != "" {
+			t.Fatalf("lookupSelf() with auth error = %v, want empty identity", id)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("auth.test calls = %d, want 2 (failure also cached)", got)
+	}
+}
+
+func TestAnnotateSelf(t *testing.T) {
+	self := &selfIdentity{UserID: "U1", BotID: "B1"}
+	tests := []struct {
+		name    string
+		payload map[string]any
+		self    *selfIdentity
+		want    bool
+	}{
+		{
+			name:    "unknown_identity",
+			payload: map[string]any{"event": map[string]any{"user": "U1"}},
+		},
+		{
+			name:    "event_by_self_user",
+			payload: map[string]any{"event": map[string]any{"user": "U1"}},
+			self:    self,
+			want:    true,
+		},
+		{
+			name:    "event_by_self_bot",
+			payload: map[string]any{"event": map[string]any{"bot_id": "B1"}},
+			self:    self,
+			want:    true,
+		},
+		{
+			name:    "event_by_other_user",
+			payload: map[string]any{"event": map[string]any{"user": "U2"}},
+			self:    self,
+		},
+		{
+			name:    "event_without_user",
+			payload: map[string]any{"event": map[string]any{}},
+			self:    &selfIdentity{},
+		},
+		{
+			name:    "interaction_by_self",
+			payload: map[string]any{"type": "block_actions", "user": map[string]any{"id": "U1"}},
+			self:    self,
+			want:    true,
+		},
+		{
+			name:    "slash_command_by_other_user",
+			payload: map[string]any{"command": "/cmd", "user_id": "U2"},
+			self:    self,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotateSelf(tt.payload, tt.self)
+			if got := tt.payload[SelfKey] == true; got != tt.want {
+				t.Errorf("annotateSelf() %s = %v, want %v", SelfKey, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package slack
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/pkg/temporal"
+)
+
+const (
+	dispatchWorkers   = 4
+	dispatchQueueSize = 100
+)
+
+type dispatchJob struct {
+	ctx      context.Context
+	payload  map[string]any
+	received time.Time
+}
+
+// dispatchPool is a bounded pool of goroutines that dispatch Slack Socket Mode events
+// as Temporal signals, so slow dispatching doesn't delay the acknowledgement of
+// subsequent events. It's shared by all the connections of the same link.
+type dispatchPool struct {
+	jobs     chan dispatchJob
+	linkID   string
+	botToken string                 // Optional, see [lookupSelf].
+	appID    atomic.Pointer[string] // Reported by Slack when connecting.
+}
+
+func newDispatchPool(ctx context.Context, tc listeners.TemporalConfig, linkID, botToken string, workers, queueSize int) *dispatchPool {
+	p := &dispatchPool{jobs: make(chan dispatchJob, queueSize), linkID: linkID, botToken: botToken}
+	for range workers {
+		go p.run(ctx, tc)
+	}
+	return p
+}
+
+// submit queues an event for dispatching. It blocks if the queue is full,
+// to apply backpressure instead of accumulating events without limit.
+func (p *dispatchPool) submit(ctx context.Context, payload map[string]any, received time.Time) {
+	select {
+	case p.jobs <- dispatchJob{ctx: ctx, payload: payload, received: received}:
+	case <-ctx.Done():
+	}
+}
+
+// setAppID records the ID of the link's Slack app, from a "hello" message.
+func (p *dispatchPool) setAppID(id string) {
+	if id != "" {
+		p.appID.Store(&id)
+	}
+}
+
+func (p *dispatchPool) run(ctx context.Context, tc listeners.TemporalConfig) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-p.jobs:
+			appID := ""
+			if id := p.appID.Load(); id != nil {
+				appID = *id
+			}
+			if j.payload != nil {
+				annotatePayload(j.payload, p.linkID, appID)
+				annotateSelf(j.payload, lookupSelf(j.ctx, p.linkID, p.botToken))
+				j.payload = temporal.WithReceipt(j.payload, j.received, temporal.ListenerWebSocket)
+			}
+			dispatchSafely(j.ctx, tc, j.payload)
+		}
+	}
+}
+
+func dispatchSafely(ctx context.Context, tc listeners.TemporalConfig, payload map[string]any) {
+	defer func() {
+		if r := recover(); r != nil {
+			listeners.ReportPanic(logger.FromContext(ctx), "slack_socket_mode", r)
+		}
+	}()
+
+	_ = dispatchWebSocketEvent(ctx, tc, payload) // Errors are already logged.
+}
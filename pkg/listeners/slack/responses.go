@@ -0,0 +1,175 @@
+package slack
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+// ResponseIDKey is added to the dispatched payloads of interactions with a deferred
+// response policy (see [SetInteractionResponses]). Workflows may pass it to the
+// "slack.timpani.interactionResponse" activity, to supply the synchronous response.
+const ResponseIDKey = "timpani_response_id"
+
+type responseKind int
+
+const (
+	responseEmpty responseKind = iota
+	responseJSON
+	responseDeferred
+)
+
+// responsePolicy determines the synchronous HTTP response to an interaction payload:
+// an empty 200 OK, a fixed JSON body, or a JSON body which is supplied by a workflow.
+// See https://docs.slack.dev/interactivity/handling-user-interaction#acknowledgment_response.
+type responsePolicy struct {
+	kind responseKind
+	body []byte // JSON body, only in [responseJSON] policies.
+}
+
+// defaultResponsePolicies covers the documented interaction types in which Slack
+// accepts a meaningful response body. All other types get an empty 200 OK response.
+// See https://docs.slack.dev/reference/interaction-payloads.
+var defaultResponsePolicies = map[string]responsePolicy{
+	"block_actions":      {kind: responseEmpty},
+	"block_suggestion":   {kind: responseDeferred},
+	"message_action":     {kind: responseEmpty},
+	"shortcut":           {kind: responseEmpty},
+	"view_closed":        {kind: responseEmpty},
+	"view_submission":    {kind: responseDeferred},
+	"workflow_step_edit": {kind: responseDeferred},
+}
+
+// responsePolicies overrides [defaultResponsePolicies], see [SetInteractionResponses].
+var responsePolicies atomic.Pointer[map[string]responsePolicy]
+
+// deferredResponseTimeout leaves a safe margin below Slack's 3-second response
+// deadline. It's a variable only to facilitate testing without long waits.
+var deferredResponseTimeout = 2500 * time.Millisecond
+
+// SetInteractionResponses overrides the default response policies of Slack interaction
+// types. Each entry is "<type>=<policy>", where the policy is "empty" (200 OK without
+// a body), "deferred" (wait briefly for a response body from a workflow, see
+// [ResponseIDKey]), or a JSON object which is used as a fixed response body.
+// Invalid entries are ignored: use [ValidateInteractionResponses] first.
+func SetInteractionResponses(entries []string) {
+	m, _ := parseResponsePolicies(entries)
+	responsePolicies.Store(&m)
+}
+
+// ValidateInteractionResponses checks the syntax of [SetInteractionResponses] entries.
+func ValidateInteractionResponses(entries []string) error {
+	_, err := parseResponsePolicies(entries)
+	return err
+}
+
+func parseResponsePolicies(entries []string) (map[string]responsePolicy, error) {
+	m := make(map[string]responsePolicy, len(entries))
+	var errs []error
+	for _, e := range entries {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+
+		t, p, ok := strings.Cut(e, "=")
+		t, p = strings.TrimSpace(t), strings.TrimSpace(p)
+		if !ok || t == "" {
+			errs = append(errs, fmt.Errorf("invalid interaction response %q: expected <type>=<policy>", e))
+			continue
+		}
+
+		switch {
+		case p == "empty":
+			m[t] = responsePolicy{kind: responseEmpty}
+		case p == "deferred":
+			m[t] = responsePolicy{kind: responseDeferred}
+		case strings.HasPrefix(p, "{") && json.Valid([]byte(p)):
+			m[t] = responsePolicy{kind: responseJSON, body: []byte(p)}
+		default:
+			errs = append(errs, fmt.Errorf("invalid %q interaction response policy: %q", t, p))
+		}
+	}
+	return m, errors.Join(errs...)
+}
+
+// interactionResponsePolicy returns the configured or default policy of an interaction type.
+func interactionResponsePolicy(interactionType string) responsePolicy {
+	if m := responsePolicies.Load(); m != nil {
+		if p, ok := (*m)[interactionType]; ok {
+			return p
+		}
+	}
+	return defaultResponsePolicies[interactionType]
+}
+
+// interactionType returns the type of an interaction payload in a web form,
+// or an empty string if the web form doesn't contain an interaction payload.
+func interactionType(webForm url.Values) string {
+	p := webForm.Get("payload")
+	if p == "" {
+		return ""
+	}
+
+	payload := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal([]byte(p), &payload); err != nil {
+		return ""
+	}
+	return payload.Type
+}
+
+// awaitResponse prepares the handling of a deferred response policy: it returns a new
+// response ID for the dispatched payload, the channel to receive the response body,
+// and a function that stops waiting for it. The ID is empty in other policies.
+func awaitResponse(p responsePolicy) (string, <-chan map[string]any, func()) {
+	if p.kind != responseDeferred {
+		return "", nil, func() {}
+	}
+
+	id := rand.Text()
+	ch, cancel := listeners.AwaitResponse(id)
+	return id, ch, cancel
+}
+
+// writeInteractionResponse writes the HTTP response to an interaction payload, according
+// to its policy. Deferred responses which don't arrive in time are empty. It returns the
+// HTTP status code for [otel.IncrementWebhookEventCounter], which is 0 if it was written.
+func writeInteractionResponse(l *slog.Logger, w http.ResponseWriter, p responsePolicy, ch <-chan map[string]any) int {
+	body := p.body
+	if p.kind == responseDeferred {
+		select {
+		case resp := <-ch:
+			if len(resp) == 0 {
+				break
+			}
+			var err error
+			if body, err = json.Marshal(resp); err != nil {
+				l.Error("failed to encode deferred Slack interaction response", slog.Any("error", err))
+				body = nil
+			}
+		case <-time.After(deferredResponseTimeout):
+			l.Debug("timeout while waiting for deferred Slack interaction response",
+				slog.Duration("timeout", deferredResponseTimeout))
+		}
+	}
+
+	if len(body) == 0 {
+		return http.StatusOK
+	}
+
+	w.Header().Add(contentTypeHeader, "application/json; charset=utf-8")
+	if _, err := w.Write(body); err != nil {
+		l.Error("failed to write Slack interaction response", slog.Any("error", err))
+	}
+	return 0 // [http.StatusOK] already written by "w.Write".
+}
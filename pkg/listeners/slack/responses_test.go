@@ -0,0 +1,191 @@
+package slack
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+func TestValidateInteractionResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "valid_policies",
+			entries: []string{"shortcut=deferred", " message_action = empty ", `view_closed={"text": "bye"}`, ""},
+		},
+		{
+			name:    "missing_policy",
+			entries: []string{"shortcut"},
+			wantErr: true,
+		},
+		{
+			name:    "missing_type",
+			entries: []string{"=empty"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_policy",
+			entries: []string{"shortcut=ignore"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid_json",
+			entries: []string{`shortcut={"text": }`},
+			wantErr: true,
+		},
+		{
+			name:    "json_array",
+			entries: []string{`shortcut=[]`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateInteractionResponses(tt.entries); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateInteractionResponses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInteractionResponse(t *testing.T) {
+	SetInteractionResponses([]string{`message_action={"text":"ok"}`, "custom_type=deferred"})
+	t.Cleanup(func() { SetInteractionResponses(nil) })
+
+	timeout := deferredResponseTimeout
+	deferredResponseTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { deferredResponseTimeout = timeout })
+
+	tests := []struct {
+		name      string
+		payload   string
+		workflow  map[string]any
+		wantID    bool
+		wantCode  int
+		wantBody  string
+		wantDelay bool
+	}{
+		{
+			name:     "not_an_interaction",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "invalid_payload",
+			payload:  "{",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "block_actions",
+			payload:  `{"type":"block_actions"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "block_suggestion",
+			payload:  `{"type":"block_suggestion"}`,
+			workflow: map[string]any{"options": []any{}},
+			wantID:   true,
+			wantBody: `{"options":[]}`,
+		},
+		{
+			name:     "message_action_with_configured_json",
+			payload:  `{"type":"message_action"}`,
+			wantBody: `{"text":"ok"}`,
+		},
+		{
+			name:     "shortcut",
+			payload:  `{"type":"shortcut"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "view_closed",
+			payload:  `{"type":"view_closed"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "view_submission",
+			payload:  `{"type":"view_submission"}`,
+			workflow: map[string]any{"response_action": "clear"},
+			wantID:   true,
+			wantBody: `{"response_action":"clear"}`,
+		},
+		{
+			name:      "view_submission_without_workflow_response",
+			payload:   `{"type":"view_submission"}`,
+			wantID:    true,
+			wantCode:  http.StatusOK,
+			wantDelay: true,
+		},
+		{
+			name:     "workflow_step_edit",
+			payload:  `{"type":"workflow_step_edit"}`,
+			workflow: map[string]any{},
+			wantID:   true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "configured_custom_type",
+			payload:  `{"type":"custom_type"}`,
+			workflow: map[string]any{"foo": "bar"},
+			wantID:   true,
+			wantBody: `{"foo":"bar"}`,
+		},
+		{
+			name:     "unknown_type",
+			payload:  `{"type":"unknown_type"}`,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.payload != "" {
+				form.Set("payload", tt.payload)
+			}
+
+			p := interactionResponsePolicy(interactionType(form))
+			id, ch, cancel := awaitResponse(p)
+			defer cancel()
+
+			if (id != "") != tt.wantID {
+				t.Fatalf("awaitResponse() ID = %q, want ID = %v", id, tt.wantID)
+			}
+			if tt.workflow != nil && !listeners.DeliverResponse(id, tt.workflow) {
+				t.Fatal("listeners.DeliverResponse() = false, want true")
+			}
+
+			w := httptest.NewRecorder()
+			start := time.Now()
+			if got := writeInteractionResponse(slog.Default(), w, p, ch); got != tt.wantCode {
+				t.Errorf("writeInteractionResponse() = %d, want %d", got, tt.wantCode)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("writeInteractionResponse() body = %q, want %q", got, tt.wantBody)
+			}
+			if d := time.Since(start); (d >= deferredResponseTimeout) != tt.wantDelay {
+				t.Errorf("writeInteractionResponse() duration = %v, want delay = %v", d, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestDeliverResponseAfterTimeout(t *testing.T) {
+	id, _, cancel := awaitResponse(responsePolicy{kind: responseDeferred})
+	cancel()
+
+	if listeners.DeliverResponse(id, map[string]any{"foo": "bar"}) {
+		t.Error("listeners.DeliverResponse() = true, want false")
+	}
+}
@@ -34,6 +34,17 @@ const (
 	slackSigVersion = "v0"
 )
 
+// WebhookVerifier exposes the verification steps of [WebhookHandler], in order.
+var WebhookVerifier = listeners.WebhookVerifier{
+	SecretName: "signing_secret",
+	Checks: []listeners.WebhookCheck{
+		{Name: "content type", Check: checkContentTypeHeader},
+		{Name: "timestamp", Check: checkTimestampHeader},
+		{Name: "signature", Check: checkSignatureHeader},
+	},
+	Sign: signRequest,
+}
+
 type slashCommandResponse struct {
 	ResponseType string `json:"response_type"`
 	Text         string `json:"text"`
@@ -43,23 +54,29 @@ func WebhookHandler(ctx context.Context, w http.ResponseWriter, r listeners.Requ
 	l := logger.FromContext(ctx).With(slog.String("link_type", "slack"), slog.String("link_medium", "webhook"))
 	t := time.Now().UTC()
 
-	if statusCode := checkContentTypeHeader(l, r); statusCode != http.StatusOK {
+	if statusCode := WebhookVerifier.Verify(l, r); statusCode != http.StatusOK {
 		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
 	}
-	if statusCode := checkTimestampHeader(l, r); statusCode != http.StatusOK {
-		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
+
+	payload, err := r.DecodeJSON()
+	if err != nil {
+		l.Warn("bad request: JSON decoding error", slog.Any("error", err))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
 	}
-	if statusCode := checkSignatureHeader(l, r); statusCode != http.StatusOK {
-		return otel.IncrementWebhookEventCounter(l, t, "", statusCode)
+
+	form, err := r.DecodeForm()
+	if err != nil {
+		l.Warn("bad request: web form decoding error", slog.Any("error", err))
+		return otel.IncrementWebhookEventCounter(l, t, "", http.StatusBadRequest)
 	}
 
 	// Special handling for some events.
 
 	// https://docs.slack.dev/reference/events/url_verification
-	if r.JSONPayload["type"] == "url_verification" {
+	if payload["type"] == "url_verification" {
 		l.Debug("replied to Slack URL verification event", slog.String("event_type", "url_verification"))
 		w.Header().Add(contentTypeHeader, "text/plain")
-		_, _ = fmt.Fprint(w, r.JSONPayload["challenge"])
+		_, _ = fmt.Fprint(w, payload["challenge"])
 
 		otel.IncrementWebhookEventCounter(l, t, "slack.events.url_verification", http.StatusOK)
 		return 0 // [http.StatusOK] already written by "w.Write" ("fmt.Fprint(w)").
@@ -67,7 +84,7 @@ func WebhookHandler(ctx context.Context, w http.ResponseWriter, r listeners.Requ
 
 	// https://docs.slack.dev/interactivity/implementing-slash-commands#command_payload_descriptions
 	// (the informational note under the payload info table).
-	if r.WebForm.Get("ssl_check") != "" {
+	if form.Get("ssl_check") != "" {
 		return otel.IncrementWebhookEventCounter(l, t, "slack.events.ssl_check", http.StatusOK)
 	}
 
@@ -75,11 +92,11 @@ func WebhookHandler(ctx context.Context, w http.ResponseWriter, r listeners.Requ
 	// https://docs.slack.dev/interactivity/implementing-slash-commands#responding_with_errors
 	// https://docs.slack.dev/interactivity/implementing-slash-commands#best-practices
 	statusCode := http.StatusOK
-	if sc := r.WebForm.Get("command"); sc != "" {
+	if sc := form.Get("command"); sc != "" {
 		l.Debug("replied to Slack slash command", slog.String("event_type", "slash_command"))
 		w.Header().Add(contentTypeHeader, "application/json; charset=utf-8")
 
-		text := fmt.Sprintf("Your command: `%s %s`", sc, r.WebForm.Get("text"))
+		text := fmt.Sprintf("Your command: `%s %s`", sc, form.Get("text"))
 		resp := slashCommandResponse{ResponseType: "ephemeral", Text: text}
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			l.Error("failed to encode JSON response", slog.Any("error", err))
@@ -88,12 +105,25 @@ func WebhookHandler(ctx context.Context, w http.ResponseWriter, r listeners.Requ
 		statusCode = 0 // [http.StatusOK] already written by "w.Write".
 	}
 
+	// https://docs.slack.dev/interactivity/handling-user-interaction#acknowledgment_response
+	var policy responsePolicy
+	if it := interactionType(form); it != "" && eventAllowed(it) {
+		policy = interactionResponsePolicy(it)
+	}
+	responseID, ch, cancel := awaitResponse(policy)
+	defer cancel()
+
 	// Dispatch the event notification, based on its type.
-	signalName, err := dispatchFromWebhook(logger.WithContext(ctx, l), r)
+	dispatched := time.Now()
+	signalName, err := dispatchFromWebhook(logger.WithContext(ctx, l), r, payload, form, responseID, t)
+	r.Timing.Dispatched(dispatched)
 	if err != nil {
 		return otel.IncrementWebhookEventCounter(l, t, signalName, http.StatusInternalServerError)
 	}
 
+	if statusCode == http.StatusOK {
+		statusCode = writeInteractionResponse(l, w, policy, ch)
+	}
 	return otel.IncrementWebhookEventCounter(l, t, signalName, statusCode)
 }
 
@@ -148,7 +178,7 @@ func checkSignatureHeader(l *slog.Logger, r listeners.RequestData) int {
 	}
 
 	ts := r.Headers.Get(timestampHeader)
-	if !verifySignature(l, secret, ts, sig, r.RawPayload) {
+	if !hmac.Equal([]byte(signature(secret, ts, r.RawPayload)), []byte(sig)) {
 		l.Warn("signature verification failed", slog.String("signature", sig),
 			slog.Bool("has_signing_secret", secret != ""))
 		return http.StatusForbidden
@@ -157,24 +187,20 @@ func checkSignatureHeader(l *slog.Logger, r listeners.RequestData) int {
 	return http.StatusOK
 }
 
-// verifySignature implements
+// signature implements
 // https://docs.slack.dev/authentication/verifying-requests-from-slack.
-func verifySignature(l *slog.Logger, signingSecret, ts, want string, body []byte) bool {
+func signature(signingSecret, ts string, body []byte) string {
 	mac := hmac.New(sha256.New, []byte(signingSecret))
+	_, _ = fmt.Fprintf(mac, "%s:%s:", slackSigVersion, ts)
+	_, _ = mac.Write(body)
+	return fmt.Sprintf("%s=%s", slackSigVersion, hex.EncodeToString(mac.Sum(nil)))
+}
 
-	n, err := mac.Write(fmt.Appendf(nil, "%s:%s:", slackSigVersion, ts))
-	if err != nil {
-		l.Error("HMAC write error", slog.Any("error", err))
-		return false
-	}
-	if n != len(ts)+4 {
-		return false
-	}
-
-	if n, err := mac.Write(body); err != nil || n != len(body) {
-		return false
+// signRequest returns the headers that Slack would send with the given body at time t.
+func signRequest(signingSecret string, body []byte, t time.Time) http.Header {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return http.Header{
+		timestampHeader: []string{ts},
+		signatureHeader: []string{signature(signingSecret, ts, body)},
 	}
-
-	got := fmt.Sprintf("%s=%s", slackSigVersion, hex.EncodeToString(mac.Sum(nil)))
-	return hmac.Equal([]byte(got), []byte(want))
 }
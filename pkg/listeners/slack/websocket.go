@@ -10,20 +10,67 @@ import (
 	"log/slog"
 	"math/big"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/tzrikka/timpani/internal/listeners"
 	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/pkg/http/client"
+	"github.com/tzrikka/timpani/pkg/otel"
 	"github.com/tzrikka/timpani/pkg/websocket"
 )
 
 const (
-	connOpenURL = "https://slack.com/api/apps.connections.open"
-	timeout     = 3 * time.Second
-	maxSize     = 1024 // 1 KiB.
+	timeout = 3 * time.Second
+	maxSize = 1024 // 1 KiB.
+
+	// maxUndecodableMessages is the number of consecutive undecodable WebSocket
+	// messages after which [clientEventLoop] fails and replaces the connection.
+	maxUndecodableMessages = 3
 )
 
+// connOpenURL is a variable only to facilitate testing with a local server.
+var connOpenURL = "https://slack.com/api/apps.connections.open"
+
+// authErrors indicate that the app-level token was revoked or rotated, so retrying
+// with it is pointless: https://docs.slack.dev/reference/methods/apps.connections.open#errors.
+var authErrors = []string{"account_inactive", "invalid_auth", "not_authed", "token_revoked"}
+
+// dispatchWebSocketEvent is a variable only to facilitate testing without Temporal.
+var dispatchWebSocketEvent = dispatchFromWebSocket
+
+// socketClient is the subset of [websocket.Client] that [ConnectionHandler] and
+// [clientEventLoop] use. It's an interface only to facilitate testing with a scripted fake.
+type socketClient interface {
+	CloseStatus() (websocket.StatusCode, string, bool)
+	Err() error
+	FailConnection(status websocket.StatusCode, reason string)
+	IncomingMessages() <-chan websocket.Message
+	RefreshConnectionIn(ctx context.Context, d time.Duration)
+	SendJSONMessage(v any) error
+}
+
+// clientFactory returns a new or cached [socketClient] with the given ID, which
+// calls the given function to get a URL whenever it (re)connects to the server.
+type clientFactory func(ctx context.Context, url func(context.Context) (string, error), id string) (socketClient, error)
+
+// urlGenerator returns a temporary Socket Mode WebSocket URL, based on an app-level token.
+type urlGenerator func(ctx context.Context, appToken string) (string, error)
+
+// ConnectionHandler opens one or more (if [listeners.LinkData.Connections] is greater than 1)
+// WebSocket connections to Slack, for redundancy, and starts a goroutine for each of them to
+// process incoming events. Slack delivers each event to only one of an app's connections,
+// but if it redelivers an event to a different connection, it will be dispatched only once.
+// If Slack rejects the app-level token when reconnecting, the connections are closed, and
+// [listeners.LinkData.OnConnFailure] is called, but not when they are closed deliberately
+// (i.e. the client is shut down, or the context is canceled).
 func ConnectionHandler(ctx context.Context, tc listeners.TemporalConfig, data listeners.LinkData) error {
+	return connectionHandler(ctx, tc, data, newWebSocketClient, generateWebSocketURL)
+}
+
+// connectionHandler implements [ConnectionHandler], with injectable dependencies for testing.
+func connectionHandler(ctx context.Context, tc listeners.TemporalConfig, data listeners.LinkData, newClient clientFactory, genURL urlGenerator) error {
 	l := logger.FromContext(ctx).With(slog.String("link_type", "slack"), slog.String("link_medium", "websocket"))
 	t := data.Secrets["app_token"]
 	if t == "" {
@@ -31,19 +78,75 @@ func ConnectionHandler(ctx context.Context, tc listeners.TemporalConfig, data li
 		return errors.New("forbidden")
 	}
 
-	c, err := websocket.NewOrCachedClient(ctx, urlFunc(t), t)
+	ctx = logger.WithContext(ctx, l)
+	n := max(data.Connections, 1)
+	envelopes := newEnvelopeCache(data.Store)
+	pool := newDispatchPool(ctx, tc, data.ID, linkBotToken(data.Secrets), dispatchWorkers, dispatchQueueSize)
+	failure := sync.Once{}
+	for i := 1; i <= n; i++ {
+		id := t
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", t, i)
+		}
+
+		c, err := newClient(ctx, urlFunc(genURL, t), id)
+		if err != nil {
+			l.Error("Slack Socket Mode connection error", slog.Any("error", err), slog.Int("conn_index", i))
+			return errors.New("internal server error")
+		}
+
+		go func() {
+			clientEventLoop(logger.WithContext(ctx, l.With(slog.Int("conn_index", i))), c, envelopes, pool)
+			if data.OnConnFailure == nil || ctx.Err() != nil || errors.Is(c.Err(), websocket.ErrClientClosed) {
+				return // Deliberate shutdown, not a connection failure.
+			}
+			failure.Do(func() { data.OnConnFailure(c.Err()) })
+		}()
+	}
+
+	return nil
+}
+
+// ProbeConnection checks that Slack accepts the app-level token of a Socket Mode link,
+// by generating a temporary WebSocket URL. If dial is true, it also checks that the URL is
+// reachable, by opening a single connection and closing it immediately. Unlike [ConnectionHandler],
+// it doesn't process events, so dialing shouldn't be done while the link's listener is running:
+// Slack distributes events between all the open connections, so the probe may drop some of them.
+func ProbeConnection(ctx context.Context, secrets map[string]string, dial bool) error {
+	t := secrets["app_token"]
+	if t == "" {
+		return errors.New("Slack app-level token not found in Thrippy link credentials")
+	}
+
+	u, err := generateWebSocketURL(ctx, t)
+	if err != nil {
+		return fmt.Errorf("failed to generate Socket Mode URL: %w", err)
+	}
+	if !dial {
+		return nil
+	}
+
+	c, err := websocket.Dial(ctx, u)
 	if err != nil {
-		l.Error("Slack Socket Mode connection error", slog.Any("error", err))
-		return errors.New("internal server error")
+		return err
 	}
 
-	go clientEventLoop(logger.WithContext(ctx, l), tc, c)
+	c.Close(websocket.StatusNormalClosure)
 	return nil
 }
 
-func urlFunc(appToken string) func(ctx context.Context) (string, error) {
+// newWebSocketClient is the default [clientFactory], based on [websocket.NewOrCachedClient].
+func newWebSocketClient(ctx context.Context, url func(context.Context) (string, error), id string) (socketClient, error) {
+	c, err := websocket.NewOrCachedClient(ctx, url, id)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func urlFunc(genURL urlGenerator, appToken string) func(ctx context.Context) (string, error) {
 	return func(ctx context.Context) (string, error) {
-		return generateWebSocketURL(ctx, appToken)
+		return genURL(ctx, appToken)
 	}
 }
 
@@ -62,7 +165,7 @@ func generateWebSocketURL(ctx context.Context, appToken string) (string, error)
 
 	req.Header.Add("Authorization", "Bearer "+appToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.HTTPClient().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send HTTP request: %w", err)
 	}
@@ -87,7 +190,11 @@ func generateWebSocketURL(ctx context.Context, appToken string) (string, error)
 		return "", fmt.Errorf("failed to parse JSON in HTTP response body: %w", err)
 	}
 	if !decoded.OK {
-		return "", fmt.Errorf("error reported by Slack API: %s", decoded.Error)
+		err := fmt.Errorf("error reported by Slack API: %s", decoded.Error)
+		if slices.Contains(authErrors, decoded.Error) {
+			err = fmt.Errorf("%w: %w", websocket.ErrUnrecoverable, err)
+		}
+		return "", err
 	}
 
 	return decoded.URL, nil
@@ -102,65 +209,118 @@ type apiResponse struct {
 // clientEventLoop runs as a goroutine to parse, acknowledge, and dispatch
 // all types of asynchronous Slack events which were received as WebSocket
 // data messages. It also prevents downtime by informing the client when
-// to refresh its underlying WebSocket connection, before it times out.
-func clientEventLoop(ctx context.Context, tc listeners.TemporalConfig, c *websocket.Client) {
+// to refresh its underlying WebSocket connection, before it times out,
+// and to replace it if it receives consecutive undecodable messages.
+// The envelope cache and dispatch pool are shared by all the connections
+// of the same link. Panics are recovered per message, so the loop never
+// stops unexpectedly.
+func clientEventLoop(ctx context.Context, c socketClient, envelopes *envelopeCache, pool *dispatchPool) {
 	l := logger.FromContext(ctx)
+	undecodable := 0
 	for {
 		raw, ok := <-c.IncomingMessages()
 		if !ok {
-			l.Error("WebSocket client is closed")
+			l.Error("WebSocket client is closed", closeStatusAttrs(c)...)
 			return
 		}
 
-		msg := socketModeMessage{}
-		if err := json.Unmarshal(raw.Data, &msg); err != nil {
-			l.Error("JSON decoding error in incoming WebSocket message", slog.Any("error", err))
+		if handleMessage(ctx, l, c, envelopes, pool, raw.Data, time.Now()) {
+			undecodable = 0
 			continue
 		}
 
-		resp := eventResponse{EnvelopeID: msg.EnvelopeID}
-		switch msg.Type {
-		// https://docs.slack.dev/apis/events-api/using-socket-mode#connect
-		case "hello":
-			t := msg.DebugInfo.ApproximateConnectionTime
-			t -= 63 + randomInt(10) // 63-72 seconds before the actual timeout.
-			c.RefreshConnectionIn(ctx, time.Duration(t)*time.Second)
-			continue
+		if undecodable++; undecodable >= maxUndecodableMessages {
+			l.Warn("replacing Slack Socket Mode connection after consecutive undecodable messages",
+				slog.Int("count", undecodable))
+			c.FailConnection(websocket.StatusPolicyViolation, "undecodable Socket Mode messages")
+			undecodable = 0
+		}
+	}
+}
 
-		// https://docs.slack.dev/apis/events-api/using-socket-mode#disconnect
-		case "disconnect":
-			continue
+// closeStatusAttrs returns log attributes with the status code and reason of
+// the client's last closed WebSocket connection, if there is one, to help
+// tell apart the causes of closures (e.g. service restarts and app errors).
+func closeStatusAttrs(c socketClient) []any {
+	status, reason, ok := c.CloseStatus()
+	if !ok {
+		return nil
+	}
+	return []any{slog.String("close_status", status.String()), slog.String("close_reason", reason)}
+}
+
+// handleMessage parses and acknowledges a single WebSocket data message, as soon as
+// possible to prevent redeliveries, and then hands it off to the dispatch pool.
+// It returns false only if the message isn't a valid JSON object.
+func handleMessage(ctx context.Context, l *slog.Logger, c socketClient, e *envelopeCache, p *dispatchPool, data []byte, received time.Time) (decoded bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			listeners.ReportPanic(l, "slack_socket_mode", r)
+			decoded = true // Panics occur only after decoding.
+		}
+	}()
 
-		// https://docs.slack.dev/apis/events-api/using-socket-mode#command
-		case "slash_commands":
-			resp.Payload = map[string]any{
-				"blocks": []map[string]any{
-					{
-						"type": "section",
-						"text": map[string]string{
-							"type": "mrkdwn",
-							"text": fmt.Sprintf("Your command: `%s %s`", msg.Payload["command"], msg.Payload["text"]),
-						},
+	msg := socketModeMessage{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		l.Error("JSON decoding error in incoming WebSocket message", slog.Any("error", err))
+		return false
+	}
+
+	resp := eventResponse{EnvelopeID: msg.EnvelopeID}
+	switch msg.Type {
+	// https://docs.slack.dev/apis/events-api/using-socket-mode#connect
+	case "hello":
+		recordHello(l, p.linkID, msg, received)
+		p.setAppID(msg.ConnectionInfo.AppID)
+		t := msg.DebugInfo.ApproximateConnectionTime
+		t -= 63 + randomInt(10) // 63-72 seconds before the actual timeout.
+		d := time.Duration(t) * time.Second
+		l.Debug("scheduling Slack Socket Mode connection refresh",
+			slog.String("host", msg.DebugInfo.Host), slog.Duration("refresh_in", d))
+		c.RefreshConnectionIn(ctx, d)
+		return true
+
+	// https://docs.slack.dev/apis/events-api/using-socket-mode#disconnect
+	case "disconnect":
+		l.Info("received Slack Socket Mode disconnect",
+			slog.String("reason", msg.Reason), slog.String("host", msg.DebugInfo.Host))
+		return true
+
+	// https://docs.slack.dev/apis/events-api/using-socket-mode#command
+	case "slash_commands":
+		resp.Payload = map[string]any{
+			"blocks": []map[string]any{
+				{
+					"type": "section",
+					"text": map[string]string{
+						"type": "mrkdwn",
+						"text": fmt.Sprintf("Your command: `%s %s`", msg.Payload["command"], msg.Payload["text"]),
 					},
 				},
-			}
+			},
 		}
+	}
 
-		l.Info("received WebSocket message",
-			slog.String("msg_type", msg.Type),
-			slog.String("envelope_id", msg.EnvelopeID),
-			slog.Bool("accepts_response_payload", msg.AcceptsResponsePayload))
+	// https://docs.slack.dev/apis/events-api/using-socket-mode#acknowledge
+	if err := c.SendJSONMessage(resp); err != nil {
+		l.Error("failed to ack Slack Socket Mode event", slog.Any("error", err))
+	} else {
+		otel.RecordAckLatency(received.UTC(), "slack_socket_mode", time.Since(received))
+	}
 
-		// https://docs.slack.dev/apis/events-api/using-socket-mode#acknowledge
-		if err := c.SendJSONMessage(resp); err != nil {
-			l.Error("failed to ack Slack Socket Mode event", slog.Any("error", err))
-		}
+	l.Info("received WebSocket message",
+		slog.String("msg_type", msg.Type),
+		slog.String("envelope_id", msg.EnvelopeID),
+		slog.Bool("accepts_response_payload", msg.AcceptsResponsePayload))
 
-		// Dispatch the event notification, based on its type.
-		if err := dispatchFromWebSocket(ctx, tc, msg.Payload); err != nil {
-			continue
-		}
+	// Dispatch the event notification, based on its type,
+	// unless it was already received by another connection.
+	if !e.add(ctx, msg.EnvelopeID) {
+		l.Debug("skipping duplicate Slack Socket Mode event", slog.String("envelope_id", msg.EnvelopeID))
+		return true
 	}
+	p.submit(ctx, msg.Payload, received)
+	return true
 }
 
 func randomInt(maxValue int64) int {
@@ -1,9 +1,718 @@
 package slack
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/pkg/temporal"
+	"github.com/tzrikka/timpani/pkg/websocket"
+	"github.com/tzrikka/timpani/pkg/websocket/websockettest"
 )
 
+// fakeClientFactory returns a [clientFactory] which records the IDs of the
+// clients that it creates, and the URLs that their URL functions return.
+func fakeClientFactory(t *testing.T, clients []*fakeSocketClient, err error) (clientFactory, *[]string) {
+	t.Helper()
+
+	ids := []string{}
+	mu := sync.Mutex{}
+	f := func(ctx context.Context, url func(context.Context) (string, error), id string) (socketClient, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		u, urlErr := url(ctx)
+		if urlErr != nil {
+			t.Errorf("URL function error = %v", urlErr)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, id+" "+u)
+		return clients[len(ids)-1], nil
+	}
+
+	return f, &ids
+}
+
+func fakeURLGenerator(_ context.Context, appToken string) (string, error) {
+	return "wss://example.com/" + appToken, nil
+}
+
+func TestConnectionHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		appToken    string
+		connections int
+		factoryErr  error
+		want        []string
+		wantErr     string
+	}{
+		{
+			name:     "default",
+			appToken: "xapp-default",
+			want:     []string{"xapp-default wss://example.com/xapp-default"},
+		},
+		{
+			name:        "single_connection",
+			appToken:    "xapp-single",
+			connections: 1,
+			want:        []string{"xapp-single wss://example.com/xapp-single"},
+		},
+		{
+			name:        "two_connections",
+			appToken:    "xapp-double",
+			connections: 2,
+			want: []string{
+				"xapp-double-1 wss://example.com/xapp-double",
+				"xapp-double-2 wss://example.com/xapp-double",
+			},
+		},
+		{
+			name:    "missing_app_token",
+			want:    []string{},
+			wantErr: "forbidden",
+		},
+		{
+			name:       "client_error",
+			appToken:   "xapp-error",
+			factoryErr: errors.New("dial error"),
+			want:       []string{},
+			wantErr:    "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := []*fakeSocketClient{newFakeSocketClient(0), newFakeSocketClient(0)}
+			newClient, ids := fakeClientFactory(t, clients, tt.factoryErr)
+
+			data := listeners.LinkData{Secrets: map[string]string{"app_token": tt.appToken}, Connections: tt.connections}
+			err := connectionHandler(t.Context(), listeners.TemporalConfig{}, data, newClient, fakeURLGenerator)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("connectionHandler() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(*ids, tt.want) {
+				t.Errorf("connectionHandler() clients = %q, want %q", *ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectionHandlerConnFailure(t *testing.T) {
+	clients := []*fakeSocketClient{newFakeSocketClient(0), newFakeSocketClient(0)}
+	newClient, _ := fakeClientFactory(t, clients, nil)
+
+	failures := make(chan error, len(clients))
+	data := listeners.LinkData{
+		Secrets:       map[string]string{"app_token": "xapp-failure"},
+		Connections:   len(clients),
+		OnConnFailure: func(err error) { failures <- err },
+	}
+	if err := connectionHandler(t.Context(), listeners.TemporalConfig{}, data, newClient, fakeURLGenerator); err != nil {
+		t.Fatalf("connectionHandler() error = %v", err)
+	}
+
+	// Both clients fail, but the callback is called only once.
+	wantErr := websocket.ErrUnrecoverable
+	for _, c := range clients {
+		c.err = wantErr
+		close(c.msgs)
+	}
+
+	select {
+	case err := <-failures:
+		if !errors.Is(err, wantErr) {
+			t.Errorf("OnConnFailure() error = %v, want %v", err, wantErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnConnFailure() wasn't called")
+	}
+
+	select {
+	case err := <-failures:
+		t.Errorf("OnConnFailure() called again with error = %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestConnectionHandlerShutdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		cancel bool
+	}{
+		{
+			name: "client_closed",
+			err:  websocket.ErrClientClosed,
+		},
+		{
+			name:   "context_canceled",
+			err:    context.Canceled,
+			cancel: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := []*fakeSocketClient{newFakeSocketClient(0), newFakeSocketClient(0)}
+			newClient, _ := fakeClientFactory(t, clients, nil)
+
+			failures := make(chan error, len(clients))
+			data := listeners.LinkData{
+				Secrets:       map[string]string{"app_token": "xapp-shutdown"},
+				Connections:   len(clients),
+				OnConnFailure: func(err error) { failures <- err },
+			}
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			if err := connectionHandler(ctx, listeners.TemporalConfig{}, data, newClient, fakeURLGenerator); err != nil {
+				t.Fatalf("connectionHandler() error = %v", err)
+			}
+
+			if tt.cancel {
+				cancel()
+			}
+			for _, c := range clients {
+				c.err = tt.err
+				close(c.msgs)
+			}
+
+			select {
+			case err := <-failures:
+				t.Errorf("OnConnFailure() called after a deliberate shutdown, with error = %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestGenerateWebSocketURL(t *testing.T) {
+	tests := []struct {
+		name              string
+		resp              string
+		want              string
+		wantErr           bool
+		wantUnrecoverable bool
+	}{
+		{
+			name: "ok",
+			resp: `{"ok": true, "url": "wss://example.com/link"}`,
+			want: "wss://example.com/link",
+		},
+		{
+			name:    "transient_error",
+			resp:    `{"ok": false, "error": "internal_error"}`,
+			wantErr: true,
+		},
+		{
+			name:              "invalid_auth",
+			resp:              `{"ok": false, "error": "invalid_auth"}`,
+			wantErr:           true,
+			wantUnrecoverable: true,
+		},
+		{
+			name:              "account_inactive",
+			resp:              `{"ok": false, "error": "account_inactive"}`,
+			wantErr:           true,
+			wantUnrecoverable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = fmt.Fprint(w, tt.resp)
+			}))
+			defer api.Close()
+
+			origURL := connOpenURL
+			connOpenURL = api.URL
+			defer func() { connOpenURL = origURL }()
+
+			got, err := generateWebSocketURL(t.Context(), "xapp-token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateWebSocketURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, websocket.ErrUnrecoverable) != tt.wantUnrecoverable {
+				t.Errorf("generateWebSocketURL() error = %v, want unrecoverable = %v", err, tt.wantUnrecoverable)
+			}
+			if got != tt.want {
+				t.Errorf("generateWebSocketURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeConnection(t *testing.T) {
+	server := websockettest.NewServer(t)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-token" {
+			_, _ = fmt.Fprint(w, `{"ok": false, "error": "invalid_auth"}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"ok": true, "url": %q}`, server.URL())
+	}))
+	defer api.Close()
+
+	origURL := connOpenURL
+	connOpenURL = api.URL
+	defer func() { connOpenURL = origURL }()
+
+	tests := []struct {
+		name     string
+		secrets  map[string]string
+		dial     bool
+		wantErr  bool
+		wantDial bool
+	}{
+		{
+			name:    "ok",
+			secrets: map[string]string{"app_token": "xapp-token"},
+		},
+		{
+			name:     "ok_with_dial",
+			secrets:  map[string]string{"app_token": "xapp-token"},
+			dial:     true,
+			wantDial: true,
+		},
+		{
+			name:    "missing_app_token",
+			secrets: map[string]string{"bot_token": "xoxb-token"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid_auth",
+			secrets: map[string]string{"app_token": "xapp-revoked"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := len(server.Frames())
+			if err := ProbeConnection(t.Context(), tt.secrets, tt.dial); (err != nil) != tt.wantErr {
+				t.Errorf("ProbeConnection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// A dialed connection is closed immediately, so the server receives a close frame.
+			if tt.wantDial {
+				frames++
+				if got := len(server.WaitForFrames(frames, 5*time.Second)); got != frames {
+					t.Errorf("WebSocket server frames = %d, want %d", got, frames)
+				}
+			} else if got := len(server.Frames()); got != frames {
+				t.Errorf("WebSocket server frames = %d, want %d (no connection)", got, frames)
+			}
+		})
+	}
+}
+
+func TestClientEventLoopPanicRecovery(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	const event = `{"type":"events_api","envelope_id":"%s","payload":{"type":"event_callback","event":{"type":"message"}}}`
+	c := newFakeSocketClient(2, fmt.Sprintf(event, "e1"), fmt.Sprintf(event, "e2"))
+	close(c.msgs)
+
+	calls := new(atomic.Int32)
+	dispatched := make(chan any, 2)
+	origDispatch := dispatchWebSocketEvent
+	dispatchWebSocketEvent = func(_ context.Context, _ listeners.TemporalConfig, payload map[string]any) error {
+		dispatched <- payload
+		if calls.Add(1) == 1 {
+			panic("unexpected payload shape")
+		}
+		return nil
+	}
+	t.Cleanup(func() { dispatchWebSocketEvent = origDispatch })
+
+	pool := newDispatchPool(t.Context(), listeners.TemporalConfig{}, "link ID", "", 1, 2)
+	clientEventLoop(t.Context(), c, newEnvelopeCache(nil), pool)
+
+	for i := range 2 {
+		select {
+		case <-dispatched:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d wasn't dispatched, the dispatch pool didn't survive a panic", i+1)
+		}
+	}
+}
+
+func TestDispatchPoolReceipt(t *testing.T) {
+	dispatched := make(chan map[string]any, 1)
+	origDispatch := dispatchWebSocketEvent
+	dispatchWebSocketEvent = func(_ context.Context, _ listeners.TemporalConfig, payload map[string]any) error {
+		dispatched <- payload
+		return nil
+	}
+	t.Cleanup(func() { dispatchWebSocketEvent = origDispatch })
+
+	received := time.Now().Add(-time.Second)
+	pool := newDispatchPool(t.Context(), listeners.TemporalConfig{}, "link ID", "", 1, 1)
+	pool.submit(t.Context(), map[string]any{"type": "event_callback"}, received)
+
+	select {
+	case payload := <-dispatched:
+		if got := payload[temporal.ListenerKey]; got != temporal.ListenerWebSocket {
+			t.Errorf("payload[%q] = %v, want %q", temporal.ListenerKey, got, temporal.ListenerWebSocket)
+		}
+		got, ok := temporal.ReceivedAt(payload)
+		if !ok || !got.Equal(received.Truncate(time.Millisecond)) {
+			t.Errorf("payload[%q] = %v, want %v", temporal.ReceivedAtKey, payload[temporal.ReceivedAtKey], received)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event wasn't dispatched")
+	}
+}
+
+func TestClientEventLoopDispatchFailure(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	const event = `{"type":"events_api","envelope_id":"%s","payload":{"type":"event_callback","event":{"type":"message"}}}`
+	c := newFakeSocketClient(2, fmt.Sprintf(event, "e1"), fmt.Sprintf(event, "e2"))
+	close(c.msgs)
+
+	calls := new(atomic.Int32)
+	dispatched := make(chan any, 2)
+	origDispatch := dispatchWebSocketEvent
+	dispatchWebSocketEvent = func(_ context.Context, _ listeners.TemporalConfig, payload map[string]any) error {
+		dispatched <- payload
+		if calls.Add(1) == 1 {
+			return errors.New("failed to send Temporal signal")
+		}
+		return nil
+	}
+	t.Cleanup(func() { dispatchWebSocketEvent = origDispatch })
+
+	pool := newDispatchPool(t.Context(), listeners.TemporalConfig{}, "link ID", "", 1, 2)
+	clientEventLoop(t.Context(), c, newEnvelopeCache(nil), pool)
+
+	// Both events are acked, regardless of dispatch failures.
+	for i := range 2 {
+		select {
+		case <-c.acks:
+		default:
+			t.Fatalf("event %d wasn't acked", i+1)
+		}
+	}
+
+	// A dispatch failure doesn't prevent dispatching subsequent events.
+	for i := range 2 {
+		select {
+		case <-dispatched:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d wasn't dispatched after a dispatch failure", i+1)
+		}
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	tests := []struct {
+		name         string
+		msg          string
+		wantAck      *eventResponse
+		wantRefresh  bool
+		wantDispatch bool
+		wantInvalid  bool
+	}{
+		{
+			name:        "hello",
+			msg:         `{"type":"hello","connection_info":{"app_id":"A123"},"debug_info":{"approximate_connection_time":3600}}`,
+			wantRefresh: true,
+		},
+		{
+			name: "disconnect",
+			msg:  `{"type":"disconnect","reason":"refresh_requested","debug_info":{"host":"applink-1"}}`,
+		},
+		{
+			name:        "invalid_json",
+			msg:         `{"type":`,
+			wantInvalid: true,
+		},
+		{
+			name:         "events_api",
+			msg:          `{"type":"events_api","envelope_id":"e1","payload":{"type":"event_callback","event":{"type":"message"}}}`,
+			wantAck:      &eventResponse{EnvelopeID: "e1"},
+			wantDispatch: true,
+		},
+		{
+			name: "slash_commands",
+			msg:  `{"type":"slash_commands","envelope_id":"e2","payload":{"command":"/foo","text":"bar"}}`,
+			wantAck: &eventResponse{EnvelopeID: "e2", Payload: map[string]any{
+				"blocks": []map[string]any{
+					{
+						"type": "section",
+						"text": map[string]string{"type": "mrkdwn", "text": "Your command: `/foo bar`"},
+					},
+				},
+			}},
+			wantDispatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeSocketClient(1)
+			pool := &dispatchPool{jobs: make(chan dispatchJob, 1)}
+			if decoded := handleMessage(t.Context(), slog.Default(), c, newEnvelopeCache(nil), pool, []byte(tt.msg), time.Now()); decoded == tt.wantInvalid {
+				t.Errorf("handleMessage() = %v, want %v", decoded, !tt.wantInvalid)
+			}
+
+			select {
+			case ack := <-c.acks:
+				if tt.wantAck == nil {
+					t.Errorf("handleMessage() ack = %v, want none", ack)
+				} else if !reflect.DeepEqual(ack, *tt.wantAck) {
+					t.Errorf("handleMessage() ack = %v, want %v", ack, *tt.wantAck)
+				}
+			default:
+				if tt.wantAck != nil {
+					t.Errorf("handleMessage() didn't ack, want %v", *tt.wantAck)
+				}
+			}
+
+			if tt.wantRefresh {
+				// 63-72 seconds before the approximate connection time.
+				minD, maxD := (3600-72)*time.Second, (3600-63)*time.Second
+				if len(c.refreshes) != 1 || c.refreshes[0] < minD || c.refreshes[0] > maxD {
+					t.Errorf("handleMessage() refreshes = %v, want 1 in [%v, %v]", c.refreshes, minD, maxD)
+				}
+				if id := pool.appID.Load(); id == nil || *id != "A123" {
+					t.Errorf("handleMessage() didn't set the app ID")
+				}
+			} else if len(c.refreshes) > 0 {
+				t.Errorf("handleMessage() refreshes = %v, want none", c.refreshes)
+			}
+
+			if got := len(pool.jobs) > 0; got != tt.wantDispatch {
+				t.Errorf("handleMessage() dispatched = %v, want %v", got, tt.wantDispatch)
+			}
+		})
+	}
+}
+
+// fakeSocketClient replays scripted messages, and records acknowledgements, refreshes, and failures.
+type fakeSocketClient struct {
+	msgs      chan websocket.Message
+	acks      chan eventResponse
+	refreshes []time.Duration
+	failures  []websocket.StatusCode
+	err       error
+}
+
+// newFakeSocketClient returns a [fakeSocketClient] with the given queued messages. The
+// capacity of the message and ack channels is the number of messages, or n if larger.
+func newFakeSocketClient(n int, msgs ...string) *fakeSocketClient {
+	n = max(n, len(msgs))
+	c := &fakeSocketClient{msgs: make(chan websocket.Message, n), acks: make(chan eventResponse, n)}
+	for _, m := range msgs {
+		c.msgs <- websocket.Message{Opcode: websocket.OpcodeText, Data: []byte(m)}
+	}
+	return c
+}
+
+func (f *fakeSocketClient) CloseStatus() (websocket.StatusCode, string, bool) {
+	return 0, "", false
+}
+
+func (f *fakeSocketClient) Err() error {
+	return f.err
+}
+
+func (f *fakeSocketClient) FailConnection(status websocket.StatusCode, _ string) {
+	f.failures = append(f.failures, status)
+}
+
+func (f *fakeSocketClient) IncomingMessages() <-chan websocket.Message {
+	return f.msgs
+}
+
+func (f *fakeSocketClient) RefreshConnectionIn(_ context.Context, d time.Duration) {
+	f.refreshes = append(f.refreshes, d)
+}
+
+func (f *fakeSocketClient) SendJSONMessage(v any) error {
+	resp, ok := v.(eventResponse)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", v)
+	}
+	f.acks <- resp
+	return nil
+}
+
+func TestClientEventLoopUndecodableMessages(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	const (
+		bad   = `{"type":`
+		event = `{"type":"events_api","envelope_id":"%s","payload":{"type":"event_callback","event":{"type":"message"}}}`
+	)
+
+	tests := []struct {
+		name string
+		msgs []string
+		want int
+	}{
+		{
+			name: "below_threshold",
+			msgs: []string{bad, bad},
+		},
+		{
+			name: "interrupted_by_valid_message",
+			msgs: []string{bad, bad, fmt.Sprintf(event, "e1"), bad, bad},
+		},
+		{
+			name: "threshold",
+			msgs: []string{bad, bad, bad},
+			want: 1,
+		},
+		{
+			name: "count_reset_after_failure",
+			msgs: []string{bad, bad, bad, bad, bad},
+			want: 1,
+		},
+		{
+			name: "repeated_failures",
+			msgs: []string{bad, bad, bad, bad, bad, bad, fmt.Sprintf(event, "e2")},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeSocketClient(0, tt.msgs...)
+			close(c.msgs)
+
+			pool := &dispatchPool{jobs: make(chan dispatchJob, len(tt.msgs))}
+			clientEventLoop(t.Context(), c, newEnvelopeCache(nil), pool)
+
+			if len(c.failures) != tt.want {
+				t.Fatalf("clientEventLoop() failures = %v, want %d", c.failures, tt.want)
+			}
+			for _, s := range c.failures {
+				if s != websocket.StatusPolicyViolation {
+					t.Errorf("clientEventLoop() failure status = %v, want %v", s, websocket.StatusPolicyViolation)
+				}
+			}
+		})
+	}
+}
+
+func TestClientEventLoopSlowDispatch(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	const event = `{"type":"events_api","envelope_id":"%s","payload":{"type":"event_callback","event":{"type":"message"}}}`
+	ids := []string{"e1", "e2", "e1", "e3"} // Including a redelivery.
+
+	c := newFakeSocketClient(len(ids))
+	for _, id := range ids {
+		c.msgs <- websocket.Message{Opcode: websocket.OpcodeText, Data: fmt.Appendf(nil, event, id)}
+	}
+	close(c.msgs)
+
+	release := make(chan struct{})
+	dispatched := new(atomic.Int32)
+	origDispatch := dispatchWebSocketEvent
+	dispatchWebSocketEvent = func(_ context.Context, _ listeners.TemporalConfig, _ map[string]any) error {
+		<-release // Slow dispatch.
+		dispatched.Add(1)
+		return nil
+	}
+	t.Cleanup(func() { dispatchWebSocketEvent = origDispatch })
+
+	pool := newDispatchPool(t.Context(), listeners.TemporalConfig{}, "link ID", "", 1, len(ids))
+	clientEventLoop(t.Context(), c, newEnvelopeCache(nil), pool)
+
+	// All the events are acked, even though none of them was dispatched yet.
+	for i, id := range ids {
+		select {
+		case ack := <-c.acks:
+			if ack.EnvelopeID != id {
+				t.Errorf("ack %d envelope ID = %q, want %q", i, ack.EnvelopeID, id)
+			}
+		default:
+			t.Fatalf("event %d (%s) wasn't acked before dispatching", i, id)
+		}
+	}
+	if got := dispatched.Load(); got != 0 {
+		t.Errorf("dispatched events before release = %d, want 0", got)
+	}
+
+	// The redelivered event is dispatched only once.
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for dispatched.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := dispatched.Load(); got != 3 {
+		t.Errorf("dispatched events = %d, want 3", got)
+	}
+}
+
+func TestClientEventLoopAnnotations(t *testing.T) {
+	t.Chdir(t.TempDir()) // Metrics files.
+
+	msgs := []string{
+		`{"type":"hello","connection_info":{"app_id":"A123"},"debug_info":{"approximate_connection_time":3600}}`,
+		`{"type":"events_api","envelope_id":"e1","payload":{"type":"event_callback","event":{"type":"message"}}}`,
+	}
+	c := newFakeSocketClient(0, msgs...)
+	close(c.msgs)
+
+	dispatched := make(chan map[string]any, 1)
+	origDispatch := dispatchWebSocketEvent
+	dispatchWebSocketEvent = func(_ context.Context, _ listeners.TemporalConfig, payload map[string]any) error {
+		dispatched <- payload
+		return nil
+	}
+	t.Cleanup(func() { dispatchWebSocketEvent = origDispatch })
+
+	pool := newDispatchPool(t.Context(), listeners.TemporalConfig{}, "link ID", "", 1, len(msgs))
+	clientEventLoop(t.Context(), c, newEnvelopeCache(nil), pool)
+
+	select {
+	case payload := <-dispatched:
+		if payload[LinkIDKey] != "link ID" || payload[AppIDKey] != "A123" {
+			t.Errorf("dispatched payload = %v, want link ID and app ID", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event wasn't dispatched")
+	}
+}
+
+func TestEnvelopeCache(t *testing.T) {
+	e := newEnvelopeCache(nil)
+
+	ctx := t.Context()
+	if !e.add(ctx, "") || !e.add(ctx, "") {
+		t.Error("envelopeCache.add(\"\") = false, want true")
+	}
+	if !e.add(ctx, "id1") {
+		t.Error("envelopeCache.add(\"id1\") = false, want true")
+	}
+	if e.add(ctx, "id1") {
+		t.Error("envelopeCache.add(\"id1\") again = true, want false")
+	}
+	if !e.add(ctx, "id2") {
+		t.Error("envelopeCache.add(\"id2\") = false, want true")
+	}
+}
+
 func TestRandomInt(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -16,16 +16,34 @@ import (
 )
 
 const (
-	DefaultMetricsFileIn  = "metrics/timpani_in_%s.csv"
-	DefaultMetricsFileOut = "metrics/timpani_out_%s.csv"
+	DefaultMetricsFileIn      = "metrics/timpani_in_%s.csv"
+	DefaultMetricsFileOut     = "metrics/timpani_out_%s.csv"
+	DefaultMetricsFileLinks   = "metrics/timpani_links_%s.csv"
+	DefaultMetricsFileRetries = "metrics/timpani_retries_%s.csv"
+	DefaultMetricsFileBuild   = "metrics/timpani_build_%s.csv"
+	DefaultMetricsFilePanics  = "metrics/timpani_panics_%s.csv"
+	DefaultMetricsFileAcks    = "metrics/timpani_acks_%s.csv"
+	DefaultMetricsFileDeprec  = "metrics/timpani_deprecated_%s.csv"
+	DefaultMetricsFileLatency = "metrics/timpani_latency_%s.csv"
+	DefaultMetricsFileSockets = "metrics/timpani_sockets_%s.csv"
+	DefaultMetricsFileSignals = "metrics/timpani_signals_%s.csv"
 
 	fileFlags = os.O_APPEND | os.O_CREATE | os.O_WRONLY
 	filePerms = xdg.NewFilePermissions
 )
 
 var (
-	muIn  sync.Mutex
-	muOut sync.Mutex
+	muIn      sync.Mutex
+	muOut     sync.Mutex
+	muLinks   sync.Mutex
+	muRetries sync.Mutex
+	muBuild   sync.Mutex
+	muPanics  sync.Mutex
+	muAcks    sync.Mutex
+	muDeprec  sync.Mutex
+	muLatency sync.Mutex
+	muSockets sync.Mutex
+	muSignals sync.Mutex
 )
 
 // IncrementWebhookEventCounter monitors incoming webhook events. It returns the HTTP
@@ -56,6 +74,49 @@ func IncrementAPICallCounter(t time.Time, method string, err error) {
 	_ = appendToCSVFile(DefaultMetricsFileOut, t, []string{t.Format(time.RFC3339), method, errMsg})
 }
 
+// SetLinkHealthGauge monitors the validity of Thrippy link credentials,
+// as a gauge which is sampled periodically (1 = healthy, 0 = unhealthy).
+func SetLinkHealthGauge(t time.Time, linkID, template string, healthy bool) {
+	muLinks.Lock()
+	defer muLinks.Unlock()
+
+	gauge := "0"
+	if healthy {
+		gauge = "1"
+	}
+
+	_ = appendToCSVFile(DefaultMetricsFileLinks, t, []string{t.Format(time.RFC3339), linkID, template, gauge})
+}
+
+// SetSocketConnectionsGauge monitors the number of WebSocket connections of a link, as reported
+// by the third-party service itself (e.g. Slack's Socket Mode "hello" message), which may include
+// connections of other replicas and processes.
+func SetSocketConnectionsGauge(t time.Time, linkID string, n int) {
+	muSockets.Lock()
+	defer muSockets.Unlock()
+
+	_ = appendToCSVFile(DefaultMetricsFileSockets, t, []string{t.Format(time.RFC3339), linkID, strconv.Itoa(n)})
+}
+
+// SetBuildInfoGauge records the version of the running process when it starts, as a
+// constant gauge (always 1) with the build details as labels, to correlate changes
+// in other metrics with deployments.
+func SetBuildInfoGauge(t time.Time, version, revision, goVersion string) {
+	muBuild.Lock()
+	defer muBuild.Unlock()
+
+	_ = appendToCSVFile(DefaultMetricsFileBuild, t, []string{t.Format(time.RFC3339), version, revision, goVersion, "1"})
+}
+
+// IncrementHTTPRetryCounter monitors automatic retries of outgoing HTTP requests.
+func IncrementHTTPRetryCounter(t time.Time, method, host string, attempt int, reason string) {
+	muRetries.Lock()
+	defer muRetries.Unlock()
+
+	record := []string{t.Format(time.RFC3339), method, host, strconv.Itoa(attempt), reason}
+	_ = appendToCSVFile(DefaultMetricsFileRetries, t, record)
+}
+
 func appendToCSVFile(filename string, t time.Time, record []string) error {
 	filename = fmt.Sprintf(filename, t.Format(time.DateOnly))
 	f, err := os.OpenFile(filename, fileFlags, filePerms) //gosec:disable G304 // Hardcoded path.
@@ -76,3 +137,54 @@ func appendToCSVFile(filename string, t time.Time, record []string) error {
 
 	return nil
 }
+
+// IncrementPanicCounter monitors panics which were recovered in
+// webhook handlers, event listeners, and Temporal activities.
+func IncrementPanicCounter(t time.Time, component, msg string) {
+	muPanics.Lock()
+	defer muPanics.Unlock()
+
+	_ = appendToCSVFile(DefaultMetricsFilePanics, t, []string{t.Format(time.RFC3339), component, msg})
+}
+
+// RecordAckLatency monitors the time it takes to acknowledge incoming events (e.g.
+// in Slack Socket Mode), as raw samples from which a histogram can be derived.
+func RecordAckLatency(t time.Time, medium string, latency time.Duration) {
+	muAcks.Lock()
+	defer muAcks.Unlock()
+
+	ms := strconv.FormatFloat(float64(latency.Microseconds())/1000, 'f', 3, 64)
+	_ = appendToCSVFile(DefaultMetricsFileAcks, t, []string{t.Format(time.RFC3339), medium, ms})
+}
+
+// RecordWebhookLatency monitors the time it takes to respond to incoming webhook requests, per
+// link template and HTTP status code, as raw samples from which a histogram can be derived.
+func RecordWebhookLatency(t time.Time, template string, statusCode int, latency time.Duration) {
+	muLatency.Lock()
+	defer muLatency.Unlock()
+
+	ms := strconv.FormatFloat(float64(latency.Microseconds())/1000, 'f', 3, 64)
+	_ = appendToCSVFile(DefaultMetricsFileLatency, t, []string{t.Format(time.RFC3339), template, strconv.Itoa(statusCode), ms})
+}
+
+// RecordSignalDispatch monitors the dispatch of incoming events as Temporal signals. Each record
+// is a dispatch attempt, with the number of workflow executions that were waiting for the signal,
+// the number of signals that were actually sent to them, the class of the error that stopped the
+// dispatch (if any), and the dispatch latency (as a raw sample from which a histogram can be derived).
+func RecordSignalDispatch(t time.Time, signal string, matched, sent int, errClass string, latency time.Duration) {
+	muSignals.Lock()
+	defer muSignals.Unlock()
+
+	ms := strconv.FormatFloat(float64(latency.Microseconds())/1000, 'f', 3, 64)
+	record := []string{t.Format(time.RFC3339), signal, strconv.Itoa(matched), strconv.Itoa(sent), errClass, ms}
+	_ = appendToCSVFile(DefaultMetricsFileSignals, t, record)
+}
+
+// IncrementDeprecationCounter monitors the usage of deprecated features
+// (e.g. legacy webhook signatures), to know when it's safe to remove them.
+func IncrementDeprecationCounter(t time.Time, feature string) {
+	muDeprec.Lock()
+	defer muDeprec.Unlock()
+
+	_ = appendToCSVFile(DefaultMetricsFileDeprec, t, []string{t.Format(time.RFC3339), feature})
+}
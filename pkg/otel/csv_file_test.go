@@ -60,3 +60,207 @@ func TestIncrementAPICallCounter(t *testing.T) {
 		t.Errorf("file content = %q, want %q", got, want)
 	}
 }
+
+func TestSetLinkHealthGauge(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.SetLinkHealthGauge(now, "link 1", "template", true)
+	otel.SetLinkHealthGauge(now, "link 2", "template", false)
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileLinks, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	ts := now.Format(time.RFC3339)
+	want := fmt.Sprintf("%s,link 1,template,1\n%s,link 2,template,0\n", ts, ts)
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestSetSocketConnectionsGauge(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.SetSocketConnectionsGauge(now, "link 1", 2)
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileSockets, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	want := fmt.Sprintf("%s,link 1,2\n", now.Format(time.RFC3339))
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestIncrementHTTPRetryCounter(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.IncrementHTTPRetryCounter(now, "GET", "example.com", 1, "503 Service Unavailable")
+	otel.IncrementHTTPRetryCounter(now, "DELETE", "example.com", 2, "connection refused")
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileRetries, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	ts := now.Format(time.RFC3339)
+	want := fmt.Sprintf("%s,GET,example.com,1,503 Service Unavailable\n%s,DELETE,example.com,2,connection refused\n", ts, ts)
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestSetBuildInfoGauge(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.SetBuildInfoGauge(now, "v1.2.3", "0123456789ab", "go1.26.1")
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileBuild, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	want := now.Format(time.RFC3339) + ",v1.2.3,0123456789ab,go1.26.1,1\n"
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestIncrementPanicCounter(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.IncrementPanicCounter(now, "webhook", "assignment to entry in nil map")
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFilePanics, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	want := now.Format(time.RFC3339) + ",webhook,assignment to entry in nil map\n"
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestRecordAckLatency(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.RecordAckLatency(now, "slack_socket_mode", 1234567*time.Nanosecond)
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileAcks, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	want := now.Format(time.RFC3339) + ",slack_socket_mode,1.234\n"
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestIncrementDeprecationCounter(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.IncrementDeprecationCounter(now, "webhook_sha1_signature")
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileDeprec, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	want := now.Format(time.RFC3339) + ",webhook_sha1_signature\n"
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestRecordWebhookLatency(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.RecordWebhookLatency(now, "slack-oauth", 200, 2345678*time.Nanosecond)
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileLatency, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	want := now.Format(time.RFC3339) + ",slack-oauth,200,2.345\n"
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestRecordSignalDispatch(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now().UTC()
+
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	otel.RecordSignalDispatch(now, "slack.events.app_mention", 2, 2, "", 12345*time.Microsecond)
+	otel.RecordSignalDispatch(now, "github.events.push", 1, 0, "signal", time.Millisecond)
+
+	f, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFileSignals, now.Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(f)
+	ts := now.Format(time.RFC3339)
+	want := fmt.Sprintf("%s,slack.events.app_mention,2,2,,12.345\n%s,github.events.push,1,0,signal,1.000\n", ts, ts)
+	if got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
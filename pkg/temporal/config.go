@@ -1,10 +1,23 @@
 package temporal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
 	altsrc "github.com/urfave/cli-altsrc/v3"
-	"github.com/urfave/cli-altsrc/v3/toml"
 	"github.com/urfave/cli/v3"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
+
+	"github.com/tzrikka/timpani/internal/config"
+	"github.com/tzrikka/timpani/internal/listeners"
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/taskqueue"
 )
 
 const (
@@ -14,7 +27,7 @@ const (
 // Flags defines CLI flags to configure a Temporal worker. These flags are usually
 // set using environment variables or the application's configuration file.
 func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
-	return []cli.Flag{
+	flags := []cli.Flag{
 		// https://pkg.go.dev/go.temporal.io/sdk/internal#ClientOptions
 		&cli.StringFlag{
 			Name:  "temporal-address",
@@ -22,7 +35,7 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Value: client.DefaultHostPort,
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("TEMPORAL_ADDRESS"),
-				toml.TOML("temporal.address", configFilePath),
+				config.TOML("temporal.address", configFilePath),
 			),
 		},
 		&cli.StringFlag{
@@ -31,21 +44,118 @@ func Flags(configFilePath altsrc.StringSourcer) []cli.Flag {
 			Value: client.DefaultNamespace,
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("TEMPORAL_NAMESPACE"),
-				toml.TOML("temporal.namespace", configFilePath),
+				config.TOML("temporal.namespace", configFilePath),
 			),
 		},
 
 		// Worker parameter.
 		&cli.StringFlag{
-			Name:  "temporal-task-queue",
+			Name:  taskqueue.MainFlag,
 			Usage: "Temporal task queue",
 			Value: DefaultTaskQueue,
 			Sources: cli.NewValueSourceChain(
 				cli.EnvVar("TEMPORAL_TASK_QUEUE"),
-				toml.TOML("temporal.task_queue", configFilePath),
+				config.TOML("temporal.task_queue", configFilePath),
 			),
 		},
 
+		&cli.StringSliceFlag{
+			Name:  "require-services",
+			Usage: "comma-separated third-party services (e.g. slack,github) which the worker must register, or else fail to start",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_REQUIRE_SERVICES"),
+				config.TOML("temporal.require_services", configFilePath),
+			),
+			Validator: validateRequiredServices,
+		},
+
 		// https://pkg.go.dev/go.temporal.io/sdk/internal#WorkerOptions
+
+		// Signal parameters.
+		&cli.BoolFlag{
+			Name:  "temporal-compress-signals",
+			Usage: "zlib-compress the payloads of Temporal signals (all the Timpani workers must be up to date)",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_TEMPORAL_COMPRESS_SIGNALS"),
+				config.TOML("temporal.compress_signals", configFilePath),
+			),
+		},
+		&cli.StringSliceFlag{
+			Name:  "temporal-link-routes",
+			Usage: "per-link Temporal destinations of signals, to isolate tenants: <Thrippy link ID>=<namespace>[/<task queue>]",
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_TEMPORAL_LINK_ROUTES"),
+				config.TOML("temporal.link_routes", configFilePath),
+			),
+			Validator: validateLinkRoutes,
+		},
+		&cli.IntFlag{
+			Name:  "temporal-max-signal-size",
+			Usage: "maximum size in bytes of Temporal signal payloads, beyond which bulky event fields are truncated",
+			Value: DefaultMaxSignalSize,
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TIMPANI_TEMPORAL_MAX_SIGNAL_SIZE"),
+				config.TOML("temporal.max_signal_size", configFilePath),
+			),
+		},
+	}
+
+	return append(flags, taskQueueFlags(configFilePath)...)
+}
+
+// taskQueueFlags defines an optional dedicated task queue for the activities
+// of each third-party service (e.g. "--temporal-task-queue-slack").
+func taskQueueFlags(configFilePath altsrc.StringSourcer) []cli.Flag {
+	flags := make([]cli.Flag, 0, len(providers))
+	for _, p := range providers {
+		flags = append(flags, &cli.StringFlag{
+			Name:  taskqueue.Flag(p.service),
+			Usage: fmt.Sprintf("dedicated Temporal task queue for %s activities (default = the main task queue)", p.service),
+			Sources: cli.NewValueSourceChain(
+				cli.EnvVar("TEMPORAL_TASK_QUEUE_"+strings.ToUpper(p.service)),
+				config.TOML("temporal.task_queue_"+p.service, configFilePath),
+			),
+		})
 	}
+	return flags
+}
+
+// validateLinkRoutes checks the syntax of the "temporal-link-routes" CLI flag.
+func validateLinkRoutes(entries []string) error {
+	_, err := listeners.ParseLinkRoutes(entries)
+	return err
+}
+
+// dialNamespaceClient is a variable only to facilitate testing with a fake Temporal client.
+var dialNamespaceClient = client.NewNamespaceClient
+
+// CheckLinkNamespaces ensures that all the namespaces in the configuration's
+// [listeners.LinkRoute]s exist, so misconfigured routes fail at startup,
+// instead of silently dropping the events of the affected links.
+func CheckLinkNamespaces(ctx context.Context, cfg listeners.TemporalConfig) error {
+	namespaces := map[string]bool{}
+	for _, r := range cfg.LinkRoutes {
+		namespaces[r.Namespace] = true
+	}
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	l := logger.FromContext(ctx)
+	c, err := dialNamespaceClient(client.Options{HostPort: cfg.HostPort, Logger: log.NewStructuredLogger(l)})
+	if err != nil {
+		return fmt.Errorf("failed to dial Temporal: %w", err)
+	}
+	defer c.Close()
+
+	var errs []error
+	for _, ns := range slices.Sorted(maps.Keys(namespaces)) {
+		if _, err := c.Describe(ctx, ns); err != nil {
+			errs = append(errs, fmt.Errorf("Temporal namespace %q of link routes: %w", ns, err))
+			continue
+		}
+		l.Debug("Temporal namespace of link routes exists", slog.String("namespace", ns))
+	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,93 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/tzrikka/timpani/internal/listeners"
+)
+
+// fakeNamespaceClient is a Temporal namespace client with predefined namespaces.
+type fakeNamespaceClient struct {
+	client.NamespaceClient
+
+	namespaces []string
+	described  []string
+}
+
+func (c *fakeNamespaceClient) Describe(_ context.Context, name string) (*workflowservice.DescribeNamespaceResponse, error) {
+	c.described = append(c.described, name)
+	if !slices.Contains(c.namespaces, name) {
+		return nil, serviceerror.NewNamespaceNotFound(name)
+	}
+	return new(workflowservice.DescribeNamespaceResponse), nil
+}
+
+func (c *fakeNamespaceClient) Close() {}
+
+func TestCheckLinkNamespaces(t *testing.T) {
+	orig := dialNamespaceClient
+	t.Cleanup(func() { dialNamespaceClient = orig })
+
+	tests := []struct {
+		name          string
+		routes        map[string]listeners.LinkRoute
+		dialErr       error
+		wantDescribed []string
+		wantErr       bool
+	}{
+		{
+			name: "no_routes",
+		},
+		{
+			name: "existing_namespaces",
+			routes: map[string]listeners.LinkRoute{
+				"link1": {Namespace: "team-b"},
+				"link2": {Namespace: "team-a", TaskQueue: "events"},
+				"link3": {Namespace: "team-a"},
+			},
+			wantDescribed: []string{"team-a", "team-b"},
+		},
+		{
+			name: "missing_namespace",
+			routes: map[string]listeners.LinkRoute{
+				"link1": {Namespace: "team-a"},
+				"link2": {Namespace: "team-c"},
+			},
+			wantDescribed: []string{"team-a", "team-c"},
+			wantErr:       true,
+		},
+		{
+			name:    "dial_error",
+			routes:  map[string]listeners.LinkRoute{"link1": {Namespace: "team-a"}},
+			dialErr: errors.New("connection refused"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeNamespaceClient{namespaces: []string{"default", "team-a", "team-b"}}
+			dialNamespaceClient = func(client.Options) (client.NamespaceClient, error) {
+				if tt.dialErr != nil {
+					return nil, tt.dialErr
+				}
+				return c, nil
+			}
+
+			err := CheckLinkNamespaces(t.Context(), listeners.TemporalConfig{LinkRoutes: tt.routes})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckLinkNamespaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(c.described, tt.wantDescribed) {
+				t.Errorf("described namespaces = %v, want %v", c.described, tt.wantDescribed)
+			}
+		})
+	}
+}
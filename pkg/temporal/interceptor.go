@@ -0,0 +1,38 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+// panicCounter is a Temporal worker interceptor that counts panics in activities.
+// The Temporal SDK already recovers them and reports them as activity failures,
+// so this interceptor only records them as a metric, and then resumes panicking.
+type panicCounter struct {
+	interceptor.WorkerInterceptorBase
+}
+
+func (*panicCounter) InterceptActivity(_ context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	return &activityPanicCounter{ActivityInboundInterceptorBase: interceptor.ActivityInboundInterceptorBase{Next: next}}
+}
+
+type activityPanicCounter struct {
+	interceptor.ActivityInboundInterceptorBase
+}
+
+func (a *activityPanicCounter) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (any, error) {
+	defer func() {
+		if r := recover(); r != nil {
+			otel.IncrementPanicCounter(time.Now().UTC(), "activity:"+activity.GetInfo(ctx).ActivityType.Name, fmt.Sprint(r))
+			panic(r)
+		}
+	}()
+
+	return a.Next.ExecuteActivity(ctx, in)
+}
@@ -0,0 +1,48 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/tzrikka/timpani/pkg/otel"
+)
+
+func TestPanicCounter(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("metrics", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	f := func(_ context.Context) error {
+		panic("unexpected response shape")
+	}
+
+	env := new(testsuite.WorkflowTestSuite).NewTestActivityEnvironment()
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{&panicCounter{}}})
+	env.RegisterActivityWithOptions(f, activity.RegisterOptions{Name: "test.panic"})
+
+	_, err := env.ExecuteActivity("test.panic")
+	var panicErr *temporal.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("ExecuteActivity() error = %v, want PanicError", err)
+	}
+
+	b, err := os.ReadFile(fmt.Sprintf(otel.DefaultMetricsFilePanics, time.Now().UTC().Format(time.DateOnly)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, ",activity:test.panic,unexpected response shape\n") {
+		t.Errorf("metrics file content = %q", got)
+	}
+}
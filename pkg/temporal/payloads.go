@@ -0,0 +1,172 @@
+package temporal
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// DefaultMaxSignalSize leaves a safe margin below Temporal's default blob size limit (2 MiB).
+	DefaultMaxSignalSize = 1 << 20 // 1 MiB.
+
+	// TruncatedKey is added to signal payloads whose bulky fields were truncated
+	// by [Signal], with a list of the truncated fields (e.g. "commits[].added").
+	TruncatedKey = "timpani_truncated"
+
+	// maxBulkyListEntries is the number of entries that [Signal] keeps in truncated lists.
+	maxBulkyListEntries = 20
+)
+
+// bulkyFields are known to bloat webhook event payloads, and are safe to truncate. Path
+// segments with a "[]" suffix are lists whose entries are traversed. The last segment is
+// truncated to [maxBulkyListEntries] if it's a list, or removed if it's anything else.
+var bulkyFields = []string{
+	// GitHub "push" events: https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+	"commits[].added",
+	"commits[].removed",
+	"commits[].modified",
+	// Bitbucket "repo:push" events: https://support.atlassian.com/bitbucket-cloud/docs/event-payloads/#Push
+	"push.changes[].commits",
+	// Bitbucket "pullrequest:*" events, which contain HTML renderings of the title and description.
+	"pullrequest.rendered",
+	"pullrequest.summary.html",
+}
+
+// signalDataConverter returns the data converter of the Temporal client in [Signal].
+// Compression requires Timpani workers to use [workerDataConverter].
+func signalDataConverter(compress bool) converter.DataConverter {
+	if !compress {
+		return converter.GetDefaultDataConverter()
+	}
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), converter.NewZlibCodec(converter.ZlibCodecOptions{}))
+}
+
+// workerDataConverter returns the data converter of the Temporal worker. It decodes
+// compressed signal payloads (see [signalDataConverter]), but never compresses anything
+// itself, because the results of Timpani activities and child workflows are decoded
+// by other workers, which may not support compression.
+func workerDataConverter() converter.DataConverter {
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), decodeOnlyCodec{converter.NewZlibCodec(converter.ZlibCodecOptions{})})
+}
+
+type decodeOnlyCodec struct {
+	converter.PayloadCodec
+}
+
+func (decodeOnlyCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	return payloads, nil
+}
+
+// limitPayloadSize checks the size of a signal payload after encoding (and compression, if
+// enabled). If it exceeds the given limit, it truncates the payload's [bulkyFields], and then
+// checks again. The input payload is never modified: a truncated copy is returned instead.
+func limitPayloadSize(l *slog.Logger, dc converter.DataConverter, payload map[string]any, limit int) (map[string]any, error) {
+	if limit <= 0 {
+		limit = DefaultMaxSignalSize
+	}
+
+	size, err := encodedSize(dc, payload)
+	if err != nil || size <= limit {
+		return payload, err
+	}
+
+	truncated, fields := truncateBulkyFields(payload)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("signal payload is too large (%d bytes, limit is %d)", size, limit)
+	}
+	truncated[TruncatedKey] = fields
+
+	newSize, err := encodedSize(dc, truncated)
+	if err != nil {
+		return nil, err
+	}
+	if newSize > limit {
+		return nil, fmt.Errorf("signal payload is too large even after truncation (%d bytes, limit is %d)", newSize, limit)
+	}
+
+	l.Warn("truncated bulky fields in large signal payload", slog.Any("fields", fields),
+		slog.Int("original_size", size), slog.Int("truncated_size", newSize))
+	return truncated, nil
+}
+
+func encodedSize(dc converter.DataConverter, payload map[string]any) (int, error) {
+	p, err := dc.ToPayload(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode signal payload: %w", err)
+	}
+	return len(p.GetData()), nil
+}
+
+// truncateBulkyFields returns a copy of the given payload without the
+// [bulkyFields], and the paths of the fields which were actually truncated.
+func truncateBulkyFields(payload map[string]any) (map[string]any, []string) {
+	truncated := copyJSON(payload).(map[string]any) //nolint:errcheck // Type conversion always succeeds.
+
+	var fields []string
+	for _, path := range bulkyFields {
+		if truncateField(truncated, strings.Split(path, ".")) {
+			fields = append(fields, path)
+		}
+	}
+	return truncated, fields
+}
+
+func truncateField(m map[string]any, path []string) bool {
+	key, isList := strings.CutSuffix(path[0], "[]")
+	v, ok := m[key]
+	if !ok {
+		return false
+	}
+
+	if len(path) > 1 {
+		var children []any
+		if isList {
+			children, _ = v.([]any)
+		} else {
+			children = []any{v}
+		}
+
+		found := false
+		for _, c := range children {
+			if cm, ok := c.(map[string]any); ok && truncateField(cm, path[1:]) {
+				found = true
+			}
+		}
+		return found
+	}
+
+	if list, ok := v.([]any); ok {
+		if len(list) <= maxBulkyListEntries {
+			return false
+		}
+		m[key] = list[:maxBulkyListEntries]
+		return true
+	}
+
+	delete(m, key)
+	return true
+}
+
+// copyJSON deep-copies maps and lists, which is enough for decoded JSON values.
+func copyJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = copyJSON(e)
+		}
+		return m
+	case []any:
+		l := make([]any, len(v))
+		for i, e := range v {
+			l[i] = copyJSON(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
@@ -0,0 +1,151 @@
+package temporal
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pushEvent returns a synthetic GitHub "push" event payload (over 5 MiB), with 60
+// commits that modify 2000 files each. Random file names make it incompressible.
+func pushEvent(random bool) map[string]any {
+	r := rand.New(rand.NewPCG(1, 2)) //gosec:disable G404 // Deterministic test data.
+	commits := make([]any, 60)
+	for i := range commits {
+		files := make([]any, 2000)
+		for j := range files {
+			if random {
+				files[j] = fmt.Sprintf("src/%016x/%016x/%016x.go", r.Uint64(), r.Uint64(), r.Uint64())
+			} else {
+				files[j] = fmt.Sprintf("src/main/java/com/example/project/module/File%05d.java", j)
+			}
+		}
+		commits[i] = map[string]any{"id": fmt.Sprintf("%040d", i), "modified": files}
+	}
+	return map[string]any{"ref": "refs/heads/main", "commits": commits}
+}
+
+func TestLimitPayloadSize(t *testing.T) {
+	l := slog.New(slog.DiscardHandler)
+
+	tests := []struct {
+		name          string
+		compress      bool
+		random        bool
+		wantTruncated []string
+	}{
+		{
+			name:     "compressible_payload_with_compression",
+			compress: true,
+		},
+		{
+			name:          "compressible_payload_without_compression",
+			wantTruncated: []string{"commits[].modified"},
+		},
+		{
+			name:          "incompressible_payload",
+			compress:      true,
+			random:        true,
+			wantTruncated: []string{"commits[].modified"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := pushEvent(tt.random)
+			dc := signalDataConverter(tt.compress)
+			if size, _ := encodedSize(signalDataConverter(false), payload); size < 5<<20 {
+				t.Fatalf("synthetic payload size = %d, want at least 5 MiB", size)
+			}
+
+			got, err := limitPayloadSize(l, dc, payload, DefaultMaxSignalSize)
+			if err != nil {
+				t.Fatalf("limitPayloadSize() error = %v", err)
+			}
+
+			if size, _ := encodedSize(dc, got); size > DefaultMaxSignalSize {
+				t.Errorf("limitPayloadSize() encoded size = %d, want <= %d", size, DefaultMaxSignalSize)
+			}
+			if truncated, _ := got[TruncatedKey].([]string); !reflect.DeepEqual(truncated, tt.wantTruncated) {
+				t.Errorf("limitPayloadSize() %s = %v, want %v", TruncatedKey, truncated, tt.wantTruncated)
+			}
+
+			want := 2000
+			if tt.wantTruncated != nil {
+				want = maxBulkyListEntries
+			}
+			if files, _ := got["commits"].([]any)[0].(map[string]any)["modified"].([]any); len(files) != want {
+				t.Errorf("limitPayloadSize() files in first commit = %d, want %d", len(files), want)
+			}
+
+			// The original payload must not be modified.
+			if files, _ := payload["commits"].([]any)[0].(map[string]any)["modified"].([]any); len(files) != 2000 {
+				t.Errorf("original payload files in first commit = %d, want 2000", len(files))
+			}
+		})
+	}
+}
+
+func TestLimitPayloadSizeWithoutBulkyFields(t *testing.T) {
+	payload := map[string]any{"text": strings.Repeat("x", 100)}
+	if _, err := limitPayloadSize(slog.New(slog.DiscardHandler), signalDataConverter(false), payload, 50); err == nil {
+		t.Error("limitPayloadSize() error = nil")
+	}
+}
+
+func TestTruncateBulkyFields(t *testing.T) {
+	long := make([]any, maxBulkyListEntries+1)
+	short := make([]any, maxBulkyListEntries)
+	payload := map[string]any{
+		"push": map[string]any{"changes": []any{
+			map[string]any{"commits": short},
+			map[string]any{"commits": long},
+		}},
+		"pullrequest": map[string]any{"id": 1.0, "rendered": map[string]any{"description": "<p>HTML</p>"}},
+	}
+
+	got, fields := truncateBulkyFields(payload)
+	if want := []string{"push.changes[].commits", "pullrequest.rendered"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("truncateBulkyFields() fields = %v, want %v", fields, want)
+	}
+
+	changes := got["push"].(map[string]any)["changes"].([]any)
+	if n := len(changes[1].(map[string]any)["commits"].([]any)); n != maxBulkyListEntries {
+		t.Errorf("truncateBulkyFields() commits = %d, want %d", n, maxBulkyListEntries)
+	}
+	if want := map[string]any{"id": 1.0}; !reflect.DeepEqual(got["pullrequest"], want) {
+		t.Errorf("truncateBulkyFields() pullrequest = %v, want %v", got["pullrequest"], want)
+	}
+}
+
+func TestWorkerDataConverter(t *testing.T) {
+	payload := map[string]any{"text": strings.Repeat("compressible ", 1000)}
+	p, err := signalDataConverter(true).ToPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(p.GetMetadata()["encoding"]); got != "binary/zlib" {
+		t.Fatalf("signal payload encoding = %q, want %q", got, "binary/zlib")
+	}
+
+	// Workers decode compressed payloads...
+	got := map[string]any{}
+	if err := workerDataConverter().FromPayload(p, &got); err != nil {
+		t.Fatalf("FromPayload() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, payload) {
+		t.Error("FromPayload() = different payload")
+	}
+
+	// ...but don't compress anything themselves.
+	p, err = workerDataConverter().ToPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(p.GetMetadata()["encoding"]); got != "json/plain" {
+		t.Errorf("worker payload encoding = %q, want %q", got, "json/plain")
+	}
+}
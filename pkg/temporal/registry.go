@@ -0,0 +1,159 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+	"github.com/tzrikka/timpani/pkg/api/bitbucket"
+	"github.com/tzrikka/timpani/pkg/api/confluence"
+	"github.com/tzrikka/timpani/pkg/api/github"
+	"github.com/tzrikka/timpani/pkg/api/jira"
+	"github.com/tzrikka/timpani/pkg/api/slack"
+)
+
+// provider registers the activities and workflows of a third-party service in the Temporal worker.
+type provider struct {
+	service  string
+	register func(ctx context.Context, cmd *cli.Command, w worker.Worker) error
+}
+
+// providers is a variable only to facilitate testing with fake providers.
+var providers = []provider{
+	{service: "bitbucket", register: bitbucket.Register},
+	{service: "confluence", register: confluence.Register},
+	{service: "github", register: github.Register},
+	{service: "jira", register: jira.Register},
+	{service: "slack", register: slack.Register},
+}
+
+// RegistrationStatus reports whether the activities and workflows of a
+// third-party service were registered in the Temporal worker, and if not, why.
+type RegistrationStatus struct {
+	Service    string `json:"service"`
+	Registered bool   `json:"registered"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+var (
+	registrations   []RegistrationStatus
+	registrationsMu sync.RWMutex
+)
+
+// Registrations returns the registration status of all the third-party services in the
+// Temporal worker, or nil if this process doesn't run a worker (or it didn't start yet).
+func Registrations() []RegistrationStatus {
+	registrationsMu.RLock()
+	defer registrationsMu.RUnlock()
+
+	return slices.Clone(registrations)
+}
+
+// registerProviders registers the activities and workflows of all the third-party services
+// which are configured, logs a summary of the results, and returns an error if any of the
+// services which are required by the "--require-services" flag were not registered.
+//
+// Services with a dedicated task queue (see [taskqueue.Service]) register their activities
+// in separate workers, which are created by the given function. This function returns the
+// ones that have any registrations, and the caller is responsible for running them.
+func registerProviders(ctx context.Context, cmd *cli.Command, w worker.Worker, newWorker func(taskQueue string) worker.Worker) ([]worker.Worker, error) {
+	statuses := make([]RegistrationStatus, 0, len(providers))
+	var registered []string
+	skipped := map[string]string{}
+
+	mainQueue := cmd.String(taskqueue.MainFlag)
+	queues := map[string]string{}           // Service -> dedicated task queue.
+	dedicated := map[string]worker.Worker{} // Dedicated task queue -> worker.
+	var used []string
+
+	for _, p := range providers {
+		pw, q := w, taskqueue.Service(cmd, p.service)
+		if q != mainQueue {
+			if _, ok := dedicated[q]; !ok {
+				dedicated[q] = newWorker(q)
+			}
+			pw = &routedWorker{Worker: dedicated[q], main: w}
+		}
+
+		s := RegistrationStatus{Service: p.service, Registered: true}
+		if err := p.register(ctx, cmd, pw); err != nil {
+			s.Registered, s.Reason = false, err.Error()
+			skipped[p.service] = s.Reason
+		} else {
+			registered = append(registered, p.service)
+			if q != mainQueue {
+				queues[p.service] = q
+				if !slices.Contains(used, q) {
+					used = append(used, q)
+				}
+			}
+		}
+		statuses = append(statuses, s)
+	}
+
+	l := logger.FromContext(ctx)
+	l.Info("Temporal worker registrations",
+		slog.Any("registered", registered), slog.Any("skipped", skipped))
+	l.Info("Temporal task queues", slog.String("main", mainQueue), slog.Any("dedicated", queues))
+
+	registrationsMu.Lock()
+	registrations = statuses
+	registrationsMu.Unlock()
+
+	var missing []string
+	for _, s := range cmd.StringSlice("require-services") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if reason, ok := skipped[s]; ok {
+			missing = append(missing, fmt.Sprintf("%s (%s)", s, reason))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("required services not registered: %s", strings.Join(missing, ", "))
+	}
+
+	workers := make([]worker.Worker, 0, len(used))
+	for _, q := range used {
+		workers = append(workers, dedicated[q])
+	}
+	return workers, nil
+}
+
+// routedWorker registers activities in the worker of a dedicated task queue, and
+// workflows in the main worker, so that callers don't need to know about the former.
+type routedWorker struct {
+	worker.Worker
+
+	main worker.Worker
+}
+
+func (w *routedWorker) RegisterWorkflow(f any) {
+	w.main.RegisterWorkflow(f)
+}
+
+func (w *routedWorker) RegisterWorkflowWithOptions(f any, opts workflow.RegisterOptions) {
+	w.main.RegisterWorkflowWithOptions(f, opts)
+}
+
+func (w *routedWorker) RegisterDynamicWorkflow(f any, opts workflow.DynamicRegisterOptions) {
+	w.main.RegisterDynamicWorkflow(f, opts)
+}
+
+// validateRequiredServices checks the values of the "--require-services" flag.
+func validateRequiredServices(services []string) error {
+	for _, s := range services {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !slices.ContainsFunc(providers, func(p provider) bool { return p.service == s }) {
+			return fmt.Errorf("unknown service in --require-services: %q", s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package temporal
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tzrikka/timpani/internal/thrippy"
+)
+
+func fakeRegister(err error) func(context.Context, *cli.Command, worker.Worker) error {
+	return func(_ context.Context, _ *cli.Command, _ worker.Worker) error {
+		return err
+	}
+}
+
+func TestRegisterProviders(t *testing.T) {
+	orig := providers
+	t.Cleanup(func() {
+		providers = orig
+		registrations = nil
+	})
+	providers = []provider{
+		{service: "bitbucket", register: fakeRegister(thrippy.ErrLinkIDInvalid)},
+		{service: "github", register: fakeRegister(nil)},
+		{service: "slack", register: fakeRegister(thrippy.ErrLinkIDNotConfigured)},
+	}
+
+	tests := []struct {
+		name    string
+		require []string
+		wantErr string
+	}{
+		{
+			name: "nothing_required",
+		},
+		{
+			name:    "required_registered",
+			require: []string{"GitHub"},
+		},
+		{
+			name:    "required_skipped",
+			require: []string{"github", "slack", "bitbucket"},
+			wantErr: "required services not registered: slack (Thrippy link ID not configured), bitbucket (invalid Thrippy link ID)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cli.Command{Flags: []cli.Flag{&cli.StringSliceFlag{Name: "require-services"}, &cli.StringFlag{Name: "temporal-task-queue"}}}
+			_ = cmd.Set("require-services", strings.Join(tt.require, ","))
+
+			_, err := registerProviders(t.Context(), cmd, nil, nil)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("registerProviders() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("registerProviders() error = %v, want %q", err, tt.wantErr)
+			}
+
+			want := []RegistrationStatus{
+				{Service: "bitbucket", Reason: "invalid Thrippy link ID"},
+				{Service: "github", Registered: true},
+				{Service: "slack", Reason: "Thrippy link ID not configured"},
+			}
+			if got := Registrations(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Registrations() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// fakeWorker records the names of the activities and workflows registered in it.
+type fakeWorker struct {
+	worker.Worker
+
+	queue      string
+	activities []string
+	workflows  []string
+}
+
+func (w *fakeWorker) RegisterActivityWithOptions(_ any, opts activity.RegisterOptions) {
+	w.activities = append(w.activities, opts.Name)
+}
+
+func (w *fakeWorker) RegisterWorkflowWithOptions(_ any, opts workflow.RegisterOptions) {
+	w.workflows = append(w.workflows, opts.Name)
+}
+
+func registerBoth(service string) func(context.Context, *cli.Command, worker.Worker) error {
+	return func(_ context.Context, _ *cli.Command, w worker.Worker) error {
+		w.RegisterActivityWithOptions(nil, activity.RegisterOptions{Name: service + ".activity"})
+		w.RegisterWorkflowWithOptions(nil, workflow.RegisterOptions{Name: service + ".workflow"})
+		return nil
+	}
+}
+
+func TestRegisterProvidersTaskQueues(t *testing.T) {
+	orig := providers
+	t.Cleanup(func() {
+		providers = orig
+		registrations = nil
+	})
+	providers = []provider{
+		{service: "bitbucket", register: fakeRegister(thrippy.ErrLinkIDNotConfigured)},
+		{service: "github", register: registerBoth("github")},
+		{service: "jira", register: registerBoth("jira")},
+		{service: "slack", register: registerBoth("slack")},
+	}
+
+	cmd := &cli.Command{Flags: append(taskQueueFlags(""),
+		&cli.StringSliceFlag{Name: "require-services"},
+		&cli.StringFlag{Name: "temporal-task-queue", Value: "timpani"},
+	)}
+	_ = cmd.Set("temporal-task-queue-bitbucket", "vcs")
+	_ = cmd.Set("temporal-task-queue-github", "vcs")
+	_ = cmd.Set("temporal-task-queue-jira", "timpani")
+	_ = cmd.Set("temporal-task-queue-slack", "chat")
+
+	main := &fakeWorker{queue: "timpani"}
+	created := map[string]*fakeWorker{}
+	newWorker := func(q string) worker.Worker {
+		created[q] = &fakeWorker{queue: q}
+		return created[q]
+	}
+
+	dedicated, err := registerProviders(t.Context(), cmd, main, newWorker)
+	if err != nil {
+		t.Fatalf("registerProviders() error = %v", err)
+	}
+
+	var got []string
+	for _, w := range dedicated {
+		got = append(got, w.(*fakeWorker).queue)
+	}
+	if want := []string{"vcs", "chat"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("registerProviders() dedicated workers = %q, want %q", got, want)
+	}
+
+	want := map[string]*fakeWorker{
+		"timpani": {queue: "timpani", activities: []string{"jira.activity"}, workflows: []string{"github.workflow", "jira.workflow", "slack.workflow"}},
+		"vcs":     {queue: "vcs", activities: []string{"github.activity"}},
+		"chat":    {queue: "chat", activities: []string{"slack.activity"}},
+	}
+	created["timpani"] = main
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("registrations = %+v, want %+v", created, want)
+	}
+}
+
+func TestValidateRequiredServices(t *testing.T) {
+	if err := validateRequiredServices([]string{"slack", " GitHub"}); err != nil {
+		t.Errorf("validateRequiredServices() error = %v", err)
+	}
+	if err := validateRequiredServices([]string{"slack", "githib"}); err == nil {
+		t.Error("validateRequiredServices() error = nil, want unknown service error")
+	}
+}
@@ -8,25 +8,32 @@ package temporal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
 	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/urfave/cli/v3"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/worker"
 	"go.temporal.io/sdk/workflow"
 
 	"github.com/tzrikka/timpani/internal/listeners"
 	"github.com/tzrikka/timpani/internal/logger"
-	"github.com/tzrikka/timpani/pkg/api/bitbucket"
-	"github.com/tzrikka/timpani/pkg/api/github"
-	"github.com/tzrikka/timpani/pkg/api/jira"
-	"github.com/tzrikka/timpani/pkg/api/slack"
+	"github.com/tzrikka/timpani/internal/taskqueue"
+	"github.com/tzrikka/timpani/pkg/otel"
 )
 
 // Run initializes the Temporal worker, and blocks to keep it running.
@@ -36,16 +43,17 @@ func Run(ctx context.Context, cmd *cli.Command, bi *debug.BuildInfo) error {
 	l.Info("Temporal server address: " + addr)
 
 	c, err := client.Dial(client.Options{
-		HostPort:  addr,
-		Namespace: cmd.String("temporal-namespace"),
-		Logger:    log.NewStructuredLogger(l),
+		HostPort:      addr,
+		Namespace:     cmd.String("temporal-namespace"),
+		Logger:        log.NewStructuredLogger(l),
+		DataConverter: workerDataConverter(),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to dial Temporal: %w", err)
 	}
 	defer c.Close()
 
-	w := worker.New(c, cmd.String("temporal-task-queue"), worker.Options{
+	opts := worker.Options{
 		DeploymentOptions: worker.DeploymentOptions{
 			UseVersioning: true,
 			Version: worker.WorkerDeploymentVersion{
@@ -54,15 +62,26 @@ func Run(ctx context.Context, cmd *cli.Command, bi *debug.BuildInfo) error {
 			},
 			DefaultVersioningBehavior: workflow.VersioningBehaviorAutoUpgrade,
 		},
-	})
+		Interceptors: []interceptor.WorkerInterceptor{&panicCounter{}},
+	}
+	w := worker.New(c, cmd.String(taskqueue.MainFlag), opts)
 
 	w.RegisterWorkflowWithOptions(waitForEventWorkflow, workflow.RegisterOptions{
 		Name: listeners.WaitForEventWorkflow,
 	})
-	bitbucket.Register(ctx, cmd, w)
-	github.Register(ctx, cmd, w)
-	jira.Register(ctx, cmd, w)
-	slack.Register(ctx, cmd, w)
+	dedicated, err := registerProviders(ctx, cmd, w, func(q string) worker.Worker {
+		return worker.New(c, q, opts)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dw := range dedicated {
+		if err := dw.Start(); err != nil {
+			return fmt.Errorf("failed to start Temporal worker of dedicated task queue: %w", err)
+		}
+		defer dw.Stop()
+	}
 
 	if err := w.Run(worker.InterruptCh()); err != nil {
 		return fmt.Errorf("failed to start Temporal worker: %w", err)
@@ -72,7 +91,8 @@ func Run(ctx context.Context, cmd *cli.Command, bi *debug.BuildInfo) error {
 }
 
 // waitForEventWorkflow is a generic Temporal workflow that waits for a specific [Signal]
-// call from an event listener. Timeouts are optional. This workflow supports cancellation.
+// call from an event listener. Timeouts and deadlines are optional. This workflow supports cancellation.
+// Signals which don't match the request's optional filter are ignored.
 func waitForEventWorkflow(ctx workflow.Context, req listeners.WaitForEventRequest) (map[string]any, error) {
 	childCtx, cancel := workflow.WithCancel(ctx)
 	defer cancel()
@@ -82,17 +102,23 @@ func waitForEventWorkflow(ctx workflow.Context, req listeners.WaitForEventReques
 	l := workflow.GetLogger(ctx)
 	startTime := time.Now()
 
+	received := false
 	selector := workflow.NewSelector(childCtx)
 	selector.AddReceive(ch, func(c workflow.ReceiveChannel, _ bool) {
+		payload = make(map[string]any)
 		c.Receive(ctx, &payload)
-		l.Debug("received signal", slog.String("signal", req.Signal),
-			slog.String("duration", time.Since(startTime).String()))
+		if received = req.Matches(payload); !received {
+			l.Debug("ignoring signal which doesn't match filter", slog.String("signal", req.Signal))
+			return
+		}
+		attrs := []any{slog.String("signal", req.Signal), slog.String("duration", time.Since(startTime).String())}
+		if t, ok := ReceivedAt(payload); ok {
+			attrs = append(attrs, slog.String("since_receipt", workflow.Now(ctx).Sub(t).String()))
+		}
+		l.Debug("received signal", attrs...)
 	})
 
-	if req.Timeout == "" {
-		req.Timeout = "0s"
-	}
-	timeout, err := time.ParseDuration(req.Timeout)
+	timeout, err := waitDuration(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -101,14 +127,18 @@ func waitForEventWorkflow(ctx workflow.Context, req listeners.WaitForEventReques
 	if timeout == 0 {
 		l.Debug("waiting for signal without timeout", slog.String("signal", req.Signal))
 	} else {
-		l.Debug("waiting for signal", slog.String("signal", req.Signal), slog.String("timeout", req.Timeout))
+		l.Debug("waiting for signal", slog.String("signal", req.Signal), slog.String("timeout", timeout.String()))
 
 		// Using a selector instead of ch.ReceiveWithTimeout() to support workflow cancellation.
 		timer = workflow.NewTimer(ctx, timeout)
 		selector.AddFuture(timer, func(_ workflow.Future) {
 			l.Debug("timeout while waiting for signal", slog.String("signal", req.Signal),
-				slog.String("timeout", req.Timeout))
-			err = fmt.Errorf("timeout (%s)", req.Timeout)
+				slog.String("timeout", timeout.String()))
+			if req.Deadline != "" {
+				err = fmt.Errorf("deadline (%s)", req.Deadline)
+			} else {
+				err = fmt.Errorf("timeout (%s)", req.Timeout)
+			}
 		})
 	}
 
@@ -116,7 +146,9 @@ func waitForEventWorkflow(ctx workflow.Context, req listeners.WaitForEventReques
 		l.Error("workflow canceled while waiting for signal", "error", childCtx.Err(), "signal", req.Signal)
 	})
 
-	selector.Select(ctx)
+	for !received && err == nil && childCtx.Err() == nil {
+		selector.Select(ctx)
+	}
 
 	switch {
 	case childCtx.Err() != nil:
@@ -128,21 +160,68 @@ func waitForEventWorkflow(ctx workflow.Context, req listeners.WaitForEventReques
 	}
 }
 
+// waitDuration converts the request's timeout or deadline into a duration (0 = no timeout).
+// Deadlines are relative to [workflow.Now], to ensure deterministic workflow replays.
+func waitDuration(ctx workflow.Context, req listeners.WaitForEventRequest) (time.Duration, error) {
+	if req.Deadline == "" {
+		if req.Timeout == "" {
+			return 0, nil
+		}
+		return time.ParseDuration(req.Timeout)
+	}
+
+	if req.Timeout != "" {
+		msg := "timeout and deadline are mutually exclusive"
+		return 0, temporal.NewNonRetryableApplicationError(msg, "InvalidDeadlineError", nil, req.Timeout, req.Deadline)
+	}
+
+	deadline, err := time.Parse(time.RFC3339, req.Deadline)
+	if err != nil {
+		return 0, temporal.NewNonRetryableApplicationError("invalid deadline: "+err.Error(), "InvalidDeadlineError", err, req.Deadline)
+	}
+
+	d := deadline.Sub(workflow.Now(ctx))
+	if d <= 0 {
+		return 0, temporal.NewNonRetryableApplicationError("deadline already passed", "InvalidDeadlineError", nil, req.Deadline)
+	}
+	return d, nil
+}
+
 // Signal sends a specific payload, which was received as an asynchronous event
 // notification, to all (zero of more) Temporal workflows that are waiting for it.
+// The payload is compressed if the configuration enables it, and if it's still too
+// large, its known bulky fields are truncated (see [TruncatedKey]). The outcome of
+// each call is recorded as a metric (see [otel.RecordSignalDispatch]).
 //
-// The ctx parameter is expected to have a ZeroLog logger attached to it:
+// The ctx parameter is expected to have the caller's [slog.Logger] attached
+// to it, so that this function's log messages include the caller's fields:
 //
-//	ctx = l.WithContext(ctx)
+//	ctx = logger.WithContext(ctx, l)
 func Signal(ctx context.Context, cfg listeners.TemporalConfig, name string, payload map[string]any) error {
 	l := logger.FromContext(ctx)
+	t := time.Now().UTC()
+	var matched, sent int
+	errClass := ""
+	defer func() {
+		recordSignalDispatch(t, name, matched, sent, errClass, time.Since(t))
+	}()
 
-	c, err := client.Dial(client.Options{
-		HostPort:  cfg.HostPort,
-		Namespace: cfg.Namespace,
-		Logger:    log.NewStructuredLogger(l),
+	if err := validateSignalName(name); err != nil {
+		name = ForbiddenSignalNameChars.ReplaceAllString(name, "_")
+		errClass = SignalErrorInvalidName
+		return err
+	}
+	name = sanitizeSignalName(l, name)
+
+	dc := signalDataConverter(cfg.CompressSignals)
+	c, err := dialClient(client.Options{
+		HostPort:      cfg.HostPort,
+		Namespace:     cfg.Namespace,
+		Logger:        log.NewStructuredLogger(l),
+		DataConverter: dc,
 	})
 	if err != nil {
+		errClass = SignalErrorDial
 		return fmt.Errorf("client dial error: %w", err)
 	}
 	defer c.Close()
@@ -150,40 +229,181 @@ func Signal(ctx context.Context, cfg listeners.TemporalConfig, name string, payl
 	// https://docs.temporal.io/list-filter
 	// https://docs.temporal.io/search-attribute
 	// https://docs.temporal.io/develop/go/observability#visibility
-	name = sanitizeSignalName(l, name)
 	list, err := c.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
 		Query: fmt.Sprintf("WaitingForSignals IN ('%s') AND ExecutionStatus = '%s'", name, "Running"),
 	})
 	if err != nil {
+		errClass = SignalErrorSearch
 		return fmt.Errorf("workflow search error: %w", err)
 	}
 
+	matched = len(list.GetExecutions())
+	if matched == 0 {
+		return nil
+	}
+	if payload, err = limitPayloadSize(l, dc, payload, cfg.MaxSignalSize); err != nil {
+		errClass = SignalErrorPayload
+		return fmt.Errorf("signal %q: %w", name, err)
+	}
+
 	for _, info := range list.GetExecutions() {
 		wid, rid := info.GetExecution().GetWorkflowId(), info.GetExecution().GetRunId()
 		l.Info("sending signal to Temporal workflow", slog.String("signal", name),
 			slog.String("workflow_id", wid), slog.String("run_id", rid))
 		if err := c.SignalWorkflow(ctx, wid, rid, name, payload); err != nil {
+			errClass = signalErrorClass(err)
 			return fmt.Errorf("signaling error: %w", err)
 		}
+		sent++
 	}
 
 	return nil
 }
 
+// Error classes of [Signal] dispatch metrics, see [otel.RecordSignalDispatch].
+const (
+	SignalErrorInvalidName = "invalid_name"
+	SignalErrorDial        = "dial"
+	SignalErrorSearch      = "search"
+	SignalErrorPayload     = "payload"
+	SignalErrorNotFound    = "not_found" // The workflow completed after the search.
+	SignalErrorSend        = "send"
+)
+
+var (
+	// dialClient is a variable only to facilitate testing with a fake Temporal client.
+	dialClient = client.Dial
+	// recordSignalDispatch is a variable only to facilitate testing with a fake metrics sink.
+	recordSignalDispatch = otel.RecordSignalDispatch
+)
+
+// signalErrorClass classifies errors of [client.Client.SignalWorkflow].
+func signalErrorClass(err error) string {
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return SignalErrorNotFound
+	}
+	return SignalErrorSend
+}
+
+// validateSignalName rejects signal names with an empty segment (e.g. "github.events."
+// if an event type header is missing), which would otherwise be sanitized into
+// ambiguous names and mis-route events, instead of being rejected.
+func validateSignalName(name string) error {
+	if slices.Contains(strings.Split(name, "."), "") {
+		return fmt.Errorf("invalid signal name %q: empty segment", name)
+	}
+	return nil
+}
+
 var ForbiddenSignalNameChars = regexp.MustCompile("[^0-9A-Za-z_.]")
 
+const (
+	// OriginalEventKey is added by listeners to signal payloads, with the original event type
+	// (e.g. "pull_request" or "pr:opened"), which may be mangled in the signal name by
+	// [Signal] if it contains forbidden characters or is too long.
+	OriginalEventKey = "timpani_original_event"
+
+	maxSignalNameLength = 100
+	signalNameHashLen   = 8 // Hex digits.
+
+	// maxSanitizedNames bounds the memory of [sanitizeSignalName]'s collision detection.
+	maxSanitizedNames = 1000
+)
+
+// WithOriginalEvent adds the original event type to a signal payload (which may be
+// nil), under [OriginalEventKey], so workflows don't depend on the signal name.
+func WithOriginalEvent(payload map[string]any, eventType string) map[string]any {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	payload[OriginalEventKey] = eventType
+	return payload
+}
+
+const (
+	// ReceivedAtKey is added by listeners to signal payloads, with the time that Timpani
+	// received the event (RFC 3339 in UTC, with milliseconds), so workflows can measure
+	// end-to-end latency, e.g. from a GitHub push to a Slack notification.
+	ReceivedAtKey = "timpani_received_at"
+	// ListenerKey is added by listeners to signal payloads, with the type of
+	// the listener that received the event: [ListenerWebhook] or [ListenerWebSocket].
+	ListenerKey = "timpani_listener"
+
+	ListenerWebhook   = "webhook"
+	ListenerWebSocket = "websocket"
+
+	receivedAtFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// WithReceipt adds the receipt time of an event and the type of the listener that received
+// it to a signal payload (which may be nil), under [ReceivedAtKey] and [ListenerKey].
+func WithReceipt(payload map[string]any, receivedAt time.Time, listener string) map[string]any {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	payload[ReceivedAtKey] = receivedAt.UTC().Format(receivedAtFormat)
+	payload[ListenerKey] = listener
+	return payload
+}
+
+// ReceivedAt parses the receipt time of an event in a signal payload, see [WithReceipt].
+func ReceivedAt(payload map[string]any) (time.Time, bool) {
+	s, ok := payload[ReceivedAtKey].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// sanitizedNames maps sanitized signal names to their original names,
+// only if they're different, to detect collisions in [sanitizeSignalName].
+var (
+	sanitizedNames   = map[string]string{}
+	sanitizedNamesMu sync.Mutex
+)
+
 // sanitizeSignalName ensures that signal names (generated from incoming events)
-// cannot manipulate Timpani's Temporal query in the [Signal] function.
+// cannot manipulate Timpani's Temporal query in the [Signal] function. Names which
+// are too long are truncated with a short hash suffix of the original name, so
+// different names with the same prefix don't collide. Other collisions, e.g.
+// "a-b" and "a+b" both becoming "a_b", are logged. Either way, listeners also
+// preserve the original event type in the payload (see [OriginalEventKey]).
 func sanitizeSignalName(l *slog.Logger, name string) string {
 	safeName := ForbiddenSignalNameChars.ReplaceAllString(name, "_")
-	if len(safeName) > 100 {
-		safeName = safeName[:100]
+	if len(safeName) > maxSignalNameLength {
+		h := sha256.Sum256([]byte(name))
+		suffix := hex.EncodeToString(h[:])[:signalNameHashLen]
+		safeName = safeName[:maxSignalNameLength-signalNameHashLen-1] + "_" + suffix
 	}
 
 	if name != safeName {
-		l.Warn("signal name contained forbidden characters",
+		l.Warn("signal name contained forbidden characters or was too long",
 			slog.String("original", name), slog.String("sanitized", safeName))
+		detectCollision(l, name, safeName)
 	}
 
 	return safeName
 }
+
+// detectCollision logs an error if different original
+// signal names were sanitized into the same name.
+func detectCollision(l *slog.Logger, name, safeName string) {
+	sanitizedNamesMu.Lock()
+	defer sanitizedNamesMu.Unlock()
+
+	prev, found := sanitizedNames[safeName]
+	if !found {
+		if len(sanitizedNames) < maxSanitizedNames {
+			sanitizedNames[safeName] = name
+		}
+		return
+	}
+
+	if prev != name {
+		l.Error("signal name collision after sanitization", slog.String("sanitized", safeName),
+			slog.String("original", name), slog.String("previous_original", prev))
+	}
+}
@@ -1,8 +1,23 @@
 package temporal
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tzrikka/timpani/internal/listeners"
 )
 
 func TestSanitizeSignalName(t *testing.T) {
@@ -29,7 +44,12 @@ func TestSanitizeSignalName(t *testing.T) {
 		{
 			name:   "long",
 			signal: "name_with_very_long_length_exceeding_one_hundred_characters_to_test_the_sanitization_functionality_which_should_truncate_it",
-			want:   "name_with_very_long_length_exceeding_one_hundred_characters_to_test_the_sanitization_functionality_w",
+			want:   "name_with_very_long_length_exceeding_one_hundred_characters_to_test_the_sanitization_functi_e8b1d4f9",
+		},
+		{
+			name:   "long_with_same_prefix",
+			signal: "name_with_very_long_length_exceeding_one_hundred_characters_to_test_the_sanitization_functionality_which_should_truncate_it_too",
+			want:   "name_with_very_long_length_exceeding_one_hundred_characters_to_test_the_sanitization_functi_64fa5320",
 		},
 	}
 
@@ -41,3 +61,374 @@ func TestSanitizeSignalName(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizeSignalNameCollision(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := slog.New(slog.NewTextHandler(buf, nil))
+
+	if got := sanitizeSignalName(l, "collision.a-b"); got != "collision.a_b" {
+		t.Fatalf("sanitizeSignalName() = %q, want %q", got, "collision.a_b")
+	}
+	if strings.Contains(buf.String(), "collision after sanitization") {
+		t.Fatalf("unexpected collision log: %s", buf)
+	}
+
+	_ = sanitizeSignalName(l, "collision.a-b") // Same original name.
+	if strings.Contains(buf.String(), "collision after sanitization") {
+		t.Fatalf("unexpected collision log: %s", buf)
+	}
+
+	_ = sanitizeSignalName(l, "collision.a+b")
+	if !strings.Contains(buf.String(), "collision after sanitization") {
+		t.Errorf("missing collision log: %s", buf)
+	}
+}
+
+func TestWithOriginalEvent(t *testing.T) {
+	if got := WithOriginalEvent(nil, "pr:opened"); got[OriginalEventKey] != "pr:opened" {
+		t.Errorf("WithOriginalEvent(nil) = %v", got)
+	}
+
+	payload := map[string]any{"foo": "bar"}
+	got := WithOriginalEvent(payload, "push")
+	if got["foo"] != "bar" || got[OriginalEventKey] != "push" {
+		t.Errorf("WithOriginalEvent() = %v", got)
+	}
+}
+
+func TestWithReceipt(t *testing.T) {
+	received := time.Date(2026, 1, 2, 3, 4, 5, 678901234, time.FixedZone("IST", 2*60*60))
+	got := WithReceipt(map[string]any{"foo": "bar"}, received, ListenerWebhook)
+
+	if got["foo"] != "bar" || got[ListenerKey] != ListenerWebhook {
+		t.Errorf("WithReceipt() = %v", got)
+	}
+	if want := "2026-01-02T01:04:05.678Z"; got[ReceivedAtKey] != want {
+		t.Errorf("WithReceipt() %s = %v, want %q", ReceivedAtKey, got[ReceivedAtKey], want)
+	}
+
+	parsed, ok := ReceivedAt(got)
+	if !ok {
+		t.Fatalf("ReceivedAt() = %v, want ok", ok)
+	}
+	if want := received.Truncate(time.Millisecond); !parsed.Equal(want) {
+		t.Errorf("ReceivedAt() = %v, want %v", parsed, want)
+	}
+
+	if _, ok := ReceivedAt(WithReceipt(nil, received, ListenerWebSocket)); !ok {
+		t.Error("ReceivedAt(WithReceipt(nil)) = false, want true")
+	}
+	if _, ok := ReceivedAt(map[string]any{ReceivedAtKey: "yesterday"}); ok {
+		t.Error("ReceivedAt(invalid) = true, want false")
+	}
+}
+
+func TestValidateSignalName(t *testing.T) {
+	tests := []struct {
+		name    string
+		signal  string
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+		{
+			name:   "valid",
+			signal: "github.events.push",
+		},
+		{
+			name:    "missing_event_type",
+			signal:  "github.events.",
+			wantErr: true,
+		},
+		{
+			name:    "empty_middle_segment",
+			signal:  "slack..events.message",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSignalName(tt.signal); (err != nil) != tt.wantErr {
+				t.Errorf("validateSignalName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWaitForEventWorkflowFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no_filter",
+			want: "link1",
+		},
+		{
+			name:   "matching_second_signal",
+			filter: map[string]string{"timpani_link_id": "link2"},
+			want:   "link2",
+		},
+		{
+			name:   "matching_nested_key",
+			filter: map[string]string{"link.id": "link2"},
+			want:   "link2",
+		},
+		{
+			name:    "no_matching_signal",
+			filter:  map[string]string{"timpani_link_id": "link3"},
+			wantErr: true,
+		},
+		{
+			name:    "no_matching_nested_key",
+			filter:  map[string]string{"timpani_link_id.id": "link2"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.RegisterWorkflow(waitForEventWorkflow)
+			for i, id := range []string{"link1", "link2"} {
+				env.RegisterDelayedCallback(func() {
+					env.SignalWorkflow("signal", map[string]any{"timpani_link_id": id, "link": map[string]any{"id": id}})
+				}, time.Duration(i+1)*time.Second)
+			}
+
+			req := listeners.WaitForEventRequest{Signal: "signal", Timeout: "1m", Filter: tt.filter}
+			env.ExecuteWorkflow(waitForEventWorkflow, req)
+
+			err := env.GetWorkflowError()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("waitForEventWorkflow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := map[string]any{}
+			if err := env.GetWorkflowResult(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got["timpani_link_id"] != tt.want {
+				t.Errorf("waitForEventWorkflow() = %v, want link ID %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForEventWorkflowDeadline(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		timeout       string
+		deadline      string
+		signalAfter   time.Duration
+		wantErr       bool
+		wantRetryable bool
+		wantDuration  time.Duration
+	}{
+		{
+			name:         "signal_before_deadline",
+			deadline:     "2026-01-01T13:00:00Z",
+			signalAfter:  30 * time.Minute,
+			wantDuration: 30 * time.Minute,
+		},
+		{
+			name:          "signal_after_deadline",
+			deadline:      "2026-01-01T13:00:00Z",
+			signalAfter:   2 * time.Hour,
+			wantErr:       true,
+			wantRetryable: true,
+			wantDuration:  time.Hour,
+		},
+		{
+			name:     "deadline_at_start_time",
+			deadline: "2026-01-01T14:00:00+02:00", // = 12:00 UTC.
+			wantErr:  true,
+		},
+		{
+			name:     "past_deadline",
+			deadline: "2026-01-01T11:59:59Z",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid_deadline",
+			deadline: "tomorrow",
+			wantErr:  true,
+		},
+		{
+			name:     "timeout_and_deadline",
+			timeout:  "1h",
+			deadline: "2026-01-01T13:00:00Z",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := new(testsuite.WorkflowTestSuite).NewTestWorkflowEnvironment()
+			env.SetStartTime(start)
+			env.RegisterWorkflow(waitForEventWorkflow)
+			if tt.signalAfter > 0 {
+				env.RegisterDelayedCallback(func() {
+					env.SignalWorkflow("signal", map[string]any{"foo": "bar"})
+				}, tt.signalAfter)
+			}
+
+			req := listeners.WaitForEventRequest{Signal: "signal", Timeout: tt.timeout, Deadline: tt.deadline}
+			env.ExecuteWorkflow(waitForEventWorkflow, req)
+
+			err := env.GetWorkflowError()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("waitForEventWorkflow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var appErr *temporal.ApplicationError
+				if errors.As(err, &appErr) && appErr.NonRetryable() == tt.wantRetryable {
+					t.Errorf("waitForEventWorkflow() error = %v, want retryable = %v", err, tt.wantRetryable)
+				}
+			}
+
+			// The timer depends only on the workflow's clock, not the wall clock.
+			if got := env.Now().Sub(start); got != tt.wantDuration {
+				t.Errorf("waitForEventWorkflow() duration = %v, want %v", got, tt.wantDuration)
+			}
+		})
+	}
+}
+
+// fakeClient is a Temporal client with predefined workflow executions
+// which are waiting for signals, and predefined signaling errors.
+type fakeClient struct {
+	client.Client
+
+	executions []string // Workflow IDs.
+	listErr    error
+	signalErrs map[string]error // Workflow ID -> error.
+}
+
+func (c *fakeClient) ListWorkflow(_ context.Context, _ *workflowservice.ListWorkflowExecutionsRequest) (*workflowservice.ListWorkflowExecutionsResponse, error) {
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+
+	resp := new(workflowservice.ListWorkflowExecutionsResponse)
+	for _, id := range c.executions {
+		resp.Executions = append(resp.Executions, &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: id, RunId: "run"},
+		})
+	}
+	return resp, nil
+}
+
+func (c *fakeClient) SignalWorkflow(_ context.Context, workflowID, _, _ string, _ any) error {
+	return c.signalErrs[workflowID]
+}
+
+func (c *fakeClient) Close() {}
+
+// signalDispatch is a record of the fake metrics sink in [TestSignalMetrics].
+type signalDispatch struct {
+	signal        string
+	matched, sent int
+	errClass      string
+}
+
+func TestSignalMetrics(t *testing.T) {
+	origDial, origRecord := dialClient, recordSignalDispatch
+	t.Cleanup(func() { dialClient, recordSignalDispatch = origDial, origRecord })
+
+	tests := []struct {
+		name    string
+		signal  string
+		client  *fakeClient
+		dialErr error
+		want    signalDispatch
+		wantErr bool
+	}{
+		{
+			name:    "invalid_name",
+			signal:  "github.events.",
+			want:    signalDispatch{signal: "github.events.", errClass: SignalErrorInvalidName},
+			wantErr: true,
+		},
+		{
+			name:    "dial_error",
+			signal:  "slack.events.app_mention",
+			dialErr: errors.New("connection refused"),
+			want:    signalDispatch{signal: "slack.events.app_mention", errClass: SignalErrorDial},
+			wantErr: true,
+		},
+		{
+			name:    "search_error",
+			signal:  "slack.events.app_mention",
+			client:  &fakeClient{listErr: errors.New("unavailable")},
+			want:    signalDispatch{signal: "slack.events.app_mention", errClass: SignalErrorSearch},
+			wantErr: true,
+		},
+		{
+			name:   "no_matches",
+			signal: "bitbucket.events.pr:opened",
+			client: &fakeClient{},
+			want:   signalDispatch{signal: "bitbucket.events.pr_opened"},
+		},
+		{
+			name:   "all_sent",
+			signal: "slack.events.app_mention",
+			client: &fakeClient{executions: []string{"w1", "w2"}},
+			want:   signalDispatch{signal: "slack.events.app_mention", matched: 2, sent: 2},
+		},
+		{
+			name:   "workflow_completed",
+			signal: "slack.events.app_mention",
+			client: &fakeClient{executions: []string{"w1", "w2"}, signalErrs: map[string]error{
+				"w2": serviceerror.NewNotFound("workflow execution already completed"),
+			}},
+			want:    signalDispatch{signal: "slack.events.app_mention", matched: 2, sent: 1, errClass: SignalErrorNotFound},
+			wantErr: true,
+		},
+		{
+			name:   "send_error",
+			signal: "slack.events.app_mention",
+			client: &fakeClient{executions: []string{"w1"}, signalErrs: map[string]error{
+				"w1": errors.New("deadline exceeded"),
+			}},
+			want:    signalDispatch{signal: "slack.events.app_mention", matched: 1, errClass: SignalErrorSend},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialClient = func(client.Options) (client.Client, error) {
+				if tt.dialErr != nil {
+					return nil, tt.dialErr
+				}
+				return tt.client, nil
+			}
+
+			var got []signalDispatch
+			recordSignalDispatch = func(_ time.Time, signal string, matched, sent int, errClass string, latency time.Duration) {
+				if latency < 0 {
+					t.Errorf("recordSignalDispatch() latency = %v", latency)
+				}
+				got = append(got, signalDispatch{signal: signal, matched: matched, sent: sent, errClass: errClass})
+			}
+
+			err := Signal(t.Context(), listeners.TemporalConfig{}, tt.signal, map[string]any{"a": "b"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Signal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("recordSignalDispatch() = %+v, want [%+v]", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,38 +1,167 @@
 package websocket
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math/big"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/redact"
 )
 
 var clients = sync.Map{}
 
+// ErrUnrecoverable should be wrapped by errors of URL functions which retrying won't
+// fix (e.g. revoked credentials). When a [Client] gets such an error while replacing
+// its connection, it stops retrying, and closes its channel of incoming messages.
+var ErrUnrecoverable = errors.New("unrecoverable error")
+
+// ErrClientClosed is the reason that a [Client] closed its channel
+// of incoming messages after a call to [Client.Shutdown], see [Client.Err].
+var ErrClientClosed = errors.New("WebSocket client closed")
+
 // Client is a long-running wrapper of connections to the same WebSocket
 // server with the same credentials. It usually manages a single [Conn],
 // except when it gets disconnected, or is about to be, in which case the
 // client automatically opens another [Conn] and switches to it seamlessly,
 // to prevent or at least minimize downtime during reconnections.
+//
+// Delivery semantics: messages of each [Conn] are always relayed in the order
+// they were received (FIFO). When the client refreshes its connection, it drains
+// the old one before relaying any message from the new one, so subscribers never
+// observe messages from both connections interleaved. Meanwhile, the client buffers
+// the new connection's messages, so it keeps reading them without delay. Draining
+// is bounded by [refreshDrainTimeout]: if the old connection doesn't end by then,
+// the client switches anyway, and discards the old connection's remaining messages.
+// Note that the server may also drop messages which it didn't send before the refresh,
+// or send the same messages through both connections: see [WithMessageKey].
+//
+// Request/response protocols are supported by [Client.Request]: messages which
+// match a pending request are delivered only to it, instead of to subscribers.
+//
+// Fan-out is supported by [Client.Subscribe]: each message is delivered to all
+// of the client's subscribers. [Client.IncomingMessages] publishes messages
+// only while there are no subscribers, for backward compatibility.
+//
+// Clients run until they encounter an [ErrUnrecoverable], or until they're shut down,
+// either explicitly with [Client.Shutdown] or [Client.Close], or by canceling the context
+// that was passed to [NewOrCachedClient].
 type Client struct {
+	id     string // Hashed.
 	logger *slog.Logger
 	url    urlFunc
 	opts   []DialOpt
 
-	conns   [2]*Conn
+	conns   [2]*Conn // Guarded by connsMu, except for reads in the message relay goroutine.
 	inMsgs  <-chan Message
 	outMsgs chan Message
 
-	refresh *time.Timer
+	refresh   *time.Timer
+	refreshMu sync.Mutex
+
+	draining     chan struct{} // Signals that conns[1] is ready, and conns[0] should be drained.
+	drainTimeout time.Duration
+	failing      chan *Conn // Signals that the application failed this connection, see [Client.FailConnection].
+
+	waiters   []*waiter // Pending calls to [Client.Request], in the order they were made.
+	waitersMu sync.Mutex
+
+	subscribers       []*subscriber // See [Client.Subscribe], nil after the client shuts down.
+	subscribed        chan struct{} // Signals that the first subscriber was added, while relaying to outMsgs.
+	subscribersClosed bool          // True after the client shuts down.
+	subscribersMu     sync.RWMutex
+
+	closeStatus atomic.Pointer[closeStatus] // Of the last replaced connection, see [Client.CloseStatus].
+
+	backoff    time.Duration // Zero = [DefaultReconnectBackoff], see [WithReconnectBackoff].
+	maxBackoff time.Duration // Zero = [DefaultMaxReconnectBackoff].
+
+	messageKey func(Message) string // See [WithMessageKey], used only by the message relay goroutine.
+	seenKeys   *recentKeys          // Nil if messageKey is nil.
+
+	pastStats  Stats // Of all the replaced connections, see [Client.Status].
+	reconnects int
+	connsMu    sync.RWMutex // Guards conns, pastStats, and reconnects.
+
+	cancel     context.CancelCauseFunc // Of the message relay goroutine, see [Client.Shutdown].
+	stopOnce   sync.Once
+	stopStatus atomic.Uint32 // Of the closing handshakes, see [Client.Close] (0 = [StatusGoingAway]).
+	discarding chan struct{} // Closed by [Client.Close], to stop relaying messages.
+	done       chan struct{} // Closed after outMsgs.
+
+	err error // Reason for closing outMsgs.
+}
+
+// waiter is a pending call to [Client.Request].
+type waiter struct {
+	key   string
+	match func(Message) (string, bool)
+	resp  chan Message // Buffered, closed if the client shuts down before a response arrives.
+}
+
+// subscriber is a channel returned by [Client.Subscribe].
+type subscriber struct {
+	msgs chan Message
+	done chan struct{} // Closed by the unsubscribe function, to stop sending to msgs.
 }
 
 type urlFunc func(ctx context.Context) (string, error)
 
+// refreshDrainTimeout is how long a [Client] waits for its old [Conn] to end during
+// a refresh, before switching to the new one. It exceeds [closeGracePeriod], so under
+// normal circumstances the old connection's closing handshake completes before it.
+const refreshDrainTimeout = closeGracePeriod + time.Second
+
+const (
+	// DefaultReconnectBackoff is the initial delay between failed attempts of a [Client]
+	// to replace its connection, unless callers override it with [WithReconnectBackoff].
+	DefaultReconnectBackoff = 100 * time.Millisecond
+
+	// DefaultMaxReconnectBackoff is the maximum delay between failed attempts of a [Client]
+	// to replace its connection, unless callers override it with [WithReconnectBackoff].
+	DefaultMaxReconnectBackoff = 30 * time.Second
+)
+
+// WithReconnectBackoff lets callers of [NewOrCachedClient] change the exponential backoff
+// between failed attempts to replace the client's connection: the delay starts with the
+// initial duration, and doubles after each failure, up to the maximum, with a random
+// jitter of ±10%. Non-positive durations mean [DefaultReconnectBackoff] and
+// [DefaultMaxReconnectBackoff], respectively. [Dial] ignores this option.
+func WithReconnectBackoff(initial, maxBackoff time.Duration) DialOpt {
+	return func(c *Conn) {
+		c.reconnectBackoff = max(initial, 0)
+		c.maxReconnectBackoff = max(maxBackoff, 0)
+	}
+}
+
+// DefaultMessageKeyCacheSize is the number of the most recently relayed message keys
+// which a [Client] remembers, to suppress duplicate messages, see [WithMessageKey].
+const DefaultMessageKeyCacheSize = 1000
+
+// WithMessageKey lets callers of [NewOrCachedClient] suppress duplicate data messages,
+// e.g. messages which the server sends through both of the client's connections while it
+// refreshes them (see [Client.RefreshConnectionIn]). The function should return a unique
+// key of each message (e.g. an ID in its payload), or an empty string to relay it anyway.
+// The client remembers the keys of the last [DefaultMessageKeyCacheSize] messages that it
+// relayed. The function is called by the client's message relay goroutine, so it should
+// be fast and must not block. [Dial] ignores this option.
+func WithMessageKey(f func(Message) string) DialOpt {
+	return func(c *Conn) {
+		c.messageKey = f
+	}
+}
+
 func NewOrCachedClient(ctx context.Context, url urlFunc, id string, opts ...DialOpt) (*Client, error) {
 	hashedID := hash(id)
 	if client, ok := clients.Load(hashedID); ok {
@@ -43,11 +172,13 @@ func NewOrCachedClient(ctx context.Context, url urlFunc, id string, opts ...Dial
 	if err != nil {
 		return nil, err
 	}
+	c.id = hashedID
 
 	actual, loaded := clients.LoadOrStore(hashedID, c)
 	if loaded { // Stored by a different goroutine since clients.Load() above.
 		deleteClient(c)
 	} else { // Newly-stored by this goroutine, so activate its message relay.
+		ctx, c.cancel = context.WithCancelCause(ctx)
 		go c.relayMessages(ctx)
 	}
 
@@ -67,14 +198,29 @@ func newClient(ctx context.Context, f urlFunc, opts ...DialOpt) (*Client, error)
 		return nil, err
 	}
 
-	return &Client{
+	c := &Client{
 		logger:  logger.FromContext(ctx),
 		url:     f,
 		opts:    opts,
 		conns:   [2]*Conn{conn},
 		inMsgs:  conn.IncomingMessages(),
 		outMsgs: make(chan Message),
-	}, nil
+
+		draining:     make(chan struct{}, 1),
+		drainTimeout: refreshDrainTimeout,
+		failing:      make(chan *Conn, 1),
+		subscribed:   make(chan struct{}, 1),
+		discarding:   make(chan struct{}),
+		done:         make(chan struct{}),
+
+		backoff:    conn.reconnectBackoff,
+		maxBackoff: conn.maxReconnectBackoff,
+		messageKey: conn.messageKey,
+	}
+	if c.messageKey != nil {
+		c.seenKeys = newRecentKeys(DefaultMessageKeyCacheSize)
+	}
+	return c, nil
 }
 
 func newConn(ctx context.Context, f urlFunc, opts ...DialOpt) (*Conn, error) {
@@ -105,88 +251,688 @@ func deleteClient(c *Client) {
 }
 
 // relayMessages runs as a [Client] goroutine, to route data [Message]s
-// from the client's underlying [Conn] to the client's subscribers.
+// from the client's underlying [Conn] to the client's subscribers, in
+// accordance with the delivery semantics which are documented in [Client].
+//
+// During a refresh, it reads the messages of both connections: it relays the old
+// connection's messages, and buffers the new connection's messages until it switches
+// to it. Duplicate messages are suppressed in both cases (see [WithMessageKey]).
+//
+// When the context is canceled, it closes the client's connections, relays the messages
+// which they already received until the closing handshake ends (unless the client is
+// discarding them, see [Client.Close]), and then shuts down the client.
 func (c *Client) relayMessages(ctx context.Context) {
+	var deadline <-chan time.Time // Non-nil only while draining the old connection during a refresh.
+	var nextMsgs <-chan Message   // Non-nil only while buffering the new connection's messages during a refresh.
+	var nextID string             // Of the new connection, for logging.
+	var pending []Message         // Received by the new connection during a refresh, to relay after switching to it.
+	stop := ctx.Done()            // Nil after the context is canceled, to close the connections only once.
 	for {
-		if msg, ok := <-c.inMsgs; ok {
-			c.outMsgs <- msg
+		select {
+		case msg, ok := <-c.inMsgs:
+			if ok {
+				c.relay(msg)
+				continue
+			}
+		case msg, ok := <-nextMsgs:
+			if ok {
+				pending = append(pending, msg)
+			} else {
+				nextMsgs = nil // Closed before the switch, so the switch will replace it too.
+			}
+			continue
+		case <-c.draining:
+			deadline = time.After(c.drainTimeout)
+			c.connsMu.RLock()
+			if c.conns[1] != nil {
+				nextMsgs, nextID = c.conns[1].IncomingMessages(), c.conns[1].id
+			}
+			c.connsMu.RUnlock()
 			continue
+		case <-deadline:
+			c.logger.Warn("WebSocket connection not drained in time, discarding its remaining messages",
+				slog.String("conn_id", c.conns[0].id), slog.Duration("timeout", c.drainTimeout))
+			go discardMessages(c.logger, c.conns[0].id, c.inMsgs)
+		case conn := <-c.failing:
+			if conn != c.conns[0] {
+				continue // Already replaced.
+			}
+			go discardMessages(c.logger, c.conns[0].id, c.inMsgs)
+		case <-stop:
+			stop = nil
+			c.closeConns()
+			select {
+			case <-c.discarding:
+				go discardMessages(c.logger, c.conns[0].id, c.inMsgs)
+			default:
+				continue
+			}
+		}
+
+		deadline = nil
+		if ctx.Err() != nil {
+			c.relayPending(pending, nextMsgs, nextID)
+			c.shutdown(context.Cause(ctx))
+			return
+		}
+		err := c.replaceConn(ctx)
+		if nextMsgs != nil && c.inMsgs != nextMsgs { // Replaced by another refresh in the meantime.
+			go discardMessages(c.logger, nextID, nextMsgs)
+		}
+		nextMsgs = nil
+		for _, msg := range pending {
+			c.relay(msg)
+		}
+		pending = nil
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+	}
+}
+
+// relay delivers a data [Message] to the pending [Client.Request] that it matches, or to the
+// subscribers of the [Client], unless it duplicates a recently-relayed message (see [WithMessageKey]).
+func (c *Client) relay(msg Message) {
+	if c.messageKey != nil {
+		if key := c.messageKey(msg); key != "" && !c.seenKeys.add(key) {
+			c.logger.Debug("suppressing duplicate WebSocket data message", slog.String("opcode", msg.Opcode.String()),
+				slog.Int("length", len(msg.Data)))
+			return
+		}
+	}
+
+	if !c.deliverResponse(msg) {
+		c.publish(msg)
+	}
+}
+
+// relayPending relays the messages which the [Client] buffered during a refresh, before
+// it shuts down, unless it's discarding them (see [Client.Close]). This includes the new
+// connection's remaining messages, because it's already closing too.
+func (c *Client) relayPending(pending []Message, nextMsgs <-chan Message, nextID string) {
+	select {
+	case <-c.discarding:
+		if nextMsgs != nil {
+			go discardMessages(c.logger, nextID, nextMsgs)
 		}
+		return
+	default:
+	}
+
+	for _, msg := range pending {
+		c.relay(msg)
+	}
+	if nextMsgs != nil {
+		for msg := range nextMsgs {
+			c.relay(msg)
+		}
+	}
+}
+
+// recentKeys is a bounded set of the most recently added message keys, see [WithMessageKey].
+// When it's full, adding a new key evicts the oldest one. It's not safe for concurrent use.
+type recentKeys struct {
+	keys map[string]struct{}
+	ring []string
+	next int
+}
 
-		c.replaceConn(ctx)
+func newRecentKeys(size int) *recentKeys {
+	return &recentKeys{keys: make(map[string]struct{}, size), ring: make([]string, size)}
+}
+
+// add reports whether the given key is new, and if so, adds it to the set.
+func (r *recentKeys) add(key string) bool {
+	if _, ok := r.keys[key]; ok {
+		return false
+	}
+
+	if old := r.ring[r.next]; old != "" {
+		delete(r.keys, old)
+	}
+	r.ring[r.next] = key
+	r.keys[key] = struct{}{}
+	r.next = (r.next + 1) % len(r.ring)
+	return true
+}
+
+// discardMessages drains the channel of a [Conn] which the [Client]
+// switched away from, to let the connection's read goroutine end.
+func discardMessages(l *slog.Logger, connID string, msgs <-chan Message) {
+	for msg := range msgs {
+		l.Warn("discarding WebSocket data message received after connection refresh or closure", slog.String("conn_id", connID),
+			slog.String("opcode", msg.Opcode.String()), slog.Int("length", len(msg.Data)))
 	}
 }
 
 // replaceConn either creates a new [Conn] (if the existing one is
 // closing/closed), or switches seamlessly to a secondary one which
 // was created by the timer-based goroutine in [RefreshConnectionIn].
-func (c *Client) replaceConn(ctx context.Context) {
+// It retries endlessly with an exponential backoff (which starts over
+// in each call, see [WithReconnectBackoff]), unless it encounters an
+// [ErrUnrecoverable], or the context is canceled.
+func (c *Client) replaceConn(ctx context.Context) error {
 	defer func() {
 		c.inMsgs = c.conns[0].IncomingMessages()
 	}()
 
+	old := c.conns[0].id
+	c.recordCloseStatus(c.conns[0])
+
 	// Switch to a fresh secondary connection.
-	if c.conns[1] != nil {
-		c.conns[0] = c.conns[1]
-		c.conns[1] = nil
-		return
+	c.connsMu.RLock()
+	next := c.conns[1]
+	c.connsMu.RUnlock()
+	if next != nil {
+		c.logger.Debug("switching to secondary WebSocket connection",
+			slog.String("old_conn_id", old), slog.String("new_conn_id", next.id))
+		c.switchConn(next)
+		return nil
 	}
 
 	// Create a new connection, with endless retries.
-	i := 0
-	for {
+	backoff := cmp.Or(c.backoff, DefaultReconnectBackoff)
+	maxBackoff := max(cmp.Or(c.maxBackoff, DefaultMaxReconnectBackoff), backoff)
+	for i := 0; ; i++ {
 		conn, err := c.newConn(ctx, c.url, c.opts...)
 		if err == nil {
-			c.conns[0] = conn
-			break
+			c.logger.Debug("replaced WebSocket connection", slog.String("old_conn_id", old), slog.String("new_conn_id", conn.id))
+			if c.switchConn(conn) {
+				// A refresh finished dialing its own connection in the meantime, and
+				// closed the previous one: drain this one too, and then switch again.
+				conn.Close(StatusGoingAway)
+			}
+			return nil
+		}
+
+		if errors.Is(err, ErrUnrecoverable) {
+			c.logger.Error("failed to replace WebSocket connection", slog.Any("error", redact.Error(err)),
+				slog.String("old_conn_id", old), slog.Int("retry", i))
+			return err
+		}
+
+		d := jitter(backoff)
+		c.logger.Error("failed to replace WebSocket connection", slog.Any("error", redact.Error(err)),
+			slog.String("old_conn_id", old), slog.Int("retry", i), slog.Duration("backoff", d))
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// jitter returns a random duration within ±10% of d, so that multiple
+// clients which were disconnected at the same time don't retry in sync.
+func jitter(d time.Duration) time.Duration {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(d/5)+1))
+	if err != nil {
+		return d
+	}
+	return d - d/10 + time.Duration(n.Int64())
+}
+
+// sleep waits for the given duration, unless the context is canceled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// recordCloseStatus stores the close status of a [Conn] which the [Client] is replacing,
+// for [Client.CloseStatus], unless it hasn't finished its closing handshake yet (e.g.
+// it wasn't drained in time during a refresh, or the server didn't respond to a failure).
+func (c *Client) recordCloseStatus(conn *Conn) {
+	status, reason, ok := conn.CloseStatus()
+	if !ok {
+		return
+	}
+
+	c.logger.Debug("WebSocket connection closed", slog.String("conn_id", conn.id),
+		slog.String("close_status", status.String()), slog.String("close_reason", reason))
+	c.closeStatus.Store(&closeStatus{status: status, reason: reason})
+}
+
+// switchConn replaces the [Client]'s current [Conn], and adds the traffic
+// counters of the replaced connection to the client's lifetime totals.
+// It reports whether a different secondary connection is pending.
+func (c *Client) switchConn(conn *Conn) bool {
+	c.connsMu.Lock()
+	defer c.connsMu.Unlock()
+
+	c.pastStats = c.pastStats.add(c.conns[0].Stats())
+	c.reconnects++
+	c.conns[0] = conn
+	if c.conns[1] == conn {
+		c.conns[1] = nil
+	}
+	return c.conns[1] != nil
+}
+
+// currentConn returns the [Client]'s current [Conn], which may be closing during a refresh.
+func (c *Client) currentConn() *Conn {
+	c.connsMu.RLock()
+	defer c.connsMu.RUnlock()
+
+	return c.conns[0]
+}
+
+// activeConn returns the [Conn] which the [Client] should send messages through: the
+// secondary one during a refresh (because the current one is closing), or the current one.
+func (c *Client) activeConn() *Conn {
+	c.connsMu.RLock()
+	defer c.connsMu.RUnlock()
+
+	if c.conns[1] != nil {
+		return c.conns[1]
+	}
+	return c.conns[0]
+}
+
+// sendTextMessage sends a text message through the [Client]'s active [Conn]. If that
+// connection is already closing, it retries once with the one that replaces it, if any.
+func (c *Client) sendTextMessage(data []byte) error {
+	conn := c.activeConn()
+	err := <-conn.SendTextMessage(data)
+	if errors.Is(err, ErrClosing) {
+		if next := c.activeConn(); next != conn {
+			c.logger.Debug("resending WebSocket message with replacement connection",
+				slog.String("old_conn_id", conn.id), slog.String("new_conn_id", next.id))
+			err = <-next.SendTextMessage(data)
 		}
+	}
+	return err
+}
+
+// closeConns starts the closing handshake of a [Client]'s connections, when it's
+// shutting down, after stopping the timer of [Client.RefreshConnectionIn].
+func (c *Client) closeConns() {
+	c.logger.Debug("shutting down WebSocket client", slog.String("conn_id", c.conns[0].id))
+	c.stopRefresh()
+
+	c.connsMu.Lock()
+	next := c.conns[1]
+	c.conns[1] = nil
+	c.connsMu.Unlock()
+
+	status := cmp.Or(StatusCode(c.stopStatus.Load()), StatusGoingAway) //gosec:disable G115 // Stored from a StatusCode.
+	c.conns[0].Close(status)
+	if next != nil {
+		next.Close(status)
+	}
+}
+
+func (c *Client) stopRefresh() {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
 
-		c.logger.Error("failed to replace WebSocket connection", slog.Any("error", err), slog.Int("retry", i))
-		i++
+	if c.refresh != nil {
+		c.refresh.Stop()
+		c.refresh = nil
 	}
 }
 
+// shutdown stops a [Client] after an unrecoverable error or [Client.Shutdown]: it removes
+// the client from the cache, so [NewOrCachedClient] won't reuse it, and closes its channel
+// of incoming messages, to notify its subscribers (see [Client.Err]).
+func (c *Client) shutdown(err error) {
+	if errors.Is(err, ErrClientClosed) || errors.Is(err, context.Canceled) {
+		c.logger.Info("closing WebSocket client", slog.Any("reason", err))
+	} else {
+		c.logger.Error("closing WebSocket client", slog.Any("error", redact.Error(err)))
+	}
+
+	c.stopRefresh()
+
+	clients.CompareAndDelete(c.id, c)
+
+	c.waitersMu.Lock()
+	c.err = err
+	for _, w := range c.waiters {
+		close(w.resp)
+	}
+	c.waiters = nil
+	c.waitersMu.Unlock()
+
+	c.subscribersMu.Lock()
+	for _, s := range c.subscribers {
+		close(s.msgs)
+	}
+	c.subscribers = nil
+	c.subscribersClosed = true
+	c.subscribersMu.Unlock()
+
+	close(c.outMsgs)
+	if c.done != nil {
+		close(c.done)
+	}
+}
+
+// Shutdown closes the client gracefully: it closes its connection with [StatusGoingAway],
+// relays the messages which the connection already received until the closing handshake
+// ends, and then closes the client's channel of incoming messages (with the error
+// [ErrClientClosed], see [Client.Err]), and removes the client from the cache of
+// [NewOrCachedClient].
+//
+// It waits until all of that is done, or until the context is canceled. Canceling the
+// context that was passed to [NewOrCachedClient] has the same effect, without waiting.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.stop(StatusGoingAway, false)
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Err returns the reason that the client closed its channel of incoming messages.
+// It should be called only after detecting that the channel is closed.
+func (c *Client) Err() error {
+	return c.err
+}
+
+// CloseStatus returns the status code and reason of the most recent [Conn] which the
+// client replaced after it was closed, and true, or false if there isn't one yet. This
+// lets subscribers tell apart the causes of reconnections, and of the client's closure
+// (see [Client.Err]), e.g. [StatusServiceRestart] or application-specific status codes.
+// See also [Conn.CloseStatus].
+func (c *Client) CloseStatus() (StatusCode, string, bool) {
+	if s := c.closeStatus.Load(); s != nil {
+		return s.status, s.reason, true
+	}
+	return 0, "", false
+}
+
+// ClientStatus is a snapshot of a [Client]'s health, returned by [Client.Status].
+type ClientStatus struct {
+	ConnID     string `json:"conn_id"`
+	Conn       Stats  `json:"conn"`  // Of the current connection.
+	Total      Stats  `json:"total"` // Lifetime totals, including the current connection.
+	Reconnects int    `json:"reconnects"`
+}
+
+// Status returns the ID and traffic counters of the client's current [Conn], as well as
+// lifetime totals across all of the client's connections, and the number of times it
+// replaced its connection. The totals of replaced connections are as of their replacement.
+func (c *Client) Status() ClientStatus {
+	c.connsMu.RLock()
+	defer c.connsMu.RUnlock()
+
+	conn := c.conns[0].Stats()
+	return ClientStatus{
+		ConnID:     c.conns[0].id,
+		Conn:       conn,
+		Total:      c.pastStats.add(conn),
+		Reconnects: c.reconnects,
+	}
+}
+
+// Close closes the client immediately: it closes its connections with the given status
+// code, discards their remaining messages, closes the client's channel of incoming messages
+// (with the error [ErrClientClosed], see [Client.Err]) without waiting for subscribers to
+// read pending messages, and removes the client from the cache of [NewOrCachedClient].
+// Unlike [Client.Shutdown], it doesn't wait for the closing handshakes to end.
+//
+// It returns an error if the client was already closed, or shut down for any other reason.
+func (c *Client) Close(status StatusCode) error {
+	if !c.stop(status, true) {
+		return errors.New("WebSocket client already closed")
+	}
+
+	<-c.done
+	if !errors.Is(c.err, ErrClientClosed) {
+		return fmt.Errorf("WebSocket client already closed: %w", c.err)
+	}
+	return nil
+}
+
+// stop signals the message relay goroutine of the [Client] to close its connections with
+// the given status code, and then to shut down. If discard is true, the client stops relaying
+// messages to its subscribers immediately. It reports whether this is the first call.
+func (c *Client) stop(status StatusCode, discard bool) bool {
+	first := false
+	c.stopOnce.Do(func() {
+		first = true
+		c.stopStatus.Store(uint32(status))
+		if discard {
+			close(c.discarding)
+		}
+		c.cancel(ErrClientClosed)
+	})
+	return first
+}
+
 // IncomingMessages returns the client's channel that publishes
-// data [Message]s as they are received from the server.
+// data [Message]s as they are received from the server, as long
+// as there are no subscribers (see [Client.Subscribe]).
 //
 // [Message]: https://pkg.go.dev/github.com/tzrikka/timpani/pkg/websocket#Message
 func (c *Client) IncomingMessages() <-chan Message {
 	return c.outMsgs
 }
 
+// Subscribe registers a new channel that publishes data [Message]s as they are received from
+// the server, in addition to the channels of all the other subscribers of the client. Each
+// message is delivered to every subscriber, so a slow subscriber delays all of them.
+//
+// The channel is closed when the client shuts down (see [Client.Err]). The returned function
+// unsubscribes the channel, without closing it; it's safe to call more than once.
+func (c *Client) Subscribe() (<-chan Message, func()) {
+	s := &subscriber{msgs: make(chan Message), done: make(chan struct{})}
+
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+
+	if c.subscribersClosed {
+		close(s.msgs)
+		return s.msgs, func() {}
+	}
+
+	c.subscribers = append(c.subscribers, s)
+	if len(c.subscribers) == 1 {
+		select {
+		case c.subscribed <- struct{}{}:
+		default:
+		}
+	}
+
+	var once sync.Once
+	return s.msgs, func() {
+		once.Do(func() {
+			close(s.done)
+			c.subscribersMu.Lock()
+			c.subscribers = slices.DeleteFunc(c.subscribers, func(sub *subscriber) bool { return sub == s })
+			c.subscribersMu.Unlock()
+		})
+	}
+}
+
+// publish delivers a data [Message] to all the subscribers of the [Client], or to its
+// channel of incoming messages if there are none. It doesn't hold the subscribers' lock
+// while waiting for them, so subscribing or unsubscribing never blocks the caller.
+func (c *Client) publish(msg Message) {
+	for {
+		c.subscribersMu.RLock()
+		subs := slices.Clone(c.subscribers)
+		c.subscribersMu.RUnlock()
+
+		if len(subs) == 0 {
+			select {
+			case c.outMsgs <- msg:
+				return
+			case <-c.subscribed: // Deliver the message to the new subscriber instead.
+				continue
+			case <-c.discarding:
+				return
+			}
+		}
+
+		// Drain a stale signal, since the message is delivered to the subscribers anyway.
+		select {
+		case <-c.subscribed:
+		default:
+		}
+
+		for _, s := range subs {
+			select {
+			case s.msgs <- msg:
+			case <-s.done:
+			case <-c.discarding:
+				return
+			}
+		}
+		return
+	}
+}
+
 // RefreshConnectionIn instructs the client to replace its underlying [Conn]
 // seamlessly after the given duration of time. This prevents unnecessary
 // downtime during normal reconnections, which is useful in connections
 // where the disconnection time is known or coordinated in advance.
 func (c *Client) RefreshConnectionIn(ctx context.Context, d time.Duration) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
 	m := "starting timer to refresh WebSocket connection"
 	if c.refresh != nil {
 		c.refresh.Stop()
 		m = "re" + m
 	}
-	c.logger.Debug(m)
+	c.logger.Debug(m, slog.String("conn_id", c.currentConn().id))
 
 	c.refresh = time.AfterFunc(d, func() {
-		c.logger.Debug("refreshing WebSocket connection")
+		old := slog.String("old_conn_id", c.currentConn().id)
+		c.logger.Debug("refreshing WebSocket connection", old)
+		c.refreshMu.Lock()
 		c.refresh = nil
+		c.refreshMu.Unlock()
 
 		conn, err := c.newConn(ctx, c.url, c.opts...)
 		if err != nil {
-			c.logger.Error("failed to refresh WebSocket connection", slog.Any("error", err))
+			c.logger.Error("failed to refresh WebSocket connection", slog.Any("error", redact.Error(err)), old)
 			return
 		}
 
+		c.logger.Debug("refreshed WebSocket connection", old, slog.String("new_conn_id", conn.id))
+
+		// The current connection may have been replaced while dialing the new one
+		// (e.g. if it failed), so close the one that is current when switching.
+		c.connsMu.Lock()
+		oldConn := c.conns[0]
 		c.conns[1] = conn
-		c.conns[0].Close(StatusGoingAway)
+		c.connsMu.Unlock()
+		select {
+		case c.draining <- struct{}{}:
+		default: // Already signaled.
+		}
+		oldConn.Close(StatusGoingAway)
 	})
 }
 
-// SendJSONMessage sends a JSON text message to the server.
+// FailConnection closes the client's current [Conn] with the given status code and reason
+// (e.g. [StatusPolicyViolation] if the server sent messages that violate the application's
+// protocol), and replaces it immediately, without waiting for the closing handshake to end.
+// Unlike [Client.RefreshConnectionIn], the remaining messages of the failed connection are
+// discarded, and subscribers may miss messages until the new connection is established.
+func (c *Client) FailConnection(status StatusCode, reason string) {
+	conn := c.currentConn()
+	c.logger.Warn("failing WebSocket connection", slog.String("conn_id", conn.id),
+		slog.String("close_status", status.String()), slog.String("close_reason", reason))
+
+	conn.CloseWithReason(status, reason)
+	select {
+	case c.failing <- conn:
+	default: // Already signaled.
+	}
+}
+
+// SendJSONMessage sends a JSON text message to the server. During a refresh (see
+// [Client.RefreshConnectionIn]), messages are sent through the new connection,
+// because the old one is closing and may not send data messages anymore.
 func (c *Client) SendJSONMessage(v any) error {
 	b, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
 
-	return <-c.conns[0].SendTextMessage(b)
+	return c.sendTextMessage(b)
+}
+
+// Request sends a text message to the server, and waits for its response: the first
+// data message from which the matcher extracts the given key. Responses are delivered
+// only to their requests, not to the subscribers of [Client.IncomingMessages].
+//
+// Concurrent requests don't interfere with each other, even if their responses
+// arrive out of order, as long as their keys are unique. If multiple pending requests
+// match the same message, it's delivered to the one that was made first.
+//
+// The matcher is called by the client's message relay goroutine, for each incoming
+// message while the request is pending, so it should be fast and must not block.
+func (c *Client) Request(ctx context.Context, payload []byte, matcher func(Message) (string, bool), key string) (Message, error) {
+	w := &waiter{key: key, match: matcher, resp: make(chan Message, 1)}
+	if err := c.addWaiter(w); err != nil {
+		return Message{}, err
+	}
+	defer c.removeWaiter(w)
+
+	// Registered before sending, to avoid missing a quick response.
+	if err := c.sendTextMessage(payload); err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case msg, ok := <-w.resp:
+		if !ok {
+			return Message{}, c.err
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+func (c *Client) addWaiter(w *waiter) error {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	c.waiters = append(c.waiters, w)
+	return nil
+}
+
+func (c *Client) removeWaiter(w *waiter) {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+
+	c.waiters = slices.DeleteFunc(c.waiters, func(x *waiter) bool {
+		return x == w
+	})
+}
+
+// deliverResponse delivers a data [Message] to the first pending
+// [Client.Request] that it matches, and reports whether there was one.
+func (c *Client) deliverResponse(msg Message) bool {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+
+	for i, w := range c.waiters {
+		if key, ok := w.match(msg); ok && key == w.key {
+			w.resp <- msg
+			c.waiters = slices.Delete(c.waiters, i, i+1)
+			return true
+		}
+	}
+
+	return false
 }
@@ -1,23 +1,29 @@
 package websocket
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/tzrikka/timpani/pkg/websocket/websockettest"
 )
 
 func TestNewOrCachedClient(t *testing.T) {
-	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Upgrade", "websocket")
-		w.Header().Set("Connection", "upgrade")
-		w.Header().Set("Sec-WebSocket-Accept", "BACScCJPNqyz+UBoqMH89VmURoA=")
-		w.WriteHeader(http.StatusSwitchingProtocols)
-	}))
-	defer s.Close()
-
+	s := websockettest.NewServer(t)
 	url := func(_ context.Context) (string, error) { //nolint:unparam // Required function signature, but not used in this test.
-		return s.URL, nil
+		return s.URL(), nil
 	}
 
 	tests := []struct {
@@ -42,9 +48,10 @@ func TestNewOrCachedClient(t *testing.T) {
 		},
 	}
 
+	ctx := t.Context() // Not the subtests' contexts, which would shut down the clients when they end.
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if _, err := NewOrCachedClient(t.Context(), url, tt.id, withTestNonceGen()); err != nil {
+			if _, err := NewOrCachedClient(ctx, url, tt.id); err != nil {
 				t.Fatalf("NewOrCachedClient() error = %v", err)
 			}
 
@@ -55,6 +62,191 @@ func TestNewOrCachedClient(t *testing.T) {
 	}
 }
 
+func TestClientUnrecoverableError(t *testing.T) {
+	// The server closes each connection right after the handshake.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Upgrade", "websocket")
+		w.Header().Set("Connection", "upgrade")
+		w.Header().Set("Sec-WebSocket-Accept", "BACScCJPNqyz+UBoqMH89VmURoA=")
+		w.WriteHeader(http.StatusSwitchingProtocols)
+		if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer s.Close()
+
+	calls := new(atomic.Int32)
+	url := func(_ context.Context) (string, error) {
+		if calls.Add(1) > 1 {
+			return "", fmt.Errorf("%w: invalid_auth", ErrUnrecoverable)
+		}
+		return s.URL, nil
+	}
+
+	c, err := NewOrCachedClient(t.Context(), url, "unrecoverable", withTestNonceGen())
+	if err != nil {
+		t.Fatalf("NewOrCachedClient() error = %v", err)
+	}
+
+	select {
+	case _, ok := <-c.IncomingMessages():
+		if ok {
+			t.Fatal("Client.IncomingMessages() returned a message, want closed channel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the client to close")
+	}
+
+	if err := c.Err(); !errors.Is(err, ErrUnrecoverable) {
+		t.Errorf("Client.Err() = %v, want %v", err, ErrUnrecoverable)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("URL function calls = %d, want 2 (no retries)", got)
+	}
+	if _, ok := clients.Load(hash("unrecoverable")); ok {
+		t.Error("closed client is still cached")
+	}
+}
+
+func TestClientShutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		cancel  bool // Cancel the client's context, instead of calling [Client.Shutdown].
+		wantErr error
+	}{
+		{
+			name:    "shutdown",
+			id:      "shutdown",
+			wantErr: ErrClientClosed,
+		},
+		{
+			name:    "context_canceled",
+			id:      "context_canceled",
+			cancel:  true,
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := websockettest.NewServer(t, websockettest.SendText("hello"))
+			url := func(_ context.Context) (string, error) { //nolint:unparam // Required function signature.
+				return s.URL(), nil
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			c, err := NewOrCachedClient(ctx, url, tt.id)
+			if err != nil {
+				t.Fatalf("NewOrCachedClient() error = %v", err)
+			}
+
+			select {
+			case msg := <-c.IncomingMessages():
+				if got := string(msg.Data); got != "hello" {
+					t.Errorf("message = %q, want %q", got, "hello")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for a message")
+			}
+
+			shutdown := make(chan error, 1)
+			if tt.cancel {
+				cancel()
+			} else {
+				go func() {
+					ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+					defer cancel()
+					shutdown <- c.Shutdown(ctx)
+				}()
+			}
+
+			select {
+			case msg, ok := <-c.IncomingMessages():
+				if ok {
+					t.Fatalf("Client.IncomingMessages() returned %q, want closed channel", msg.Data)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for the client to close")
+			}
+
+			if err := c.Err(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Client.Err() = %v, want %v", err, tt.wantErr)
+			}
+			if _, ok := clients.Load(hash(tt.id)); ok {
+				t.Error("closed client is still cached")
+			}
+
+			frames := s.WaitForFrames(1, 5*time.Second)
+			if len(frames) == 0 || frames[0].Opcode != websockettest.OpClose || string(frames[0].Payload) != string(closePayload(StatusGoingAway)) {
+				t.Errorf("client frames = %v, want a close frame with status %s", frames, StatusGoingAway)
+			}
+
+			if !tt.cancel {
+				if err := <-shutdown; err != nil {
+					t.Errorf("Client.Shutdown() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	s := websockettest.NewServer(t, websockettest.SendText("1"), websockettest.SendText("2"))
+	url := func(_ context.Context) (string, error) { //nolint:unparam // Required function signature.
+		return s.URL(), nil
+	}
+
+	c, err := NewOrCachedClient(t.Context(), url, "close")
+	if err != nil {
+		t.Fatalf("NewOrCachedClient() error = %v", err)
+	}
+	c.RefreshConnectionIn(t.Context(), time.Hour)
+
+	// Closing doesn't wait for subscribers to read pending messages.
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Close(StatusNormalClosure)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Client.Close() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for Client.Close()")
+	}
+
+	if _, ok := <-c.IncomingMessages(); ok {
+		t.Error("Client.IncomingMessages() returned a message, want closed channel")
+	}
+	if err := c.Err(); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Client.Err() = %v, want %v", err, ErrClientClosed)
+	}
+	if _, ok := clients.Load(hash("close")); ok {
+		t.Error("closed client is still cached")
+	}
+	c.refreshMu.Lock()
+	if c.refresh != nil {
+		t.Error("refresh timer wasn't stopped")
+	}
+	c.refreshMu.Unlock()
+
+	frames := s.WaitForFrames(1, 5*time.Second)
+	if len(frames) == 0 || frames[0].Opcode != websockettest.OpClose || string(frames[0].Payload) != string(closePayload(StatusNormalClosure)) {
+		t.Errorf("client frames = %v, want a close frame with status %s", frames, StatusNormalClosure)
+	}
+
+	if err := c.Close(StatusNormalClosure); err == nil {
+		t.Error("second Client.Close() error = nil")
+	}
+	if err := c.Shutdown(t.Context()); err != nil {
+		t.Errorf("Client.Shutdown() after Client.Close() error = %v", err)
+	}
+}
+
 func lenClients() int {
 	count := 0
 	clients.Range(func(_, _ any) bool {
@@ -73,3 +265,798 @@ func TestHash(t *testing.T) {
 		t.Errorf("hash() isn't stable: %q != %q", h1, h2)
 	}
 }
+
+// chanWriter publishes each log record that is written to it.
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestClientRefreshLogsRedaction(t *testing.T) {
+	w := make(chanWriter, 10)
+	c := &Client{
+		logger: slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		url: func(_ context.Context) (string, error) {
+			return "ws://127.0.0.1:1/link?ticket=secret", nil // Connection refused.
+		},
+		conns: [2]*Conn{{id: "old"}},
+	}
+
+	c.RefreshConnectionIn(t.Context(), 0)
+
+	for {
+		select {
+		case rec := <-w:
+			if strings.Contains(rec, "secret") {
+				t.Fatalf("log record contains the Socket Mode ticket: %s", rec)
+			}
+			if strings.Contains(rec, "failed to refresh WebSocket connection") {
+				if !strings.Contains(rec, "old_conn_id=old") || !strings.Contains(rec, "ticket=REDACTED") {
+					t.Errorf("unexpected log record: %s", rec)
+				}
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for WebSocket connection refresh failure")
+		}
+	}
+}
+
+func TestClientRefreshOrdering(t *testing.T) {
+	tests := []struct {
+		name     string
+		closeOld bool
+		want     []string
+	}{
+		{
+			name:     "old_conn_drained_before_switch",
+			closeOld: true,
+			want:     []string{"old 1", "old 2", "new 1", "new 2"},
+		},
+		{
+			name: "drain_timeout",
+			want: []string{"old 1", "old 2", "new 1", "new 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldMsgs, newMsgs := make(chan Message), make(chan Message)
+			c := &Client{
+				logger:       slog.New(slog.DiscardHandler),
+				conns:        [2]*Conn{{id: "old", reader: oldMsgs}},
+				inMsgs:       oldMsgs,
+				outMsgs:      make(chan Message, 4),
+				draining:     make(chan struct{}, 1),
+				drainTimeout: 100 * time.Millisecond,
+			}
+			go c.relayMessages(context.WithoutCancel(t.Context())) // Don't close stub connections when the test ends.
+
+			// The new connection is ready to deliver messages before the old one is drained.
+			c.conns[1] = &Conn{id: "new", reader: newMsgs}
+			c.draining <- struct{}{}
+			go func() {
+				newMsgs <- Message{Opcode: OpcodeText, Data: []byte("new 1")}
+				newMsgs <- Message{Opcode: OpcodeText, Data: []byte("new 2")}
+			}()
+
+			oldMsgs <- Message{Opcode: OpcodeText, Data: []byte("old 1")}
+			oldMsgs <- Message{Opcode: OpcodeText, Data: []byte("old 2")}
+			if tt.closeOld {
+				close(oldMsgs)
+			}
+
+			for i, want := range tt.want {
+				select {
+				case msg := <-c.IncomingMessages():
+					if got := string(msg.Data); got != want {
+						t.Fatalf("message %d = %q, want %q", i, got, want)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timeout waiting for message %d (%q)", i, want)
+				}
+			}
+
+			if !tt.closeOld {
+				// Messages of the old connection after the drain timeout are discarded.
+				select {
+				case oldMsgs <- Message{Opcode: OpcodeText, Data: []byte("old 3")}:
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout waiting for the old connection to be drained")
+				}
+				close(oldMsgs)
+			}
+		})
+	}
+}
+
+func TestClientRefreshDeduplication(t *testing.T) {
+	oldMsgs, newMsgs := make(chan Message), make(chan Message)
+	c := &Client{
+		logger:       slog.New(slog.DiscardHandler),
+		conns:        [2]*Conn{{id: "old", reader: oldMsgs}},
+		inMsgs:       oldMsgs,
+		outMsgs:      make(chan Message, 10),
+		draining:     make(chan struct{}, 1),
+		drainTimeout: time.Minute,
+		messageKey: func(msg Message) string {
+			key, _, _ := strings.Cut(string(msg.Data), ":")
+			return key
+		},
+		seenKeys: newRecentKeys(DefaultMessageKeyCacheSize),
+	}
+	go c.relayMessages(context.WithoutCancel(t.Context())) // Don't close stub connections when the test ends.
+
+	c.conns[1] = &Conn{id: "new", reader: newMsgs}
+	c.draining <- struct{}{}
+
+	// Both connections deliver interleaved duplicate and unique messages during the overlap.
+	send := func(msgs chan Message, data string) {
+		select {
+		case msgs <- Message{Opcode: OpcodeText, Data: []byte(data)}:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout sending %q", data)
+		}
+	}
+	send(oldMsgs, "e1:old")
+	send(newMsgs, "e1:new")
+	send(newMsgs, "e3:new")
+	send(oldMsgs, "e2:old")
+	send(newMsgs, "e2:new")
+	send(newMsgs, "e4:new")
+	send(oldMsgs, "e4:old")
+	send(newMsgs, ":new") // Without a key.
+	close(oldMsgs)
+
+	// After the switch, messages which were already relayed are still suppressed.
+	send(newMsgs, "e5:new")
+	send(newMsgs, "e1:new")
+	send(newMsgs, ":new")
+
+	want := []string{"e1:old", "e2:old", "e4:old", "e3:new", ":new", "e5:new", ":new"}
+	for i, w := range want {
+		select {
+		case msg := <-c.IncomingMessages():
+			if got := string(msg.Data); got != w {
+				t.Fatalf("message %d = %q, want %q", i, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for message %d (%q)", i, w)
+		}
+	}
+
+	select {
+	case msg := <-c.IncomingMessages():
+		t.Errorf("unexpected message %q", msg.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRecentKeys(t *testing.T) {
+	r := newRecentKeys(2)
+	steps := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"a", false},
+		{"b", true},
+		{"c", true}, // Evicts "a".
+		{"a", true}, // Evicts "b".
+		{"c", false},
+		{"b", true},
+	}
+	for i, s := range steps {
+		if got := r.add(s.key); got != s.want {
+			t.Errorf("step %d: recentKeys.add(%q) = %v, want %v", i, s.key, got, s.want)
+		}
+	}
+}
+
+// newPipeTestConn initializes a [Conn] over an in-memory connection (without a WebSocket
+// handshake or a read goroutine), and returns it along with the server side of the pair.
+// Incoming messages are injected by the caller into the given reader channel.
+func newPipeTestConn(t *testing.T, id string, reader chan Message) (*Conn, net.Conn) {
+	t.Helper()
+
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	return &Conn{
+		id:         id,
+		logger:     slog.New(slog.DiscardHandler),
+		bufio:      bufio.NewReadWriter(bufio.NewReader(client), bufio.NewWriter(client)),
+		reader:     reader,
+		closer:     client,
+		closeGrace: time.Hour,
+	}, server
+}
+
+// readPipeFrame is like [readClientFrame], but it may be called in
+// non-test goroutines: it returns an empty payload instead of failing.
+func readPipeFrame(r io.Reader) (Opcode, []byte) {
+	b := make([]byte, 6)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil
+	}
+
+	payload := make([]byte, b[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil
+	}
+	for i := range payload {
+		payload[i] ^= b[2+i%4]
+	}
+
+	return Opcode(b[0] & 0x0f), payload
+}
+
+func TestClientFailConnection(t *testing.T) {
+	oldMsgs, newMsgs := make(chan Message), make(chan Message)
+	old, server := newPipeTestConn(t, "old", oldMsgs)
+	c := &Client{
+		logger:  slog.New(slog.DiscardHandler),
+		inMsgs:  oldMsgs,
+		outMsgs: make(chan Message, 4),
+		failing: make(chan *Conn, 1),
+	}
+	c.conns[0] = old
+	go c.relayMessages(context.WithoutCancel(t.Context()))
+
+	// A secondary connection allows the client to replace the failed one without dialing.
+	c.conns[1] = &Conn{id: "new", reader: newMsgs}
+
+	frames := make(chan []byte, 1)
+	go func() {
+		_, payload := readPipeFrame(server)
+		frames <- payload
+	}()
+
+	c.FailConnection(StatusPolicyViolation, "invalid messages")
+
+	select {
+	case got := <-frames:
+		want := append([]byte{0x03, 0xf0}, "invalid messages"...) // 1008.
+		if string(got) != string(want) {
+			t.Errorf("close frame payload = %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the close frame")
+	}
+
+	// The new connection replaces the failed one, even before its closing handshake ends.
+	go func() {
+		newMsgs <- Message{Opcode: OpcodeText, Data: []byte("new 1")}
+	}()
+	select {
+	case msg := <-c.IncomingMessages():
+		if got := string(msg.Data); got != "new 1" {
+			t.Errorf("message = %q, want %q", got, "new 1")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a message from the new connection")
+	}
+
+	// Messages of the failed connection are discarded.
+	select {
+	case oldMsgs <- Message{Opcode: OpcodeText, Data: []byte("old 1")}:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the failed connection to be drained")
+	}
+
+	close(oldMsgs)
+}
+
+func TestClientCloseStatus(t *testing.T) {
+	oldMsgs := make(chan Message)
+	old, server := newPipeTestConn(t, "old", oldMsgs)
+	go old.readMessages()
+
+	c := &Client{
+		logger:  slog.New(slog.DiscardHandler),
+		inMsgs:  oldMsgs,
+		outMsgs: make(chan Message),
+	}
+	c.conns[0] = old
+	c.conns[1] = &Conn{id: "new", reader: make(chan Message)}
+
+	if _, _, ok := c.CloseStatus(); ok {
+		t.Fatal("Client.CloseStatus() ok = true before any closure")
+	}
+
+	go c.relayMessages(context.WithoutCancel(t.Context()))
+
+	// The server closes the connection, and the client completes the closing handshake.
+	go func() {
+		_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = server.Write(serverFrame(true, false, opcodeClose, append(closePayload(StatusServiceRestart), "restarting"...)))
+		readPipeFrame(server)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, reason, ok := c.CloseStatus()
+		if ok {
+			if status != StatusServiceRestart || reason != "restarting" {
+				t.Errorf("Client.CloseStatus() = %s %q, want %s %q", status, reason, StatusServiceRestart, "restarting")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the client to replace its connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	t1, t2 := time.Unix(100, 0), time.Unix(200, 0)
+	old := &Conn{id: "old"}
+	old.framesIn.Store(3)
+	old.messagesIn.Store(2)
+	old.lastRead.Store(t2.UnixNano())
+	old.lastWrite.Store(t1.UnixNano())
+
+	c := &Client{}
+	c.conns[0] = old
+	if got := c.Status(); got.ConnID != "old" || got.Conn != got.Total || got.Reconnects != 0 {
+		t.Errorf("Client.Status() = %+v, want current conn stats as totals", got)
+	}
+
+	conn := &Conn{id: "new"}
+	conn.framesIn.Store(1)
+	conn.framesOut.Store(4)
+	conn.lastRead.Store(t1.UnixNano())
+	conn.lastWrite.Store(t2.UnixNano())
+	c.switchConn(conn)
+
+	got := c.Status()
+	want := ClientStatus{
+		ConnID:     "new",
+		Conn:       Stats{FramesIn: 1, FramesOut: 4, LastRead: t1.UTC(), LastWrite: t2.UTC()},
+		Total:      Stats{FramesIn: 4, MessagesIn: 2, FramesOut: 4, LastRead: t2.UTC(), LastWrite: t2.UTC()},
+		Reconnects: 1,
+	}
+	if got != want {
+		t.Errorf("Client.Status() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClientReconnectBackoff(t *testing.T) {
+	s := websockettest.NewServer(t)
+
+	var calls []time.Time
+	failures := 0
+	url := func(_ context.Context) (string, error) {
+		calls = append(calls, time.Now())
+		if failures > 0 {
+			failures--
+			return "", errors.New("server unavailable")
+		}
+		return s.URL(), nil
+	}
+
+	c := &Client{
+		logger:     slog.New(slog.DiscardHandler),
+		url:        url,
+		backoff:    20 * time.Millisecond,
+		maxBackoff: 50 * time.Millisecond,
+	}
+	c.conns[0] = &Conn{id: "old"}
+
+	// Backoff delays: 20 ms, 40 ms, 50 ms (capped), each ±10%.
+	failures = 3
+	if err := c.replaceConn(t.Context()); err != nil {
+		t.Fatalf("Client.replaceConn() error = %v", err)
+	}
+	t.Cleanup(func() { c.conns[0].Close(StatusNormalClosure) })
+
+	if len(calls) != 4 {
+		t.Fatalf("URL function calls = %d, want 4", len(calls))
+	}
+	for i, want := range []time.Duration{20, 40, 50} {
+		want *= time.Millisecond
+		if got := calls[i+1].Sub(calls[i]); got < want-want/10 {
+			t.Errorf("delay before retry %d = %v, want at least %v", i+1, got, want-want/10)
+		}
+	}
+	if got := calls[3].Sub(calls[0]); got > time.Second {
+		t.Errorf("total delay = %v, want about 110ms", got)
+	}
+
+	// The backoff starts over after a successful connection.
+	calls, failures = nil, 1
+	if err := c.replaceConn(t.Context()); err != nil {
+		t.Fatalf("Client.replaceConn() error = %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("URL function calls = %d, want 2", len(calls))
+	}
+	if got := calls[1].Sub(calls[0]); got < 18*time.Millisecond || got >= 45*time.Millisecond {
+		t.Errorf("delay after reset = %v, want about 20ms", got)
+	}
+	if got := c.Status().Reconnects; got != 2 {
+		t.Errorf("Client.Status().Reconnects = %d, want 2", got)
+	}
+
+	// Canceling the context stops the retries.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	calls, failures = nil, 1
+	if err := c.replaceConn(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Client.replaceConn() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	d := 100 * time.Millisecond
+	for range 100 {
+		if got := jitter(d); got < 90*time.Millisecond || got > 110*time.Millisecond {
+			t.Fatalf("jitter(%v) = %v, want within ±10%%", d, got)
+		}
+	}
+}
+
+func TestClientSendDuringRefresh(t *testing.T) {
+	s := websockettest.NewServer(t)
+	url := func(_ context.Context) (string, error) { //nolint:unparam // Required function signature.
+		return s.URL(), nil
+	}
+
+	c, err := NewOrCachedClient(t.Context(), url, "send_during_refresh")
+	if err != nil {
+		t.Fatalf("NewOrCachedClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(StatusNormalClosure) })
+
+	const senders, messages = 4, 50
+	stop := make(chan struct{})
+	var sent atomic.Int32
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Go(func() {
+			for j := range messages {
+				if err := c.SendJSONMessage(map[string]int{"sender": i, "message": j}); err != nil {
+					t.Errorf("Client.SendJSONMessage() error = %v", err)
+					return
+				}
+				sent.Add(1)
+				select {
+				case <-stop:
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		})
+	}
+
+	for i := 1; i <= 3; i++ {
+		c.RefreshConnectionIn(t.Context(), 0)
+		deadline := time.Now().Add(5 * time.Second)
+		for c.Status().Reconnects < i {
+			if time.Now().After(deadline) {
+				close(stop)
+				t.Fatalf("timeout waiting for refresh %d", i)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	// Every message was sent exactly once, none of them after a close frame.
+	n := int(sent.Load())
+	var texts int
+	closed := map[int]bool{}
+	for _, f := range s.WaitForFrames(n+3, 5*time.Second) {
+		switch f.Opcode {
+		case websockettest.OpText:
+			texts++
+			if closed[f.Conn] {
+				t.Errorf("text frame after close frame in connection %d", f.Conn)
+			}
+		case websockettest.OpClose:
+			closed[f.Conn] = true
+		}
+	}
+	if texts != n {
+		t.Errorf("text frames = %d, want %d", texts, n)
+	}
+}
+
+func TestClientRefreshAfterReplace(t *testing.T) {
+	kill := make(chan struct{})
+	s := websockettest.NewServer(t, func(c *websockettest.Conn) error {
+		if c.Index() == 1 {
+			<-kill
+			return websockettest.SendClose(uint16(StatusGoingAway), "")(c)
+		}
+		return nil
+	})
+
+	var calls atomic.Int32
+	dialing, release := make(chan struct{}), make(chan struct{})
+	url := func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 2 { // The refresh's dial.
+			close(dialing)
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		return s.URL(), nil
+	}
+
+	c, err := NewOrCachedClient(t.Context(), url, "refresh_after_replace")
+	if err != nil {
+		t.Fatalf("NewOrCachedClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(StatusNormalClosure) })
+
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second) // Shorter than [refreshDrainTimeout].
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout waiting for %s", desc)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// The server closes the first connection while the refresh is dialing,
+	// so the client replaces it before the refresh's connection is ready.
+	c.RefreshConnectionIn(t.Context(), 0)
+	<-dialing
+	close(kill)
+	waitFor("the replacement of the first connection", func() bool { return c.Status().Reconnects == 1 })
+
+	// The refresh closes and drains the replacement connection, not the first one.
+	close(release)
+	waitFor("the refresh", func() bool { return c.Status().Reconnects == 2 })
+	waitFor("the replacement connection to be closed", func() bool {
+		return slices.ContainsFunc(s.Frames(), func(f websockettest.Frame) bool {
+			return f.Conn == 2 && f.Opcode == websockettest.OpClose
+		})
+	})
+}
+
+// keyMatcher extracts the key of test messages in the format "<key>:<data>".
+func keyMatcher(msg Message) (string, bool) {
+	key, _, found := strings.Cut(string(msg.Data), ":")
+	return key, found
+}
+
+func TestClientRequestOutOfOrder(t *testing.T) {
+	inMsgs := make(chan Message)
+	conn, server := newPipeTestConn(t, "conn", inMsgs)
+	c := &Client{
+		logger:   slog.New(slog.DiscardHandler),
+		conns:    [2]*Conn{conn},
+		inMsgs:   inMsgs,
+		outMsgs:  make(chan Message, 1),
+		draining: make(chan struct{}, 1),
+	}
+	go c.relayMessages(context.WithoutCancel(t.Context()))
+
+	// Fake server: acknowledge all the requests, then respond in reverse order,
+	// with an unrelated message (which isn't a response) before the responses.
+	keys := []string{"a", "b", "c"}
+	go func() {
+		var reqs []string
+		for range keys {
+			_, payload := readPipeFrame(server)
+			reqs = append(reqs, string(payload))
+		}
+		inMsgs <- Message{Opcode: OpcodeText, Data: []byte("event")}
+		for _, req := range slices.Backward(reqs) {
+			inMsgs <- Message{Opcode: OpcodeText, Data: []byte(req + ":response")}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+			defer cancel()
+
+			msg, err := c.Request(ctx, []byte(key), keyMatcher, key)
+			if err != nil {
+				t.Errorf("Client.Request(%q) error = %v", key, err)
+				return
+			}
+			if got, want := string(msg.Data), key+":response"; got != want {
+				t.Errorf("Client.Request(%q) = %q, want %q", key, got, want)
+			}
+		})
+	}
+	wg.Wait()
+
+	select {
+	case msg := <-c.IncomingMessages():
+		if got := string(msg.Data); got != "event" {
+			t.Errorf("Client.IncomingMessages() = %q, want %q", got, "event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for unrelated message")
+	}
+	if n := len(c.waiters); n != 0 {
+		t.Errorf("pending requests = %d, want 0", n)
+	}
+}
+
+func TestClientRequestCanceled(t *testing.T) {
+	conn, server := newPipeTestConn(t, "conn", nil)
+	c := &Client{conns: [2]*Conn{conn}}
+	go readPipeFrame(server)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Request(ctx, []byte("a"), keyMatcher, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Client.Request() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := len(c.waiters); n != 0 {
+		t.Errorf("pending requests = %d, want 0", n)
+	}
+}
+
+func TestClientRequestShutdown(t *testing.T) {
+	conn, server := newPipeTestConn(t, "conn", nil)
+	c := &Client{
+		logger:  slog.New(slog.DiscardHandler),
+		conns:   [2]*Conn{conn},
+		outMsgs: make(chan Message),
+	}
+	go func() {
+		readPipeFrame(server)
+		c.shutdown(ErrUnrecoverable)
+	}()
+
+	if _, err := c.Request(t.Context(), []byte("a"), keyMatcher, "a"); !errors.Is(err, ErrUnrecoverable) {
+		t.Errorf("Client.Request() error = %v, want %v", err, ErrUnrecoverable)
+	}
+	if _, err := c.Request(t.Context(), []byte("b"), keyMatcher, "b"); !errors.Is(err, ErrUnrecoverable) {
+		t.Errorf("Client.Request() after shutdown error = %v, want %v", err, ErrUnrecoverable)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	inMsgs := make(chan Message)
+	c := &Client{
+		logger:     slog.New(slog.DiscardHandler),
+		inMsgs:     inMsgs,
+		outMsgs:    make(chan Message),
+		subscribed: make(chan struct{}, 1),
+	}
+
+	const n = 3
+	subs := make([]<-chan Message, n)
+	unsubs := make([]func(), n)
+	for i := range n {
+		subs[i], unsubs[i] = c.Subscribe()
+	}
+
+	go c.relayMessages(context.WithoutCancel(t.Context()))
+
+	// Every message is delivered to all the concurrent subscribers, in order.
+	want := []string{"1", "2", "3"}
+	var wg sync.WaitGroup
+	got := make([][]string, n)
+	for i := range n {
+		wg.Go(func() {
+			for range want {
+				select {
+				case msg := <-subs[i]:
+					got[i] = append(got[i], string(msg.Data))
+				case <-time.After(5 * time.Second):
+					return
+				}
+			}
+		})
+	}
+	for _, s := range want {
+		inMsgs <- Message{Opcode: OpcodeText, Data: []byte(s)}
+	}
+	wg.Wait()
+
+	for i := range n {
+		if !slices.Equal(got[i], want) {
+			t.Errorf("subscriber %d messages = %q, want %q", i, got[i], want)
+		}
+	}
+
+	// Unsubscribed channels don't block or receive further messages.
+	unsubs[0]()
+	unsubs[0]()
+	c.subscribersMu.RLock()
+	if l := len(c.subscribers); l != n-1 {
+		t.Errorf("len(subscribers) = %d, want %d", l, n-1)
+	}
+	c.subscribersMu.RUnlock()
+
+	inMsgs <- Message{Opcode: OpcodeText, Data: []byte("4")}
+	for i := 1; i < n; i++ {
+		select {
+		case msg := <-subs[i]:
+			if string(msg.Data) != "4" {
+				t.Errorf("subscriber %d message = %q, want %q", i, msg.Data, "4")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for subscriber %d", i)
+		}
+	}
+	select {
+	case msg := <-subs[0]:
+		t.Errorf("unsubscribed channel received %q", msg.Data)
+	default:
+	}
+
+	// Without subscribers, messages are published by Client.IncomingMessages.
+	for i := 1; i < n; i++ {
+		unsubs[i]()
+	}
+	inMsgs <- Message{Opcode: OpcodeText, Data: []byte("5")}
+	select {
+	case msg := <-c.IncomingMessages():
+		if string(msg.Data) != "5" {
+			t.Errorf("Client.IncomingMessages() = %q, want %q", msg.Data, "5")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for Client.IncomingMessages()")
+	}
+
+	// A message which is pending in Client.IncomingMessages goes to a new subscriber instead.
+	inMsgs <- Message{Opcode: OpcodeText, Data: []byte("6")}
+	sub, unsub := c.Subscribe()
+	defer unsub()
+	select {
+	case msg := <-sub:
+		if string(msg.Data) != "6" {
+			t.Errorf("new subscriber message = %q, want %q", msg.Data, "6")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the new subscriber")
+	}
+}
+
+func TestClientSubscribeShutdown(t *testing.T) {
+	s := websockettest.NewServer(t)
+	url := func(_ context.Context) (string, error) { //nolint:unparam // Required function signature.
+		return s.URL(), nil
+	}
+
+	c, err := NewOrCachedClient(t.Context(), url, "subscribe")
+	if err != nil {
+		t.Fatalf("NewOrCachedClient() error = %v", err)
+	}
+
+	sub1, unsub1 := c.Subscribe()
+	sub2, _ := c.Subscribe()
+	unsub1()
+
+	if err := c.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Client.Shutdown() error = %v", err)
+	}
+
+	// Shutting down closes the channels of current subscribers only.
+	if _, ok := <-sub2; ok {
+		t.Error("subscriber channel returned a message, want closed channel")
+	}
+	select {
+	case <-sub1:
+		t.Error("unsubscribed channel was closed")
+	default:
+	}
+
+	sub3, unsub3 := c.Subscribe()
+	if _, ok := <-sub3; ok {
+		t.Error("Client.Subscribe() after shutdown returned a message, want closed channel")
+	}
+	unsub3()
+}
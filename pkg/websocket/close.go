@@ -123,13 +123,28 @@ const (
 	maxCloseReason = maxControlPayload - 2
 )
 
+// closeGracePeriod is how long a [Conn] waits for the server's close control
+// frame, after sending its own, before closing the underlying connection anyway.
+const closeGracePeriod = 5 * time.Second
+
+// closeStatus is the status code and reason of a connection
+// closure, see [Conn.CloseStatus] and [Client.CloseStatus].
+type closeStatus struct {
+	status StatusCode
+	reason string
+}
+
 // parseClosePayload extracts the [StatusCode] and the optional
-// UTF-8 reason from an incoming connection-close control frame.
+// UTF-8 reason from an incoming connection-close control frame,
+// and records them for [Conn.CloseStatus]. The returned values
+// are meant for the response, so they may differ from the
+// recorded ones if the payload is empty or invalid.
 func (c *Conn) parseClosePayload(payload []byte) (status StatusCode, reason string) {
 	switch len(payload) {
 	case 0:
 		c.logger.Debug("received WebSocket close control frame",
 			slog.String("close_status", StatusNotReceived.String()))
+		c.recordCloseStatus(StatusNotReceived, "")
 		status = StatusNormalClosure
 		return status, reason
 	case 1:
@@ -138,6 +153,7 @@ func (c *Conn) parseClosePayload(payload []byte) (status StatusCode, reason stri
 		status = StatusCode(binary.BigEndian.Uint16(payload))
 	}
 
+	received := status
 	if len(payload) > 2 {
 		r := payload[2:]
 		if !utf8.Valid(r) {
@@ -149,10 +165,17 @@ func (c *Conn) parseClosePayload(payload []byte) (status StatusCode, reason stri
 
 	c.logger.Debug("received WebSocket close control frame",
 		slog.String("close_status", status.String()), slog.String("close_reason", reason))
+	c.recordCloseStatus(received, reason)
 
 	return status, reason
 }
 
+// recordCloseStatus stores the status code and reason of the connection's
+// closure, for [Conn.CloseStatus]. Only the first call has an effect.
+func (c *Conn) recordCloseStatus(status StatusCode, reason string) {
+	c.closeStatus.CompareAndSwap(nil, &closeStatus{status: status, reason: reason})
+}
+
 // checkClosePayload performs protocol sanity checks and corrections on the
 // [StatusCode] and UTF-8 reason of an incoming connection-close control frame.
 func checkClosePayload(status StatusCode, reason string) (StatusCode, string) {
@@ -212,6 +235,8 @@ func (c *Conn) sendCloseControlFrame(status StatusCode, reason string) {
 	time.Sleep(time.Millisecond)
 
 	status, reason = checkClosePayload(status, reason)
+	c.closing.Store(true)
+	c.markDone()
 
 	binary.BigEndian.PutUint16(c.closeBuf[:2], uint16(status))
 	if len(reason) > 0 {
@@ -226,14 +251,40 @@ func (c *Conn) sendCloseControlFrame(status StatusCode, reason string) {
 		l.Debug("sent WebSocket close control frame")
 	}
 
-	// Handle (or prepare for) the next step in the
-	// WebSocket closing handshake, if relevant.
+	// Handle (or prepare for) the next step in the WebSocket closing handshake,
+	// even if sending our close control frame failed: [Conn.readMessages]
+	// keeps reading (and discarding data frames) until the server's close
+	// control frame arrives, or until the grace period is over.
 	c.closeSent = true
 
-	if c.closeReceived {
-		_ = c.closer.Close()
+	if c.closeReceived.Load() {
+		c.closeTransport()
 		return
 	}
+
+	time.AfterFunc(c.closeGrace, c.closeTransport)
+}
+
+// fail records the status code and reason of a connection failure for [Conn.CloseStatus],
+// and initiates the closing handshake. It is called by [Conn.readMessage] when the
+// server's frames violate the protocol or the connection's limits, right before it
+// stops reading them.
+func (c *Conn) fail(status StatusCode, reason string) {
+	c.recordCloseStatus(checkClosePayload(status, reason))
+	c.sendCloseControlFrame(status, reason)
+}
+
+// closeTransport closes the underlying connection, to ensure it doesn't linger
+// (e.g. in the CLOSE_WAIT state) regardless of how the WebSocket connection ended.
+//
+// This function is idempotent: when calling it multiple
+// times, all calls after the initial one are no-ops.
+func (c *Conn) closeTransport() {
+	c.closeOnce.Do(func() {
+		if err := c.closer.Close(); err != nil {
+			c.logger.Debug("failed to close WebSocket transport", slog.Any("error", err))
+		}
+	})
 }
 
 func (c *Conn) isCloseSent() bool {
@@ -251,10 +302,40 @@ func (c *Conn) Close(s StatusCode) {
 	c.sendCloseControlFrame(s, "")
 }
 
+// CloseWithReason is like [Conn.Close], but it also sends a reason
+// to the server (e.g. which application-level rule a message violated).
+// Reasons longer than 123 bytes are truncated, see [checkClosePayload].
+func (c *Conn) CloseWithReason(s StatusCode, reason string) {
+	c.sendCloseControlFrame(s, reason)
+}
+
+// CloseStatus returns the status code and reason of the connection's closure, and true,
+// but only after the connection stopped receiving messages from the server (i.e. after
+// the channel of [Conn.IncomingMessages] is closed). Usually, these are the status code
+// and reason which the server sent in its close control frame, but:
+//   - If the server's close control frame didn't contain a status code,
+//     it returns [StatusNotReceived]
+//   - If the client failed the connection due to the server's frames (e.g.
+//     [StatusMessageTooBig], see [WithMaxMessageSize]), it returns the status
+//     code and reason which the client sent in its close control frame
+//   - If the server closed the underlying connection without a closing handshake,
+//     or didn't respond to the client's close control frame in time (see
+//     [closeGracePeriod]), it returns [StatusClosedAbnormally]
+func (c *Conn) CloseStatus() (StatusCode, string, bool) {
+	if !c.readDone.Load() {
+		return 0, "", false
+	}
+
+	if s := c.closeStatus.Load(); s != nil {
+		return s.status, s.reason, true
+	}
+	return StatusClosedAbnormally, "", true
+}
+
 func (c *Conn) IsClosed() bool {
-	return c.closeReceived && c.isCloseSent()
+	return c.closeReceived.Load() && c.isCloseSent()
 }
 
 func (c *Conn) IsClosing() bool {
-	return c.closeReceived || c.isCloseSent()
+	return c.closeReceived.Load() || c.isCloseSent()
 }
@@ -1,7 +1,13 @@
 package websocket
 
 import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestValidUTF8(t *testing.T) {
@@ -35,3 +41,309 @@ func TestValidUTF8(t *testing.T) {
 		})
 	}
 }
+
+// newTCPTestConn initializes a [Conn] over a real TCP connection (without a
+// WebSocket handshake), and returns it along with the server side of the pair.
+func newTCPTestConn(t *testing.T, grace time.Duration) (*Conn, *net.TCPConn) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := l.Accept()
+		accepted <- conn
+	}()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.Fatal("failed to accept TCP connection")
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	c := &Conn{
+		id:         "test",
+		logger:     slog.New(slog.DiscardHandler),
+		bufio:      bufio.NewReadWriter(bufio.NewReader(client), bufio.NewWriter(client)),
+		reader:     make(chan Message),
+		closer:     client,
+		closeGrace: grace,
+		done:       make(chan struct{}),
+	}
+
+	go c.readMessages()
+
+	return c, server.(*net.TCPConn)
+}
+
+// writeServerFrame sends a short, unfragmented and unmasked frame to the client.
+func writeServerFrame(t *testing.T, w io.Writer, op Opcode, payload []byte) {
+	t.Helper()
+
+	if _, err := w.Write(append([]byte{0x80 | byte(op), byte(len(payload))}, payload...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readClientFrame reads a short, unfragmented and masked frame from the client.
+func readClientFrame(t *testing.T, r io.Reader) (Opcode, []byte) {
+	t.Helper()
+
+	b := make([]byte, 6)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, b[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	for i := range payload {
+		payload[i] ^= b[2+i%4]
+	}
+
+	return Opcode(b[0] & 0x0f), payload
+}
+
+// waitForEOF checks that the client closes its side of the TCP connection in time.
+func waitForEOF(t *testing.T, conn net.Conn, timeout time.Duration) {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	if _, err := io.Copy(io.Discard, conn); err != nil {
+		t.Errorf("client didn't close the TCP connection: %v", err)
+	}
+}
+
+func closePayload(status StatusCode) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(status))
+}
+
+func TestClosingHandshakeOrdering(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientCloses bool
+		serverFrames []Message
+		serverCloses bool
+		wantMessages int
+	}{
+		{
+			name:         "server_initiated",
+			serverCloses: true,
+		},
+		{
+			name:         "server_initiated_after_data",
+			serverFrames: []Message{{Opcode: OpcodeText, Data: []byte("before")}},
+			serverCloses: true,
+			wantMessages: 1,
+		},
+		{
+			name:         "client_initiated",
+			clientCloses: true,
+			serverCloses: true,
+		},
+		{
+			name:         "client_initiated_data_after_close_is_ignored",
+			clientCloses: true,
+			serverFrames: []Message{{Opcode: OpcodeText, Data: []byte("after")}, {Opcode: OpcodeBinary, Data: []byte("after")}},
+			serverCloses: true,
+		},
+		{
+			name:         "client_initiated_without_server_reply",
+			clientCloses: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTCPTestConn(t, 100*time.Millisecond)
+
+			received := make(chan int)
+			go func() {
+				n := 0
+				for range c.IncomingMessages() {
+					n++
+				}
+				received <- n
+			}()
+
+			if tt.clientCloses {
+				go c.Close(StatusGoingAway)
+				op, payload := readClientFrame(t, server)
+				if op != opcodeClose || binary.BigEndian.Uint16(payload) != uint16(StatusGoingAway) {
+					t.Fatalf("client frame = %s %v, want close %d", op, payload, StatusGoingAway)
+				}
+			}
+
+			for _, m := range tt.serverFrames {
+				writeServerFrame(t, server, m.Opcode, m.Data)
+			}
+
+			if tt.serverCloses {
+				writeServerFrame(t, server, opcodeClose, closePayload(StatusNormalClosure))
+			}
+
+			if !tt.clientCloses {
+				op, payload := readClientFrame(t, server)
+				if op != opcodeClose || binary.BigEndian.Uint16(payload) != uint16(StatusNormalClosure) {
+					t.Fatalf("client frame = %s %v, want close %d", op, payload, StatusNormalClosure)
+				}
+			}
+
+			waitForEOF(t, server, time.Second)
+
+			if n := <-received; n != tt.wantMessages {
+				t.Errorf("received data messages = %d, want %d", n, tt.wantMessages)
+			}
+			if !c.IsClosing() {
+				t.Error("Conn.IsClosing() = false, want true")
+			}
+		})
+	}
+}
+
+// TestCloseWaitLeak checks that the client closes its side of the TCP
+// connection when the server closes its side without a closing handshake.
+func TestCloseWaitLeak(t *testing.T) {
+	c, server := newTCPTestConn(t, time.Hour)
+
+	if err := server.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	for range c.IncomingMessages() {
+	}
+
+	waitForEOF(t, server, time.Second)
+
+	if !c.IsClosed() {
+		t.Error("Conn.IsClosed() = false, want true")
+	}
+}
+
+// TestCloseGracePeriod checks that the client closes the TCP connection after
+// sending a close control frame, even if the server keeps sending data frames.
+func TestCloseGracePeriod(t *testing.T) {
+	c, server := newTCPTestConn(t, 100*time.Millisecond)
+
+	go c.Close(StatusNormalClosure)
+	if op, _ := readClientFrame(t, server); op != opcodeClose {
+		t.Fatalf("client frame opcode = %s, want close", op)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := server.Write([]byte{0x81, 0x01, 'x'}); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	for range c.IncomingMessages() {
+		t.Error("received data message after sending close control frame")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("client didn't close the TCP connection after the grace period")
+	}
+}
+
+func TestConnCloseStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    []byte
+		frame      []byte
+		eof        bool
+		wantStatus StatusCode
+		wantReason string
+	}{
+		{
+			name:       "status_and_reason",
+			payload:    append(closePayload(StatusServiceRestart), "restarting"...),
+			wantStatus: StatusServiceRestart,
+			wantReason: "restarting",
+		},
+		{
+			name:       "application_status",
+			payload:    closePayload(4000),
+			wantStatus: 4000,
+		},
+		{
+			name:       "without_status",
+			payload:    []byte{},
+			wantStatus: StatusNotReceived,
+		},
+		{
+			name:       "invalid_reason",
+			payload:    append(closePayload(StatusGoingAway), 0xff, 0xfe),
+			wantStatus: StatusGoingAway,
+		},
+		{
+			name:       "client_failure",
+			frame:      []byte{0x83, 0x00}, // Unknown opcode.
+			wantStatus: StatusProtocolError,
+			wantReason: "unknown opcode 3",
+		},
+		{
+			name:       "without_closing_handshake",
+			eof:        true,
+			wantStatus: StatusClosedAbnormally,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTCPTestConn(t, time.Hour)
+
+			if _, _, ok := c.CloseStatus(); ok {
+				t.Fatal("Conn.CloseStatus() ok = true before closure")
+			}
+
+			switch {
+			case tt.eof:
+				if err := server.CloseWrite(); err != nil {
+					t.Fatal(err)
+				}
+			case tt.frame != nil:
+				if _, err := server.Write(tt.frame); err != nil {
+					t.Fatal(err)
+				}
+				if op, _ := readClientFrame(t, server); op != opcodeClose {
+					t.Fatalf("client frame opcode = %s, want close", op)
+				}
+			default:
+				writeServerFrame(t, server, opcodeClose, tt.payload)
+				if op, _ := readClientFrame(t, server); op != opcodeClose {
+					t.Fatalf("client frame opcode = %s, want close", op)
+				}
+			}
+
+			for range c.IncomingMessages() {
+			}
+
+			status, reason, ok := c.CloseStatus()
+			if !ok {
+				t.Fatal("Conn.CloseStatus() ok = false after closure")
+			}
+			if status != tt.wantStatus || reason != tt.wantReason {
+				t.Errorf("Conn.CloseStatus() = %s %q, want %s %q", status, reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
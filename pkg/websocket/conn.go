@@ -2,33 +2,95 @@ package websocket
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Conn respresents the configuration and state of
 // an open client connection to a WebSocket server.
 type Conn struct {
 	// Initialized before the handshake.
+	id      string
+	url     string
 	logger  *slog.Logger
 	client  *http.Client
 	headers http.Header
 
+	checkRedirect func(*http.Request, []*http.Request) error // Of the caller's custom HTTP client.
+	subprotocols  []string                                   // Offered, see [WithSubprotocols].
+
+	// Offered before the handshake, and nil after it if the server declined, see [WithDeflate].
+	deflate *deflate
+
+	maxMessageSize  int64 // 0 = unlimited, see [DefaultMaxMessageSize] and [WithMaxMessageSize].
+	frameSize       int   // Of outgoing fragments, see [DefaultFrameSize] and [WithFrameSize].
+	readBufferSize  int   // 0 = [DefaultBufferSize], see [WithReadBufferSize].
+	writeBufferSize int   // 0 = [DefaultBufferSize], see [WithWriteBufferSize].
+
+	// Not used by the connection itself, only forwarded to its [Client],
+	// see [WithReconnectBackoff] and [WithMessageKey].
+	reconnectBackoff    time.Duration
+	maxReconnectBackoff time.Duration
+	messageKey          func(Message) string
+
 	// Initialized after the handshake.
 	bufio  *bufio.ReadWriter
 	reader chan Message
-	writer chan internalMessage
 	closer io.ReadWriteCloser
+	dialed time.Time
+
+	subprotocol string // Selected by the server, see [Conn.Subprotocol].
 
-	// No need for synchronization: value changes are possible only in
-	// one direction (false to true), and are always done by a single
-	// function, which is guaranteed to run in a single goroutine.
-	closeReceived bool
+	// Serialize concurrent data messages and calls to [Conn.writeFrame],
+	// respectively, see [Conn.send] and [Conn.sendFrom].
+	dataMu  sync.Mutex
+	writeMu sync.Mutex
+
+	// Updated only by the read goroutine and while holding writeMu, respectively, see [Conn.Stats].
+	framesIn   atomic.Int64
+	bytesIn    atomic.Int64
+	pingsIn    atomic.Int64
+	messagesIn atomic.Int64
+	lastRead   atomic.Int64 // Unix nanoseconds.
+	framesOut  atomic.Int64
+	bytesOut   atomic.Int64
+	pongsOut   atomic.Int64
+	lastWrite  atomic.Int64 // Unix nanoseconds.
+
+	// Value changes are possible only in one direction (false to true), and are
+	// always done by the read goroutine, but [Conn.sendCloseControlFrame] may
+	// check it concurrently in other goroutines, e.g. when calling [Conn.Close].
+	closeReceived atomic.Bool
+	closeStatus   atomic.Pointer[closeStatus] // Received or sent, see [Conn.CloseStatus].
+	readDone      atomic.Bool                 // Set by [Conn.readMessages] before closing reader.
 
 	closeSent   bool
 	closeSentMu sync.RWMutex
+	closing     atomic.Bool // Set right before sending the close control frame, see [ErrClosing].
+
+	closeGrace time.Duration
+	closeOnce  sync.Once
+
+	done     chan struct{} // Closed when the connection starts closing or stops reading, see [Conn.markDone].
+	doneOnce sync.Once
+
+	// Ping/pong bookkeeping, see [Conn.Ping], [WithUnsolicitedPongLimit], and [WithKeepAlive].
+	pendingPings     []string
+	pingPending      string        // Payload of the latest ping of [Conn.keepAlive], until its pong arrives.
+	keepAlivePongs   chan struct{} // Signals [Conn.keepAlive] that its latest ping got a pong.
+	pingsMu          sync.Mutex
+	pingInterval     time.Duration
+	pingTimeout      time.Duration
+	unsolicitedPongs atomic.Int64 // Lifetime total, for [Conn.DebugInfo].
+	pongLimit        int64
+	pongWindowStart  time.Time // Of the current [unsolicitedPongWindow].
+	pongWindowCount  int64     // Unsolicited pongs in the current [unsolicitedPongWindow].
 
 	// Only for the purpose of minimizing memory allocations (safely),
 	// not for state management or memory sharing of any kind.
@@ -48,11 +110,127 @@ type Message struct {
 	Data   []byte
 }
 
-// internalMessage is used to synchronize concurrent calls to [Conn.writeFrame].
+// internalMessage is a data message which [Conn.readMessage] passes to [Conn.readMessages].
 type internalMessage struct {
 	Opcode Opcode
 	Data   []byte
-	err    chan<- error
+}
+
+// DebugInfo is a snapshot of a [Conn]'s identity and traffic
+// counters, returned by [Conn.DebugInfo]. Byte counters
+// include only frame payloads, not frame headers.
+type DebugInfo struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	DialTime  time.Time `json:"dial_time"`
+	FramesIn  int64     `json:"frames_in"`
+	BytesIn   int64     `json:"bytes_in"`
+	FramesOut int64     `json:"frames_out"`
+	BytesOut  int64     `json:"bytes_out"`
+	State     string    `json:"state"`
+
+	UnsolicitedPongs int64 `json:"unsolicited_pongs"`
+}
+
+// Stats is a snapshot of a [Conn]'s traffic counters, returned by [Conn.Stats].
+// Byte counters include only frame payloads, not frame headers. MessagesIn counts
+// only data messages that were delivered to subscribers. The last read and write
+// times are zero if nothing was received or sent yet.
+type Stats struct {
+	FramesIn   int64     `json:"frames_in"`
+	BytesIn    int64     `json:"bytes_in"`
+	PingsIn    int64     `json:"pings_in"`
+	MessagesIn int64     `json:"messages_in"`
+	LastRead   time.Time `json:"last_read,omitzero"`
+
+	FramesOut int64     `json:"frames_out"`
+	BytesOut  int64     `json:"bytes_out"`
+	PongsOut  int64     `json:"pongs_out"`
+	LastWrite time.Time `json:"last_write,omitzero"`
+}
+
+// Stats returns a snapshot of the connection's traffic counters. It's safe to call
+// concurrently with reads and writes, but the counters aren't updated atomically
+// as a group, so the snapshot may be slightly inconsistent while data is flowing.
+func (c *Conn) Stats() Stats {
+	return Stats{
+		FramesIn:   c.framesIn.Load(),
+		BytesIn:    c.bytesIn.Load(),
+		PingsIn:    c.pingsIn.Load(),
+		MessagesIn: c.messagesIn.Load(),
+		LastRead:   unixNano(c.lastRead.Load()),
+
+		FramesOut: c.framesOut.Load(),
+		BytesOut:  c.bytesOut.Load(),
+		PongsOut:  c.pongsOut.Load(),
+		LastWrite: unixNano(c.lastWrite.Load()),
+	}
+}
+
+// add returns the sum of two [Stats] snapshots, with the latest of their timestamps.
+func (s Stats) add(o Stats) Stats {
+	s.FramesIn += o.FramesIn
+	s.BytesIn += o.BytesIn
+	s.PingsIn += o.PingsIn
+	s.MessagesIn += o.MessagesIn
+	s.FramesOut += o.FramesOut
+	s.BytesOut += o.BytesOut
+	s.PongsOut += o.PongsOut
+
+	if o.LastRead.After(s.LastRead) {
+		s.LastRead = o.LastRead
+	}
+	if o.LastWrite.After(s.LastWrite) {
+		s.LastWrite = o.LastWrite
+	}
+	return s
+}
+
+// unixNano converts a timestamp which was stored atomically to a [time.Time], or zero if it's unset.
+func unixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// DebugInfo returns the connection's ID, remote URL (without
+// its path and query parameters), dial time, traffic counters,
+// close state ("open", "closing", or "closed"), and the number
+// of unsolicited pong control frames received from the server.
+func (c *Conn) DebugInfo() DebugInfo {
+	state := "open"
+	switch {
+	case c.IsClosed():
+		state = "closed"
+	case c.IsClosing():
+		state = "closing"
+	}
+
+	return DebugInfo{
+		ID:        c.id,
+		URL:       c.url,
+		DialTime:  c.dialed,
+		FramesIn:  c.framesIn.Load(),
+		BytesIn:   c.bytesIn.Load(),
+		FramesOut: c.framesOut.Load(),
+		BytesOut:  c.bytesOut.Load(),
+		State:     state,
+
+		UnsolicitedPongs: c.unsolicitedPongs.Load(),
+	}
+}
+
+// String returns the connection's ID and remote URL (without its path and query parameters).
+func (c *Conn) String() string {
+	return fmt.Sprintf("%s (%s)", c.id, c.url)
+}
+
+// Subprotocol returns the application-level subprotocol which the server
+// selected during the handshake, or an empty string if the client didn't
+// offer any (see [WithSubprotocols]).
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
 }
 
 // IncomingMessages returns the connection's channel that publishes
@@ -66,22 +244,147 @@ func (c *Conn) IncomingMessages() <-chan Message {
 // readMessages runs as a [Conn] goroutine, to call [Conn.readMessage]
 // continuously, in order to process control and data frames, and
 // publish data [Message]s to the connection's subscribers.
+//
+// After sending a close control frame, it discards incoming data
+// messages until the server's close control frame arrives. When it
+// stops reading, for any reason, it also closes the underlying connection.
 func (c *Conn) readMessages() {
 	msg := c.readMessage()
 	for msg != nil {
-		c.reader <- Message{Opcode: msg.Opcode, Data: msg.Data}
+		c.lastRead.Store(time.Now().UnixNano()) // Per message, not per frame, to keep frame reading lean.
+		if c.isCloseSent() {
+			c.logger.Debug("discarding WebSocket data message received after sending close control frame",
+				slog.String("opcode", msg.Opcode.String()), slog.Int("length", len(msg.Data)))
+		} else {
+			c.messagesIn.Add(1)
+			c.reader <- Message{Opcode: msg.Opcode, Data: msg.Data}
+		}
 		msg = c.readMessage()
 	}
+	c.readDone.Store(true)
+	c.markDone()
 	close(c.reader)
+	c.closeTransport()
+}
+
+// markDone closes the connection's done channel (if it has one), to let goroutines
+// such as [Conn.keepAlive] end as soon as the connection starts closing or stops
+// reading, instead of waiting for their next timer. It is safe to call it repeatedly.
+func (c *Conn) markDone() {
+	if c.done == nil {
+		return
+	}
+	c.doneOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// noError is a closed channel, which [Conn.send] returns
+// after successful writes, to avoid allocating a new one.
+var noError = func() chan error {
+	err := make(chan error)
+	close(err)
+	return err
+}()
+
+// ErrClosing is returned when sending a data message after the client started the
+// WebSocket closing handshake, because "after sending a Close frame, the application
+// MUST NOT send any more data frames" (https://datatracker.ietf.org/doc/html/rfc6455#section-5.5.1).
+var ErrClosing = errors.New("WebSocket connection is closing")
+
+// send serializes concurrent calls to [Conn.writeFrame] with a mutex, instead of
+// a dedicated goroutine and channel. The returned channel is already closed, so
+// callers can receive the result immediately. Data messages are also serialized
+// with the fragmented ones of [Conn.sendFrom], but control frames aren't.
+//
+// If the "permessage-deflate" extension was negotiated (see [WithDeflate]),
+// this function also compresses data messages, but not control frames.
+func (c *Conn) send(op Opcode, data []byte) <-chan error {
+	isData := op == OpcodeText || op == OpcodeBinary
+	if isData {
+		c.dataMu.Lock()
+		defer c.dataMu.Unlock()
+	}
+
+	c.writeMu.Lock()
+	payload, compressed := data, false
+	var err error
+	switch {
+	case isData && c.closing.Load():
+		err = ErrClosing
+	case isData && c.deflate != nil:
+		payload, compressed, err = c.deflate.compress(data)
+	}
+	if err == nil {
+		err = c.writeFrame(op, payload, true, compressed)
+	}
+	if err == nil {
+		c.countFrameOut(len(payload))
+	}
+	c.writeMu.Unlock()
+
+	return errChan(err)
+}
+
+// sendFrom is like [Conn.send], but it streams a data message from a reader, in
+// fragments of up to [Conn.frameSize] bytes, without compressing them. Data messages
+// are serialized for the duration of the entire message, but each fragment is written
+// separately, so control frames (e.g. pongs) may be interleaved between them, as
+// defined in https://datatracker.ietf.org/doc/html/rfc6455#section-5.4.
+//
+// If the reader fails after some fragments were already sent, the message can't be
+// completed, so this function also closes the connection with [StatusInternalError].
+func (c *Conn) sendFrom(op Opcode, r io.Reader) <-chan error {
+	c.dataMu.Lock()
+	defer c.dataMu.Unlock()
+
+	size := c.frameSize
+	if size <= 0 {
+		size = DefaultFrameSize
+	}
+	buf := make([]byte, size)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		fin := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !fin {
+			if op == opcodeContinuation {
+				c.CloseWithReason(StatusInternalError, "message streaming error")
+			}
+			return errChan(fmt.Errorf("failed to read WebSocket message payload: %w", err))
+		}
+
+		c.writeMu.Lock()
+		if c.closing.Load() {
+			err = ErrClosing
+		} else if err = c.writeFrame(op, buf[:n], fin, false); err == nil {
+			c.countFrameOut(n)
+		}
+		c.writeMu.Unlock()
+
+		if err != nil || fin {
+			return errChan(err)
+		}
+		op = opcodeContinuation
+	}
+}
+
+// countFrameOut updates the connection's outbound traffic counters
+// (see [Conn.Stats]), after writing a frame while holding writeMu.
+func (c *Conn) countFrameOut(n int) {
+	c.framesOut.Add(1)
+	c.bytesOut.Add(int64(n))
+	c.lastWrite.Store(time.Now().UnixNano())
 }
 
-// writeMessages runs as a [Conn] goroutine, to synchronize concurrent
-// calls to [Conn.writeFrame]. For the time being, this package doesn't
-// need to implement frame fragmentation in outbound messages.
-func (c *Conn) writeMessages() {
-	for msg := range c.writer {
-		msg.err <- c.writeFrame(msg.Opcode, msg.Data)
-		// The message's error channel can be used at most once.
-		close(msg.err)
+// errChan returns [noError] if err is nil, or a new closed channel which contains it.
+func errChan(err error) <-chan error {
+	if err == nil {
+		return noError
 	}
+
+	ch := make(chan error, 1)
+	ch <- err
+	close(ch)
+	return ch
 }
@@ -0,0 +1,226 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// deflateExtension is the name of the "permessage-deflate"
+	// extension, as defined in https://datatracker.ietf.org/doc/html/rfc7692.
+	deflateExtension = "permessage-deflate"
+
+	// deflateOffer is the extension negotiation offer of [WithDeflate]. This client
+	// always compresses each message independently, so it lets the server know that
+	// it doesn't need to keep its decompression context between messages. It doesn't
+	// offer "client_max_window_bits", because [flate.Writer] always uses 32 KiB.
+	deflateOffer = deflateExtension + "; client_no_context_takeover"
+
+	// deflateWindowSize is the LZ77 sliding window size of the server (2^15 bytes, the
+	// default "server_max_window_bits"), which is also the maximum size of dictionaries.
+	deflateWindowSize = 1 << 15
+)
+
+// deflateTail is appended to the payload of compressed messages before decompressing
+// them: the first 4 bytes are the end of the empty stored block which senders remove
+// (https://datatracker.ietf.org/doc/html/rfc7692#section-7.2.2), and the rest is a final
+// empty stored block, so [flate.NewReader] returns [io.EOF] instead of waiting for more.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// flateWriters is a pool of [flate.Writer]s for each compression level, because
+// they're relatively large (hundreds of KiB) and the client resets them after
+// compressing each message anyway, so connections don't need to own them.
+var flateWriters [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+
+// deflate is the state of the "permessage-deflate" extension in a [Conn].
+type deflate struct {
+	level int
+
+	// Negotiated during the handshake, see [Conn.negotiateDeflate].
+	serverNoContextTakeover bool
+
+	// Used only by the read goroutine, see [deflate.decompress].
+	reader io.ReadCloser
+	window []byte
+
+	// Used only while holding the connection's writeMu, see [deflate.compress].
+	buf bytes.Buffer
+}
+
+// WithDeflate lets callers of [Dial] offer the [permessage-deflate] extension to the
+// server, to compress data messages with the given [compress/flate] level. If the server
+// accepts it, outgoing messages are compressed whenever that reduces their size, and
+// incoming messages are decompressed transparently. Invalid levels are replaced with the
+// nearest valid one. Don't use this together with a custom "Sec-WebSocket-Extensions"
+// header (see [WithHTTPHeader]) that offers the same extension.
+//
+// [permessage-deflate]: https://datatracker.ietf.org/doc/html/rfc7692
+func WithDeflate(level int) DialOpt {
+	return func(c *Conn) {
+		c.deflate = &deflate{level: min(max(level, flate.HuffmanOnly), flate.BestCompression)}
+	}
+}
+
+// negotiateDeflate checks the server's response to the offer of [WithDeflate], as defined in
+// https://datatracker.ietf.org/doc/html/rfc7692#section-5. If the server declined the offer,
+// it disables the extension in the connection. Unexpected extension parameters are errors.
+func (c *Conn) negotiateDeflate(resp http.Header) error {
+	if c.deflate == nil {
+		return nil
+	}
+
+	const key = "Sec-WebSocket-Extensions"
+	var params []string
+	found := false
+	for _, v := range resp.Values(key) {
+		for ext := range strings.SplitSeq(v, ",") {
+			ps := strings.Split(ext, ";")
+			if !strings.EqualFold(strings.TrimSpace(ps[0]), deflateExtension) {
+				continue
+			}
+			if found {
+				return extensionError{fmt.Errorf("WebSocket handshake response header %q: got %q more than once", key, deflateExtension)}
+			}
+			found, params = true, ps[1:]
+		}
+	}
+
+	if !found {
+		c.deflate = nil
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, p := range params {
+		name, value, hasValue := strings.Cut(p, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if name == "" {
+			continue
+		}
+
+		if seen[name] {
+			return extensionError{fmt.Errorf("WebSocket handshake response header %q: duplicate parameter %q", key, name)}
+		}
+		seen[name] = true
+
+		switch name {
+		case "server_no_context_takeover", "client_no_context_takeover":
+			if hasValue {
+				return extensionError{fmt.Errorf("WebSocket handshake response header %q: unexpected value in parameter %q", key, name)}
+			}
+			if name == "server_no_context_takeover" {
+				c.deflate.serverNoContextTakeover = true
+			}
+		case "server_max_window_bits":
+			// The server may use a smaller sliding window than the default, but
+			// [flate.NewReader] supports all window sizes, so it doesn't matter.
+			if n, err := strconv.Atoi(value); err != nil || n < 8 || n > 15 {
+				return extensionError{fmt.Errorf("WebSocket handshake response header %q: invalid parameter %q", key, p)}
+			}
+		default: // Including "client_max_window_bits", which the client didn't offer.
+			return extensionError{fmt.Errorf("WebSocket handshake response header %q: unexpected parameter %q", key, name)}
+		}
+	}
+
+	return nil
+}
+
+// extensionError is returned by [Conn.negotiateDeflate] and [checkHandshakeResponse],
+// so [Dial] can fail the connection which the server already considers to be open.
+type extensionError struct {
+	error
+}
+
+// compress compresses the payload of a data message, as defined in
+// https://datatracker.ietf.org/doc/html/rfc7692#section-7.2.1. It returns the
+// original payload (and false) if compressing it doesn't reduce its size.
+//
+// The result is valid only until the next call, so this function must be called
+// while holding the connection's writeMu, and only if the extension was negotiated.
+func (d *deflate) compress(data []byte) ([]byte, bool, error) {
+	d.buf.Reset()
+
+	pool := &flateWriters[d.level-flate.HuffmanOnly]
+	fw, ok := pool.Get().(*flate.Writer)
+	if ok {
+		fw.Reset(&d.buf)
+	} else {
+		fw, _ = flate.NewWriter(&d.buf, d.level) // The level is always valid.
+	}
+	defer pool.Put(fw)
+
+	if _, err := fw.Write(data); err != nil {
+		return nil, false, fmt.Errorf("failed to compress WebSocket message: %w", err)
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, false, fmt.Errorf("failed to compress WebSocket message: %w", err)
+	}
+
+	// Remove the 4 octets of the empty stored block at the end of the flushed data.
+	payload := bytes.TrimSuffix(d.buf.Bytes(), deflateTail[:4])
+	if len(payload) >= len(data) {
+		return data, false, nil
+	}
+	return payload, true, nil
+}
+
+// errMessageTooBig is returned by [deflate.decompress] if
+// the decompressed data exceeds the maximum message size.
+var errMessageTooBig = errors.New("decompressed WebSocket message is too big")
+
+// decompress decompresses the payload of a data message, as defined in
+// https://datatracker.ietf.org/doc/html/rfc7692#section-7.2.2. Unless the server
+// declined to take over its compression context between messages, it also keeps
+// the last 32 KiB of decompressed data, as the dictionary of the next message.
+// If maxSize is positive, it stops decompressing as soon as the data exceeds it.
+//
+// Do not call this function directly, it is meant to be used
+// exclusively by [Conn.readMessage], and only if the extension was negotiated!
+func (d *deflate) decompress(payload []byte, maxSize int64) ([]byte, error) {
+	r := io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail))
+	if d.reader == nil {
+		d.reader = flate.NewReaderDict(r, d.window)
+	} else if err := d.reader.(flate.Resetter).Reset(r, d.window); err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = d.reader
+	if maxSize > 0 {
+		src = io.LimitReader(d.reader, min(maxSize, math.MaxInt64-1)+1) // Avoid overflows.
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, errMessageTooBig
+	}
+
+	if !d.serverNoContextTakeover {
+		d.updateWindow(data)
+	}
+	return data, nil
+}
+
+// updateWindow appends decompressed data to the dictionary of the next message,
+// and trims it to the size of the server's LZ77 sliding window, if necessary.
+func (d *deflate) updateWindow(data []byte) {
+	if len(data) >= deflateWindowSize {
+		d.window = append(d.window[:0], data[len(data)-deflateWindowSize:]...)
+		return
+	}
+
+	if n := len(d.window) + len(data) - deflateWindowSize; n > 0 {
+		d.window = append(d.window[:0], d.window[n:]...)
+	}
+	d.window = append(d.window, data...)
+}
@@ -0,0 +1,435 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiateDeflate(t *testing.T) {
+	tests := []struct {
+		name            string
+		resp            []string
+		want            bool
+		wantNoTakeover  bool
+		wantErr         bool
+		wantErrContains string
+	}{
+		{
+			name: "declined",
+		},
+		{
+			name: "other_extension",
+			resp: []string{"x-webkit-deflate-frame"},
+		},
+		{
+			name: "accepted",
+			resp: []string{"permessage-deflate"},
+			want: true,
+		},
+		{
+			name:           "all_valid_params",
+			resp:           []string{`permessage-deflate; client_no_context_takeover; server_no_context_takeover; server_max_window_bits="10"`},
+			want:           true,
+			wantNoTakeover: true,
+		},
+		{
+			name:           "multiple_headers",
+			resp:           []string{"x-other", "Permessage-Deflate;Server_No_Context_Takeover"},
+			want:           true,
+			wantNoTakeover: true,
+		},
+		{
+			name:            "duplicate_extension",
+			resp:            []string{"permessage-deflate, permessage-deflate"},
+			wantErr:         true,
+			wantErrContains: "more than once",
+		},
+		{
+			name:            "duplicate_param",
+			resp:            []string{"permessage-deflate; server_no_context_takeover; server_no_context_takeover"},
+			wantErr:         true,
+			wantErrContains: "duplicate",
+		},
+		{
+			name:            "param_with_unexpected_value",
+			resp:            []string{"permessage-deflate; client_no_context_takeover=1"},
+			wantErr:         true,
+			wantErrContains: "unexpected value",
+		},
+		{
+			name:            "invalid_window_bits",
+			resp:            []string{"permessage-deflate; server_max_window_bits=16"},
+			wantErr:         true,
+			wantErrContains: "invalid parameter",
+		},
+		{
+			name:            "unoffered_client_window_bits",
+			resp:            []string{"permessage-deflate; client_max_window_bits=10"},
+			wantErr:         true,
+			wantErrContains: "unexpected parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for _, v := range tt.resp {
+				h.Add("Sec-WebSocket-Extensions", v)
+			}
+
+			c := &Conn{}
+			WithDeflate(flate.BestSpeed)(c)
+			err := c.negotiateDeflate(h)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Conn.negotiateDeflate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.wantErrContains) {
+					t.Errorf("Conn.negotiateDeflate() error = %q, want it to contain %q", err, tt.wantErrContains)
+				}
+				return
+			}
+
+			if got := c.deflate != nil; got != tt.want {
+				t.Fatalf("Conn.negotiateDeflate() negotiated = %v, want %v", got, tt.want)
+			}
+			if tt.want && c.deflate.serverNoContextTakeover != tt.wantNoTakeover {
+				t.Errorf("Conn.negotiateDeflate() server_no_context_takeover = %v, want %v",
+					c.deflate.serverNoContextTakeover, tt.wantNoTakeover)
+			}
+		})
+	}
+}
+
+func TestWithDeflateLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		want  int
+	}{
+		{
+			name:  "valid",
+			level: flate.BestSpeed,
+			want:  flate.BestSpeed,
+		},
+		{
+			name:  "too_low",
+			level: -10,
+			want:  flate.HuffmanOnly,
+		},
+		{
+			name:  "too_high",
+			level: 10,
+			want:  flate.BestCompression,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Conn{}
+			WithDeflate(tt.level)(c)
+			if c.deflate.level != tt.want {
+				t.Errorf("WithDeflate(%d) level = %d, want %d", tt.level, c.deflate.level, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnCheckFrameHeaderDeflate(t *testing.T) {
+	tests := []struct {
+		name    string
+		deflate bool
+		h       frameHeader
+		msgType Opcode
+		wantErr bool
+	}{
+		{
+			name:    "compressed_without_extension",
+			h:       frameHeader{fin: true, rsv: [3]bool{true, false, false}, opcode: OpcodeText},
+			wantErr: true,
+		},
+		{
+			name:    "compressed_text",
+			deflate: true,
+			h:       frameHeader{fin: true, rsv: [3]bool{true, false, false}, opcode: OpcodeText},
+		},
+		{
+			name:    "compressed_binary",
+			deflate: true,
+			h:       frameHeader{rsv: [3]bool{true, false, false}, opcode: OpcodeBinary},
+		},
+		{
+			name:    "compressed_continuation",
+			deflate: true,
+			h:       frameHeader{fin: true, rsv: [3]bool{true, false, false}, opcode: opcodeContinuation},
+			msgType: OpcodeText,
+			wantErr: true,
+		},
+		{
+			name:    "compressed_ping",
+			deflate: true,
+			h:       frameHeader{fin: true, rsv: [3]bool{true, false, false}, opcode: opcodePing},
+			wantErr: true,
+		},
+		{
+			name:    "rsv2_with_extension",
+			deflate: true,
+			h:       frameHeader{fin: true, rsv: [3]bool{false, true, false}, opcode: OpcodeText},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Conn{}
+			if tt.deflate {
+				c.deflate = &deflate{}
+			}
+			if _, err := c.checkFrameHeader(tt.h, tt.msgType); (err != nil) != tt.wantErr {
+				t.Errorf("Conn.checkFrameHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// deflateServerWriter compresses messages like a server with context takeover: it
+// doesn't reset its compressor between messages, so messages may refer to earlier ones.
+type deflateServerWriter struct {
+	buf bytes.Buffer
+	fw  *flate.Writer
+}
+
+func newDeflateServerWriter(t *testing.T) *deflateServerWriter {
+	t.Helper()
+
+	w := new(deflateServerWriter)
+	fw, err := flate.NewWriter(&w.buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.fw = fw
+	return w
+}
+
+func (w *deflateServerWriter) compress(t *testing.T, s string) []byte {
+	t.Helper()
+
+	w.buf.Reset()
+	if _, err := w.fw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.fw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSuffix(w.buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff})
+}
+
+// serverFrame constructs a short and unmasked frame, with custom FIN and RSV1 bits.
+func serverFrame(fin, rsv1 bool, op Opcode, payload []byte) []byte {
+	b := byte(op)
+	if fin {
+		b |= bit0
+	}
+	if rsv1 {
+		b |= bit1
+	}
+	return append([]byte{b, byte(len(payload))}, payload...)
+}
+
+// readCompressedFrame reads a short, unfragmented and masked frame from the
+// client, and decompresses its payload if the client set the RSV1 bit.
+func readCompressedFrame(t *testing.T, r io.Reader) (Opcode, bool, []byte) {
+	t.Helper()
+
+	b := make([]byte, 6)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, b[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	for i := range payload {
+		payload[i] ^= b[2+i%4]
+	}
+
+	compressed := b[0]&bit1 != 0
+	if compressed {
+		var err error
+		payload, err = io.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail))))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return Opcode(b[0] & 0x0f), compressed, payload
+}
+
+func TestDeflateMessages(t *testing.T) {
+	c, server := newPipeTestConn(t, "deflate", make(chan Message))
+	c.deflate = &deflate{level: flate.BestSpeed}
+	go c.readMessages()
+
+	w := newDeflateServerWriter(t)
+	var frames []byte
+
+	// A compressed message, and a second one which refers to the first one.
+	frames = append(frames, serverFrame(true, true, OpcodeText, w.compress(t, "hello hello hello"))...)
+	frames = append(frames, serverFrame(true, true, OpcodeText, w.compress(t, "hello hello hello"))...)
+
+	// A fragmented compressed message, with an interleaved control frame.
+	payload := w.compress(t, "fragmented binary message")
+	frames = append(frames, serverFrame(false, true, OpcodeBinary, payload[:3])...)
+	frames = append(frames, serverFrame(true, false, opcodePong, nil)...)
+	frames = append(frames, serverFrame(true, false, opcodeContinuation, payload[3:])...)
+
+	// Servers may also send uncompressed messages.
+	frames = append(frames, serverFrame(true, false, OpcodeText, []byte("plain"))...)
+
+	go func() {
+		_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = server.Write(frames)
+	}()
+
+	want := []Message{
+		{Opcode: OpcodeText, Data: []byte("hello hello hello")},
+		{Opcode: OpcodeText, Data: []byte("hello hello hello")},
+		{Opcode: OpcodeBinary, Data: []byte("fragmented binary message")},
+		{Opcode: OpcodeText, Data: []byte("plain")},
+	}
+	for i, w := range want {
+		select {
+		case got := <-c.IncomingMessages():
+			if got.Opcode != w.Opcode || !bytes.Equal(got.Data, w.Data) {
+				t.Errorf("message %d = %s %q, want %s %q", i, got.Opcode, got.Data, w.Opcode, w.Data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for message %d", i)
+		}
+	}
+
+	// Compressible outgoing messages are compressed, others aren't.
+	tests := []struct {
+		data           string
+		wantCompressed bool
+	}{
+		{data: strings.Repeat("compressible ", 100), wantCompressed: true},
+		{data: "x"},
+	}
+	for _, tt := range tests {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- <-c.SendTextMessage([]byte(tt.data))
+		}()
+
+		op, compressed, got := readCompressedFrame(t, server)
+		if err := <-errs; err != nil {
+			t.Fatalf("Conn.SendTextMessage() error = %v", err)
+		}
+		if op != OpcodeText || compressed != tt.wantCompressed || string(got) != tt.data {
+			t.Errorf("sent frame = %s %v %q, want %s %v %q", op, compressed, got, OpcodeText, tt.wantCompressed, tt.data)
+		}
+	}
+}
+
+func TestDeflateInvalidData(t *testing.T) {
+	c, server := newPipeTestConn(t, "deflate", make(chan Message))
+	c.deflate = &deflate{level: flate.BestSpeed}
+	go c.readMessages()
+
+	go func() {
+		_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = server.Write(serverFrame(true, true, OpcodeText, []byte{0xff, 0xff, 0xff}))
+	}()
+
+	op, _, payload := readCompressedFrame(t, server)
+	if op != opcodeClose || !bytes.HasPrefix(payload, closePayload(StatusInvalidData)) {
+		t.Errorf("close frame = %s %q, want prefix %q", op, payload, closePayload(StatusInvalidData))
+	}
+}
+
+func TestDialDeflate(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name: "declined",
+		},
+		{
+			name:     "accepted",
+			response: "permessage-deflate; client_no_context_takeover",
+			want:     true,
+		},
+		{
+			name:     "invalid_params",
+			response: "permessage-deflate; client_max_window_bits=9",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offered, frames := make(chan string, 1), make(chan []byte, 1)
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				offered <- r.Header.Get("Sec-WebSocket-Extensions")
+				conn, rw, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer conn.Close()
+
+				resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+					"Sec-WebSocket-Accept: BACScCJPNqyz+UBoqMH89VmURoA=\r\n"
+				if tt.response != "" {
+					resp += "Sec-WebSocket-Extensions: " + tt.response + "\r\n"
+				}
+				_, _ = rw.WriteString(resp + "\r\n")
+				_ = rw.Flush()
+
+				_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+				if op, payload := readPipeFrame(rw); op == opcodeClose {
+					frames <- payload
+				}
+			}))
+			defer s.Close()
+
+			c, err := Dial(t.Context(), s.URL, withTestNonceGen(), WithDeflate(flate.DefaultCompression))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := <-offered; got != deflateOffer {
+				t.Errorf("offered extensions = %q, want %q", got, deflateOffer)
+			}
+
+			if !tt.wantErr {
+				if got := c.deflate != nil; got != tt.want {
+					t.Errorf("Dial() negotiated deflate = %v, want %v", got, tt.want)
+				}
+				c.Close(StatusNormalClosure)
+				return
+			}
+
+			select {
+			case got := <-frames:
+				want := closePayload(StatusProtocolError)
+				if !bytes.HasPrefix(got, want) {
+					t.Errorf("close frame payload = %q, want prefix %q", got, want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for the close frame")
+			}
+		})
+	}
+}
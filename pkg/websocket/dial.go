@@ -2,18 +2,25 @@ package websocket
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"crypto/rand"
 	"crypto/sha1" //gosec:disable G505 // Required by the WebSocket protocol.
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/tzrikka/timpani/internal/logger"
+	"github.com/tzrikka/timpani/internal/redact"
 )
 
 type DialOpt func(*Conn)
@@ -48,16 +55,132 @@ func WithHTTPHeaders(hs http.Header) DialOpt {
 	}
 }
 
+// WithSubprotocols lets callers of [Dial] offer one or more application-level
+// [subprotocols] to the server, in order of preference. The server must select
+// exactly one of them, otherwise [Dial] fails, and the selected one is available
+// via [Conn.Subprotocol].
+//
+// [subprotocols]: https://datatracker.ietf.org/doc/html/rfc6455#section-1.9
+func WithSubprotocols(protos ...string) DialOpt {
+	return func(c *Conn) {
+		c.subprotocols = slices.Clone(protos)
+	}
+}
+
+// DefaultMaxMessageSize is the maximum length of incoming data messages, unless
+// callers of [Dial] override it with [WithMaxMessageSize]. It protects clients
+// from running out of memory due to frames with huge (declared) payload lengths.
+const DefaultMaxMessageSize = 16 << 20 // 16 MiB.
+
+// WithMaxMessageSize lets callers of [Dial] fail the connection with
+// [StatusMessageTooBig] if the server sends a data message which is longer
+// than n bytes, after defragmentation and decompression (see [WithDeflate]),
+// instead of the [DefaultMaxMessageSize]. Zero (or a negative value) means no
+// limit, which is appropriate only if the server is trusted to send reasonable
+// messages. The limit is enforced before reading each frame's payload, and the
+// resulting status code is available via [Conn.CloseStatus].
+func WithMaxMessageSize(n int64) DialOpt {
+	return func(c *Conn) {
+		c.maxMessageSize = max(n, 0)
+	}
+}
+
+// DefaultFrameSize is the maximum payload size of the fragments which
+// [Conn.SendTextMessageFrom] and [Conn.SendBinaryMessageFrom] send,
+// unless callers of [Dial] override it with [WithFrameSize].
+const DefaultFrameSize = 64 << 10 // 64 KiB.
+
+// WithFrameSize lets callers of [Dial] change the maximum payload size of the
+// fragments which [Conn.SendTextMessageFrom] and [Conn.SendBinaryMessageFrom]
+// send, instead of the [DefaultFrameSize]. Zero (or a negative value) means the
+// default. This doesn't affect other messages, which are never fragmented.
+func WithFrameSize(n int) DialOpt {
+	return func(c *Conn) {
+		c.frameSize = max(n, 0)
+	}
+}
+
+// DefaultBufferSize is the size of the read and write buffers of each [Conn]
+// (the same as [bufio.NewReader] and [bufio.NewWriter]), unless callers of
+// [Dial] override it with [WithReadBufferSize] or [WithWriteBufferSize].
+const DefaultBufferSize = 4 << 10 // 4 KiB.
+
+// WithReadBufferSize lets callers of [Dial] change the size of the connection's
+// read buffer, instead of the [DefaultBufferSize], e.g. to read high-throughput
+// streams of frames from the network in fewer (and larger) reads. Zero (or a
+// negative value) means the default.
+func WithReadBufferSize(n int) DialOpt {
+	return func(c *Conn) {
+		c.readBufferSize = max(n, 0)
+	}
+}
+
+// WithWriteBufferSize lets callers of [Dial] change the size of the connection's
+// write buffer, instead of the [DefaultBufferSize], e.g. so that each frame whose
+// payload fits in it is written to the network at once, along with its header.
+// Zero (or a negative value) means the default.
+func WithWriteBufferSize(n int) DialOpt {
+	return func(c *Conn) {
+		c.writeBufferSize = max(n, 0)
+	}
+}
+
+// WithUnsolicitedPongLimit lets callers of [Dial] fail the connection with
+// [StatusPolicyViolation] if the server sends more than n pong control frames
+// that don't respond to [Conn.Ping] calls within a minute. The limit is a rate,
+// not a lifetime total, because unsolicited pongs are a legitimate unidirectional
+// heartbeat, so occasional ones are always allowed, even in long-lived
+// connections. The default (0) means no limit.
+func WithUnsolicitedPongLimit(n int) DialOpt {
+	return func(c *Conn) {
+		c.pongLimit = int64(max(n, 0))
+	}
+}
+
+// WithKeepAlive lets callers of [Dial] send a ping control frame to the server after
+// each interval, to keep the connection alive, and to detect dead (e.g. half-open) TCP
+// connections, which would otherwise block reading forever: if the server doesn't respond
+// with a matching pong control frame within the timeout, the underlying connection is
+// closed immediately, and [Conn.CloseStatus] returns [StatusClosedAbnormally] with the
+// reason "ping timeout". A non-positive timeout is the same as the interval. The default
+// (a non-positive interval) disables this.
+func WithKeepAlive(interval, timeout time.Duration) DialOpt {
+	return func(c *Conn) {
+		c.pingInterval = max(interval, 0)
+		c.pingTimeout = timeout
+		if timeout <= 0 {
+			c.pingTimeout = c.pingInterval
+		}
+	}
+}
+
+// WithPingInterval is a shorthand for [WithKeepAlive], with the same interval and timeout.
+func WithPingInterval(d time.Duration) DialOpt {
+	return WithKeepAlive(d, d)
+}
+
 // Dial performs a [WebSocket handshake] to establish
 // a connection to the given URL ("ws://..." or "wss://").
 //
+// HTTP redirects are followed (up to [maxHandshakeRedirects]), with a new nonce
+// in each handshake attempt, as long as they don't downgrade a secure connection.
+// Credentials in request headers aren't forwarded to a different host.
+// The connection's URL (see [Conn.DebugInfo]) is the final one.
+//
 // [WebSocket handshake]: https://datatracker.ietf.org/doc/html/rfc6455#section-4.1
 func Dial(ctx context.Context, wsURL string, opts ...DialOpt) (*Conn, error) {
 	// Initialize optional configuration details and internal helpers.
+	id := newConnID()
 	c := &Conn{
-		logger:   logger.FromContext(ctx),
-		headers:  http.Header{},
-		nonceGen: rand.Reader,
+		id:         id,
+		url:        redactedURL(wsURL),
+		logger:     logger.FromContext(ctx).With(slog.String("conn_id", id)),
+		headers:    http.Header{},
+		nonceGen:   rand.Reader,
+		closeGrace: closeGracePeriod,
+
+		maxMessageSize: DefaultMaxMessageSize,
+		frameSize:      DefaultFrameSize,
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -65,24 +188,26 @@ func Dial(ctx context.Context, wsURL string, opts ...DialOpt) (*Conn, error) {
 	if c.client == nil {
 		c.client = defaultClient
 	} else {
+		c.checkRedirect = c.client.CheckRedirect
 		c.client = adjustHTTPClient(*c.client)
 	}
 
-	// Send handshake request & check response.
-	nonce, err := generateNonce(c.nonceGen)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate nonce for WebSocket handshake: %w", err)
-	}
-	req, err := c.handshakeRequest(ctx, wsURL, nonce)
+	// Send handshake request & check response, following redirects.
+	resp, req, nonce, err := c.handshake(ctx, wsURL)
 	if err != nil {
 		return nil, err
 	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send WebSocket handshake request: %w", err)
+	err = checkHandshakeResponse(resp, req.Header, nonce)
+	if err == nil {
+		err = c.negotiateDeflate(resp.Header)
 	}
-	if err = checkHandshakeResponse(resp, nonce); err != nil {
+	if err != nil {
+		switch {
+		case errors.As(err, new(subprotocolError)):
+			c.failHandshake(resp, StatusProtocolError, "unexpected subprotocol")
+		case errors.As(err, new(extensionError)):
+			c.failHandshake(resp, StatusProtocolError, "unexpected extension")
+		}
 		_ = resp.Body.Close()
 		return nil, err
 	}
@@ -90,43 +215,184 @@ func Dial(ctx context.Context, wsURL string, opts ...DialOpt) (*Conn, error) {
 	// Post-handshake connection state initializations.
 	rwc, ok := resp.Body.(io.ReadWriteCloser)
 	if !ok {
+		_ = resp.Body.Close()
 		return nil, fmt.Errorf("WebSocket handshake response body type: got %T, want io.ReadWriteCloser", resp.Body)
 	}
 
-	c.bufio = bufio.NewReadWriter(bufio.NewReader(rwc), bufio.NewWriter(rwc))
+	c.bufio = c.newBufio(rwc, rwc)
 	c.reader = make(chan Message)
-	c.writer = make(chan internalMessage)
 	c.closer = rwc
+	c.done = make(chan struct{})
+	c.dialed = time.Now().UTC()
+	c.subprotocol = strings.TrimSpace(resp.Header.Get("Sec-WebSocket-Protocol"))
 
 	go c.readMessages()
-	go c.writeMessages()
+	if c.pingInterval > 0 {
+		go c.keepAlive()
+	}
 
-	c.logger.Debug("WebSocket connection initialized")
+	c.logger.Debug("WebSocket connection initialized", slog.String("url", c.url))
 	return c, nil
 }
 
-// adjustHTTPClient returns a modified shallow copy of the given [http.Client].
-func adjustHTTPClient(c http.Client) *http.Client {
-	// Wrap the HTTP client's CheckRedirect function, to convert
-	// ws/wss URL schemes to http/https, respectively.
-	origCheckRedirect := c.CheckRedirect
-	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		switch req.URL.Scheme {
-		case "ws":
-			req.URL.Scheme = "http"
-		case "wss":
-			req.URL.Scheme = "https"
+// newConnID generates a short random ID for a [Conn], to
+// attribute log records to specific concurrent connections.
+func newConnID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b) // Never returns an error.
+	return hex.EncodeToString(b)
+}
+
+// redactedURL returns only the scheme and host of the given URL, because its path
+// and query parameters may contain secrets (e.g. Slack Socket Mode tickets).
+func redactedURL(wsURL string) string {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return "invalid URL"
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+}
+
+// maxHandshakeRedirects is the maximum number of HTTP redirects that [Dial] follows.
+const maxHandshakeRedirects = 5
+
+// handshake sends WebSocket handshake requests, starting with the given URL, and follows
+// HTTP redirects manually, instead of the HTTP client, to generate a new nonce for each
+// request, and to enforce security restrictions. It returns the last response, along with
+// the request and nonce that it responds to. The caller must close the response body.
+func (c *Conn) handshake(ctx context.Context, wsURL string) (*http.Response, *http.Request, string, error) {
+	var via []*http.Request
+	for {
+		nonce, err := generateNonce(c.nonceGen)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to generate nonce for WebSocket handshake: %w", err)
+		}
+		req, err := c.handshakeRequest(ctx, wsURL, nonce)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		if len(via) > 0 {
+			if err := c.redirectRequest(req, via); err != nil {
+				return nil, nil, "", err
+			}
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to send WebSocket handshake request: %w", redact.Error(err))
+		}
+		if resp.ProtoMajor != 1 {
+			_ = resp.Body.Close()
+			return nil, nil, "", http2Error(resp)
+		}
+
+		if !isRedirect(resp.StatusCode) {
+			c.url = redactedURL(wsURL)
+			return resp, req, nonce, nil
+		}
+
+		_ = resp.Body.Close()
+		via = append(via, req)
+		if len(via) > maxHandshakeRedirects {
+			return nil, nil, "", fmt.Errorf("WebSocket handshake stopped after %d redirects", maxHandshakeRedirects)
+		}
+
+		loc := resp.Header.Get("Location")
+		next, err := req.URL.Parse(loc)
+		if loc == "" || err != nil {
+			return nil, nil, "", fmt.Errorf("WebSocket handshake redirect (%d) without a valid Location header", resp.StatusCode)
 		}
 
-		if origCheckRedirect != nil {
-			return origCheckRedirect(req, via)
+		c.logger.Debug("following WebSocket handshake redirect", slog.Int("status", resp.StatusCode),
+			slog.String("from", redactedURL(req.URL.String())), slog.String("to", redactedURL(next.String())))
+		wsURL = next.String()
+	}
+}
+
+// redirectRequest enforces security restrictions on a WebSocket handshake request which
+// follows an HTTP redirect: it refuses to downgrade a secure connection, and it removes
+// credentials from the request if it's sent to a different host than the original one,
+// including any header which the caller specified that may contain them (e.g. tokens
+// and API keys, see [redact.SensitiveHeader]).
+// It also applies the redirect policy of the caller's custom [http.Client], if there is one.
+func (c *Conn) redirectRequest(req *http.Request, via []*http.Request) error {
+	prev := via[len(via)-1]
+	if prev.URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("WebSocket handshake redirect refused: downgrade from %s to %s",
+			redactedURL(prev.URL.String()), redactedURL(req.URL.String()))
+	}
+
+	if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+		for k := range req.Header {
+			if redact.SensitiveHeader(k) {
+				req.Header.Del(k)
+			}
 		}
-		return nil
+		req.URL.User = nil
+	}
+
+	if c.checkRedirect != nil {
+		return c.checkRedirect(req, via)
 	}
+	return nil
+}
 
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// adjustHTTPClient returns a modified shallow copy of the given [http.Client].
+func adjustHTTPClient(c http.Client) *http.Client {
+	// Redirects are followed by [Conn.handshake] instead of the HTTP client.
+	c.CheckRedirect = func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	c.Transport = http1Transport(c.Transport)
 	return &c
 }
 
+// http1Transport returns a modified clone of the given [http.Transport] (or
+// [http.DefaultTransport] if it's nil), which never negotiates HTTP/2, because
+// WebSocket handshakes require HTTP/1.1 (see RFC 8441 for the alternative).
+// Other [http.RoundTripper] implementations are returned as-is.
+func http1Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+
+	t = t.Clone()
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	t.Protocols = new(http.Protocols)
+	t.Protocols.SetHTTP1(true)
+	if t.TLSClientConfig != nil {
+		t.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(t.TLSClientConfig.NextProtos), func(p string) bool {
+			return p == "h2"
+		})
+	}
+
+	return t
+}
+
+// http2Error explains why a WebSocket handshake which was
+// sent over HTTP/2 (by a custom [http.RoundTripper]) fails.
+func http2Error(resp *http.Response) error {
+	return fmt.Errorf("WebSocket handshake requires HTTP/1.1, but the HTTP client used %s "+
+		"(a custom HTTP transport must not negotiate HTTP/2, e.g. via ALPN)", resp.Proto)
+}
+
 // generateNonce generates a nonce consisting of a randomly
 // selected 16-byte value that has been Base64-encoded. The
 // nonce MUST be selected randomly for each connection.
@@ -143,7 +409,7 @@ func generateNonce(r io.Reader) (string, error) {
 func (c *Conn) handshakeRequest(ctx context.Context, wsURL, nonce string) (*http.Request, error) {
 	u, err := url.Parse(wsURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse WebSocket URL: %w", err)
+		return nil, fmt.Errorf("failed to parse WebSocket URL: %w", redact.Error(err))
 	}
 
 	switch u.Scheme {
@@ -159,7 +425,7 @@ func (c *Conn) handshakeRequest(ctx context.Context, wsURL, nonce string) (*http
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create WebSocket handshake request: %w", err)
+		return nil, fmt.Errorf("failed to create WebSocket handshake request: %w", redact.Error(err))
 	}
 
 	req.Header = c.headers.Clone()
@@ -167,14 +433,22 @@ func (c *Conn) handshakeRequest(ctx context.Context, wsURL, nonce string) (*http
 	req.Header.Set("Connection", "Upgrade")
 	req.Header.Set("Sec-WebSocket-Key", nonce)
 	req.Header.Set("Sec-WebSocket-Version", "13")
-	// Sec-WebSocket-Extensions, Sec-WebSocket-Protocol.
+	if len(c.subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(c.subprotocols, ", "))
+	}
+	// Sec-WebSocket-Extensions are not offered by default, but callers
+	// may add them with [WithDeflate] or [WithHTTPHeader].
+	if c.deflate != nil {
+		req.Header.Add("Sec-WebSocket-Extensions", deflateOffer)
+	}
 
 	return req, nil
 }
 
 // checkHandshakeResponse checks the server response details in
-// https://datatracker.ietf.org/doc/html/rfc6455#section-4.2.2.
-func checkHandshakeResponse(resp *http.Response, nonce string) error {
+// https://datatracker.ietf.org/doc/html/rfc6455#section-4.2.2,
+// against the headers of the client's handshake request.
+func checkHandshakeResponse(resp *http.Response, req http.Header, nonce string) error {
 	if resp.StatusCode != http.StatusSwitchingProtocols {
 		msg := "WebSocket handshake response status: got %d, want %d"
 		msg = fmt.Sprintf(msg, resp.StatusCode, http.StatusSwitchingProtocols)
@@ -201,11 +475,90 @@ func checkHandshakeResponse(resp *http.Response, nonce string) error {
 		return err
 	}
 
-	// Sec-WebSocket-Protocol, Sec-WebSocket-Extensions.
+	// The client must fail the connection if the server selected
+	// extensions or a subprotocol that the client didn't offer.
+	if err := checkOfferedValues(resp.Header, req, "Sec-WebSocket-Extensions"); err != nil {
+		return extensionError{err}
+	}
+
+	return checkSubprotocol(resp.Header, req)
+}
+
+// subprotocolError is returned by [checkSubprotocol], so [Dial] can
+// fail the connection which the server already considers to be open.
+type subprotocolError struct {
+	error
+}
+
+// checkSubprotocol checks that the server selected exactly one of the subprotocols
+// that the client offered, if it offered any, or none if the client didn't offer any.
+func checkSubprotocol(resp, req http.Header) error {
+	const key = "Sec-WebSocket-Protocol"
+	if err := checkOfferedValues(resp, req, key); err != nil {
+		return subprotocolError{err}
+	}
+
+	ps := headerTokens(resp, key)
+	if len(ps) > 1 {
+		return subprotocolError{fmt.Errorf("WebSocket handshake response header %q: got %q, want at most 1 subprotocol", key, ps)}
+	}
+	if offered := headerTokens(req, key); len(offered) > 0 && len(ps) == 0 {
+		return subprotocolError{fmt.Errorf("WebSocket handshake response header %q: missing, want one of %q", key, offered)}
+	}
+
+	return nil
+}
+
+// newBufio initializes the buffered reader and writer of a [Conn] after the handshake,
+// with the sizes which the caller of [Dial] specified, or the [DefaultBufferSize].
+func (c *Conn) newBufio(r io.Reader, w io.Writer) *bufio.ReadWriter {
+	br := bufio.NewReaderSize(r, cmp.Or(c.readBufferSize, DefaultBufferSize))
+	bw := bufio.NewWriterSize(w, cmp.Or(c.writeBufferSize, DefaultBufferSize))
+	return bufio.NewReadWriter(br, bw)
+}
+
+// failHandshake sends a close control frame to a server which completed the WebSocket
+// handshake (from its point of view), when the client rejects the server's response.
+func (c *Conn) failHandshake(resp *http.Response, status StatusCode, reason string) {
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return
+	}
+
+	c.bufio = c.newBufio(rwc, rwc)
+	c.closer = rwc
+	c.closeReceived.Store(true) // Don't wait for the server's close control frame.
+	c.sendCloseControlFrame(status, reason)
+}
 
+// checkOfferedValues checks that all the values of a handshake response header
+// (extension names or a subprotocol) were offered in the same request header, as
+// required in https://datatracker.ietf.org/doc/html/rfc6455#section-4.1 (items 5-6).
+func checkOfferedValues(resp, req http.Header, key string) error {
+	offered := headerTokens(req, key)
+	for _, got := range headerTokens(resp, key) {
+		if !slices.ContainsFunc(offered, func(s string) bool { return strings.EqualFold(s, got) }) {
+			return fmt.Errorf("WebSocket handshake response header %q: got %q, want one of %q", key, got, offered)
+		}
+	}
 	return nil
 }
 
+// headerTokens splits comma-separated header values, without extension parameters
+// (see https://datatracker.ietf.org/doc/html/rfc6455#section-9.1).
+func headerTokens(h http.Header, key string) []string {
+	var tokens []string
+	for _, v := range h.Values(key) {
+		for t := range strings.SplitSeq(v, ",") {
+			t, _, _ = strings.Cut(t, ";")
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
 func checkHTTPHeader(headers http.Header, key, want string) error {
 	if got := headers.Get(key); !strings.EqualFold(got, want) {
 		return fmt.Errorf("WebSocket handshake response header %q: got %q, want %q", key, got, want)
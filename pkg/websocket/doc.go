@@ -13,18 +13,26 @@
 //  2. Preemptively switch connections before each anticipated
 //     disconnection, to prevent downtime during reconnections
 //  3. Fast detection and recovery from unexpected disconnections
+//     (including half-open connections, see [WithKeepAlive]), with
+//     exponential backoff during outages (see [WithReconnectBackoff])
 //  4. Idiomatic, minimalistic, and modern code patterns
 //
 // Note A: optimization 1 relies on Go channels to dispatch and
-// potentially fan-out messages efficiently and reliably.
+// potentially fan-out messages efficiently and reliably (see
+// [Client.Subscribe]).
 //
 // Note B: optimization 2 requires careful balancing of optimization 1
 // with ensuring state isolation, correct and efficient garbage collection,
 // and ensuring that users of this package do not receive duplicate copies
-// of messages while a client temporarily has an extra connection.
+// of messages while a client temporarily has an extra connection
+// (see [WithMessageKey]). See [Client] for the resulting message
+// ordering guarantees.
 //
-// Note C: WebSocket [extensions] and [subprotocols] are not supported yet.
+// Note C: the only supported WebSocket [extension] is [permessage-deflate]
+// (see [WithDeflate]). Application-level [subprotocols] are supported
+// too (see [WithSubprotocols]).
 //
-// [extensions]: https://www.iana.org/assignments/websocket/websocket.xhtml#extension-name
+// [extension]: https://www.iana.org/assignments/websocket/websocket.xhtml#extension-name
+// [permessage-deflate]: https://datatracker.ietf.org/doc/html/rfc7692
 // [subprotocols]: https://www.iana.org/assignments/websocket/websocket.xhtml#subprotocol-name
 package websocket
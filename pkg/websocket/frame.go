@@ -160,11 +160,19 @@ func (c *Conn) checkFrameHeader(h frameHeader, msgType Opcode) (string, error) {
 	// meanings for non-zero values. If a nonzero value is received and none of
 	// the negotiated extensions defines the meaning of such a nonzero value,
 	// the receiving endpoint MUST _Fail the WebSocket Connection_".
-	if h.rsv[0] || h.rsv[1] || h.rsv[2] {
+	if h.rsv[1] || h.rsv[2] || (h.rsv[0] && c.deflate == nil) {
 		reason := "invalid reserved bits"
 		return reason, fmt.Errorf("WebSocket server sent %s", reason)
 	}
 
+	// "permessage-deflate" uses RSV1 as the "Per-Message Compressed" bit, which
+	// is valid only in the first frame of a data message, as defined in
+	// https://datatracker.ietf.org/doc/html/rfc7692#section-6.
+	if h.rsv[0] && h.opcode != OpcodeText && h.opcode != OpcodeBinary {
+		reason := "invalid compressed bit"
+		return reason, fmt.Errorf("WebSocket server sent %s in %s frame", reason, h.opcode)
+	}
+
 	// "If an unknown opcode is received, the receiving
 	// endpoint MUST _Fail the WebSocket Connection_".
 	if (h.opcode > 2 && h.opcode < 8) || h.opcode > 10 {
@@ -208,7 +216,9 @@ func (c *Conn) checkFrameHeader(h frameHeader, msgType Opcode) (string, error) {
 	return "", nil
 }
 
-// writeFrame is optimized to send a single, unfragmented, masked frame.
+// writeFrame is optimized to send a single masked frame. It's unfragmented unless
+// fin is false (see [Conn.sendFrom]). The compressed flag sets the RSV1 bit, see
+// [deflate.compress].
 //
 // Do not call this function directly, call [sendControlFrame] instead,
 // to ensure we always send one frame at a time!
@@ -217,9 +227,16 @@ func (c *Conn) checkFrameHeader(h frameHeader, msgType Opcode) (string, error) {
 //   - Base framing protocol: https://datatracker.ietf.org/doc/html/rfc6455#section-5.2
 //   - Client-to-server masking: https://datatracker.ietf.org/doc/html/rfc6455#section-5.3
 //   - Sending data: https://datatracker.ietf.org/doc/html/rfc6455#section-6.1
-func (c *Conn) writeFrame(op Opcode, payload []byte) error {
-	// Construct the header (automatically set the FIN and MASKED bits).
-	if err := c.bufio.WriteByte(bit0 | byte(op)); err != nil { //gosec:disable G115 // Constrained op value cannot overflow.
+func (c *Conn) writeFrame(op Opcode, payload []byte, fin, compressed bool) error {
+	// Construct the header (automatically set the MASKED bit).
+	b := byte(op) //gosec:disable G115 // Constrained op value cannot overflow.
+	if fin {
+		b |= bit0
+	}
+	if compressed {
+		b |= bit1
+	}
+	if err := c.bufio.WriteByte(b); err != nil {
 		return fmt.Errorf("failed to write WebSocket control frame header: %w", err)
 	}
 
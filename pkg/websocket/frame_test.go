@@ -74,7 +74,7 @@ func TestConnWriteFrame(t *testing.T) {
 
 	payload := []byte("hello")
 	origPayload := []byte("hello")
-	if err := c.writeFrame(OpcodeText, payload); err != nil {
+	if err := c.writeFrame(OpcodeText, payload, true, false); err != nil {
 		t.Fatalf("Conn.writeFrame() error = %v", err)
 	}
 
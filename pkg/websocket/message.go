@@ -2,10 +2,14 @@ package websocket
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"log/slog"
+	"time"
 	"unicode/utf8"
+
+	"github.com/tzrikka/timpani/internal/logger"
 )
 
 // readMessage reads incoming frames from the server, responds to
@@ -24,42 +28,62 @@ import (
 //   - Receiving data: https://datatracker.ietf.org/doc/html/rfc6455#section-6.2
 //   - Closing the connection: https://datatracker.ietf.org/doc/html/rfc6455#section-7
 //   - Handling Errors in UTF-8-Encoded Data: https://datatracker.ietf.org/doc/html/rfc6455#section-8.1
+//   - Compression extension: https://datatracker.ietf.org/doc/html/rfc7692#section-6
 func (c *Conn) readMessage() *internalMessage {
 	var msg bytes.Buffer
 	var op Opcode
+	var compressed bool
 
 	for {
 		h, err := c.readFrameHeader()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				c.logger.Debug("WebSocket connection closed")
-				c.closeReceived = true
+				c.recordCloseStatus(StatusClosedAbnormally, "")
+				c.closeReceived.Store(true)
+				c.closeSentMu.Lock()
 				c.closeSent = true
+				c.closeSentMu.Unlock()
+				return nil
+			}
+			if c.isCloseSent() {
+				// The grace period after sending our close control frame is over.
+				c.logger.Debug("WebSocket connection closed without receiving close control frame", slog.Any("error", err))
 				return nil
 			}
 			c.logger.Error("failed to read WebSocket frame header", slog.Any("error", err))
-			c.sendCloseControlFrame(StatusInternalError, "frame header reading error")
+			c.fail(StatusInternalError, "frame header reading error")
 			return nil
 		}
 
-		c.logger.Debug("received WebSocket frame", slog.Bool("fin", h.fin),
+		c.logger.Log(context.Background(), logger.LevelTrace, "received WebSocket frame", slog.Bool("fin", h.fin),
 			slog.String("opcode", h.opcode.String()), slog.Any("length", h.payloadLength))
 
+		// Check the frame header, and enforce the maximum message size, before reading
+		// (and allocating memory for) the payload, whose length the server controls.
+		if reason, err := c.checkFrameHeader(h, op); err != nil {
+			c.logger.Error("protocol error due to invalid frame", slog.Any("error", err))
+			c.fail(StatusProtocolError, reason)
+			return nil
+		}
+		if h.opcode <= OpcodeBinary && c.messageTooBig(msg.Len(), h.payloadLength) {
+			c.logger.Error("WebSocket data message exceeds maximum size", slog.Int("buffered", msg.Len()),
+				slog.Any("frame_length", h.payloadLength), slog.Int64("max_message_size", c.maxMessageSize))
+			c.fail(StatusMessageTooBig, "message too big")
+			return nil
+		}
+
 		var data []byte
 		if h.payloadLength > 0 {
 			data = make([]byte, h.payloadLength)
 			if _, err := io.ReadFull(c.bufio, data); err != nil {
 				c.logger.Error("failed to read WebSocket frame payload", slog.Any("error", err))
-				c.sendCloseControlFrame(StatusInternalError, "frame payload reading error")
+				c.fail(StatusInternalError, "frame payload reading error")
 				return nil
 			}
 		}
-
-		if reason, err := c.checkFrameHeader(h, op); err != nil {
-			c.logger.Error("protocol error due to invalid frame", slog.Any("error", err))
-			c.sendCloseControlFrame(StatusProtocolError, reason)
-			return nil
-		}
+		c.framesIn.Add(1)
+		c.bytesIn.Add(int64(len(data)))
 
 		switch h.opcode {
 		// "A fragmented message consists of a single frame with the FIN bit
@@ -69,11 +93,12 @@ func (c *Conn) readMessage() *internalMessage {
 		case opcodeContinuation, OpcodeText, OpcodeBinary:
 			if h.opcode != opcodeContinuation {
 				op = h.opcode
+				compressed = h.rsv[0]
 			}
 			if h.payloadLength > 0 {
 				if _, err := msg.Write(data); err != nil {
 					c.logger.Error("failed to store WebSocket data frame payload", slog.Any("error", err))
-					c.sendCloseControlFrame(StatusInternalError, "data frame payload storing error")
+					c.fail(StatusInternalError, "data frame payload storing error")
 					return nil
 				}
 			}
@@ -81,36 +106,71 @@ func (c *Conn) readMessage() *internalMessage {
 		// "If an endpoint receives a Close frame and did not previously send
 		// a Close frame, the endpoint MUST send a Close frame in response".
 		case opcodeClose:
-			c.closeReceived = true
 			status, reason := c.parseClosePayload(data)
+			c.closeReceived.Store(true) // After parsing, see [Conn.CloseStatus].
 			c.sendCloseControlFrame(status, reason)
 			return nil // Not an error, but we no longer need to receive new frames.
 
 		// "An endpoint MUST be capable of handling control
 		// frames in the middle of a fragmented message".
 		case opcodePing:
+			c.pingsIn.Add(1)
+			c.lastRead.Store(time.Now().UnixNano())
 			if err := <-c.sendControlFrame(opcodePong, data); err != nil {
 				c.logger.Error("failed to send WebSocket pong control frame",
 					slog.Any("error", err), slog.Any("payload", data))
+			} else {
+				c.pongsOut.Add(1)
 			}
 
 		case opcodePong:
-			// No need to handle "Pong" control frames, since this
-			// client doesn't send unsolicited "Ping" control frames.
+			c.lastRead.Store(time.Now().UnixNano())
+			if !c.handlePong(data) {
+				c.logger.Error("policy violation due to too many unsolicited pong control frames",
+					slog.Int64("unsolicited_pongs", c.unsolicitedPongs.Load()))
+				c.fail(StatusPolicyViolation, "too many unsolicited pongs")
+				return nil
+			}
 		}
 
 		if h.fin && h.opcode <= OpcodeBinary {
-			return c.finalizeMessage(op, msg.Bytes())
+			data := msg.Bytes()
+			if compressed {
+				if data, err = c.deflate.decompress(data, c.maxMessageSize); err != nil {
+					if errors.Is(err, errMessageTooBig) {
+						c.logger.Error("decompressed WebSocket data message exceeds maximum size",
+							slog.Int64("max_message_size", c.maxMessageSize))
+						c.fail(StatusMessageTooBig, "message too big")
+						return nil
+					}
+					c.logger.Error("failed to decompress WebSocket data message", slog.Any("error", err))
+					c.fail(StatusInvalidData, "invalid compressed data")
+					return nil
+				}
+			}
+			return c.finalizeMessage(op, data)
 		}
 	}
 }
 
+// messageTooBig checks whether a data frame with the given payload length exceeds the
+// connection's maximum message size (see [WithMaxMessageSize]), when it's appended
+// to the given number of bytes that were already buffered from previous fragments.
+func (c *Conn) messageTooBig(buffered int, payloadLength uint64) bool {
+	if c.maxMessageSize <= 0 {
+		return false
+	}
+	// Subtraction instead of addition, to avoid overflows. The buffer's length
+	// never exceeds the maximum, because this function is called before each write.
+	return payloadLength > uint64(c.maxMessageSize)-uint64(buffered) //gosec:disable G115 // Both values are non-negative.
+}
+
 func (c *Conn) finalizeMessage(op Opcode, data []byte) *internalMessage {
 	if data == nil {
 		data = []byte{}
 	}
 
-	c.logger.Debug("finished receiving WebSocket data message",
+	c.logger.Log(context.Background(), logger.LevelTrace, "finished receiving WebSocket data message",
 		slog.String("opcode", op.String()), slog.Int("length", len(data)))
 
 	// "When an endpoint is to interpret a byte stream as UTF-8 but finds
@@ -119,7 +179,7 @@ func (c *Conn) finalizeMessage(op Opcode, data []byte) *internalMessage {
 	// during the opening handshake and during subsequent data exchange".
 	if op == OpcodeText && len(data) > 0 && !utf8.Valid(data) {
 		c.logger.Error("protocol error due to invalid UTF-8 text")
-		c.sendCloseControlFrame(StatusInvalidData, "invalid UTF-8 text")
+		c.fail(StatusInvalidData, "invalid UTF-8 text")
 		return nil
 	}
 
@@ -128,46 +188,64 @@ func (c *Conn) finalizeMessage(op Opcode, data []byte) *internalMessage {
 
 // SendTextMessage sends a [UTF-8 text] message to the server.
 //
-// This is done asynchronously, to manage [isolation or safe multiplexing]
-// of multiple concurrent calls, including interleaved control frames.
-// Despite that, this function enables the caller to block and/or
-// handle errors, with the returned channel.
+// Concurrent calls are serialized, to ensure [isolation or safe multiplexing]
+// of messages, including interleaved control frames. This function blocks
+// until the message is sent, and then returns a closed channel which
+// contains the error, if there is one (e.g. [ErrClosing] after [Conn.Close]).
 //
 // [UTF-8 text]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.6
 // [isolation or safe multiplexing]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.4
 func (c *Conn) SendTextMessage(data []byte) <-chan error {
-	err := make(chan error)
-	c.writer <- internalMessage{Opcode: OpcodeText, Data: data, err: err}
-	return err
+	return c.send(OpcodeText, data)
 }
 
 // SendBinaryMessage sends a [binary] message to the server.
 //
-// This is done asynchronously, to manage [isolation or safe multiplexing]
-// of multiple concurrent calls, including interleaved control frames.
-// Despite that, this function enables the caller to block and/or
-// handle errors, with the returned channel.
+// Concurrent calls are serialized, to ensure [isolation or safe multiplexing]
+// of messages, including interleaved control frames. This function blocks
+// until the message is sent, and then returns a closed channel which
+// contains the error, if there is one (e.g. [ErrClosing] after [Conn.Close]).
 //
 // [binary]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.6
 // [isolation or safe multiplexing]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.4
 func (c *Conn) SendBinaryMessage(data []byte) <-chan error {
-	err := make(chan error)
-	c.writer <- internalMessage{Opcode: OpcodeBinary, Data: data, err: err}
-	return err
+	return c.send(OpcodeBinary, data)
+}
+
+// SendTextMessageFrom is like [Conn.SendTextMessage], but it reads the message from r
+// until [io.EOF], and sends it in fragments (see [WithFrameSize]), instead of requiring
+// the caller to buffer the entire message in memory. The message isn't compressed,
+// even if the "permessage-deflate" extension was negotiated (see [WithDeflate]).
+//
+// Other data messages wait until this one is sent, but control frames don't. If r
+// fails after the first fragment was sent, the connection is closed, because the
+// message can't be completed.
+func (c *Conn) SendTextMessageFrom(r io.Reader) <-chan error {
+	return c.sendFrom(OpcodeText, r)
+}
+
+// SendBinaryMessageFrom is like [Conn.SendBinaryMessage], but it reads the message from r
+// until [io.EOF], and sends it in fragments (see [WithFrameSize]), instead of requiring
+// the caller to buffer the entire message in memory. The message isn't compressed,
+// even if the "permessage-deflate" extension was negotiated (see [WithDeflate]).
+//
+// Other data messages wait until this one is sent, but control frames don't. If r
+// fails after the first fragment was sent, the connection is closed, because the
+// message can't be completed.
+func (c *Conn) SendBinaryMessageFrom(r io.Reader) <-chan error {
+	return c.sendFrom(OpcodeBinary, r)
 }
 
 // sendControlFrame sends a [WebSocket control frame] to the server.
 //
-// This is done asynchronously, to manage [isolation or safe multiplexing]
-// of multiple concurrent calls, including interleaved control frames.
-// Despite that, this function enables the caller to block and/or
-// handle errors, with the returned channel.
+// Concurrent calls are serialized, to ensure [isolation or safe multiplexing]
+// of messages, including interleaved control frames. This function blocks
+// until the message is sent, and then returns a closed channel which
+// contains the error, if there is one.
 //
 // Use this function instead of calling [writeFrame] directly!
 //
 // [WebSocket control frame]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.5
 func (c *Conn) sendControlFrame(op Opcode, payload []byte) <-chan error {
-	err := make(chan error)
-	c.writer <- internalMessage{Opcode: op, Data: payload, err: err}
-	return err
+	return c.send(op, payload)
 }
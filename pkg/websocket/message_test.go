@@ -3,19 +3,28 @@ package websocket
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"io"
 	"log/slog"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/tzrikka/timpani/pkg/websocket/websockettest"
 )
 
 type benchmark struct {
-	name      string
-	msgLen    int
-	bufLen    int
-	frameLens []int
-	frames    int
+	name           string
+	msgLen         int
+	bufLen         int
+	frameLens      []int
+	frames         int
+	readBufferSize int // 0 = [DefaultBufferSize].
 }
 
 func BenchmarkReadMessage(b *testing.B) {
@@ -62,6 +71,16 @@ func BenchmarkReadMessage(b *testing.B) {
 			frameLens: []int{len64bits, 65536},
 			frames:    1,
 		},
+		{
+			// Same allocations as "one_64k_frame": payloads are read into their
+			// own slices, so the read buffer size affects only the number of reads.
+			name:           "one_64k_frame_64k_buffer",
+			msgLen:         65536,
+			bufLen:         2 + 8 + 65536,
+			frameLens:      []int{len64bits, 65536},
+			frames:         1,
+			readBufferSize: 64 << 10,
+		},
 		{
 			name:      "one_128k_frame",
 			msgLen:    131072,
@@ -92,13 +111,19 @@ func BenchmarkReadMessage(b *testing.B) {
 		},
 	}
 
-	c := &Conn{logger: slog.Default()}
-
 	for _, bb := range benchmarks {
 		b.Run(bb.name, func(b *testing.B) {
 			f := constructBenchmarkFrame(b, bb)
+			r := bytes.NewReader(f)
+
+			// Reuse the buffered reader, like a real connection does, so that
+			// only the allocations of reading each message are measured.
+			c := &Conn{logger: slog.Default(), readBufferSize: bb.readBufferSize}
+			c.bufio = c.newBufio(r, nil)
+
 			for b.Loop() {
-				c.bufio = bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(f)), nil)
+				r.Reset(f)
+				c.bufio.Reader.Reset(r)
 				msg := c.readMessage()
 				if n := len(msg.Data); n != bb.msgLen {
 					b.Fatalf("len(msg): got %d, want %d", n, bb.msgLen)
@@ -108,6 +133,318 @@ func BenchmarkReadMessage(b *testing.B) {
 	}
 }
 
+func TestReadMessageMaxSize(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 125)
+
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write(bytes.Repeat(data, 2))
+	_ = fw.Flush()
+
+	tests := []struct {
+		name           string
+		maxMessageSize int64
+		deflate        bool
+		frames         [][]byte
+		wantLen        int
+		wantClose      StatusCode
+	}{
+		{
+			name:    "unlimited",
+			frames:  [][]byte{serverFrame(true, false, OpcodeText, data)},
+			wantLen: 125,
+		},
+		{
+			name:           "at_limit",
+			maxMessageSize: 125,
+			frames:         [][]byte{serverFrame(true, false, OpcodeText, data)},
+			wantLen:        125,
+		},
+		{
+			name:           "over_limit",
+			maxMessageSize: 124,
+			frames:         [][]byte{serverFrame(true, false, OpcodeText, data)},
+			wantClose:      StatusMessageTooBig,
+		},
+		{
+			name:           "fragments_at_limit",
+			maxMessageSize: 100,
+			frames: [][]byte{
+				serverFrame(false, false, OpcodeBinary, data[:50]),
+				serverFrame(true, false, opcodeContinuation, data[:50]),
+			},
+			wantLen: 100,
+		},
+		{
+			name:           "fragments_over_limit",
+			maxMessageSize: 100,
+			frames: [][]byte{
+				serverFrame(false, false, OpcodeBinary, data[:50]),
+				serverFrame(false, false, opcodeContinuation, data[:50]),
+				serverFrame(true, false, opcodeContinuation, data[:1]),
+			},
+			wantClose: StatusMessageTooBig,
+		},
+		{
+			name:           "interleaved_control_frame",
+			maxMessageSize: 10,
+			frames: [][]byte{
+				serverFrame(false, false, OpcodeBinary, data[:5]),
+				serverFrame(true, false, opcodePong, data[:20]),
+				serverFrame(true, false, opcodeContinuation, data[:5]),
+			},
+			wantLen: 10,
+		},
+		{
+			name:           "huge_frame_length",
+			maxMessageSize: DefaultMaxMessageSize,
+			frames:         [][]byte{{0x82, 0x7f, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}}, // 1 TiB, without payload.
+			wantClose:      StatusMessageTooBig,
+		},
+		{
+			name:           "huge_fragment_length",
+			maxMessageSize: DefaultMaxMessageSize,
+			frames: [][]byte{
+				serverFrame(false, false, OpcodeBinary, data),
+				{0x00, 0x7f, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, // 16 MiB, without payload.
+			},
+			wantClose: StatusMessageTooBig,
+		},
+		{
+			name:      "huge_control_frame_length",
+			frames:    [][]byte{{0x89, 0x7f, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}}, // 1 TiB, without payload.
+			wantClose: StatusProtocolError,
+		},
+		{
+			name:           "decompressed_at_limit",
+			maxMessageSize: 250,
+			deflate:        true,
+			frames:         [][]byte{serverFrame(true, true, OpcodeText, bytes.TrimSuffix(compressed.Bytes(), deflateTail[:4]))},
+			wantLen:        250,
+		},
+		{
+			name:           "decompressed_over_limit",
+			maxMessageSize: 249,
+			deflate:        true,
+			frames:         [][]byte{serverFrame(true, true, OpcodeText, bytes.TrimSuffix(compressed.Bytes(), deflateTail[:4]))},
+			wantClose:      StatusMessageTooBig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			c := &Conn{
+				logger:         slog.New(slog.DiscardHandler),
+				bufio:          bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(bytes.Join(tt.frames, nil))), bufio.NewWriter(out)),
+				closeGrace:     time.Hour,
+				maxMessageSize: tt.maxMessageSize,
+			}
+			if tt.deflate {
+				c.deflate = &deflate{}
+			}
+
+			msg := c.readMessage()
+			if tt.wantClose != 0 {
+				if msg != nil {
+					t.Fatalf("Conn.readMessage() = %d bytes, want nil", len(msg.Data))
+				}
+				op, _, payload := readCompressedFrame(t, out)
+				if op != opcodeClose || !bytes.HasPrefix(payload, closePayload(tt.wantClose)) {
+					t.Errorf("close frame = %s %q, want prefix %q", op, payload, closePayload(tt.wantClose))
+				}
+				if s := c.closeStatus.Load(); s == nil || s.status != tt.wantClose {
+					t.Errorf("recorded close status = %v, want %s", s, tt.wantClose)
+				}
+				return
+			}
+
+			if msg == nil {
+				t.Fatal("Conn.readMessage() = nil")
+			}
+			if len(msg.Data) != tt.wantLen {
+				t.Errorf("Conn.readMessage() = %d bytes, want %d", len(msg.Data), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestConnStats(t *testing.T) {
+	c, server := newTCPTestConn(t, time.Hour)
+	_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if got := c.Stats(); got != (Stats{}) {
+		t.Fatalf("Conn.Stats() = %+v, want zero", got)
+	}
+
+	start := time.Now()
+	writeServerFrame(t, server, opcodePing, []byte("ping"))
+	if op, payload := readClientFrame(t, server); op != opcodePong || string(payload) != "ping" {
+		t.Fatalf("client frame = %s %q, want %s %q", op, payload, opcodePong, "ping")
+	}
+
+	writeServerFrame(t, server, OpcodeText, []byte("hello"))
+	select {
+	case <-c.IncomingMessages():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a message")
+	}
+
+	if err := <-c.SendTextMessage([]byte("hi")); err != nil {
+		t.Fatalf("Conn.SendTextMessage() error = %v", err)
+	}
+	readClientFrame(t, server)
+
+	got := c.Stats()
+	if got.LastRead.Before(start) || got.LastWrite.Before(start) {
+		t.Errorf("Conn.Stats() last read/write = %v/%v, want after %v", got.LastRead, got.LastWrite, start)
+	}
+	got.LastRead, got.LastWrite = time.Time{}, time.Time{}
+
+	want := Stats{FramesIn: 2, BytesIn: 9, PingsIn: 1, MessagesIn: 1, FramesOut: 2, BytesOut: 6, PongsOut: 1}
+	if got != want {
+		t.Errorf("Conn.Stats() = %+v, want %+v", got, want)
+	}
+}
+
+// fragment is a frame which the client sent, as read by [readClientFragment].
+type fragment struct {
+	fin     bool
+	op      Opcode
+	payload string
+}
+
+// readClientFragment is like [readClientFrame], but it also returns the FIN bit.
+func readClientFragment(t *testing.T, r io.Reader) fragment {
+	t.Helper()
+
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	fin := b[0]&0x80 != 0
+
+	op, payload := readClientFrame(t, io.MultiReader(bytes.NewReader([]byte{b[0] | 0x80}), r))
+	return fragment{fin: fin, op: op, payload: string(payload)}
+}
+
+func TestConnSendMessageFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		binary  bool
+		r       io.Reader
+		want    []fragment
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			r:    strings.NewReader(""),
+			want: []fragment{{fin: true, op: OpcodeText}},
+		},
+		{
+			name: "single_fragment",
+			r:    strings.NewReader("abc"),
+			want: []fragment{{fin: true, op: OpcodeText, payload: "abc"}},
+		},
+		{
+			name: "multiple_fragments",
+			r:    strings.NewReader("hello world"),
+			want: []fragment{
+				{op: OpcodeText, payload: "hell"},
+				{op: opcodeContinuation, payload: "o wo"},
+				{fin: true, op: opcodeContinuation, payload: "rld"},
+			},
+		},
+		{
+			name:   "exact_multiple_binary",
+			binary: true,
+			r:      strings.NewReader("abcdefgh"),
+			want: []fragment{
+				{op: OpcodeBinary, payload: "abcd"},
+				{op: opcodeContinuation, payload: "efgh"},
+				{fin: true, op: opcodeContinuation},
+			},
+		},
+		{
+			name:    "immediate_reader_error",
+			r:       iotest.ErrReader(errors.New("oops")),
+			wantErr: true,
+		},
+		{
+			name: "reader_error_after_first_fragment",
+			r:    io.MultiReader(strings.NewReader("abcd"), iotest.ErrReader(errors.New("oops"))),
+			want: []fragment{
+				{op: OpcodeText, payload: "abcd"},
+				{fin: true, op: opcodeClose, payload: string(closePayload(StatusInternalError)) + "message streaming error"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTCPTestConn(t, time.Hour)
+			_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+			c.frameSize = 4
+
+			send := c.SendTextMessageFrom
+			if tt.binary {
+				send = c.SendBinaryMessageFrom
+			}
+			if err := <-send(tt.r); (err != nil) != tt.wantErr {
+				t.Fatalf("Conn.Send*MessageFrom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for i, want := range tt.want {
+				if got := readClientFragment(t, server); got != want {
+					t.Errorf("frame %d = %+v, want %+v", i, got, want)
+				}
+			}
+			if got, want := c.Stats().FramesOut, int64(len(tt.want)); got != want {
+				t.Errorf("Conn.Stats().FramesOut = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkSendTextMessage(b *testing.B) {
+	s := websockettest.NewServer(b)
+	before := runtime.NumGoroutine()
+	c, err := Dial(b.Context(), s.URL())
+	if err != nil {
+		b.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close(StatusNormalClosure)
+
+	// Includes the test server's goroutines for the connection.
+	goroutines := float64(runtime.NumGoroutine() - before)
+
+	data := bytes.Repeat([]byte("x"), 125)
+	b.Run("serial", func(b *testing.B) {
+		for b.Loop() {
+			if err := <-c.SendTextMessage(data); err != nil {
+				b.Fatalf("Conn.SendTextMessage() error = %v", err)
+			}
+		}
+		b.ReportMetric(goroutines, "goroutines")
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			data := bytes.Repeat([]byte("x"), 125) // Masking modifies the data temporarily.
+			for pb.Next() {
+				if err := <-c.SendTextMessage(data); err != nil {
+					b.Errorf("Conn.SendTextMessage() error = %v", err)
+					return
+				}
+			}
+		})
+		b.ReportMetric(goroutines, "goroutines")
+	})
+}
+
 func constructBenchmarkFrame(b *testing.B, bb benchmark) []byte {
 	b.Helper()
 
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// maxPendingPings is the maximum number of [Conn.Ping] payloads that
+// are remembered while waiting for the server's pong control frames.
+const maxPendingPings = 16
+
+// unsolicitedPongWindow is the time window of [WithUnsolicitedPongLimit].
+const unsolicitedPongWindow = time.Minute
+
+// keepAlivePayload is the prefix of the payloads of the ping control frames which
+// [Conn.keepAlive] sends, followed by a sequence number, to match their pongs.
+const keepAlivePayload = "keepalive "
+
+// Ping sends a [ping control frame] to the server, e.g. to check that the
+// connection is still alive. The server's [pong control frame] in response
+// is recorded, so it isn't counted as unsolicited (see [WithUnsolicitedPongLimit]).
+//
+// This is done just like [Conn.SendTextMessage],
+// and the returned channel can be used in the same way.
+//
+// [ping control frame]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.5.2
+// [pong control frame]: https://datatracker.ietf.org/doc/html/rfc6455#section-5.5.3
+func (c *Conn) Ping(payload []byte) <-chan error {
+	// "All control frames MUST have a payload length of 125 bytes or less".
+	if len(payload) > maxControlPayload {
+		err := make(chan error, 1)
+		err <- errors.New("WebSocket ping payload too large")
+		close(err)
+		return err
+	}
+
+	c.pingsMu.Lock()
+	c.pendingPings = append(c.pendingPings, string(payload))
+	if len(c.pendingPings) > maxPendingPings {
+		c.pendingPings = c.pendingPings[1:]
+	}
+	c.pingsMu.Unlock()
+
+	return c.sendControlFrame(opcodePing, payload)
+}
+
+// handlePong matches an incoming pong control frame with a previous [Conn.Ping]
+// call, or counts it as unsolicited. It returns false if the connection exceeded its
+// limit of unsolicited pong control frames in the current [unsolicitedPongWindow],
+// and therefore needs to be closed.
+func (c *Conn) handlePong(payload []byte) bool {
+	c.pingsMu.Lock()
+	defer c.pingsMu.Unlock()
+
+	// The response to the latest ping of [Conn.keepAlive].
+	if c.pingPending != "" && string(payload) == c.pingPending {
+		c.pingPending = ""
+		select {
+		case c.keepAlivePongs <- struct{}{}:
+		default: // Not waiting anymore.
+		}
+		return true
+	}
+
+	// "If an endpoint receives a Ping frame and has not yet sent Pong frame(s)
+	// in response to previous Ping frame(s), the endpoint MAY elect to send a
+	// Pong frame for only the most recently processed Ping frame".
+	for i := len(c.pendingPings) - 1; i >= 0; i-- {
+		if c.pendingPings[i] == string(payload) {
+			c.pendingPings = c.pendingPings[i+1:]
+			return true
+		}
+	}
+
+	// "A Pong frame MAY be sent unsolicited. This serves as a
+	// unidirectional heartbeat. A response to an unsolicited
+	// Pong frame is not expected". But not too many of them at once.
+	c.unsolicitedPongs.Add(1)
+	if now := time.Now(); now.Sub(c.pongWindowStart) >= unsolicitedPongWindow {
+		c.pongWindowStart, c.pongWindowCount = now, 0
+	}
+	c.pongWindowCount++
+	return c.pongLimit == 0 || c.pongWindowCount <= c.pongLimit
+}
+
+// keepAlive runs as a [Conn] goroutine if [WithKeepAlive] is set. It sends a ping
+// control frame to the server after each interval, as allowed by
+// https://datatracker.ietf.org/doc/html/rfc6455#section-5.5.2, to keep the connection
+// alive, and to detect dead (e.g. half-open) TCP connections: if the server doesn't
+// respond with a matching pong control frame within the timeout, it closes the underlying
+// connection immediately, without a closing handshake, so [Conn.readMessages] stops
+// blocking, and a [Client] can replace the connection. It ends as soon as the connection
+// starts closing, or stops receiving messages for any other reason.
+func (c *Conn) keepAlive() {
+	c.pingsMu.Lock()
+	c.keepAlivePongs = make(chan struct{}, 1)
+	c.pingsMu.Unlock()
+
+	timer := time.NewTimer(c.pingInterval)
+	defer timer.Stop()
+
+	for seq := 1; ; seq++ {
+		select {
+		case <-c.done:
+			return
+		case <-timer.C:
+		}
+		if c.IsClosing() || c.readDone.Load() {
+			return
+		}
+
+		payload := keepAlivePayload + strconv.Itoa(seq)
+		c.pingsMu.Lock()
+		c.pingPending = payload
+		c.pingsMu.Unlock()
+
+		if err := <-c.sendControlFrame(opcodePing, []byte(payload)); err != nil {
+			c.logger.Error("failed to send WebSocket keep-alive ping", slog.Any("error", err))
+		}
+
+		timer.Reset(c.pingTimeout)
+		select {
+		case <-c.keepAlivePongs: // Timer.Reset below discards the pending timeout, since Go 1.23.
+		case <-c.done:
+			return
+		case <-timer.C:
+			if c.IsClosing() || c.readDone.Load() {
+				return
+			}
+			c.logger.Warn("no response to WebSocket keep-alive ping, closing dead connection",
+				slog.Duration("timeout", c.pingTimeout))
+			c.recordCloseStatus(StatusClosedAbnormally, "ping timeout")
+			c.closeTransport()
+			return
+		}
+
+		timer.Reset(c.pingInterval)
+	}
+}
@@ -0,0 +1,306 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPingPong(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		pings     []string
+		pongs     []string
+		wantCount int64
+		wantClose bool
+	}{
+		{
+			name:  "solicited",
+			pings: []string{"a"},
+			pongs: []string{"a"},
+		},
+		{
+			name:  "empty_payload",
+			pings: []string{""},
+			pongs: []string{""},
+		},
+		{
+			name:      "only_latest_ping",
+			pings:     []string{"a", "b"},
+			pongs:     []string{"b", "a"},
+			wantCount: 1,
+		},
+		{
+			name:      "duplicate_pong",
+			pings:     []string{"a"},
+			pongs:     []string{"a", "a"},
+			wantCount: 1,
+		},
+		{
+			name:      "unsolicited_without_limit",
+			pongs:     []string{"x", "y", "z"},
+			wantCount: 3,
+		},
+		{
+			name:      "unsolicited_within_limit",
+			limit:     2,
+			pongs:     []string{"x", "y"},
+			wantCount: 2,
+		},
+		{
+			name:      "unsolicited_over_limit",
+			limit:     2,
+			pings:     []string{"a"},
+			pongs:     []string{"a", "x", "y", "z"},
+			wantCount: 3,
+			wantClose: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTCPTestConn(t, time.Second)
+			c.pongLimit = tt.limit
+
+			for _, p := range tt.pings {
+				if err := <-c.Ping([]byte(p)); err != nil {
+					t.Fatalf("Conn.Ping() error = %v", err)
+				}
+				if op, payload := readClientFrame(t, server); op != opcodePing || string(payload) != p {
+					t.Fatalf("client frame = %s %q, want ping %q", op, payload, p)
+				}
+			}
+
+			for _, p := range tt.pongs {
+				writeServerFrame(t, server, opcodePong, []byte(p))
+			}
+
+			// The client's response to a ping from the server proves
+			// that it already processed all the preceding pongs.
+			writeServerFrame(t, server, opcodePing, []byte("sync"))
+			op, payload := readClientFrame(t, server)
+
+			if tt.wantClose {
+				if op != opcodeClose || binary.BigEndian.Uint16(payload) != uint16(StatusPolicyViolation) {
+					t.Errorf("client frame = %s %q, want close %d", op, payload, StatusPolicyViolation)
+				}
+			} else if op != opcodePong || !bytes.Equal(payload, []byte("sync")) {
+				t.Errorf("client frame = %s %q, want pong %q", op, payload, "sync")
+			}
+
+			if got := c.DebugInfo().UnsolicitedPongs; got != tt.wantCount {
+				t.Errorf("Conn.DebugInfo().UnsolicitedPongs = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestUnsolicitedPongWindow(t *testing.T) {
+	c, server := newTCPTestConn(t, time.Second)
+	c.pongLimit = 2
+
+	sendPongs := func(payloads ...string) (Opcode, []byte) {
+		for _, p := range payloads {
+			writeServerFrame(t, server, opcodePong, []byte(p))
+		}
+		// The client's response to a ping from the server proves
+		// that it already processed all the preceding pongs.
+		writeServerFrame(t, server, opcodePing, []byte("sync"))
+		return readClientFrame(t, server)
+	}
+
+	// Occasional unsolicited pongs, in separate windows, never exceed the limit.
+	for i := range 3 {
+		if op, payload := sendPongs("x", "y"); op != opcodePong {
+			t.Fatalf("window %d: client frame = %s %q, want pong", i+1, op, payload)
+		}
+
+		c.pingsMu.Lock()
+		c.pongWindowStart = c.pongWindowStart.Add(-unsolicitedPongWindow)
+		c.pingsMu.Unlock()
+	}
+
+	if got := c.DebugInfo().UnsolicitedPongs; got != 6 {
+		t.Errorf("Conn.DebugInfo().UnsolicitedPongs = %d, want 6", got)
+	}
+
+	// Too many in a single window.
+	op, payload := sendPongs("x", "y", "z")
+	if op != opcodeClose || binary.BigEndian.Uint16(payload) != uint16(StatusPolicyViolation) {
+		t.Errorf("client frame = %s %q, want close %d", op, payload, StatusPolicyViolation)
+	}
+}
+
+func TestPingPayloadTooLarge(t *testing.T) {
+	c := &Conn{}
+	if err := <-c.Ping([]byte(strings.Repeat("x", maxControlPayload+1))); err == nil {
+		t.Error("Conn.Ping() error = nil, want error")
+	}
+	if len(c.pendingPings) > 0 {
+		t.Errorf("Conn.pendingPings = %q, want none", c.pendingPings)
+	}
+}
+
+func TestPendingPingsLimit(t *testing.T) {
+	c, server := newTCPTestConn(t, time.Second)
+
+	for i := range maxPendingPings + 1 {
+		if err := <-c.Ping([]byte{byte(i)}); err != nil {
+			t.Fatalf("Conn.Ping() error = %v", err)
+		}
+		readClientFrame(t, server)
+	}
+
+	c.pingsMu.Lock()
+	defer c.pingsMu.Unlock()
+	if len(c.pendingPings) != maxPendingPings || c.pendingPings[0] != "\x01" {
+		t.Errorf("Conn.pendingPings = %q, want the latest %d", c.pendingPings, maxPendingPings)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	tests := []struct {
+		name     string
+		pong     func(ping []byte) []byte // Nil = no response.
+		wantDead bool
+	}{
+		{
+			name: "pong_received",
+			pong: func(ping []byte) []byte { return ping },
+		},
+		{
+			name:     "pong_missed",
+			wantDead: true,
+		},
+		{
+			name:     "pong_mismatched",
+			pong:     func([]byte) []byte { return []byte("other") },
+			wantDead: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTCPTestConn(t, time.Hour)
+			_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+			c.pingInterval = 10 * time.Millisecond
+			c.pingTimeout = 5 * time.Second
+			if tt.wantDead {
+				c.pingTimeout = 50 * time.Millisecond
+			}
+			go c.keepAlive()
+
+			for i := range 3 {
+				op, payload := readClientFrame(t, server)
+				want := keepAlivePayload + strconv.Itoa(i+1)
+				if op != opcodePing || string(payload) != want {
+					t.Fatalf("client frame = %s %q, want ping %q", op, payload, want)
+				}
+				if tt.pong != nil {
+					writeServerFrame(t, server, opcodePong, tt.pong(payload))
+				}
+				if tt.wantDead {
+					break
+				}
+			}
+
+			if !tt.wantDead {
+				if c.IsClosing() {
+					t.Error("Conn.IsClosing() = true, want false")
+				}
+				if got := c.DebugInfo().UnsolicitedPongs; got != 0 {
+					t.Errorf("Conn.DebugInfo().UnsolicitedPongs = %d, want 0", got)
+				}
+				return
+			}
+
+			// The client closes the dead connection without a closing handshake.
+			waitForEOF(t, server, 5*time.Second)
+			for range c.IncomingMessages() {
+			}
+			status, reason, _ := c.CloseStatus()
+			if status != StatusClosedAbnormally || reason != "ping timeout" {
+				t.Errorf("Conn.CloseStatus() = %s %q, want %s %q", status, reason, StatusClosedAbnormally, "ping timeout")
+			}
+		})
+	}
+}
+
+func TestKeepAliveEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(c *Conn, server net.Conn)
+	}{
+		{
+			name: "client_close",
+			stop: func(c *Conn, _ net.Conn) { c.Close(StatusNormalClosure) },
+		},
+		{
+			name: "server_eof",
+			stop: func(_ *Conn, server net.Conn) { _ = server.Close() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, server := newTCPTestConn(t, time.Hour)
+			c.pingInterval = time.Hour // Much longer than the test.
+
+			done := make(chan struct{})
+			go func() {
+				c.keepAlive()
+				close(done)
+			}()
+
+			go func() {
+				_, _ = io.Copy(io.Discard, server) // Let the client write its close frame.
+			}()
+			tt.stop(c, server)
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Conn.keepAlive() didn't end before its next ping")
+			}
+		})
+	}
+}
+
+func TestKeepAliveClosingHandshake(t *testing.T) {
+	c, server := newTCPTestConn(t, time.Hour)
+	c.pingInterval = 10 * time.Millisecond
+	c.pingTimeout = 10 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		c.keepAlive()
+		close(done)
+	}()
+
+	c.Close(StatusNormalClosure)
+	_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+	if op, _ := readClientFrame(t, server); op != opcodeClose {
+		t.Fatalf("client frame = %s, want close", op)
+	}
+
+	// No pings after the client started closing, and no timeout while waiting for the server.
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Conn.keepAlive() didn't end after the closing handshake started")
+	}
+
+	_ = server.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, err := server.Read(make([]byte, 1)); n > 0 || err == nil {
+		t.Errorf("unexpected client frame after close frame: n = %d, err = %v", n, err)
+	}
+	if _, _, ok := c.CloseStatus(); ok {
+		t.Error("Conn.CloseStatus() ok = true before the closing handshake ended")
+	}
+}
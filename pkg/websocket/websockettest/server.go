@@ -0,0 +1,375 @@
+// Package websockettest provides a minimal, scripted WebSocket server
+// (RFC 6455) for [httptest], to test code which is based on the [websocket]
+// package (or any other WebSocket client) without external dependencies.
+//
+// Each connection to a [Server] runs the same script of [Step]s, e.g. sending
+// (optionally fragmented and delayed) text/binary/ping/close frames, and
+// the server records all the frames that clients send, see [Server.Frames].
+//
+// This package intentionally doesn't depend on the [websocket] package, so
+// the latter's own unit tests can use it too. It doesn't check all the protocol
+// requirements that real servers do, and it doesn't support extensions.
+//
+// [websocket]: https://pkg.go.dev/github.com/tzrikka/timpani/pkg/websocket
+package websockettest
+
+import (
+	"bufio"
+	"crypto/sha1" //gosec:disable G505 // Required by the WebSocket protocol.
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Opcode denotes the type of a WebSocket frame, as defined in
+// https://datatracker.ietf.org/doc/html/rfc6455#section-5.2.
+type Opcode byte
+
+const (
+	OpContinuation Opcode = 0
+	OpText         Opcode = 1
+	OpBinary       Opcode = 2
+	OpClose        Opcode = 8
+	OpPing         Opcode = 9
+	OpPong         Opcode = 10
+)
+
+// Frame is a WebSocket frame that a client sent to the [Server], after unmasking.
+type Frame struct {
+	Conn    int // 1-based index of the connection in the server's lifetime.
+	Fin     bool
+	Opcode  Opcode
+	Payload []byte
+}
+
+// Server is a wrapper of [httptest.Server] which accepts WebSocket connections.
+type Server struct {
+	*httptest.Server
+
+	script []Step
+
+	mu     sync.Mutex
+	conns  []net.Conn
+	frames []Frame
+	added  chan struct{} // Closed and replaced whenever a frame is recorded.
+}
+
+// NewServer starts a [Server] which runs the given script in each connection,
+// and then keeps the connection open until the client closes it. It also echoes
+// the client's close frame if the script didn't send one. The server is closed
+// automatically when the test and all its subtests complete.
+func NewServer(t testing.TB, script ...Step) *Server {
+	t.Helper()
+
+	s := &Server{script: script, added: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// URL returns the server's WebSocket URL ("ws://...").
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.Server.URL, "http")
+}
+
+// Close closes all the WebSocket connections (which [httptest.Server.Close]
+// doesn't track after they're hijacked), and then shuts down the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	s.Server.Close()
+}
+
+// Frames returns a copy of all the frames that clients sent so far.
+func (s *Server) Frames() []Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Frame(nil), s.frames...)
+}
+
+// WaitForFrames waits until clients sent at least n frames, or until the
+// timeout, and returns all the frames that clients sent until then.
+func (s *Server) WaitForFrames(n int, timeout time.Duration) []Frame {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		fs, added := append([]Frame(nil), s.frames...), s.added
+		s.mu.Unlock()
+
+		if len(fs) >= n {
+			return fs
+		}
+
+		select {
+		case <-added:
+		case <-deadline:
+			return fs
+		}
+	}
+}
+
+func (s *Server) record(f Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames = append(s.frames, f)
+	close(s.added)
+	s.added = make(chan struct{})
+}
+
+// handle performs the server side of a WebSocket handshake, as defined in
+// https://datatracker.ietf.org/doc/html/rfc6455#section-4.2.2, and then runs the script.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "not a WebSocket handshake", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Upgrade", "websocket")
+	w.Header().Set("Connection", "Upgrade")
+	w.Header().Set("Sec-WebSocket-Accept", AcceptValue(key))
+	w.WriteHeader(http.StatusSwitchingProtocols)
+
+	nc, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, nc)
+	c := &Conn{index: len(s.conns), rw: rw, done: make(chan struct{})}
+	s.mu.Unlock()
+
+	go c.readFrames(s.record)
+
+	for _, step := range s.script {
+		if err := step(c); err != nil {
+			_ = nc.Close()
+			return
+		}
+	}
+
+	<-c.done
+	_ = nc.Close()
+}
+
+var acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// AcceptValue returns the expected "Sec-WebSocket-Accept" handshake response header for the
+// given "Sec-WebSocket-Key" request header, see https://datatracker.ietf.org/doc/html/rfc6455#section-4.2.2.
+func AcceptValue(key string) string {
+	h := sha1.New() //gosec:disable G401 // Required by the WebSocket protocol.
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Conn is the server side of a single WebSocket connection, which [Step]s operate on.
+type Conn struct {
+	index int
+	rw    *bufio.ReadWriter
+
+	writeMu   sync.Mutex
+	closeSent bool
+	done      chan struct{} // Closed when the client stops sending frames.
+}
+
+// Index returns the 1-based index of the connection in the server's lifetime.
+func (c *Conn) Index() int {
+	return c.index
+}
+
+// WriteFrame sends a single unmasked frame to the client.
+func (c *Conn) WriteFrame(fin bool, op Opcode, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	b := byte(op)
+	if fin {
+		b |= 0x80
+	}
+	header := []byte{b}
+
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	if op == OpClose {
+		c.closeSent = true
+	}
+	if _, err := c.rw.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readFrames runs as a goroutine, to record and unmask the frames that the client sends.
+// It responds to a close frame with a close frame, unless one was already sent.
+func (c *Conn) readFrames(record func(Frame)) {
+	defer close(c.done)
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		record(f)
+
+		if f.Opcode == OpClose {
+			c.writeMu.Lock()
+			sent := c.closeSent
+			c.writeMu.Unlock()
+			if !sent {
+				_ = c.WriteFrame(true, OpClose, f.Payload)
+			}
+			return
+		}
+	}
+}
+
+func (c *Conn) readFrame() (Frame, error) {
+	f := Frame{Conn: c.index}
+	b := make([]byte, 2)
+	if _, err := io.ReadFull(c.rw, b); err != nil {
+		return f, err
+	}
+
+	f.Fin = b[0]&0x80 != 0
+	f.Opcode = Opcode(b[0] & 0x0f)
+	masked := b[1]&0x80 != 0
+	n := uint64(b[1] & 0x7f)
+
+	switch n {
+	case 126:
+		if _, err := io.ReadFull(c.rw, b); err != nil {
+			return f, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b))
+	case 127:
+		b8 := make([]byte, 8)
+		if _, err := io.ReadFull(c.rw, b8); err != nil {
+			return f, err
+		}
+		n = binary.BigEndian.Uint64(b8)
+	}
+
+	if !masked {
+		return f, errors.New("client frame is not masked")
+	}
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(c.rw, mask); err != nil {
+		return f, err
+	}
+
+	f.Payload = make([]byte, n)
+	if _, err := io.ReadFull(c.rw, f.Payload); err != nil {
+		return f, err
+	}
+	for i := range f.Payload {
+		f.Payload[i] ^= mask[i%4]
+	}
+
+	return f, nil
+}
+
+// Step is a single scripted action of a [Server] in a connection.
+// If it returns an error, the server closes the connection abruptly.
+type Step func(c *Conn) error
+
+// SendOpt configures the data frames of [SendText] and [SendBinary].
+type SendOpt func(*sendOpts)
+
+type sendOpts struct {
+	fragments int
+	delay     time.Duration
+}
+
+// WithFragments splits a data message into (up to) n frames, instead of 1.
+func WithFragments(n int) SendOpt {
+	return func(o *sendOpts) {
+		o.fragments = max(n, 1)
+	}
+}
+
+// WithFragmentDelay waits for the given duration between the fragments of a data message.
+func WithFragmentDelay(d time.Duration) SendOpt {
+	return func(o *sendOpts) {
+		o.delay = d
+	}
+}
+
+// SendText sends a text message to the client.
+func SendText(s string, opts ...SendOpt) Step {
+	return sendData(OpText, []byte(s), opts...)
+}
+
+// SendBinary sends a binary message to the client.
+func SendBinary(b []byte, opts ...SendOpt) Step {
+	return sendData(OpBinary, b, opts...)
+}
+
+func sendData(op Opcode, data []byte, opts ...SendOpt) Step {
+	o := &sendOpts{fragments: 1}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *Conn) error {
+		frameOp, size := op, max((len(data)+o.fragments-1)/o.fragments, 1)
+		for i := 0; i == 0 || i < len(data); i += size {
+			end := min(i+size, len(data))
+			if i > 0 {
+				frameOp = OpContinuation
+				time.Sleep(o.delay)
+			}
+			if err := c.WriteFrame(end == len(data), frameOp, data[i:end]); err != nil {
+				return fmt.Errorf("failed to send data frame: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// SendPing sends a ping control frame to the client.
+func SendPing(payload []byte) Step {
+	return func(c *Conn) error {
+		return c.WriteFrame(true, OpPing, payload)
+	}
+}
+
+// SendClose initiates a closing handshake with the given status code and reason.
+func SendClose(status uint16, reason string) Step {
+	return func(c *Conn) error {
+		payload := binary.BigEndian.AppendUint16(nil, status)
+		return c.WriteFrame(true, OpClose, append(payload, reason...))
+	}
+}
+
+// Sleep pauses the script for the given duration.
+func Sleep(d time.Duration) Step {
+	return func(_ *Conn) error {
+		time.Sleep(d)
+		return nil
+	}
+}